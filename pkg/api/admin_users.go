@@ -37,6 +37,9 @@ func (hs *HTTPServer) AdminCreateUser(c *models.ReqContext) response.Response {
 	if len(cmd.Password) < 4 {
 		return response.Error(400, "Password is missing or too short", nil)
 	}
+	if ok, rsp := hs.validatePasswordPolicy(c.Req.Context(), cmd.Password, &models.User{Login: cmd.Login, Email: cmd.Email}); !ok {
+		return rsp
+	}
 
 	user, err := hs.Login.CreateUser(cmd)
 	if err != nil {
@@ -51,6 +54,8 @@ func (hs *HTTPServer) AdminCreateUser(c *models.ReqContext) response.Response {
 		return response.Error(500, "failed to create user", err)
 	}
 
+	hs.recordPassword(c.Req.Context(), user.Id, user.Password, user.Salt)
+
 	metrics.MApiAdminUserCreate.Inc()
 
 	result := models.UserIdDTO{
@@ -82,6 +87,10 @@ func (hs *HTTPServer) AdminUpdateUserPassword(c *models.ReqContext) response.Res
 		return response.Error(500, "Could not read user from database", err)
 	}
 
+	if ok, rsp := hs.validatePasswordPolicy(c.Req.Context(), form.Password, userQuery.Result); !ok {
+		return rsp
+	}
+
 	passwordHashed, err := util.EncodePassword(form.Password, userQuery.Result.Salt)
 	if err != nil {
 		return response.Error(500, "Could not encode password", err)
@@ -95,6 +104,7 @@ func (hs *HTTPServer) AdminUpdateUserPassword(c *models.ReqContext) response.Res
 	if err := hs.SQLStore.ChangeUserPassword(c.Req.Context(), &cmd); err != nil {
 		return response.Error(500, "Failed to update user password", err)
 	}
+	hs.recordPassword(c.Req.Context(), userID, passwordHashed, userQuery.Result.Salt)
 
 	return response.Success("User password updated")
 }
@@ -193,6 +203,30 @@ func (hs *HTTPServer) AdminEnableUser(c *models.ReqContext) response.Response {
 	return response.Success("User enabled")
 }
 
+// POST /api/admin/users/:id/unlock
+func (hs *HTTPServer) AdminUnlockUser(c *models.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+
+	userQuery := models.GetUserByIdQuery{Id: userID}
+	if err := hs.SQLStore.GetUserById(c.Req.Context(), &userQuery); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return response.Error(404, models.ErrUserNotFound.Error(), nil)
+		}
+		return response.Error(500, "Could not read user from database", err)
+	}
+
+	// the failed logins are counted by the name the user logged in with
+	cmd := models.DeleteUserLoginAttemptsCommand{Usernames: []string{userQuery.Result.Login, userQuery.Result.Email}}
+	if err := hs.SQLStore.DeleteUserLoginAttempts(c.Req.Context(), &cmd); err != nil {
+		return response.Error(500, "Failed to unlock user", err)
+	}
+
+	return response.Success("User unlocked")
+}
+
 // POST /api/admin/users/:id/logout
 func (hs *HTTPServer) AdminLogoutUser(c *models.ReqContext) response.Response {
 	userID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
@@ -12,6 +12,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/auth"
 	"github.com/grafana/grafana/pkg/services/login/loginservice"
 	"github.com/grafana/grafana/pkg/services/login/logintest"
+	"github.com/grafana/grafana/pkg/services/passwordpolicy/passwordpolicytest"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/services/sqlstore/mockstore"
 	"github.com/grafana/grafana/pkg/setting"
@@ -406,6 +407,7 @@ func adminCreateUserScenario(t *testing.T, desc string, url string, routePattern
 				AlreadyExitingLogin: existingTestLogin,
 				GeneratedUserId:     testUserID,
 			},
+			passwordPolicyService: passwordpolicytest.NewPasswordPolicyServiceFake(),
 		}
 
 		sc := setupScenarioContext(t, url)
@@ -45,5 +45,24 @@ func ToDashboardErrorResponse(ctx context.Context, pluginStore plugins.Store, er
 		return response.JSON(http.StatusPreconditionFailed, util.DynMap{"status": "plugin-dashboard", "message": message})
 	}
 
+	var mergeErr models.DashboardMergeConflictError
+	if ok := errors.As(err, &mergeErr); ok {
+		return response.JSON(http.StatusPreconditionFailed, util.DynMap{
+			"status":    "merge-conflict",
+			"message":   mergeErr.Error(),
+			"version":   mergeErr.Version,
+			"conflicts": mergeErr.Conflicts,
+		})
+	}
+
+	var lintErr models.DashboardLintError
+	if ok := errors.As(err, &lintErr); ok {
+		return response.JSON(http.StatusUnprocessableEntity, util.DynMap{
+			"status":   "lint-failed",
+			"message":  lintErr.Error(),
+			"problems": lintErr.Problems,
+		})
+	}
+
 	return response.Error(http.StatusInternalServerError, "Failed to save dashboard", err)
 }
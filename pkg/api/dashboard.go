@@ -417,14 +417,19 @@ func (hs *HTTPServer) postDashboard(c *models.ReqContext, cmd models.SaveDashboa
 	}
 
 	c.TimeRequest(metrics.MApiDashboardSave)
-	return response.JSON(http.StatusOK, util.DynMap{
+	result := util.DynMap{
 		"status":  "success",
 		"slug":    dashboard.Slug,
 		"version": dashboard.Version,
 		"id":      dashboard.Id,
 		"uid":     dashboard.Uid,
 		"url":     dashboard.GetUrl(),
-	})
+	}
+	// the problems found by the lint rules that are only warnings
+	if len(dashItem.LintProblems) > 0 {
+		result["lintWarnings"] = dashItem.LintProblems
+	}
+	return response.JSON(http.StatusOK, result)
 }
 
 // GetHomeDashboard returns the home dashboard.
@@ -593,6 +598,7 @@ func (hs *HTTPServer) GetDashboardVersion(c *models.ReqContext) response.Respons
 		Created:       query.Result.Created,
 		Message:       query.Result.Message,
 		CreatedBy:     creator,
+		Tags:          query.Result.Tags,
 	}
 
 	return response.JSON(http.StatusOK, dashVersionMeta)
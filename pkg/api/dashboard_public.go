@@ -0,0 +1,192 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/services/publicdashboards"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// GET /api/dashboards/uid/:uid/public-config
+func (hs *HTTPServer) GetPublicDashboardConfig(c *models.ReqContext) response.Response {
+	dash, rsp := hs.getPublicDashboardAdminHelper(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	pd, err := hs.publicDashboardService.GetPublicDashboard(c.Req.Context(), c.OrgId, dash.Uid)
+	if err != nil {
+		return publicDashboardErrResponse(err, "Failed to get public dashboard")
+	}
+	return response.JSON(http.StatusOK, pd.ToDTO())
+}
+
+// POST /api/dashboards/uid/:uid/public-config
+func (hs *HTTPServer) SavePublicDashboardConfig(c *models.ReqContext) response.Response {
+	cmd := publicdashboards.SavePublicDashboardCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	dash, rsp := hs.getPublicDashboardAdminHelper(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	pd, err := hs.publicDashboardService.SavePublicDashboard(c.Req.Context(), c.SignedInUser, dash.Uid, &cmd)
+	if err != nil {
+		return publicDashboardErrResponse(err, "Failed to save public dashboard")
+	}
+	return response.JSON(http.StatusOK, pd.ToDTO())
+}
+
+// POST /api/dashboards/uid/:uid/public-config/rotate
+func (hs *HTTPServer) RotatePublicDashboardAccessToken(c *models.ReqContext) response.Response {
+	dash, rsp := hs.getPublicDashboardAdminHelper(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	pd, err := hs.publicDashboardService.RotateAccessToken(c.Req.Context(), c.SignedInUser, dash.Uid)
+	if err != nil {
+		return publicDashboardErrResponse(err, "Failed to rotate public dashboard access token")
+	}
+	return response.JSON(http.StatusOK, pd.ToDTO())
+}
+
+// DELETE /api/dashboards/uid/:uid/public-config
+func (hs *HTTPServer) RevokePublicDashboard(c *models.ReqContext) response.Response {
+	dash, rsp := hs.getPublicDashboardAdminHelper(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	pd, err := hs.publicDashboardService.RevokePublicDashboard(c.Req.Context(), c.SignedInUser, dash.Uid)
+	if err != nil {
+		return publicDashboardErrResponse(err, "Failed to revoke public dashboard")
+	}
+	return response.JSON(http.StatusOK, pd.ToDTO())
+}
+
+// getPublicDashboardAdminHelper returns the dashboard of the request, if the user can administrate it. Sharing a
+// dashboard publicly requires the same permission as changing who can access it.
+func (hs *HTTPServer) getPublicDashboardAdminHelper(c *models.ReqContext) (*models.Dashboard, response.Response) {
+	dash, rsp := hs.getDashboardHelper(c.Req.Context(), c.OrgId, 0, web.Params(c.Req)[":uid"])
+	if rsp != nil {
+		return nil, rsp
+	}
+	if dash.IsFolder {
+		return nil, response.Error(http.StatusBadRequest, "Folders can't be shared publicly", nil)
+	}
+
+	g := guardian.New(c.Req.Context(), dash.Id, c.OrgId, c.SignedInUser)
+	if canAdmin, err := g.CanAdmin(); err != nil || !canAdmin {
+		return nil, dashboardGuardianResponse(err)
+	}
+	return dash, nil
+}
+
+// GET /api/public/dashboards/:accessToken
+// The queries and datasources of the dashboard are not returned, the panels are queried by id.
+func (hs *HTTPServer) GetPublicDashboard(c *models.ReqContext) response.Response {
+	pd, dash, rsp := hs.getPublicDashboardHelper(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	meta := dtos.DashboardMeta{
+		Type:     models.DashTypeDB,
+		Slug:     dash.Slug,
+		Created:  dash.Created,
+		Updated:  dash.Updated,
+		Version:  dash.Version,
+		IsPublic: true,
+	}
+	data, err := publicdashboards.SanitizeDashboard(dash.Data)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get public dashboard", err)
+	}
+	c.Logger.Info("Public dashboard viewed", "uid", pd.UID, "dashboardUid", pd.DashboardUID, "orgId", pd.OrgID)
+	return response.JSON(http.StatusOK, dtos.DashboardFullWithMeta{Dashboard: data, Meta: meta})
+}
+
+// POST /api/public/dashboards/:accessToken/panels/:panelId/query
+func (hs *HTTPServer) QueryPublicDashboard(c *models.ReqContext) response.Response {
+	panelID, err := strconv.ParseInt(web.Params(c.Req)[":panelId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "panelId is invalid", err)
+	}
+	cmd := publicdashboards.QueryPanelCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	pd, dash, rsp := hs.getPublicDashboardHelper(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	reqDTOs, err := hs.publicDashboardService.BuildMetricRequests(c.Req.Context(), pd, dash, panelID, cmd)
+	if err != nil {
+		return publicDashboardErrResponse(err, "Failed to query panel")
+	}
+
+	// the query audit log records the dashboard the queries were sent from
+	httpReq := c.Req.Clone(c.Req.Context())
+	httpReq.Header.Set("X-Dashboard-Uid", dash.Uid)
+	httpReq.Header.Set("X-Dashboard-Id", strconv.FormatInt(dash.Id, 10))
+	httpReq.Header.Set("X-Panel-Id", strconv.FormatInt(panelID, 10))
+
+	resp := &backend.QueryDataResponse{Responses: backend.Responses{}}
+	for _, reqDTO := range reqDTOs {
+		reqDTO.HTTPRequest = httpReq
+		dsResp, err := hs.queryDataService.QueryData(c.Req.Context(), pd.SignedInUser(), c.SkipCache, reqDTO, true)
+		if err != nil {
+			return hs.handleQueryMetricsError(err)
+		}
+		for refID, res := range dsResp.Responses {
+			resp.Responses[refID] = res
+		}
+	}
+	return hs.toJsonStreamingResponse(resp)
+}
+
+// getPublicDashboardHelper returns the public dashboard of the access token of the request, and the dashboard it
+// shares.
+func (hs *HTTPServer) getPublicDashboardHelper(c *models.ReqContext) (*publicdashboards.PublicDashboard, *models.Dashboard, response.Response) {
+	pd, err := hs.publicDashboardService.GetPublicDashboardByAccessToken(c.Req.Context(), web.Params(c.Req)[":accessToken"])
+	if err != nil {
+		return nil, nil, publicDashboardErrResponse(err, "Failed to get public dashboard")
+	}
+
+	dash, err := hs.publicDashboardService.GetDashboard(c.Req.Context(), pd)
+	if err != nil {
+		return nil, nil, publicDashboardErrResponse(err, "Failed to get public dashboard")
+	}
+	return pd, dash, nil
+}
+
+func publicDashboardErrResponse(err error, message string) response.Response {
+	switch {
+	case errors.Is(err, publicdashboards.ErrPublicDashboardNotFound):
+		return response.Error(http.StatusNotFound, "Public dashboard not found", nil)
+	case errors.Is(err, publicdashboards.ErrPanelNotFound):
+		return response.Error(http.StatusNotFound, "Panel not found", nil)
+	case errors.Is(err, publicdashboards.ErrPanelHasNoQueries),
+		errors.Is(err, publicdashboards.ErrInvalidTimeRange),
+		errors.Is(err, publicdashboards.ErrTimeRangeTooLong):
+		return response.Error(http.StatusBadRequest, util.Capitalize(err.Error()), err)
+	case errors.Is(err, models.ErrDataSourceNotFound):
+		return response.Error(http.StatusNotFound, "Data source not found", err)
+	}
+	return response.Error(http.StatusInternalServerError, message, err)
+}
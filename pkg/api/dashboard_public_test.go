@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/correlations/correlationstest"
+	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement/labelenforcementtest"
+	"github.com/grafana/grafana/pkg/services/datasources/ratelimit"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/publicdashboards"
+	"github.com/grafana/grafana/pkg/services/publicdashboards/publicdashboardstest"
+	"github.com/grafana/grafana/pkg/services/query"
+	"github.com/grafana/grafana/pkg/services/queryaudit/queryaudittest"
+	"github.com/grafana/grafana/pkg/web/webtest"
+)
+
+func TestAPIEndpoint_PublicDashboards(t *testing.T) {
+	pd := &publicdashboards.PublicDashboard{UID: "pd", DashboardUID: "dash", OrgID: 1, AccessToken: "token", IsEnabled: true}
+	dashboard := models.NewDashboard("Status")
+	dashboard.Uid = "dash"
+	dashboard.Data.Set("panels", []interface{}{map[string]interface{}{
+		"id": 1, "datasource": map[string]interface{}{"uid": "grafana"}, "targets": []interface{}{map[string]interface{}{"refId": "A"}},
+	}})
+
+	var receivedQueries []backend.DataQuery
+	qds := query.ProvideService(
+		nil,
+		nil,
+		nil,
+		&fakePluginRequestValidator{},
+		&fakeDatasources.FakeDataSourceService{},
+		&fakePluginClient{
+			QueryDataHandlerFunc: func(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+				receivedQueries = append(receivedQueries, req.Queries...)
+				return &backend.QueryDataResponse{Responses: backend.Responses{"A": backend.DataResponse{}}}, nil
+			},
+		},
+		&fakeOAuthTokenService{},
+		ratelimit.ProvideService(),
+		queryaudittest.NewQueryAuditServiceFake(),
+		correlationstest.NewCorrelationsServiceFake(),
+		labelenforcementtest.NewLabelEnforcementServiceFake(),
+	)
+
+	pdService := publicdashboardstest.NewPublicDashboardServiceFake()
+	pdService.ExpectedDisabled = false
+	pdService.ExpectedPublicDashboard = pd
+	pdService.ExpectedDashboard = dashboard
+	pdService.ExpectedMetricRequests = []dtos.MetricRequest{{
+		From: "1600000000000",
+		To:   "1600003600000",
+		Queries: []*simplejson.Json{simplejson.NewFromAny(map[string]interface{}{
+			"refId":      "A",
+			"datasource": map[string]interface{}{"uid": "grafana", "type": "datasource"},
+			"queryType":  "randomWalk",
+		})},
+	}}
+
+	server := SetupAPITestServer(t, func(hs *HTTPServer) {
+		hs.queryDataService = qds
+		hs.publicDashboardService = pdService
+		hs.Features = featuremgmt.WithFeatures(featuremgmt.FlagPublicDashboards)
+	})
+
+	t.Run("the dashboard shared by the access token is returned", func(t *testing.T) {
+		req := server.NewGetRequest("/api/public/dashboards/token")
+		webtest.RequestWithSignedInUser(req, pd.SignedInUser())
+		resp, err := server.Send(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		result := dtos.DashboardFullWithMeta{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+		require.NoError(t, resp.Body.Close())
+		assert.True(t, result.Meta.IsPublic)
+		assert.False(t, result.Meta.CanSave)
+		assert.Equal(t, "Status", result.Dashboard.Get("title").MustString())
+		// the panels are queried by id, their queries are not shared
+		panel := result.Dashboard.Get("panels").GetIndex(0)
+		assert.Equal(t, int64(1), panel.Get("id").MustInt64())
+		assert.Equal(t, map[string]interface{}{"id": json.Number("1")}, panel.Interface())
+	})
+
+	t.Run("the queries saved in the panel are sent to the datasource", func(t *testing.T) {
+		receivedQueries = nil
+		req := server.NewPostRequest("/api/public/dashboards/token/panels/1/query", strings.NewReader(`{"from": "now-1h", "to": "now"}`))
+		webtest.RequestWithSignedInUser(req, pd.SignedInUser())
+		resp, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		require.Len(t, receivedQueries, 1)
+		assert.Equal(t, "A", receivedQueries[0].RefID)
+		assert.Equal(t, int64(1600000000000), receivedQueries[0].TimeRange.From.UnixMilli())
+	})
+
+	t.Run("unknown access tokens and panels are not found", func(t *testing.T) {
+		pdService.ExpectedError = publicdashboards.ErrPublicDashboardNotFound
+		t.Cleanup(func() { pdService.ExpectedError = nil })
+
+		req := server.NewGetRequest("/api/public/dashboards/unknown")
+		webtest.RequestWithSignedInUser(req, &models.SignedInUser{IsAnonymous: true})
+		resp, err := server.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("time ranges longer than the maximum are rejected", func(t *testing.T) {
+		pdService.ExpectedError = publicdashboards.ErrTimeRangeTooLong
+		t.Cleanup(func() { pdService.ExpectedError = nil })
+
+		req := server.NewPostRequest("/api/public/dashboards/token/panels/1/query", strings.NewReader(`{"from": "now-1y", "to": "now"}`))
+		webtest.RequestWithSignedInUser(req, pd.SignedInUser())
+		resp, err := server.SendJSON(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("public dashboards are not served when the feature is disabled", func(t *testing.T) {
+		serverDisabled := SetupAPITestServer(t, func(hs *HTTPServer) {
+			hs.queryDataService = qds
+			hs.publicDashboardService = pdService
+		})
+
+		req := serverDisabled.NewGetRequest("/api/public/dashboards/token")
+		webtest.RequestWithSignedInUser(req, pd.SignedInUser())
+		resp, err := serverDisabled.Send(req)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/metrics"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
 	"github.com/grafana/grafana/pkg/services/guardian"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
@@ -134,7 +136,14 @@ func (hs *HTTPServer) CreateDashboardSnapshot(c *models.ReqContext) response.Res
 	}
 
 	if err := hs.DashboardsnapshotsService.CreateDashboardSnapshot(c.Req.Context(), &cmd); err != nil {
-		c.JsonApiErr(500, "Failed to create snapshot", err)
+		switch {
+		case errors.Is(err, dashboardsnapshots.ErrSnapshotTooLarge):
+			c.JsonApiErr(http.StatusRequestEntityTooLarge, "Snapshot is too large", err)
+		case errors.Is(err, dashboardsnapshots.ErrSnapshotQuotaReached):
+			c.JsonApiErr(http.StatusForbidden, "Snapshot storage quota reached", err)
+		default:
+			c.JsonApiErr(500, "Failed to create snapshot", err)
+		}
 		return nil
 	}
 
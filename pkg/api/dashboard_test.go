@@ -16,6 +16,7 @@ import (
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/components/dashdiffs"
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/usagestats"
 	"github.com/grafana/grafana/pkg/models"
@@ -101,7 +102,7 @@ func newTestLive(t *testing.T, store *sqlstore.SQLStore) *live.GrafanaLive {
 		nil,
 		&usagestats.UsageStatsMock{T: t},
 		nil,
-		features, accesscontrolmock.New())
+		features, accesscontrolmock.New(), nil)
 	require.NoError(t, err)
 	return gLive
 }
@@ -233,7 +234,7 @@ func TestDashboardAPIEndpoint(t *testing.T) {
 			AccessControl:         accesscontrolmock.New(),
 			dashboardService: service.ProvideDashboardService(
 				cfg, dashboardStore, nil, features,
-				accesscontrolmock.NewMockedPermissionsService(), accesscontrolmock.NewMockedPermissionsService(),
+				accesscontrolmock.NewMockedPermissionsService(), accesscontrolmock.NewMockedPermissionsService(), nil,
 			),
 		}
 		hs.SQLStore = mockSQLStore
@@ -695,6 +696,8 @@ func TestDashboardAPIEndpoint(t *testing.T) {
 				{SaveError: models.ErrDashboardUidTooLong, ExpectedStatusCode: 400},
 				{SaveError: models.ErrDashboardCannotSaveProvisionedDashboard, ExpectedStatusCode: 400},
 				{SaveError: models.UpdatePluginDashboardError{PluginId: "plug"}, ExpectedStatusCode: 412},
+				{SaveError: models.DashboardMergeConflictError{Version: 3, Conflicts: []*dashdiffs.Conflict{{Path: "title"}}}, ExpectedStatusCode: 412},
+				{SaveError: models.DashboardLintError{Problems: []models.DashboardLintProblem{{Rule: "max-panels", Severity: models.DashboardLintSeverityBlocking}}}, ExpectedStatusCode: 422},
 			}
 
 			cmd := models.SaveDashboardCommand{
@@ -939,7 +942,7 @@ func getDashboardShouldReturn200WithConfig(t *testing.T, sc *scenarioContext, pr
 		AccessControl:         accesscontrolmock.New(),
 		dashboardProvisioningService: service.ProvideDashboardService(
 			cfg, dashboardStore, nil, features,
-			accesscontrolmock.NewMockedPermissionsService(), accesscontrolmock.NewMockedPermissionsService(),
+			accesscontrolmock.NewMockedPermissionsService(), accesscontrolmock.NewMockedPermissionsService(), nil,
 		),
 	}
 
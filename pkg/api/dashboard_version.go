@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// POST /api/dashboards/id/:dashboardId/versions/:id/tags
+func (hs *HTTPServer) AddDashboardVersionTag(c *models.ReqContext) response.Response {
+	cmd := models.AddDashboardVersionTagCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	dashID, version, rsp := dashboardVersionParams(c)
+	if rsp != nil {
+		return rsp
+	}
+	cmd.OrgId = c.OrgId
+	cmd.DashboardId = dashID
+	cmd.Version = version
+
+	if err := hs.SQLStore.AddDashboardVersionTag(c.Req.Context(), &cmd); err != nil {
+		return dashboardVersionTagErrorResponse(err, "Failed to tag dashboard version")
+	}
+
+	return response.Success("Dashboard version tagged")
+}
+
+// DELETE /api/dashboards/id/:dashboardId/versions/:id/tags/:tag
+func (hs *HTTPServer) DeleteDashboardVersionTag(c *models.ReqContext) response.Response {
+	dashID, version, rsp := dashboardVersionParams(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	cmd := models.DeleteDashboardVersionTagCommand{
+		OrgId:       c.OrgId,
+		DashboardId: dashID,
+		Version:     version,
+		Tag:         web.Params(c.Req)[":tag"],
+	}
+	if err := hs.SQLStore.DeleteDashboardVersionTag(c.Req.Context(), &cmd); err != nil {
+		return dashboardVersionTagErrorResponse(err, "Failed to remove dashboard version tag")
+	}
+
+	return response.Success("Dashboard version tag removed")
+}
+
+// dashboardVersionParams parses the dashboard id and version of the request, and checks that the user can save
+// the dashboard, as for the other version endpoints.
+func dashboardVersionParams(c *models.ReqContext) (int64, int, response.Response) {
+	dashID, err := strconv.ParseInt(web.Params(c.Req)[":dashboardId"], 10, 64)
+	if err != nil {
+		return 0, 0, response.Error(http.StatusBadRequest, "dashboardId is invalid", err)
+	}
+	version, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 32)
+	if err != nil {
+		return 0, 0, response.Error(http.StatusBadRequest, "version is invalid", err)
+	}
+
+	guardian := guardian.New(c.Req.Context(), dashID, c.OrgId, c.SignedInUser)
+	if canSave, err := guardian.CanSave(); err != nil || !canSave {
+		return 0, 0, dashboardGuardianResponse(err)
+	}
+	return dashID, int(version), nil
+}
+
+func dashboardVersionTagErrorResponse(err error, message string) response.Response {
+	if errors.Is(err, models.ErrDashboardVersionTagInvalid) {
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+	if errors.Is(err, models.ErrDashboardVersionNotFound) {
+		return response.Error(http.StatusNotFound, err.Error(), err)
+	}
+	return response.Error(http.StatusInternalServerError, message, err)
+}
+
+// GET /api/org/dashboard-versions/retention
+func (hs *HTTPServer) GetDashboardVersionRetention(c *models.ReqContext) response.Response {
+	query := models.GetDashboardVersionRetentionQuery{OrgId: c.OrgId}
+	err := hs.SQLStore.GetDashboardVersionRetention(c.Req.Context(), &query)
+	if errors.Is(err, models.ErrDashboardVersionRetentionNotFound) {
+		policy := hs.Cfg.DashboardVersionRetention
+		return response.JSON(http.StatusOK, dtos.DashboardVersionRetention{
+			KeepAll:       formatRetentionDuration(policy.KeepAll),
+			KeepDaily:     formatRetentionDuration(policy.KeepDaily),
+			KeepWeekly:    formatRetentionDuration(policy.KeepWeekly),
+			ProtectedTags: hs.dashboardVersionProtectedTags(),
+			Default:       true,
+		})
+	}
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get dashboard version retention", err)
+	}
+
+	return response.JSON(http.StatusOK, hs.toDashboardVersionRetentionDTO(query.Result))
+}
+
+// PUT /api/org/dashboard-versions/retention
+func (hs *HTTPServer) UpdateDashboardVersionRetention(c *models.ReqContext) response.Response {
+	cmd := models.SaveDashboardVersionRetentionCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if _, err := setting.ParseDashboardVersionRetention(cmd.KeepAll, cmd.KeepDaily, cmd.KeepWeekly); err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+	cmd.OrgId = c.OrgId
+
+	if err := hs.SQLStore.SaveDashboardVersionRetention(c.Req.Context(), &cmd); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to save dashboard version retention", err)
+	}
+
+	return response.JSON(http.StatusOK, hs.toDashboardVersionRetentionDTO(cmd.Result))
+}
+
+// DELETE /api/org/dashboard-versions/retention
+func (hs *HTTPServer) DeleteDashboardVersionRetention(c *models.ReqContext) response.Response {
+	cmd := models.DeleteDashboardVersionRetentionCommand{OrgId: c.OrgId}
+	if err := hs.SQLStore.DeleteDashboardVersionRetention(c.Req.Context(), &cmd); err != nil {
+		if errors.Is(err, models.ErrDashboardVersionRetentionNotFound) {
+			return response.Error(http.StatusNotFound, err.Error(), err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to delete dashboard version retention", err)
+	}
+
+	return response.Success("Dashboard version retention reset to the default one")
+}
+
+func (hs *HTTPServer) toDashboardVersionRetentionDTO(retention *models.DashboardVersionRetention) dtos.DashboardVersionRetention {
+	return dtos.DashboardVersionRetention{
+		KeepAll:       retention.KeepAll,
+		KeepDaily:     retention.KeepDaily,
+		KeepWeekly:    retention.KeepWeekly,
+		ProtectedTags: hs.dashboardVersionProtectedTags(),
+		Updated:       &retention.Updated,
+	}
+}
+
+func (hs *HTTPServer) dashboardVersionProtectedTags() []string {
+	if hs.Cfg.DashboardVersionProtectedTags == nil {
+		return []string{}
+	}
+	return hs.Cfg.DashboardVersionProtectedTags
+}
+
+// formatRetentionDuration formats a duration of the retention policy in days when it is a number of days.
+func formatRetentionDuration(duration time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case duration == 0:
+		return ""
+	case duration%day == 0:
+		return fmt.Sprintf("%dd", duration/day)
+	default:
+		return duration.String()
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tunnel"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins/adapters"
 	"github.com/grafana/grafana/pkg/services/datasources"
@@ -416,6 +417,17 @@ func (hs *HTTPServer) CallDatasourceResource(c *models.ReqContext) {
 		return
 	}
 
+	// the label rules are only enforced on queries, so the users they restrict cannot reach the datasource directly
+	matchers, err := hs.labelEnforcementService.GetMatchers(c.Req.Context(), c.SignedInUser, ds)
+	if err != nil {
+		c.JsonApiErr(500, "Failed to get label rules", err)
+		return
+	}
+	if len(matchers) > 0 {
+		c.JsonApiErr(403, "Data source resources are not available to users restricted by label rules", nil)
+		return
+	}
+
 	plugin, exists := hs.pluginStore.Plugin(c.Req.Context(), ds.Type)
 	if !exists {
 		c.JsonApiErr(500, "Unable to find datasource plugin", err)
@@ -506,6 +518,20 @@ func (hs *HTTPServer) CheckDatasourceHealth(c *models.ReqContext) response.Respo
 		return response.Error(http.StatusForbidden, "Access denied", err)
 	}
 
+	// report tunnel failures before the plugin reports them as connection errors
+	if ds.JsonData != nil {
+		tunnelCfg, err := tunnel.ConfigFromJSONData(ds.JsonData.MustMap(), dsInstanceSettings.DecryptedSecureJSONData)
+		if err == nil && tunnelCfg != nil {
+			err = tunnel.CheckHealth(c.Req.Context(), tunnelCfg)
+		}
+		if err != nil {
+			return response.JSON(http.StatusBadRequest, map[string]interface{}{
+				"status":  backend.HealthStatusError.String(),
+				"message": err.Error(),
+			})
+		}
+	}
+
 	resp, err := hs.pluginClient.CheckHealth(c.Req.Context(), req)
 	if err != nil {
 		return translatePluginRequestErrorToAPIError(err)
@@ -10,6 +10,7 @@ type DashboardMeta struct {
 	IsStarred              bool                  `json:"isStarred,omitempty"`
 	IsHome                 bool                  `json:"isHome,omitempty"`
 	IsSnapshot             bool                  `json:"isSnapshot,omitempty"`
+	IsPublic               bool                  `json:"isPublic,omitempty"`
 	Type                   string                `json:"type,omitempty"`
 	CanSave                bool                  `json:"canSave"`
 	CanEdit                bool                  `json:"canEdit"`
@@ -74,3 +75,12 @@ type CalculateDiffTarget struct {
 type RestoreDashboardVersionCommand struct {
 	Version int `json:"version" binding:"Required"`
 }
+
+type DashboardVersionRetention struct {
+	KeepAll       string     `json:"keepAll"`
+	KeepDaily     string     `json:"keepDaily"`
+	KeepWeekly    string     `json:"keepWeekly"`
+	ProtectedTags []string   `json:"protectedTags"`
+	Default       bool       `json:"default"`
+	Updated       *time.Time `json:"updated,omitempty"`
+}
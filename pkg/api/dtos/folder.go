@@ -1,22 +1,28 @@
 package dtos
 
-import "time"
+import (
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+)
 
 type Folder struct {
-	Id        int64     `json:"id"`
-	Uid       string    `json:"uid"`
-	Title     string    `json:"title"`
-	Url       string    `json:"url"`
-	HasAcl    bool      `json:"hasAcl"`
-	CanSave   bool      `json:"canSave"`
-	CanEdit   bool      `json:"canEdit"`
-	CanAdmin  bool      `json:"canAdmin"`
-	CanDelete bool      `json:"canDelete"`
-	CreatedBy string    `json:"createdBy"`
-	Created   time.Time `json:"created"`
-	UpdatedBy string    `json:"updatedBy"`
-	Updated   time.Time `json:"updated"`
-	Version   int       `json:"version"`
+	Id        int64                    `json:"id"`
+	Uid       string                   `json:"uid"`
+	Title     string                   `json:"title"`
+	Url       string                   `json:"url"`
+	HasAcl    bool                     `json:"hasAcl"`
+	CanSave   bool                     `json:"canSave"`
+	CanEdit   bool                     `json:"canEdit"`
+	CanAdmin  bool                     `json:"canAdmin"`
+	CanDelete bool                     `json:"canDelete"`
+	CreatedBy string                   `json:"createdBy"`
+	Created   time.Time                `json:"created"`
+	UpdatedBy string                   `json:"updatedBy"`
+	Updated   time.Time                `json:"updated"`
+	Version   int                      `json:"version"`
+	ParentUid string                   `json:"parentUid,omitempty"`
+	Parents   []*models.FolderPathItem `json:"parents,omitempty"`
 }
 
 type FolderSearchHit struct {
@@ -4,6 +4,7 @@ import "time"
 
 type UserToken struct {
 	Id                     int64     `json:"id"`
+	UserId                 int64     `json:"userId"`
 	IsActive               bool      `json:"isActive"`
 	ClientIp               string    `json:"clientIp"`
 	Device                 string    `json:"device"`
@@ -14,3 +15,10 @@ type UserToken struct {
 	CreatedAt              time.Time `json:"createdAt"`
 	SeenAt                 time.Time `json:"seenAt"`
 }
+
+// RevokeUserAuthTokensForm filters the tokens revoked by an admin
+type RevokeUserAuthTokensForm struct {
+	UserId int64 `json:"userId"`
+	// ClientIp is an ip address, or a network in CIDR notation
+	ClientIp string `json:"clientIp"`
+}
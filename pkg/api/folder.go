@@ -44,7 +44,7 @@ func (hs *HTTPServer) GetFolderByUID(c *models.ReqContext) response.Response {
 	}
 
 	g := guardian.New(c.Req.Context(), folder.Id, c.OrgId, c.SignedInUser)
-	return response.JSON(http.StatusOK, hs.toFolderDto(c.Req.Context(), g, folder))
+	return response.JSON(http.StatusOK, hs.toFolderDto(c.Req.Context(), c.SignedInUser, g, folder))
 }
 
 func (hs *HTTPServer) GetFolderByID(c *models.ReqContext) response.Response {
@@ -58,7 +58,7 @@ func (hs *HTTPServer) GetFolderByID(c *models.ReqContext) response.Response {
 	}
 
 	g := guardian.New(c.Req.Context(), folder.Id, c.OrgId, c.SignedInUser)
-	return response.JSON(http.StatusOK, hs.toFolderDto(c.Req.Context(), g, folder))
+	return response.JSON(http.StatusOK, hs.toFolderDto(c.Req.Context(), c.SignedInUser, g, folder))
 }
 
 func (hs *HTTPServer) CreateFolder(c *models.ReqContext) response.Response {
@@ -66,13 +66,13 @@ func (hs *HTTPServer) CreateFolder(c *models.ReqContext) response.Response {
 	if err := web.Bind(c.Req, &cmd); err != nil {
 		return response.Error(http.StatusBadRequest, "bad request data", err)
 	}
-	folder, err := hs.folderService.CreateFolder(c.Req.Context(), c.SignedInUser, c.OrgId, cmd.Title, cmd.Uid)
+	folder, err := hs.folderService.CreateFolder(c.Req.Context(), c.SignedInUser, c.OrgId, cmd.Title, cmd.Uid, cmd.ParentUid)
 	if err != nil {
 		return apierrors.ToFolderErrorResponse(err)
 	}
 
 	g := guardian.New(c.Req.Context(), folder.Id, c.OrgId, c.SignedInUser)
-	return response.JSON(http.StatusOK, hs.toFolderDto(c.Req.Context(), g, folder))
+	return response.JSON(http.StatusOK, hs.toFolderDto(c.Req.Context(), c.SignedInUser, g, folder))
 }
 
 func (hs *HTTPServer) UpdateFolder(c *models.ReqContext) response.Response {
@@ -86,18 +86,50 @@ func (hs *HTTPServer) UpdateFolder(c *models.ReqContext) response.Response {
 	}
 
 	g := guardian.New(c.Req.Context(), cmd.Result.Id, c.OrgId, c.SignedInUser)
-	return response.JSON(http.StatusOK, hs.toFolderDto(c.Req.Context(), g, cmd.Result))
+	return response.JSON(http.StatusOK, hs.toFolderDto(c.Req.Context(), c.SignedInUser, g, cmd.Result))
 }
 
-func (hs *HTTPServer) DeleteFolder(c *models.ReqContext) response.Response { // temporarily adding this function to HTTPServer, will be removed from HTTPServer when librarypanels featuretoggle is removed
-	err := hs.LibraryElementService.DeleteLibraryElementsInFolder(c.Req.Context(), c.SignedInUser, web.Params(c.Req)[":uid"])
+func (hs *HTTPServer) MoveFolder(c *models.ReqContext) response.Response {
+	cmd := models.MoveFolderCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	err := hs.folderService.MoveFolder(c.Req.Context(), c.SignedInUser, c.OrgId, web.Params(c.Req)[":uid"], &cmd)
 	if err != nil {
-		if errors.Is(err, libraryelements.ErrFolderHasConnectedLibraryElements) {
-			return response.Error(403, "Folder could not be deleted because it contains library elements in use", err)
-		}
 		return apierrors.ToFolderErrorResponse(err)
 	}
 
+	g := guardian.New(c.Req.Context(), cmd.Result.Id, c.OrgId, c.SignedInUser)
+	return response.JSON(http.StatusOK, hs.toFolderDto(c.Req.Context(), c.SignedInUser, g, cmd.Result))
+}
+
+func (hs *HTTPServer) DeleteFolder(c *models.ReqContext) response.Response { // temporarily adding this function to HTTPServer, will be removed from HTTPServer when librarypanels featuretoggle is removed
+	folderUIDs := []string{web.Params(c.Req)[":uid"]}
+	if hs.Cfg.MaxFolderDepth > 1 {
+		// subfolders are deleted with their parent, and so are their library elements
+		folder, err := hs.folderService.GetFolderByUID(c.Req.Context(), c.SignedInUser, c.OrgId, folderUIDs[0])
+		if err != nil {
+			return apierrors.ToFolderErrorResponse(err)
+		}
+		subfolders, err := hs.folderService.GetDescendants(c.Req.Context(), c.OrgId, folder.Id)
+		if err != nil {
+			return apierrors.ToFolderErrorResponse(err)
+		}
+		for _, subfolder := range subfolders {
+			folderUIDs = append(folderUIDs, subfolder.Uid)
+		}
+	}
+
+	for _, folderUID := range folderUIDs {
+		err := hs.LibraryElementService.DeleteLibraryElementsInFolder(c.Req.Context(), c.SignedInUser, folderUID)
+		if err != nil {
+			if errors.Is(err, libraryelements.ErrFolderHasConnectedLibraryElements) {
+				return response.Error(403, "Folder could not be deleted because it contains library elements in use", err)
+			}
+			return apierrors.ToFolderErrorResponse(err)
+		}
+	}
+
 	f, err := hs.folderService.DeleteFolder(c.Req.Context(), c.SignedInUser, c.OrgId, web.Params(c.Req)[":uid"], c.QueryBool("forceDeleteRules"))
 	if err != nil {
 		return apierrors.ToFolderErrorResponse(err)
@@ -110,7 +142,7 @@ func (hs *HTTPServer) DeleteFolder(c *models.ReqContext) response.Response { //
 	})
 }
 
-func (hs *HTTPServer) toFolderDto(ctx context.Context, g guardian.DashboardGuardian, folder *models.Folder) dtos.Folder {
+func (hs *HTTPServer) toFolderDto(ctx context.Context, user *models.SignedInUser, g guardian.DashboardGuardian, folder *models.Folder) dtos.Folder {
 	canEdit, _ := g.CanEdit()
 	canSave, _ := g.CanSave()
 	canAdmin, _ := g.CanAdmin()
@@ -125,6 +157,19 @@ func (hs *HTTPServer) toFolderDto(ctx context.Context, g guardian.DashboardGuard
 		updater = hs.getUserLogin(ctx, folder.UpdatedBy)
 	}
 
+	var parents []*models.FolderPathItem
+	if folder.ParentId > 0 {
+		var err error
+		if parents, err = hs.folderService.GetParents(ctx, user, user.OrgId, folder.Id); err != nil {
+			hs.log.Warn("Failed to get parents of folder", "folder", folder.Uid, "error", err)
+		}
+	}
+	// the parent is not shown when the user cannot view it
+	parentUID := ""
+	if len(parents) > 0 && parents[len(parents)-1].ID == folder.ParentId {
+		parentUID = parents[len(parents)-1].UID
+	}
+
 	return dtos.Folder{
 		Id:        folder.Id,
 		Uid:       folder.Uid,
@@ -140,5 +185,7 @@ func (hs *HTTPServer) toFolderDto(ctx context.Context, g guardian.DashboardGuard
 		UpdatedBy: updater,
 		Updated:   folder.Updated,
 		Version:   folder.Version,
+		ParentUid: parentUID,
+		Parents:   parents,
 	}
 }
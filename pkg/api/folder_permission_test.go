@@ -42,7 +42,7 @@ func TestFolderPermissionAPIEndpoint(t *testing.T) {
 		folderPermissionsService:    folderPermissions,
 		dashboardPermissionsService: dashboardPermissions,
 		dashboardService: service.ProvideDashboardService(
-			settings, dashboardStore, nil, features, folderPermissions, dashboardPermissions,
+			settings, dashboardStore, nil, features, folderPermissions, dashboardPermissions, nil,
 		),
 		AccessControl: accesscontrolmock.New().WithDisabled(),
 	}
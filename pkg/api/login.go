@@ -15,7 +15,9 @@ import (
 	"github.com/grafana/grafana/pkg/login"
 	"github.com/grafana/grafana/pkg/middleware/cookies"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/passwordpolicy"
 	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/totp"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util/errutil"
 	"github.com/grafana/grafana/pkg/web"
@@ -205,7 +207,7 @@ func (hs *HTTPServer) LoginPost(c *models.ReqContext) response.Response {
 		ReqContext: c,
 		Username:   cmd.User,
 		Password:   cmd.Password,
-		IpAddress:  c.Req.RemoteAddr,
+		IpAddress:  loginIPAddress(c),
 		Cfg:        hs.Cfg,
 	}
 
@@ -213,8 +215,8 @@ func (hs *HTTPServer) LoginPost(c *models.ReqContext) response.Response {
 	authModule = authQuery.AuthModule
 	if err != nil {
 		resp = response.Error(401, "Invalid username or password", err)
-		if errors.Is(err, login.ErrInvalidCredentials) || errors.Is(err, login.ErrTooManyLoginAttempts) || errors.Is(err,
-			models.ErrUserNotFound) {
+		if errors.Is(err, login.ErrInvalidCredentials) || errors.Is(err, login.ErrTooManyLoginAttempts) ||
+			errors.Is(err, login.ErrAccountLocked) || errors.Is(err, models.ErrUserNotFound) {
 			return resp
 		}
 
@@ -231,20 +233,201 @@ func (hs *HTTPServer) LoginPost(c *models.ReqContext) response.Response {
 
 	user = authQuery.User
 
-	err = hs.loginUserWithUser(user, c)
+	if authModule == "grafana" {
+		// users with an expired password change it before completing their login
+		expired, err := hs.passwordPolicyService.IsExpired(c.Req.Context(), user)
+		if err != nil {
+			resp = response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+			return resp
+		}
+		if expired {
+			token, err := hs.passwordPolicyService.CreateChangeChallenge(c.Req.Context(), user)
+			if err != nil {
+				resp = response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+				return resp
+			}
+			resp = response.JSON(http.StatusUnauthorized, map[string]interface{}{
+				"message":        "Password expired",
+				"passwordChange": token,
+			})
+			return resp
+		}
+
+		resp = hs.completeGrafanaLogin(c, user)
+		return resp
+	}
+
+	result := map[string]interface{}{
+		"message": "Logged in",
+	}
+	resp = hs.completeLogin(c, user, result)
+	return resp
+}
+
+// completeGrafanaLogin completes the login of a user authenticated with a Grafana password with its second factor.
+func (hs *HTTPServer) completeGrafanaLogin(c *models.ReqContext, user *models.User) *response.NormalResponse {
+	challenge, err := hs.totpService.CreateChallenge(c.Req.Context(), user)
 	if err != nil {
-		var createTokenErr *models.CreateTokenErr
-		if errors.As(err, &createTokenErr) {
-			resp = response.Error(createTokenErr.StatusCode, createTokenErr.ExternalErr, createTokenErr.InternalErr)
-		} else {
-			resp = response.Error(http.StatusInternalServerError, "Error while signing in user", err)
+		return response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+	}
+	if challenge != nil {
+		result := map[string]interface{}{
+			"message":   "Two-factor authentication required",
+			"challenge": challenge.Token,
+		}
+		if challenge.Enrollment != nil {
+			result["enrollment"] = challenge.Enrollment
+		}
+		return response.JSON(http.StatusUnauthorized, result)
+	}
+
+	result := map[string]interface{}{
+		"message": "Logged in",
+	}
+	return hs.completeLogin(c, user, result)
+}
+
+// LoginChangePasswordPost changes the expired password of a user logging in, and continues its login.
+func (hs *HTTPServer) LoginChangePasswordPost(c *models.ReqContext) response.Response {
+	cmd := passwordpolicy.ChangeExpiredPasswordCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad login data", err)
+	}
+
+	var user *models.User
+	var resp *response.NormalResponse
+
+	defer func() {
+		err := resp.Err()
+		if err == nil && resp.ErrMessage() != "" {
+			err = errors.New(resp.ErrMessage())
+		}
+		loginUsername := ""
+		if user != nil {
+			loginUsername = user.Login
+		}
+		hs.HooksService.RunLoginHook(&models.LoginInfo{
+			AuthModule:    "grafana",
+			User:          user,
+			LoginUsername: loginUsername,
+			HTTPStatus:    resp.Status(),
+			Error:         err,
+		}, c)
+	}()
+
+	if setting.DisableLoginForm {
+		resp = response.Error(http.StatusUnauthorized, "Login is disabled", nil)
+		return resp
+	}
+
+	userID, err := hs.passwordPolicyService.ChangeExpiredPassword(c.Req.Context(), &cmd)
+	if err != nil {
+		switch {
+		case errors.Is(err, passwordpolicy.ErrChallengeNotFound):
+			resp = response.Error(http.StatusUnauthorized, err.Error(), err)
+		case errors.Is(err, passwordpolicy.ErrPasswordsMismatch), errors.Is(err, passwordpolicy.ErrPolicyViolation):
+			resp = response.Error(http.StatusBadRequest, err.Error(), err)
+		default:
+			resp = response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+		}
+		return resp
+	}
+
+	query := models.GetUserByIdQuery{Id: userID}
+	if err := hs.SQLStore.GetUserById(c.Req.Context(), &query); err != nil {
+		resp = response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+		return resp
+	}
+	user = query.Result
+
+	if user.IsDisabled {
+		resp = response.Error(http.StatusUnauthorized, "Invalid username or password", login.ErrUserDisabled)
+		return resp
+	}
+
+	resp = hs.completeGrafanaLogin(c, user)
+	return resp
+}
+
+// LoginTwoFactorPost completes a login with the code of the second factor of the user.
+func (hs *HTTPServer) LoginTwoFactorPost(c *models.ReqContext) response.Response {
+	cmd := totp.VerifyChallengeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad login data", err)
+	}
+	cmd.IPAddress = loginIPAddress(c)
+
+	var user *models.User
+	var resp *response.NormalResponse
+
+	defer func() {
+		err := resp.Err()
+		if err == nil && resp.ErrMessage() != "" {
+			err = errors.New(resp.ErrMessage())
 		}
+		loginUsername := ""
+		if user != nil {
+			loginUsername = user.Login
+		}
+		hs.HooksService.RunLoginHook(&models.LoginInfo{
+			AuthModule:    "grafana",
+			User:          user,
+			LoginUsername: loginUsername,
+			HTTPStatus:    resp.Status(),
+			Error:         err,
+		}, c)
+	}()
+
+	if setting.DisableLoginForm {
+		resp = response.Error(http.StatusUnauthorized, "Login is disabled", nil)
+		return resp
+	}
+
+	verified, err := hs.totpService.VerifyChallenge(c.Req.Context(), &cmd)
+	if err != nil {
+		switch {
+		case errors.Is(err, totp.ErrInvalidCode):
+			resp = response.Error(http.StatusUnauthorized, "Invalid two-factor authentication code", err)
+		case errors.Is(err, totp.ErrChallengeNotFound), errors.Is(err, totp.ErrTooManyAttempts):
+			resp = response.Error(http.StatusUnauthorized, err.Error(), err)
+		default:
+			resp = response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+		}
+		return resp
+	}
+
+	query := models.GetUserByIdQuery{Id: verified.UserID}
+	if err := hs.SQLStore.GetUserById(c.Req.Context(), &query); err != nil {
+		resp = response.Error(http.StatusInternalServerError, "Error while trying to authenticate user", err)
+		return resp
+	}
+	user = query.Result
+
+	if user.IsDisabled {
+		resp = response.Error(http.StatusUnauthorized, "Invalid username or password", login.ErrUserDisabled)
 		return resp
 	}
 
 	result := map[string]interface{}{
 		"message": "Logged in",
 	}
+	if len(verified.RecoveryCodes) > 0 {
+		result["recoveryCodes"] = verified.RecoveryCodes
+	}
+	resp = hs.completeLogin(c, user, result)
+	return resp
+}
+
+// completeLogin creates the session of the authenticated user, and responds with the result of the login.
+func (hs *HTTPServer) completeLogin(c *models.ReqContext, user *models.User, result map[string]interface{}) *response.NormalResponse {
+	err := hs.loginUserWithUser(user, c)
+	if err != nil {
+		var createTokenErr *models.CreateTokenErr
+		if errors.As(err, &createTokenErr) {
+			return response.Error(createTokenErr.StatusCode, createTokenErr.ExternalErr, createTokenErr.InternalErr)
+		}
+		return response.Error(http.StatusInternalServerError, "Error while signing in user", err)
+	}
 
 	if redirectTo := c.GetCookie("redirect_to"); len(redirectTo) > 0 {
 		if err := hs.ValidateRedirectTo(redirectTo); err == nil {
@@ -256,8 +439,16 @@ func (hs *HTTPServer) LoginPost(c *models.ReqContext) response.Response {
 	}
 
 	metrics.MApiLoginPost.Inc()
-	resp = response.JSON(http.StatusOK, result)
-	return resp
+	return response.JSON(http.StatusOK, result)
+}
+
+// loginIPAddress returns the IP address of the client, login attempts are counted per address.
+func loginIPAddress(c *models.ReqContext) string {
+	addr := c.RemoteAddr()
+	if ip, err := network.GetIPFromAddress(addr); err == nil {
+		return ip.String()
+	}
+	return addr
 }
 
 func (hs *HTTPServer) loginUserWithUser(user *models.User, c *models.ReqContext) error {
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -27,10 +28,14 @@ import (
 	"github.com/grafana/grafana/pkg/services/auth"
 	"github.com/grafana/grafana/pkg/services/hooks"
 	"github.com/grafana/grafana/pkg/services/licensing"
+	"github.com/grafana/grafana/pkg/services/passwordpolicy/passwordpolicytest"
 	"github.com/grafana/grafana/pkg/services/secrets"
 	"github.com/grafana/grafana/pkg/services/secrets/fakes"
 	secretsManager "github.com/grafana/grafana/pkg/services/secrets/manager"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/services/totp/totptest"
 	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
 )
 
 func fakeSetIndexViewData(t *testing.T) {
@@ -317,11 +322,13 @@ func TestLoginPostRedirect(t *testing.T) {
 	fakeViewIndex(t)
 	sc := setupScenarioContext(t, "/login")
 	hs := &HTTPServer{
-		log:              log.NewNopLogger(),
-		Cfg:              setting.NewCfg(),
-		HooksService:     &hooks.HooksService{},
-		License:          &licensing.OSSLicensingService{},
-		AuthTokenService: auth.NewFakeUserAuthTokenService(),
+		log:                   log.NewNopLogger(),
+		Cfg:                   setting.NewCfg(),
+		HooksService:          &hooks.HooksService{},
+		License:               &licensing.OSSLicensingService{},
+		AuthTokenService:      auth.NewFakeUserAuthTokenService(),
+		totpService:           &totptest.FakeService{},
+		passwordPolicyService: passwordpolicytest.NewPasswordPolicyServiceFake(),
 	}
 	hs.Cfg.CookieSecure = true
 
@@ -597,11 +604,13 @@ func TestLoginPostRunLokingHook(t *testing.T) {
 	sc := setupScenarioContext(t, "/login")
 	hookService := &hooks.HooksService{}
 	hs := &HTTPServer{
-		log:              log.New("test"),
-		Cfg:              setting.NewCfg(),
-		License:          &licensing.OSSLicensingService{},
-		AuthTokenService: auth.NewFakeUserAuthTokenService(),
-		HooksService:     hookService,
+		log:                   log.New("test"),
+		Cfg:                   setting.NewCfg(),
+		License:               &licensing.OSSLicensingService{},
+		AuthTokenService:      auth.NewFakeUserAuthTokenService(),
+		HooksService:          hookService,
+		totpService:           &totptest.FakeService{},
+		passwordPolicyService: passwordpolicytest.NewPasswordPolicyServiceFake(),
 	}
 
 	sc.defaultHandler = routing.Wrap(func(c *models.ReqContext) response.Response {
@@ -686,6 +695,80 @@ func TestLoginPostRunLokingHook(t *testing.T) {
 	}
 }
 
+func TestLoginPostTwoFactorChallenge(t *testing.T) {
+	sc := setupScenarioContext(t, "/login")
+	tokenService := auth.NewFakeUserAuthTokenService()
+	tokenCreated := false
+	tokenService.CreateTokenProvider = func(ctx context.Context, user *models.User, clientIP net.IP, userAgent string) (*models.UserToken, error) {
+		tokenCreated = true
+		return &models.UserToken{UserId: user.Id}, nil
+	}
+	totpService := &totptest.FakeService{}
+	hs := &HTTPServer{
+		log:                   log.NewNopLogger(),
+		Cfg:                   setting.NewCfg(),
+		License:               &licensing.OSSLicensingService{},
+		AuthTokenService:      tokenService,
+		HooksService:          &hooks.HooksService{},
+		totpService:           totpService,
+		passwordPolicyService: passwordpolicytest.NewPasswordPolicyServiceFake(),
+	}
+
+	sc.defaultHandler = routing.Wrap(func(c *models.ReqContext) response.Response {
+		c.Req.Header.Set("Content-Type", "application/json")
+		c.Req.Body = io.NopCloser(bytes.NewBufferString(`{"user":"admin","password":"admin"}`))
+		return hs.LoginPost(c)
+	})
+	sc.m.Post(sc.url, sc.defaultHandler)
+
+	testUser := &models.User{Id: 42}
+
+	t.Run("Grafana user with a second factor gets a challenge", func(t *testing.T) {
+		tokenCreated = false
+		totpService.ExpectedChallenge = &totp.Challenge{Token: "challenge-token"}
+		hs.authenticator = &fakeAuthenticator{testUser, "grafana", nil}
+		sc.fakeReqNoAssertions("POST", sc.url).exec()
+
+		assert.Equal(t, http.StatusUnauthorized, sc.resp.Code)
+		assert.False(t, tokenCreated)
+		respJSON, err := simplejson.NewJson(sc.resp.Body.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, "challenge-token", respJSON.Get("challenge").MustString())
+	})
+
+	t.Run("Grafana user without a second factor is logged in", func(t *testing.T) {
+		tokenCreated = false
+		totpService.ExpectedChallenge = nil
+		hs.authenticator = &fakeAuthenticator{testUser, "grafana", nil}
+		sc.fakeReqNoAssertions("POST", sc.url).exec()
+
+		assert.Equal(t, http.StatusOK, sc.resp.Code)
+		assert.True(t, tokenCreated)
+	})
+
+	t.Run("LDAP user does not get a challenge", func(t *testing.T) {
+		tokenCreated = false
+		totpService.ExpectedChallenge = &totp.Challenge{Token: "challenge-token"}
+		hs.authenticator = &fakeAuthenticator{testUser, "ldap", nil}
+		sc.fakeReqNoAssertions("POST", sc.url).exec()
+
+		assert.Equal(t, http.StatusOK, sc.resp.Code)
+		assert.True(t, tokenCreated)
+	})
+}
+
+func TestLoginIPAddress(t *testing.T) {
+	for remoteAddr, expected := range map[string]string{
+		"192.168.1.1:56433": "192.168.1.1",
+		"[::1]:56433":       "::1",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = remoteAddr
+		c := &models.ReqContext{Context: &web.Context{Req: req}}
+		assert.Equal(t, expected, loginIPAddress(c))
+	}
+}
+
 type mockSocialService struct {
 	oAuthInfo       *social.OAuthInfo
 	oAuthInfos      map[string]*social.OAuthInfo
@@ -11,6 +11,7 @@ import (
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins/backendplugin"
+	"github.com/grafana/grafana/pkg/services/datasources/ratelimit"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/query"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
@@ -26,6 +27,7 @@ func (hs *HTTPServer) QueryMetricsV2(c *models.ReqContext) response.Response {
 	if err := web.Bind(c.Req, &reqDTO); err != nil {
 		return response.Error(http.StatusBadRequest, "bad request data", err)
 	}
+	reqDTO.HTTPRequest = c.Req
 
 	resp, err := hs.queryDataService.QueryData(c.Req.Context(), c.SignedInUser, c.SkipCache, reqDTO, true)
 	if err != nil {
@@ -41,6 +43,11 @@ func (hs *HTTPServer) handleQueryMetricsError(err error) *response.NormalRespons
 	if errors.Is(err, models.ErrDataSourceNotFound) {
 		return response.Error(http.StatusNotFound, "Data source not found", err)
 	}
+	var limitErr *ratelimit.LimitExceededError
+	if errors.As(err, &limitErr) {
+		return response.Error(http.StatusTooManyRequests, "Data source limit reached", err).
+			SetHeader("Retry-After", strconv.Itoa(limitErr.RetryAfterSeconds()))
+	}
 	var badQuery *query.ErrBadQuery
 	if errors.As(err, &badQuery) {
 		return response.Error(http.StatusBadRequest, util.Capitalize(badQuery.Message), err)
@@ -123,6 +130,7 @@ func (hs *HTTPServer) QueryMetricsFromDashboard(c *models.ReqContext) response.R
 	if err := web.Bind(c.Req, &reqDTO); err != nil {
 		return response.Error(http.StatusBadRequest, "bad request data", err)
 	}
+	reqDTO.HTTPRequest = c.Req
 	params := web.Params(c.Req)
 	getDashboardQuery, panelId, err := parseDashboardQueryParams(params)
 
@@ -160,6 +168,7 @@ func (hs *HTTPServer) QueryMetrics(c *models.ReqContext) response.Response {
 	if err := web.Bind(c.Req, &reqDto); err != nil {
 		return response.Error(http.StatusBadRequest, "bad request data", err)
 	}
+	reqDto.HTTPRequest = c.Req
 
 	sdkResp, err := hs.queryDataService.QueryData(c.Req.Context(), c.SignedInUser, c.SkipCache, reqDto, false)
 	if err != nil {
@@ -20,9 +20,13 @@ import (
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/services/correlations/correlationstest"
 	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement/labelenforcementtest"
+	"github.com/grafana/grafana/pkg/services/datasources/ratelimit"
 	datasources "github.com/grafana/grafana/pkg/services/datasources/service"
 	"github.com/grafana/grafana/pkg/services/query"
+	"github.com/grafana/grafana/pkg/services/queryaudit/queryaudittest"
 	"github.com/grafana/grafana/pkg/services/secrets/fakes"
 	"github.com/grafana/grafana/pkg/services/secrets/kvstore"
 	secretsManager "github.com/grafana/grafana/pkg/services/secrets/manager"
@@ -213,6 +217,10 @@ func TestAPIEndpoint_Metrics_QueryMetricsFromDashboard(t *testing.T) {
 		ds,
 		&dashboardFakePluginClient{},
 		&fakeOAuthTokenService{},
+		ratelimit.ProvideService(),
+		queryaudittest.NewQueryAuditServiceFake(),
+		correlationstest.NewCorrelationsServiceFake(),
+		labelenforcementtest.NewLabelEnforcementServiceFake(),
 	)
 
 	sc.hs.Features = featuremgmt.WithFeatures(featuremgmt.FlagValidatedQueries, true)
@@ -523,6 +531,10 @@ func TestAPIEndpoint_Metrics_QueryMetricsV2(t *testing.T) {
 			},
 		},
 		&fakeOAuthTokenService{},
+		ratelimit.ProvideService(),
+		queryaudittest.NewQueryAuditServiceFake(),
+		correlationstest.NewCorrelationsServiceFake(),
+		labelenforcementtest.NewLabelEnforcementServiceFake(),
 	)
 	serverFeatureEnabled := SetupAPITestServer(t, func(hs *HTTPServer) {
 		hs.queryDataService = qds
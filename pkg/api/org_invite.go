@@ -197,6 +197,10 @@ func (hs *HTTPServer) CompleteInvite(c *models.ReqContext) response.Response {
 		SkipOrgSetup: true,
 	}
 
+	if ok, rsp := hs.validatePasswordPolicy(c.Req.Context(), completeInvite.Password, &models.User{Login: completeInvite.Username, Email: completeInvite.Email}); !ok {
+		return rsp
+	}
+
 	user, err := hs.Login.CreateUser(cmd)
 	if err != nil {
 		if errors.Is(err, models.ErrUserAlreadyExists) {
@@ -205,6 +209,7 @@ func (hs *HTTPServer) CompleteInvite(c *models.ReqContext) response.Response {
 
 		return response.Error(500, "failed to create user", err)
 	}
+	hs.recordPassword(c.Req.Context(), user.Id, user.Password, user.Salt)
 
 	if err := bus.Publish(c.Req.Context(), &events.SignUpCompleted{
 		Name:  user.NameOrFallback(),
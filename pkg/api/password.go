@@ -8,6 +8,7 @@ import (
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/passwordpolicy"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
 	"github.com/grafana/grafana/pkg/web"
@@ -64,6 +65,10 @@ func (hs *HTTPServer) ResetPassword(c *models.ReqContext) response.Response {
 		return response.Error(400, "Passwords do not match", nil)
 	}
 
+	if ok, rsp := hs.validatePasswordPolicy(c.Req.Context(), form.NewPassword, query.Result); !ok {
+		return rsp
+	}
+
 	cmd := models.ChangeUserPasswordCommand{}
 	cmd.UserId = query.Result.Id
 	var err error
@@ -75,6 +80,27 @@ func (hs *HTTPServer) ResetPassword(c *models.ReqContext) response.Response {
 	if err := hs.SQLStore.ChangeUserPassword(c.Req.Context(), &cmd); err != nil {
 		return response.Error(500, "Failed to change user password", err)
 	}
+	hs.recordPassword(c.Req.Context(), cmd.UserId, cmd.NewPassword, query.Result.Salt)
 
 	return response.Success("User password changed")
 }
+
+// validatePasswordPolicy checks the password the user wants to set against the password policy, and returns the
+// response to send when it does not comply.
+func (hs *HTTPServer) validatePasswordPolicy(ctx context.Context, password string, user *models.User) (bool, response.Response) {
+	if err := hs.passwordPolicyService.Validate(ctx, password, user); err != nil {
+		if errors.Is(err, passwordpolicy.ErrPolicyViolation) {
+			return false, response.Error(http.StatusBadRequest, err.Error(), nil)
+		}
+		return false, response.Error(http.StatusInternalServerError, "Failed to validate password", err)
+	}
+	return true, nil
+}
+
+// recordPassword adds the password the user has just set to its password history. The password is already
+// changed, so a failure is only logged.
+func (hs *HTTPServer) recordPassword(ctx context.Context, userID int64, hashedPassword string, salt string) {
+	if err := hs.passwordPolicyService.RecordPassword(ctx, userID, hashedPassword, salt); err != nil {
+		hs.log.Error("Failed to record password history", "userId", userID, "error", err)
+	}
+}
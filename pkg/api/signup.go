@@ -92,6 +92,10 @@ func (hs *HTTPServer) SignUpStep2(c *models.ReqContext) response.Response {
 		createUserCmd.EmailVerified = true
 	}
 
+	if ok, rsp := hs.validatePasswordPolicy(c.Req.Context(), form.Password, &models.User{Login: form.Username, Email: form.Email}); !ok {
+		return rsp
+	}
+
 	user, err := hs.Login.CreateUser(createUserCmd)
 	if err != nil {
 		if errors.Is(err, models.ErrUserAlreadyExists) {
@@ -100,6 +104,7 @@ func (hs *HTTPServer) SignUpStep2(c *models.ReqContext) response.Response {
 
 		return response.Error(500, "Failed to create user", err)
 	}
+	hs.recordPassword(c.Req.Context(), user.Id, user.Password, user.Salt)
 
 	// publish signup event
 	if err := bus.Publish(c.Req.Context(), &events.SignUpCompleted{
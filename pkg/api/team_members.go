@@ -45,7 +45,7 @@ func (hs *HTTPServer) GetTeamMembers(c *models.ReqContext) response.Response {
 		member.AvatarUrl = dtos.GetGravatarUrl(member.Email)
 		member.Labels = []string{}
 
-		if hs.License.FeatureEnabled("teamgroupsync") && member.External {
+		if member.External {
 			authProvider := GetAuthProviderLabel(member.AuthModule)
 			member.Labels = append(member.Labels, authProvider)
 		}
@@ -125,6 +125,9 @@ func (hs *HTTPServer) UpdateTeamMember(c *models.ReqContext) response.Response {
 
 	err = addOrUpdateTeamMember(c.Req.Context(), hs.teamPermissionsService, userId, orgId, teamId, getPermissionName(cmd.Permission))
 	if err != nil {
+		if errors.Is(err, models.ErrTeamMemberExternal) {
+			return response.Error(400, "Team member is synced from an external group and cannot be updated", nil)
+		}
 		return response.Error(500, "Failed to update team member.", err)
 	}
 	return response.Success("Team member updated")
@@ -168,6 +171,10 @@ func (hs *HTTPServer) RemoveTeamMember(c *models.ReqContext) response.Response {
 			return response.Error(404, "Team member not found", nil)
 		}
 
+		if errors.Is(err, models.ErrTeamMemberExternal) {
+			return response.Error(400, "Team member is synced from an external group and cannot be removed", nil)
+		}
+
 		return response.Error(500, "Failed to remove Member from Team", err)
 	}
 	return response.Success("Team Member removed")
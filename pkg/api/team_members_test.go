@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -397,6 +398,15 @@ func TestDeleteTeamMembersAPIEndpoint_LegacyAccessControl(t *testing.T) {
 		response := callAPI(sc.server, http.MethodDelete, fmt.Sprintf(teamMemberDeleteRoute, "1", "3"), nil, t)
 		assert.Equal(t, http.StatusOK, response.Code)
 	})
+
+	externalUserID := createUser(sc.db, 1, t)
+	err = sc.db.AddTeamMember(externalUserID, 1, 1, true, 0)
+	require.NoError(t, err)
+	setInitCtxSignedInOrgAdmin(sc.initCtx)
+	t.Run("Team members synced from an external group cannot be removed", func(t *testing.T) {
+		response := callAPI(sc.server, http.MethodDelete, fmt.Sprintf(teamMemberDeleteRoute, "1", strconv.FormatInt(externalUserID, 10)), nil, t)
+		assert.Equal(t, http.StatusBadRequest, response.Code)
+	})
 }
 
 func TestDeleteTeamMembersAPIEndpoint_RBAC(t *testing.T) {
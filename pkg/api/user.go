@@ -284,6 +284,9 @@ func (hs *HTTPServer) ChangeUserPassword(c *models.ReqContext) response.Response
 	if password.IsWeak() {
 		return response.Error(400, "New password is too short", nil)
 	}
+	if ok, rsp := hs.validatePasswordPolicy(c.Req.Context(), cmd.NewPassword, userQuery.Result); !ok {
+		return rsp
+	}
 
 	cmd.UserId = c.UserId
 	cmd.NewPassword, err = util.EncodePassword(cmd.NewPassword, userQuery.Result.Salt)
@@ -294,6 +297,7 @@ func (hs *HTTPServer) ChangeUserPassword(c *models.ReqContext) response.Response
 	if err := hs.SQLStore.ChangeUserPassword(c.Req.Context(), &cmd); err != nil {
 		return response.Error(500, "Failed to change user password", err)
 	}
+	hs.recordPassword(c.Req.Context(), cmd.UserId, cmd.NewPassword, userQuery.Result.Salt)
 
 	return response.Success("User password changed")
 }
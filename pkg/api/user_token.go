@@ -3,7 +3,10 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/api/dtos"
@@ -63,6 +66,13 @@ func (hs *HTTPServer) getUserAuthTokensInternal(c *models.ReqContext, userID int
 		return response.Error(500, "Failed to get user auth tokens", err)
 	}
 
+	return response.JSON(http.StatusOK, userTokenDTOs(c, tokens))
+}
+
+// userTokenDTOs returns the tokens with their client parsed from their user agent
+func userTokenDTOs(c *models.ReqContext, tokens []*models.UserToken) []*dtos.UserToken {
+	parser := uaparser.NewFromSaved()
+
 	result := []*dtos.UserToken{}
 	for _, token := range tokens {
 		isActive := false
@@ -70,7 +80,6 @@ func (hs *HTTPServer) getUserAuthTokensInternal(c *models.ReqContext, userID int
 			isActive = true
 		}
 
-		parser := uaparser.NewFromSaved()
 		client := parser.Parse(token.UserAgent)
 
 		osVersion := ""
@@ -100,6 +109,7 @@ func (hs *HTTPServer) getUserAuthTokensInternal(c *models.ReqContext, userID int
 
 		result = append(result, &dtos.UserToken{
 			Id:                     token.Id,
+			UserId:                 token.UserId,
 			IsActive:               isActive,
 			ClientIp:               token.ClientIp,
 			Device:                 client.Device.ToString(),
@@ -112,7 +122,116 @@ func (hs *HTTPServer) getUserAuthTokensInternal(c *models.ReqContext, userID int
 		})
 	}
 
-	return response.JSON(http.StatusOK, result)
+	return result
+}
+
+// revokeUserAuthTokensBatchSize is the number of tokens revoked at once by an admin
+const revokeUserAuthTokensBatchSize = 1000
+
+// GET /api/admin/auth-tokens
+func (hs *HTTPServer) AdminSearchUserAuthTokens(c *models.ReqContext) response.Response {
+	query, err := searchUserTokensQuery(c.QueryInt64("userId"), c.Query("clientIp"))
+	if err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+	if activeSince := c.Query("activeSince"); activeSince != "" {
+		if query.ActiveSince, err = time.Parse(time.RFC3339, activeSince); err != nil {
+			return response.Error(http.StatusBadRequest, "activeSince should be a RFC 3339 time", err)
+		}
+	}
+	query.AfterId = c.QueryInt64("afterId")
+	query.Limit = c.QueryInt("limit")
+
+	tokens, err := hs.AuthTokenService.SearchUserTokens(c.Req.Context(), query)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to search user auth tokens", err)
+	}
+
+	return response.JSON(http.StatusOK, userTokenDTOs(c, tokens))
+}
+
+// POST /api/admin/auth-tokens/revoke
+func (hs *HTTPServer) AdminRevokeUserAuthTokens(c *models.ReqContext) response.Response {
+	form := dtos.RevokeUserAuthTokensForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if form.UserId == 0 && form.ClientIp == "" {
+		return response.Error(http.StatusBadRequest, "A user id or a client ip is required", nil)
+	}
+
+	query, err := searchUserTokensQuery(form.UserId, form.ClientIp)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+
+	// the tokens are revoked page by page
+	query.Limit = revokeUserAuthTokensBatchSize
+	count := 0
+	for {
+		tokens, err := hs.AuthTokenService.SearchUserTokens(c.Req.Context(), query)
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to search user auth tokens", err)
+		}
+		if len(tokens) == 0 {
+			break
+		}
+
+		tokenIds := make([]int64, 0, len(tokens))
+		for _, token := range tokens {
+			// the session making the request is kept, like when revoking a single token
+			if c.UserToken != nil && c.UserToken.Id == token.Id {
+				continue
+			}
+			tokenIds = append(tokenIds, token.Id)
+		}
+
+		if err := hs.AuthTokenService.BatchRevokeTokens(c.Req.Context(), tokenIds); err != nil {
+			return response.Error(http.StatusInternalServerError, "Failed to revoke user auth tokens", err)
+		}
+		count += len(tokenIds)
+		if len(tokens) < query.Limit {
+			break
+		}
+		query.AfterId = tokens[len(tokens)-1].Id
+	}
+
+	c.Logger.Info("User auth tokens revoked", "userId", form.UserId, "clientIp", form.ClientIp, "count", count)
+
+	return response.JSON(http.StatusOK, util.DynMap{
+		"message": "User auth tokens revoked",
+		"count":   count,
+	})
+}
+
+// searchUserTokensQuery returns the query of the tokens of the user and of the client ip, which is either an ip
+// address or a network in CIDR notation
+func searchUserTokensQuery(userID int64, clientIP string) (*models.SearchUserTokensQuery, error) {
+	query := &models.SearchUserTokensQuery{UserId: userID}
+	if clientIP == "" {
+		return query, nil
+	}
+
+	if strings.Contains(clientIP, "/") {
+		_, ipNet, err := net.ParseCIDR(clientIP)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client ip network %q", clientIP)
+		}
+		query.ClientIPNet = ipNet
+		return query, nil
+	}
+
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid client ip %q", clientIP)
+	}
+	bits := net.IPv6len * 8
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = net.IPv4len * 8
+	}
+	query.ClientIPNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	return query, nil
 }
 
 func (hs *HTTPServer) revokeUserAuthTokenInternal(c *models.ReqContext, userID int64, cmd models.RevokeAuthTokenCmd) response.Response {
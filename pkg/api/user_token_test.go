@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/api/routing"
 	"github.com/grafana/grafana/pkg/models"
@@ -13,6 +14,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/services/sqlstore/mockstore"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUserTokenAPIEndpoint(t *testing.T) {
@@ -144,6 +146,96 @@ func TestUserTokenAPIEndpoint(t *testing.T) {
 	})
 }
 
+func TestAdminRevokeUserAuthTokens(t *testing.T) {
+	currentToken := &models.UserToken{Id: 1}
+
+	adminRevokeUserAuthTokensScenario(t, "Should revoke the tokens of the network but the current one",
+		dtos.RevokeUserAuthTokensForm{ClientIp: "10.0.0.0/8"}, currentToken, func(sc *scenarioContext) {
+			var query *models.SearchUserTokensQuery
+			sc.userAuthTokenService.SearchUserTokensProvider = func(ctx context.Context, q *models.SearchUserTokensQuery) ([]*models.UserToken, error) {
+				query = q
+				return []*models.UserToken{{Id: 1}, {Id: 2}, {Id: 3}}, nil
+			}
+			var revoked []int64
+			sc.userAuthTokenService.BatchRevokeTokensProvider = func(ctx context.Context, tokenIds []int64) error {
+				revoked = tokenIds
+				return nil
+			}
+			sc.fakeReqWithParams("POST", sc.url, map[string]string{}).exec()
+
+			assert.Equal(t, 200, sc.resp.Code)
+			assert.Equal(t, "10.0.0.0/8", query.ClientIPNet.String())
+			assert.Equal(t, []int64{2, 3}, revoked)
+			assert.Equal(t, 2, sc.ToJSON().Get("count").MustInt())
+		})
+
+	adminRevokeUserAuthTokensScenario(t, "Should revoke the tokens page by page",
+		dtos.RevokeUserAuthTokensForm{UserId: 2}, currentToken, func(sc *scenarioContext) {
+			var afterIds []int64
+			sc.userAuthTokenService.SearchUserTokensProvider = func(ctx context.Context, q *models.SearchUserTokensQuery) ([]*models.UserToken, error) {
+				afterIds = append(afterIds, q.AfterId)
+				tokens := []*models.UserToken{}
+				for id := q.AfterId + 1; id <= 1500 && len(tokens) < q.Limit; id++ {
+					tokens = append(tokens, &models.UserToken{Id: id})
+				}
+				return tokens, nil
+			}
+			revoked := 0
+			sc.userAuthTokenService.BatchRevokeTokensProvider = func(ctx context.Context, tokenIds []int64) error {
+				revoked += len(tokenIds)
+				return nil
+			}
+			sc.fakeReqWithParams("POST", sc.url, map[string]string{}).exec()
+
+			assert.Equal(t, 200, sc.resp.Code)
+			assert.Equal(t, []int64{0, 1000}, afterIds)
+			assert.Equal(t, 1499, revoked)
+			assert.Equal(t, 1499, sc.ToJSON().Get("count").MustInt())
+		})
+
+	adminRevokeUserAuthTokensScenario(t, "Should require a filter",
+		dtos.RevokeUserAuthTokensForm{}, currentToken, func(sc *scenarioContext) {
+			sc.fakeReqWithParams("POST", sc.url, map[string]string{}).exec()
+			assert.Equal(t, 400, sc.resp.Code)
+		})
+
+	adminRevokeUserAuthTokensScenario(t, "Should reject an invalid client ip",
+		dtos.RevokeUserAuthTokensForm{ClientIp: "10.0.0"}, currentToken, func(sc *scenarioContext) {
+			sc.fakeReqWithParams("POST", sc.url, map[string]string{}).exec()
+			assert.Equal(t, 400, sc.resp.Code)
+		})
+}
+
+func TestSearchUserTokensQuery(t *testing.T) {
+	for _, tc := range []struct {
+		clientIP string
+		network  string
+		err      bool
+	}{
+		{clientIP: "", network: ""},
+		{clientIP: "192.168.1.10", network: "192.168.1.10/32"},
+		{clientIP: "192.168.1.10/24", network: "192.168.1.0/24"},
+		{clientIP: "2001:db8::1", network: "2001:db8::1/128"},
+		{clientIP: "192.168.1", err: true},
+		{clientIP: "192.168.1.10/33", err: true},
+	} {
+		t.Run(tc.clientIP, func(t *testing.T) {
+			query, err := searchUserTokensQuery(12, tc.clientIP)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(12), query.UserId)
+			if tc.network == "" {
+				assert.Nil(t, query.ClientIPNet)
+				return
+			}
+			assert.Equal(t, tc.network, query.ClientIPNet.String())
+		})
+	}
+}
+
 func revokeUserAuthTokenScenario(t *testing.T, desc string, url string, routePattern string, cmd models.RevokeAuthTokenCmd,
 	userId int64, fn scenarioFunc, sqlStore sqlstore.Store) {
 	t.Run(fmt.Sprintf("%s %s", desc, url), func(t *testing.T) {
@@ -273,3 +365,29 @@ func getUserAuthTokensInternalScenario(t *testing.T, desc string, token *models.
 		fn(sc)
 	})
 }
+
+func adminRevokeUserAuthTokensScenario(t *testing.T, desc string, form dtos.RevokeUserAuthTokensForm,
+	token *models.UserToken, fn scenarioFunc) {
+	t.Run(desc, func(t *testing.T) {
+		fakeAuthTokenService := auth.NewFakeUserAuthTokenService()
+
+		hs := HTTPServer{
+			AuthTokenService: fakeAuthTokenService,
+		}
+
+		sc := setupScenarioContext(t, "/")
+		sc.userAuthTokenService = fakeAuthTokenService
+		sc.defaultHandler = routing.Wrap(func(c *models.ReqContext) response.Response {
+			c.Req.Body = mockRequestBody(form)
+			sc.context = c
+			sc.context.UserId = testUserID
+			sc.context.OrgId = testOrgID
+			sc.context.IsGrafanaAdmin = true
+			sc.context.UserToken = token
+
+			return hs.AdminRevokeUserAuthTokens(c)
+		})
+		sc.m.Post("/", sc.defaultHandler)
+		fn(sc)
+	})
+}
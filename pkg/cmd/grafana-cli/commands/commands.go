@@ -265,6 +265,36 @@ so must be recompiled to validate newly-added CUE files.`,
 	},
 }
 
+var dashboardCommands = []*cli.Command{
+	{
+		Name:  "lint",
+		Usage: "check a dashboard JSON file with the dashboard lint rules",
+		Description: `lint checks a dashboard with the built-in lint rules and their default
+configuration, or with the rules configured by an organization when
+--grafana-url is set. Exits 1 if the dashboard has blocking problems.`,
+		Action: runCueCommand(cmd.lintDashboard),
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "dashboard",
+				Usage: "path to file containing dashboard JSON",
+			},
+			&cli.StringFlag{
+				Name:  "grafana-url",
+				Usage: "URL of a Grafana server checking the dashboard with the rules of its organization",
+			},
+			&cli.StringFlag{
+				Name:  "token",
+				Usage: "API key or service account token for the Grafana server",
+			},
+			&cli.BoolFlag{
+				Name:  "strict",
+				Usage: "exit 1 if the dashboard has any problems, including warnings",
+				Value: false,
+			},
+		},
+	},
+}
+
 var Commands = []*cli.Command{
 	{
 		Name:        "plugins",
@@ -281,4 +311,9 @@ var Commands = []*cli.Command{
 		Usage:       "Cue validation commands",
 		Subcommands: cueCommands,
 	},
+	{
+		Name:        "dashboards",
+		Usage:       "Dashboard commands",
+		Subcommands: dashboardCommands,
+	},
 }
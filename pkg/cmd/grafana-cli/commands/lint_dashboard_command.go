@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/services/dashboardlint"
+)
+
+// lintDashboard checks a dashboard file with the lint rules. Without --grafana-url, the built-in rules are checked
+// with their default configuration, except the rules that need a Grafana server, e.g. missing-datasource.
+func (cmd Command) lintDashboard(c utils.CommandLine) error {
+	filename := c.String("dashboard")
+	if filename == "" {
+		return errors.New("must specify dashboard file path with --dashboard")
+	}
+
+	b, err := os.ReadFile(filepath.Clean(filename))
+	if err != nil {
+		return err
+	}
+	dashboard, err := simplejson.NewJson(b)
+	if err != nil {
+		return fmt.Errorf("failed to parse dashboard JSON: %w", err)
+	}
+	// files exported with the HTTP API have the dashboard in a dashboard property
+	if wrapped, ok := dashboard.CheckGet("dashboard"); ok && wrapped.MustMap() != nil {
+		dashboard = wrapped
+	}
+
+	var report *dashboardlint.Report
+	if grafanaURL := c.String("grafana-url"); grafanaURL != "" {
+		report, err = lintDashboardWithServer(grafanaURL, c.String("token"), dashboard)
+	} else {
+		report, err = dashboardlint.Lint(context.Background(), dashboardlint.DefaultRules(), nil, &dashboardlint.Target{Dashboard: dashboard})
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, problem := range report.Problems {
+		panel := ""
+		if problem.PanelId != 0 {
+			panel = fmt.Sprintf(" (panel %d)", problem.PanelId)
+		}
+		logger.Infof("%s: %s [%s]%s\n", problem.Severity, problem.Message, problem.Rule, panel)
+	}
+
+	switch {
+	case report.Blocking:
+		return fmt.Errorf("dashboard %s has blocking lint problems", filename)
+	case c.Bool("strict") && len(report.Problems) > 0:
+		return fmt.Errorf("dashboard %s has %d lint problems", filename, len(report.Problems))
+	}
+	logger.Infof("dashboard %s has %d lint problems\n", filename, len(report.Problems))
+	return nil
+}
+
+// lintDashboardWithServer checks the dashboard with the rules configured by the organization of the token.
+func lintDashboardWithServer(grafanaURL string, token string, dashboard *simplejson.Json) (*dashboardlint.Report, error) {
+	body, err := json.Marshal(dashboardlint.LintDashboardCommand{Dashboard: dashboard})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(grafanaURL, "/")+"/api/dashboards/lint", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger.Warn("Failed to close response body", "err", err)
+		}
+	}()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to lint dashboard, status %d: %s", resp.StatusCode, respBody)
+	}
+
+	report := &dashboardlint.Report{}
+	if err := json.Unmarshal(respBody, report); err != nil {
+		return nil, fmt.Errorf("failed to parse lint report: %w", err)
+	}
+	return report, nil
+}
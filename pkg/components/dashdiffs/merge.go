@@ -0,0 +1,263 @@
+package dashdiffs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// Conflict is a change made to the same value of a dashboard by both the
+// stored and the incoming version since their common base version.
+type Conflict struct {
+	// Path locates the value in the dashboard, list items being identified by
+	// their key, e.g. `panels[id=4].targets[refId=A].expr`.
+	Path     string      `json:"path"`
+	Base     interface{} `json:"base"`
+	Stored   interface{} `json:"stored"`
+	Incoming interface{} `json:"incoming"`
+}
+
+// missing marks a value that is not present in one of the merged versions.
+type missingValue struct{}
+
+var missing = missingValue{}
+
+// dashboard fields that are owned by the stored dashboard and never merged
+var storedFields = []string{"id", "uid", "version"}
+
+// Merge performs a three-way merge of the changes made to a dashboard by the
+// stored and the incoming versions since their common base version.
+//
+// Panels are matched by `id`, or by `gridPos` when they have none, targets by
+// `refId`, and template variables and annotations by `name`. Other lists are
+// merged as a whole. When both versions changed the same value, a conflict is
+// returned and the merged dashboard keeps the stored value.
+func Merge(base, stored, incoming *simplejson.Json) (*simplejson.Json, []*Conflict, error) {
+	baseData, err := normalize(base)
+	if err != nil {
+		return nil, nil, err
+	}
+	storedData, err := normalize(stored)
+	if err != nil {
+		return nil, nil, err
+	}
+	incomingData, err := normalize(incoming)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, field := range storedFields {
+		value, ok := storedData[field]
+		for _, data := range []map[string]interface{}{baseData, incomingData} {
+			if ok {
+				data[field] = value
+			} else {
+				delete(data, field)
+			}
+		}
+	}
+
+	conflicts := []*Conflict{}
+	merged := mergeObject("", "", baseData, storedData, incomingData, &conflicts)
+
+	return simplejson.NewFromAny(merged), conflicts, nil
+}
+
+// normalize returns a copy of the dashboard data as decoded from JSON, so
+// that values built in code compare equal to the ones read from the database.
+func normalize(data *simplejson.Json) (map[string]interface{}, error) {
+	if data == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	raw, err := data.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func mergeValue(path, field, parentField string, base, stored, incoming interface{}, conflicts *[]*Conflict) interface{} {
+	switch {
+	case reflect.DeepEqual(stored, incoming):
+		return stored
+	case reflect.DeepEqual(base, stored):
+		return incoming
+	case reflect.DeepEqual(base, incoming):
+		return stored
+	}
+
+	switch s := stored.(type) {
+	case map[string]interface{}:
+		if i, ok := incoming.(map[string]interface{}); ok {
+			b, _ := base.(map[string]interface{})
+			return mergeObject(path, field, b, s, i, conflicts)
+		}
+	case []interface{}:
+		if i, ok := incoming.([]interface{}); ok {
+			if key := listKey(field, parentField); key != nil {
+				b, _ := base.([]interface{})
+				if merged, ok := mergeList(path, field, key, b, s, i, conflicts); ok {
+					return merged
+				}
+			}
+		}
+	}
+
+	*conflicts = append(*conflicts, &Conflict{
+		Path:     path,
+		Base:     conflictValue(base),
+		Stored:   conflictValue(stored),
+		Incoming: conflictValue(incoming),
+	})
+	return stored
+}
+
+func mergeObject(path, field string, base, stored, incoming map[string]interface{}, conflicts *[]*Conflict) map[string]interface{} {
+	keys := map[string]bool{}
+	for _, values := range []map[string]interface{}{base, stored, incoming} {
+		for key := range values {
+			keys[key] = true
+		}
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	merged := make(map[string]interface{}, len(sorted))
+	for _, key := range sorted {
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+
+		value := mergeValue(childPath, key, field, lookup(base, key), lookup(stored, key), lookup(incoming, key), conflicts)
+		if value != missing {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// mergeList merges lists whose items are identified by a key. It returns
+// false when an item has no key or shares it with another item, in which case
+// the list is merged as a whole.
+func mergeList(path, field string, key func(interface{}) string, base, stored, incoming []interface{}, conflicts *[]*Conflict) ([]interface{}, bool) {
+	baseItems, _, ok := indexList(key, base)
+	if !ok {
+		return nil, false
+	}
+	storedItems, storedKeys, ok := indexList(key, stored)
+	if !ok {
+		return nil, false
+	}
+	incomingItems, incomingKeys, ok := indexList(key, incoming)
+	if !ok {
+		return nil, false
+	}
+
+	// keep the order of the stored list, items added by the incoming version
+	// are appended in their own order
+	keys := storedKeys
+	for _, k := range incomingKeys {
+		if _, ok := storedItems[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	merged := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		value := mergeValue(fmt.Sprintf("%s[%s]", path, k), field, field,
+			lookup(baseItems, k), lookup(storedItems, k), lookup(incomingItems, k), conflicts)
+		if value != missing {
+			merged = append(merged, value)
+		}
+	}
+	return merged, true
+}
+
+func indexList(key func(interface{}) string, list []interface{}) (map[string]interface{}, []string, bool) {
+	items := make(map[string]interface{}, len(list))
+	keys := make([]string, 0, len(list))
+	for _, item := range list {
+		k := key(item)
+		if k == "" {
+			return nil, nil, false
+		}
+		if _, exists := items[k]; exists {
+			return nil, nil, false
+		}
+		items[k] = item
+		keys = append(keys, k)
+	}
+	return items, keys, true
+}
+
+// listKey returns how the items of the list stored in field are identified,
+// or nil if the list is merged as a whole.
+func listKey(field, parentField string) func(interface{}) string {
+	switch {
+	case field == "panels":
+		return panelKey
+	case field == "targets":
+		return fieldKey("refId")
+	case field == "list" && (parentField == "templating" || parentField == "annotations"):
+		return fieldKey("name")
+	}
+	return nil
+}
+
+func panelKey(item interface{}) string {
+	panel, ok := item.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if id, ok := panel["id"]; ok && id != nil {
+		return fmt.Sprintf("id=%v", id)
+	}
+	if gridPos, ok := panel["gridPos"].(map[string]interface{}); ok {
+		return fmt.Sprintf("gridPos=%v,%v", gridPos["x"], gridPos["y"])
+	}
+	return ""
+}
+
+func fieldKey(name string) func(interface{}) string {
+	return func(item interface{}) string {
+		values, ok := item.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		value, ok := values[name].(string)
+		if !ok || value == "" {
+			return ""
+		}
+		return fmt.Sprintf("%s=%s", name, value)
+	}
+}
+
+func lookup(values map[string]interface{}, key string) interface{} {
+	if value, ok := values[key]; ok {
+		return value
+	}
+	return missing
+}
+
+func conflictValue(value interface{}) interface{} {
+	if value == missing {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,201 @@
+package dashdiffs
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+const mergeBaseJSON = `{
+	"id": 1,
+	"uid": "abc",
+	"version": 3,
+	"title": "Servers",
+	"tags": ["prod"],
+	"panels": [
+		{"id": 1, "title": "CPU", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}, "targets": [
+			{"refId": "A", "expr": "cpu"},
+			{"refId": "B", "expr": "load"}
+		]},
+		{"id": 2, "title": "Memory", "gridPos": {"x": 12, "y": 0, "w": 12, "h": 8}},
+		{"id": 3, "type": "row", "title": "Disks", "collapsed": true, "gridPos": {"x": 0, "y": 8, "w": 24, "h": 1}, "panels": [
+			{"id": 4, "title": "IO", "gridPos": {"x": 0, "y": 9, "w": 24, "h": 8}}
+		]}
+	],
+	"templating": {"list": [
+		{"name": "host", "query": "hosts"},
+		{"name": "env", "query": "envs"}
+	]},
+	"annotations": {"list": [
+		{"name": "Deploys", "enable": true}
+	]}
+}`
+
+func mergeJSON(t *testing.T, base, stored, incoming string) (map[string]interface{}, []*Conflict) {
+	t.Helper()
+
+	parse := func(data string) *simplejson.Json {
+		j, err := simplejson.NewJson([]byte(data))
+		require.NoError(t, err)
+		return j
+	}
+
+	merged, conflicts, err := Merge(parse(base), parse(stored), parse(incoming))
+	require.NoError(t, err)
+
+	raw, err := merged.Encode()
+	require.NoError(t, err)
+	result := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(raw, &result))
+	return result, conflicts
+}
+
+// edit applies changes to a copy of the base dashboard
+func edit(t *testing.T, change func(dash *simplejson.Json)) string {
+	t.Helper()
+
+	dash, err := simplejson.NewJson([]byte(mergeBaseJSON))
+	require.NoError(t, err)
+	change(dash)
+	raw, err := dash.Encode()
+	require.NoError(t, err)
+	return string(raw)
+}
+
+func TestMerge(t *testing.T) {
+	t.Run("changes to different panels are merged", func(t *testing.T) {
+		stored := edit(t, func(dash *simplejson.Json) {
+			dash.Set("version", 4)
+			dash.Get("panels").GetIndex(0).Set("title", "CPU usage")
+		})
+		incoming := edit(t, func(dash *simplejson.Json) {
+			dash.Get("panels").GetIndex(1).Set("title", "Memory usage")
+			dash.Set("tags", []interface{}{"prod", "servers"})
+		})
+
+		merged, conflicts := mergeJSON(t, mergeBaseJSON, stored, incoming)
+		require.Empty(t, conflicts)
+
+		j := simplejson.NewFromAny(merged)
+		assert.Equal(t, "CPU usage", j.Get("panels").GetIndex(0).Get("title").MustString())
+		assert.Equal(t, "Memory usage", j.Get("panels").GetIndex(1).Get("title").MustString())
+		assert.Equal(t, []string{"prod", "servers"}, j.Get("tags").MustStringArray())
+		assert.Equal(t, 4, j.Get("version").MustInt())
+	})
+
+	t.Run("targets, variables and annotations are matched by key", func(t *testing.T) {
+		stored := edit(t, func(dash *simplejson.Json) {
+			dash.Get("panels").GetIndex(0).Set("targets", []interface{}{
+				map[string]interface{}{"refId": "B", "expr": "load"},
+				map[string]interface{}{"refId": "A", "expr": "cpu"},
+				map[string]interface{}{"refId": "C", "expr": "steal"},
+			})
+			dash.Get("templating").Set("list", []interface{}{
+				map[string]interface{}{"name": "host", "query": "hosts"},
+			})
+		})
+		incoming := edit(t, func(dash *simplejson.Json) {
+			dash.Get("panels").GetIndex(0).Get("targets").GetIndex(0).Set("expr", "rate(cpu[5m])")
+			dash.Get("templating").Get("list").GetIndex(0).Set("query", "servers")
+			dash.Get("annotations").Set("list", []interface{}{
+				map[string]interface{}{"name": "Deploys", "enable": true},
+				map[string]interface{}{"name": "Incidents", "enable": true},
+			})
+		})
+
+		merged, conflicts := mergeJSON(t, mergeBaseJSON, stored, incoming)
+		require.Empty(t, conflicts)
+
+		j := simplejson.NewFromAny(merged)
+		targets := j.Get("panels").GetIndex(0).Get("targets")
+		assert.Len(t, targets.MustArray(), 3)
+		assert.Equal(t, "B", targets.GetIndex(0).Get("refId").MustString())
+		assert.Equal(t, "rate(cpu[5m])", targets.GetIndex(1).Get("expr").MustString())
+		assert.Equal(t, "steal", targets.GetIndex(2).Get("expr").MustString())
+
+		variables := j.Get("templating").Get("list")
+		assert.Len(t, variables.MustArray(), 1)
+		assert.Equal(t, "servers", variables.GetIndex(0).Get("query").MustString())
+
+		assert.Len(t, j.Get("annotations").Get("list").MustArray(), 2)
+	})
+
+	t.Run("panels added and deleted on both sides", func(t *testing.T) {
+		stored := edit(t, func(dash *simplejson.Json) {
+			panels := dash.Get("panels").MustArray()
+			dash.Set("panels", append(panels[1:], map[string]interface{}{"id": 5, "title": "Network"}))
+		})
+		incoming := edit(t, func(dash *simplejson.Json) {
+			row := dash.Get("panels").GetIndex(2)
+			row.Set("panels", append(row.Get("panels").MustArray(),
+				map[string]interface{}{"title": "Latency", "gridPos": map[string]interface{}{"x": 0, "y": 17, "w": 24, "h": 8}}))
+			panels := dash.Get("panels").MustArray()
+			dash.Set("panels", append(panels, map[string]interface{}{"id": 6, "title": "Errors"}))
+		})
+
+		merged, conflicts := mergeJSON(t, mergeBaseJSON, stored, incoming)
+		require.Empty(t, conflicts)
+
+		j := simplejson.NewFromAny(merged)
+		titles := []string{}
+		for i := range j.Get("panels").MustArray() {
+			titles = append(titles, j.Get("panels").GetIndex(i).Get("title").MustString())
+		}
+		assert.Equal(t, []string{"Memory", "Disks", "Network", "Errors"}, titles)
+		assert.Len(t, j.Get("panels").GetIndex(1).Get("panels").MustArray(), 2)
+	})
+
+	t.Run("changes to the same value conflict", func(t *testing.T) {
+		stored := edit(t, func(dash *simplejson.Json) {
+			dash.Get("panels").GetIndex(0).Get("targets").GetIndex(0).Set("expr", "cpu_seconds")
+			dash.Set("title", "Hosts")
+		})
+		incoming := edit(t, func(dash *simplejson.Json) {
+			dash.Get("panels").GetIndex(0).Get("targets").GetIndex(0).Set("expr", "cpu_total")
+			dash.Get("panels").GetIndex(1).Set("title", "Memory usage")
+			dash.Set("title", "Hosts")
+		})
+
+		merged, conflicts := mergeJSON(t, mergeBaseJSON, stored, incoming)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, "panels[id=1].targets[refId=A].expr", conflicts[0].Path)
+		assert.Equal(t, "cpu", conflicts[0].Base)
+		assert.Equal(t, "cpu_seconds", conflicts[0].Stored)
+		assert.Equal(t, "cpu_total", conflicts[0].Incoming)
+
+		j := simplejson.NewFromAny(merged)
+		assert.Equal(t, "cpu_seconds", j.Get("panels").GetIndex(0).Get("targets").GetIndex(0).Get("expr").MustString())
+		assert.Equal(t, "Memory usage", j.Get("panels").GetIndex(1).Get("title").MustString())
+	})
+
+	t.Run("deleting a panel changed by the other side conflicts", func(t *testing.T) {
+		stored := edit(t, func(dash *simplejson.Json) {
+			dash.Set("panels", dash.Get("panels").MustArray()[1:])
+		})
+		incoming := edit(t, func(dash *simplejson.Json) {
+			dash.Get("panels").GetIndex(0).Set("title", "CPU usage")
+		})
+
+		_, conflicts := mergeJSON(t, mergeBaseJSON, stored, incoming)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, "panels[id=1]", conflicts[0].Path)
+		assert.Nil(t, conflicts[0].Stored)
+	})
+
+	t.Run("lists without keys are merged as a whole", func(t *testing.T) {
+		stored := edit(t, func(dash *simplejson.Json) {
+			dash.Set("tags", []interface{}{"prod", "a"})
+		})
+		incoming := edit(t, func(dash *simplejson.Json) {
+			dash.Set("tags", []interface{}{"prod", "b"})
+		})
+
+		_, conflicts := mergeJSON(t, mergeBaseJSON, stored, incoming)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, "tags", conflicts[0].Path)
+	})
+}
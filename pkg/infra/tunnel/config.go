@@ -0,0 +1,94 @@
+package tunnel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	TypeSSH    = "ssh"
+	TypeSOCKS5 = "socks5"
+)
+
+// Config holds the tunnel settings of a data source. The non secret settings
+// are read from jsonData, the credentials from secureJsonData.
+type Config struct {
+	Type string
+	// Host is the address of the SSH server or SOCKS5 proxy, host:port.
+	Host string
+	User string
+	// Password authenticates against the SOCKS5 proxy.
+	Password string
+	// PrivateKey is the PEM encoded key used to authenticate against the SSH server.
+	PrivateKey string
+	Passphrase string
+	// HostKey is the public key of the SSH server in authorized_keys format.
+	HostKey           string
+	SkipHostKeyVerify bool
+}
+
+// ConfigFromJSONData returns the tunnel config of a data source or nil if
+// the data source does not use a tunnel.
+func ConfigFromJSONData(jsonData map[string]interface{}, secureJSONData map[string]string) (*Config, error) {
+	tunnelType, _ := jsonData["tunnelType"].(string)
+	if tunnelType == "" {
+		return nil, nil
+	}
+
+	cfg := &Config{
+		Type:       tunnelType,
+		Password:   secureJSONData["tunnelPassword"],
+		PrivateKey: secureJSONData["tunnelSshPrivateKey"],
+		Passphrase: secureJSONData["tunnelSshPassphrase"],
+	}
+	cfg.Host, _ = jsonData["tunnelHost"].(string)
+	cfg.User, _ = jsonData["tunnelUser"].(string)
+	cfg.HostKey, _ = jsonData["tunnelSshHostKey"].(string)
+	cfg.SkipHostKeyVerify, _ = jsonData["tunnelSshSkipHostKeyVerify"].(bool)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks that the config is complete.
+func (cfg *Config) Validate() error {
+	if cfg.Host == "" {
+		return errors.New("tunnel host is required")
+	}
+	if _, _, err := net.SplitHostPort(cfg.Host); err != nil {
+		return fmt.Errorf("invalid tunnel host: %w", err)
+	}
+
+	switch cfg.Type {
+	case TypeSSH:
+		if cfg.User == "" {
+			return errors.New("tunnel user is required for ssh tunnels")
+		}
+		if cfg.PrivateKey == "" {
+			return errors.New("tunnel ssh private key is required")
+		}
+		if cfg.HostKey == "" && !cfg.SkipHostKeyVerify {
+			return errors.New("tunnel ssh host key is required unless host key verification is skipped")
+		}
+	case TypeSOCKS5:
+	default:
+		return fmt.Errorf("unsupported tunnel type %q", cfg.Type)
+	}
+
+	return nil
+}
+
+// Key identifies tunnels with the same settings, they share a connection.
+func (cfg *Config) Key() string {
+	h := sha256.New()
+	for _, v := range []string{cfg.Type, cfg.Host, cfg.User, cfg.Password, cfg.PrivateKey, cfg.Passphrase, cfg.HostKey, fmt.Sprint(cfg.SkipHostKeyVerify)} {
+		_, _ = h.Write([]byte(v))
+		_, _ = h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -0,0 +1,53 @@
+package tunnel
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// socks5Dialer dials through a SOCKS5 proxy. SOCKS5 has no session, every
+// connection is a new proxy connection.
+type socks5Dialer struct {
+	host    string
+	dialer  proxy.ContextDialer
+	forward *net.Dialer
+}
+
+func newSOCKS5Dialer(cfg *Config, dialTimeout time.Duration) (*socks5Dialer, error) {
+	var auth *proxy.Auth
+	if cfg.User != "" || cfg.Password != "" {
+		auth = &proxy.Auth{User: cfg.User, Password: cfg.Password}
+	}
+
+	forward := &net.Dialer{Timeout: dialTimeout, KeepAlive: defaultKeepAliveInterval}
+	d, err := proxy.SOCKS5("tcp", cfg.Host, auth, forward)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+
+	return &socks5Dialer{
+		host:    cfg.Host,
+		dialer:  d.(proxy.ContextDialer),
+		forward: forward,
+	}, nil
+}
+
+func (d *socks5Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+	return conn, nil
+}
+
+// checkHealth verifies that the proxy accepts connections.
+func (d *socks5Dialer) checkHealth(ctx context.Context) error {
+	conn, err := d.forward.DialContext(ctx, "tcp", d.host)
+	if err != nil {
+		return &Error{Err: err}
+	}
+	return conn.Close()
+}
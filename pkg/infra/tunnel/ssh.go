@@ -0,0 +1,290 @@
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+// sshTunnel forwards connections through an SSH jump host. All connections
+// share one SSH connection, which is reopened when it breaks.
+type sshTunnel struct {
+	cfg               *Config
+	clientConfig      *ssh.ClientConfig
+	dialTimeout       time.Duration
+	keepAliveInterval time.Duration
+	log               log.Logger
+
+	mu       sync.Mutex
+	client   *ssh.Client
+	active   int
+	lastUsed time.Time
+}
+
+func newSSHTunnel(cfg *Config, dialTimeout, keepAliveInterval time.Duration, logger log.Logger) (*sshTunnel, error) {
+	var signer ssh.Signer
+	var err error
+	if cfg.Passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(cfg.PrivateKey), []byte(cfg.Passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey([]byte(cfg.PrivateKey))
+	}
+	if err != nil {
+		return nil, &Error{Err: fmt.Errorf("invalid ssh private key: %w", err)}
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey() // #nosec G106 only used when explicitly configured
+	if !cfg.SkipHostKeyVerify {
+		hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(cfg.HostKey))
+		if err != nil {
+			return nil, &Error{Err: fmt.Errorf("invalid ssh host key: %w", err)}
+		}
+		hostKeyCallback = ssh.FixedHostKey(hostKey)
+	}
+
+	return &sshTunnel{
+		cfg: cfg,
+		clientConfig: &ssh.ClientConfig{
+			User:            cfg.User,
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+			HostKeyCallback: hostKeyCallback,
+			Timeout:         dialTimeout,
+		},
+		dialTimeout:       dialTimeout,
+		keepAliveInterval: keepAliveInterval,
+		log:               logger,
+		lastUsed:          time.Now(),
+	}, nil
+}
+
+// DialContext opens a connection through the tunnel, which the caller
+// acquired from the pool. The tunnel is released when the connection is
+// closed.
+func (t *sshTunnel) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, broken, err := t.dial(ctx, network, addr)
+	if broken {
+		// the SSH connection broke since the last keepalive, retry with a new one
+		conn, _, err = t.dial(ctx, network, addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &trackedConn{Conn: conn, release: t.release}, nil
+}
+
+// dial opens a connection through the tunnel. broken is true if the SSH
+// connection failed while dialing.
+func (t *sshTunnel) dial(ctx context.Context, network, addr string) (conn net.Conn, broken bool, err error) {
+	client, err := t.connect(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := client.Dial(network, addr)
+		done <- result{conn: conn, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			// the target rejected the connection, the SSH connection is fine
+			var openErr *ssh.OpenChannelError
+			if errors.As(res.err, &openErr) {
+				return nil, false, &Error{Err: res.err}
+			}
+			t.reset(client)
+			return nil, true, &Error{Err: res.err}
+		}
+		return res.conn, false, nil
+	case <-ctx.Done():
+		go func() {
+			if res := <-done; res.conn != nil {
+				_ = res.conn.Close()
+			}
+		}()
+		return nil, false, ctx.Err()
+	}
+}
+
+// connect returns the SSH connection, opening it if needed. The lock is not
+// held while opening the connection, so a slow SSH server does not block the
+// pool. Concurrent dials may open a connection each, all but the first one
+// are closed.
+func (t *sshTunnel) connect(ctx context.Context) (*ssh.Client, error) {
+	t.mu.Lock()
+	client := t.client
+	t.mu.Unlock()
+	if client != nil {
+		return client, nil
+	}
+
+	client, err := t.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	if current := t.client; current != nil {
+		t.mu.Unlock()
+		_ = client.Close()
+		return current, nil
+	}
+	t.client = client
+	t.mu.Unlock()
+
+	go t.keepAlive(client)
+
+	t.log.Debug("Opened ssh tunnel", "host", t.cfg.Host, "user", t.cfg.User)
+	return client, nil
+}
+
+// open dials the SSH server and performs the handshake.
+func (t *sshTunnel) open(ctx context.Context) (*ssh.Client, error) {
+	d := net.Dialer{Timeout: t.dialTimeout, KeepAlive: t.keepAliveInterval}
+	conn, err := d.DialContext(ctx, "tcp", t.cfg.Host)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+
+	// bound the handshake, ssh.ClientConfig.Timeout only covers the TCP dial
+	_ = conn.SetDeadline(time.Now().Add(t.dialTimeout))
+	c, chans, reqs, err := ssh.NewClientConn(conn, t.cfg.Host, t.clientConfig)
+	if err != nil {
+		_ = conn.Close()
+		return nil, &Error{Err: err}
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// keepAlive sends keepalive requests until the connection breaks, so broken
+// connections are detected before they are used.
+func (t *sshTunnel) keepAlive(client *ssh.Client) {
+	closed := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(t.keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			t.reset(client)
+			return
+		case <-ticker.C:
+			if err := sendKeepAlive(client, t.dialTimeout); err != nil {
+				t.log.Warn("Ssh tunnel keepalive failed", "host", t.cfg.Host, "error", err)
+				t.reset(client)
+				return
+			}
+		}
+	}
+}
+
+func sendKeepAlive(client *ssh.Client, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return errors.New("keepalive timed out")
+	}
+}
+
+// reset closes the connection and forgets it if it is still the current one.
+func (t *sshTunnel) reset(client *ssh.Client) {
+	t.mu.Lock()
+	if t.client == client {
+		t.client = nil
+	}
+	t.mu.Unlock()
+
+	_ = client.Close()
+}
+
+func (t *sshTunnel) checkHealth(ctx context.Context) error {
+	client, err := t.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := sendKeepAlive(client, t.dialTimeout); err != nil {
+		t.reset(client)
+		return &Error{Err: err}
+	}
+	return nil
+}
+
+// acquire marks the tunnel as used, so that it is not closed for being idle.
+func (t *sshTunnel) acquire() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.active++
+	t.lastUsed = time.Now()
+}
+
+func (t *sshTunnel) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.active--
+	t.lastUsed = time.Now()
+}
+
+func (t *sshTunnel) idleSince(since time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.active == 0 && t.lastUsed.Before(since)
+}
+
+// close closes the SSH connection, it returns false if it was not open.
+func (t *sshTunnel) close() bool {
+	t.mu.Lock()
+	client := t.client
+	t.client = nil
+	t.mu.Unlock()
+
+	if client == nil {
+		return false
+	}
+	_ = client.Close()
+	return true
+}
+
+// trackedConn counts the open connections of a tunnel, so that tunnels in
+// use are not closed for being idle.
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
@@ -0,0 +1,183 @@
+package tunnel
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+const (
+	defaultDialTimeout       = 10 * time.Second
+	defaultKeepAliveInterval = 30 * time.Second
+	defaultIdleTimeout       = 10 * time.Minute
+)
+
+// Dialer opens connections through a tunnel.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// Error is returned when the tunnel could not be established. Its message is
+// safe to show to users, unlike connection errors of the target.
+type Error struct {
+	Err error
+}
+
+func (e *Error) Error() string {
+	return "tunnel: " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Pool shares tunnels between data sources with the same tunnel settings.
+// SSH connections are kept alive and closed once they have been idle for a
+// while. Idle tunnels are removed from the pool with their private keys, so
+// tunnels of changed or deleted data sources are not kept.
+type Pool struct {
+	DialTimeout       time.Duration
+	KeepAliveInterval time.Duration
+	IdleTimeout       time.Duration
+
+	mu          sync.Mutex
+	tunnels     map[string]*sshTunnel
+	janitorOnce sync.Once
+	log         log.Logger
+}
+
+func NewPool() *Pool {
+	return &Pool{
+		DialTimeout:       defaultDialTimeout,
+		KeepAliveInterval: defaultKeepAliveInterval,
+		IdleTimeout:       defaultIdleTimeout,
+		tunnels:           map[string]*sshTunnel{},
+		log:               log.New("tunnel"),
+	}
+}
+
+var defaultPool = NewPool()
+
+// NewDialer returns a dialer for the tunnel from the shared pool.
+func NewDialer(cfg *Config) (Dialer, error) {
+	return defaultPool.Dialer(cfg)
+}
+
+// CheckHealth verifies that the tunnel from the shared pool can be established.
+func CheckHealth(ctx context.Context, cfg *Config) error {
+	return defaultPool.CheckHealth(ctx, cfg)
+}
+
+// Dialer returns a dialer for the tunnel.
+func (p *Pool) Dialer(cfg *Config) (Dialer, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, &Error{Err: err}
+	}
+
+	if cfg.Type == TypeSOCKS5 {
+		return newSOCKS5Dialer(cfg, p.DialTimeout)
+	}
+
+	d := &poolDialer{pool: p, cfg: cfg, key: cfg.Key()}
+	// create the tunnel now, so that invalid keys are reported with the settings
+	t, err := p.acquire(d.key, cfg)
+	if err != nil {
+		return nil, err
+	}
+	t.release()
+	return d, nil
+}
+
+// CheckHealth verifies that the tunnel can be established.
+func (p *Pool) CheckHealth(ctx context.Context, cfg *Config) error {
+	if err := cfg.Validate(); err != nil {
+		return &Error{Err: err}
+	}
+
+	if cfg.Type == TypeSOCKS5 {
+		d, err := newSOCKS5Dialer(cfg, p.DialTimeout)
+		if err != nil {
+			return err
+		}
+		return d.checkHealth(ctx)
+	}
+
+	t, err := p.acquire(cfg.Key(), cfg)
+	if err != nil {
+		return err
+	}
+	defer t.release()
+	return t.checkHealth(ctx)
+}
+
+// acquire returns the tunnel with the settings, creating it if needed. The
+// tunnel must be released once it is no longer used.
+func (p *Pool) acquire(key string, cfg *Config) (*sshTunnel, error) {
+	p.janitorOnce.Do(func() {
+		go p.closeIdleTunnels()
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, ok := p.tunnels[key]
+	if !ok {
+		var err error
+		t, err = newSSHTunnel(cfg, p.DialTimeout, p.KeepAliveInterval, p.log)
+		if err != nil {
+			return nil, err
+		}
+		p.tunnels[key] = t
+	}
+	// acquired under the pool lock, so the tunnel is not removed for being idle meanwhile
+	t.acquire()
+	return t, nil
+}
+
+func (p *Pool) closeIdleTunnels() {
+	ticker := time.NewTicker(p.IdleTimeout / 2)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		idle := []*sshTunnel{}
+		p.mu.Lock()
+		for key, t := range p.tunnels {
+			// dialers look the tunnel up again when used, a new one is created then
+			if t.idleSince(time.Now().Add(-p.IdleTimeout)) {
+				delete(p.tunnels, key)
+				idle = append(idle, t)
+			}
+		}
+		p.mu.Unlock()
+
+		for _, t := range idle {
+			if t.close() {
+				p.log.Debug("Closed idle tunnel", "host", t.cfg.Host)
+			}
+		}
+	}
+}
+
+// poolDialer dials through the tunnel of the pool with its settings.
+type poolDialer struct {
+	pool *Pool
+	cfg  *Config
+	key  string
+}
+
+func (d *poolDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	t, err := d.pool.acquire(d.key, d.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := t.DialContext(ctx, network, addr)
+	if err != nil {
+		t.release()
+		return nil, err
+	}
+	return conn, nil
+}
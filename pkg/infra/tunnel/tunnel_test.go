@@ -0,0 +1,387 @@
+package tunnel
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestConfigFromJSONData(t *testing.T) {
+	t.Run("returns nil without tunnel type", func(t *testing.T) {
+		cfg, err := ConfigFromJSONData(map[string]interface{}{"tunnelHost": "bastion:22"}, nil)
+		require.NoError(t, err)
+		require.Nil(t, cfg)
+	})
+
+	t.Run("reads ssh settings", func(t *testing.T) {
+		cfg, err := ConfigFromJSONData(map[string]interface{}{
+			"tunnelType":       "ssh",
+			"tunnelHost":       "bastion:22",
+			"tunnelUser":       "grafana",
+			"tunnelSshHostKey": "ssh-ed25519 AAAA",
+		}, map[string]string{"tunnelSshPrivateKey": "key", "tunnelSshPassphrase": "secret"})
+		require.NoError(t, err)
+		require.Equal(t, &Config{
+			Type:       TypeSSH,
+			Host:       "bastion:22",
+			User:       "grafana",
+			PrivateKey: "key",
+			Passphrase: "secret",
+			HostKey:    "ssh-ed25519 AAAA",
+		}, cfg)
+	})
+
+	t.Run("requires host key unless verification is skipped", func(t *testing.T) {
+		jsonData := map[string]interface{}{
+			"tunnelType": "ssh",
+			"tunnelHost": "bastion:22",
+			"tunnelUser": "grafana",
+		}
+		secureJSONData := map[string]string{"tunnelSshPrivateKey": "key"}
+
+		_, err := ConfigFromJSONData(jsonData, secureJSONData)
+		require.Error(t, err)
+
+		jsonData["tunnelSshSkipHostKeyVerify"] = true
+		_, err = ConfigFromJSONData(jsonData, secureJSONData)
+		require.NoError(t, err)
+	})
+
+	t.Run("rejects invalid settings", func(t *testing.T) {
+		_, err := ConfigFromJSONData(map[string]interface{}{"tunnelType": "socks5", "tunnelHost": "proxy"}, nil)
+		require.Error(t, err)
+
+		_, err = ConfigFromJSONData(map[string]interface{}{"tunnelType": "vpn", "tunnelHost": "proxy:1080"}, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestSSHTunnel(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(target.Close)
+
+	get := func(t *testing.T, dialer Dialer) {
+		t.Helper()
+		client := &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext, DisableKeepAlives: true}}
+		resp, err := client.Get(target.URL)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		require.Equal(t, "ok", string(body))
+	}
+
+	t.Run("forwards connections over one ssh connection", func(t *testing.T) {
+		server := newTestSSHServer(t)
+		pool := NewPool()
+
+		dialer, err := pool.Dialer(server.config())
+		require.NoError(t, err)
+		get(t, dialer)
+		get(t, dialer)
+
+		other, err := pool.Dialer(server.config())
+		require.NoError(t, err)
+		get(t, other)
+		require.Len(t, pool.tunnels, 1)
+
+		require.NoError(t, pool.CheckHealth(context.Background(), server.config()))
+		require.Equal(t, 1, server.connections())
+	})
+
+	t.Run("reconnects when the ssh connection breaks", func(t *testing.T) {
+		server := newTestSSHServer(t)
+		pool := NewPool()
+
+		dialer, err := pool.Dialer(server.config())
+		require.NoError(t, err)
+		get(t, dialer)
+
+		server.closeConnections()
+		get(t, dialer)
+		require.Equal(t, 2, server.connections())
+	})
+
+	t.Run("keeps the ssh connection when the target rejects a connection", func(t *testing.T) {
+		server := newTestSSHServer(t)
+		pool := NewPool()
+
+		dialer, err := pool.Dialer(server.config())
+		require.NoError(t, err)
+
+		_, err = dialer.DialContext(context.Background(), "tcp", closedAddr(t))
+		var tunnelErr *Error
+		require.True(t, errors.As(err, &tunnelErr))
+
+		get(t, dialer)
+		require.Equal(t, 1, server.connections())
+	})
+
+	t.Run("rejects unknown host keys", func(t *testing.T) {
+		server := newTestSSHServer(t)
+		pool := NewPool()
+
+		cfg := server.config()
+		cfg.HostKey = newTestSSHServer(t).config().HostKey
+
+		err := pool.CheckHealth(context.Background(), cfg)
+		var tunnelErr *Error
+		require.True(t, errors.As(err, &tunnelErr))
+		require.Equal(t, 0, server.connections())
+	})
+
+	t.Run("closes idle ssh connections and removes their tunnels", func(t *testing.T) {
+		server := newTestSSHServer(t)
+		pool := NewPool()
+		pool.IdleTimeout = 50 * time.Millisecond
+
+		dialer, err := pool.Dialer(server.config())
+		require.NoError(t, err)
+		get(t, dialer)
+
+		pool.mu.Lock()
+		tunnel := pool.tunnels[server.config().Key()]
+		pool.mu.Unlock()
+
+		require.Eventually(t, func() bool {
+			pool.mu.Lock()
+			defer pool.mu.Unlock()
+			return len(pool.tunnels) == 0
+		}, time.Second, 10*time.Millisecond)
+		tunnel.mu.Lock()
+		require.Nil(t, tunnel.client)
+		tunnel.mu.Unlock()
+
+		get(t, dialer)
+		require.Equal(t, 2, server.connections())
+	})
+
+	t.Run("does not lock the tunnel while connecting", func(t *testing.T) {
+		server := newTestSSHServer(t)
+		pool := NewPool()
+
+		// accepts connections but never completes the handshake
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = listener.Close() })
+		accepted := make(chan net.Conn, 1)
+		go func() {
+			conn, err := listener.Accept()
+			if err == nil {
+				accepted <- conn
+			}
+		}()
+
+		cfg := server.config()
+		cfg.Host = listener.Addr().String()
+		dialer, err := pool.Dialer(cfg)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			_, _ = dialer.DialContext(ctx, "tcp", "127.0.0.1:1")
+		}()
+		conn := <-accepted
+		t.Cleanup(func() { _ = conn.Close() })
+
+		idle := make(chan bool)
+		go func() {
+			pool.mu.Lock()
+			defer pool.mu.Unlock()
+			idle <- pool.tunnels[cfg.Key()].idleSince(time.Now())
+		}()
+		select {
+		case isIdle := <-idle:
+			require.False(t, isIdle)
+		case <-time.After(time.Second):
+			t.Fatal("tunnel is locked while connecting")
+		}
+	})
+}
+
+func TestSOCKS5Tunnel(t *testing.T) {
+	pool := NewPool()
+
+	err := pool.CheckHealth(context.Background(), &Config{Type: TypeSOCKS5, Host: closedAddr(t)})
+	var tunnelErr *Error
+	require.True(t, errors.As(err, &tunnelErr))
+}
+
+// testSSHServer is an SSH server that forwards direct-tcpip channels.
+type testSSHServer struct {
+	addr       string
+	hostKey    ssh.PublicKey
+	privateKey string
+
+	mu    sync.Mutex
+	conns []*ssh.ServerConn
+	total int
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+
+	hostSigner, err := ssh.NewSignerFromKey(generateKey(t))
+	require.NoError(t, err)
+
+	clientKey := generateKey(t)
+	der, err := x509.MarshalECPrivateKey(clientKey)
+	require.NoError(t, err)
+	clientPublicKey, err := ssh.NewPublicKey(&clientKey.PublicKey)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "grafana" && string(key.Marshal()) == string(clientPublicKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unauthorized")
+		},
+	}
+	config.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &testSSHServer{
+		addr:       listener.Addr().String(),
+		hostKey:    hostSigner.PublicKey(),
+		privateKey: string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})),
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+		s.closeConnections()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, config)
+		}
+	}()
+
+	return s
+}
+
+func (s *testSSHServer) serve(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+
+	s.mu.Lock()
+	s.conns = append(s.conns, serverConn)
+	s.total++
+	s.mu.Unlock()
+
+	go func() {
+		for req := range reqs {
+			if req.WantReply {
+				_ = req.Reply(true, nil)
+			}
+		}
+	}()
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "direct-tcpip" {
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+
+		var payload struct {
+			Host     string
+			Port     uint32
+			OrigHost string
+			OrigPort uint32
+		}
+		if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+			_ = newChannel.Reject(ssh.Prohibited, err.Error())
+			continue
+		}
+
+		target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+		if err != nil {
+			_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			_ = target.Close()
+			continue
+		}
+		go ssh.DiscardRequests(requests)
+		go func() {
+			_, _ = io.Copy(channel, target)
+			_ = channel.Close()
+		}()
+		go func() {
+			_, _ = io.Copy(target, channel)
+			_ = target.Close()
+		}()
+	}
+}
+
+func (s *testSSHServer) config() *Config {
+	return &Config{
+		Type:       TypeSSH,
+		Host:       s.addr,
+		User:       "grafana",
+		PrivateKey: s.privateKey,
+		HostKey:    string(ssh.MarshalAuthorizedKey(s.hostKey)),
+	}
+}
+
+func (s *testSSHServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.total
+}
+
+func (s *testSSHServer) closeConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+func generateKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+// closedAddr returns an address nothing listens on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+	return addr
+}
@@ -17,6 +17,7 @@ var (
 	ErrNoEmail               = errors.New("login provider didn't return an email address")
 	ErrProviderDeniedRequest = errors.New("login provider denied login request")
 	ErrTooManyLoginAttempts  = errors.New("too many consecutive incorrect login attempts for user - login for user temporarily blocked")
+	ErrAccountLocked         = errors.New("too many consecutive incorrect login attempts for user - account locked")
 	ErrPasswordEmpty         = errors.New("no password provided")
 	ErrUserDisabled          = errors.New("user is disabled")
 	ErrAbsoluteRedirectTo    = errors.New("absolute URLs are not allowed for redirect_to cookie value")
@@ -57,6 +58,11 @@ func (a *AuthenticatorService) AuthenticateUser(ctx context.Context, query *mode
 	if err == nil || (!errors.Is(err, models.ErrUserNotFound) && !errors.Is(err, ErrInvalidCredentials) &&
 		!errors.Is(err, ErrUserDisabled)) {
 		query.AuthModule = "grafana"
+		if err == nil {
+			if err := resetLoginAttempts(ctx, query, a.store); err != nil {
+				loginLogger.Error("Failed to reset login attempts", "err", err)
+			}
+		}
 		return err
 	}
 
@@ -11,6 +11,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/login/logintest"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/services/sqlstore/mockstore"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -224,6 +225,7 @@ func authScenario(t *testing.T, desc string, fn authScenarioFunc) {
 				Username:  "user",
 				Password:  "pwd",
 				IpAddress: "192.168.1.1:56433",
+				Cfg:       setting.NewCfg(),
 			},
 		}
 
@@ -6,6 +6,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 var (
@@ -18,16 +19,20 @@ var validateLoginAttempts = func(ctx context.Context, query *models.LoginUserQue
 		return nil
 	}
 
+	maxAttempts, window := loginAttemptsLimits(query.Cfg)
 	loginAttemptCountQuery := models.GetUserLoginAttemptCountQuery{
 		Username: query.Username,
-		Since:    time.Now().Add(-loginAttemptsWindow),
+		Since:    time.Now().Add(-window),
 	}
 
 	if err := store.GetUserLoginAttemptCount(ctx, &loginAttemptCountQuery); err != nil {
 		return err
 	}
 
-	if loginAttemptCountQuery.Result >= maxInvalidLoginAttempts {
+	if loginAttemptCountQuery.Result >= maxAttempts {
+		if isAccountLockoutEnabled(query.Cfg) {
+			return ErrAccountLocked
+		}
 		return ErrTooManyLoginAttempts
 	}
 
@@ -46,3 +51,28 @@ var saveInvalidLoginAttempt = func(ctx context.Context, query *models.LoginUserQ
 
 	return store.CreateLoginAttempt(ctx, &loginAttemptCommand)
 }
+
+// resetLoginAttempts forgets the failed logins of a user once it logged in, so that the account lockout only counts
+// consecutive failures
+var resetLoginAttempts = func(ctx context.Context, query *models.LoginUserQuery, store sqlstore.Store) error {
+	if query.Cfg.DisableBruteForceLoginProtection || !isAccountLockoutEnabled(query.Cfg) {
+		return nil
+	}
+
+	return store.DeleteUserLoginAttempts(ctx, &models.DeleteUserLoginAttemptsCommand{
+		Usernames: []string{query.Username, query.User.Login, query.User.Email},
+	})
+}
+
+// loginAttemptsLimits returns the number of failed logins after which a user cannot log in, and the window they are
+// counted in. The account lockout of the password policy replaces the default limits.
+func loginAttemptsLimits(cfg *setting.Cfg) (int64, time.Duration) {
+	if isAccountLockoutEnabled(cfg) {
+		return int64(cfg.PasswordPolicyLockoutAttempts), cfg.PasswordPolicyLockoutDuration
+	}
+	return maxInvalidLoginAttempts, loginAttemptsWindow
+}
+
+func isAccountLockoutEnabled(cfg *setting.Cfg) bool {
+	return cfg.PasswordPolicyEnabled && cfg.PasswordPolicyLockoutAttempts > 0
+}
@@ -3,6 +3,7 @@ package login
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/sqlstore/mockstore"
@@ -100,6 +101,54 @@ func TestSaveInvalidLoginAttempt(t *testing.T) {
 	})
 }
 
+func TestAccountLockout(t *testing.T) {
+	cfg := cfgWithBruteForceLoginProtectionEnabled(t)
+	cfg.PasswordPolicyEnabled = true
+	cfg.PasswordPolicyLockoutAttempts = 3
+	cfg.PasswordPolicyLockoutDuration = time.Hour
+
+	t.Run("When the attempts count is below the lockout threshold", func(t *testing.T) {
+		store := mockstore.NewSQLStoreMock()
+		store.ExpectedLoginAttempts = 2
+
+		err := validateLoginAttempts(context.Background(), &models.LoginUserQuery{Username: "user", Cfg: cfg}, store)
+		require.NoError(t, err)
+	})
+
+	t.Run("When the attempts count reaches the lockout threshold", func(t *testing.T) {
+		store := mockstore.NewSQLStoreMock()
+		store.ExpectedLoginAttempts = 3
+
+		err := validateLoginAttempts(context.Background(), &models.LoginUserQuery{Username: "user", Cfg: cfg}, store)
+		require.Equal(t, ErrAccountLocked, err)
+	})
+
+	t.Run("When the user logs in, its attempts are reset", func(t *testing.T) {
+		store := mockstore.NewSQLStoreMock()
+		query := &models.LoginUserQuery{
+			Username: "User@example.com",
+			Cfg:      cfg,
+			User:     &models.User{Login: "user", Email: "user@example.com"},
+		}
+
+		require.NoError(t, resetLoginAttempts(context.Background(), query, store))
+		require.NotNil(t, store.LastDeleteUserLoginAttemptsCommand)
+		assert.Equal(t, []string{"User@example.com", "user", "user@example.com"}, store.LastDeleteUserLoginAttemptsCommand.Usernames)
+	})
+
+	t.Run("When the account lockout is disabled, the attempts are not reset", func(t *testing.T) {
+		store := mockstore.NewSQLStoreMock()
+		query := &models.LoginUserQuery{
+			Username: "user",
+			Cfg:      cfgWithBruteForceLoginProtectionEnabled(t),
+			User:     &models.User{Login: "user"},
+		}
+
+		require.NoError(t, resetLoginAttempts(context.Background(), query, store))
+		require.Nil(t, store.LastDeleteUserLoginAttemptsCommand)
+	})
+}
+
 func cfgWithBruteForceLoginProtectionDisabled(t *testing.T) *setting.Cfg {
 	t.Helper()
 	cfg := setting.NewCfg()
@@ -20,13 +20,18 @@ import (
 	"github.com/grafana/grafana/pkg/infra/tracing"
 	"github.com/grafana/grafana/pkg/login"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
 	"github.com/grafana/grafana/pkg/services/auth"
 	"github.com/grafana/grafana/pkg/services/contexthandler"
 	"github.com/grafana/grafana/pkg/services/contexthandler/authproxy"
 	"github.com/grafana/grafana/pkg/services/login/loginservice"
 	"github.com/grafana/grafana/pkg/services/login/logintest"
+	"github.com/grafana/grafana/pkg/services/passwordpolicy/passwordpolicytest"
+	"github.com/grafana/grafana/pkg/services/publicdashboards/publicdashboardstest"
 	"github.com/grafana/grafana/pkg/services/rendering"
 	"github.com/grafana/grafana/pkg/services/sqlstore/mockstore"
+	"github.com/grafana/grafana/pkg/services/totp/totptest"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
 	"github.com/grafana/grafana/pkg/web"
@@ -185,6 +190,84 @@ func TestMiddlewareContext(t *testing.T) {
 		assert.Equal(t, "Expired API key", sc.respJson["message"])
 	})
 
+	middlewareScenario(t, "Valid API key, but not from an allowed IP address", func(t *testing.T, sc *scenarioContext) {
+		keyhash, err := util.EncodePassword("v5nAwpMafFP6znaS4urhdWDLS5511M42", "asd")
+		require.NoError(t, err)
+
+		sc.mockSQLStore.ExpectedAPIKey = &models.ApiKey{OrgId: 12, Role: models.ROLE_EDITOR, Key: keyhash, IPAllowList: []string{"192.168.1.10", "10.0.0.0/8"}}
+
+		sc.fakeReq("GET", "/").withValidApiKey()
+		sc.req.RemoteAddr = "192.168.1.11:12345"
+		sc.exec()
+
+		assert.Equal(t, 401, sc.resp.Code)
+		assert.Equal(t, contexthandler.InvalidAPIKey, sc.respJson["message"])
+	})
+
+	middlewareScenario(t, "Valid API key, but from an allowed IP address set in forwarded headers", func(t *testing.T, sc *scenarioContext) {
+		keyhash, err := util.EncodePassword("v5nAwpMafFP6znaS4urhdWDLS5511M42", "asd")
+		require.NoError(t, err)
+
+		sc.mockSQLStore.ExpectedAPIKey = &models.ApiKey{OrgId: 12, Role: models.ROLE_EDITOR, Key: keyhash, IPAllowList: []string{"192.168.1.10", "10.0.0.0/8"}}
+
+		sc.fakeReq("GET", "/").withValidApiKey()
+		sc.req.RemoteAddr = "192.168.1.11:12345"
+		sc.req.Header.Set("X-Real-IP", "192.168.1.10")
+		sc.req.Header.Set("X-Forwarded-For", "10.1.2.3")
+		sc.exec()
+
+		assert.Equal(t, 401, sc.resp.Code)
+		assert.Equal(t, contexthandler.InvalidAPIKey, sc.respJson["message"])
+	})
+
+	middlewareScenario(t, "Valid API key from an allowed IP address", func(t *testing.T, sc *scenarioContext) {
+		keyhash, err := util.EncodePassword("v5nAwpMafFP6znaS4urhdWDLS5511M42", "asd")
+		require.NoError(t, err)
+
+		sc.mockSQLStore.ExpectedAPIKey = &models.ApiKey{OrgId: 12, Role: models.ROLE_EDITOR, Key: keyhash, IPAllowList: []string{"192.168.1.10", "10.0.0.0/8"}}
+
+		sc.fakeReq("GET", "/").withValidApiKey()
+		sc.req.RemoteAddr = "10.1.2.3:12345"
+		sc.exec()
+
+		require.Equal(t, 200, sc.resp.Code)
+		assert.True(t, sc.context.IsSignedIn)
+	})
+
+	middlewareScenario(t, "Valid service account token with permissions", func(t *testing.T, sc *scenarioContext) {
+		const orgID int64 = 12
+		serviceAccountID := int64(3)
+		keyhash, err := util.EncodePassword("v5nAwpMafFP6znaS4urhdWDLS5511M42", "asd")
+		require.NoError(t, err)
+
+		sc.mockSQLStore.ExpectedAPIKey = &models.ApiKey{OrgId: orgID, Role: models.ROLE_VIEWER, Key: keyhash, ServiceAccountId: &serviceAccountID,
+			Permissions: []models.ApiKeyPermission{{Action: "dashboards:read", Scope: "folders:uid:ci"}, {Action: "dashboards:delete", Scope: "folders:*"}}}
+		sc.mockSQLStore.ExpectedSignedInUser = &models.SignedInUser{OrgId: orgID, UserId: serviceAccountID, IsServiceAccount: true}
+		sc.accessControl.GetUserPermissionsFunc = func(context.Context, *models.SignedInUser, accesscontrol.Options) ([]*accesscontrol.Permission, error) {
+			return []*accesscontrol.Permission{{Action: "dashboards:read", Scope: "folders:*"}, {Action: "dashboards:write", Scope: "folders:*"}}, nil
+		}
+
+		sc.fakeReq("GET", "/").withValidApiKey().exec()
+
+		require.Equal(t, 200, sc.resp.Code)
+		assert.Equal(t, map[int64]map[string][]string{orgID: {"dashboards:read": {"folders:uid:ci"}}}, sc.context.Permissions)
+	})
+
+	middlewareScenario(t, "Valid service account token with permissions, but access control disabled", func(t *testing.T, sc *scenarioContext) {
+		serviceAccountID := int64(3)
+		keyhash, err := util.EncodePassword("v5nAwpMafFP6znaS4urhdWDLS5511M42", "asd")
+		require.NoError(t, err)
+
+		sc.mockSQLStore.ExpectedAPIKey = &models.ApiKey{OrgId: 12, Role: models.ROLE_VIEWER, Key: keyhash, ServiceAccountId: &serviceAccountID,
+			Permissions: []models.ApiKeyPermission{{Action: "dashboards:read", Scope: "folders:uid:ci"}}}
+		sc.mockSQLStore.ExpectedSignedInUser = &models.SignedInUser{OrgId: 12, UserId: serviceAccountID, IsServiceAccount: true}
+		sc.accessControl.IsDisabledFunc = func() bool { return true }
+
+		sc.fakeReq("GET", "/").withValidApiKey().exec()
+
+		assert.Equal(t, 401, sc.resp.Code)
+	})
+
 	middlewareScenario(t, "Non-expired auth token in cookie which is not being rotated", func(
 		t *testing.T, sc *scenarioContext) {
 		const userID int64 = 12
@@ -596,7 +679,8 @@ func middlewareScenario(t *testing.T, desc string, fn scenarioFunc, cbs ...func(
 
 		sc.mockSQLStore = mockstore.NewSQLStoreMock()
 		sc.loginService = &loginservice.LoginServiceMock{}
-		ctxHdlr := getContextHandler(t, cfg, sc.mockSQLStore, sc.loginService)
+		sc.accessControl = accesscontrolmock.New()
+		ctxHdlr := getContextHandler(t, cfg, sc.mockSQLStore, sc.loginService, sc.accessControl)
 		sc.sqlStore = ctxHdlr.SQLStore
 		sc.contextHandler = ctxHdlr
 		sc.m.Use(ctxHdlr.Middleware)
@@ -626,12 +710,16 @@ func middlewareScenario(t *testing.T, desc string, fn scenarioFunc, cbs ...func(
 	})
 }
 
-func getContextHandler(t *testing.T, cfg *setting.Cfg, mockSQLStore *mockstore.SQLStoreMock, loginService *loginservice.LoginServiceMock) *contexthandler.ContextHandler {
+func getContextHandler(t *testing.T, cfg *setting.Cfg, mockSQLStore *mockstore.SQLStoreMock, loginService *loginservice.LoginServiceMock,
+	accessControl *accesscontrolmock.Mock) *contexthandler.ContextHandler {
 	t.Helper()
 
 	if cfg == nil {
 		cfg = setting.NewCfg()
 	}
+	if accessControl == nil {
+		accessControl = accesscontrolmock.New()
+	}
 	cfg.RemoteCacheOptions = &setting.RemoteCacheOptions{
 		Name: "database",
 	}
@@ -644,7 +732,7 @@ func getContextHandler(t *testing.T, cfg *setting.Cfg, mockSQLStore *mockstore.S
 	authProxy := authproxy.ProvideAuthProxy(cfg, remoteCacheSvc, loginService, mockSQLStore)
 	authenticator := &logintest.AuthenticatorFake{ExpectedUser: &models.User{}}
 	require.NoError(t, err)
-	return contexthandler.ProvideService(cfg, userAuthTokenSvc, authJWTSvc, remoteCacheSvc, renderSvc, mockSQLStore, tracer, authProxy, loginService, authenticator)
+	return contexthandler.ProvideService(cfg, userAuthTokenSvc, authJWTSvc, remoteCacheSvc, renderSvc, mockSQLStore, tracer, authProxy, loginService, authenticator, &totptest.FakeService{}, accessControl, passwordpolicytest.NewPasswordPolicyServiceFake(), publicdashboardstest.NewPublicDashboardServiceFake())
 }
 
 type fakeRenderService struct {
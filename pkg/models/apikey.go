@@ -10,6 +10,7 @@ var (
 	ErrInvalidApiKey           = errors.New("invalid API key")
 	ErrInvalidApiKeyExpiration = errors.New("negative value for SecondsToLive")
 	ErrDuplicateApiKey         = errors.New("API key, organization ID and name must be unique")
+	ErrApiKeyIPNotAllowed      = errors.New("API key is not allowed from this IP address")
 )
 
 type ApiKey struct {
@@ -22,6 +23,17 @@ type ApiKey struct {
 	Updated          time.Time
 	Expires          *int64
 	ServiceAccountId *int64
+	// Permissions restrict a service account token to a subset of the permissions of its service account
+	Permissions []ApiKeyPermission `xorm:"permissions"`
+	// IPAllowList restricts a service account token to the given IP addresses and CIDR ranges
+	IPAllowList []string   `xorm:"ip_allow_list"`
+	LastUsedAt  *time.Time `xorm:"last_used_at"`
+	LastUsedIP  string     `xorm:"last_used_ip"`
+}
+
+type ApiKeyPermission struct {
+	Action string `json:"action"`
+	Scope  string `json:"scope"`
 }
 
 // ---------------------
@@ -41,6 +53,12 @@ type DeleteApiKeyCommand struct {
 	OrgId int64 `json:"-"`
 }
 
+type UpdateApiKeyLastUsedCommand struct {
+	Id         int64
+	LastUsedAt time.Time
+	LastUsedIP string
+}
+
 // ----------------------
 // QUERIES
 
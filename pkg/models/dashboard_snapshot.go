@@ -1,11 +1,14 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 )
 
+var ErrDashboardSnapshotQuotaReached = errors.New("snapshot storage quota of the organization is reached")
+
 // DashboardSnapshot model
 type DashboardSnapshot struct {
 	Id                int64
@@ -24,6 +27,10 @@ type DashboardSnapshot struct {
 
 	Dashboard          *simplejson.Json
 	DashboardEncrypted []byte
+
+	// BlobKey is the key of the compressed and encrypted dashboard in the snapshot storage
+	BlobKey  string
+	BlobSize int64
 }
 
 // DashboardSnapshotDTO without dashboard map
@@ -76,6 +83,10 @@ type CreateDashboardSnapshotCommand struct {
 	UserId int64 `json:"-"`
 
 	DashboardEncrypted []byte `json:"-"`
+	BlobKey            string `json:"-"`
+	BlobSize           int64  `json:"-"`
+	// MaxOrgSize is the storage available for the snapshots of the organization in bytes, nil for no limit
+	MaxOrgSize *int64 `json:"-"`
 
 	Result *DashboardSnapshot
 }
@@ -86,6 +97,26 @@ type DeleteDashboardSnapshotCommand struct {
 
 type DeleteExpiredSnapshotsCommand struct {
 	DeletedRows int64
+	// BlobKeys are the storage keys of the deleted snapshots
+	BlobKeys []string
+}
+
+type GetDashboardSnapshotsSizeQuery struct {
+	OrgId int64
+
+	Result int64
+}
+
+type GetDashboardSnapshotsWithoutBlobQuery struct {
+	Limit int
+
+	Result []*DashboardSnapshot
+}
+
+type SetDashboardSnapshotBlobCommand struct {
+	Id       int64
+	BlobKey  string
+	BlobSize int64
 }
 
 type GetDashboardSnapshotQuery struct {
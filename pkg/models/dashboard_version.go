@@ -10,6 +10,9 @@ import (
 var (
 	ErrDashboardVersionNotFound = errors.New("dashboard version not found")
 	ErrNoVersionsForDashboardId = errors.New("no dashboard versions found for the given DashboardId")
+
+	ErrDashboardVersionTagInvalid        = errors.New("dashboard version tag must be between 1 and 50 characters")
+	ErrDashboardVersionRetentionNotFound = errors.New("dashboard version retention not found")
 )
 
 // A DashboardVersion represents the comparable data in a dashboard, allowing
@@ -26,6 +29,7 @@ type DashboardVersion struct {
 
 	Message string           `json:"message"`
 	Data    *simplejson.Json `json:"data"`
+	Tags    []string         `json:"tags" xorm:"-"`
 }
 
 // DashboardVersionMeta extends the dashboard version model with the names
@@ -41,6 +45,7 @@ type DashboardVersionMeta struct {
 	Message       string           `json:"message"`
 	Data          *simplejson.Json `json:"data"`
 	CreatedBy     string           `json:"createdBy"`
+	Tags          []string         `json:"tags"`
 }
 
 // DashboardVersionDTO represents a dashboard version, without the dashboard
@@ -54,6 +59,27 @@ type DashboardVersionDTO struct {
 	Created       time.Time `json:"created"`
 	CreatedBy     string    `json:"createdBy"`
 	Message       string    `json:"message"`
+	Tags          []string  `json:"tags" xorm:"-"`
+}
+
+// DashboardVersionTag tags a dashboard version, which is never deleted when
+// the tag is protected.
+type DashboardVersionTag struct {
+	Id                 int64
+	DashboardVersionId int64
+	DashboardId        int64
+	Term               string
+}
+
+// DashboardVersionRetention overrides the retention policy of the dashboard
+// versions for an organization, with durations such as 7d.
+type DashboardVersionRetention struct {
+	Id         int64     `json:"-"`
+	OrgId      int64     `json:"-"`
+	KeepAll    string    `json:"keepAll"`
+	KeepDaily  string    `json:"keepDaily"`
+	KeepWeekly string    `json:"keepWeekly"`
+	Updated    time.Time `json:"updated"`
 }
 
 //
@@ -77,6 +103,12 @@ type GetDashboardVersionsQuery struct {
 	Result []*DashboardVersionDTO
 }
 
+type GetDashboardVersionRetentionQuery struct {
+	OrgId int64
+
+	Result *DashboardVersionRetention
+}
+
 //
 // Commands
 //
@@ -84,3 +116,32 @@ type GetDashboardVersionsQuery struct {
 type DeleteExpiredVersionsCommand struct {
 	DeletedRows int64
 }
+
+type AddDashboardVersionTagCommand struct {
+	Tag string `json:"tag"`
+
+	DashboardId int64 `json:"-"`
+	OrgId       int64 `json:"-"`
+	Version     int   `json:"-"`
+}
+
+type DeleteDashboardVersionTagCommand struct {
+	Tag         string
+	DashboardId int64
+	OrgId       int64
+	Version     int
+}
+
+type SaveDashboardVersionRetentionCommand struct {
+	KeepAll    string `json:"keepAll"`
+	KeepDaily  string `json:"keepDaily"`
+	KeepWeekly string `json:"keepWeekly"`
+
+	OrgId int64 `json:"-"`
+
+	Result *DashboardVersionRetention `json:"-"`
+}
+
+type DeleteDashboardVersionRetentionCommand struct {
+	OrgId int64
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/gosimple/slug"
+	"github.com/grafana/grafana/pkg/components/dashdiffs"
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
@@ -62,6 +63,14 @@ var (
 		Reason:     "A Dashboard Folder cannot be added to another folder",
 		StatusCode: 400,
 	}
+	ErrDashboardFolderInvalidParent = DashboardErr{
+		Reason:     "A folder cannot be moved into itself or one of its subfolders",
+		StatusCode: 400,
+	}
+	ErrDashboardFolderMaxDepthExceeded = DashboardErr{
+		Reason:     "Maximum folder depth reached",
+		StatusCode: 400,
+	}
 	ErrDashboardsWithSameSlugExists = DashboardErr{
 		Reason:     "Multiple dashboards with the same slug exists",
 		StatusCode: 412,
@@ -176,6 +185,55 @@ func (d UpdatePluginDashboardError) Error() string {
 	return "Dashboard belongs to plugin"
 }
 
+// DashboardMergeConflictError is returned when a dashboard saved from an outdated version cannot be merged
+// automatically with the changes saved since then.
+type DashboardMergeConflictError struct {
+	// Version is the version of the stored dashboard, to save the resolved dashboard with.
+	Version   int
+	Conflicts []*dashdiffs.Conflict
+}
+
+func (d DashboardMergeConflictError) Error() string {
+	return fmt.Sprintf("Dashboard has %d conflicting changes with version %d", len(d.Conflicts), d.Version)
+}
+
+// DashboardLintSeverity is what happens to the dashboards with the problems found by a lint rule.
+type DashboardLintSeverity string
+
+const (
+	// DashboardLintSeverityOff disables the rule.
+	DashboardLintSeverityOff DashboardLintSeverity = "off"
+	// DashboardLintSeverityWarning reports the problems, and saves the dashboards anyway.
+	DashboardLintSeverityWarning DashboardLintSeverity = "warning"
+	// DashboardLintSeverityBlocking reports the problems, and doesn't save the dashboards.
+	DashboardLintSeverityBlocking DashboardLintSeverity = "blocking"
+)
+
+// DashboardLintProblem is a problem found in a dashboard by a lint rule.
+type DashboardLintProblem struct {
+	Rule     string                `json:"rule"`
+	Severity DashboardLintSeverity `json:"severity"`
+	Message  string                `json:"message"`
+	// PanelId is the id of the panel with the problem, 0 for the problems of the dashboard itself.
+	PanelId int64 `json:"panelId,omitempty"`
+}
+
+// DashboardLintError is returned when a dashboard is not saved because lint rules of its organization
+// block it. Problems are all the problems found, including the warnings.
+type DashboardLintError struct {
+	Problems []DashboardLintProblem
+}
+
+func (d DashboardLintError) Error() string {
+	blocking := 0
+	for _, problem := range d.Problems {
+		if problem.Severity == DashboardLintSeverityBlocking {
+			blocking++
+		}
+	}
+	return fmt.Sprintf("Dashboard has %d blocking lint problems", blocking)
+}
+
 const (
 	DashTypeDB       = "db"
 	DashTypeSnapshot = "snapshot"
@@ -20,11 +20,12 @@ var (
 )
 
 type Folder struct {
-	Id      int64
-	Uid     string
-	Title   string
-	Url     string
-	Version int
+	Id       int64
+	Uid      string
+	Title    string
+	Url      string
+	Version  int
+	ParentId int64
 
 	Created time.Time
 	Updated time.Time
@@ -49,6 +50,7 @@ func DashboardToFolder(dash *Dashboard) *Folder {
 		Id:        dash.Id,
 		Uid:       dash.Uid,
 		Title:     dash.Title,
+		ParentId:  dash.FolderId,
 		HasAcl:    dash.HasAcl,
 		Url:       dash.GetUrl(),
 		Version:   dash.Version,
@@ -80,13 +82,22 @@ func (cmd *UpdateFolderCommand) UpdateDashboardModel(dashFolder *Dashboard, orgI
 	dashFolder.UpdateSlug()
 }
 
+// FolderPathItem is a folder on the path from a root folder down to a nested folder
+type FolderPathItem struct {
+	ID    int64  `json:"id"`
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
 //
 // COMMANDS
 //
 
 type CreateFolderCommand struct {
-	Uid   string `json:"uid"`
-	Title string `json:"title"`
+	Uid       string `json:"uid"`
+	Title     string `json:"title"`
+	ParentUid string `json:"parentUid"`
 
 	Result *Folder `json:"-"`
 }
@@ -100,6 +111,16 @@ type UpdateFolderCommand struct {
 	Result *Folder `json:"-"`
 }
 
+// MoveFolderCommand moves a folder, with its dashboards and subfolders, below another folder or to the root
+// when ParentUid is empty
+type MoveFolderCommand struct {
+	ParentUid string `json:"parentUid"`
+	Version   int    `json:"version"`
+	Overwrite bool   `json:"overwrite"`
+
+	Result *Folder `json:"-"`
+}
+
 //
 // QUERIES
 //
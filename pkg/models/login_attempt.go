@@ -26,6 +26,11 @@ type DeleteOldLoginAttemptsCommand struct {
 	DeletedRows int64
 }
 
+// DeleteUserLoginAttemptsCommand deletes the login attempts of a user, by any of the names it can log in with
+type DeleteUserLoginAttemptsCommand struct {
+	Usernames []string
+}
+
 // ---------------------
 // QUERIES
 
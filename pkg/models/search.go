@@ -47,21 +47,22 @@ const (
 )
 
 type Hit struct {
-	ID           int64    `json:"id"`
-	UID          string   `json:"uid"`
-	Title        string   `json:"title"`
-	URI          string   `json:"uri"`
-	URL          string   `json:"url"`
-	Slug         string   `json:"slug"`
-	Type         HitType  `json:"type"`
-	Tags         []string `json:"tags"`
-	IsStarred    bool     `json:"isStarred"`
-	FolderID     int64    `json:"folderId,omitempty"`
-	FolderUID    string   `json:"folderUid,omitempty"`
-	FolderTitle  string   `json:"folderTitle,omitempty"`
-	FolderURL    string   `json:"folderUrl,omitempty"`
-	SortMeta     int64    `json:"sortMeta"`
-	SortMetaName string   `json:"sortMetaName,omitempty"`
+	ID           int64             `json:"id"`
+	UID          string            `json:"uid"`
+	Title        string            `json:"title"`
+	URI          string            `json:"uri"`
+	URL          string            `json:"url"`
+	Slug         string            `json:"slug"`
+	Type         HitType           `json:"type"`
+	Tags         []string          `json:"tags"`
+	IsStarred    bool              `json:"isStarred"`
+	FolderID     int64             `json:"folderId,omitempty"`
+	FolderUID    string            `json:"folderUid,omitempty"`
+	FolderTitle  string            `json:"folderTitle,omitempty"`
+	FolderURL    string            `json:"folderUrl,omitempty"`
+	FolderPath   []*FolderPathItem `json:"folderPath,omitempty"`
+	SortMeta     int64             `json:"sortMeta"`
+	SortMetaName string            `json:"sortMetaName,omitempty"`
 }
 
 type HitList []*Hit
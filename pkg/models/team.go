@@ -13,6 +13,7 @@ var (
 	ErrLastTeamAdmin                        = errors.New("not allowed to remove last admin")
 	ErrNotAllowedToUpdateTeam               = errors.New("user not allowed to update team")
 	ErrNotAllowedToUpdateTeamInDifferentOrg = errors.New("user not allowed to update team in another org")
+	ErrTeamMemberExternal                   = errors.New("team member is synced from an external group")
 )
 
 // Team model
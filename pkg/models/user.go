@@ -183,6 +183,7 @@ type SignedInUser struct {
 	IsGrafanaAdmin     bool
 	IsAnonymous        bool
 	IsDisabled         bool
+	IsServiceAccount   bool
 	HelpFlags1         HelpFlags1
 	LastSeenAt         time.Time
 	Teams              []int64
@@ -34,7 +34,8 @@ type ExternalUserInfo struct {
 	Name           string
 	Groups         []string
 	OrgRoles       map[int64]RoleType
-	IsGrafanaAdmin *bool // This is a pointer to know if we should sync this or not (nil = ignore sync)
+	OrgTeams       map[int64][]string // Names of the teams of each org the user is synced to, from LDAP group mappings
+	IsGrafanaAdmin *bool              // This is a pointer to know if we should sync this or not (nil = ignore sync)
 	IsDisabled     bool
 }
 
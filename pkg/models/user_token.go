@@ -4,15 +4,26 @@ import (
 	"context"
 	"errors"
 	"net"
+	"time"
 
 	"github.com/grafana/grafana/pkg/registry"
 )
 
 // Typed errors
 var (
-	ErrUserTokenNotFound = errors.New("user token not found")
+	ErrUserTokenNotFound            = errors.New("user token not found")
+	ErrMaxConcurrentSessionsReached = errors.New("maximum number of concurrent sessions reached")
 )
 
+// Policies applied when a user reaches the maximum number of concurrent sessions
+const (
+	ConcurrentSessionsPolicyEvictOldest = "evict_oldest"
+	ConcurrentSessionsPolicyReject      = "reject"
+)
+
+// TokenRevokedReasonMaxConcurrentSessions is the revoked reason of the tokens evicted by newer sessions
+const TokenRevokedReasonMaxConcurrentSessions = "max_concurrent_sessions"
+
 // CreateTokenErr represents a token creation error; used in Enterprise
 type CreateTokenErr struct {
 	StatusCode  int
@@ -56,6 +67,8 @@ type UserToken struct {
 	CreatedAt     int64
 	UpdatedAt     int64
 	RevokedAt     int64
+	RevokedReason string
+	LastActiveAt  int64
 	UnhashedToken string
 }
 
@@ -63,6 +76,20 @@ type RevokeAuthTokenCmd struct {
 	AuthTokenId int64 `json:"authTokenId"`
 }
 
+// SearchUserTokensQuery filters the active tokens of all users
+type SearchUserTokensQuery struct {
+	// UserId only matches the tokens of the user if set
+	UserId int64
+	// ClientIPNet only matches the tokens used from the network if set
+	ClientIPNet *net.IPNet
+	// ActiveSince only matches the tokens active since the time if set
+	ActiveSince time.Time
+	// AfterId only matches the tokens with a greater id, the next page starts after the last token of a page
+	AfterId int64
+	// Limit is the maximum number of tokens returned
+	Limit int
+}
+
 // UserTokenService are used for generating and validating user tokens
 type UserTokenService interface {
 	CreateToken(ctx context.Context, user *User, clientIP net.IP, userAgent string) (*UserToken, error)
@@ -74,6 +101,8 @@ type UserTokenService interface {
 	GetUserToken(ctx context.Context, userId, userTokenId int64) (*UserToken, error)
 	GetUserTokens(ctx context.Context, userId int64) ([]*UserToken, error)
 	GetUserRevokedTokens(ctx context.Context, userId int64) ([]*UserToken, error)
+	SearchUserTokens(ctx context.Context, query *SearchUserTokensQuery) ([]*UserToken, error)
+	BatchRevokeTokens(ctx context.Context, tokenIds []int64) error
 }
 
 type UserTokenBackgroundService interface {
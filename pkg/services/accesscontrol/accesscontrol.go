@@ -3,6 +3,7 @@ package accesscontrol
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/grafana/grafana/pkg/models"
@@ -43,6 +44,35 @@ type PermissionsProvider interface {
 	GetUserPermissions(ctx context.Context, query GetUserPermissionsQuery) ([]*Permission, error)
 }
 
+// RoleService manages custom roles. Custom roles are created by users and
+// are resolved alongside the fixed roles.
+type RoleService interface {
+	GetCustomRoles(ctx context.Context, orgID int64) ([]*RoleDTO, error)
+	GetCustomRole(ctx context.Context, orgID int64, uid string) (*RoleDTO, error)
+	// CreateCustomRole creates a role, user must have all the permissions of the role.
+	CreateCustomRole(ctx context.Context, user *models.SignedInUser, cmd CreateRoleCommand) (*RoleDTO, error)
+	// UpdateCustomRole replaces a role, user must have all the permissions of the role.
+	UpdateCustomRole(ctx context.Context, user *models.SignedInUser, cmd UpdateRoleCommand) (*RoleDTO, error)
+	DeleteCustomRole(ctx context.Context, orgID int64, uid string) error
+	// AssignCustomRole assigns a role, user must have all the permissions of the role.
+	AssignCustomRole(ctx context.Context, user *models.SignedInUser, cmd RoleAssignmentCommand) error
+	UnassignCustomRole(ctx context.Context, cmd RoleAssignmentCommand) error
+}
+
+// RoleStore persists custom roles and their assignments.
+type RoleStore interface {
+	GetCustomRoles(ctx context.Context, orgID int64) ([]*RoleDTO, error)
+	GetCustomRole(ctx context.Context, orgID int64, uid string) (*RoleDTO, error)
+	CreateCustomRole(ctx context.Context, cmd CreateRoleCommand) (*RoleDTO, error)
+	UpdateCustomRole(ctx context.Context, cmd UpdateRoleCommand) (*RoleDTO, error)
+	DeleteCustomRole(ctx context.Context, orgID int64, uid string) error
+	AddRoleAssignment(ctx context.Context, cmd RoleAssignmentCommand) error
+	RemoveRoleAssignment(ctx context.Context, cmd RoleAssignmentCommand) error
+	// GetUserCustomRolePermissions returns the permissions of the custom roles assigned to the
+	// user directly, through its teams or through its built-in roles.
+	GetUserCustomRolePermissions(ctx context.Context, query GetUserPermissionsQuery) ([]*Permission, error)
+}
+
 type TeamPermissionsService interface {
 	GetPermissions(ctx context.Context, user *models.SignedInUser, resourceID string) ([]ResourcePermission, error)
 	SetUserPermission(ctx context.Context, orgID int64, user User, resourceID, permission string) (*ResourcePermission, error)
@@ -155,6 +185,46 @@ func GroupScopesByAction(permissions []*Permission) map[string][]string {
 	return m
 }
 
+// IntersectPermissions restricts permissions grouped by action to the ones allowed by the restriction, also grouped
+// by action. An empty scope allows all the scopes of the action, so the narrower of the two scopes is kept: an
+// empty scope restricted to "dashboards:uid:1", or "dashboards:*" restricted to "dashboards:uid:1", gives
+// "dashboards:uid:1". Actions of the restriction that the permissions do not have are dropped.
+func IntersectPermissions(permissions map[string][]string, restriction map[string][]string) map[string][]string {
+	result := make(map[string][]string)
+	for action, allowed := range restriction {
+		scopes, ok := permissions[action]
+		if !ok {
+			continue
+		}
+
+		kept := map[string]bool{}
+		for _, target := range allowed {
+			for _, scope := range scopes {
+				switch {
+				case target == "":
+					kept[scope] = true
+				case scope == "":
+					kept[target] = true
+				case match(target, scope):
+					kept[scope] = true
+				case match(scope, target):
+					kept[target] = true
+				}
+			}
+		}
+
+		if len(kept) == 0 {
+			continue
+		}
+		result[action] = make([]string, 0, len(kept))
+		for scope := range kept {
+			result[action] = append(result[action], scope)
+		}
+		sort.Strings(result[action])
+	}
+	return result
+}
+
 func ValidateScope(scope string) bool {
 	prefix, last := scope[:len(scope)-1], scope[len(scope)-1]
 	// verify that last char is either ':' or '/' if last character of scope is '*'
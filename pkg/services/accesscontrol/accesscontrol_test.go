@@ -85,3 +85,62 @@ func TestGetResourcesMetadata(t *testing.T) {
 		})
 	}
 }
+
+func TestIntersectPermissions(t *testing.T) {
+	permissions := map[string][]string{
+		"dashboards:read":   {"dashboards:*", "folders:*"},
+		"dashboards:write":  {"folders:uid:general"},
+		"dashboards:create": {"folders:*"},
+		"users:read":        {"global.users:*"},
+		"server.stats:read": {""},
+		"teams:read":        {""},
+	}
+
+	tests := []struct {
+		desc        string
+		restriction map[string][]string
+		expected    map[string][]string
+	}{
+		{
+			desc:        "should keep the narrower scopes",
+			restriction: map[string][]string{"dashboards:read": {"folders:uid:ci"}},
+			expected:    map[string][]string{"dashboards:read": {"folders:uid:ci"}},
+		},
+		{
+			desc:        "should keep all the scopes of the action given an empty scope",
+			restriction: map[string][]string{"dashboards:read": {""}, "server.stats:read": {""}},
+			expected:    map[string][]string{"dashboards:read": {"dashboards:*", "folders:*"}, "server.stats:read": {""}},
+		},
+		{
+			desc:        "should keep the scopes of the permissions that are narrower than the restriction",
+			restriction: map[string][]string{"dashboards:write": {"folders:*"}},
+			expected:    map[string][]string{"dashboards:write": {"folders:uid:general"}},
+		},
+		{
+			desc:        "should keep the scope of the restriction given an unscoped permission",
+			restriction: map[string][]string{"teams:read": {"teams:id:1"}, "server.stats:read": {""}},
+			expected:    map[string][]string{"teams:read": {"teams:id:1"}, "server.stats:read": {""}},
+		},
+		{
+			desc:        "should keep the scopes of the permission given an unscoped restriction",
+			restriction: map[string][]string{"users:read": {""}},
+			expected:    map[string][]string{"users:read": {"global.users:*"}},
+		},
+		{
+			desc:        "should drop the scopes of the permissions that do not match the restriction",
+			restriction: map[string][]string{"users:read": {"users:id:1"}, "dashboards:create": {"dashboards:uid:1"}},
+			expected:    map[string][]string{},
+		},
+		{
+			desc:        "should drop the actions and scopes the permissions do not have",
+			restriction: map[string][]string{"dashboards:write": {"folders:uid:ci"}, "dashboards:delete": {"folders:*"}},
+			expected:    map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			assert.Equal(t, tt.expected, IntersectPermissions(permissions, tt.restriction))
+		})
+	}
+}
@@ -1,24 +1,51 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/api/routing"
 	"github.com/grafana/grafana/pkg/middleware"
 	"github.com/grafana/grafana/pkg/models"
 	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/web"
 )
 
 type AccessControlAPI struct {
 	RouteRegister routing.RouteRegister
 	AccessControl ac.AccessControl
+	RoleService   ac.RoleService
 }
 
 func (api *AccessControlAPI) RegisterAPIEndpoints() {
+	authorize := ac.Middleware(api.AccessControl)
+	roleScope := ac.ScopeRolesProvider.GetResourceScopeUID(ac.Parameter(":roleUID"))
+
 	// Users
 	api.RouteRegister.Get("/api/access-control/user/permissions",
 		middleware.ReqSignedIn, routing.Wrap(api.getUsersPermissions))
+
+	if api.RoleService == nil {
+		return
+	}
+
+	// Custom roles
+	api.RouteRegister.Group("/api/access-control", func(r routing.RouteRegister) {
+		r.Get("/roles", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ac.ActionRolesRead)), routing.Wrap(api.getRoles))
+		r.Post("/roles", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ac.ActionRolesWrite)), routing.Wrap(api.createRole))
+		r.Get("/roles/:roleUID", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ac.ActionRolesRead, roleScope)), routing.Wrap(api.getRole))
+		r.Put("/roles/:roleUID", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ac.ActionRolesWrite, roleScope)), routing.Wrap(api.updateRole))
+		r.Delete("/roles/:roleUID", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ac.ActionRolesDelete, roleScope)), routing.Wrap(api.deleteRole))
+
+		r.Post("/users/:userId/roles", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ac.ActionUsersRolesAdd)), routing.Wrap(api.addUserRole))
+		r.Delete("/users/:userId/roles/:roleUID", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ac.ActionUsersRolesRemove, roleScope)), routing.Wrap(api.removeUserRole))
+		r.Post("/teams/:teamId/roles", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ac.ActionTeamsRolesAdd)), routing.Wrap(api.addTeamRole))
+		r.Delete("/teams/:teamId/roles/:roleUID", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ac.ActionTeamsRolesRemove, roleScope)), routing.Wrap(api.removeTeamRole))
+		r.Post("/builtin-roles", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ac.ActionBuiltinRolesAdd)), routing.Wrap(api.addBuiltinRole))
+		r.Delete("/builtin-roles/:builtinRole/roles/:roleUID", authorize(middleware.ReqOrgAdmin, ac.EvalPermission(ac.ActionBuiltinRolesRemove, roleScope)), routing.Wrap(api.removeBuiltinRole))
+	})
 }
 
 // GET /api/access-control/user/permissions
@@ -32,3 +59,167 @@ func (api *AccessControlAPI) getUsersPermissions(c *models.ReqContext) response.
 
 	return response.JSON(http.StatusOK, ac.BuildPermissionsMap(permissions))
 }
+
+// GET /api/access-control/roles
+func (api *AccessControlAPI) getRoles(c *models.ReqContext) response.Response {
+	roles, err := api.RoleService.GetCustomRoles(c.Req.Context(), c.OrgId)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get roles", err)
+	}
+
+	return response.JSON(http.StatusOK, roles)
+}
+
+// GET /api/access-control/roles/:roleUID
+func (api *AccessControlAPI) getRole(c *models.ReqContext) response.Response {
+	role, err := api.RoleService.GetCustomRole(c.Req.Context(), c.OrgId, web.Params(c.Req)[":roleUID"])
+	if err != nil {
+		return roleErrorResponse(err, "Failed to get role")
+	}
+
+	return response.JSON(http.StatusOK, role)
+}
+
+// POST /api/access-control/roles
+func (api *AccessControlAPI) createRole(c *models.ReqContext) response.Response {
+	cmd := ac.CreateRoleCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.OrgID = c.OrgId
+
+	role, err := api.RoleService.CreateCustomRole(c.Req.Context(), c.SignedInUser, cmd)
+	if err != nil {
+		return roleErrorResponse(err, "Failed to create role")
+	}
+
+	return response.JSON(http.StatusOK, role)
+}
+
+// PUT /api/access-control/roles/:roleUID
+func (api *AccessControlAPI) updateRole(c *models.ReqContext) response.Response {
+	cmd := ac.UpdateRoleCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.OrgID = c.OrgId
+	cmd.UID = web.Params(c.Req)[":roleUID"]
+
+	role, err := api.RoleService.UpdateCustomRole(c.Req.Context(), c.SignedInUser, cmd)
+	if err != nil {
+		return roleErrorResponse(err, "Failed to update role")
+	}
+
+	return response.JSON(http.StatusOK, role)
+}
+
+// DELETE /api/access-control/roles/:roleUID
+func (api *AccessControlAPI) deleteRole(c *models.ReqContext) response.Response {
+	if err := api.RoleService.DeleteCustomRole(c.Req.Context(), c.OrgId, web.Params(c.Req)[":roleUID"]); err != nil {
+		return roleErrorResponse(err, "Failed to delete role")
+	}
+
+	return response.Success("Role deleted")
+}
+
+// POST /api/access-control/users/:userId/roles
+func (api *AccessControlAPI) addUserRole(c *models.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":userId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "userId is invalid", err)
+	}
+
+	cmd := ac.RoleAssignmentCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	return api.assignRole(c, ac.RoleAssignmentCommand{OrgID: c.OrgId, RoleUID: cmd.RoleUID, UserID: userID})
+}
+
+// DELETE /api/access-control/users/:userId/roles/:roleUID
+func (api *AccessControlAPI) removeUserRole(c *models.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":userId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "userId is invalid", err)
+	}
+
+	return api.unassignRole(c, ac.RoleAssignmentCommand{OrgID: c.OrgId, RoleUID: web.Params(c.Req)[":roleUID"], UserID: userID})
+}
+
+// POST /api/access-control/teams/:teamId/roles
+func (api *AccessControlAPI) addTeamRole(c *models.ReqContext) response.Response {
+	teamID, err := strconv.ParseInt(web.Params(c.Req)[":teamId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "teamId is invalid", err)
+	}
+
+	cmd := ac.RoleAssignmentCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	return api.assignRole(c, ac.RoleAssignmentCommand{OrgID: c.OrgId, RoleUID: cmd.RoleUID, TeamID: teamID})
+}
+
+// DELETE /api/access-control/teams/:teamId/roles/:roleUID
+func (api *AccessControlAPI) removeTeamRole(c *models.ReqContext) response.Response {
+	teamID, err := strconv.ParseInt(web.Params(c.Req)[":teamId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "teamId is invalid", err)
+	}
+
+	return api.unassignRole(c, ac.RoleAssignmentCommand{OrgID: c.OrgId, RoleUID: web.Params(c.Req)[":roleUID"], TeamID: teamID})
+}
+
+// POST /api/access-control/builtin-roles
+func (api *AccessControlAPI) addBuiltinRole(c *models.ReqContext) response.Response {
+	cmd := ac.RoleAssignmentCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	return api.assignRole(c, ac.RoleAssignmentCommand{OrgID: c.OrgId, RoleUID: cmd.RoleUID, BuiltinRole: cmd.BuiltinRole})
+}
+
+// DELETE /api/access-control/builtin-roles/:builtinRole/roles/:roleUID
+func (api *AccessControlAPI) removeBuiltinRole(c *models.ReqContext) response.Response {
+	return api.unassignRole(c, ac.RoleAssignmentCommand{
+		OrgID:       c.OrgId,
+		RoleUID:     web.Params(c.Req)[":roleUID"],
+		BuiltinRole: web.Params(c.Req)[":builtinRole"],
+	})
+}
+
+func (api *AccessControlAPI) assignRole(c *models.ReqContext, cmd ac.RoleAssignmentCommand) response.Response {
+	if err := api.RoleService.AssignCustomRole(c.Req.Context(), c.SignedInUser, cmd); err != nil {
+		return roleErrorResponse(err, "Failed to assign role")
+	}
+
+	return response.Success("Role assigned")
+}
+
+func (api *AccessControlAPI) unassignRole(c *models.ReqContext, cmd ac.RoleAssignmentCommand) response.Response {
+	if err := api.RoleService.UnassignCustomRole(c.Req.Context(), cmd); err != nil {
+		return roleErrorResponse(err, "Failed to remove role")
+	}
+
+	return response.Success("Role removed")
+}
+
+func roleErrorResponse(err error, message string) response.Response {
+	switch {
+	case errors.Is(err, ac.ErrRoleNotFound), errors.Is(err, ac.ErrRoleNotAssigned),
+		errors.Is(err, models.ErrUserNotFound), errors.Is(err, models.ErrTeamNotFound):
+		return response.Error(http.StatusNotFound, err.Error(), err)
+	case errors.Is(err, ac.ErrRoleNameTaken), errors.Is(err, ac.ErrRoleVersionMismatch), errors.Is(err, ac.ErrRoleAlreadyAssigned):
+		return response.Error(http.StatusConflict, err.Error(), err)
+	case errors.Is(err, ac.ErrPermissionEscalation):
+		return response.Error(http.StatusForbidden, err.Error(), err)
+	case errors.Is(err, ac.ErrUnknownAction), errors.Is(err, ac.ErrInvalidScope), errors.Is(err, ac.ErrRoleNameReserved),
+		errors.Is(err, ac.ErrInvalidBuiltinRole), errors.Is(err, ac.ErrRoleNameRequired):
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+
+	return response.Error(http.StatusInternalServerError, message, err)
+}
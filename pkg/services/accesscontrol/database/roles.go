@@ -0,0 +1,320 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// customRolesFilter excludes the roles managed by Grafana, fixed and basic roles are
+// only stored by Grafana Enterprise.
+var customRolesFilter = "role.name NOT LIKE '" + accesscontrol.ManagedRolePrefix + "%'" +
+	" AND role.name NOT LIKE '" + accesscontrol.FixedRolePrefix + "%'" +
+	" AND role.name NOT LIKE '" + accesscontrol.BasicRolePrefix + "%'"
+
+func (s *AccessControlStore) GetCustomRoles(ctx context.Context, orgID int64) ([]*accesscontrol.RoleDTO, error) {
+	result := make([]*accesscontrol.RoleDTO, 0)
+	err := s.sql.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		roles := make([]*accesscontrol.Role, 0)
+		if err := sess.Table("role").Where("org_id = ? AND "+customRolesFilter, orgID).Asc("name").Find(&roles); err != nil {
+			return err
+		}
+
+		for _, role := range roles {
+			dto, err := getRolePermissions(sess, role)
+			if err != nil {
+				return err
+			}
+			result = append(result, dto)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+func (s *AccessControlStore) GetCustomRole(ctx context.Context, orgID int64, uid string) (*accesscontrol.RoleDTO, error) {
+	var result *accesscontrol.RoleDTO
+	err := s.sql.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		role, err := getCustomRole(sess, orgID, uid)
+		if err != nil {
+			return err
+		}
+
+		result, err = getRolePermissions(sess, role)
+		return err
+	})
+
+	return result, err
+}
+
+func (s *AccessControlStore) CreateCustomRole(ctx context.Context, cmd accesscontrol.CreateRoleCommand) (*accesscontrol.RoleDTO, error) {
+	var result *accesscontrol.RoleDTO
+	err := s.sql.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		if err := checkRoleName(sess, cmd.OrgID, cmd.Name, 0); err != nil {
+			return err
+		}
+
+		uid, err := generateNewRoleUID(sess, cmd.OrgID)
+		if err != nil {
+			return err
+		}
+
+		role := &accesscontrol.Role{
+			OrgID:       cmd.OrgID,
+			Version:     1,
+			UID:         uid,
+			Name:        cmd.Name,
+			DisplayName: cmd.DisplayName,
+			Group:       cmd.Group,
+			Description: cmd.Description,
+			Created:     time.Now(),
+			Updated:     time.Now(),
+		}
+		if _, err := sess.Insert(role); err != nil {
+			return err
+		}
+
+		if err := insertRolePermissions(sess, role.ID, cmd.Permissions); err != nil {
+			return err
+		}
+
+		result, err = getRolePermissions(sess, role)
+		return err
+	})
+
+	return result, err
+}
+
+func (s *AccessControlStore) UpdateCustomRole(ctx context.Context, cmd accesscontrol.UpdateRoleCommand) (*accesscontrol.RoleDTO, error) {
+	var result *accesscontrol.RoleDTO
+	err := s.sql.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		role, err := getCustomRole(sess, cmd.OrgID, cmd.UID)
+		if err != nil {
+			return err
+		}
+
+		if cmd.Version <= role.Version {
+			return accesscontrol.ErrRoleVersionMismatch
+		}
+
+		if err := checkRoleName(sess, cmd.OrgID, cmd.Name, role.ID); err != nil {
+			return err
+		}
+
+		currentVersion := role.Version
+		role.Version = cmd.Version
+		role.Name = cmd.Name
+		role.DisplayName = cmd.DisplayName
+		role.Group = cmd.Group
+		role.Description = cmd.Description
+		role.Updated = time.Now()
+
+		// the version check prevents concurrent updates from both succeeding
+		affected, err := sess.ID(role.ID).Where("version = ?", currentVersion).
+			Cols("version", "name", "display_name", "group_name", "description", "updated").Update(role)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return accesscontrol.ErrRoleVersionMismatch
+		}
+
+		if _, err := sess.Exec("DELETE FROM permission WHERE role_id = ?", role.ID); err != nil {
+			return err
+		}
+
+		if err := insertRolePermissions(sess, role.ID, cmd.Permissions); err != nil {
+			return err
+		}
+
+		result, err = getRolePermissions(sess, role)
+		return err
+	})
+
+	return result, err
+}
+
+func (s *AccessControlStore) DeleteCustomRole(ctx context.Context, orgID int64, uid string) error {
+	return s.sql.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		role, err := getCustomRole(sess, orgID, uid)
+		if err != nil {
+			return err
+		}
+
+		deletes := []string{
+			"DELETE FROM permission WHERE role_id = ?",
+			"DELETE FROM user_role WHERE role_id = ?",
+			"DELETE FROM team_role WHERE role_id = ?",
+			"DELETE FROM builtin_role WHERE role_id = ?",
+			"DELETE FROM role WHERE id = ?",
+		}
+		for _, sql := range deletes {
+			if _, err := sess.Exec(sql, role.ID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *AccessControlStore) AddRoleAssignment(ctx context.Context, cmd accesscontrol.RoleAssignmentCommand) error {
+	return s.sql.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		role, err := getCustomRole(sess, cmd.OrgID, cmd.RoleUID)
+		if err != nil {
+			return err
+		}
+
+		var add roleAdder
+		switch {
+		case cmd.UserID != 0:
+			if exists, err := sess.Table("org_user").Where("org_id = ? AND user_id = ?", cmd.OrgID, cmd.UserID).Exist(); err != nil {
+				return err
+			} else if !exists {
+				return models.ErrUserNotFound
+			}
+			add = s.userAdder(sess, cmd.OrgID, cmd.UserID)
+		case cmd.TeamID != 0:
+			if exists, err := sess.Table("team").Where("org_id = ? AND id = ?", cmd.OrgID, cmd.TeamID).Exist(); err != nil {
+				return err
+			} else if !exists {
+				return models.ErrTeamNotFound
+			}
+			add = s.teamAdder(sess, cmd.OrgID, cmd.TeamID)
+		default:
+			add = s.builtInRoleAdder(sess, cmd.OrgID, cmd.BuiltinRole)
+		}
+
+		if exists, err := hasRoleAssignment(sess, role.ID, cmd); err != nil {
+			return err
+		} else if exists {
+			return accesscontrol.ErrRoleAlreadyAssigned
+		}
+
+		return add(role.ID)
+	})
+}
+
+func (s *AccessControlStore) RemoveRoleAssignment(ctx context.Context, cmd accesscontrol.RoleAssignmentCommand) error {
+	return s.sql.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		role, err := getCustomRole(sess, cmd.OrgID, cmd.RoleUID)
+		if err != nil {
+			return err
+		}
+
+		table, where, args := roleAssignmentFilter(role.ID, cmd)
+		res, err := sess.Exec(append([]interface{}{"DELETE FROM " + table + " WHERE " + where}, args...)...)
+		if err != nil {
+			return err
+		}
+
+		if affected, err := res.RowsAffected(); err != nil {
+			return err
+		} else if affected == 0 {
+			return accesscontrol.ErrRoleNotAssigned
+		}
+		return nil
+	})
+}
+
+func (s *AccessControlStore) GetUserCustomRolePermissions(ctx context.Context, query accesscontrol.GetUserPermissionsQuery) ([]*accesscontrol.Permission, error) {
+	result := make([]*accesscontrol.Permission, 0)
+	err := s.sql.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		filter, params := userRolesFilter(query.OrgID, query.UserID, query.Roles)
+
+		q := `SELECT
+			permission.id,
+			permission.role_id,
+			permission.action,
+			permission.scope,
+			permission.updated,
+			permission.created
+			FROM permission
+			INNER JOIN role ON role.id = permission.role_id
+		` + filter + " AND " + customRolesFilter
+
+		return sess.SQL(q, params...).Find(&result)
+	})
+
+	return result, err
+}
+
+func getCustomRole(sess *sqlstore.DBSession, orgID int64, uid string) (*accesscontrol.Role, error) {
+	var role accesscontrol.Role
+	has, err := sess.Table("role").Where("org_id = ? AND uid = ? AND "+customRolesFilter, orgID, uid).Get(&role)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, accesscontrol.ErrRoleNotFound
+	}
+	return &role, nil
+}
+
+func getRolePermissions(sess *sqlstore.DBSession, role *accesscontrol.Role) (*accesscontrol.RoleDTO, error) {
+	permissions := make([]accesscontrol.Permission, 0)
+	if err := sess.Where("role_id = ?", role.ID).Asc("action", "scope").Find(&permissions); err != nil {
+		return nil, err
+	}
+
+	return &accesscontrol.RoleDTO{
+		ID:          role.ID,
+		OrgID:       role.OrgID,
+		Version:     role.Version,
+		UID:         role.UID,
+		Name:        role.Name,
+		DisplayName: role.DisplayName,
+		Description: role.Description,
+		Group:       role.Group,
+		Permissions: permissions,
+		Updated:     role.Updated,
+		Created:     role.Created,
+	}, nil
+}
+
+// checkRoleName fails if another role of the organization has the name.
+func checkRoleName(sess *sqlstore.DBSession, orgID int64, name string, roleID int64) error {
+	exists, err := sess.Table("role").Where("org_id = ? AND name = ? AND id <> ?", orgID, name, roleID).Exist()
+	if err != nil {
+		return err
+	}
+	if exists {
+		return accesscontrol.ErrRoleNameTaken
+	}
+	return nil
+}
+
+func insertRolePermissions(sess *sqlstore.DBSession, roleID int64, permissions []accesscontrol.Permission) error {
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([]accesscontrol.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		rows = append(rows, accesscontrol.Permission{RoleID: roleID, Action: p.Action, Scope: p.Scope, Created: now, Updated: now})
+	}
+
+	_, err := sess.InsertMulti(&rows)
+	return err
+}
+
+func hasRoleAssignment(sess *sqlstore.DBSession, roleID int64, cmd accesscontrol.RoleAssignmentCommand) (bool, error) {
+	table, where, args := roleAssignmentFilter(roleID, cmd)
+	return sess.Table(table).Where(where, args...).Exist()
+}
+
+func roleAssignmentFilter(roleID int64, cmd accesscontrol.RoleAssignmentCommand) (string, string, []interface{}) {
+	switch {
+	case cmd.UserID != 0:
+		return "user_role", "org_id = ? AND user_id = ? AND role_id = ?", []interface{}{cmd.OrgID, cmd.UserID, roleID}
+	case cmd.TeamID != 0:
+		return "team_role", "org_id = ? AND team_id = ? AND role_id = ?", []interface{}{cmd.OrgID, cmd.TeamID, roleID}
+	default:
+		return "builtin_role", "org_id = ? AND role = ? AND role_id = ?", []interface{}{cmd.OrgID, cmd.BuiltinRole, roleID}
+	}
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/resourcepermissions/types"
+)
+
+func TestAccessControlStore_CustomRoles(t *testing.T) {
+	store, sql := setupTestEnv(t)
+	ctx := context.Background()
+
+	role, err := store.CreateCustomRole(ctx, accesscontrol.CreateRoleCommand{
+		OrgID:       1,
+		Name:        "dashboard editor",
+		Description: "Edit all dashboards",
+		Permissions: []accesscontrol.Permission{
+			{Action: "dashboards:write", Scope: "dashboards:*"},
+			{Action: "dashboards:read", Scope: "dashboards:*"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), role.Version)
+	assert.NotEmpty(t, role.UID)
+	assert.Len(t, role.Permissions, 2)
+
+	_, err = store.CreateCustomRole(ctx, accesscontrol.CreateRoleCommand{OrgID: 1, Name: "dashboard editor"})
+	assert.ErrorIs(t, err, accesscontrol.ErrRoleNameTaken)
+
+	t.Run("should update role with a newer version", func(t *testing.T) {
+		updated, err := store.UpdateCustomRole(ctx, accesscontrol.UpdateRoleCommand{
+			OrgID:       1,
+			UID:         role.UID,
+			Version:     2,
+			Name:        "dashboard writer",
+			Permissions: []accesscontrol.Permission{{Action: "dashboards:write", Scope: "dashboards:*"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), updated.Version)
+		assert.Equal(t, "dashboard writer", updated.Name)
+		assert.Len(t, updated.Permissions, 1)
+
+		_, err = store.UpdateCustomRole(ctx, accesscontrol.UpdateRoleCommand{OrgID: 1, UID: role.UID, Version: 2, Name: "stale"})
+		assert.ErrorIs(t, err, accesscontrol.ErrRoleVersionMismatch)
+	})
+
+	t.Run("should not return managed roles", func(t *testing.T) {
+		user, _ := createUserAndTeam(t, sql, 1)
+		_, err := store.SetUserResourcePermission(ctx, 1, accesscontrol.User{ID: user.Id}, types.SetResourcePermissionCommand{
+			Actions:    []string{"dashboards:read"},
+			Resource:   "dashboards",
+			ResourceID: "1",
+		}, nil)
+		require.NoError(t, err)
+
+		roles, err := store.GetCustomRoles(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, roles, 1)
+		assert.Equal(t, role.UID, roles[0].UID)
+
+		_, err = store.GetCustomRole(ctx, 2, role.UID)
+		assert.ErrorIs(t, err, accesscontrol.ErrRoleNotFound)
+	})
+
+	t.Run("should return permissions of assigned roles", func(t *testing.T) {
+		err := store.AddRoleAssignment(ctx, accesscontrol.RoleAssignmentCommand{OrgID: 1, RoleUID: role.UID, BuiltinRole: "Editor"})
+		require.NoError(t, err)
+
+		permissions, err := store.GetUserCustomRolePermissions(ctx, accesscontrol.GetUserPermissionsQuery{OrgID: 1, UserID: 100, Roles: []string{"Editor"}})
+		require.NoError(t, err)
+		assert.Len(t, permissions, 1)
+
+		permissions, err = store.GetUserCustomRolePermissions(ctx, accesscontrol.GetUserPermissionsQuery{OrgID: 1, UserID: 100, Roles: []string{"Viewer"}})
+		require.NoError(t, err)
+		assert.Len(t, permissions, 0)
+	})
+
+	t.Run("should delete role and its assignments", func(t *testing.T) {
+		require.NoError(t, store.DeleteCustomRole(ctx, 1, role.UID))
+
+		_, err := store.GetCustomRole(ctx, 1, role.UID)
+		assert.ErrorIs(t, err, accesscontrol.ErrRoleNotFound)
+
+		permissions, err := store.GetUserCustomRolePermissions(ctx, accesscontrol.GetUserPermissionsQuery{OrgID: 1, UserID: 100, Roles: []string{"Editor"}})
+		require.NoError(t, err)
+		assert.Len(t, permissions, 0)
+	})
+}
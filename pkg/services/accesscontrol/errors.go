@@ -6,4 +6,13 @@ var (
 	ErrFixedRolePrefixMissing = errors.New("fixed role should be prefixed with '" + FixedRolePrefix + "'")
 	ErrInvalidBuiltinRole     = errors.New("built-in role is not valid")
 	ErrInvalidScope           = errors.New("invalid scope")
+	ErrRoleNotFound           = errors.New("role not found")
+	ErrRoleNameRequired       = errors.New("role name is required")
+	ErrRoleNameTaken          = errors.New("a role with the same name already exists")
+	ErrRoleNameReserved       = errors.New("role name uses a reserved prefix")
+	ErrRoleVersionMismatch    = errors.New("role version must be greater than the current version")
+	ErrRoleAlreadyAssigned    = errors.New("role is already assigned")
+	ErrRoleNotAssigned        = errors.New("role is not assigned")
+	ErrUnknownAction          = errors.New("unknown action")
+	ErrPermissionEscalation   = errors.New("cannot grant permissions the user does not have")
 )
@@ -213,6 +213,39 @@ type GetUserPermissionsQuery struct {
 	Actions []string
 }
 
+// CreateRoleCommand creates a custom role in an organization.
+type CreateRoleCommand struct {
+	OrgID       int64        `json:"-"`
+	Name        string       `json:"name"`
+	DisplayName string       `json:"displayName"`
+	Description string       `json:"description"`
+	Group       string       `json:"group"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// UpdateRoleCommand replaces a custom role. Version must be greater than the
+// current version of the role, so concurrent updates do not overwrite each other.
+type UpdateRoleCommand struct {
+	OrgID       int64        `json:"-"`
+	UID         string       `json:"-"`
+	Version     int64        `json:"version"`
+	Name        string       `json:"name"`
+	DisplayName string       `json:"displayName"`
+	Description string       `json:"description"`
+	Group       string       `json:"group"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// RoleAssignmentCommand assigns a custom role to either a user, a team or a
+// built-in role.
+type RoleAssignmentCommand struct {
+	OrgID       int64  `json:"-"`
+	RoleUID     string `json:"roleUid"`
+	UserID      int64  `json:"-"`
+	TeamID      int64  `json:"-"`
+	BuiltinRole string `json:"builtinRole"`
+}
+
 // ScopeParams holds the parameters used to fill in scope templates
 type ScopeParams struct {
 	OrgID     int64
@@ -330,6 +363,20 @@ const (
 	// Plugin actions
 	ActionPluginsManage = "plugins:manage"
 
+	// Custom roles actions
+	ActionRolesRead          = "roles:read"
+	ActionRolesWrite         = "roles:write"
+	ActionRolesDelete        = "roles:delete"
+	ActionUsersRolesAdd      = "users.roles:add"
+	ActionUsersRolesRemove   = "users.roles:remove"
+	ActionTeamsRolesAdd      = "teams.roles:add"
+	ActionTeamsRolesRemove   = "teams.roles:remove"
+	ActionBuiltinRolesAdd    = "roles.builtin:add"
+	ActionBuiltinRolesRemove = "roles.builtin:remove"
+
+	// Custom roles scopes
+	ScopeRolesAll = "roles:*"
+
 	// Global Scopes
 	ScopeGlobalUsersAll = "global.users:*"
 
@@ -397,6 +444,9 @@ var (
 	// Team scope
 	ScopeTeamsID = Scope("teams", "id", Parameter(":teamId"))
 
+	// Custom roles scopes
+	ScopeRolesProvider = NewScopeProvider("roles")
+
 	// Annotation scopes
 	ScopeAnnotationsRoot             = "annotations"
 	ScopeAnnotationsProvider         = NewScopeProvider(ScopeAnnotationsRoot)
@@ -14,14 +14,15 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-func ProvideService(features featuremgmt.FeatureToggles,
-	provider accesscontrol.PermissionsProvider, routeRegister routing.RouteRegister) (*OSSAccessControlService, error) {
+func ProvideService(features featuremgmt.FeatureToggles, provider accesscontrol.PermissionsProvider,
+	roleStore accesscontrol.RoleStore, routeRegister routing.RouteRegister) (*OSSAccessControlService, error) {
 	var errDeclareRoles error
-	s := ProvideOSSAccessControl(features, provider)
+	s := ProvideOSSAccessControl(features, provider, roleStore)
 	if !s.IsDisabled() {
 		api := api.AccessControlAPI{
 			RouteRegister: routeRegister,
 			AccessControl: s,
+			RoleService:   s,
 		}
 		api.RegisterAPIEndpoints()
 
@@ -31,10 +32,12 @@ func ProvideService(features featuremgmt.FeatureToggles,
 	return s, errDeclareRoles
 }
 
-func ProvideOSSAccessControl(features featuremgmt.FeatureToggles, provider accesscontrol.PermissionsProvider) *OSSAccessControlService {
+func ProvideOSSAccessControl(features featuremgmt.FeatureToggles, provider accesscontrol.PermissionsProvider,
+	roleStore accesscontrol.RoleStore) *OSSAccessControlService {
 	s := &OSSAccessControlService{
 		features:       features,
 		provider:       provider,
+		roleStore:      roleStore,
 		log:            log.New("accesscontrol"),
 		scopeResolvers: accesscontrol.NewScopeResolvers(),
 		roles:          accesscontrol.BuildBasicRoleDefinitions(),
@@ -49,6 +52,7 @@ type OSSAccessControlService struct {
 	features       featuremgmt.FeatureToggles
 	scopeResolvers accesscontrol.ScopeResolvers
 	provider       accesscontrol.PermissionsProvider
+	roleStore      accesscontrol.RoleStore
 	registrations  accesscontrol.RegistrationList
 	roles          map[string]*accesscontrol.RoleDTO
 }
@@ -105,7 +109,7 @@ func (ac *OSSAccessControlService) GetUserRoles(ctx context.Context, user *model
 	return nil, errors.New("unsupported function") //OSS users will continue to use builtin roles via GetUserPermissions
 }
 
-// GetUserPermissions returns user permissions based on built-in roles
+// GetUserPermissions returns user permissions based on built-in roles and custom roles
 func (ac *OSSAccessControlService) GetUserPermissions(ctx context.Context, user *models.SignedInUser, _ accesscontrol.Options) ([]*accesscontrol.Permission, error) {
 	timer := prometheus.NewTimer(metrics.MAccessPermissionsSummary)
 	defer timer.ObserveDuration()
@@ -123,6 +127,19 @@ func (ac *OSSAccessControlService) GetUserPermissions(ctx context.Context, user
 	}
 
 	permissions = append(permissions, dbPermissions...)
+
+	if ac.roleStore != nil {
+		customPermissions, err := ac.roleStore.GetUserCustomRolePermissions(ctx, accesscontrol.GetUserPermissionsQuery{
+			OrgID:  user.OrgId,
+			UserID: user.UserId,
+			Roles:  ac.GetUserBuiltInRoles(user),
+		})
+		if err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, customPermissions...)
+	}
+
 	resolved := make([]*accesscontrol.Permission, 0, len(permissions))
 	keywordMutator := ac.scopeResolvers.GetScopeKeywordMutator(user)
 	for _, p := range permissions {
@@ -19,12 +19,14 @@ import (
 func setupTestEnv(t testing.TB) *OSSAccessControlService {
 	t.Helper()
 
+	store := database.ProvideService(sqlstore.InitTestDB(t))
 	ac := &OSSAccessControlService{
 		features:       featuremgmt.WithFeatures(featuremgmt.FlagAccesscontrol),
 		log:            log.New("accesscontrol"),
 		registrations:  accesscontrol.RegistrationList{},
 		scopeResolvers: accesscontrol.NewScopeResolvers(),
-		provider:       database.ProvideService(sqlstore.InitTestDB(t)),
+		provider:       store,
+		roleStore:      store,
 		roles:          accesscontrol.BuildBasicRoleDefinitions(),
 	}
 	require.NoError(t, ac.RegisterFixedRoles(context.Background()))
@@ -133,9 +135,11 @@ func TestUsageMetrics(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			store := database.ProvideService(sqlstore.InitTestDB(t))
 			s, errInitAc := ProvideService(
 				featuremgmt.WithFeatures("accesscontrol", tt.enabled),
-				database.ProvideService(sqlstore.InitTestDB(t)),
+				store,
+				store,
 				routing.NewRouteRegister(),
 			)
 			require.NoError(t, errInitAc)
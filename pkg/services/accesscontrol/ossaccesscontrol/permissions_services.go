@@ -74,6 +74,16 @@ func ProvideTeamPermissions(
 			if err != nil {
 				return err
 			}
+			// the members added by the sync of an external group are only changed by the sync
+			if !user.IsExternal {
+				isExternal, err := sqlstore.IsExternalTeamMemberHook(session, orgID, teamId, user.ID)
+				if err != nil {
+					return err
+				}
+				if isExternal {
+					return models.ErrTeamMemberExternal
+				}
+			}
 			switch permission {
 			case "Member":
 				return sqlstore.AddOrUpdateTeamMemberHook(session, user.ID, orgID, teamId, user.IsExternal, 0)
@@ -139,14 +149,7 @@ func ProvideDashboardPermissions(
 			if err != nil {
 				return nil, err
 			}
-			if dashboard.FolderId > 0 {
-				query := &models.GetDashboardQuery{Id: dashboard.FolderId, OrgId: orgID}
-				if err := sql.GetDashboard(ctx, query); err != nil {
-					return nil, err
-				}
-				return []string{dashboards.ScopeFoldersProvider.GetResourceScopeUID(query.Result.Uid)}, nil
-			}
-			return []string{}, nil
+			return folderPathScopes(ctx, sql, orgID, dashboard.FolderId)
 		},
 		Assignments: resourcepermissions.Assignments{
 			Users:        true,
@@ -197,6 +200,15 @@ func ProvideFolderPermissions(
 
 			return nil
 		},
+		InheritedScopePrefixes: []string{"folders:uid:"},
+		InheritedScopesSolver: func(ctx context.Context, orgID int64, resourceID string) ([]string, error) {
+			// nested folders inherit the permissions of their parents
+			query := &models.GetDashboardQuery{Uid: resourceID, OrgId: orgID}
+			if err := sql.GetDashboard(ctx, query); err != nil {
+				return nil, err
+			}
+			return folderPathScopes(ctx, sql, orgID, query.Result.FolderId)
+		},
 		Assignments: resourcepermissions.Assignments{
 			Users:        true,
 			Teams:        true,
@@ -249,3 +261,23 @@ func (e DatasourcePermissionsService) SetPermissions(ctx context.Context, orgID
 func (e DatasourcePermissionsService) MapActions(permission accesscontrol.ResourcePermission) string {
 	return ""
 }
+
+// folderPathScopes returns the scopes of the folder and of its parents, from the root folder to the folder itself.
+// The folder itself comes last, so it is the inherited scope used to authorize requests to the permissions API.
+func folderPathScopes(ctx context.Context, sql *sqlstore.SQLStore, orgID, folderID int64) ([]string, error) {
+	if folderID == 0 {
+		return []string{}, nil
+	}
+
+	paths, err := sql.GetFolderPaths(ctx, orgID, []int64{folderID})
+	if err != nil {
+		return nil, err
+	}
+
+	path := paths[folderID]
+	scopes := make([]string, 0, len(path))
+	for _, folder := range path {
+		scopes = append(scopes, dashboards.ScopeFoldersProvider.GetResourceScopeUID(folder.UID))
+	}
+	return scopes, nil
+}
@@ -0,0 +1,157 @@
+package ossaccesscontrol
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+var reservedRolePrefixes = []string{accesscontrol.FixedRolePrefix, accesscontrol.ManagedRolePrefix, accesscontrol.BasicRolePrefix}
+
+func (ac *OSSAccessControlService) GetCustomRoles(ctx context.Context, orgID int64) ([]*accesscontrol.RoleDTO, error) {
+	return ac.roleStore.GetCustomRoles(ctx, orgID)
+}
+
+func (ac *OSSAccessControlService) GetCustomRole(ctx context.Context, orgID int64, uid string) (*accesscontrol.RoleDTO, error) {
+	return ac.roleStore.GetCustomRole(ctx, orgID, uid)
+}
+
+// CreateCustomRole validates and creates a custom role
+func (ac *OSSAccessControlService) CreateCustomRole(ctx context.Context, user *models.SignedInUser, cmd accesscontrol.CreateRoleCommand) (*accesscontrol.RoleDTO, error) {
+	permissions, err := ac.validateCustomRole(ctx, user, cmd.Name, cmd.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Permissions = permissions
+
+	return ac.roleStore.CreateCustomRole(ctx, cmd)
+}
+
+// UpdateCustomRole validates and replaces a custom role
+func (ac *OSSAccessControlService) UpdateCustomRole(ctx context.Context, user *models.SignedInUser, cmd accesscontrol.UpdateRoleCommand) (*accesscontrol.RoleDTO, error) {
+	permissions, err := ac.validateCustomRole(ctx, user, cmd.Name, cmd.Permissions)
+	if err != nil {
+		return nil, err
+	}
+	cmd.Permissions = permissions
+
+	return ac.roleStore.UpdateCustomRole(ctx, cmd)
+}
+
+func (ac *OSSAccessControlService) DeleteCustomRole(ctx context.Context, orgID int64, uid string) error {
+	return ac.roleStore.DeleteCustomRole(ctx, orgID, uid)
+}
+
+// AssignCustomRole assigns a custom role to a user, a team or a built-in role
+func (ac *OSSAccessControlService) AssignCustomRole(ctx context.Context, user *models.SignedInUser, cmd accesscontrol.RoleAssignmentCommand) error {
+	if cmd.UserID == 0 && cmd.TeamID == 0 {
+		if err := accesscontrol.ValidateBuiltInRoles([]string{cmd.BuiltinRole}); err != nil {
+			return err
+		}
+	}
+
+	role, err := ac.roleStore.GetCustomRole(ctx, cmd.OrgID, cmd.RoleUID)
+	if err != nil {
+		return err
+	}
+
+	if err := ac.checkEscalation(ctx, user, role.Permissions); err != nil {
+		return err
+	}
+
+	return ac.roleStore.AddRoleAssignment(ctx, cmd)
+}
+
+func (ac *OSSAccessControlService) UnassignCustomRole(ctx context.Context, cmd accesscontrol.RoleAssignmentCommand) error {
+	return ac.roleStore.RemoveRoleAssignment(ctx, cmd)
+}
+
+// validateCustomRole checks the name and permissions of a role and returns the
+// permissions without duplicates.
+func (ac *OSSAccessControlService) validateCustomRole(ctx context.Context, user *models.SignedInUser, name string, permissions []accesscontrol.Permission) ([]accesscontrol.Permission, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, accesscontrol.ErrRoleNameRequired
+	}
+	for _, prefix := range reservedRolePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return nil, fmt.Errorf("'%s' %w", prefix, accesscontrol.ErrRoleNameReserved)
+		}
+	}
+
+	known := ac.knownActions()
+	seen := map[accesscontrol.Permission]bool{}
+	result := make([]accesscontrol.Permission, 0, len(permissions))
+	for _, p := range permissions {
+		if _, ok := known[p.Action]; !ok {
+			return nil, fmt.Errorf("%w: %s", accesscontrol.ErrUnknownAction, p.Action)
+		}
+		if p.Scope != "" && !accesscontrol.ValidateScope(p.Scope) {
+			return nil, fmt.Errorf("%w: %s", accesscontrol.ErrInvalidScope, p.Scope)
+		}
+
+		p = p.OSSPermission()
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		result = append(result, p)
+	}
+
+	if err := ac.checkEscalation(ctx, user, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// checkEscalation fails if the user does not have all the permissions, so that
+// users cannot grant more access than they have.
+func (ac *OSSAccessControlService) checkEscalation(ctx context.Context, user *models.SignedInUser, permissions []accesscontrol.Permission) error {
+	for _, p := range permissions {
+		var evaluator accesscontrol.Evaluator
+		if p.Scope == "" {
+			evaluator = accesscontrol.EvalPermission(p.Action)
+		} else {
+			evaluator = accesscontrol.EvalPermission(p.Action, p.Scope)
+		}
+
+		hasAccess, err := ac.Evaluate(ctx, user, evaluator)
+		if err != nil {
+			return err
+		}
+		if !hasAccess {
+			return fmt.Errorf("%w: %s", accesscontrol.ErrPermissionEscalation, evaluator.String())
+		}
+	}
+	return nil
+}
+
+// knownActions returns the actions of the fixed roles, the basic roles and
+// the managed permissions.
+func (ac *OSSAccessControlService) knownActions() map[string]struct{} {
+	actions := map[string]struct{}{}
+
+	ac.registrations.Range(func(registration accesscontrol.RoleRegistration) bool {
+		for _, p := range registration.Role.Permissions {
+			actions[p.Action] = struct{}{}
+		}
+		return true
+	})
+
+	for _, role := range ac.roles {
+		for _, p := range role.Permissions {
+			actions[p.Action] = struct{}{}
+		}
+	}
+
+	for _, list := range [][]string{TeamAdminActions, DashboardAdminActions, FolderAdminActions} {
+		for _, action := range list {
+			actions[action] = struct{}{}
+		}
+	}
+
+	return actions
+}
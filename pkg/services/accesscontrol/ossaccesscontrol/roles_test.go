@@ -0,0 +1,167 @@
+package ossaccesscontrol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/database"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func setupCustomRolesTestEnv(t *testing.T) (*OSSAccessControlService, *sqlstore.SQLStore) {
+	t.Helper()
+
+	sql := sqlstore.InitTestDB(t)
+	store := database.ProvideService(sql)
+	ac := &OSSAccessControlService{
+		features:       featuremgmt.WithFeatures(featuremgmt.FlagAccesscontrol),
+		log:            log.New("accesscontrol"),
+		registrations:  accesscontrol.RegistrationList{},
+		scopeResolvers: accesscontrol.NewScopeResolvers(),
+		provider:       store,
+		roleStore:      store,
+		roles:          accesscontrol.BuildBasicRoleDefinitions(),
+	}
+	require.NoError(t, accesscontrol.DeclareFixedRoles(ac))
+	require.NoError(t, ac.DeclareFixedRoles(accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Name: "fixed:dashboards:writer",
+			Permissions: []accesscontrol.Permission{
+				{Action: "dashboards:read", Scope: "dashboards:*"},
+				{Action: "dashboards:write", Scope: "dashboards:*"},
+			},
+		},
+		Grants: []string{string(models.ROLE_EDITOR)},
+	}, accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Name: "fixed:datasources:reader",
+			Permissions: []accesscontrol.Permission{
+				{Action: "datasources:read", Scope: "datasources:*"},
+			},
+		},
+		Grants: []string{string(models.ROLE_ADMIN)},
+	}))
+	require.NoError(t, ac.RegisterFixedRoles(context.Background()))
+	return ac, sql
+}
+
+func TestOSSAccessControlService_CustomRoles(t *testing.T) {
+	admin := &models.SignedInUser{UserId: 1, OrgId: 1, OrgRole: models.ROLE_ADMIN}
+	dashboardEditor := []accesscontrol.Permission{
+		{Action: "dashboards:read", Scope: "dashboards:*"},
+		{Action: "dashboards:write", Scope: "dashboards:*"},
+		{Action: "dashboards:write", Scope: "dashboards:*"},
+	}
+
+	t.Run("should reject unknown actions", func(t *testing.T) {
+		ac, _ := setupCustomRolesTestEnv(t)
+
+		_, err := ac.CreateCustomRole(context.Background(), admin, accesscontrol.CreateRoleCommand{
+			OrgID:       1,
+			Name:        "custom",
+			Permissions: []accesscontrol.Permission{{Action: "dashboards:fly"}},
+		})
+		assert.ErrorIs(t, err, accesscontrol.ErrUnknownAction)
+	})
+
+	t.Run("should reject reserved names", func(t *testing.T) {
+		ac, _ := setupCustomRolesTestEnv(t)
+
+		_, err := ac.CreateCustomRole(context.Background(), admin, accesscontrol.CreateRoleCommand{OrgID: 1, Name: "fixed:custom"})
+		assert.ErrorIs(t, err, accesscontrol.ErrRoleNameReserved)
+	})
+
+	t.Run("should reject permissions the user does not have", func(t *testing.T) {
+		ac, _ := setupCustomRolesTestEnv(t)
+		editor := &models.SignedInUser{UserId: 2, OrgId: 1, OrgRole: models.ROLE_EDITOR}
+
+		_, err := ac.CreateCustomRole(context.Background(), editor, accesscontrol.CreateRoleCommand{
+			OrgID:       1,
+			Name:        "datasources reader",
+			Permissions: []accesscontrol.Permission{{Action: "datasources:read", Scope: "datasources:*"}},
+		})
+		assert.ErrorIs(t, err, accesscontrol.ErrPermissionEscalation)
+	})
+
+	t.Run("should resolve permissions of assigned roles", func(t *testing.T) {
+		ac, sql := setupCustomRolesTestEnv(t)
+
+		role, err := ac.CreateCustomRole(context.Background(), admin, accesscontrol.CreateRoleCommand{
+			OrgID:       1,
+			Name:        "dashboard editor",
+			Permissions: dashboardEditor,
+		})
+		require.NoError(t, err)
+		assert.Len(t, role.Permissions, 2)
+
+		user, err := sql.CreateUser(context.Background(), models.CreateUserCommand{Login: "viewer", OrgId: 1})
+		require.NoError(t, err)
+		viewer := &models.SignedInUser{UserId: user.Id, OrgId: 1, OrgRole: models.ROLE_VIEWER}
+
+		hasAccess, err := ac.Evaluate(context.Background(), viewer, accesscontrol.EvalPermission("dashboards:write", "dashboards:uid:abc"))
+		require.NoError(t, err)
+		assert.False(t, hasAccess)
+
+		err = ac.AssignCustomRole(context.Background(), admin, accesscontrol.RoleAssignmentCommand{OrgID: 1, RoleUID: role.UID, UserID: user.Id})
+		require.NoError(t, err)
+
+		viewer.Permissions = nil
+		hasAccess, err = ac.Evaluate(context.Background(), viewer, accesscontrol.EvalPermission("dashboards:write", "dashboards:uid:abc"))
+		require.NoError(t, err)
+		assert.True(t, hasAccess)
+
+		hasAccess, err = ac.Evaluate(context.Background(), viewer, accesscontrol.EvalPermission("datasources:read", "datasources:*"))
+		require.NoError(t, err)
+		assert.False(t, hasAccess)
+
+		err = ac.AssignCustomRole(context.Background(), admin, accesscontrol.RoleAssignmentCommand{OrgID: 1, RoleUID: role.UID, UserID: user.Id})
+		assert.ErrorIs(t, err, accesscontrol.ErrRoleAlreadyAssigned)
+
+		require.NoError(t, ac.UnassignCustomRole(context.Background(), accesscontrol.RoleAssignmentCommand{OrgID: 1, RoleUID: role.UID, UserID: user.Id}))
+		viewer.Permissions = nil
+		hasAccess, err = ac.Evaluate(context.Background(), viewer, accesscontrol.EvalPermission("dashboards:write", "dashboards:uid:abc"))
+		require.NoError(t, err)
+		assert.False(t, hasAccess)
+	})
+
+	t.Run("should resolve permissions of roles assigned to teams and built-in roles", func(t *testing.T) {
+		ac, sql := setupCustomRolesTestEnv(t)
+
+		role, err := ac.CreateCustomRole(context.Background(), admin, accesscontrol.CreateRoleCommand{
+			OrgID:       1,
+			Name:        "dashboard editor",
+			Permissions: dashboardEditor,
+		})
+		require.NoError(t, err)
+
+		user, err := sql.CreateUser(context.Background(), models.CreateUserCommand{Login: "member", OrgId: 1})
+		require.NoError(t, err)
+		team, err := sql.CreateTeam("team", "", 1)
+		require.NoError(t, err)
+		require.NoError(t, sql.AddTeamMember(user.Id, 1, team.Id, false, models.PERMISSION_VIEW))
+
+		err = ac.AssignCustomRole(context.Background(), admin, accesscontrol.RoleAssignmentCommand{OrgID: 1, RoleUID: role.UID, TeamID: team.Id})
+		require.NoError(t, err)
+
+		permissions, err := ac.GetUserPermissions(context.Background(), &models.SignedInUser{UserId: user.Id, OrgId: 1, OrgRole: models.ROLE_VIEWER}, accesscontrol.Options{})
+		require.NoError(t, err)
+		assert.Contains(t, accesscontrol.GroupScopesByAction(permissions)["dashboards:write"], "dashboards:*")
+
+		err = ac.AssignCustomRole(context.Background(), admin, accesscontrol.RoleAssignmentCommand{OrgID: 1, RoleUID: role.UID, BuiltinRole: "Viewer"})
+		require.NoError(t, err)
+
+		permissions, err = ac.GetUserPermissions(context.Background(), &models.SignedInUser{UserId: 100, OrgId: 1, OrgRole: models.ROLE_VIEWER}, accesscontrol.Options{})
+		require.NoError(t, err)
+		assert.Contains(t, accesscontrol.GroupScopesByAction(permissions)["dashboards:write"], "dashboards:*")
+
+		err = ac.AssignCustomRole(context.Background(), admin, accesscontrol.RoleAssignmentCommand{OrgID: 1, RoleUID: role.UID, BuiltinRole: "Owner"})
+		assert.ErrorIs(t, err, accesscontrol.ErrInvalidBuiltinRole)
+	})
+}
@@ -185,6 +185,62 @@ var (
 			},
 		}),
 	}
+
+	rolesReaderRole = RoleDTO{
+		Name:        "fixed:roles:reader",
+		DisplayName: "Role reader",
+		Description: "Read custom roles of the organization.",
+		Group:       "Roles",
+		Version:     1,
+		Permissions: []Permission{
+			{
+				Action: ActionRolesRead,
+				Scope:  ScopeRolesAll,
+			},
+		},
+	}
+
+	rolesWriterRole = RoleDTO{
+		Name:        "fixed:roles:writer",
+		DisplayName: "Role writer",
+		Description: "Create, update and delete custom roles of the organization and assign them to users, teams and basic roles.",
+		Group:       "Roles",
+		Version:     1,
+		Permissions: ConcatPermissions(rolesReaderRole.Permissions, []Permission{
+			{
+				Action: ActionRolesWrite,
+				Scope:  ScopeRolesAll,
+			},
+			{
+				Action: ActionRolesDelete,
+				Scope:  ScopeRolesAll,
+			},
+			{
+				Action: ActionUsersRolesAdd,
+				Scope:  ScopeRolesAll,
+			},
+			{
+				Action: ActionUsersRolesRemove,
+				Scope:  ScopeRolesAll,
+			},
+			{
+				Action: ActionTeamsRolesAdd,
+				Scope:  ScopeRolesAll,
+			},
+			{
+				Action: ActionTeamsRolesRemove,
+				Scope:  ScopeRolesAll,
+			},
+			{
+				Action: ActionBuiltinRolesAdd,
+				Scope:  ScopeRolesAll,
+			},
+			{
+				Action: ActionBuiltinRolesRemove,
+				Scope:  ScopeRolesAll,
+			},
+		}),
+	}
 )
 
 // Declare OSS roles to the accesscontrol service
@@ -221,9 +277,17 @@ func DeclareFixedRoles(ac AccessControl) error {
 		Role:   usersWriterRole,
 		Grants: []string{RoleGrafanaAdmin},
 	}
+	rolesReader := RoleRegistration{
+		Role:   rolesReaderRole,
+		Grants: []string{string(models.ROLE_ADMIN)},
+	}
+	rolesWriter := RoleRegistration{
+		Role:   rolesWriterRole,
+		Grants: []string{string(models.ROLE_ADMIN)},
+	}
 
 	return ac.DeclareFixedRoles(ldapReader, ldapWriter, orgUsersReader, orgUsersWriter,
-		settingsReader, statsReader, usersReader, usersWriter)
+		settingsReader, statsReader, usersReader, usersWriter, rolesReader, rolesWriter)
 }
 
 func ConcatPermissions(permissions ...[]Permission) []Permission {
@@ -0,0 +1,297 @@
+package annotationwebhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+const (
+	AdapterGeneric      = "generic"
+	AdapterGitHub       = "github"
+	AdapterAlertmanager = "alertmanager"
+)
+
+// Adapter converts the payloads sent by an external tool to events.
+type Adapter interface {
+	// Validate checks that a webhook can use the adapter.
+	Validate(webhook *Webhook) error
+	// Events returns the events of a payload.
+	Events(webhook *Webhook, payload *simplejson.Json) ([]Event, error)
+}
+
+// genericAdapter converts JSON payloads with the field mapping of the webhook.
+type genericAdapter struct{}
+
+func (genericAdapter) Validate(webhook *Webhook) error {
+	if webhook.Mapping == nil || webhook.Mapping.Text == "" {
+		return ErrMissingMapping
+	}
+	return nil
+}
+
+func (genericAdapter) Events(webhook *Webhook, payload *simplejson.Json) ([]Event, error) {
+	m := webhook.Mapping
+	items := []*simplejson.Json{payload}
+	if m.Events != "" {
+		items = arrayValue(lookup(payload, m.Events))
+	} else if _, isArray := payload.Interface().([]interface{}); isArray {
+		items = arrayValue(payload)
+	}
+
+	events := make([]Event, 0, len(items))
+	for _, item := range items {
+		event := Event{Kind: EventPoint, Text: stringValue(lookup(item, m.Text))}
+		if m.ID != "" {
+			event.ID = stringValue(lookup(item, m.ID))
+		}
+		if m.Tags != "" {
+			event.Tags = tagsValue(lookup(item, m.Tags))
+		}
+		if m.Status != "" && event.ID != "" {
+			status := stringValue(lookup(item, m.Status))
+			switch {
+			case contains(m.StartValues, status):
+				event.Kind = EventStart
+			case contains(m.EndValues, status):
+				event.Kind = EventEnd
+			}
+		}
+
+		var err error
+		if m.Time != "" {
+			if event.Time, err = timeValue(lookup(item, m.Time)); err != nil {
+				return nil, err
+			}
+		}
+		if m.TimeEnd != "" {
+			if event.TimeEnd, err = timeValue(lookup(item, m.TimeEnd)); err != nil {
+				return nil, err
+			}
+		}
+		// the time of an end event is the end of its region, unless the end time is mapped
+		if event.Kind == EventEnd && event.TimeEnd == 0 {
+			event.Time, event.TimeEnd = 0, event.Time
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// gitHubAdapter converts the deployment and deployment_status events of GitHub to regions lasting from the
+// creation of a deployment to its last status.
+type gitHubAdapter struct{}
+
+func (gitHubAdapter) Validate(*Webhook) error {
+	return nil
+}
+
+func (gitHubAdapter) Events(_ *Webhook, payload *simplejson.Json) ([]Event, error) {
+	deployment, ok := payload.CheckGet("deployment")
+	if !ok {
+		// GitHub sends a ping when the webhook is added
+		if _, isPing := payload.CheckGet("zen"); isPing {
+			return nil, nil
+		}
+		return nil, ErrUnsupportedPayload
+	}
+
+	repository := payload.Get("repository").Get("full_name").MustString()
+	environment := deployment.Get("environment").MustString()
+	text := fmt.Sprintf("Deployment of %s %s to %s", repository, deployment.Get("ref").MustString(), environment)
+	event := Event{
+		ID:   stringValue(deployment.Get("id")),
+		Kind: EventStart,
+		Tags: []string{"github", "deployment", "repository:" + repository, "environment:" + environment},
+	}
+
+	status, ok := payload.CheckGet("deployment_status")
+	if !ok {
+		created, err := timeValue(deployment.Get("created_at"))
+		if err != nil {
+			return nil, err
+		}
+		event.Time = created
+		event.Text = withDescription(text, deployment.Get("description").MustString())
+		return []Event{event}, nil
+	}
+
+	state := status.Get("state").MustString()
+	at := status.Get("updated_at")
+	if at.Interface() == nil {
+		at = status.Get("created_at")
+	}
+	statusTime, err := timeValue(at)
+	if err != nil {
+		return nil, err
+	}
+	event.Text = withDescription(fmt.Sprintf("%s: %s", text, state), status.Get("description").MustString())
+	event.Tags = append(event.Tags, "state:"+state)
+	switch state {
+	case "pending", "queued", "in_progress":
+		event.Time = statusTime
+	default:
+		event.Kind = EventEnd
+		event.TimeEnd = statusTime
+	}
+	return []Event{event}, nil
+}
+
+// alertmanagerAdapter converts the alerts of the Alertmanager webhook notifications to regions lasting from when
+// they start firing until they are resolved.
+type alertmanagerAdapter struct{}
+
+func (alertmanagerAdapter) Validate(*Webhook) error {
+	return nil
+}
+
+func (alertmanagerAdapter) Events(_ *Webhook, payload *simplejson.Json) ([]Event, error) {
+	alerts, ok := payload.CheckGet("alerts")
+	if !ok {
+		return nil, ErrUnsupportedPayload
+	}
+
+	events := make([]Event, 0)
+	for _, alert := range arrayValue(alerts) {
+		labels := alert.Get("labels")
+		labelTags := tagsValue(labels)
+		tags := append([]string{"alertmanager"}, labelTags...)
+
+		id := alert.Get("fingerprint").MustString()
+		if id == "" {
+			id = strings.Join(labelTags, ",")
+		}
+
+		text := labels.Get("alertname").MustString()
+		annotations := alert.Get("annotations")
+		if summary := annotations.Get("summary").MustString(annotations.Get("description").MustString()); summary != "" {
+			text = fmt.Sprintf("%s: %s", text, summary)
+		}
+
+		startsAt, err := timeValue(alert.Get("startsAt"))
+		if err != nil {
+			return nil, err
+		}
+		event := Event{ID: id, Kind: EventStart, Time: startsAt, Text: text, Tags: tags}
+		if alert.Get("status").MustString() == "resolved" {
+			event.Kind = EventEnd
+			if event.TimeEnd, err = timeValue(alert.Get("endsAt")); err != nil {
+				return nil, err
+			}
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// lookup returns the value at the path of keys separated by dots, where array elements are referenced by their
+// index.
+func lookup(data *simplejson.Json, path string) *simplejson.Json {
+	for _, key := range strings.Split(path, ".") {
+		if i, err := strconv.Atoi(key); err == nil {
+			if _, isArray := data.Interface().([]interface{}); isArray {
+				data = data.GetIndex(i)
+				continue
+			}
+		}
+		data = data.Get(key)
+	}
+	return data
+}
+
+func arrayValue(data *simplejson.Json) []*simplejson.Json {
+	values := data.MustArray()
+	items := make([]*simplejson.Json, 0, len(values))
+	for _, v := range values {
+		items = append(items, simplejson.NewFromAny(v))
+	}
+	return items
+}
+
+func stringValue(data *simplejson.Json) string {
+	switch v := data.Interface().(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool, int, int64:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// tagsValue returns a string as a tag, the strings of an array as tags, and the entries of an object as key:value
+// tags.
+func tagsValue(data *simplejson.Json) []string {
+	tags := make([]string, 0)
+	switch v := data.Interface().(type) {
+	case []interface{}:
+		for _, item := range v {
+			if tag := stringValue(simplejson.NewFromAny(item)); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	case map[string]interface{}:
+		for key, value := range v {
+			tags = append(tags, key+":"+stringValue(simplejson.NewFromAny(value)))
+		}
+		sort.Strings(tags)
+	default:
+		if tag := stringValue(data); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// timeValue returns the time of a value in epoch milliseconds. Numbers are in epoch seconds or milliseconds, and
+// strings are numbers or RFC 3339 times. Null values and zero times are 0.
+func timeValue(data *simplejson.Json) (int64, error) {
+	s := stringValue(data)
+	if s == "" {
+		return 0, nil
+	}
+
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		// epoch seconds until the year 33658
+		if n < 1e12 {
+			n *= 1000
+		}
+		return int64(n), nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0, ErrInvalidTime
+	}
+	// Alertmanager sends the zero time as the end of the alerts that are still firing
+	if t.Year() <= 1 {
+		return 0, nil
+	}
+	return t.UnixMilli(), nil
+}
+
+func withDescription(text string, description string) string {
+	if description == "" {
+		return text
+	}
+	return text + "\n" + description
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
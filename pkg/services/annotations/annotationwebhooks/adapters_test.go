@@ -0,0 +1,120 @@
+package annotationwebhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+func mustJSON(t *testing.T, s string) *simplejson.Json {
+	t.Helper()
+	data, err := simplejson.NewJson([]byte(s))
+	require.NoError(t, err)
+	return data
+}
+
+func TestGenericAdapter(t *testing.T) {
+	webhook := &Webhook{Mapping: &FieldMapping{
+		Events:      "builds",
+		ID:          "id",
+		Text:        "pipeline.name",
+		Time:        "at",
+		Tags:        "labels",
+		Status:      "state",
+		StartValues: []string{"running"},
+		EndValues:   []string{"passed", "failed"},
+	}}
+	payload := mustJSON(t, `{"builds": [
+		{"id": 12, "pipeline": {"name": "Build api"}, "at": 1600000000, "labels": {"service": "api"}, "state": "running"},
+		{"id": 12, "pipeline": {"name": "Build api"}, "at": "2020-09-13T12:36:40Z", "labels": ["ci"], "state": "passed"},
+		{"pipeline": {"name": "Release"}, "at": 1600000000000, "state": "passed"}
+	]}`)
+
+	events, err := genericAdapter{}.Events(webhook, payload)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	assert.Equal(t, Event{ID: "12", Kind: EventStart, Time: 1600000000000, Text: "Build api", Tags: []string{"service:api"}}, events[0])
+	// the time of end events is the end of their region
+	assert.Equal(t, Event{ID: "12", Kind: EventEnd, TimeEnd: 1600000600000, Text: "Build api", Tags: []string{"ci"}}, events[1])
+	// events without id are not correlated
+	assert.Equal(t, EventPoint, events[2].Kind)
+	assert.Equal(t, int64(1600000000000), events[2].Time)
+
+	t.Run("invalid times are rejected", func(t *testing.T) {
+		_, err := genericAdapter{}.Events(webhook, mustJSON(t, `{"builds": [{"at": "yesterday"}]}`))
+		require.ErrorIs(t, err, ErrInvalidTime)
+	})
+
+	t.Run("the text must be mapped", func(t *testing.T) {
+		require.ErrorIs(t, genericAdapter{}.Validate(&Webhook{}), ErrMissingMapping)
+		require.ErrorIs(t, genericAdapter{}.Validate(&Webhook{Mapping: &FieldMapping{ID: "id"}}), ErrMissingMapping)
+		require.NoError(t, genericAdapter{}.Validate(webhook))
+	})
+}
+
+func TestGitHubAdapter(t *testing.T) {
+	deployment := `"deployment": {"id": 42, "ref": "v1.2.0", "environment": "production", "created_at": "2022-05-02T10:00:00Z"},
+		"repository": {"full_name": "acme/api"}`
+
+	events, err := gitHubAdapter{}.Events(nil, mustJSON(t, `{"action": "created", `+deployment+`}`))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, Event{
+		ID:   "42",
+		Kind: EventStart,
+		Time: 1651485600000,
+		Text: "Deployment of acme/api v1.2.0 to production",
+		Tags: []string{"github", "deployment", "repository:acme/api", "environment:production"},
+	}, events[0])
+
+	events, err = gitHubAdapter{}.Events(nil, mustJSON(t, `{"deployment_status": {"state": "success", "updated_at": "2022-05-02T10:05:00Z", "description": "Deployed"}, `+deployment+`}`))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventEnd, events[0].Kind)
+	assert.Equal(t, int64(1651485900000), events[0].TimeEnd)
+	assert.Equal(t, "Deployment of acme/api v1.2.0 to production: success\nDeployed", events[0].Text)
+	assert.Contains(t, events[0].Tags, "state:success")
+
+	events, err = gitHubAdapter{}.Events(nil, mustJSON(t, `{"zen": "Keep it logically awesome."}`))
+	require.NoError(t, err)
+	require.Empty(t, events)
+
+	_, err = gitHubAdapter{}.Events(nil, mustJSON(t, `{"pull_request": {}}`))
+	require.ErrorIs(t, err, ErrUnsupportedPayload)
+}
+
+func TestAlertmanagerAdapter(t *testing.T) {
+	events, err := alertmanagerAdapter{}.Events(nil, mustJSON(t, `{"status": "firing", "alerts": [
+		{"status": "firing", "fingerprint": "a1", "labels": {"alertname": "HighLatency", "severity": "critical"},
+			"annotations": {"summary": "p99 above 1s"}, "startsAt": "2022-05-02T10:00:00Z", "endsAt": "0001-01-01T00:00:00Z"},
+		{"status": "resolved", "labels": {"alertname": "DiskFull"},
+			"startsAt": "2022-05-02T09:00:00Z", "endsAt": "2022-05-02T09:30:00Z"}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	assert.Equal(t, Event{
+		ID:   "a1",
+		Kind: EventStart,
+		Time: 1651485600000,
+		Text: "HighLatency: p99 above 1s",
+		Tags: []string{"alertmanager", "alertname:HighLatency", "severity:critical"},
+	}, events[0])
+
+	// alerts without fingerprint are identified by their labels
+	assert.Equal(t, Event{
+		ID:      "alertname:DiskFull",
+		Kind:    EventEnd,
+		Time:    1651482000000,
+		TimeEnd: 1651483800000,
+		Text:    "DiskFull",
+		Tags:    []string{"alertmanager", "alertname:DiskFull"},
+	}, events[1])
+
+	_, err = alertmanagerAdapter{}.Events(nil, mustJSON(t, `{"status": "firing"}`))
+	require.ErrorIs(t, err, ErrUnsupportedPayload)
+}
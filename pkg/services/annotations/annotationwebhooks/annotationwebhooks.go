@@ -0,0 +1,305 @@
+package annotationwebhooks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/annotations"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	// closed regions are only kept to ignore the end events sent again
+	closedRegionRetention = 24 * time.Hour
+	openRegionRetention   = 30 * 24 * time.Hour
+	// maxEventIDLength is the length of the event_id column, longer ids are hashed
+	maxEventIDLength = 190
+)
+
+// Service annotates the events that external tools, such as CI/CD pipelines or incident management tools, send
+// to annotation webhooks. The payloads of each tool are converted to events by an adapter.
+type Service interface {
+	// RegisterAdapter adds an adapter, which webhooks can then use.
+	RegisterAdapter(name string, adapter Adapter)
+	// DeleteStaleRegions forgets the regions closed a day ago, and the regions left open for 30 days.
+	DeleteStaleRegions(ctx context.Context) (int64, error)
+}
+
+type AnnotationWebhookService struct {
+	cfg           *setting.Cfg
+	sqlStore      *sqlstore.SQLStore
+	accessControl accesscontrol.AccessControl
+	routeRegister routing.RouteRegister
+	adapters      map[string]Adapter
+	log           log.Logger
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, accessControl accesscontrol.AccessControl,
+	routeRegister routing.RouteRegister) (*AnnotationWebhookService, error) {
+	s := &AnnotationWebhookService{
+		cfg:           cfg,
+		sqlStore:      sqlStore,
+		accessControl: accessControl,
+		routeRegister: routeRegister,
+		adapters: map[string]Adapter{
+			AdapterGeneric:      genericAdapter{},
+			AdapterGitHub:       gitHubAdapter{},
+			AdapterAlertmanager: alertmanagerAdapter{},
+		},
+		log: log.New("annotations.webhooks"),
+	}
+
+	if err := RegisterRoles(accessControl); err != nil {
+		return nil, err
+	}
+
+	s.registerAPIEndpoints()
+
+	return s, nil
+}
+
+func (s *AnnotationWebhookService) RegisterAdapter(name string, adapter Adapter) {
+	s.adapters[name] = adapter
+}
+
+func (s *AnnotationWebhookService) DeleteStaleRegions(ctx context.Context) (int64, error) {
+	now := time.Now()
+	return s.deleteStaleRegions(ctx, now.Add(-closedRegionRetention), now.Add(-openRegionRetention))
+}
+
+// Receive annotates the events of the payload sent to the webhook by the service account, and returns the ids
+// of their annotations.
+func (s *AnnotationWebhookService) Receive(ctx context.Context, user *models.SignedInUser, webhook *Webhook, body []byte) ([]int64, error) {
+	adapter, ok := s.adapters[webhook.Adapter]
+	if !ok {
+		return nil, ErrUnknownAdapter
+	}
+
+	payload, err := simplejson.NewJson(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPayload, err)
+	}
+	events, err := adapter.Events(webhook, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UnixMilli()
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		id, err := s.annotate(ctx, user, webhook, event, now)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// annotate saves a point event as an annotation, and the start and end events as a region annotation. An end
+// event without start event is annotated as a region, from its start time when it has one.
+func (s *AnnotationWebhookService) annotate(ctx context.Context, user *models.SignedInUser, webhook *Webhook, event Event, now int64) (int64, error) {
+	event.Tags = append(append([]string{}, webhook.Tags...), event.Tags...)
+	if event.ID == "" {
+		event.Kind = EventPoint
+	}
+	if len(event.ID) > maxEventIDLength {
+		sum := sha256.Sum256([]byte(event.ID))
+		event.ID = hex.EncodeToString(sum[:])
+	}
+
+	switch event.Kind {
+	case EventStart:
+		r, err := s.getRegion(ctx, webhook.ID, event.ID)
+		if err != nil {
+			return 0, err
+		}
+		if r != nil && !r.Closed {
+			return r.AnnotationID, nil
+		}
+		if event.Time == 0 {
+			event.Time = now
+		}
+		id, err := s.createAnnotation(ctx, user, webhook, event, event.Time, event.Time)
+		if err != nil {
+			return 0, err
+		}
+		return id, s.saveRegion(ctx, webhook, r, event.ID, id, false)
+	case EventEnd:
+		if event.TimeEnd == 0 {
+			event.TimeEnd = now
+		}
+		r, err := s.getRegion(ctx, webhook.ID, event.ID)
+		if err != nil {
+			return 0, err
+		}
+		if r != nil && r.Closed {
+			return r.AnnotationID, nil
+		}
+		if r != nil {
+			closed, err := s.closeRegion(ctx, user, r.AnnotationID, event)
+			if err != nil {
+				return 0, err
+			}
+			if closed {
+				return r.AnnotationID, s.saveRegion(ctx, webhook, r, event.ID, r.AnnotationID, true)
+			}
+		}
+		if event.Text == "" {
+			event.Text = event.ID
+		}
+		start := event.Time
+		if start == 0 || start > event.TimeEnd {
+			start = event.TimeEnd
+		}
+		id, err := s.createAnnotation(ctx, user, webhook, event, start, event.TimeEnd)
+		if err != nil {
+			return 0, err
+		}
+		return id, s.saveRegion(ctx, webhook, r, event.ID, id, true)
+	default:
+		if event.Time == 0 {
+			event.Time = now
+		}
+		if event.TimeEnd < event.Time {
+			event.TimeEnd = event.Time
+		}
+		return s.createAnnotation(ctx, user, webhook, event, event.Time, event.TimeEnd)
+	}
+}
+
+// createAnnotation saves the event as an annotation of the dashboard of the first rule matching its tags, or of
+// the organization when none matches.
+func (s *AnnotationWebhookService) createAnnotation(ctx context.Context, user *models.SignedInUser, webhook *Webhook, event Event, epoch int64, epochEnd int64) (int64, error) {
+	if event.Text == "" {
+		return 0, ErrMissingText
+	}
+
+	item := annotations.Item{
+		OrgId:    webhook.OrgID,
+		UserId:   user.UserId,
+		Epoch:    epoch,
+		EpochEnd: epochEnd,
+		Text:     event.Text,
+		Tags:     event.Tags,
+		Data:     simplejson.NewFromAny(map[string]interface{}{"webhookUid": webhook.UID, "eventId": event.ID}),
+	}
+	for _, rule := range webhook.DashboardRules {
+		if !rule.matches(event.Tags) {
+			continue
+		}
+		query := models.GetDashboardQuery{OrgId: webhook.OrgID, Uid: rule.DashboardUID}
+		if err := s.sqlStore.GetDashboard(ctx, &query); err != nil {
+			if errors.Is(err, models.ErrDashboardNotFound) {
+				return 0, ErrDashboardNotFound
+			}
+			return 0, err
+		}
+		item.DashboardId = query.Result.Id
+		item.PanelId = rule.PanelID
+		break
+	}
+
+	if err := s.checkCanAnnotate(ctx, user, item.DashboardId); err != nil {
+		return 0, err
+	}
+
+	if err := annotations.GetRepository().Save(&item); err != nil {
+		return 0, err
+	}
+	return item.Id, nil
+}
+
+// closeRegion ends the region annotation at the end of the event, and adds the tags of the event to it. The text
+// of the event replaces the text of the annotation, if it has one. It reports whether the annotation still exists.
+func (s *AnnotationWebhookService) closeRegion(ctx context.Context, user *models.SignedInUser, annotationID int64, event Event) (bool, error) {
+	repo := annotations.GetRepository()
+
+	// the annotation is not returned to the service account, its permissions are checked before it is updated
+	reader := &models.SignedInUser{
+		OrgId: user.OrgId,
+		Permissions: map[int64]map[string][]string{
+			user.OrgId: {
+				dashboards.ActionDashboardsRead:     {dashboards.ScopeDashboardsAll},
+				accesscontrol.ActionAnnotationsRead: {accesscontrol.ScopeAnnotationsAll},
+			},
+		},
+	}
+	items, err := repo.Find(ctx, &annotations.ItemQuery{OrgId: user.OrgId, AnnotationId: annotationID, SignedInUser: reader})
+	if err != nil {
+		return false, err
+	}
+	if len(items) == 0 {
+		return false, nil
+	}
+	existing := items[0]
+
+	if err := s.checkCanAnnotate(ctx, user, existing.DashboardId); err != nil {
+		return false, err
+	}
+
+	item := annotations.Item{
+		Id:       existing.Id,
+		OrgId:    user.OrgId,
+		Epoch:    existing.Time,
+		EpochEnd: event.TimeEnd,
+		Text:     existing.Text,
+		Tags:     existing.Tags,
+	}
+	if item.EpochEnd < item.Epoch {
+		item.EpochEnd = item.Epoch
+	}
+	if event.Text != "" {
+		item.Text = event.Text
+	}
+	for _, tag := range event.Tags {
+		if !contains(item.Tags, tag) {
+			item.Tags = append(item.Tags, tag)
+		}
+	}
+	return true, repo.Update(ctx, &item)
+}
+
+// checkCanAnnotate checks that the user can create the annotations of the dashboard, or of the organization when
+// dashboardID is 0, as when creating them with the annotations API.
+func (s *AnnotationWebhookService) checkCanAnnotate(ctx context.Context, user *models.SignedInUser, dashboardID int64) error {
+	scope := accesscontrol.ScopeAnnotationsTypeOrganization
+	if dashboardID != 0 {
+		scope = accesscontrol.ScopeAnnotationsTypeDashboard
+	}
+
+	if !s.accessControl.IsDisabled() {
+		canCreate, err := s.accessControl.Evaluate(ctx, user, accesscontrol.EvalPermission(accesscontrol.ActionAnnotationsCreate, scope))
+		if err != nil {
+			return err
+		}
+		if !canCreate {
+			return ErrCannotAnnotate
+		}
+	} else if dashboardID == 0 && !user.HasRole(models.ROLE_EDITOR) {
+		return ErrCannotAnnotate
+	}
+
+	if dashboardID != 0 {
+		canEdit, err := guardian.New(ctx, dashboardID, user.OrgId, user).CanEdit()
+		if err != nil {
+			return err
+		}
+		if !canEdit {
+			return ErrCannotAnnotate
+		}
+	}
+	return nil
+}
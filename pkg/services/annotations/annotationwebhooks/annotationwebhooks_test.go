@@ -0,0 +1,219 @@
+package annotationwebhooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/annotations"
+	dashboardsdb "github.com/grafana/grafana/pkg/services/dashboards/database"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+type testEnv struct {
+	service   *AnnotationWebhookService
+	sqlStore  *sqlstore.SQLStore
+	user      *models.SignedInUser
+	dashboard *models.Dashboard
+}
+
+func setupTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	sqlStore := sqlstore.InitTestDB(t)
+	dashboard, err := dashboardsdb.ProvideDashboardStore(sqlStore).SaveDashboard(models.SaveDashboardCommand{
+		OrgId:     1,
+		Dashboard: simplejson.NewFromAny(map[string]interface{}{"title": "Production"}),
+	})
+	require.NoError(t, err)
+
+	origGuardian := guardian.New
+	guardian.MockDashboardGuardian(&guardian.FakeDashboardGuardian{CanEditValue: true})
+	t.Cleanup(func() { guardian.New = origGuardian })
+
+	service, err := ProvideService(sqlStore.Cfg, sqlStore, accesscontrolmock.New().WithDisabled(), routing.NewRouteRegister())
+	require.NoError(t, err)
+
+	return &testEnv{
+		service:   service,
+		sqlStore:  sqlStore,
+		user:      &models.SignedInUser{UserId: 2, OrgId: 1, OrgRole: models.ROLE_EDITOR, IsServiceAccount: true},
+		dashboard: dashboard,
+	}
+}
+
+func (env *testEnv) createWebhook(t *testing.T, webhook *Webhook) *Webhook {
+	t.Helper()
+	webhook.UID = webhook.Name
+	webhook.OrgID = 1
+	webhook.Created = time.Now()
+	webhook.Updated = time.Now()
+	require.NoError(t, env.service.createWebhook(context.Background(), webhook))
+	return webhook
+}
+
+func (env *testEnv) getAnnotation(t *testing.T, id int64) *annotations.ItemDTO {
+	t.Helper()
+	items, err := annotations.GetRepository().Find(context.Background(), &annotations.ItemQuery{
+		OrgId:        1,
+		AnnotationId: id,
+		SignedInUser: &models.SignedInUser{OrgId: 1, OrgRole: models.ROLE_ADMIN, Permissions: map[int64]map[string][]string{
+			1: {"dashboards:read": {"dashboards:*"}, "annotations:read": {"annotations:*"}},
+		}},
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	return items[0]
+}
+
+func TestReceive(t *testing.T) {
+	ctx := context.Background()
+	env := setupTestEnv(t)
+	s := env.service
+
+	t.Run("generic events are annotated with their mapping, on the dashboard of the matching rule", func(t *testing.T) {
+		webhook := env.createWebhook(t, &Webhook{
+			Name:    "ci",
+			Adapter: AdapterGeneric,
+			Mapping: &FieldMapping{Text: "message", Time: "time", Tags: "tags"},
+			Tags:    []string{"ci"},
+			DashboardRules: []DashboardRule{
+				{Tags: []string{"ci", "production"}, DashboardUID: env.dashboard.Uid, PanelID: 3},
+			},
+		})
+
+		ids, err := s.Receive(ctx, env.user, webhook, []byte(`[
+			{"message": "Deployed api", "time": 1600000000000, "tags": ["production"]},
+			{"message": "Deployed api to staging", "tags": ["staging"]}
+		]`))
+		require.NoError(t, err)
+		require.Len(t, ids, 2)
+
+		production := env.getAnnotation(t, ids[0])
+		assert.Equal(t, "Deployed api", production.Text)
+		assert.Equal(t, int64(1600000000000), production.Time)
+		assert.ElementsMatch(t, []string{"ci", "production"}, production.Tags)
+		assert.Equal(t, env.dashboard.Id, production.DashboardId)
+		assert.Equal(t, int64(3), production.PanelId)
+
+		staging := env.getAnnotation(t, ids[1])
+		assert.Equal(t, int64(0), staging.DashboardId)
+
+		_, err = s.Receive(ctx, env.user, webhook, []byte(`{"time": 1600000000000}`))
+		require.ErrorIs(t, err, ErrMissingText)
+		_, err = s.Receive(ctx, env.user, webhook, []byte(`not json`))
+		require.ErrorIs(t, err, ErrUnsupportedPayload)
+	})
+
+	t.Run("Alertmanager alerts are annotated as regions from when they fire until they are resolved", func(t *testing.T) {
+		webhook := env.createWebhook(t, &Webhook{Name: "alertmanager", Adapter: AdapterAlertmanager})
+		firing := []byte(`{"alerts": [{"status": "firing", "fingerprint": "a1", "labels": {"alertname": "HighLatency"},
+			"startsAt": "2022-05-02T10:00:00Z", "endsAt": "0001-01-01T00:00:00Z"}]}`)
+		resolved := []byte(`{"alerts": [{"status": "resolved", "fingerprint": "a1", "labels": {"alertname": "HighLatency"},
+			"startsAt": "2022-05-02T10:00:00Z", "endsAt": "2022-05-02T10:30:00Z"}]}`)
+
+		ids, err := s.Receive(ctx, env.user, webhook, firing)
+		require.NoError(t, err)
+		require.Len(t, ids, 1)
+		annotationID := ids[0]
+
+		// repeated notifications don't add annotations
+		ids, err = s.Receive(ctx, env.user, webhook, firing)
+		require.NoError(t, err)
+		require.Equal(t, []int64{annotationID}, ids)
+
+		ids, err = s.Receive(ctx, env.user, webhook, resolved)
+		require.NoError(t, err)
+		require.Equal(t, []int64{annotationID}, ids)
+		ids, err = s.Receive(ctx, env.user, webhook, resolved)
+		require.NoError(t, err)
+		require.Equal(t, []int64{annotationID}, ids)
+
+		region := env.getAnnotation(t, annotationID)
+		assert.Equal(t, int64(1651485600000), region.Time)
+		assert.Equal(t, int64(1651487400000), region.TimeEnd)
+		assert.Equal(t, "HighLatency", region.Text)
+
+		// the alert firing again starts a new region
+		ids, err = s.Receive(ctx, env.user, webhook, firing)
+		require.NoError(t, err)
+		require.NotEqual(t, annotationID, ids[0])
+	})
+
+	t.Run("GitHub deployments are annotated as regions until their last status", func(t *testing.T) {
+		webhook := env.createWebhook(t, &Webhook{Name: "github", Adapter: AdapterGitHub})
+		deployment := `"deployment": {"id": 7, "ref": "main", "environment": "production", "created_at": "2022-05-02T10:00:00Z"},
+			"repository": {"full_name": "acme/api"}`
+
+		ids, err := s.Receive(ctx, env.user, webhook, []byte(`{"action": "created", `+deployment+`}`))
+		require.NoError(t, err)
+		annotationID := ids[0]
+
+		ids, err = s.Receive(ctx, env.user, webhook, []byte(`{"deployment_status": {"state": "failure", "updated_at": "2022-05-02T10:05:00Z"}, `+deployment+`}`))
+		require.NoError(t, err)
+		require.Equal(t, []int64{annotationID}, ids)
+
+		region := env.getAnnotation(t, annotationID)
+		assert.Equal(t, int64(1651485900000), region.TimeEnd)
+		assert.Equal(t, "Deployment of acme/api main to production: failure", region.Text)
+		assert.Contains(t, region.Tags, "state:failure")
+		assert.Contains(t, region.Tags, "environment:production")
+	})
+
+	t.Run("end events without start event are annotated as regions", func(t *testing.T) {
+		webhook := env.createWebhook(t, &Webhook{Name: "resolved", Adapter: AdapterAlertmanager})
+		ids, err := s.Receive(ctx, env.user, webhook, []byte(`{"alerts": [{"status": "resolved", "fingerprint": "b2",
+			"labels": {"alertname": "DiskFull"}, "startsAt": "2022-05-02T09:00:00Z", "endsAt": "2022-05-02T09:30:00Z"}]}`))
+		require.NoError(t, err)
+
+		region := env.getAnnotation(t, ids[0])
+		assert.Equal(t, int64(1651482000000), region.Time)
+		assert.Equal(t, int64(1651483800000), region.TimeEnd)
+	})
+
+	t.Run("service accounts must be allowed to create the annotations", func(t *testing.T) {
+		webhook := env.createWebhook(t, &Webhook{Name: "viewer", Adapter: AdapterGeneric, Mapping: &FieldMapping{Text: "message"}})
+		viewer := &models.SignedInUser{UserId: 3, OrgId: 1, OrgRole: models.ROLE_VIEWER, IsServiceAccount: true}
+		_, err := s.Receive(ctx, viewer, webhook, []byte(`{"message": "Deployed"}`))
+		require.ErrorIs(t, err, ErrCannotAnnotate)
+	})
+}
+
+func TestDeleteStaleRegions(t *testing.T) {
+	ctx := context.Background()
+	env := setupTestEnv(t)
+	s := env.service
+	webhook := env.createWebhook(t, &Webhook{Name: "alertmanager", Adapter: AdapterAlertmanager})
+
+	require.NoError(t, s.saveRegion(ctx, webhook, nil, "open", 1, false))
+	require.NoError(t, s.saveRegion(ctx, webhook, nil, "closed", 2, true))
+
+	now := time.Now()
+	deleted, err := s.deleteStaleRegions(ctx, now.Add(-time.Hour), now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(0), deleted)
+
+	deleted, err = s.deleteStaleRegions(ctx, now.Add(time.Hour), now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), deleted)
+
+	r, err := s.getRegion(ctx, webhook.ID, "closed")
+	require.NoError(t, err)
+	require.Nil(t, r)
+	r, err = s.getRegion(ctx, webhook.ID, "open")
+	require.NoError(t, err)
+	require.NotNil(t, r)
+
+	require.NoError(t, s.deleteWebhook(ctx, 1, webhook.UID))
+	r, err = s.getRegion(ctx, webhook.ID, "open")
+	require.NoError(t, err)
+	require.Nil(t, r)
+}
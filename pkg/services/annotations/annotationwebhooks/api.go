@@ -0,0 +1,217 @@
+package annotationwebhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/annotations"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// maxPayloadSize is the size of the largest payload accepted by the webhooks, 1MB
+const maxPayloadSize = 1 << 20
+
+func (s *AnnotationWebhookService) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(s.accessControl)
+
+	s.routeRegister.Group("/api/annotations/webhooks", func(webhooksRoute routing.RouteRegister) {
+		webhooksRoute.Get("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionRead)), routing.Wrap(s.getWebhooksHandler))
+		webhooksRoute.Post("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionCreate)), routing.Wrap(s.createWebhookHandler))
+		webhooksRoute.Get("/:uid", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionRead, ScopeUID)), routing.Wrap(s.getWebhookHandler))
+		webhooksRoute.Put("/:uid", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionWrite, ScopeUID)), routing.Wrap(s.updateWebhookHandler))
+		webhooksRoute.Delete("/:uid", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionDelete, ScopeUID)), routing.Wrap(s.deleteWebhookHandler))
+		// the permissions of the service account sending the events are checked for each annotation
+		webhooksRoute.Post("/:uid/events", middleware.ReqSignedIn, routing.Wrap(s.receiveEventsHandler))
+	})
+}
+
+// GET /api/annotations/webhooks
+func (s *AnnotationWebhookService) getWebhooksHandler(c *models.ReqContext) response.Response {
+	webhooks, err := s.getWebhooks(c.Req.Context(), c.OrgId)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get annotation webhooks", err)
+	}
+
+	dtos := make([]*WebhookDTO, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		dtos = append(dtos, s.toDTO(webhook))
+	}
+	return response.JSON(http.StatusOK, dtos)
+}
+
+// GET /api/annotations/webhooks/:uid
+func (s *AnnotationWebhookService) getWebhookHandler(c *models.ReqContext) response.Response {
+	webhook, err := s.getWebhook(c.Req.Context(), c.OrgId, web.Params(c.Req)[":uid"])
+	if err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			return response.Error(http.StatusNotFound, "Annotation webhook not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get annotation webhook", err)
+	}
+	return response.JSON(http.StatusOK, s.toDTO(webhook))
+}
+
+// POST /api/annotations/webhooks
+func (s *AnnotationWebhookService) createWebhookHandler(c *models.ReqContext) response.Response {
+	cmd := SaveWebhookCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	now := time.Now()
+	webhook := &Webhook{UID: util.GenerateShortUID(), OrgID: c.OrgId, Created: now}
+	if errResp := s.applyCommand(c.Req.Context(), webhook, &cmd, now); errResp != nil {
+		return errResp
+	}
+
+	if err := s.createWebhook(c.Req.Context(), webhook); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to create annotation webhook", err)
+	}
+	return response.JSON(http.StatusOK, s.toDTO(webhook))
+}
+
+// PUT /api/annotations/webhooks/:uid
+func (s *AnnotationWebhookService) updateWebhookHandler(c *models.ReqContext) response.Response {
+	cmd := SaveWebhookCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	webhook, err := s.getWebhook(c.Req.Context(), c.OrgId, web.Params(c.Req)[":uid"])
+	if err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			return response.Error(http.StatusNotFound, "Annotation webhook not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get annotation webhook", err)
+	}
+
+	if errResp := s.applyCommand(c.Req.Context(), webhook, &cmd, time.Now()); errResp != nil {
+		return errResp
+	}
+
+	if err := s.updateWebhook(c.Req.Context(), webhook); err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			return response.Error(http.StatusNotFound, "Annotation webhook not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to update annotation webhook", err)
+	}
+	return response.JSON(http.StatusOK, s.toDTO(webhook))
+}
+
+// DELETE /api/annotations/webhooks/:uid
+func (s *AnnotationWebhookService) deleteWebhookHandler(c *models.ReqContext) response.Response {
+	if err := s.deleteWebhook(c.Req.Context(), c.OrgId, web.Params(c.Req)[":uid"]); err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			return response.Error(http.StatusNotFound, "Annotation webhook not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to delete annotation webhook", err)
+	}
+	return response.Success("Annotation webhook deleted")
+}
+
+// POST /api/annotations/webhooks/:uid/events
+func (s *AnnotationWebhookService) receiveEventsHandler(c *models.ReqContext) response.Response {
+	if !c.SignedInUser.IsServiceAccount {
+		return response.Error(http.StatusForbidden, util.Capitalize(ErrNotAServiceAccount.Error()), nil)
+	}
+
+	webhook, err := s.getWebhook(c.Req.Context(), c.OrgId, web.Params(c.Req)[":uid"])
+	if err != nil {
+		if errors.Is(err, ErrWebhookNotFound) {
+			return response.Error(http.StatusNotFound, "Annotation webhook not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get annotation webhook", err)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Req.Body, maxPayloadSize+1))
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Failed to read payload", err)
+	}
+	if len(body) > maxPayloadSize {
+		return response.Error(http.StatusRequestEntityTooLarge, util.Capitalize(ErrPayloadTooLarge.Error()), nil)
+	}
+
+	ids, err := s.Receive(c.Req.Context(), c.SignedInUser, webhook, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnsupportedPayload), errors.Is(err, ErrMissingText), errors.Is(err, ErrInvalidTime),
+			errors.Is(err, ErrDashboardNotFound), errors.Is(err, ErrUnknownAdapter),
+			errors.Is(err, annotations.ErrTimerangeMissing):
+			return response.Error(http.StatusBadRequest, util.Capitalize(err.Error()), err)
+		case errors.Is(err, ErrCannotAnnotate):
+			return response.Error(http.StatusForbidden, util.Capitalize(err.Error()), nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to annotate events", err)
+	}
+
+	s.log.Info("Annotated webhook events", "webhook", webhook.UID, "serviceAccount", c.Login, "annotations", ids)
+	return response.JSON(http.StatusOK, util.DynMap{
+		"message": "Events annotated",
+		"ids":     ids,
+	})
+}
+
+// applyCommand validates the command and applies it to the webhook
+func (s *AnnotationWebhookService) applyCommand(ctx context.Context, webhook *Webhook, cmd *SaveWebhookCommand, now time.Time) response.Response {
+	webhook.Name = cmd.Name
+	webhook.Adapter = cmd.Adapter
+	webhook.Mapping = nil
+	if cmd.Adapter == AdapterGeneric {
+		webhook.Mapping = cmd.Mapping
+	}
+	webhook.Tags = cmd.Tags
+	if webhook.Tags == nil {
+		webhook.Tags = []string{}
+	}
+	webhook.DashboardRules = cmd.DashboardRules
+	if webhook.DashboardRules == nil {
+		webhook.DashboardRules = []DashboardRule{}
+	}
+	webhook.Updated = now
+
+	adapter, ok := s.adapters[cmd.Adapter]
+	if !ok {
+		return response.Error(http.StatusBadRequest, util.Capitalize(ErrUnknownAdapter.Error()), nil)
+	}
+	if err := adapter.Validate(webhook); err != nil {
+		return response.Error(http.StatusBadRequest, util.Capitalize(err.Error()), err)
+	}
+
+	for _, rule := range webhook.DashboardRules {
+		if len(rule.Tags) == 0 || rule.DashboardUID == "" {
+			return response.Error(http.StatusBadRequest, util.Capitalize(ErrInvalidRule.Error()), nil)
+		}
+		query := models.GetDashboardQuery{OrgId: webhook.OrgID, Uid: rule.DashboardUID}
+		if err := s.sqlStore.GetDashboard(ctx, &query); err != nil {
+			if errors.Is(err, models.ErrDashboardNotFound) {
+				return response.Error(http.StatusNotFound, "Dashboard not found", nil)
+			}
+			return response.Error(http.StatusInternalServerError, "Failed to get dashboard", err)
+		}
+	}
+	return nil
+}
+
+func (s *AnnotationWebhookService) toDTO(webhook *Webhook) *WebhookDTO {
+	return &WebhookDTO{
+		UID:            webhook.UID,
+		Name:           webhook.Name,
+		Adapter:        webhook.Adapter,
+		Mapping:        webhook.Mapping,
+		Tags:           webhook.Tags,
+		DashboardRules: webhook.DashboardRules,
+		URL:            strings.TrimSuffix(s.cfg.AppURL, "/") + "/api/annotations/webhooks/" + webhook.UID + "/events",
+		Created:        webhook.Created,
+		Updated:        webhook.Updated,
+	}
+}
@@ -0,0 +1,121 @@
+package annotationwebhooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func (s *AnnotationWebhookService) getWebhooks(ctx context.Context, orgID int64) ([]*Webhook, error) {
+	webhooks := make([]*Webhook, 0)
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("org_id = ?", orgID).Asc("name", "id").Find(&webhooks)
+	})
+	return webhooks, err
+}
+
+func (s *AnnotationWebhookService) getWebhook(ctx context.Context, orgID int64, uid string) (*Webhook, error) {
+	webhook := &Webhook{}
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		exists, err := sess.Where("org_id = ? AND uid = ?", orgID, uid).Get(webhook)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrWebhookNotFound
+		}
+		return nil
+	})
+	return webhook, err
+}
+
+func (s *AnnotationWebhookService) createWebhook(ctx context.Context, webhook *Webhook) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Insert(webhook)
+		return err
+	})
+}
+
+func (s *AnnotationWebhookService) updateWebhook(ctx context.Context, webhook *Webhook) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		affected, err := sess.ID(webhook.ID).
+			Cols("name", "adapter", "mapping", "tags", "dashboard_rules", "updated").
+			Update(webhook)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrWebhookNotFound
+		}
+		return nil
+	})
+}
+
+// deleteWebhook deletes the webhook and its regions. The annotations of its events are kept.
+func (s *AnnotationWebhookService) deleteWebhook(ctx context.Context, orgID int64, uid string) error {
+	return s.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		webhook := &Webhook{}
+		exists, err := sess.Where("org_id = ? AND uid = ?", orgID, uid).Get(webhook)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrWebhookNotFound
+		}
+
+		if _, err := sess.Where("webhook_id = ?", webhook.ID).Delete(&region{}); err != nil {
+			return err
+		}
+		_, err = sess.ID(webhook.ID).Delete(&Webhook{})
+		return err
+	})
+}
+
+// getRegion returns the region of the event of the webhook, nil if there is none.
+func (s *AnnotationWebhookService) getRegion(ctx context.Context, webhookID int64, eventID string) (*region, error) {
+	var r *region
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		found := &region{}
+		exists, err := sess.Where("webhook_id = ? AND event_id = ?", webhookID, eventID).Get(found)
+		if exists {
+			r = found
+		}
+		return err
+	})
+	return r, err
+}
+
+// saveRegion records the annotation of the event, in the region r when the event already had one.
+func (s *AnnotationWebhookService) saveRegion(ctx context.Context, webhook *Webhook, r *region, eventID string, annotationID int64, closed bool) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		if r == nil {
+			_, err := sess.Insert(&region{
+				OrgID:        webhook.OrgID,
+				WebhookID:    webhook.ID,
+				EventID:      eventID,
+				AnnotationID: annotationID,
+				Closed:       closed,
+				Updated:      time.Now(),
+			})
+			return err
+		}
+
+		r.AnnotationID = annotationID
+		r.Closed = closed
+		r.Updated = time.Now()
+		_, err := sess.ID(r.ID).Cols("annotation_id", "closed", "updated").Update(r)
+		return err
+	})
+}
+
+func (s *AnnotationWebhookService) deleteStaleRegions(ctx context.Context, closedBefore time.Time, openBefore time.Time) (int64, error) {
+	var deleted int64
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		affected, err := sess.Where("(closed = ? AND updated < ?) OR updated < ?", true, closedBefore, openBefore).
+			Delete(&region{})
+		deleted = affected
+		return err
+	})
+	return deleted, err
+}
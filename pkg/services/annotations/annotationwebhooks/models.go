@@ -0,0 +1,162 @@
+package annotationwebhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+const (
+	ActionRead   = "annotations.webhooks:read"
+	ActionCreate = "annotations.webhooks:create"
+	ActionWrite  = "annotations.webhooks:write"
+	ActionDelete = "annotations.webhooks:delete"
+
+	ScopeAll = "annotations.webhooks:*"
+)
+
+var ScopeUID = accesscontrol.Scope("annotations.webhooks", "uid", accesscontrol.Parameter(":uid"))
+
+var (
+	ErrWebhookNotFound    = errors.New("annotation webhook not found")
+	ErrUnknownAdapter     = errors.New("adapter must be generic, github or alertmanager")
+	ErrMissingMapping     = errors.New("the generic adapter requires a mapping of the text field")
+	ErrInvalidRule        = errors.New("dashboard rules require tags and a dashboard uid")
+	ErrDashboardNotFound  = errors.New("dashboard not found")
+	ErrUnsupportedPayload = errors.New("payload is not supported by the adapter of the webhook")
+	ErrMissingText        = errors.New("event has no text")
+	ErrInvalidTime        = errors.New("event time is invalid")
+	ErrNotAServiceAccount = errors.New("annotation webhooks only accept service account tokens")
+	ErrPayloadTooLarge    = errors.New("payload is too large")
+	ErrCannotAnnotate     = errors.New("service account is not allowed to create the annotation")
+)
+
+// Webhook receives the events of an external tool, converts them with its adapter, and saves them as annotations
+// of the organization, or of the dashboard of the first rule matching their tags.
+type Webhook struct {
+	ID             int64           `xorm:"pk autoincr 'id'"`
+	UID            string          `xorm:"uid"`
+	OrgID          int64           `xorm:"org_id"`
+	Name           string          `xorm:"name"`
+	Adapter        string          `xorm:"adapter"`
+	Mapping        *FieldMapping   `xorm:"mapping"`
+	Tags           []string        `xorm:"tags"`
+	DashboardRules []DashboardRule `xorm:"dashboard_rules"`
+	Created        time.Time
+	Updated        time.Time
+}
+
+func (Webhook) TableName() string {
+	return "annotation_webhook"
+}
+
+// FieldMapping maps the fields of a JSON payload to an annotation, for the generic adapter. Fields are paths of
+// keys separated by dots, array elements are referenced by their index, e.g. `commits.0.message`.
+type FieldMapping struct {
+	// Events is the path of an array of events, when a payload contains several of them.
+	Events string `json:"events,omitempty"`
+	// ID is the path of the id correlating the start and end events of a region.
+	ID      string `json:"id,omitempty"`
+	Text    string `json:"text"`
+	Time    string `json:"time,omitempty"`
+	TimeEnd string `json:"timeEnd,omitempty"`
+	// Tags is the path of a string, an array of strings or an object, whose entries become key:value tags.
+	Tags string `json:"tags,omitempty"`
+	// Status is the path of the field telling whether an event starts or ends a region, when its value is one of
+	// StartValues or EndValues.
+	Status      string   `json:"status,omitempty"`
+	StartValues []string `json:"startValues,omitempty"`
+	EndValues   []string `json:"endValues,omitempty"`
+}
+
+func (m *FieldMapping) FromDB(data []byte) error {
+	return json.Unmarshal(data, m)
+}
+
+func (m *FieldMapping) ToDB() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+// DashboardRule targets the annotations having all of its tags to a dashboard, and to one of its panels when
+// PanelID is set.
+type DashboardRule struct {
+	Tags         []string `json:"tags"`
+	DashboardUID string   `json:"dashboardUid"`
+	PanelID      int64    `json:"panelId,omitempty"`
+}
+
+func (r DashboardRule) matches(tags []string) bool {
+	for _, ruleTag := range r.Tags {
+		found := false
+		for _, tag := range tags {
+			if tag == ruleTag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+type WebhookDTO struct {
+	UID            string          `json:"uid"`
+	Name           string          `json:"name"`
+	Adapter        string          `json:"adapter"`
+	Mapping        *FieldMapping   `json:"mapping,omitempty"`
+	Tags           []string        `json:"tags"`
+	DashboardRules []DashboardRule `json:"dashboardRules"`
+	URL            string          `json:"url"`
+	Created        time.Time       `json:"created"`
+	Updated        time.Time       `json:"updated"`
+}
+
+// SaveWebhookCommand creates or updates a webhook.
+type SaveWebhookCommand struct {
+	Name           string          `json:"name" binding:"Required"`
+	Adapter        string          `json:"adapter" binding:"Required"`
+	Mapping        *FieldMapping   `json:"mapping"`
+	Tags           []string        `json:"tags"`
+	DashboardRules []DashboardRule `json:"dashboardRules"`
+}
+
+// region is a region annotation opened by a start event, which the end event with the same id closes. Closed
+// regions are kept for a while, so that the end events sent several times don't add annotations.
+type region struct {
+	ID           int64     `xorm:"pk autoincr 'id'"`
+	OrgID        int64     `xorm:"org_id"`
+	WebhookID    int64     `xorm:"webhook_id"`
+	EventID      string    `xorm:"event_id"`
+	AnnotationID int64     `xorm:"annotation_id"`
+	Closed       bool      `xorm:"closed"`
+	Updated      time.Time `xorm:"updated"`
+}
+
+func (region) TableName() string {
+	return "annotation_webhook_region"
+}
+
+type EventKind string
+
+const (
+	// EventPoint is annotated on its own, as a point in time or as a region when it has an end time.
+	EventPoint EventKind = "point"
+	// EventStart opens a region, until the EventEnd with the same id.
+	EventStart EventKind = "start"
+	EventEnd   EventKind = "end"
+)
+
+// Event is an annotation converted from a payload by an adapter. Times are in epoch milliseconds, and default to
+// the time the event is received.
+type Event struct {
+	ID      string
+	Kind    EventKind
+	Time    int64
+	TimeEnd int64
+	Text    string
+	Tags    []string
+}
@@ -0,0 +1,51 @@
+package annotationwebhooks
+
+import (
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+func RegisterRoles(ac accesscontrol.AccessControl) error {
+	webhooksReader := accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Version:     1,
+			Name:        "fixed:annotations.webhooks:reader",
+			DisplayName: "Annotation webhook reader",
+			Description: "Read all annotation webhooks.",
+			Group:       "Annotations",
+			Permissions: []accesscontrol.Permission{
+				{
+					Action: ActionRead,
+					Scope:  ScopeAll,
+				},
+			},
+		},
+		Grants: []string{string(models.ROLE_ADMIN)},
+	}
+
+	webhooksWriter := accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Version:     1,
+			Name:        "fixed:annotations.webhooks:writer",
+			DisplayName: "Annotation webhook writer",
+			Description: "Create, read, update or delete all annotation webhooks.",
+			Group:       "Annotations",
+			Permissions: accesscontrol.ConcatPermissions(webhooksReader.Role.Permissions, []accesscontrol.Permission{
+				{
+					Action: ActionCreate,
+				},
+				{
+					Action: ActionWrite,
+					Scope:  ScopeAll,
+				},
+				{
+					Action: ActionDelete,
+					Scope:  ScopeAll,
+				},
+			}),
+		},
+		Grants: []string{string(models.ROLE_ADMIN)},
+	}
+
+	return ac.DeclareFixedRoles(webhooksReader, webhooksWriter)
+}
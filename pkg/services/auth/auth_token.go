@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"net"
+	"net/http"
 	"strings"
 	"time"
 
@@ -23,6 +24,12 @@ var getTime = time.Now
 
 const urgentRotateTime = 1 * time.Minute
 
+// activityUpdateInterval is how often the last activity of the tokens is updated when the idle timeout is enforced
+const activityUpdateInterval = 1 * time.Minute
+
+// maxSearchUserTokensLimit is the maximum number of tokens returned by a search
+const maxSearchUserTokensLimit = 1000
+
 func ProvideUserAuthTokenService(sqlStore *sqlstore.SQLStore, serverLockService *serverlock.ServerLockService,
 	cfg *setting.Cfg) *UserAuthTokenService {
 	s := &UserAuthTokenService{
@@ -46,10 +53,8 @@ func (s *UserAuthTokenService) ActiveTokenCount(ctx context.Context) (int64, err
 	var err error
 	err = s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
 		var model userAuthToken
-		count, err = dbSession.Where(`created_at > ? AND rotated_at > ? AND revoked_at = 0`,
-			s.createdAfterParam(),
-			s.rotatedAfterParam()).
-			Count(&model)
+		cond, args := s.activeCondition()
+		count, err = dbSession.Where(cond, args...).Count(&model)
 
 		return err
 	})
@@ -82,10 +87,14 @@ func (s *UserAuthTokenService) CreateToken(ctx context.Context, user *models.Use
 		UpdatedAt:     now,
 		SeenAt:        0,
 		RevokedAt:     0,
+		LastActiveAt:  now,
 		AuthTokenSeen: false,
 	}
 
-	err = s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+	err = s.SQLStore.WithTransactionalDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		if err := s.enforceMaxConcurrentSessions(dbSession, user.Id, now); err != nil {
+			return err
+		}
 		_, err = dbSession.Insert(&userAuthToken)
 		return err
 	})
@@ -126,10 +135,14 @@ func (s *UserAuthTokenService) LookupToken(ctx context.Context, unhashedToken st
 	}
 
 	if model.RevokedAt > 0 {
-		return nil, &models.TokenRevokedError{
+		revokedErr := &models.TokenRevokedError{
 			UserID:  model.UserId,
 			TokenID: model.Id,
 		}
+		if model.RevokedReason == models.TokenRevokedReasonMaxConcurrentSessions {
+			revokedErr.MaxConcurrentSessions = s.Cfg.LoginMaxConcurrentSessions
+		}
+		return nil, revokedErr
 	}
 
 	if model.CreatedAt <= s.createdAfterParam() || model.RotatedAt <= s.rotatedAfterParam() {
@@ -139,6 +152,27 @@ func (s *UserAuthTokenService) LookupToken(ctx context.Context, unhashedToken st
 		}
 	}
 
+	if s.Cfg.LoginIdleTimeout > 0 {
+		if model.LastActiveAt <= s.idleAfterParam() {
+			return nil, &models.TokenExpiredError{
+				UserID:  model.UserId,
+				TokenID: model.Id,
+			}
+		}
+
+		now := getTime()
+		if model.LastActiveAt <= now.Add(-activityUpdateInterval).Unix() {
+			err = s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+				_, err := dbSession.Exec("UPDATE user_auth_token SET last_active_at = ? WHERE id = ?", now.Unix(), model.Id)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+			model.LastActiveAt = now.Unix()
+		}
+	}
+
 	if model.AuthToken != hashedToken && model.PrevAuthToken == hashedToken && model.AuthTokenSeen {
 		modelCopy := model
 		modelCopy.AuthTokenSeen = false
@@ -251,13 +285,14 @@ func (s *UserAuthTokenService) TryRotateToken(ctx context.Context, token *models
 			prev_auth_token = case when auth_token_seen = ? then auth_token else prev_auth_token end,
 			auth_token = ?,
 			auth_token_seen = ?,
-			rotated_at = ?
+			rotated_at = ?,
+			last_active_at = ?
 		WHERE id = ? AND (auth_token_seen = ? OR rotated_at < ?)`
 
 	var affected int64
 	err = s.SQLStore.WithTransactionalDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
 		res, err := dbSession.Exec(sql, userAgent, clientIPStr, s.SQLStore.Dialect.BooleanStr(true), hashedToken,
-			s.SQLStore.Dialect.BooleanStr(false), now.Unix(), model.Id, s.SQLStore.Dialect.BooleanStr(true),
+			s.SQLStore.Dialect.BooleanStr(false), now.Unix(), now.Unix(), model.Id, s.SQLStore.Dialect.BooleanStr(true),
 			now.Add(-30*time.Second).Unix())
 		if err != nil {
 			return err
@@ -394,10 +429,8 @@ func (s *UserAuthTokenService) GetUserTokens(ctx context.Context, userId int64)
 	result := []*models.UserToken{}
 	err := s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
 		var tokens []*userAuthToken
-		err := dbSession.Where("user_id = ? AND created_at > ? AND rotated_at > ? AND revoked_at = 0",
-			userId,
-			s.createdAfterParam(),
-			s.rotatedAfterParam()).
+		cond, args := s.activeCondition()
+		err := dbSession.Where("user_id = ? AND "+cond, append([]interface{}{userId}, args...)...).
 			Find(&tokens)
 		if err != nil {
 			return err
@@ -440,6 +473,145 @@ func (s *UserAuthTokenService) GetUserRevokedTokens(ctx context.Context, userId
 	return result, err
 }
 
+// SearchUserTokens returns a page of the active tokens matching the query ordered by id, of all users unless the
+// query is for a single one
+func (s *UserAuthTokenService) SearchUserTokens(ctx context.Context, query *models.SearchUserTokensQuery) ([]*models.UserToken, error) {
+	limit := query.Limit
+	if limit <= 0 || limit > maxSearchUserTokensLimit {
+		limit = maxSearchUserTokensLimit
+	}
+
+	result := []*models.UserToken{}
+	err := s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		cond, args := s.activeCondition()
+		if query.UserId != 0 {
+			cond += " AND user_id = ?"
+			args = append(args, query.UserId)
+		}
+		if !query.ActiveSince.IsZero() {
+			cond += " AND last_active_at >= ?"
+			args = append(args, query.ActiveSince.Unix())
+		}
+
+		// the client ips are stored as strings, so the networks can only be matched once they are parsed, the
+		// tokens are read by batches until the page is full
+		afterID := query.AfterId
+		for len(result) < limit {
+			var tokens []*userAuthToken
+			err := dbSession.Where(cond+" AND id > ?", append(args, afterID)...).Asc("id").Limit(limit).Find(&tokens)
+			if err != nil {
+				return err
+			}
+
+			for _, token := range tokens {
+				afterID = token.Id
+				if query.ClientIPNet != nil {
+					clientIP := net.ParseIP(token.ClientIp)
+					if clientIP == nil || !query.ClientIPNet.Contains(clientIP) {
+						continue
+					}
+				}
+
+				var userToken models.UserToken
+				if err := token.toUserToken(&userToken); err != nil {
+					return err
+				}
+				result = append(result, &userToken)
+				if len(result) == limit {
+					break
+				}
+			}
+
+			if len(tokens) < limit {
+				break
+			}
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// BatchRevokeTokens revokes the tokens, whichever user they belong to
+func (s *UserAuthTokenService) BatchRevokeTokens(ctx context.Context, tokenIds []int64) error {
+	return s.SQLStore.WithTransactionalDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		if len(tokenIds) == 0 {
+			return nil
+		}
+
+		affected, err := dbSession.In("id", tokenIds).Delete(&userAuthToken{})
+		if err != nil {
+			return err
+		}
+
+		s.log.Debug("user tokens revoked", "tokensCount", len(tokenIds), "count", affected)
+
+		return nil
+	})
+}
+
+// enforceMaxConcurrentSessions makes room for a new session of the user, by revoking its oldest sessions or by
+// rejecting the new one, depending on the concurrent sessions policy.
+// It locks the user until the end of the transaction, so that concurrent logins of the user are counted one after
+// the other, and must be called in the transaction that inserts the new session.
+func (s *UserAuthTokenService) enforceMaxConcurrentSessions(dbSession *sqlstore.DBSession, userID int64, now int64) error {
+	maxSessions := s.Cfg.LoginMaxConcurrentSessions
+	if maxSessions <= 0 {
+		return nil
+	}
+
+	// The sessions of the user can't be locked when there are none yet, so the user is locked instead
+	if _, err := dbSession.ID(userID).Cols("id").ForUpdate().Get(&models.User{}); err != nil {
+		return err
+	}
+
+	var tokens []*userAuthToken
+	cond, args := s.activeCondition()
+	err := dbSession.Where("user_id = ? AND "+cond, append([]interface{}{userID}, args...)...).
+		Asc("created_at", "id").
+		ForUpdate().
+		Find(&tokens)
+	if err != nil {
+		return err
+	}
+
+	excess := int64(len(tokens)) - maxSessions + 1
+	if excess <= 0 {
+		return nil
+	}
+
+	if s.Cfg.LoginConcurrentSessionsPolicy == models.ConcurrentSessionsPolicyReject {
+		return &models.CreateTokenErr{
+			StatusCode:  http.StatusForbidden,
+			InternalErr: models.ErrMaxConcurrentSessionsReached,
+			ExternalErr: "Maximum number of concurrent sessions reached",
+		}
+	}
+
+	for _, token := range tokens[:excess] {
+		token.RevokedAt = now
+		token.RevokedReason = models.TokenRevokedReasonMaxConcurrentSessions
+		if _, err := dbSession.ID(token.Id).Cols("revoked_at", "revoked_reason").Update(token); err != nil {
+			return err
+		}
+		s.log.Debug("user auth token evicted by a newer session", "tokenId", token.Id, "userId", token.UserId, "clientIP", token.ClientIp, "userAgent", token.UserAgent)
+	}
+
+	return nil
+}
+
+// activeCondition returns the condition of the tokens that are neither expired nor revoked
+func (s *UserAuthTokenService) activeCondition() (string, []interface{}) {
+	cond := "created_at > ? AND rotated_at > ? AND revoked_at = 0"
+	args := []interface{}{s.createdAfterParam(), s.rotatedAfterParam()}
+	if s.Cfg.LoginIdleTimeout > 0 {
+		cond += " AND last_active_at > ?"
+		args = append(args, s.idleAfterParam())
+	}
+	return cond, args
+}
+
 func (s *UserAuthTokenService) createdAfterParam() int64 {
 	return getTime().Add(-s.Cfg.LoginMaxLifetime).Unix()
 }
@@ -448,6 +620,10 @@ func (s *UserAuthTokenService) rotatedAfterParam() int64 {
 	return getTime().Add(-s.Cfg.LoginMaxInactiveLifetime).Unix()
 }
 
+func (s *UserAuthTokenService) idleAfterParam() int64 {
+	return getTime().Add(-s.Cfg.LoginIdleTimeout).Unix()
+}
+
 func hashToken(token string) string {
 	hashBytes := sha256.Sum256([]byte(token + setting.SecretKey))
 	return hex.EncodeToString(hashBytes[:])
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"net"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -474,6 +476,9 @@ func TestUserAuthToken(t *testing.T) {
 			RotatedAt:     4,
 			CreatedAt:     5,
 			UpdatedAt:     6,
+			RevokedAt:     7,
+			RevokedReason: "f",
+			LastActiveAt:  8,
 			UnhashedToken: "e",
 		}
 		utBytes, err := json.Marshal(ut)
@@ -507,6 +512,9 @@ func TestUserAuthToken(t *testing.T) {
 			RotatedAt:     4,
 			CreatedAt:     5,
 			UpdatedAt:     6,
+			RevokedAt:     7,
+			RevokedReason: "f",
+			LastActiveAt:  8,
 			UnhashedToken: "e",
 		}
 		uatBytes, err := json.Marshal(uat)
@@ -528,6 +536,196 @@ func TestUserAuthToken(t *testing.T) {
 	})
 }
 
+func TestUserAuthTokenSessionLimits(t *testing.T) {
+	user := &models.User{Id: int64(10)}
+	now := time.Date(2018, 12, 13, 13, 45, 0, 0, time.UTC)
+	getTime = func() time.Time { return now }
+	defer func() { getTime = time.Now }()
+
+	createTokens := func(t *testing.T, ctx *testContext, count int) []*models.UserToken {
+		t.Helper()
+		tokens := []*models.UserToken{}
+		for i := 0; i < count; i++ {
+			token, err := ctx.tokenService.CreateToken(context.Background(), user, net.ParseIP("192.168.10.11"), "some user agent")
+			require.NoError(t, err)
+			tokens = append(tokens, token)
+		}
+		return tokens
+	}
+
+	t.Run("oldest sessions are evicted when the maximum is reached", func(t *testing.T) {
+		ctx := createTestContext(t)
+		ctx.tokenService.Cfg.LoginMaxConcurrentSessions = 2
+		ctx.tokenService.Cfg.LoginConcurrentSessionsPolicy = models.ConcurrentSessionsPolicyEvictOldest
+
+		tokens := createTokens(t, ctx, 3)
+
+		active, err := ctx.tokenService.GetUserTokens(context.Background(), user.Id)
+		require.NoError(t, err)
+		require.Len(t, active, 2)
+		require.Equal(t, tokens[1].Id, active[0].Id)
+		require.Equal(t, tokens[2].Id, active[1].Id)
+
+		_, err = ctx.tokenService.LookupToken(context.Background(), tokens[0].UnhashedToken)
+		var revokedErr *models.TokenRevokedError
+		require.ErrorAs(t, err, &revokedErr)
+		require.Equal(t, int64(2), revokedErr.MaxConcurrentSessions)
+	})
+
+	t.Run("new sessions are rejected when the maximum is reached", func(t *testing.T) {
+		ctx := createTestContext(t)
+		ctx.tokenService.Cfg.LoginMaxConcurrentSessions = 2
+		ctx.tokenService.Cfg.LoginConcurrentSessionsPolicy = models.ConcurrentSessionsPolicyReject
+
+		createTokens(t, ctx, 2)
+
+		_, err := ctx.tokenService.CreateToken(context.Background(), user, net.ParseIP("192.168.10.11"), "some user agent")
+		var createTokenErr *models.CreateTokenErr
+		require.ErrorAs(t, err, &createTokenErr)
+		require.Equal(t, 403, createTokenErr.StatusCode)
+		require.ErrorIs(t, createTokenErr.InternalErr, models.ErrMaxConcurrentSessionsReached)
+
+		active, err := ctx.tokenService.GetUserTokens(context.Background(), user.Id)
+		require.NoError(t, err)
+		require.Len(t, active, 2)
+	})
+
+	t.Run("concurrent sessions are counted one after the other", func(t *testing.T) {
+		ctx := createTestContext(t)
+		ctx.tokenService.Cfg.LoginMaxConcurrentSessions = 2
+		ctx.tokenService.Cfg.LoginConcurrentSessionsPolicy = models.ConcurrentSessionsPolicyReject
+
+		var wg sync.WaitGroup
+		var created int64
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := ctx.tokenService.CreateToken(context.Background(), user, net.ParseIP("192.168.10.11"), "some user agent"); err == nil {
+					atomic.AddInt64(&created, 1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		active, err := ctx.tokenService.GetUserTokens(context.Background(), user.Id)
+		require.NoError(t, err)
+		require.Len(t, active, 2)
+		require.Equal(t, int64(2), created)
+	})
+
+	t.Run("manually revoked tokens do not report the maximum", func(t *testing.T) {
+		ctx := createTestContext(t)
+		ctx.tokenService.Cfg.LoginMaxConcurrentSessions = 2
+
+		tokens := createTokens(t, ctx, 1)
+		require.NoError(t, ctx.tokenService.RevokeToken(context.Background(), tokens[0], true))
+
+		_, err := ctx.tokenService.LookupToken(context.Background(), tokens[0].UnhashedToken)
+		var revokedErr *models.TokenRevokedError
+		require.ErrorAs(t, err, &revokedErr)
+		require.Zero(t, revokedErr.MaxConcurrentSessions)
+	})
+}
+
+func TestUserAuthTokenIdleTimeout(t *testing.T) {
+	user := &models.User{Id: int64(10)}
+	now := time.Date(2018, 12, 13, 13, 45, 0, 0, time.UTC)
+	getTime = func() time.Time { return now }
+	defer func() { getTime = time.Now }()
+
+	ctx := createTestContext(t)
+	ctx.tokenService.Cfg.LoginIdleTimeout = 15 * time.Minute
+
+	token, err := ctx.tokenService.CreateToken(context.Background(), user, net.ParseIP("192.168.10.11"), "some user agent")
+	require.NoError(t, err)
+
+	t.Run("using the token keeps it active", func(t *testing.T) {
+		getTime = func() time.Time { return now.Add(14 * time.Minute) }
+		_, err := ctx.tokenService.LookupToken(context.Background(), token.UnhashedToken)
+		require.NoError(t, err)
+
+		model, err := ctx.getAuthTokenByID(token.Id)
+		require.NoError(t, err)
+		require.Equal(t, now.Add(14*time.Minute).Unix(), model.LastActiveAt)
+
+		getTime = func() time.Time { return now.Add(28 * time.Minute) }
+		_, err = ctx.tokenService.LookupToken(context.Background(), token.UnhashedToken)
+		require.NoError(t, err)
+	})
+
+	t.Run("the token expires once idle for longer than the timeout", func(t *testing.T) {
+		getTime = func() time.Time { return now.Add(43 * time.Minute) }
+		_, err := ctx.tokenService.LookupToken(context.Background(), token.UnhashedToken)
+		var expiredErr *models.TokenExpiredError
+		require.ErrorAs(t, err, &expiredErr)
+
+		count, err := ctx.tokenService.ActiveTokenCount(context.Background())
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+}
+
+func TestSearchUserTokens(t *testing.T) {
+	ctx := createTestContext(t)
+
+	for _, token := range []struct {
+		userID   int64
+		clientIP string
+	}{
+		{userID: 1, clientIP: "10.0.1.1"},
+		{userID: 1, clientIP: "192.168.0.1"},
+		{userID: 2, clientIP: "10.0.2.1"},
+		{userID: 2, clientIP: "2001:db8::1"},
+	} {
+		_, err := ctx.tokenService.CreateToken(context.Background(), &models.User{Id: token.userID}, net.ParseIP(token.clientIP), "some user agent")
+		require.NoError(t, err)
+	}
+
+	clientIPs := func(t *testing.T, query *models.SearchUserTokensQuery) []string {
+		t.Helper()
+		tokens, err := ctx.tokenService.SearchUserTokens(context.Background(), query)
+		require.NoError(t, err)
+		result := []string{}
+		for _, token := range tokens {
+			result = append(result, token.ClientIp)
+		}
+		return result
+	}
+
+	_, network, err := net.ParseCIDR("10.0.0.0/16")
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"10.0.1.1", "192.168.0.1", "10.0.2.1", "2001:db8::1"}, clientIPs(t, &models.SearchUserTokensQuery{}))
+	require.Equal(t, []string{"10.0.1.1", "10.0.2.1"}, clientIPs(t, &models.SearchUserTokensQuery{ClientIPNet: network}))
+	require.Equal(t, []string{"10.0.2.1"}, clientIPs(t, &models.SearchUserTokensQuery{UserId: 2, ClientIPNet: network}))
+
+	t.Run("should page the tokens", func(t *testing.T) {
+		tokens, err := ctx.tokenService.SearchUserTokens(context.Background(), &models.SearchUserTokensQuery{Limit: 1, ClientIPNet: network})
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		require.Equal(t, "10.0.1.1", tokens[0].ClientIp)
+
+		query := &models.SearchUserTokensQuery{Limit: 1, ClientIPNet: network, AfterId: tokens[0].Id}
+		require.Equal(t, []string{"10.0.2.1"}, clientIPs(t, query))
+		require.Equal(t, []string{"192.168.0.1", "10.0.2.1"}, clientIPs(t, &models.SearchUserTokensQuery{Limit: 2, AfterId: tokens[0].Id}))
+	})
+
+	t.Run("should match the tokens active since a time", func(t *testing.T) {
+		tokens, err := ctx.tokenService.SearchUserTokens(context.Background(), &models.SearchUserTokensQuery{UserId: 2})
+		require.NoError(t, err)
+		_, err = ctx.sqlstore.NewSession(context.Background()).Exec("UPDATE user_auth_token SET last_active_at = ? WHERE id = ?", time.Now().Add(-2*time.Hour).Unix(), tokens[0].Id)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"10.0.1.1", "192.168.0.1", "2001:db8::1"}, clientIPs(t, &models.SearchUserTokensQuery{ActiveSince: time.Now().Add(-time.Hour)}))
+	})
+
+	tokens, err := ctx.tokenService.SearchUserTokens(context.Background(), &models.SearchUserTokensQuery{ClientIPNet: network})
+	require.NoError(t, err)
+	require.NoError(t, ctx.tokenService.BatchRevokeTokens(context.Background(), []int64{tokens[0].Id, tokens[1].Id}))
+	require.Equal(t, []string{"192.168.0.1", "2001:db8::1"}, clientIPs(t, &models.SearchUserTokensQuery{}))
+}
+
 func createTestContext(t *testing.T) *testContext {
 	t.Helper()
 	maxInactiveDurationVal, _ := time.ParseDuration("168h")
@@ -536,9 +734,10 @@ func createTestContext(t *testing.T) *testContext {
 	tokenService := &UserAuthTokenService{
 		SQLStore: sqlstore,
 		Cfg: &setting.Cfg{
-			LoginMaxInactiveLifetime:     maxInactiveDurationVal,
-			LoginMaxLifetime:             maxLifetimeDurationVal,
-			TokenRotationIntervalMinutes: 10,
+			LoginMaxInactiveLifetime:      maxInactiveDurationVal,
+			LoginMaxLifetime:              maxLifetimeDurationVal,
+			TokenRotationIntervalMinutes:  10,
+			LoginConcurrentSessionsPolicy: models.ConcurrentSessionsPolicyEvictOldest,
 		},
 		log: log.New("test-logger"),
 	}
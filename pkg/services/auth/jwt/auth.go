@@ -51,7 +51,7 @@ type AuthService struct {
 	Cfg         *setting.Cfg
 	RemoteCache *remotecache.RemoteCache
 
-	keySet           keySet
+	keySet           KeySet
 	log              log.Logger
 	expect           map[string]interface{}
 	expectRegistered jwt.Expected
@@ -68,13 +68,30 @@ func sanitizeJWT(jwtToken string) string {
 func (s *AuthService) Verify(ctx context.Context, strToken string) (models.JWTClaims, error) {
 	s.log.Debug("Parsing JSON Web Token")
 
+	claims, err := VerifySignature(ctx, s.keySet, strToken)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Debug("Validating JSON Web Token claims")
+
+	if err = s.validateClaims(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// VerifySignature parses the token and verifies its signature with the keys of the key set. It returns the claims of
+// the token, which still have to be validated.
+func VerifySignature(ctx context.Context, keySet KeySet, strToken string) (models.JWTClaims, error) {
 	strToken = sanitizeJWT(strToken)
 	token, err := jwt.ParseSigned(strToken)
 	if err != nil {
 		return nil, err
 	}
 
-	keys, err := s.keySet.Key(ctx, token.Headers[0].KeyID)
+	keys, err := keySet.Key(ctx, token.Headers[0].KeyID)
 	if err != nil {
 		return nil, err
 	}
@@ -82,8 +99,6 @@ func (s *AuthService) Verify(ctx context.Context, strToken string) (models.JWTCl
 		return nil, errors.New("no keys found")
 	}
 
-	s.log.Debug("Trying to verify JSON Web Token using a key")
-
 	var claims models.JWTClaims
 	for _, key := range keys {
 		if err = token.Claims(key, &claims); err == nil {
@@ -94,11 +109,5 @@ func (s *AuthService) Verify(ctx context.Context, strToken string) (models.JWTCl
 		return nil, err
 	}
 
-	s.log.Debug("Validating JSON Web Token claims")
-
-	if err = s.validateClaims(claims); err != nil {
-		return nil, err
-	}
-
 	return claims, nil
 }
@@ -25,10 +25,20 @@ var ErrKeySetIsNotConfigured = errors.New("key set for jwt verification is not c
 var ErrKeySetConfigurationAmbiguous = errors.New("key set configuration is ambiguous: you should set either key_file, jwk_set_file or jwk_set_url")
 var ErrJWTSetURLMustHaveHTTPSScheme = errors.New("jwt_set_url must have https scheme")
 
-type keySet interface {
+// KeySet provides the keys verifying the signature of JSON Web Tokens
+type KeySet interface {
 	Key(ctx context.Context, kid string) ([]jose.JSONWebKey, error)
 }
 
+// KeySetSettings are the sources of the keys of a key set, only one of KeyFile, JWKSetFile and JWKSetURL should be set
+type KeySetSettings struct {
+	KeyFile    string
+	JWKSetFile string
+	JWKSetURL  string
+	// CacheTTL is how long the keys of JWKSetURL are cached
+	CacheTTL time.Duration
+}
+
 type keySetJWKS struct {
 	jose.JSONWebKeySet
 }
@@ -42,15 +52,15 @@ type keySetHTTP struct {
 	cacheExpiration time.Duration
 }
 
-func (s *AuthService) checkKeySetConfiguration() error {
+func checkKeySetConfiguration(settings KeySetSettings) error {
 	var count int
-	if s.Cfg.JWTAuthKeyFile != "" {
+	if settings.KeyFile != "" {
 		count++
 	}
-	if s.Cfg.JWTAuthJWKSetFile != "" {
+	if settings.JWKSetFile != "" {
 		count++
 	}
-	if s.Cfg.JWTAuthJWKSetURL != "" {
+	if settings.JWKSetURL != "" {
 		count++
 	}
 
@@ -66,101 +76,116 @@ func (s *AuthService) checkKeySetConfiguration() error {
 }
 
 func (s *AuthService) initKeySet() error {
-	if err := s.checkKeySetConfiguration(); err != nil {
+	keySet, err := NewKeySet(KeySetSettings{
+		KeyFile:    s.Cfg.JWTAuthKeyFile,
+		JWKSetFile: s.Cfg.JWTAuthJWKSetFile,
+		JWKSetURL:  s.Cfg.JWTAuthJWKSetURL,
+		CacheTTL:   s.Cfg.JWTAuthCacheTTL,
+	}, s.RemoteCache, s.log)
+	if err != nil {
 		return err
 	}
 
-	if keyFilePath := s.Cfg.JWTAuthKeyFile; keyFilePath != "" {
+	s.keySet = keySet
+	return nil
+}
+
+// NewKeySet loads the key set of the settings. The keys of a JWK set URL are fetched when needed.
+func NewKeySet(settings KeySetSettings, remoteCache *remotecache.RemoteCache, logger log.Logger) (KeySet, error) {
+	if err := checkKeySetConfiguration(settings); err != nil {
+		return nil, err
+	}
+
+	if keyFilePath := settings.KeyFile; keyFilePath != "" {
 		// nolint:gosec
 		// We can ignore the gosec G304 warning on this one because `fileName` comes from grafana configuration file
 		file, err := os.Open(keyFilePath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defer func() {
 			if err := file.Close(); err != nil {
-				s.log.Warn("Failed to close file", "path", keyFilePath, "err", err)
+				logger.Warn("Failed to close file", "path", keyFilePath, "err", err)
 			}
 		}()
 
 		data, err := ioutil.ReadAll(file)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		block, _ := pem.Decode(data)
 		if block == nil {
-			return ErrFailedToParsePemFile
+			return nil, ErrFailedToParsePemFile
 		}
 
 		var key interface{}
 		switch block.Type {
 		case "PUBLIC KEY":
 			if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
-				return err
+				return nil, err
 			}
 		case "PRIVATE KEY":
 			if key, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
-				return err
+				return nil, err
 			}
 		case "RSA PUBLIC KEY":
 			if key, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
-				return err
+				return nil, err
 			}
 		case "RSA PRIVATE KEY":
 			if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
-				return err
+				return nil, err
 			}
 		case "EC PRIVATE KEY":
 			if key, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
-				return err
+				return nil, err
 			}
 		default:
-			return fmt.Errorf("unknown pem block type %q", block.Type)
+			return nil, fmt.Errorf("unknown pem block type %q", block.Type)
 		}
 
-		s.keySet = keySetJWKS{
+		return keySetJWKS{
 			jose.JSONWebKeySet{
 				Keys: []jose.JSONWebKey{{Key: key}},
 			},
-		}
-	} else if keyFilePath := s.Cfg.JWTAuthJWKSetFile; keyFilePath != "" {
+		}, nil
+	} else if keyFilePath := settings.JWKSetFile; keyFilePath != "" {
 		// nolint:gosec
 		// We can ignore the gosec G304 warning on this one because `fileName` comes from grafana configuration file
 		file, err := os.Open(keyFilePath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defer func() {
 			if err := file.Close(); err != nil {
-				s.log.Warn("Failed to close file", "path", keyFilePath, "err", err)
+				logger.Warn("Failed to close file", "path", keyFilePath, "err", err)
 			}
 		}()
 
 		var jwks jose.JSONWebKeySet
 		if err := json.NewDecoder(file).Decode(&jwks); err != nil {
-			return err
+			return nil, err
 		}
 
-		s.keySet = keySetJWKS{jwks}
-	} else if urlStr := s.Cfg.JWTAuthJWKSetURL; urlStr != "" {
-		urlParsed, err := url.Parse(urlStr)
-		if err != nil {
-			return err
-		}
-		if urlParsed.Scheme != "https" {
-			return ErrJWTSetURLMustHaveHTTPSScheme
-		}
-		s.keySet = &keySetHTTP{
-			url:             urlStr,
-			log:             s.log,
-			client:          &http.Client{},
-			cacheKey:        fmt.Sprintf("auth-jwt:jwk-%s", urlStr),
-			cacheExpiration: s.Cfg.JWTAuthCacheTTL,
-			cache:           s.RemoteCache,
-		}
+		return keySetJWKS{jwks}, nil
 	}
 
-	return nil
+	urlStr := settings.JWKSetURL
+	urlParsed, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+	if urlParsed.Scheme != "https" {
+		return nil, ErrJWTSetURLMustHaveHTTPSScheme
+	}
+	return &keySetHTTP{
+		url:             urlStr,
+		log:             logger,
+		client:          &http.Client{},
+		cacheKey:        fmt.Sprintf("auth-jwt:jwk-%s", urlStr),
+		cacheExpiration: settings.CacheTTL,
+		cache:           remoteCache,
+	}, nil
 }
 
 func (ks keySetJWKS) Key(ctx context.Context, keyID string) ([]jose.JSONWebKey, error) {
@@ -0,0 +1,58 @@
+package jwtbearer
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/network"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/serviceaccounts"
+)
+
+func (s *JWTBearerService) registerAPIEndpoints() {
+	s.routeRegister.Post("/api/auth/token", routing.Wrap(s.tokenHandler))
+}
+
+// POST /api/auth/token
+func (s *JWTBearerService) tokenHandler(c *models.ReqContext) response.Response {
+	ip, _ := network.GetIPFromAddress(c.RemoteAddr())
+
+	if err := c.Req.ParseForm(); err != nil {
+		return tokenError(http.StatusBadRequest, "invalid_request", "Failed to parse the request")
+	}
+	if grantType := c.Req.PostForm.Get("grant_type"); grantType != GrantType {
+		return tokenError(http.StatusBadRequest, "unsupported_grant_type", "grant_type should be "+GrantType)
+	}
+	assertion := c.Req.PostForm.Get("assertion")
+	if assertion == "" {
+		return tokenError(http.StatusBadRequest, "invalid_request", "assertion is required")
+	}
+
+	token, err := s.Exchange(c.Req.Context(), assertion)
+	if err != nil {
+		s.log.Warn("Rejected JWT bearer token exchange", "ip", ip, "err", err)
+		switch {
+		case errors.Is(err, serviceaccounts.ErrServiceAccountNotFound), errors.Is(err, ErrServiceAccountDisabled),
+			errors.Is(err, ErrNoServiceAccountMapping):
+			return tokenError(http.StatusBadRequest, "invalid_grant", "The token is not allowed to access Grafana")
+		case errors.Is(err, models.ErrDuplicateApiKey):
+			return tokenError(http.StatusInternalServerError, "server_error", "Failed to issue a token")
+		}
+		return tokenError(http.StatusBadRequest, "invalid_grant", "The token is invalid")
+	}
+
+	s.log.Info("Issued service account token in exchange of a JWT", "ip", ip, "iss", token.Issuer, "sub", token.Subject,
+		"orgId", token.OrgID, "serviceAccountId", token.ServiceAccountID, "tokenId", token.TokenID, "expiresIn", token.ExpiresIn)
+
+	return response.JSON(http.StatusOK, TokenResponse{
+		AccessToken: token.Token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(token.ExpiresIn.Seconds()),
+	})
+}
+
+func tokenError(status int, code string, description string) response.Response {
+	return response.JSON(status, ErrorResponse{Error: code, ErrorDescription: description})
+}
@@ -0,0 +1,269 @@
+package jwtbearer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/components/apikeygen"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+	jwtauth "github.com/grafana/grafana/pkg/services/auth/jwt"
+	"github.com/grafana/grafana/pkg/services/serviceaccounts"
+	"github.com/grafana/grafana/pkg/services/serviceaccounts/database"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// tokenNamePrefix starts the name of the service account tokens issued by the exchange
+const tokenNamePrefix = "jwt-bearer-"
+
+// keySetCacheTTL is how long the keys of the JWK set URLs of the issuers are cached
+const keySetCacheTTL = time.Hour
+
+// JWTBearerService lets machine clients, like Kubernetes workloads with projected service account tokens, exchange
+// the JSON Web Tokens of trusted issuers for short-lived Grafana service account tokens.
+type JWTBearerService struct {
+	cfg           *setting.Cfg
+	sqlStore      *sqlstore.SQLStore
+	saStore       serviceaccounts.Store
+	routeRegister routing.RouteRegister
+	config        *Config
+	log           log.Logger
+	// now returns the current time.
+	// Stubbable by tests.
+	now func() time.Time
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, remoteCache *remotecache.RemoteCache,
+	routeRegister routing.RouteRegister) (*JWTBearerService, error) {
+	s := &JWTBearerService{
+		cfg:           cfg,
+		sqlStore:      sqlStore,
+		saStore:       database.NewServiceAccountsStore(sqlStore),
+		routeRegister: routeRegister,
+		config:        &Config{},
+		log:           log.New("auth.jwt_bearer"),
+		now:           time.Now,
+	}
+
+	if !cfg.JWTBearerEnabled {
+		return s, nil
+	}
+
+	config, err := readConfig(cfg.JWTBearerConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, issuer := range config.Issuers {
+		issuer.keySet, err = jwtauth.NewKeySet(jwtauth.KeySetSettings{
+			KeyFile:    issuer.KeyFile,
+			JWKSetFile: issuer.JWKSetFile,
+			JWKSetURL:  issuer.JWKSetURL,
+			CacheTTL:   keySetCacheTTL,
+		}, remoteCache, s.log)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the keys of issuer %q: %w", issuer.Issuer, err)
+		}
+	}
+	s.config = config
+
+	s.registerAPIEndpoints()
+
+	return s, nil
+}
+
+func readConfig(configFile string) (*Config, error) {
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `configFile` comes from grafana configuration file
+	fileBytes, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jwt bearer config file: %w", err)
+	}
+
+	content, err := setting.ExpandVar(string(fileBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand variables: %w", err)
+	}
+
+	config := &Config{}
+	if _, err := toml.Decode(content, config); err != nil {
+		return nil, fmt.Errorf("failed to load jwt bearer config file: %w", err)
+	}
+
+	for _, issuer := range config.Issuers {
+		if issuer.Issuer == "" {
+			return nil, fmt.Errorf("jwt bearer issuer has no issuer claim")
+		}
+		if len(issuer.Audience) == 0 {
+			return nil, fmt.Errorf("jwt bearer issuer %q has no audience", issuer.Issuer)
+		}
+		for _, mapping := range issuer.ServiceAccounts {
+			if mapping.Subject == "" || mapping.ServiceAccount == "" {
+				return nil, fmt.Errorf("service account mapping of issuer %q needs a subject and a service account", issuer.Issuer)
+			}
+			if _, err := path.Match(mapping.Subject, ""); err != nil {
+				return nil, fmt.Errorf("subject %q of issuer %q is invalid: %w", mapping.Subject, issuer.Issuer, err)
+			}
+			if mapping.OrgID == 0 {
+				mapping.OrgID = 1
+			}
+		}
+	}
+	return config, nil
+}
+
+// Exchange verifies the token, and issues a token of the service account the token is mapped to.
+// The issued token expires at the latest when the exchanged one does.
+func (s *JWTBearerService) Exchange(ctx context.Context, assertion string) (*AccessToken, error) {
+	claims, expiry, mapping, err := s.verify(ctx, assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	saID, err := s.saStore.RetrieveServiceAccountIdByName(ctx, mapping.OrgID, mapping.ServiceAccount)
+	if err != nil {
+		return nil, err
+	}
+	sa, err := s.saStore.RetrieveServiceAccount(ctx, mapping.OrgID, saID)
+	if err != nil {
+		return nil, err
+	}
+	if sa.IsDisabled {
+		return nil, ErrServiceAccountDisabled
+	}
+
+	ttl := s.cfg.JWTBearerTokenTTL
+	if untilExpiry := expiry.Sub(s.now()); untilExpiry < ttl {
+		ttl = untilExpiry
+	}
+	ttl = ttl.Truncate(time.Second)
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+
+	if err := s.deleteExpiredTokens(ctx, mapping.OrgID, saID); err != nil {
+		s.log.Warn("Failed to delete expired tokens", "serviceAccountId", saID, "err", err)
+	}
+
+	name := tokenNamePrefix + util.GenerateShortUID()
+	key, err := apikeygen.New(mapping.OrgID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := serviceaccounts.AddServiceAccountTokenCommand{
+		Name:          name,
+		OrgId:         mapping.OrgID,
+		Key:           key.HashedKey,
+		SecondsToLive: int64(ttl.Seconds()),
+	}
+	if err := s.saStore.AddServiceAccountToken(ctx, saID, &cmd); err != nil {
+		return nil, err
+	}
+
+	return &AccessToken{
+		Token:            key.ClientSecret,
+		ExpiresIn:        ttl,
+		Issuer:           claims.Issuer,
+		Subject:          claims.Subject,
+		OrgID:            mapping.OrgID,
+		ServiceAccountID: saID,
+		TokenID:          cmd.Result.Id,
+	}, nil
+}
+
+// verify verifies the signature and the claims of the token, and returns the service account mapping it matches
+func (s *JWTBearerService) verify(ctx context.Context, assertion string) (*jwt.Claims, time.Time, *ServiceAccountMapping, error) {
+	token, err := jwt.ParseSigned(assertion)
+	if err != nil {
+		return nil, time.Time{}, nil, err
+	}
+
+	// the claims are only trusted once the signature is verified with the keys of their issuer
+	var claims jwt.Claims
+	if err := token.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return nil, time.Time{}, nil, err
+	}
+
+	issuer := s.issuer(claims.Issuer)
+	if issuer == nil {
+		return nil, time.Time{}, nil, ErrUntrustedIssuer
+	}
+
+	allClaims, err := jwtauth.VerifySignature(ctx, issuer.keySet, assertion)
+	if err != nil {
+		return nil, time.Time{}, nil, err
+	}
+
+	if claims.Expiry == nil {
+		return nil, time.Time{}, nil, ErrTokenWithoutExpiry
+	}
+	if err := claims.Validate(jwt.Expected{Issuer: issuer.Issuer, Time: s.now()}); err != nil {
+		return nil, time.Time{}, nil, err
+	}
+	if !issuer.acceptsAudience(claims.Audience) {
+		return nil, time.Time{}, nil, ErrAudienceMismatch
+	}
+
+	mapping := issuer.mapping(claims.Subject, allClaims)
+	if mapping == nil {
+		return nil, time.Time{}, nil, ErrNoServiceAccountMapping
+	}
+
+	return &claims, claims.Expiry.Time(), mapping, nil
+}
+
+func (s *JWTBearerService) issuer(iss string) *Issuer {
+	for _, issuer := range s.config.Issuers {
+		if issuer.Issuer == iss {
+			return issuer
+		}
+	}
+	return nil
+}
+
+func (i *Issuer) acceptsAudience(audience jwt.Audience) bool {
+	for _, accepted := range i.Audience {
+		if audience.Contains(accepted) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Issuer) mapping(subject string, claims models.JWTClaims) *ServiceAccountMapping {
+	for _, mapping := range i.ServiceAccounts {
+		if matched, _ := path.Match(mapping.Subject, subject); !matched {
+			continue
+		}
+
+		matched := true
+		for name, expected := range mapping.Claims {
+			if value, ok := claims[name].(string); !ok || value != expected {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return mapping
+		}
+	}
+	return nil
+}
+
+func (s *JWTBearerService) deleteExpiredTokens(ctx context.Context, orgID, saID int64) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		rawSQL := "DELETE FROM api_key WHERE org_id = ? AND service_account_id = ? AND name LIKE ? AND expires <= ?"
+		_, err := sess.Exec(rawSQL, orgID, saID, tokenNamePrefix+"%", s.now().Unix())
+		return err
+	})
+}
@@ -0,0 +1,282 @@
+//go:build integration
+// +build integration
+
+package jwtbearer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/components/apikeygen"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+	jwtauth "github.com/grafana/grafana/pkg/services/auth/jwt"
+	"github.com/grafana/grafana/pkg/services/serviceaccounts"
+	"github.com/grafana/grafana/pkg/services/serviceaccounts/tests"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const (
+	testIssuer  = "https://kubernetes.default.svc.cluster.local"
+	testSubject = "system:serviceaccount:monitoring:agent"
+)
+
+type testEnv struct {
+	service  *JWTBearerService
+	sqlStore *sqlstore.SQLStore
+	key      *rsa.PrivateKey
+	saID     int64
+}
+
+func setupTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	cfg := setting.NewCfg()
+	cfg.JWTBearerTokenTTL = 10 * time.Minute
+	sqlStore := sqlstore.InitTestDB(t)
+
+	service, err := ProvideService(cfg, sqlStore, remotecache.NewFakeStore(t), routing.NewRouteRegister())
+	require.NoError(t, err)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "key-1", Algorithm: string(jose.RS256)}}})
+	require.NoError(t, err)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(jwksFile, jwks, 0600))
+
+	keySet, err := jwtauth.NewKeySet(jwtauth.KeySetSettings{JWKSetFile: jwksFile}, nil, log.New("test"))
+	require.NoError(t, err)
+	service.config = &Config{Issuers: []*Issuer{{
+		Issuer:   testIssuer,
+		Audience: []string{"grafana"},
+		ServiceAccounts: []*ServiceAccountMapping{
+			{Subject: "system:serviceaccount:ci:*", OrgID: 1, ServiceAccount: "ci", Claims: map[string]string{"environment": "production"}},
+			{Subject: "system:serviceaccount:monitoring:*", OrgID: 1, ServiceAccount: "monitoring"},
+		},
+		keySet: keySet,
+	}}}
+
+	sa := tests.SetupUserServiceAccount(t, sqlStore, tests.TestUser{Name: "monitoring", Login: "sa-monitoring", IsServiceAccount: true})
+
+	return &testEnv{service: service, sqlStore: sqlStore, key: key, saID: sa.Id}
+}
+
+func (env *testEnv) sign(t *testing.T, key interface{}, claims ...interface{}) string {
+	t.Helper()
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "key-1"))
+	require.NoError(t, err)
+	builder := jwt.Signed(signer)
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+	token, err := builder.CompactSerialize()
+	require.NoError(t, err)
+	return token
+}
+
+func validClaims(expiry time.Duration) jwt.Claims {
+	return jwt.Claims{
+		Issuer:   testIssuer,
+		Subject:  testSubject,
+		Audience: jwt.Audience{"grafana"},
+		Expiry:   jwt.NewNumericDate(time.Now().Add(expiry)),
+	}
+}
+
+func TestExchange(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("a valid token is exchanged for a token of its service account", func(t *testing.T) {
+		env := setupTestEnv(t)
+
+		token, err := env.service.Exchange(ctx, env.sign(t, env.key, validClaims(time.Hour)))
+		require.NoError(t, err)
+		assert.Equal(t, 10*time.Minute, token.ExpiresIn)
+		assert.Equal(t, env.saID, token.ServiceAccountID)
+		assert.Equal(t, testSubject, token.Subject)
+
+		decoded, err := apikeygen.Decode(token.Token)
+		require.NoError(t, err)
+		query := models.GetApiKeyByNameQuery{KeyName: decoded.Name, OrgId: decoded.OrgId}
+		require.NoError(t, env.sqlStore.GetApiKeyByName(ctx, &query))
+		assert.Equal(t, env.saID, *query.Result.ServiceAccountId)
+		valid, err := apikeygen.IsValid(decoded, query.Result.Key)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("the issued token expires at the latest with the exchanged token", func(t *testing.T) {
+		env := setupTestEnv(t)
+
+		token, err := env.service.Exchange(ctx, env.sign(t, env.key, validClaims(2*time.Minute)))
+		require.NoError(t, err)
+		assert.LessOrEqual(t, token.ExpiresIn, 2*time.Minute)
+		assert.Greater(t, token.ExpiresIn, time.Minute)
+	})
+
+	t.Run("the claims of the mappings are matched", func(t *testing.T) {
+		env := setupTestEnv(t)
+		ci := tests.SetupUserServiceAccount(t, env.sqlStore, tests.TestUser{Name: "ci", Login: "sa-ci", IsServiceAccount: true})
+		env.service.config.Issuers[0].ServiceAccounts[0].OrgID = ci.OrgId
+		claims := validClaims(time.Hour)
+		claims.Subject = "system:serviceaccount:ci:deployer"
+
+		_, err := env.service.Exchange(ctx, env.sign(t, env.key, claims, map[string]interface{}{"environment": "staging"}))
+		require.ErrorIs(t, err, ErrNoServiceAccountMapping)
+
+		token, err := env.service.Exchange(ctx, env.sign(t, env.key, claims, map[string]interface{}{"environment": "production"}))
+		require.NoError(t, err)
+		assert.Equal(t, ci.Id, token.ServiceAccountID)
+	})
+
+	t.Run("expired issued tokens are deleted", func(t *testing.T) {
+		env := setupTestEnv(t)
+
+		_, err := env.service.Exchange(ctx, env.sign(t, env.key, validClaims(time.Hour)))
+		require.NoError(t, err)
+		env.service.now = func() time.Time { return time.Now().Add(time.Hour) }
+		_, err = env.service.Exchange(ctx, env.sign(t, env.key, validClaims(2*time.Hour)))
+		require.NoError(t, err)
+
+		tokens, err := env.service.saStore.ListTokens(ctx, 1, env.saID)
+		require.NoError(t, err)
+		assert.Len(t, tokens, 1)
+	})
+
+	t.Run("tokens of disabled service accounts are not exchanged", func(t *testing.T) {
+		env := setupTestEnv(t)
+		disabled := true
+		_, err := env.service.saStore.UpdateServiceAccount(ctx, 1, env.saID, &serviceaccounts.UpdateServiceAccountForm{IsDisabled: &disabled})
+		require.NoError(t, err)
+
+		_, err = env.service.Exchange(ctx, env.sign(t, env.key, validClaims(time.Hour)))
+		require.ErrorIs(t, err, ErrServiceAccountDisabled)
+	})
+
+	t.Run("invalid tokens are rejected", func(t *testing.T) {
+		env := setupTestEnv(t)
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+
+		untrusted := validClaims(time.Hour)
+		untrusted.Issuer = "https://example.org"
+		withoutExpiry := validClaims(time.Hour)
+		withoutExpiry.Expiry = nil
+		otherAudience := validClaims(time.Hour)
+		otherAudience.Audience = jwt.Audience{"vault"}
+		withoutAudience := validClaims(time.Hour)
+		withoutAudience.Audience = nil
+		unmapped := validClaims(time.Hour)
+		unmapped.Subject = "system:serviceaccount:default:default"
+		missingServiceAccount := validClaims(time.Hour)
+		missingServiceAccount.Subject = "system:serviceaccount:ci:deployer"
+
+		_, err = env.service.Exchange(ctx, env.sign(t, env.key, untrusted))
+		assert.ErrorIs(t, err, ErrUntrustedIssuer)
+		_, err = env.service.Exchange(ctx, env.sign(t, otherKey, validClaims(time.Hour)))
+		assert.Error(t, err)
+		_, err = env.service.Exchange(ctx, env.sign(t, env.key, validClaims(-time.Hour)))
+		assert.ErrorIs(t, err, jwt.ErrExpired)
+		_, err = env.service.Exchange(ctx, env.sign(t, env.key, withoutExpiry))
+		assert.ErrorIs(t, err, ErrTokenWithoutExpiry)
+		_, err = env.service.Exchange(ctx, env.sign(t, env.key, otherAudience))
+		assert.ErrorIs(t, err, ErrAudienceMismatch)
+		_, err = env.service.Exchange(ctx, env.sign(t, env.key, withoutAudience))
+		assert.ErrorIs(t, err, ErrAudienceMismatch)
+		_, err = env.service.Exchange(ctx, env.sign(t, env.key, unmapped))
+		assert.ErrorIs(t, err, ErrNoServiceAccountMapping)
+		_, err = env.service.Exchange(ctx, env.sign(t, env.key, missingServiceAccount, map[string]interface{}{"environment": "production"}))
+		assert.ErrorIs(t, err, serviceaccounts.ErrServiceAccountNotFound)
+	})
+}
+
+func TestTokenHandler(t *testing.T) {
+	env := setupTestEnv(t)
+
+	post := func(t *testing.T, form url.Values) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		c := &models.ReqContext{Context: &web.Context{Req: req}, Logger: log.New("test")}
+
+		resp := env.service.tokenHandler(c).(*response.NormalResponse)
+		body := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(resp.Body(), &body))
+		return resp.Status(), body
+	}
+
+	status, body := post(t, url.Values{"grant_type": {GrantType}, "assertion": {env.sign(t, env.key, validClaims(time.Hour))}})
+	require.Equal(t, http.StatusOK, status, body)
+	assert.Equal(t, "Bearer", body["token_type"])
+	assert.Equal(t, float64(600), body["expires_in"])
+	assert.NotEmpty(t, body["access_token"])
+
+	status, body = post(t, url.Values{"grant_type": {"client_credentials"}})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "unsupported_grant_type", body["error"])
+
+	status, body = post(t, url.Values{"grant_type": {GrantType}, "assertion": {"invalid"}})
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, "invalid_grant", body["error"])
+}
+
+func TestReadConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jwt_bearer.toml")
+	content := `
+[[issuers]]
+issuer = "https://kubernetes.default.svc.cluster.local"
+audience = ["grafana"]
+jwk_set_file = "/etc/grafana/kubernetes-jwks.json"
+
+[[issuers.service_accounts]]
+subject = "system:serviceaccount:monitoring:*"
+service_account = "monitoring"
+
+[[issuers.service_accounts]]
+subject = "system:serviceaccount:ci:deployer"
+org_id = 2
+service_account = "ci"
+[issuers.service_accounts.claims]
+environment = "production"
+`
+	require.NoError(t, os.WriteFile(file, []byte(content), 0600))
+
+	config, err := readConfig(file)
+	require.NoError(t, err)
+	require.Len(t, config.Issuers, 1)
+	issuer := config.Issuers[0]
+	assert.Equal(t, []string{"grafana"}, issuer.Audience)
+	require.Len(t, issuer.ServiceAccounts, 2)
+	assert.Equal(t, &ServiceAccountMapping{Subject: "system:serviceaccount:monitoring:*", OrgID: 1, ServiceAccount: "monitoring"}, issuer.ServiceAccounts[0])
+	assert.Equal(t, map[string]string{"environment": "production"}, issuer.ServiceAccounts[1].Claims)
+
+	require.NoError(t, os.WriteFile(file, []byte("[[issuers]]\nissuer = \"a\"\n[[issuers.service_accounts]]\nsubject = \"a\"\nservice_account = \"a\"\n"), 0600))
+	_, err = readConfig(file)
+	require.EqualError(t, err, `jwt bearer issuer "a" has no audience`)
+
+	require.NoError(t, os.WriteFile(file, []byte("[[issuers]]\nissuer = \"a\"\naudience = [\"a\"]\n[[issuers.service_accounts]]\nsubject = \"[\"\nservice_account = \"a\"\n"), 0600))
+	_, err = readConfig(file)
+	require.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package jwtbearer
+
+import (
+	"errors"
+	"time"
+
+	jwtauth "github.com/grafana/grafana/pkg/services/auth/jwt"
+)
+
+// GrantType is the grant type of the JWT bearer token exchange, see RFC 7523
+const GrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+var (
+	ErrUntrustedIssuer         = errors.New("issuer of the token is not trusted")
+	ErrTokenWithoutExpiry      = errors.New("token has no expiration time")
+	ErrAudienceMismatch        = errors.New("token audience is not accepted")
+	ErrNoServiceAccountMapping = errors.New("token is not mapped to a service account")
+	ErrServiceAccountDisabled  = errors.New("service account is disabled")
+)
+
+// Config is the JWT bearer config file, that lists the trusted issuers and maps their tokens to service accounts
+type Config struct {
+	Issuers []*Issuer `toml:"issuers"`
+}
+
+type Issuer struct {
+	// Issuer is the value of the iss claim of the tokens of the issuer
+	Issuer string `toml:"issuer"`
+	// Audience lists the accepted values of the aud claim, the tokens must have one of them
+	Audience   []string `toml:"audience"`
+	KeyFile    string   `toml:"key_file"`
+	JWKSetFile string   `toml:"jwk_set_file"`
+	JWKSetURL  string   `toml:"jwk_set_url"`
+	// ServiceAccounts map the tokens to service accounts, the first matching mapping is used
+	ServiceAccounts []*ServiceAccountMapping `toml:"service_accounts"`
+
+	keySet jwtauth.KeySet
+}
+
+type ServiceAccountMapping struct {
+	// Subject matches the sub claim, and can contain * wildcards
+	Subject string `toml:"subject"`
+	// Claims are other claims the token must have
+	Claims         map[string]string `toml:"claims"`
+	OrgID          int64             `toml:"org_id"`
+	ServiceAccount string            `toml:"service_account"`
+}
+
+// AccessToken is a service account token issued in exchange of a JSON Web Token
+type AccessToken struct {
+	Token     string
+	ExpiresIn time.Duration
+
+	// Issuer and Subject are the claims of the exchanged token
+	Issuer           string
+	Subject          string
+	OrgID            int64
+	ServiceAccountID int64
+	TokenID          int64
+}
+
+// TokenResponse is the successful response of the token endpoint, see RFC 6749 section 5.1
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// ErrorResponse is the error response of the token endpoint, see RFC 6749 section 5.2
+type ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -19,6 +19,8 @@ type userAuthToken struct {
 	CreatedAt     int64
 	UpdatedAt     int64
 	RevokedAt     int64
+	RevokedReason string
+	LastActiveAt  int64
 	UnhashedToken string `xorm:"-"`
 }
 
@@ -45,6 +47,8 @@ func (uat *userAuthToken) fromUserToken(ut *models.UserToken) error {
 	uat.CreatedAt = ut.CreatedAt
 	uat.UpdatedAt = ut.UpdatedAt
 	uat.RevokedAt = ut.RevokedAt
+	uat.RevokedReason = ut.RevokedReason
+	uat.LastActiveAt = ut.LastActiveAt
 	uat.UnhashedToken = ut.UnhashedToken
 
 	return nil
@@ -67,6 +71,8 @@ func (uat *userAuthToken) toUserToken(ut *models.UserToken) error {
 	ut.CreatedAt = uat.CreatedAt
 	ut.UpdatedAt = uat.UpdatedAt
 	ut.RevokedAt = uat.RevokedAt
+	ut.RevokedReason = uat.RevokedReason
+	ut.LastActiveAt = uat.LastActiveAt
 	ut.UnhashedToken = uat.UnhashedToken
 
 	return nil
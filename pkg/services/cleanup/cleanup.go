@@ -8,6 +8,8 @@ import (
 	"path"
 	"time"
 
+	"github.com/grafana/grafana/pkg/services/annotations/annotationwebhooks"
+	"github.com/grafana/grafana/pkg/services/dashboardsnapshots"
 	"github.com/grafana/grafana/pkg/services/queryhistory"
 	"github.com/grafana/grafana/pkg/services/shorturls"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
@@ -20,25 +22,30 @@ import (
 )
 
 func ProvideService(cfg *setting.Cfg, serverLockService *serverlock.ServerLockService,
-	shortURLService shorturls.Service, store sqlstore.Store, queryHistoryService queryhistory.Service) *CleanUpService {
+	shortURLService shorturls.Service, store sqlstore.Store, queryHistoryService queryhistory.Service,
+	dashboardSnapshotsService *dashboardsnapshots.Service, annotationWebhookService annotationwebhooks.Service) *CleanUpService {
 	s := &CleanUpService{
-		Cfg:                 cfg,
-		ServerLockService:   serverLockService,
-		ShortURLService:     shortURLService,
-		QueryHistoryService: queryHistoryService,
-		store:               store,
-		log:                 log.New("cleanup"),
+		Cfg:                       cfg,
+		ServerLockService:         serverLockService,
+		ShortURLService:           shortURLService,
+		QueryHistoryService:       queryHistoryService,
+		DashboardSnapshotsService: dashboardSnapshotsService,
+		AnnotationWebhookService:  annotationWebhookService,
+		store:                     store,
+		log:                       log.New("cleanup"),
 	}
 	return s
 }
 
 type CleanUpService struct {
-	log                 log.Logger
-	store               sqlstore.Store
-	Cfg                 *setting.Cfg
-	ServerLockService   *serverlock.ServerLockService
-	ShortURLService     shorturls.Service
-	QueryHistoryService queryhistory.Service
+	log                       log.Logger
+	store                     sqlstore.Store
+	Cfg                       *setting.Cfg
+	ServerLockService         *serverlock.ServerLockService
+	ShortURLService           shorturls.Service
+	QueryHistoryService       queryhistory.Service
+	DashboardSnapshotsService *dashboardsnapshots.Service
+	AnnotationWebhookService  annotationwebhooks.Service
 }
 
 func (srv *CleanUpService) Run(ctx context.Context) error {
@@ -58,6 +65,7 @@ func (srv *CleanUpService) Run(ctx context.Context) error {
 			srv.expireOldUserInvites(ctx)
 			srv.deleteStaleShortURLs(ctx)
 			srv.deleteStaleQueryHistory(ctx)
+			srv.deleteStaleAnnotationWebhookRegions(ctx)
 			err := srv.ServerLockService.LockAndExecute(ctx, "delete old login attempts",
 				time.Minute*10, func(context.Context) {
 					srv.deleteOldLoginAttempts(ctx)
@@ -133,7 +141,7 @@ func (srv *CleanUpService) shouldCleanupTempFile(filemtime time.Time, now time.T
 
 func (srv *CleanUpService) deleteExpiredSnapshots(ctx context.Context) {
 	cmd := models.DeleteExpiredSnapshotsCommand{}
-	if err := srv.store.DeleteExpiredSnapshots(ctx, &cmd); err != nil {
+	if err := srv.DashboardSnapshotsService.DeleteExpiredSnapshots(ctx, &cmd); err != nil {
 		srv.log.Error("Failed to delete expired snapshots", "error", err.Error())
 	} else {
 		srv.log.Debug("Deleted expired snapshots", "rows affected", cmd.DeletedRows)
@@ -154,8 +162,14 @@ func (srv *CleanUpService) deleteOldLoginAttempts(ctx context.Context) {
 		return
 	}
 
+	// the attempts are kept as long as they can lock an account
+	maxAge := time.Minute * 10
+	if srv.Cfg.PasswordPolicyEnabled && srv.Cfg.PasswordPolicyLockoutAttempts > 0 && srv.Cfg.PasswordPolicyLockoutDuration > maxAge {
+		maxAge = srv.Cfg.PasswordPolicyLockoutDuration
+	}
+
 	cmd := models.DeleteOldLoginAttemptsCommand{
-		OlderThan: time.Now().Add(time.Minute * -10),
+		OlderThan: time.Now().Add(-maxAge),
 	}
 	if err := srv.store.DeleteOldLoginAttempts(ctx, &cmd); err != nil {
 		srv.log.Error("Problem deleting expired login attempts", "error", err.Error())
@@ -188,6 +202,15 @@ func (srv *CleanUpService) deleteStaleShortURLs(ctx context.Context) {
 	}
 }
 
+func (srv *CleanUpService) deleteStaleAnnotationWebhookRegions(ctx context.Context) {
+	rowsCount, err := srv.AnnotationWebhookService.DeleteStaleRegions(ctx)
+	if err != nil {
+		srv.log.Error("Problem deleting stale annotation webhook regions", "error", err.Error())
+	} else {
+		srv.log.Debug("Deleted stale annotation webhook regions", "rows affected", rowsCount)
+	}
+}
+
 func (srv *CleanUpService) deleteStaleQueryHistory(ctx context.Context) {
 	// Delete query history from 14+ days ago with exception of starred queries
 	maxQueryHistoryLifetime := time.Hour * 24 * 14
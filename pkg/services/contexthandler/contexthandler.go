@@ -4,6 +4,7 @@ package contexthandler
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -18,10 +19,14 @@ import (
 	loginpkg "github.com/grafana/grafana/pkg/login"
 	"github.com/grafana/grafana/pkg/middleware/cookies"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/contexthandler/authproxy"
 	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/passwordpolicy"
+	"github.com/grafana/grafana/pkg/services/publicdashboards"
 	"github.com/grafana/grafana/pkg/services/rendering"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/totp"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
 	"github.com/grafana/grafana/pkg/web"
@@ -35,9 +40,14 @@ const (
 
 const ServiceName = "ContextHandler"
 
+// requests to public dashboards carry their access token in the path, after this prefix
+const publicDashboardsPath = "/api/public/dashboards/"
+
 func ProvideService(cfg *setting.Cfg, tokenService models.UserTokenService, jwtService models.JWTService,
 	remoteCache *remotecache.RemoteCache, renderService rendering.Service, sqlStore sqlstore.Store,
-	tracer tracing.Tracer, authProxy *authproxy.AuthProxy, loginService login.Service, authenticator loginpkg.Authenticator) *ContextHandler {
+	tracer tracing.Tracer, authProxy *authproxy.AuthProxy, loginService login.Service, authenticator loginpkg.Authenticator,
+	totpService totp.Service, accessControl accesscontrol.AccessControl, passwordPolicyService passwordpolicy.Service,
+	publicDashboardService publicdashboards.Service) *ContextHandler {
 	return &ContextHandler{
 		Cfg:              cfg,
 		AuthTokenService: tokenService,
@@ -49,6 +59,10 @@ func ProvideService(cfg *setting.Cfg, tokenService models.UserTokenService, jwtS
 		authProxy:        authProxy,
 		authenticator:    authenticator,
 		loginService:     loginService,
+		totpService:      totpService,
+		accessControl:    accessControl,
+		passwordPolicy:   passwordPolicyService,
+		publicDashboards: publicDashboardService,
 	}
 }
 
@@ -64,6 +78,10 @@ type ContextHandler struct {
 	authProxy        *authproxy.AuthProxy
 	authenticator    loginpkg.Authenticator
 	loginService     login.Service
+	totpService      totp.Service
+	accessControl    accesscontrol.AccessControl
+	passwordPolicy   passwordpolicy.Service
+	publicDashboards publicdashboards.Service
 	// GetTime returns the current time.
 	// Stubbable by tests.
 	GetTime func() time.Time
@@ -132,7 +150,9 @@ func (h *ContextHandler) Middleware(mContext *web.Context) {
 	// then init session and look for userId in session
 	// then look for api key in session (special case for render calls via api)
 	// then test if anonymous access is enabled
+	// requests to public dashboards are always anonymous, whatever credentials they come with
 	switch {
+	case h.initContextWithPublicDashboard(reqContext):
 	case h.initContextWithRenderAuth(reqContext):
 	case h.initContextWithAPIKey(reqContext):
 	case h.initContextWithBasicAuth(reqContext, orgID):
@@ -185,6 +205,37 @@ func (h *ContextHandler) initContextWithAnonymousUser(reqContext *models.ReqCont
 	return true
 }
 
+// initContextWithPublicDashboard serves the requests to a public dashboard as an anonymous viewer of the
+// organization of the dashboard.
+func (h *ContextHandler) initContextWithPublicDashboard(reqContext *models.ReqContext) bool {
+	path := reqContext.Req.URL.Path
+	if h.Cfg.ServeFromSubPath {
+		path = strings.TrimPrefix(path, h.Cfg.AppSubURL)
+	}
+	if !strings.HasPrefix(path, publicDashboardsPath) || h.publicDashboards.IsDisabled() {
+		return false
+	}
+
+	_, span := h.tracer.Start(reqContext.Req.Context(), "initContextWithPublicDashboard")
+	defer span.End()
+
+	accessToken := strings.SplitN(strings.TrimPrefix(path, publicDashboardsPath), "/", 2)[0]
+	pd, err := h.publicDashboards.GetPublicDashboardByAccessToken(reqContext.Req.Context(), accessToken)
+	if err != nil {
+		if errors.Is(err, publicdashboards.ErrPublicDashboardNotFound) {
+			reqContext.JsonApiErr(http.StatusNotFound, "Public dashboard not found", nil)
+			return true
+		}
+		reqContext.JsonApiErr(http.StatusInternalServerError, "Failed to get public dashboard", err)
+		return true
+	}
+
+	reqContext.IsSignedIn = false
+	reqContext.AllowAnonymous = true
+	reqContext.SignedInUser = pd.SignedInUser()
+	return true
+}
+
 func (h *ContextHandler) initContextWithAPIKey(reqContext *models.ReqContext) bool {
 	header := reqContext.Req.Header.Get("Authorization")
 	parts := strings.SplitN(header, " ", 2)
@@ -242,6 +293,18 @@ func (h *ContextHandler) initContextWithAPIKey(reqContext *models.ReqContext) bo
 		return true
 	}
 
+	// the address of the peer, clients can set X-Real-IP and X-Forwarded-For to any address
+	ip, err := network.GetIPFromAddress(reqContext.Req.RemoteAddr)
+	if err != nil {
+		reqContext.Logger.Debug("Failed to parse client IP address", "err", err)
+	}
+	if len(apikey.IPAllowList) > 0 && !isIPAllowed(ip, apikey.IPAllowList) {
+		reqContext.JsonApiErr(401, InvalidAPIKey, models.ErrApiKeyIPNotAllowed)
+		return true
+	}
+
+	h.updateAPIKeyLastUsed(reqContext, apikey, ip, getTime())
+
 	if apikey.ServiceAccountId == nil || *apikey.ServiceAccountId < 1 { //There is no service account attached to the apikey
 		//Use the old APIkey method.  This provides backwards compatibility.
 		reqContext.SignedInUser = &models.SignedInUser{}
@@ -273,11 +336,74 @@ func (h *ContextHandler) initContextWithAPIKey(reqContext *models.ReqContext) bo
 		return true
 	}
 
+	// tokens with permissions are restricted to the ones the service account has as well
+	if len(apikey.Permissions) > 0 {
+		if h.accessControl.IsDisabled() {
+			reqContext.JsonApiErr(http.StatusUnauthorized, "API key with permissions requires access control", nil)
+			return true
+		}
+		if err := h.restrictAPIKeyPermissions(reqContext.Req.Context(), querySignedInUser.Result, apikey); err != nil {
+			reqContext.JsonApiErr(http.StatusInternalServerError, "Failed to restrict API key permissions", err)
+			return true
+		}
+	}
+
 	reqContext.IsSignedIn = true
 	reqContext.SignedInUser = querySignedInUser.Result
 	return true
 }
 
+func (h *ContextHandler) restrictAPIKeyPermissions(ctx context.Context, user *models.SignedInUser, apikey *models.ApiKey) error {
+	permissions, err := h.accessControl.GetUserPermissions(ctx, user, accesscontrol.Options{})
+	if err != nil {
+		return err
+	}
+
+	restriction := make(map[string][]string, len(apikey.Permissions))
+	for _, p := range apikey.Permissions {
+		restriction[p.Action] = append(restriction[p.Action], p.Scope)
+	}
+
+	user.Permissions = map[int64]map[string][]string{
+		user.OrgId: accesscontrol.IntersectPermissions(accesscontrol.GroupScopesByAction(permissions), restriction),
+	}
+	return nil
+}
+
+// updateAPIKeyLastUsed records the use of the API key, at most once a minute unless it is used from another IP address
+func (h *ContextHandler) updateAPIKeyLastUsed(reqContext *models.ReqContext, apikey *models.ApiKey, ip net.IP, now time.Time) {
+	lastUsedIP := ""
+	if ip != nil {
+		lastUsedIP = ip.String()
+	}
+	if apikey.LastUsedAt != nil && now.Sub(*apikey.LastUsedAt) < time.Minute && apikey.LastUsedIP == lastUsedIP {
+		return
+	}
+
+	cmd := models.UpdateApiKeyLastUsedCommand{Id: apikey.Id, LastUsedAt: now, LastUsedIP: lastUsedIP}
+	if err := h.SQLStore.UpdateApiKeyLastUsed(reqContext.Req.Context(), &cmd); err != nil {
+		reqContext.Logger.Warn("Failed to update API key last use", "id", apikey.Id, "err", err)
+	}
+}
+
+func isIPAllowed(ip net.IP, ipAllowList []string) bool {
+	if ip == nil {
+		return false
+	}
+	for _, entry := range ipAllowList {
+		if allowed := net.ParseIP(entry); allowed != nil {
+			if allowed.Equal(ip) {
+				return true
+			}
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *ContextHandler) initContextWithBasicAuth(reqContext *models.ReqContext, orgID int64) bool {
 	if !h.Cfg.BasicAuthEnabled {
 		return false
@@ -318,6 +444,33 @@ func (h *ContextHandler) initContextWithBasicAuth(reqContext *models.ReqContext,
 
 	user := authQuery.User
 
+	// a password alone must not be enough for users that enabled a second factor, or that must enroll one
+	if authQuery.AuthModule == "grafana" {
+		enabled, err := h.totpService.IsEnabled(ctx, user.Id)
+		if err == nil && !enabled {
+			enabled, err = h.totpService.IsRequired(ctx, user.Id)
+		}
+		if err != nil {
+			reqContext.JsonApiErr(500, "Failed to authenticate user", err)
+			return true
+		}
+		if enabled {
+			reqContext.JsonApiErr(401, totp.ErrBasicAuthForbidden.Error(), totp.ErrBasicAuthForbidden)
+			return true
+		}
+
+		// expired passwords can only be changed by logging in
+		expired, err := h.passwordPolicy.IsExpired(ctx, user)
+		if err != nil {
+			reqContext.JsonApiErr(500, "Failed to authenticate user", err)
+			return true
+		}
+		if expired {
+			reqContext.JsonApiErr(401, "Password expired", nil)
+			return true
+		}
+	}
+
 	query := models.GetSignedInUserQuery{UserId: user.Id, OrgId: orgID}
 	if err := h.SQLStore.GetSignedInUserWithCacheCtx(ctx, &query); err != nil {
 		reqContext.Logger.Error(
@@ -5,12 +5,15 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/services/totp/totptest"
 	"github.com/grafana/grafana/pkg/util"
 	"github.com/grafana/grafana/pkg/web"
 	"github.com/stretchr/testify/assert"
@@ -79,6 +82,49 @@ func TestTokenRotationAtEndOfRequest(t *testing.T) {
 	assert.True(t, foundLoginCookie, "Could not find cookie")
 }
 
+func TestBasicAuthWithTwoFactorAuthentication(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		totp    *totptest.FakeService
+		refused bool
+	}{
+		{desc: "is not refused without two-factor authentication", totp: &totptest.FakeService{}},
+		{desc: "is refused when two-factor authentication is enabled", totp: &totptest.FakeService{ExpectedEnabled: true}, refused: true},
+		{desc: "is refused when two-factor authentication is required", totp: &totptest.FakeService{ExpectedRequired: true}, refused: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			ctxHdlr := getContextHandler(t)
+			ctxHdlr.Cfg.BasicAuthEnabled = true
+			ctxHdlr.authenticator = &passwordAuthenticator{user: &models.User{Id: userID}}
+			ctxHdlr.totpService = tc.totp
+
+			reqContext, rr, err := initTokenRotationScenario(context.Background(), t, ctxHdlr)
+			require.NoError(t, err)
+			reqContext.Req.Header.Set("Authorization", util.GetBasicAuthHeader("user", "password"))
+
+			require.True(t, ctxHdlr.initContextWithBasicAuth(reqContext, 1))
+			assert.Equal(t, tc.refused, strings.Contains(rr.Body.String(), totp.ErrBasicAuthForbidden.Error()))
+			if tc.refused {
+				assert.Equal(t, http.StatusUnauthorized, rr.Code)
+				assert.False(t, reqContext.IsSignedIn)
+			}
+		})
+	}
+}
+
+// passwordAuthenticator authenticates any user with a Grafana password
+type passwordAuthenticator struct {
+	user *models.User
+}
+
+func (a *passwordAuthenticator) AuthenticateUser(c context.Context, query *models.LoginUserQuery) error {
+	query.User = a.user
+	query.AuthModule = "grafana"
+	return nil
+}
+
 func initTokenRotationScenario(ctx context.Context, t *testing.T, ctxHdlr *ContextHandler) (
 	*models.ReqContext, *httptest.ResponseRecorder, error) {
 	t.Helper()
@@ -0,0 +1,112 @@
+package correlations
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func (s *CorrelationsService) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(s.AccessControl)
+	uidScope := datasources.ScopeProvider.GetResourceScopeUID(accesscontrol.Parameter(":uid"))
+
+	s.RouteRegister.Group("/api/datasources/uid/:uid/correlations", func(entities routing.RouteRegister) {
+		entities.Get("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(datasources.ActionRead, uidScope)), routing.Wrap(s.getCorrelationsHandler))
+		entities.Post("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(datasources.ActionWrite, uidScope)), routing.Wrap(s.createHandler))
+		entities.Get("/:correlationUID", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(datasources.ActionRead, uidScope)), routing.Wrap(s.getCorrelationHandler))
+		entities.Patch("/:correlationUID", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(datasources.ActionWrite, uidScope)), routing.Wrap(s.updateHandler))
+		entities.Delete("/:correlationUID", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(datasources.ActionWrite, uidScope)), routing.Wrap(s.deleteHandler))
+	})
+}
+
+func (s *CorrelationsService) createHandler(c *models.ReqContext) response.Response {
+	cmd := CreateCorrelationCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.OrgID = c.OrgId
+	cmd.SourceUID = web.Params(c.Req)[":uid"]
+
+	correlation, err := s.CreateCorrelation(c.Req.Context(), cmd)
+	if err != nil {
+		return correlationErrorResponse(err, "Failed to add correlation")
+	}
+
+	return response.JSON(http.StatusOK, CorrelationResponse{Message: "Correlation created", Result: correlation})
+}
+
+func (s *CorrelationsService) updateHandler(c *models.ReqContext) response.Response {
+	cmd := UpdateCorrelationCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	cmd.OrgID = c.OrgId
+	cmd.SourceUID = web.Params(c.Req)[":uid"]
+	cmd.UID = web.Params(c.Req)[":correlationUID"]
+
+	correlation, err := s.UpdateCorrelation(c.Req.Context(), cmd)
+	if err != nil {
+		return correlationErrorResponse(err, "Failed to update correlation")
+	}
+
+	return response.JSON(http.StatusOK, CorrelationResponse{Message: "Correlation updated", Result: correlation})
+}
+
+func (s *CorrelationsService) deleteHandler(c *models.ReqContext) response.Response {
+	err := s.DeleteCorrelation(c.Req.Context(), DeleteCorrelationCommand{
+		OrgID:     c.OrgId,
+		SourceUID: web.Params(c.Req)[":uid"],
+		UID:       web.Params(c.Req)[":correlationUID"],
+	})
+	if err != nil {
+		return correlationErrorResponse(err, "Failed to delete correlation")
+	}
+
+	return response.JSON(http.StatusOK, DeleteCorrelationResponse{Message: "Correlation deleted"})
+}
+
+func (s *CorrelationsService) getCorrelationHandler(c *models.ReqContext) response.Response {
+	correlation, err := s.GetCorrelation(c.Req.Context(), GetCorrelationQuery{
+		OrgID:     c.OrgId,
+		SourceUID: web.Params(c.Req)[":uid"],
+		UID:       web.Params(c.Req)[":correlationUID"],
+	})
+	if err != nil {
+		return correlationErrorResponse(err, "Failed to get correlation")
+	}
+
+	return response.JSON(http.StatusOK, correlation)
+}
+
+func (s *CorrelationsService) getCorrelationsHandler(c *models.ReqContext) response.Response {
+	correlations, err := s.GetCorrelationsBySourceUID(c.Req.Context(), GetCorrelationsBySourceUIDQuery{
+		OrgID:     c.OrgId,
+		SourceUID: web.Params(c.Req)[":uid"],
+	})
+	if err != nil {
+		return correlationErrorResponse(err, "Failed to get correlations")
+	}
+
+	return response.JSON(http.StatusOK, correlations)
+}
+
+func correlationErrorResponse(err error, message string) response.Response {
+	switch {
+	case errors.Is(err, ErrCorrelationNotFound):
+		return response.Error(http.StatusNotFound, "Correlation not found", err)
+	case errors.Is(err, ErrSourceDataSourceDoesNotExists):
+		return response.Error(http.StatusNotFound, "Data source not found", err)
+	case errors.Is(err, ErrTargetDataSourceDoesNotExists),
+		errors.Is(err, ErrCorrelationFieldRequired),
+		errors.Is(err, ErrCorrelationQueryRequired):
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+	return response.Error(http.StatusInternalServerError, message, err)
+}
@@ -0,0 +1,67 @@
+package correlations
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestApplyCorrelations(t *testing.T) {
+	s := CorrelationsService{
+		Cfg:   &setting.Cfg{AppSubURL: "/grafana"},
+		cache: localcache.New(cacheTTL, cacheTTL),
+		log:   log.New("correlations.test"),
+	}
+	s.cache.SetDefault(cacheKey(1, "prometheus"), []Correlation{
+		{
+			UID:       "host-logs",
+			SourceUID: "prometheus",
+			TargetUID: "elasticsearch",
+			Label:     "Host logs",
+			Field:     "host",
+			Query:     simplejson.NewFromAny(map[string]interface{}{"query": "host:${__field.labels.host}"}),
+		},
+	})
+
+	sharedConfig := &data.FieldConfig{DisplayNameFromDS: "cpu"}
+	resp := backend.NewQueryDataResponse()
+	resp.Responses["A"] = backend.DataResponse{Frames: data.Frames{
+		data.NewFrame("cpu",
+			data.NewField("Time", nil, []float64{}),
+			data.NewField("Value", data.Labels{"host": "web-1"}, []float64{}).SetConfig(sharedConfig),
+		),
+	}}
+
+	s.ApplyCorrelations(context.Background(), 1, "prometheus", resp)
+	s.ApplyCorrelations(context.Background(), 1, "prometheus", resp)
+
+	fields := resp.Responses["A"].Frames[0].Fields
+	require.Nil(t, fields[0].Config)
+	require.Len(t, fields[1].Config.Links, 1)
+	require.Equal(t, "cpu", fields[1].Config.DisplayNameFromDS)
+	require.Len(t, sharedConfig.Links, 0, "the original field config should not be modified")
+
+	link := fields[1].Config.Links[0]
+	require.Equal(t, "Host logs", link.Title)
+	require.True(t, strings.HasPrefix(link.URL, "/grafana/explore?orgId=1&left="))
+	require.Contains(t, link.URL, "${__field.labels.host}")
+	require.Contains(t, link.URL, "${__from}")
+
+	left, err := url.QueryUnescape(strings.TrimPrefix(link.URL, "/grafana/explore?orgId=1&left="))
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"datasource": "elasticsearch",
+		"queries": [{"refId": "A", "query": "host:${__field.labels.host}", "datasource": {"uid": "elasticsearch"}}],
+		"range": {"from": "${__from}", "to": "${__to}"}
+	}`, left)
+}
@@ -0,0 +1,227 @@
+package correlations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/events"
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const cacheTTL = time.Minute
+
+// escapedVariable matches data link variables such as ${__value.raw} after
+// they have been query escaped.
+var escapedVariable = regexp.MustCompile(`%24%7B(.+?)%7D`)
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, routeRegister routing.RouteRegister,
+	dataSourceService datasources.DataSourceService, accessControl accesscontrol.AccessControl, bus bus.Bus) *CorrelationsService {
+	s := &CorrelationsService{
+		SQLStore:          sqlStore,
+		Cfg:               cfg,
+		RouteRegister:     routeRegister,
+		DataSourceService: dataSourceService,
+		AccessControl:     accessControl,
+		cache:             localcache.New(cacheTTL, 10*time.Minute),
+		log:               log.New("correlations"),
+	}
+
+	s.registerAPIEndpoints()
+	bus.AddEventListener(s.handleDataSourceDeleted)
+
+	return s
+}
+
+type Service interface {
+	CreateCorrelation(ctx context.Context, cmd CreateCorrelationCommand) (Correlation, error)
+	UpdateCorrelation(ctx context.Context, cmd UpdateCorrelationCommand) (Correlation, error)
+	DeleteCorrelation(ctx context.Context, cmd DeleteCorrelationCommand) error
+	GetCorrelation(ctx context.Context, query GetCorrelationQuery) (Correlation, error)
+	GetCorrelationsBySourceUID(ctx context.Context, query GetCorrelationsBySourceUIDQuery) ([]Correlation, error)
+	// ApplyCorrelations adds a data link for every correlation of the source
+	// data source to the matching fields of the response frames.
+	ApplyCorrelations(ctx context.Context, orgID int64, sourceUID string, resp *backend.QueryDataResponse)
+}
+
+type CorrelationsService struct {
+	SQLStore          *sqlstore.SQLStore
+	Cfg               *setting.Cfg
+	RouteRegister     routing.RouteRegister
+	DataSourceService datasources.DataSourceService
+	AccessControl     accesscontrol.AccessControl
+	cache             *localcache.CacheService
+	log               log.Logger
+}
+
+func (s CorrelationsService) CreateCorrelation(ctx context.Context, cmd CreateCorrelationCommand) (Correlation, error) {
+	return s.createCorrelation(ctx, cmd)
+}
+
+func (s CorrelationsService) UpdateCorrelation(ctx context.Context, cmd UpdateCorrelationCommand) (Correlation, error) {
+	return s.updateCorrelation(ctx, cmd)
+}
+
+func (s CorrelationsService) DeleteCorrelation(ctx context.Context, cmd DeleteCorrelationCommand) error {
+	return s.deleteCorrelation(ctx, cmd)
+}
+
+func (s CorrelationsService) GetCorrelation(ctx context.Context, query GetCorrelationQuery) (Correlation, error) {
+	return s.getCorrelation(ctx, query)
+}
+
+func (s CorrelationsService) GetCorrelationsBySourceUID(ctx context.Context, query GetCorrelationsBySourceUIDQuery) ([]Correlation, error) {
+	return s.getCorrelationsBySourceUID(ctx, query)
+}
+
+func (s CorrelationsService) ApplyCorrelations(ctx context.Context, orgID int64, sourceUID string, resp *backend.QueryDataResponse) {
+	if resp == nil || sourceUID == "" {
+		return
+	}
+
+	correlations, err := s.cachedCorrelations(ctx, orgID, sourceUID)
+	if err != nil {
+		s.log.Warn("Failed to get correlations", "datasource", sourceUID, "error", err)
+		return
+	}
+	if len(correlations) == 0 {
+		return
+	}
+
+	type correlationLink struct {
+		correlation Correlation
+		link        data.DataLink
+	}
+	links := make([]correlationLink, 0, len(correlations))
+	for _, c := range correlations {
+		link, err := s.dataLink(orgID, c)
+		if err != nil {
+			s.log.Warn("Failed to create correlation link", "correlation", c.UID, "error", err)
+			continue
+		}
+		links = append(links, correlationLink{correlation: c, link: link})
+	}
+
+	for _, dr := range resp.Responses {
+		for _, frame := range dr.Frames {
+			for _, field := range frame.Fields {
+				for _, cl := range links {
+					if matchesField(cl.correlation, field) {
+						addLink(field, cl.link)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (s CorrelationsService) cachedCorrelations(ctx context.Context, orgID int64, sourceUID string) ([]Correlation, error) {
+	key := cacheKey(orgID, sourceUID)
+	if cached, ok := s.cache.Get(key); ok {
+		return cached.([]Correlation), nil
+	}
+
+	correlations, err := s.getCorrelationsBySourceUID(ctx, GetCorrelationsBySourceUIDQuery{OrgID: orgID, SourceUID: sourceUID})
+	if err != nil {
+		return nil, err
+	}
+
+	s.cache.SetDefault(key, correlations)
+	return correlations, nil
+}
+
+func (s CorrelationsService) invalidateCache(orgID int64, sourceUID string) {
+	s.cache.Delete(cacheKey(orgID, sourceUID))
+}
+
+func (s CorrelationsService) handleDataSourceDeleted(ctx context.Context, e *events.DataSourceDeleted) error {
+	if e.UID == "" {
+		return nil
+	}
+	return s.deleteCorrelationsByDataSource(ctx, e.OrgID, e.UID)
+}
+
+// dataLink returns a link that opens the query of the correlation in Explore.
+// Data link variables in the query are left unescaped so the frontend can
+// interpolate them.
+func (s CorrelationsService) dataLink(orgID int64, c Correlation) (data.DataLink, error) {
+	query := map[string]interface{}{}
+	for k, v := range c.Query.MustMap() {
+		query[k] = v
+	}
+	if _, ok := query["refId"]; !ok {
+		query["refId"] = "A"
+	}
+	query["datasource"] = map[string]interface{}{"uid": c.TargetUID}
+
+	state, err := json.Marshal(map[string]interface{}{
+		"datasource": c.TargetUID,
+		"queries":    []interface{}{query},
+		"range":      map[string]string{"from": "${__from}", "to": "${__to}"},
+	})
+	if err != nil {
+		return data.DataLink{}, err
+	}
+
+	left := escapedVariable.ReplaceAllStringFunc(url.QueryEscape(string(state)), func(m string) string {
+		unescaped, err := url.QueryUnescape(m)
+		if err != nil {
+			return m
+		}
+		return unescaped
+	})
+
+	title := c.Label
+	if title == "" {
+		title = c.Field
+	}
+
+	return data.DataLink{
+		Title: title,
+		URL:   fmt.Sprintf("%s/explore?orgId=%d&left=%s", s.Cfg.AppSubURL, orgID, left),
+	}, nil
+}
+
+// matchesField returns true if the field is named like the correlation field
+// or has a label with that name.
+func matchesField(c Correlation, field *data.Field) bool {
+	if field.Name == c.Field {
+		return true
+	}
+	_, ok := field.Labels[c.Field]
+	return ok
+}
+
+// addLink adds the link to a copy of the field config, the config may be
+// shared with cached frames.
+func addLink(field *data.Field, link data.DataLink) {
+	config := data.FieldConfig{}
+	if field.Config != nil {
+		for _, existing := range field.Config.Links {
+			if existing.URL == link.URL {
+				return
+			}
+		}
+		config = *field.Config
+	}
+
+	config.Links = append(append([]data.DataLink{}, config.Links...), link)
+	field.Config = &config
+}
+
+func cacheKey(orgID int64, sourceUID string) string {
+	return fmt.Sprintf("correlations-%d-%s", orgID, sourceUID)
+}
@@ -0,0 +1,106 @@
+//go:build integration
+// +build integration
+
+package correlations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/events"
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	fakeDatasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func setupTestService(t *testing.T) CorrelationsService {
+	t.Helper()
+
+	return CorrelationsService{
+		SQLStore: sqlstore.InitTestDB(t),
+		Cfg:      setting.NewCfg(),
+		DataSourceService: &fakeDatasources.FakeDataSourceService{
+			DataSources: []*models.DataSource{
+				{Id: 1, Uid: "loki", OrgId: 1, Type: models.DS_LOKI},
+				{Id: 2, Uid: "tempo", OrgId: 1, Type: "tempo"},
+			},
+		},
+		cache: localcache.New(cacheTTL, cacheTTL),
+		log:   log.New("correlations.test"),
+	}
+}
+
+func TestCorrelationsCRUD(t *testing.T) {
+	ctx := context.Background()
+	s := setupTestService(t)
+
+	created, err := s.CreateCorrelation(ctx, CreateCorrelationCommand{
+		OrgID:     1,
+		SourceUID: "loki",
+		TargetUID: "tempo",
+		Label:     "Trace",
+		Field:     "traceID",
+		Query:     simplejson.NewFromAny(map[string]interface{}{"query": "${__value.raw}"}),
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, created.UID)
+
+	t.Run("should validate the data sources", func(t *testing.T) {
+		_, err := s.CreateCorrelation(ctx, CreateCorrelationCommand{
+			OrgID:     1,
+			SourceUID: "loki",
+			TargetUID: "unknown",
+			Field:     "traceID",
+			Query:     simplejson.NewFromAny(map[string]interface{}{"query": "${__value.raw}"}),
+		})
+		require.ErrorIs(t, err, ErrTargetDataSourceDoesNotExists)
+	})
+
+	t.Run("should require a field and a query", func(t *testing.T) {
+		_, err := s.CreateCorrelation(ctx, CreateCorrelationCommand{OrgID: 1, SourceUID: "loki", TargetUID: "tempo"})
+		require.ErrorIs(t, err, ErrCorrelationFieldRequired)
+
+		_, err = s.CreateCorrelation(ctx, CreateCorrelationCommand{OrgID: 1, SourceUID: "loki", TargetUID: "tempo", Field: "traceID"})
+		require.ErrorIs(t, err, ErrCorrelationQueryRequired)
+	})
+
+	t.Run("should update a correlation", func(t *testing.T) {
+		label := "Open trace"
+		updated, err := s.UpdateCorrelation(ctx, UpdateCorrelationCommand{OrgID: 1, SourceUID: "loki", UID: created.UID, Label: &label})
+		require.NoError(t, err)
+		require.Equal(t, "Open trace", updated.Label)
+		require.Equal(t, "traceID", updated.Field)
+
+		found, err := s.GetCorrelation(ctx, GetCorrelationQuery{OrgID: 1, SourceUID: "loki", UID: created.UID})
+		require.NoError(t, err)
+		require.Equal(t, "Open trace", found.Label)
+		require.Equal(t, "${__value.raw}", found.Query.Get("query").MustString())
+	})
+
+	t.Run("should not return correlations of other orgs", func(t *testing.T) {
+		_, err := s.GetCorrelation(ctx, GetCorrelationQuery{OrgID: 2, SourceUID: "loki", UID: created.UID})
+		require.ErrorIs(t, err, ErrCorrelationNotFound)
+
+		correlations, err := s.GetCorrelationsBySourceUID(ctx, GetCorrelationsBySourceUIDQuery{OrgID: 1, SourceUID: "loki"})
+		require.NoError(t, err)
+		require.Len(t, correlations, 1)
+	})
+
+	t.Run("should delete correlations of deleted data sources", func(t *testing.T) {
+		err := s.handleDataSourceDeleted(ctx, &events.DataSourceDeleted{OrgID: 1, UID: "tempo"})
+		require.NoError(t, err)
+
+		correlations, err := s.GetCorrelationsBySourceUID(ctx, GetCorrelationsBySourceUIDQuery{OrgID: 1, SourceUID: "loki"})
+		require.NoError(t, err)
+		require.Len(t, correlations, 0)
+
+		err = s.DeleteCorrelation(ctx, DeleteCorrelationCommand{OrgID: 1, SourceUID: "loki", UID: created.UID})
+		require.ErrorIs(t, err, ErrCorrelationNotFound)
+	})
+}
@@ -0,0 +1,48 @@
+package correlationstest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+
+	"github.com/grafana/grafana/pkg/services/correlations"
+)
+
+type FakeCorrelationsService struct {
+	ExpectedCorrelation  correlations.Correlation
+	ExpectedCorrelations []correlations.Correlation
+	ExpectedError        error
+
+	// ApplyFn is called by ApplyCorrelations when set.
+	ApplyFn func(orgID int64, sourceUID string, resp *backend.QueryDataResponse)
+}
+
+func NewCorrelationsServiceFake() *FakeCorrelationsService {
+	return &FakeCorrelationsService{}
+}
+
+func (f *FakeCorrelationsService) CreateCorrelation(ctx context.Context, cmd correlations.CreateCorrelationCommand) (correlations.Correlation, error) {
+	return f.ExpectedCorrelation, f.ExpectedError
+}
+
+func (f *FakeCorrelationsService) UpdateCorrelation(ctx context.Context, cmd correlations.UpdateCorrelationCommand) (correlations.Correlation, error) {
+	return f.ExpectedCorrelation, f.ExpectedError
+}
+
+func (f *FakeCorrelationsService) DeleteCorrelation(ctx context.Context, cmd correlations.DeleteCorrelationCommand) error {
+	return f.ExpectedError
+}
+
+func (f *FakeCorrelationsService) GetCorrelation(ctx context.Context, query correlations.GetCorrelationQuery) (correlations.Correlation, error) {
+	return f.ExpectedCorrelation, f.ExpectedError
+}
+
+func (f *FakeCorrelationsService) GetCorrelationsBySourceUID(ctx context.Context, query correlations.GetCorrelationsBySourceUIDQuery) ([]correlations.Correlation, error) {
+	return f.ExpectedCorrelations, f.ExpectedError
+}
+
+func (f *FakeCorrelationsService) ApplyCorrelations(ctx context.Context, orgID int64, sourceUID string, resp *backend.QueryDataResponse) {
+	if f.ApplyFn != nil {
+		f.ApplyFn(orgID, sourceUID, resp)
+	}
+}
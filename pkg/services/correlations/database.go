@@ -0,0 +1,164 @@
+package correlations
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func (s CorrelationsService) createCorrelation(ctx context.Context, cmd CreateCorrelationCommand) (Correlation, error) {
+	if cmd.Field == "" {
+		return Correlation{}, ErrCorrelationFieldRequired
+	}
+	if cmd.Query == nil || len(cmd.Query.MustMap()) == 0 {
+		return Correlation{}, ErrCorrelationQueryRequired
+	}
+	if err := s.checkDataSources(ctx, cmd.OrgID, cmd.SourceUID, cmd.TargetUID); err != nil {
+		return Correlation{}, err
+	}
+
+	now := time.Now().Unix()
+	correlation := Correlation{
+		UID:         util.GenerateShortUID(),
+		OrgID:       cmd.OrgID,
+		SourceUID:   cmd.SourceUID,
+		TargetUID:   cmd.TargetUID,
+		Label:       cmd.Label,
+		Description: cmd.Description,
+		Field:       cmd.Field,
+		Query:       cmd.Query,
+		Created:     now,
+		Updated:     now,
+	}
+
+	err := s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		_, err := session.Insert(&correlation)
+		return err
+	})
+	if err != nil {
+		return Correlation{}, err
+	}
+
+	s.invalidateCache(cmd.OrgID, cmd.SourceUID)
+	return correlation, nil
+}
+
+func (s CorrelationsService) updateCorrelation(ctx context.Context, cmd UpdateCorrelationCommand) (Correlation, error) {
+	correlation, err := s.getCorrelation(ctx, GetCorrelationQuery{OrgID: cmd.OrgID, SourceUID: cmd.SourceUID, UID: cmd.UID})
+	if err != nil {
+		return Correlation{}, err
+	}
+
+	if cmd.TargetUID != nil {
+		if err := s.checkDataSources(ctx, cmd.OrgID, cmd.SourceUID, *cmd.TargetUID); err != nil {
+			return Correlation{}, err
+		}
+		correlation.TargetUID = *cmd.TargetUID
+	}
+	if cmd.Label != nil {
+		correlation.Label = *cmd.Label
+	}
+	if cmd.Description != nil {
+		correlation.Description = *cmd.Description
+	}
+	if cmd.Field != nil {
+		if *cmd.Field == "" {
+			return Correlation{}, ErrCorrelationFieldRequired
+		}
+		correlation.Field = *cmd.Field
+	}
+	if cmd.Query != nil {
+		if len(cmd.Query.MustMap()) == 0 {
+			return Correlation{}, ErrCorrelationQueryRequired
+		}
+		correlation.Query = cmd.Query
+	}
+	correlation.Updated = time.Now().Unix()
+
+	err = s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		_, err := session.ID(correlation.ID).AllCols().Update(&correlation)
+		return err
+	})
+	if err != nil {
+		return Correlation{}, err
+	}
+
+	s.invalidateCache(cmd.OrgID, cmd.SourceUID)
+	return correlation, nil
+}
+
+func (s CorrelationsService) deleteCorrelation(ctx context.Context, cmd DeleteCorrelationCommand) error {
+	err := s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		affected, err := session.Delete(&Correlation{OrgID: cmd.OrgID, SourceUID: cmd.SourceUID, UID: cmd.UID})
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrCorrelationNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidateCache(cmd.OrgID, cmd.SourceUID)
+	return nil
+}
+
+func (s CorrelationsService) getCorrelation(ctx context.Context, query GetCorrelationQuery) (Correlation, error) {
+	correlation := Correlation{OrgID: query.OrgID, SourceUID: query.SourceUID, UID: query.UID}
+	err := s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		found, err := session.Get(&correlation)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return ErrCorrelationNotFound
+		}
+		return nil
+	})
+	return correlation, err
+}
+
+func (s CorrelationsService) getCorrelationsBySourceUID(ctx context.Context, query GetCorrelationsBySourceUIDQuery) ([]Correlation, error) {
+	correlations := make([]Correlation, 0)
+	err := s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		return session.Where("org_id = ? AND source_uid = ?", query.OrgID, query.SourceUID).Asc("id").Find(&correlations)
+	})
+	return correlations, err
+}
+
+func (s CorrelationsService) deleteCorrelationsByDataSource(ctx context.Context, orgID int64, uid string) error {
+	err := s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		_, err := session.Where("org_id = ? AND (source_uid = ? OR target_uid = ?)", orgID, uid, uid).Delete(&Correlation{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	s.cache.Flush()
+	return nil
+}
+
+func (s CorrelationsService) checkDataSources(ctx context.Context, orgID int64, sourceUID string, targetUID string) error {
+	if err := s.DataSourceService.GetDataSource(ctx, &models.GetDataSourceQuery{OrgId: orgID, Uid: sourceUID}); err != nil {
+		if err == models.ErrDataSourceNotFound {
+			return ErrSourceDataSourceDoesNotExists
+		}
+		return err
+	}
+
+	if err := s.DataSourceService.GetDataSource(ctx, &models.GetDataSourceQuery{OrgId: orgID, Uid: targetUID}); err != nil {
+		if err == models.ErrDataSourceNotFound {
+			return ErrTargetDataSourceDoesNotExists
+		}
+		return err
+	}
+
+	return nil
+}
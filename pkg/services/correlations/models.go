@@ -0,0 +1,79 @@
+package correlations
+
+import (
+	"errors"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+var (
+	ErrCorrelationNotFound           = errors.New("correlation not found")
+	ErrSourceDataSourceDoesNotExists = errors.New("source data source does not exist")
+	ErrTargetDataSourceDoesNotExists = errors.New("target data source does not exist")
+	ErrCorrelationFieldRequired      = errors.New("correlation field is required")
+	ErrCorrelationQueryRequired      = errors.New("correlation query is required")
+)
+
+// Correlation links a field in the results of the source data source to a
+// query against the target data source. The query may use data link
+// variables such as ${__value.raw} to reference the value of the field.
+type Correlation struct {
+	ID          int64            `xorm:"pk autoincr 'id'" json:"-"`
+	UID         string           `xorm:"uid" json:"uid"`
+	OrgID       int64            `xorm:"org_id" json:"-"`
+	SourceUID   string           `xorm:"source_uid" json:"sourceUid"`
+	TargetUID   string           `xorm:"target_uid" json:"targetUid"`
+	Label       string           `json:"label"`
+	Description string           `json:"description"`
+	Field       string           `json:"field"`
+	Query       *simplejson.Json `json:"query"`
+	Created     int64            `json:"created"`
+	Updated     int64            `json:"updated"`
+}
+
+type CreateCorrelationCommand struct {
+	OrgID       int64            `json:"-"`
+	SourceUID   string           `json:"-"`
+	TargetUID   string           `json:"targetUid" binding:"Required"`
+	Label       string           `json:"label"`
+	Description string           `json:"description"`
+	Field       string           `json:"field"`
+	Query       *simplejson.Json `json:"query"`
+}
+
+type UpdateCorrelationCommand struct {
+	OrgID       int64            `json:"-"`
+	SourceUID   string           `json:"-"`
+	UID         string           `json:"-"`
+	TargetUID   *string          `json:"targetUid"`
+	Label       *string          `json:"label"`
+	Description *string          `json:"description"`
+	Field       *string          `json:"field"`
+	Query       *simplejson.Json `json:"query"`
+}
+
+type DeleteCorrelationCommand struct {
+	OrgID     int64
+	SourceUID string
+	UID       string
+}
+
+type GetCorrelationQuery struct {
+	OrgID     int64
+	SourceUID string
+	UID       string
+}
+
+type GetCorrelationsBySourceUIDQuery struct {
+	OrgID     int64
+	SourceUID string
+}
+
+type CorrelationResponse struct {
+	Message string      `json:"message"`
+	Result  Correlation `json:"result"`
+}
+
+type DeleteCorrelationResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,87 @@
+package dashboardlint
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func (s *DashboardLintService) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(s.accessControl)
+
+	// the missing-datasource rule tells whether data sources exist, so linting is limited to the users saving dashboards
+	lintEval := accesscontrol.EvalAny(
+		accesscontrol.EvalPermission(dashboards.ActionDashboardsCreate),
+		accesscontrol.EvalPermission(dashboards.ActionDashboardsWrite),
+	)
+
+	s.routeRegister.Group("/api/dashboards/lint", func(lintRoute routing.RouteRegister) {
+		lintRoute.Post("/", authorize(middleware.ReqEditorRole, lintEval), routing.Wrap(s.lintDashboardHandler))
+		lintRoute.Get("/rules", middleware.ReqSignedIn, routing.Wrap(s.getRulesHandler))
+		lintRoute.Put("/rules/:rule", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionRulesWrite)), routing.Wrap(s.configureRuleHandler))
+		lintRoute.Delete("/rules/:rule", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionRulesWrite)), routing.Wrap(s.resetRuleHandler))
+	})
+}
+
+// POST /api/dashboards/lint
+func (s *DashboardLintService) lintDashboardHandler(c *models.ReqContext) response.Response {
+	cmd := LintDashboardCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if cmd.Dashboard == nil {
+		return response.Error(http.StatusBadRequest, ErrMissingDashboard.Error(), nil)
+	}
+
+	report, err := s.lint(c.Req.Context(), c.OrgId, cmd.Dashboard)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to lint dashboard", err)
+	}
+	return response.JSON(http.StatusOK, report)
+}
+
+// GET /api/dashboards/lint/rules
+func (s *DashboardLintService) getRulesHandler(c *models.ReqContext) response.Response {
+	rules, err := s.getRules(c.Req.Context(), c.OrgId)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get dashboard lint rules", err)
+	}
+	return response.JSON(http.StatusOK, rules)
+}
+
+// PUT /api/dashboards/lint/rules/:rule
+func (s *DashboardLintService) configureRuleHandler(c *models.ReqContext) response.Response {
+	cmd := SaveRuleConfigCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	if err := s.configureRule(c.Req.Context(), c.OrgId, web.Params(c.Req)[":rule"], cmd); err != nil {
+		switch {
+		case errors.Is(err, ErrRuleNotFound):
+			return response.Error(http.StatusNotFound, "Dashboard lint rule not found", nil)
+		case errors.Is(err, ErrInvalidSeverity), errors.Is(err, ErrInvalidThreshold):
+			return response.Error(http.StatusBadRequest, err.Error(), nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to configure dashboard lint rule", err)
+	}
+	return response.Success("Dashboard lint rule configured")
+}
+
+// DELETE /api/dashboards/lint/rules/:rule
+func (s *DashboardLintService) resetRuleHandler(c *models.ReqContext) response.Response {
+	if err := s.resetRule(c.Req.Context(), c.OrgId, web.Params(c.Req)[":rule"]); err != nil {
+		if errors.Is(err, ErrRuleNotFound) {
+			return response.Error(http.StatusNotFound, "Dashboard lint rule not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to reset dashboard lint rule", err)
+	}
+	return response.Success("Dashboard lint rule reset to its defaults")
+}
@@ -0,0 +1,168 @@
+package dashboardlint
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// Service checks dashboards against lint rules, such as rules finding the panels of deprecated types. Each
+// organization configures the severity of the rules, and the dashboards with blocking problems are not saved.
+type Service interface {
+	// RegisterRule adds a rule, which is then checked with the built-in rules.
+	RegisterRule(rule Rule)
+	// CheckDashboard returns the problems found in the dashboard, or a models.DashboardLintError when some
+	// of them block the dashboard from being saved.
+	CheckDashboard(ctx context.Context, orgID int64, dashboard *simplejson.Json) ([]models.DashboardLintProblem, error)
+}
+
+type DashboardLintService struct {
+	cfg           *setting.Cfg
+	sqlStore      *sqlstore.SQLStore
+	accessControl accesscontrol.AccessControl
+	routeRegister routing.RouteRegister
+	rules         []Rule
+	log           log.Logger
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, accessControl accesscontrol.AccessControl,
+	routeRegister routing.RouteRegister) (*DashboardLintService, error) {
+	s := &DashboardLintService{
+		cfg:           cfg,
+		sqlStore:      sqlStore,
+		accessControl: accessControl,
+		routeRegister: routeRegister,
+		rules:         DefaultRules(),
+		log:           log.New("dashboards.lint"),
+	}
+
+	if err := RegisterRoles(accessControl); err != nil {
+		return nil, err
+	}
+
+	s.registerAPIEndpoints()
+
+	return s, nil
+}
+
+func (s *DashboardLintService) RegisterRule(rule Rule) {
+	for i, registered := range s.rules {
+		if registered.ID() == rule.ID() {
+			s.rules[i] = rule
+			return
+		}
+	}
+	s.rules = append(s.rules, rule)
+}
+
+// CheckDashboard checks the dashboard when it is saved, imported or provisioned, unless lint_on_save is disabled.
+func (s *DashboardLintService) CheckDashboard(ctx context.Context, orgID int64, dashboard *simplejson.Json) ([]models.DashboardLintProblem, error) {
+	if !s.cfg.DashboardLintOnSave {
+		return nil, nil
+	}
+
+	report, err := s.lint(ctx, orgID, dashboard)
+	if err != nil {
+		return nil, err
+	}
+	if report.Blocking {
+		return nil, models.DashboardLintError{Problems: report.Problems}
+	}
+	if len(report.Problems) > 0 {
+		s.log.Debug("Dashboard has lint problems", "orgId", orgID, "dashboardUid", dashboard.Get("uid").MustString(), "problems", len(report.Problems))
+	}
+	return report.Problems, nil
+}
+
+// lint checks the dashboard with the rules configured by the organization.
+func (s *DashboardLintService) lint(ctx context.Context, orgID int64, dashboard *simplejson.Json) (*Report, error) {
+	configs, err := s.getRuleConfigs(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	return Lint(ctx, s.rules, configs, &Target{
+		OrgID:       orgID,
+		Dashboard:   dashboard,
+		DataSources: sqlDataSourceLookup{sqlStore: s.sqlStore},
+	})
+}
+
+// getRules gets the rules with their configuration by the organization.
+func (s *DashboardLintService) getRules(ctx context.Context, orgID int64) ([]RuleDTO, error) {
+	configs, err := s.getRuleConfigs(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]RuleDTO, 0, len(s.rules))
+	for _, rule := range s.rules {
+		dto := RuleDTO{
+			ID:               rule.ID(),
+			Description:      rule.Description(),
+			Severity:         rule.DefaultSeverity(),
+			Threshold:        rule.DefaultThreshold(),
+			DefaultSeverity:  rule.DefaultSeverity(),
+			DefaultThreshold: rule.DefaultThreshold(),
+		}
+		if config, ok := configs[rule.ID()]; ok {
+			dto.Severity = config.Severity
+			if config.Threshold != "" {
+				dto.Threshold = config.Threshold
+			}
+		}
+		rules = append(rules, dto)
+	}
+	return rules, nil
+}
+
+func (s *DashboardLintService) getRule(id string) (Rule, error) {
+	for _, rule := range s.rules {
+		if rule.ID() == id {
+			return rule, nil
+		}
+	}
+	return nil, ErrRuleNotFound
+}
+
+// configureRule saves the configuration of the rule by the organization. An empty threshold is the default one.
+func (s *DashboardLintService) configureRule(ctx context.Context, orgID int64, id string, cmd SaveRuleConfigCommand) error {
+	rule, err := s.getRule(id)
+	if err != nil {
+		return err
+	}
+	switch cmd.Severity {
+	case SeverityOff, SeverityWarning, SeverityBlocking:
+	default:
+		return ErrInvalidSeverity
+	}
+	if cmd.Threshold != "" {
+		if err := rule.ValidateThreshold(cmd.Threshold); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidThreshold, err)
+		}
+	}
+
+	return s.saveRuleConfig(ctx, &RuleConfig{
+		OrgID:     orgID,
+		Rule:      id,
+		Severity:  cmd.Severity,
+		Threshold: cmd.Threshold,
+		Updated:   time.Now(),
+	})
+}
+
+// resetRule restores the default configuration of the rule for the organization.
+func (s *DashboardLintService) resetRule(ctx context.Context, orgID int64, id string) error {
+	if _, err := s.getRule(id); err != nil {
+		return err
+	}
+	return s.deleteRuleConfig(ctx, orgID, id)
+}
@@ -0,0 +1,119 @@
+package dashboardlint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/models"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func setupTestService(t *testing.T) *DashboardLintService {
+	t.Helper()
+
+	sqlStore := sqlstore.InitTestDB(t)
+	sqlStore.Cfg.DashboardLintOnSave = true
+	require.NoError(t, sqlStore.AddDataSource(context.Background(), &models.AddDataSourceCommand{
+		OrgId: 1, Name: "Prometheus", Uid: "prom", Type: "prometheus", Access: models.DS_ACCESS_PROXY,
+	}))
+
+	service, err := ProvideService(sqlStore.Cfg, sqlStore, accesscontrolmock.New().WithDisabled(), routing.NewRouteRegister())
+	require.NoError(t, err)
+	return service
+}
+
+func TestCheckDashboard(t *testing.T) {
+	ctx := context.Background()
+	s := setupTestService(t)
+	dashboard := mustJSON(t, `{"uid": "api", "panels": [
+		{"id": 1, "type": "graph", "datasource": {"uid": "prom"}},
+		{"id": 2, "type": "timeseries", "datasource": "Prometheus"},
+		{"id": 3, "type": "timeseries", "datasource": {"uid": "deleted"}}
+	]}`)
+
+	problems, err := s.CheckDashboard(ctx, 1, dashboard)
+	require.NoError(t, err)
+	require.Len(t, problems, 2)
+	assert.Equal(t, "missing-datasource", problems[0].Rule)
+	assert.Equal(t, int64(3), problems[0].PanelId)
+	assert.Equal(t, "deprecated-panel-type", problems[1].Rule)
+
+	t.Run("blocking rules prevent the dashboard from being saved", func(t *testing.T) {
+		require.NoError(t, s.configureRule(ctx, 1, "missing-datasource", SaveRuleConfigCommand{Severity: SeverityBlocking}))
+		t.Cleanup(func() { require.NoError(t, s.resetRule(ctx, 1, "missing-datasource")) })
+
+		_, err := s.CheckDashboard(ctx, 1, dashboard)
+		var lintErr models.DashboardLintError
+		require.ErrorAs(t, err, &lintErr)
+		require.Len(t, lintErr.Problems, 2)
+		require.Equal(t, "Dashboard has 1 blocking lint problems", lintErr.Error())
+
+		// the rules are configured by organization, and the data sources are not found in other organizations
+		problems, err := s.CheckDashboard(ctx, 2, mustJSON(t, `{"panels": [{"id": 1, "datasource": {"uid": "prom"}}]}`))
+		require.NoError(t, err)
+		require.Len(t, problems, 1)
+		require.Equal(t, SeverityWarning, problems[0].Severity)
+	})
+
+	t.Run("dashboards are not checked when lint_on_save is disabled", func(t *testing.T) {
+		s.cfg.DashboardLintOnSave = false
+		t.Cleanup(func() { s.cfg.DashboardLintOnSave = true })
+
+		problems, err := s.CheckDashboard(ctx, 1, dashboard)
+		require.NoError(t, err)
+		require.Empty(t, problems)
+	})
+}
+
+func TestConfigureRule(t *testing.T) {
+	ctx := context.Background()
+	s := setupTestService(t)
+
+	require.NoError(t, s.configureRule(ctx, 1, "max-panels", SaveRuleConfigCommand{Severity: SeverityBlocking, Threshold: "20"}))
+	require.NoError(t, s.configureRule(ctx, 1, "max-panels", SaveRuleConfigCommand{Severity: SeverityBlocking, Threshold: "10"}))
+	require.NoError(t, s.configureRule(ctx, 1, "missing-description", SaveRuleConfigCommand{Severity: SeverityWarning}))
+
+	rules, err := s.getRules(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, rules, len(DefaultRules()))
+	byID := make(map[string]RuleDTO)
+	for _, rule := range rules {
+		byID[rule.ID] = rule
+	}
+	assert.Equal(t, RuleDTO{
+		ID:               "max-panels",
+		Description:      maxPanelsRule{}.Description(),
+		Severity:         SeverityBlocking,
+		Threshold:        "10",
+		DefaultSeverity:  SeverityWarning,
+		DefaultThreshold: "50",
+	}, byID["max-panels"])
+	assert.Equal(t, SeverityWarning, byID["missing-description"].Severity)
+	assert.Equal(t, "1m", byID["min-refresh-interval"].Threshold)
+
+	t.Run("invalid configurations are rejected", func(t *testing.T) {
+		require.ErrorIs(t, s.configureRule(ctx, 1, "unknown", SaveRuleConfigCommand{Severity: SeverityWarning}), ErrRuleNotFound)
+		require.ErrorIs(t, s.configureRule(ctx, 1, "max-panels", SaveRuleConfigCommand{Severity: "fatal"}), ErrInvalidSeverity)
+		require.ErrorIs(t, s.configureRule(ctx, 1, "max-panels", SaveRuleConfigCommand{Severity: SeverityWarning, Threshold: "-1"}), ErrInvalidThreshold)
+		require.ErrorIs(t, s.configureRule(ctx, 1, "deprecated-panel-type", SaveRuleConfigCommand{Severity: SeverityWarning, Threshold: "1"}), ErrInvalidThreshold)
+	})
+
+	t.Run("reset rules use their defaults", func(t *testing.T) {
+		require.NoError(t, s.resetRule(ctx, 1, "max-panels"))
+		require.ErrorIs(t, s.resetRule(ctx, 1, "unknown"), ErrRuleNotFound)
+
+		rules, err := s.getRules(ctx, 1)
+		require.NoError(t, err)
+		for _, rule := range rules {
+			if rule.ID == "max-panels" {
+				assert.Equal(t, SeverityWarning, rule.Severity)
+				assert.Equal(t, "50", rule.Threshold)
+			}
+		}
+	})
+}
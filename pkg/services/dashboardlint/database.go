@@ -0,0 +1,68 @@
+package dashboardlint
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// getRuleConfigs gets the configuration of the rules by an organization, by rule id.
+func (s *DashboardLintService) getRuleConfigs(ctx context.Context, orgID int64) (map[string]RuleConfig, error) {
+	var configs []RuleConfig
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("org_id = ?", orgID).Find(&configs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byRule := make(map[string]RuleConfig, len(configs))
+	for _, config := range configs {
+		byRule[config.Rule] = config
+	}
+	return byRule, nil
+}
+
+func (s *DashboardLintService) saveRuleConfig(ctx context.Context, config *RuleConfig) error {
+	return s.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		existing := RuleConfig{}
+		exists, err := sess.Where("org_id = ? AND rule = ?", config.OrgID, config.Rule).Get(&existing)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			_, err = sess.Insert(config)
+			return err
+		}
+
+		config.ID = existing.ID
+		_, err = sess.ID(config.ID).Cols("severity", "threshold", "updated").Update(config)
+		return err
+	})
+}
+
+func (s *DashboardLintService) deleteRuleConfig(ctx context.Context, orgID int64, rule string) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Where("org_id = ? AND rule = ?", orgID, rule).Delete(&RuleConfig{})
+		return err
+	})
+}
+
+// sqlDataSourceLookup looks up the data sources saved in the database.
+type sqlDataSourceLookup struct {
+	sqlStore *sqlstore.SQLStore
+}
+
+func (l sqlDataSourceLookup) DataSourceExists(ctx context.Context, orgID int64, uid string, name string) (bool, error) {
+	query := &models.GetDataSourceQuery{OrgId: orgID, Uid: uid}
+	if uid == "" {
+		query.Name = name
+	}
+	err := l.sqlStore.GetDataSource(ctx, query)
+	if errors.Is(err, models.ErrDataSourceNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
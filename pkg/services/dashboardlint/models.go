@@ -0,0 +1,68 @@
+package dashboardlint
+
+import (
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+const (
+	ActionRulesWrite = "dashboards.lint.rules:write"
+)
+
+// Severity is what happens to the dashboards with the problems found by a rule.
+type Severity = models.DashboardLintSeverity
+
+const (
+	SeverityOff      = models.DashboardLintSeverityOff
+	SeverityWarning  = models.DashboardLintSeverityWarning
+	SeverityBlocking = models.DashboardLintSeverityBlocking
+)
+
+var (
+	ErrRuleNotFound     = errors.New("dashboard lint rule not found")
+	ErrInvalidSeverity  = errors.New("severity must be off, warning or blocking")
+	ErrInvalidThreshold = errors.New("invalid threshold")
+	ErrMissingDashboard = errors.New("dashboard is required")
+)
+
+// RuleConfig is the configuration of a rule by an organization.
+type RuleConfig struct {
+	ID        int64    `xorm:"pk autoincr 'id'"`
+	OrgID     int64    `xorm:"org_id"`
+	Rule      string   `xorm:"rule"`
+	Severity  Severity `xorm:"severity"`
+	Threshold string   `xorm:"threshold"`
+	Updated   time.Time
+}
+
+func (RuleConfig) TableName() string {
+	return "dashboard_lint_rule"
+}
+
+// Report is the result of checking a dashboard.
+type Report struct {
+	Problems []models.DashboardLintProblem `json:"problems"`
+	// Blocking is true when some problems block the dashboard from being saved.
+	Blocking bool `json:"blocking"`
+}
+
+type RuleDTO struct {
+	ID               string   `json:"id"`
+	Description      string   `json:"description"`
+	Severity         Severity `json:"severity"`
+	Threshold        string   `json:"threshold,omitempty"`
+	DefaultSeverity  Severity `json:"defaultSeverity"`
+	DefaultThreshold string   `json:"defaultThreshold,omitempty"`
+}
+
+type SaveRuleConfigCommand struct {
+	Severity  Severity `json:"severity"`
+	Threshold string   `json:"threshold"`
+}
+
+type LintDashboardCommand struct {
+	Dashboard *simplejson.Json `json:"dashboard"`
+}
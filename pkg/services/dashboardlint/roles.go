@@ -0,0 +1,26 @@
+package dashboardlint
+
+import (
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+func RegisterRoles(ac accesscontrol.AccessControl) error {
+	rulesWriter := accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Version:     1,
+			Name:        "fixed:dashboards.lint.rules:writer",
+			DisplayName: "Dashboard lint rule writer",
+			Description: "Configure the severity and threshold of the dashboard lint rules.",
+			Group:       "Dashboards",
+			Permissions: []accesscontrol.Permission{
+				{
+					Action: ActionRulesWrite,
+				},
+			},
+		},
+		Grants: []string{string(models.ROLE_ADMIN)},
+	}
+
+	return ac.DeclareFixedRoles(rulesWriter)
+}
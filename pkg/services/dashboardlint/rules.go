@@ -0,0 +1,331 @@
+package dashboardlint
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// Rule checks dashboards for a kind of problem. Rules with a threshold, such as the maximum number of panels,
+// are checked with the threshold of the organization, or their default one.
+type Rule interface {
+	// ID identifies the rule in the configuration of the organizations, e.g. max-panels.
+	ID() string
+	Description() string
+	// DefaultSeverity is the severity of the problems found by the rule, unless the organization changes it.
+	DefaultSeverity() Severity
+	// DefaultThreshold is the threshold of the rule, unless the organization changes it. Empty when the
+	// rule has no threshold.
+	DefaultThreshold() string
+	// ValidateThreshold returns an error when the threshold is not valid for the rule.
+	ValidateThreshold(threshold string) error
+	// Check returns the problems found in the dashboard.
+	Check(ctx context.Context, target *Target, threshold string) ([]Finding, error)
+}
+
+// Target is a dashboard checked by the rules.
+type Target struct {
+	OrgID     int64
+	Dashboard *simplejson.Json
+	// DataSources looks up the data sources of the organization. It is nil when the dashboard is checked
+	// without Grafana, e.g. by the CLI, and the rules that need it are skipped.
+	DataSources DataSourceLookup
+}
+
+// DataSourceLookup looks up the data sources of an organization.
+type DataSourceLookup interface {
+	// DataSourceExists returns true if the organization has a data source with the uid, or with the name
+	// when the uid is empty.
+	DataSourceExists(ctx context.Context, orgID int64, uid string, name string) (bool, error)
+}
+
+// Finding is a problem found by a rule.
+type Finding struct {
+	// PanelID is the id of the panel with the problem, 0 for the problems of the dashboard itself.
+	PanelID int64
+	Message string
+}
+
+// DefaultRules returns the built-in rules.
+func DefaultRules() []Rule {
+	return []Rule{
+		missingDataSourceRule{},
+		missingDescriptionRule{},
+		minRefreshIntervalRule{},
+		maxPanelsRule{},
+		deprecatedPanelTypeRule{},
+	}
+}
+
+// Lint checks the dashboard with the rules, with the configuration of the organization or their defaults, and
+// reports the problems found by the rules that are not off.
+func Lint(ctx context.Context, rules []Rule, configs map[string]RuleConfig, target *Target) (*Report, error) {
+	report := &Report{Problems: make([]models.DashboardLintProblem, 0)}
+	for _, rule := range rules {
+		severity, threshold := rule.DefaultSeverity(), rule.DefaultThreshold()
+		if config, ok := configs[rule.ID()]; ok {
+			severity = config.Severity
+			if config.Threshold != "" {
+				threshold = config.Threshold
+			}
+		}
+		if severity == SeverityOff {
+			continue
+		}
+
+		findings, err := rule.Check(ctx, target, threshold)
+		if err != nil {
+			return nil, fmt.Errorf("lint rule %s failed: %w", rule.ID(), err)
+		}
+		for _, finding := range findings {
+			report.Problems = append(report.Problems, models.DashboardLintProblem{
+				Rule:     rule.ID(),
+				Severity: severity,
+				Message:  finding.Message,
+				PanelId:  finding.PanelID,
+			})
+			if severity == SeverityBlocking {
+				report.Blocking = true
+			}
+		}
+	}
+	return report, nil
+}
+
+// getPanels returns the panels of the dashboard, including the panels of collapsed rows and of the rows of the
+// dashboards saved before schema version 16, but not the rows themselves.
+func getPanels(dashboard *simplejson.Json) []*simplejson.Json {
+	var panels []*simplejson.Json
+	var add func(list []interface{})
+	add = func(list []interface{}) {
+		for _, item := range list {
+			panel := simplejson.NewFromAny(item)
+			if panel.Get("type").MustString() == "row" {
+				add(panel.Get("panels").MustArray())
+				continue
+			}
+			panels = append(panels, panel)
+		}
+	}
+
+	add(dashboard.Get("panels").MustArray())
+	for _, row := range dashboard.Get("rows").MustArray() {
+		add(simplejson.NewFromAny(row).Get("panels").MustArray())
+	}
+	return panels
+}
+
+func panelName(panel *simplejson.Json) string {
+	if title := panel.Get("title").MustString(); title != "" {
+		return fmt.Sprintf("Panel %q", title)
+	}
+	return fmt.Sprintf("Panel %d", panel.Get("id").MustInt64())
+}
+
+func validateNoThreshold(threshold string) error {
+	if threshold != "" {
+		return fmt.Errorf("rule has no threshold")
+	}
+	return nil
+}
+
+// missingDataSourceRule finds the panels and queries using data sources that don't exist, e.g. because they
+// were deleted.
+type missingDataSourceRule struct{}
+
+func (missingDataSourceRule) ID() string { return "missing-datasource" }
+func (missingDataSourceRule) Description() string {
+	return "Panels and queries must use existing data sources."
+}
+func (missingDataSourceRule) DefaultSeverity() Severity { return SeverityWarning }
+func (missingDataSourceRule) DefaultThreshold() string  { return "" }
+func (missingDataSourceRule) ValidateThreshold(threshold string) error {
+	return validateNoThreshold(threshold)
+}
+
+// builtInDataSources are the uids and names of the data sources that are not saved in the organizations.
+var builtInDataSources = map[string]bool{
+	"grafana":         true,
+	"-- Grafana --":   true,
+	"-- Mixed --":     true,
+	"-- Dashboard --": true,
+	"default":         true,
+}
+
+func (missingDataSourceRule) Check(ctx context.Context, target *Target, _ string) ([]Finding, error) {
+	if target.DataSources == nil {
+		return nil, nil
+	}
+
+	var findings []Finding
+	for _, panel := range getPanels(target.Dashboard) {
+		refs := []*simplejson.Json{panel.Get("datasource")}
+		for _, query := range panel.Get("targets").MustArray() {
+			refs = append(refs, simplejson.NewFromAny(query).Get("datasource"))
+		}
+
+		missing := make(map[string]bool)
+		for _, ref := range refs {
+			uid, name := ref.Get("uid").MustString(), ref.MustString()
+			key := uid + name
+			// queries without data source use the one of their panel, and variables are resolved when queried
+			if key == "" || strings.Contains(key, "$") || builtInDataSources[key] || missing[key] {
+				continue
+			}
+
+			exists, err := target.DataSources.DataSourceExists(ctx, target.OrgID, uid, name)
+			if err != nil {
+				return nil, err
+			}
+			if !exists {
+				missing[key] = true
+				findings = append(findings, Finding{
+					PanelID: panel.Get("id").MustInt64(),
+					Message: fmt.Sprintf("%s uses the data source %q, which doesn't exist", panelName(panel), key),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// missingDescriptionRule finds the dashboard and the panels without description.
+type missingDescriptionRule struct{}
+
+func (missingDescriptionRule) ID() string { return "missing-description" }
+func (missingDescriptionRule) Description() string {
+	return "The dashboard and its panels must have a description."
+}
+func (missingDescriptionRule) DefaultSeverity() Severity { return SeverityOff }
+func (missingDescriptionRule) DefaultThreshold() string  { return "" }
+func (missingDescriptionRule) ValidateThreshold(threshold string) error {
+	return validateNoThreshold(threshold)
+}
+
+func (missingDescriptionRule) Check(_ context.Context, target *Target, _ string) ([]Finding, error) {
+	var findings []Finding
+	if strings.TrimSpace(target.Dashboard.Get("description").MustString()) == "" {
+		findings = append(findings, Finding{Message: "Dashboard has no description"})
+	}
+	for _, panel := range getPanels(target.Dashboard) {
+		if strings.TrimSpace(panel.Get("description").MustString()) == "" {
+			findings = append(findings, Finding{
+				PanelID: panel.Get("id").MustInt64(),
+				Message: fmt.Sprintf("%s has no description", panelName(panel)),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// minRefreshIntervalRule finds the dashboards refreshed more often than the threshold.
+type minRefreshIntervalRule struct{}
+
+func (minRefreshIntervalRule) ID() string { return "min-refresh-interval" }
+func (minRefreshIntervalRule) Description() string {
+	return "The dashboard must not be refreshed more often than the threshold, e.g. 1m."
+}
+func (minRefreshIntervalRule) DefaultSeverity() Severity { return SeverityWarning }
+func (minRefreshIntervalRule) DefaultThreshold() string  { return "1m" }
+
+func (minRefreshIntervalRule) ValidateThreshold(threshold string) error {
+	if _, err := gtime.ParseDuration(threshold); err != nil {
+		return fmt.Errorf("threshold must be a duration, e.g. 1m: %w", err)
+	}
+	return nil
+}
+
+func (minRefreshIntervalRule) Check(_ context.Context, target *Target, threshold string) ([]Finding, error) {
+	minInterval, err := gtime.ParseDuration(threshold)
+	if err != nil {
+		return nil, err
+	}
+
+	// refresh is false, or empty, when the dashboard is not refreshed
+	refresh := target.Dashboard.Get("refresh").MustString()
+	if refresh == "" {
+		return nil, nil
+	}
+	interval, err := gtime.ParseDuration(refresh)
+	if err != nil || interval >= minInterval {
+		return nil, nil
+	}
+	return []Finding{{Message: fmt.Sprintf("Dashboard is refreshed every %s, more often than every %s", refresh, threshold)}}, nil
+}
+
+// maxPanelsRule finds the dashboards with more panels than the threshold.
+type maxPanelsRule struct{}
+
+func (maxPanelsRule) ID() string { return "max-panels" }
+func (maxPanelsRule) Description() string {
+	return "The dashboard must not have more panels than the threshold, including the panels of collapsed rows."
+}
+func (maxPanelsRule) DefaultSeverity() Severity { return SeverityWarning }
+func (maxPanelsRule) DefaultThreshold() string  { return "50" }
+
+func (maxPanelsRule) ValidateThreshold(threshold string) error {
+	if max, err := strconv.Atoi(threshold); err != nil || max < 1 {
+		return fmt.Errorf("threshold must be a positive number of panels")
+	}
+	return nil
+}
+
+func (maxPanelsRule) Check(_ context.Context, target *Target, threshold string) ([]Finding, error) {
+	max, err := strconv.Atoi(threshold)
+	if err != nil {
+		return nil, err
+	}
+	if count := len(getPanels(target.Dashboard)); count > max {
+		return []Finding{{Message: fmt.Sprintf("Dashboard has %d panels, more than %d", count, max)}}, nil
+	}
+	return nil, nil
+}
+
+// deprecatedPanelTypes are the deprecated panel types, and the panel types replacing them.
+var deprecatedPanelTypes = map[string]string{
+	"graph":                    "timeseries",
+	"singlestat":               "stat",
+	"grafana-singlestat-panel": "stat",
+	"table-old":                "table",
+	"grafana-piechart-panel":   "piechart",
+	"grafana-worldmap-panel":   "geomap",
+}
+
+// deprecatedPanelTypeRule finds the panels of deprecated types.
+type deprecatedPanelTypeRule struct{}
+
+func (deprecatedPanelTypeRule) ID() string { return "deprecated-panel-type" }
+func (deprecatedPanelTypeRule) Description() string {
+	types := make([]string, 0, len(deprecatedPanelTypes))
+	for panelType := range deprecatedPanelTypes {
+		types = append(types, panelType)
+	}
+	sort.Strings(types)
+	return "Panels must not use deprecated types: " + strings.Join(types, ", ") + "."
+}
+func (deprecatedPanelTypeRule) DefaultSeverity() Severity { return SeverityWarning }
+func (deprecatedPanelTypeRule) DefaultThreshold() string  { return "" }
+func (deprecatedPanelTypeRule) ValidateThreshold(threshold string) error {
+	return validateNoThreshold(threshold)
+}
+
+func (deprecatedPanelTypeRule) Check(_ context.Context, target *Target, _ string) ([]Finding, error) {
+	var findings []Finding
+	for _, panel := range getPanels(target.Dashboard) {
+		panelType := panel.Get("type").MustString()
+		if replacement, ok := deprecatedPanelTypes[panelType]; ok {
+			findings = append(findings, Finding{
+				PanelID: panel.Get("id").MustInt64(),
+				Message: fmt.Sprintf("%s uses the deprecated type %s, replaced by %s", panelName(panel), panelType, replacement),
+			})
+		}
+	}
+	return findings, nil
+}
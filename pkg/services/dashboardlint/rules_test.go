@@ -0,0 +1,123 @@
+package dashboardlint
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+func mustJSON(t *testing.T, s string) *simplejson.Json {
+	t.Helper()
+	data, err := simplejson.NewJson([]byte(s))
+	require.NoError(t, err)
+	return data
+}
+
+type fakeDataSourceLookup map[string]bool
+
+func (f fakeDataSourceLookup) DataSourceExists(_ context.Context, _ int64, uid string, name string) (bool, error) {
+	return f[uid+name], nil
+}
+
+func check(t *testing.T, rule Rule, target *Target, threshold string) []Finding {
+	t.Helper()
+	findings, err := rule.Check(context.Background(), target, threshold)
+	require.NoError(t, err)
+	return findings
+}
+
+func TestMissingDataSourceRule(t *testing.T) {
+	dashboard := mustJSON(t, `{"panels": [
+		{"id": 1, "title": "CPU", "datasource": {"uid": "prom"}, "targets": [{"datasource": {"uid": "deleted"}}, {"datasource": {"uid": "deleted"}}]},
+		{"id": 2, "datasource": "Old Graphite"},
+		{"id": 3, "datasource": "${ds}", "targets": [{"datasource": {"uid": "$ds"}}]},
+		{"id": 4, "datasource": "-- Mixed --", "targets": [{"datasource": {"uid": "grafana"}}, {}]},
+		{"id": 5, "type": "row", "collapsed": true, "panels": [{"id": 6, "datasource": {"uid": "gone"}}]}
+	]}`)
+	target := &Target{OrgID: 1, Dashboard: dashboard, DataSources: fakeDataSourceLookup{"prom": true}}
+
+	findings := check(t, missingDataSourceRule{}, target, "")
+	require.Len(t, findings, 3)
+	// the data sources used by several queries of a panel are reported once
+	assert.Equal(t, Finding{PanelID: 1, Message: `Panel "CPU" uses the data source "deleted", which doesn't exist`}, findings[0])
+	assert.Equal(t, int64(2), findings[1].PanelID)
+	assert.Equal(t, int64(6), findings[2].PanelID)
+
+	t.Run("the rule is skipped without data source lookup", func(t *testing.T) {
+		require.Empty(t, check(t, missingDataSourceRule{}, &Target{Dashboard: dashboard}, ""))
+	})
+}
+
+func TestMissingDescriptionRule(t *testing.T) {
+	dashboard := mustJSON(t, `{"description": "Service health", "panels": [{"id": 1, "description": "Requests"}, {"id": 2, "title": "Errors"}]}`)
+	findings := check(t, missingDescriptionRule{}, &Target{Dashboard: dashboard}, "")
+	require.Equal(t, []Finding{{PanelID: 2, Message: `Panel "Errors" has no description`}}, findings)
+
+	findings = check(t, missingDescriptionRule{}, &Target{Dashboard: mustJSON(t, `{"description": " "}`)}, "")
+	require.Equal(t, []Finding{{Message: "Dashboard has no description"}}, findings)
+}
+
+func TestMinRefreshIntervalRule(t *testing.T) {
+	rule := minRefreshIntervalRule{}
+	require.Len(t, check(t, rule, &Target{Dashboard: mustJSON(t, `{"refresh": "10s"}`)}, "1m"), 1)
+	require.Empty(t, check(t, rule, &Target{Dashboard: mustJSON(t, `{"refresh": "5m"}`)}, "1m"))
+	require.Empty(t, check(t, rule, &Target{Dashboard: mustJSON(t, `{"refresh": false}`)}, "1m"))
+	require.Empty(t, check(t, rule, &Target{Dashboard: mustJSON(t, `{"refresh": "10s"}`)}, "5s"))
+
+	require.NoError(t, rule.ValidateThreshold("30s"))
+	require.Error(t, rule.ValidateThreshold("often"))
+}
+
+func TestMaxPanelsRule(t *testing.T) {
+	rule := maxPanelsRule{}
+	// rows are not counted, but the panels of collapsed rows and of legacy rows are
+	dashboard := mustJSON(t, `{
+		"panels": [{"id": 1}, {"id": 2, "type": "row", "panels": [{"id": 3}]}],
+		"rows": [{"panels": [{"id": 4}]}]
+	}`)
+	require.Equal(t, []Finding{{Message: "Dashboard has 3 panels, more than 2"}}, check(t, rule, &Target{Dashboard: dashboard}, "2"))
+	require.Empty(t, check(t, rule, &Target{Dashboard: dashboard}, "3"))
+
+	require.NoError(t, rule.ValidateThreshold("10"))
+	require.Error(t, rule.ValidateThreshold("0"))
+	require.Error(t, rule.ValidateThreshold("many"))
+}
+
+func TestDeprecatedPanelTypeRule(t *testing.T) {
+	dashboard := mustJSON(t, `{"panels": [{"id": 1, "type": "graph", "title": "CPU"}, {"id": 2, "type": "timeseries"}, {"id": 3, "type": "table-old"}]}`)
+	findings := check(t, deprecatedPanelTypeRule{}, &Target{Dashboard: dashboard}, "")
+	require.Len(t, findings, 2)
+	assert.Equal(t, Finding{PanelID: 1, Message: `Panel "CPU" uses the deprecated type graph, replaced by timeseries`}, findings[0])
+	assert.Equal(t, int64(3), findings[1].PanelID)
+}
+
+func TestLint(t *testing.T) {
+	ctx := context.Background()
+	target := &Target{Dashboard: mustJSON(t, `{"refresh": "5s", "panels": [{"id": 1, "type": "graph"}, {"id": 2, "type": "stat"}]}`)}
+
+	report, err := Lint(ctx, DefaultRules(), nil, target)
+	require.NoError(t, err)
+	require.False(t, report.Blocking)
+	require.Len(t, report.Problems, 2)
+	assert.Equal(t, "min-refresh-interval", report.Problems[0].Rule)
+	assert.Equal(t, SeverityWarning, report.Problems[0].Severity)
+	assert.Equal(t, "deprecated-panel-type", report.Problems[1].Rule)
+
+	t.Run("the configuration of the organization overrides the defaults", func(t *testing.T) {
+		report, err := Lint(ctx, DefaultRules(), map[string]RuleConfig{
+			"min-refresh-interval":  {Severity: SeverityWarning, Threshold: "5s"},
+			"deprecated-panel-type": {Severity: SeverityBlocking},
+			"max-panels":            {Severity: SeverityOff, Threshold: "1"},
+		}, target)
+		require.NoError(t, err)
+		require.True(t, report.Blocking)
+		require.Len(t, report.Problems, 1)
+		assert.Equal(t, "deprecated-panel-type", report.Problems[0].Rule)
+		assert.Equal(t, SeverityBlocking, report.Problems[0].Severity)
+		assert.Equal(t, int64(1), report.Problems[0].PanelId)
+	})
+}
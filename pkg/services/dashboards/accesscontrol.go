@@ -2,9 +2,11 @@ package dashboards
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"strings"
 
+	"github.com/grafana/grafana/pkg/models"
 	ac "github.com/grafana/grafana/pkg/services/accesscontrol"
 )
 
@@ -52,7 +54,7 @@ func NewFolderNameScopeResolver(db Store) (string, ac.ScopeAttributeResolver) {
 		if err != nil {
 			return nil, err
 		}
-		return []string{ScopeFoldersProvider.GetResourceScopeUID(folder.Uid)}, nil
+		return folderScopes(ctx, db, orgID, folder)
 	})
 }
 
@@ -78,6 +80,49 @@ func NewFolderIDScopeResolver(db Store) (string, ac.ScopeAttributeResolver) {
 			return nil, err
 		}
 
-		return []string{ScopeFoldersProvider.GetResourceScopeUID(folder.Uid)}, nil
+		return folderScopes(ctx, db, orgID, folder)
 	})
 }
+
+// NewFolderUIDScopeResolver provides an ScopeAttributeResolver that is able to convert a scope prefixed with "folders:uid:"
+// into the scopes of the folder and of its parents, so that nested folders inherit the permissions of their parents.
+func NewFolderUIDScopeResolver(db Store) (string, ac.ScopeAttributeResolver) {
+	prefix := ScopeFoldersProvider.GetResourceScopeUID("")
+	return prefix, ac.ScopeAttributeResolverFunc(func(ctx context.Context, orgID int64, scope string) ([]string, error) {
+		if !strings.HasPrefix(scope, prefix) {
+			return nil, ac.ErrInvalidScope
+		}
+
+		uid := scope[len(prefix):]
+		if uid == "" || uid == "*" || uid == ac.GeneralFolderUID {
+			return []string{scope}, nil
+		}
+
+		folder, err := db.GetFolderByUID(ctx, orgID, uid)
+		if err != nil {
+			if errors.Is(err, models.ErrFolderNotFound) {
+				return []string{scope}, nil
+			}
+			return nil, err
+		}
+
+		return folderScopes(ctx, db, orgID, folder)
+	})
+}
+
+// folderScopes returns the uid based scope of the folder followed by the scopes of its parents
+func folderScopes(ctx context.Context, db Store, orgID int64, folder *models.Folder) ([]string, error) {
+	scopes := []string{ScopeFoldersProvider.GetResourceScopeUID(folder.Uid)}
+	if folder.ParentId == 0 {
+		return scopes, nil
+	}
+
+	parents, err := db.GetFolderPath(ctx, orgID, folder.ParentId)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		scopes = append(scopes, ScopeFoldersProvider.GetResourceScopeUID(parents[i].UID))
+	}
+	return scopes, nil
+}
@@ -144,3 +144,56 @@ func TestNewFolderIDScopeResolver(t *testing.T) {
 		require.Nil(t, resolvedScopes)
 	})
 }
+
+func TestNewFolderUIDScopeResolver(t *testing.T) {
+	t.Run("prefix should be expected", func(t *testing.T) {
+		prefix, _ := NewFolderUIDScopeResolver(&FakeDashboardStore{})
+		require.Equal(t, "folders:uid:", prefix)
+	})
+
+	t.Run("resolver should add the scopes of the parents", func(t *testing.T) {
+		dashboardStore := &FakeDashboardStore{}
+
+		_, resolver := NewFolderUIDScopeResolver(dashboardStore)
+
+		orgId := rand.Int63()
+		folder := &models.Folder{Id: 3, Uid: "environment", ParentId: 2}
+		dashboardStore.On("GetFolderByUID", mock.Anything, orgId, folder.Uid).Return(folder, nil).Once()
+		dashboardStore.On("GetFolderPath", mock.Anything, orgId, folder.ParentId).Return([]*models.FolderPathItem{
+			{ID: 1, UID: "team"},
+			{ID: 2, UID: "service"},
+		}, nil).Once()
+
+		resolvedScopes, err := resolver.Resolve(context.Background(), orgId, "folders:uid:environment")
+		require.NoError(t, err)
+		require.Equal(t, []string{"folders:uid:environment", "folders:uid:service", "folders:uid:team"}, resolvedScopes)
+	})
+
+	t.Run("resolver should keep the scope of root folders", func(t *testing.T) {
+		dashboardStore := &FakeDashboardStore{}
+
+		_, resolver := NewFolderUIDScopeResolver(dashboardStore)
+
+		orgId := rand.Int63()
+		dashboardStore.On("GetFolderByUID", mock.Anything, orgId, "team").Return(&models.Folder{Id: 1, Uid: "team"}, nil).Once()
+
+		resolvedScopes, err := resolver.Resolve(context.Background(), orgId, "folders:uid:team")
+		require.NoError(t, err)
+		require.Equal(t, []string{"folders:uid:team"}, resolvedScopes)
+		dashboardStore.AssertNotCalled(t, "GetFolderPath", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("resolver should keep wildcard, general and unknown folders", func(t *testing.T) {
+		dashboardStore := &FakeDashboardStore{}
+
+		_, resolver := NewFolderUIDScopeResolver(dashboardStore)
+
+		dashboardStore.On("GetFolderByUID", mock.Anything, mock.Anything, "unknown").Return(nil, models.ErrFolderNotFound).Once()
+
+		for _, scope := range []string{"folders:uid:*", "folders:uid:general", "folders:uid:unknown"} {
+			resolvedScopes, err := resolver.Resolve(context.Background(), rand.Int63(), scope)
+			require.NoError(t, err)
+			require.Equal(t, []string{scope}, resolvedScopes)
+		}
+	})
+}
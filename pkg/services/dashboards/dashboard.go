@@ -3,6 +3,7 @@ package dashboards
 import (
 	"context"
 
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/models"
 )
 
@@ -16,6 +17,13 @@ type DashboardService interface {
 	UpdateDashboardACL(ctx context.Context, uid int64, items []*models.DashboardAcl) error
 }
 
+// DashboardLinter checks dashboards against the lint rules of their organization before they are saved.
+type DashboardLinter interface {
+	// CheckDashboard returns the problems found in the dashboard, or a models.DashboardLintError when some
+	// of them block the dashboard from being saved.
+	CheckDashboard(ctx context.Context, orgID int64, dashboard *simplejson.Json) ([]models.DashboardLintProblem, error)
+}
+
 // PluginService is a service for operating on plugin dashboards.
 type PluginService interface {
 	GetDashboardsByPluginID(ctx context.Context, query *models.GetDashboardsByPluginIdQuery) error
@@ -52,6 +60,10 @@ type Store interface {
 	// GetDashboardsByPluginID retrieves dashboards identified by plugin.
 	GetDashboardsByPluginID(ctx context.Context, query *models.GetDashboardsByPluginIdQuery) error
 	DeleteDashboard(ctx context.Context, cmd *models.DeleteDashboardCommand) error
+	// GetDashboard retrieves a dashboard by its ID, UID or slug.
+	GetDashboard(ctx context.Context, query *models.GetDashboardQuery) error
+	// GetDashboardVersion retrieves a saved version of a dashboard.
+	GetDashboardVersion(ctx context.Context, query *models.GetDashboardVersionQuery) error
 	FolderStore
 }
 
@@ -64,4 +76,8 @@ type FolderStore interface {
 	GetFolderByUID(ctx context.Context, orgID int64, uid string) (*models.Folder, error)
 	// GetFolderByID retrieves a folder by its ID
 	GetFolderByID(ctx context.Context, orgID int64, id int64) (*models.Folder, error)
+	// GetFolderPath retrieves a folder and its parents, from the root folder to the folder itself
+	GetFolderPath(ctx context.Context, orgID int64, folderID int64) ([]*models.FolderPathItem, error)
+	// GetDescendantFolders retrieves the subfolders of a folder and their own subfolders
+	GetDescendantFolders(ctx context.Context, orgID int64, folderID int64) ([]*models.Folder, error)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/infra/log"
@@ -49,16 +50,34 @@ func (d *DashboardStore) ValidateDashboardBeforeSave(dashboard *models.Dashboard
 	return isParentFolderChanged, nil
 }
 
+// GetFolderByTitle returns the root folder with the given title. When there is no such folder and folders are
+// nested, the title is read as the path of a subfolder, with the titles of its parents separated by a slash.
 func (d *DashboardStore) GetFolderByTitle(ctx context.Context, orgID int64, title string) (*models.Folder, error) {
 	if title == "" {
 		return nil, models.ErrFolderTitleEmpty
 	}
 
+	folder, err := d.getFolderByTitle(ctx, orgID, 0, title)
+	if !errors.Is(err, models.ErrFolderNotFound) || d.sqlStore.Cfg.MaxFolderDepth <= 1 || !strings.Contains(title, "/") {
+		return folder, err
+	}
+
+	var parentID int64
+	for _, part := range strings.Split(title, "/") {
+		folder, err = d.getFolderByTitle(ctx, orgID, parentID, part)
+		if err != nil {
+			return nil, err
+		}
+		parentID = folder.Id
+	}
+	return folder, nil
+}
+
+func (d *DashboardStore) getFolderByTitle(ctx context.Context, orgID int64, parentID int64, title string) (*models.Folder, error) {
 	// there is a unique constraint on org_id, folder_id, title
-	// there are no nested folders so the parent folder id is always 0
-	dashboard := models.Dashboard{OrgId: orgID, FolderId: 0, Title: title}
+	dashboard := models.Dashboard{OrgId: orgID, FolderId: parentID, Title: title}
 	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
-		has, err := sess.Table(&models.Dashboard{}).Where("is_folder = " + d.sqlStore.Dialect.BooleanStr(true)).Where("folder_id=0").Get(&dashboard)
+		has, err := sess.Table(&models.Dashboard{}).Where("is_folder = "+d.sqlStore.Dialect.BooleanStr(true)).Where("folder_id=?", parentID).Get(&dashboard)
 		if err != nil {
 			return err
 		}
@@ -73,9 +92,9 @@ func (d *DashboardStore) GetFolderByTitle(ctx context.Context, orgID int64, titl
 }
 
 func (d *DashboardStore) GetFolderByID(ctx context.Context, orgID int64, id int64) (*models.Folder, error) {
-	dashboard := models.Dashboard{OrgId: orgID, FolderId: 0, Id: id}
+	dashboard := models.Dashboard{OrgId: orgID, Id: id}
 	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
-		has, err := sess.Table(&models.Dashboard{}).Where("is_folder = " + d.sqlStore.Dialect.BooleanStr(true)).Where("folder_id=0").Get(&dashboard)
+		has, err := sess.Table(&models.Dashboard{}).Where("is_folder = " + d.sqlStore.Dialect.BooleanStr(true)).Get(&dashboard)
 		if err != nil {
 			return err
 		}
@@ -97,9 +116,9 @@ func (d *DashboardStore) GetFolderByUID(ctx context.Context, orgID int64, uid st
 		return nil, models.ErrDashboardIdentifierNotSet
 	}
 
-	dashboard := models.Dashboard{OrgId: orgID, FolderId: 0, Uid: uid}
+	dashboard := models.Dashboard{OrgId: orgID, Uid: uid}
 	err := d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
-		has, err := sess.Table(&models.Dashboard{}).Where("is_folder = " + d.sqlStore.Dialect.BooleanStr(true)).Where("folder_id=0").Get(&dashboard)
+		has, err := sess.Table(&models.Dashboard{}).Where("is_folder = " + d.sqlStore.Dialect.BooleanStr(true)).Get(&dashboard)
 		if err != nil {
 			return err
 		}
@@ -116,6 +135,48 @@ func (d *DashboardStore) GetFolderByUID(ctx context.Context, orgID int64, uid st
 	return models.DashboardToFolder(&dashboard), nil
 }
 
+// GetFolderPath returns the folder and its parents, from the root folder to the folder itself
+func (d *DashboardStore) GetFolderPath(ctx context.Context, orgID int64, folderID int64) ([]*models.FolderPathItem, error) {
+	paths, err := d.sqlStore.GetFolderPaths(ctx, orgID, []int64{folderID})
+	if err != nil {
+		return nil, err
+	}
+	path, ok := paths[folderID]
+	if !ok {
+		return nil, models.ErrFolderNotFound
+	}
+	return path, nil
+}
+
+// GetDescendantFolders returns the subfolders of the folder, and their own subfolders, closest ones first
+func (d *DashboardStore) GetDescendantFolders(ctx context.Context, orgID int64, folderID int64) ([]*models.Folder, error) {
+	var folders []*models.Folder
+	err := d.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		parents := []int64{folderID}
+		seen := map[int64]bool{folderID: true}
+		for len(parents) > 0 {
+			var children []*models.Dashboard
+			err := sess.Table(&models.Dashboard{}).Where("org_id = ? AND is_folder = "+d.sqlStore.Dialect.BooleanStr(true), orgID).
+				In("folder_id", parents).Asc("title").Find(&children)
+			if err != nil {
+				return err
+			}
+
+			parents = parents[:0]
+			for _, child := range children {
+				if seen[child.Id] {
+					continue
+				}
+				seen[child.Id] = true
+				folders = append(folders, models.DashboardToFolder(child))
+				parents = append(parents, child.Id)
+			}
+		}
+		return nil
+	})
+	return folders, err
+}
+
 func (d *DashboardStore) GetProvisionedDataByDashboardID(dashboardID int64) (*models.DashboardProvisioning, error) {
 	var data models.DashboardProvisioning
 	err := d.sqlStore.WithTransactionalDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
@@ -163,6 +224,9 @@ func (d *DashboardStore) GetProvisionedDashboardData(name string) ([]*models.Das
 
 func (d *DashboardStore) SaveProvisionedDashboard(cmd models.SaveDashboardCommand, provisioning *models.DashboardProvisioning) (*models.Dashboard, error) {
 	err := d.sqlStore.WithTransactionalDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+		if err := d.validateParentFolder(sess, cmd.GetDashboardModel()); err != nil {
+			return err
+		}
 		if err := saveDashboard(sess, &cmd); err != nil {
 			return err
 		}
@@ -179,11 +243,91 @@ func (d *DashboardStore) SaveProvisionedDashboard(cmd models.SaveDashboardComman
 
 func (d *DashboardStore) SaveDashboard(cmd models.SaveDashboardCommand) (*models.Dashboard, error) {
 	err := d.sqlStore.WithTransactionalDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+		if err := d.validateParentFolder(sess, cmd.GetDashboardModel()); err != nil {
+			return err
+		}
 		return saveDashboard(sess, &cmd)
 	})
 	return cmd.Result, err
 }
 
+// validateParentFolder checks again, in the transaction that saves the folder, that it is not moved into one of its
+// own subfolders nor nested deeper than the maximum folder depth, as the checks of the dashboard service could have
+// been outdated by concurrent moves. The folder and its new parents are locked until the end of the transaction, so
+// that the moves of the same folders are checked one after the other.
+func (d *DashboardStore) validateParentFolder(sess *sqlstore.DBSession, dash *models.Dashboard) error {
+	// without nested folders, the dashboard service refuses any parent folder regardless of the other folders
+	maxDepth := d.sqlStore.Cfg.MaxFolderDepth
+	if !dash.IsFolder || dash.FolderId == 0 || maxDepth <= 1 {
+		return nil
+	}
+
+	isFolder := "is_folder = " + d.sqlStore.Dialect.BooleanStr(true)
+	if dash.Id > 0 {
+		var existing models.Dashboard
+		has, err := sess.Table(&models.Dashboard{}).Cols("id", "folder_id").
+			Where("org_id = ? AND id = ? AND "+isFolder, dash.OrgId, dash.Id).ForUpdate().Get(&existing)
+		if err != nil {
+			return err
+		}
+		if has && existing.FolderId == dash.FolderId {
+			return nil
+		}
+	}
+
+	// the number of folders from the root one to the new parent
+	depth := 0
+	for parentID := dash.FolderId; parentID > 0; depth++ {
+		if parentID == dash.Id {
+			return models.ErrDashboardFolderInvalidParent
+		}
+		if depth >= maxDepth {
+			return models.ErrDashboardFolderMaxDepthExceeded
+		}
+		var parent models.Dashboard
+		has, err := sess.Table(&models.Dashboard{}).Cols("id", "folder_id").
+			Where("org_id = ? AND id = ? AND "+isFolder, dash.OrgId, parentID).ForUpdate().Get(&parent)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrDashboardFolderNotFound
+		}
+		parentID = parent.FolderId
+	}
+
+	// the number of levels of the folder and its subfolders
+	height := 1
+	if dash.Id > 0 {
+		parents := []int64{dash.Id}
+		seen := map[int64]bool{dash.Id: true}
+		for depth+height <= maxDepth {
+			var children []*models.Dashboard
+			err := sess.Table(&models.Dashboard{}).Cols("id").Where("org_id = ? AND "+isFolder, dash.OrgId).
+				In("folder_id", parents).ForUpdate().Find(&children)
+			if err != nil {
+				return err
+			}
+			parents = parents[:0]
+			for _, child := range children {
+				if !seen[child.Id] {
+					seen[child.Id] = true
+					parents = append(parents, child.Id)
+				}
+			}
+			if len(parents) == 0 {
+				break
+			}
+			height++
+		}
+	}
+
+	if depth+height > maxDepth {
+		return models.ErrDashboardFolderMaxDepthExceeded
+	}
+	return nil
+}
+
 func (d *DashboardStore) UpdateDashboardACL(ctx context.Context, dashboardID int64, items []*models.DashboardAcl) error {
 	return d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
 		// delete existing items
@@ -688,6 +832,16 @@ func (d *DashboardStore) GetDashboardsByPluginID(ctx context.Context, query *mod
 	})
 }
 
+// GetDashboard retrieves a dashboard by its ID, UID or slug
+func (d *DashboardStore) GetDashboard(ctx context.Context, query *models.GetDashboardQuery) error {
+	return d.sqlStore.GetDashboard(ctx, query)
+}
+
+// GetDashboardVersion retrieves a saved version of a dashboard
+func (d *DashboardStore) GetDashboardVersion(ctx context.Context, query *models.GetDashboardVersionQuery) error {
+	return d.sqlStore.GetDashboardVersion(ctx, query)
+}
+
 func (d *DashboardStore) DeleteDashboard(ctx context.Context, cmd *models.DeleteDashboardCommand) error {
 	return d.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
 		return d.deleteDashboard(cmd, sess)
@@ -709,12 +863,27 @@ func (d *DashboardStore) deleteDashboard(cmd *models.DeleteDashboardCommand, ses
 		"DELETE FROM dashboard WHERE id = ?",
 		"DELETE FROM playlist_item WHERE type = 'dashboard_by_id' AND value = ?",
 		"DELETE FROM dashboard_version WHERE dashboard_id = ?",
+		"DELETE FROM dashboard_version_tag WHERE dashboard_id = ?",
 		"DELETE FROM annotation WHERE dashboard_id = ?",
 		"DELETE FROM dashboard_provisioning WHERE dashboard_id = ?",
 		"DELETE FROM dashboard_acl WHERE dashboard_id = ?",
 	}
 
 	if dashboard.IsFolder {
+		// subfolders are deleted with their parent
+		var subfolderIds []int64
+		err = sess.SQL("SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ? AND is_folder = "+d.sqlStore.Dialect.BooleanStr(true),
+			dashboard.OrgId, dashboard.Id).Find(&subfolderIds)
+		if err != nil {
+			return err
+		}
+		for _, id := range subfolderIds {
+			subfolderCmd := &models.DeleteDashboardCommand{Id: id, OrgId: dashboard.OrgId, ForceDeleteFolderRules: cmd.ForceDeleteFolderRules}
+			if err := d.deleteDashboard(subfolderCmd, sess); err != nil {
+				return err
+			}
+		}
+
 		deletes = append(deletes, "DELETE FROM dashboard WHERE folder_id = ?")
 
 		var dashIds []struct {
@@ -751,9 +920,11 @@ func (d *DashboardStore) deleteDashboard(cmd *models.DeleteDashboardCommand, ses
 				"DELETE FROM dashboard_tag WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
 				"DELETE FROM star WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
 				"DELETE FROM dashboard_version WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
+				"DELETE FROM dashboard_version_tag WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
 				"DELETE FROM annotation WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
 				"DELETE FROM dashboard_provisioning WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
 				"DELETE FROM dashboard_acl WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
+				"DELETE FROM dashboard_public_config WHERE org_id = ? AND dashboard_uid IN (SELECT uid FROM dashboard WHERE org_id = dashboard_public_config.org_id AND folder_id = ?)",
 			}
 			for _, sql := range childrenDeletes {
 				_, err := sess.Exec(sql, dashboard.OrgId, dashboard.Id)
@@ -791,6 +962,11 @@ func (d *DashboardStore) deleteDashboard(cmd *models.DeleteDashboardCommand, ses
 		if err != nil {
 			return err
 		}
+
+		_, err = sess.Exec("DELETE FROM dashboard_public_config WHERE org_id = ? AND dashboard_uid = ?", dashboard.OrgId, dashboard.Uid)
+		if err != nil {
+			return err
+		}
 	}
 
 	if err := d.deleteAlertDefinition(dashboard.Id, sess); err != nil {
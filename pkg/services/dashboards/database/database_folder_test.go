@@ -578,6 +578,145 @@ func TestDashboardFolderDataAccess(t *testing.T) {
 				require.ErrorIs(t, err, models.ErrFolderNotFound)
 			})
 		})
+
+		t.Run("Given nested folders", func(t *testing.T) {
+			var orgId int64 = 1
+			sqlStore := sqlstore.InitTestDB(t)
+			sqlStore.Cfg.MaxFolderDepth = 3
+			dashboardStore := ProvideDashboardStore(sqlStore)
+			root := insertTestDashboard(t, dashboardStore, "Team", orgId, 0, true, "prod")
+			child := insertTestDashboard(t, dashboardStore, "Service", orgId, root.Id, true, "prod")
+			grandchild := insertTestDashboard(t, dashboardStore, "Environment", orgId, child.Id, true, "prod")
+			dash := insertTestDashboard(t, dashboardStore, "Nested dashboard", orgId, grandchild.Id, false, "prod")
+			viewer := CreateUser(t, sqlStore, "viewer", "Viewer", false)
+
+			t.Run("GetFolderByUID should return nested folder", func(t *testing.T) {
+				f, err := dashboardStore.GetFolderByUID(context.Background(), orgId, grandchild.Uid)
+				require.NoError(t, err)
+				require.Equal(t, grandchild.Id, f.Id)
+				require.Equal(t, child.Id, f.ParentId)
+			})
+
+			t.Run("GetFolderByTitle should resolve the path of nested folder", func(t *testing.T) {
+				f, err := dashboardStore.GetFolderByTitle(context.Background(), orgId, "Team/Service/Environment")
+				require.NoError(t, err)
+				require.Equal(t, grandchild.Id, f.Id)
+
+				_, err = dashboardStore.GetFolderByTitle(context.Background(), orgId, "Team/Environment")
+				require.ErrorIs(t, err, models.ErrFolderNotFound)
+			})
+
+			t.Run("GetFolderPath should return the folder and its parents", func(t *testing.T) {
+				path, err := dashboardStore.GetFolderPath(context.Background(), orgId, grandchild.Id)
+				require.NoError(t, err)
+				require.Len(t, path, 3)
+				require.Equal(t, root.Uid, path[0].UID)
+				require.Equal(t, child.Uid, path[1].UID)
+				require.Equal(t, grandchild.Uid, path[2].UID)
+				require.Equal(t, "Environment", path[2].Title)
+
+				_, err = dashboardStore.GetFolderPath(context.Background(), orgId, dash.Id)
+				require.ErrorIs(t, err, models.ErrFolderNotFound)
+			})
+
+			t.Run("GetDescendantFolders should return subfolders", func(t *testing.T) {
+				descendants, err := dashboardStore.GetDescendantFolders(context.Background(), orgId, root.Id)
+				require.NoError(t, err)
+				require.Len(t, descendants, 2)
+				require.Equal(t, child.Id, descendants[0].Id)
+				require.Equal(t, grandchild.Id, descendants[1].Id)
+			})
+
+			t.Run("permissions of root folder should be inherited by nested dashboards", func(t *testing.T) {
+				err := updateDashboardAcl(t, dashboardStore, root.Id, models.DashboardAcl{
+					DashboardID: root.Id, OrgID: orgId, UserID: viewer.Id, Permission: models.PERMISSION_EDIT,
+				})
+				require.NoError(t, err)
+
+				query := &models.FindPersistedDashboardsQuery{
+					SignedInUser: &models.SignedInUser{UserId: viewer.Id, OrgId: orgId, OrgRole: models.ROLE_VIEWER},
+					OrgId:        orgId,
+					Permission:   models.PERMISSION_EDIT,
+				}
+				err = sqlStore.SearchDashboards(context.Background(), query)
+				require.NoError(t, err)
+				require.Len(t, query.Result, 4)
+
+				for _, hit := range query.Result {
+					if hit.ID == dash.Id {
+						require.Len(t, hit.FolderPath, 3)
+						require.Equal(t, root.Uid, hit.FolderPath[0].UID)
+					}
+				}
+
+				aclQuery := &models.GetDashboardAclInfoListQuery{OrgID: orgId, DashboardID: dash.Id}
+				err = sqlStore.GetDashboardAclInfoList(context.Background(), aclQuery)
+				require.NoError(t, err)
+				require.Len(t, aclQuery.Result, 1)
+				require.True(t, aclQuery.Result[0].Inherited)
+				require.Equal(t, viewer.Id, aclQuery.Result[0].UserId)
+			})
+
+			t.Run("folder path should not contain the parents the user cannot view", func(t *testing.T) {
+				other := CreateUser(t, sqlStore, "other", "Viewer", false)
+				err := updateDashboardAcl(t, dashboardStore, grandchild.Id, models.DashboardAcl{
+					DashboardID: grandchild.Id, OrgID: orgId, UserID: other.Id, Permission: models.PERMISSION_VIEW,
+				})
+				require.NoError(t, err)
+
+				query := &models.FindPersistedDashboardsQuery{
+					SignedInUser: &models.SignedInUser{UserId: other.Id, OrgId: orgId, OrgRole: models.ROLE_VIEWER},
+					OrgId:        orgId,
+					DashboardIds: []int64{dash.Id},
+				}
+				err = sqlStore.SearchDashboards(context.Background(), query)
+				require.NoError(t, err)
+				require.Len(t, query.Result, 1)
+				require.Len(t, query.Result[0].FolderPath, 1)
+				require.Equal(t, grandchild.Uid, query.Result[0].FolderPath[0].UID)
+			})
+
+			t.Run("saving a folder should check its parents in the same transaction", func(t *testing.T) {
+				_, err := dashboardStore.SaveDashboard(models.SaveDashboardCommand{
+					OrgId:     orgId,
+					FolderId:  grandchild.Id,
+					IsFolder:  true,
+					Dashboard: root.Data,
+					Overwrite: true,
+				})
+				require.ErrorIs(t, err, models.ErrDashboardFolderInvalidParent)
+
+				_, err = dashboardStore.SaveDashboard(models.SaveDashboardCommand{
+					OrgId:    orgId,
+					FolderId: grandchild.Id,
+					IsFolder: true,
+					Dashboard: simplejson.NewFromAny(map[string]interface{}{
+						"title": "Region",
+					}),
+				})
+				require.ErrorIs(t, err, models.ErrDashboardFolderMaxDepthExceeded)
+
+				_, err = dashboardStore.SaveDashboard(models.SaveDashboardCommand{
+					OrgId:     orgId,
+					FolderId:  child.Id,
+					IsFolder:  true,
+					Dashboard: root.Data,
+					Overwrite: true,
+				})
+				require.ErrorIs(t, err, models.ErrDashboardFolderInvalidParent)
+			})
+
+			t.Run("deleting a folder should delete its subfolders and their dashboards", func(t *testing.T) {
+				err := dashboardStore.DeleteDashboard(context.Background(), &models.DeleteDashboardCommand{Id: root.Id, OrgId: orgId})
+				require.NoError(t, err)
+
+				for _, id := range []int64{root.Id, child.Id, grandchild.Id, dash.Id} {
+					query := &models.GetDashboardQuery{Id: id, OrgId: orgId}
+					err := sqlStore.GetDashboard(context.Background(), query)
+					require.ErrorIs(t, err, models.ErrDashboardNotFound)
+				}
+			})
+		})
 	})
 }
 
@@ -42,6 +42,34 @@ func (_m *FakeDashboardStore) DeleteOrphanedProvisionedDashboards(ctx context.Co
 	return r0
 }
 
+// GetDashboard provides a mock function with given fields: ctx, query
+func (_m *FakeDashboardStore) GetDashboard(ctx context.Context, query *models.GetDashboardQuery) error {
+	ret := _m.Called(ctx, query)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.GetDashboardQuery) error); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetDashboardVersion provides a mock function with given fields: ctx, query
+func (_m *FakeDashboardStore) GetDashboardVersion(ctx context.Context, query *models.GetDashboardVersionQuery) error {
+	ret := _m.Called(ctx, query)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.GetDashboardVersionQuery) error); ok {
+		r0 = rf(ctx, query)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // GetDashboardsByPluginID provides a mock function with given fields: ctx, query
 func (_m *FakeDashboardStore) GetDashboardsByPluginID(ctx context.Context, query *models.GetDashboardsByPluginIdQuery) error {
 	ret := _m.Called(ctx, query)
@@ -56,6 +84,29 @@ func (_m *FakeDashboardStore) GetDashboardsByPluginID(ctx context.Context, query
 	return r0
 }
 
+// GetDescendantFolders provides a mock function with given fields: ctx, orgID, folderID
+func (_m *FakeDashboardStore) GetDescendantFolders(ctx context.Context, orgID int64, folderID int64) ([]*models.Folder, error) {
+	ret := _m.Called(ctx, orgID, folderID)
+
+	var r0 []*models.Folder
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) []*models.Folder); ok {
+		r0 = rf(ctx, orgID, folderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Folder)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, orgID, folderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetFolderByID provides a mock function with given fields: ctx, orgID, id
 func (_m *FakeDashboardStore) GetFolderByID(ctx context.Context, orgID int64, id int64) (*models.Folder, error) {
 	ret := _m.Called(ctx, orgID, id)
@@ -125,6 +176,29 @@ func (_m *FakeDashboardStore) GetFolderByUID(ctx context.Context, orgID int64, u
 	return r0, r1
 }
 
+// GetFolderPath provides a mock function with given fields: ctx, orgID, folderID
+func (_m *FakeDashboardStore) GetFolderPath(ctx context.Context, orgID int64, folderID int64) ([]*models.FolderPathItem, error) {
+	ret := _m.Called(ctx, orgID, folderID)
+
+	var r0 []*models.FolderPathItem
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) []*models.FolderPathItem); ok {
+		r0 = rf(ctx, orgID, folderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.FolderPathItem)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, orgID, folderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetProvisionedDashboardData provides a mock function with given fields: name
 func (_m *FakeDashboardStore) GetProvisionedDashboardData(name string) ([]*models.DashboardProvisioning, error) {
 	ret := _m.Called(name)
@@ -14,13 +14,13 @@ type FakeFolderService struct {
 	mock.Mock
 }
 
-// CreateFolder provides a mock function with given fields: ctx, user, orgID, title, uid
-func (_m *FakeFolderService) CreateFolder(ctx context.Context, user *models.SignedInUser, orgID int64, title string, uid string) (*models.Folder, error) {
-	ret := _m.Called(ctx, user, orgID, title, uid)
+// CreateFolder provides a mock function with given fields: ctx, user, orgID, title, uid, parentUID
+func (_m *FakeFolderService) CreateFolder(ctx context.Context, user *models.SignedInUser, orgID int64, title string, uid string, parentUID string) (*models.Folder, error) {
+	ret := _m.Called(ctx, user, orgID, title, uid, parentUID)
 
 	var r0 *models.Folder
-	if rf, ok := ret.Get(0).(func(context.Context, *models.SignedInUser, int64, string, string) *models.Folder); ok {
-		r0 = rf(ctx, user, orgID, title, uid)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.SignedInUser, int64, string, string, string) *models.Folder); ok {
+		r0 = rf(ctx, user, orgID, title, uid, parentUID)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(*models.Folder)
@@ -28,8 +28,8 @@ func (_m *FakeFolderService) CreateFolder(ctx context.Context, user *models.Sign
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, *models.SignedInUser, int64, string, string) error); ok {
-		r1 = rf(ctx, user, orgID, title, uid)
+	if rf, ok := ret.Get(1).(func(context.Context, *models.SignedInUser, int64, string, string, string) error); ok {
+		r1 = rf(ctx, user, orgID, title, uid, parentUID)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -60,6 +60,29 @@ func (_m *FakeFolderService) DeleteFolder(ctx context.Context, user *models.Sign
 	return r0, r1
 }
 
+// GetDescendants provides a mock function with given fields: ctx, orgID, folderID
+func (_m *FakeFolderService) GetDescendants(ctx context.Context, orgID int64, folderID int64) ([]*models.Folder, error) {
+	ret := _m.Called(ctx, orgID, folderID)
+
+	var r0 []*models.Folder
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) []*models.Folder); ok {
+		r0 = rf(ctx, orgID, folderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Folder)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, orgID, folderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetFolderByID provides a mock function with given fields: ctx, user, id, orgID
 func (_m *FakeFolderService) GetFolderByID(ctx context.Context, user *models.SignedInUser, id int64, orgID int64) (*models.Folder, error) {
 	ret := _m.Called(ctx, user, id, orgID)
@@ -152,6 +175,29 @@ func (_m *FakeFolderService) GetFolders(ctx context.Context, user *models.Signed
 	return r0, r1
 }
 
+// GetParents provides a mock function with given fields: ctx, user, orgID, folderID
+func (_m *FakeFolderService) GetParents(ctx context.Context, user *models.SignedInUser, orgID int64, folderID int64) ([]*models.FolderPathItem, error) {
+	ret := _m.Called(ctx, user, orgID, folderID)
+
+	var r0 []*models.FolderPathItem
+	if rf, ok := ret.Get(0).(func(context.Context, *models.SignedInUser, int64, int64) []*models.FolderPathItem); ok {
+		r0 = rf(ctx, user, orgID, folderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.FolderPathItem)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.SignedInUser, int64, int64) error); ok {
+		r1 = rf(ctx, user, orgID, folderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // MakeUserAdmin provides a mock function with given fields: ctx, orgID, userID, folderID, setViewAndEditPermissions
 func (_m *FakeFolderService) MakeUserAdmin(ctx context.Context, orgID int64, userID int64, folderID int64, setViewAndEditPermissions bool) error {
 	ret := _m.Called(ctx, orgID, userID, folderID, setViewAndEditPermissions)
@@ -166,6 +212,20 @@ func (_m *FakeFolderService) MakeUserAdmin(ctx context.Context, orgID int64, use
 	return r0
 }
 
+// MoveFolder provides a mock function with given fields: ctx, user, orgID, uid, cmd
+func (_m *FakeFolderService) MoveFolder(ctx context.Context, user *models.SignedInUser, orgID int64, uid string, cmd *models.MoveFolderCommand) error {
+	ret := _m.Called(ctx, user, orgID, uid, cmd)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.SignedInUser, int64, string, *models.MoveFolderCommand) error); ok {
+		r0 = rf(ctx, user, orgID, uid, cmd)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UpdateFolder provides a mock function with given fields: ctx, user, orgID, existingUid, cmd
 func (_m *FakeFolderService) UpdateFolder(ctx context.Context, user *models.SignedInUser, orgID int64, existingUid string, cmd *models.UpdateFolderCommand) error {
 	ret := _m.Called(ctx, user, orgID, existingUid, cmd)
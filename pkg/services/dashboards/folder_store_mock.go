@@ -14,6 +14,29 @@ type FakeFolderStore struct {
 	mock.Mock
 }
 
+// GetDescendantFolders provides a mock function with given fields: ctx, orgID, folderID
+func (_m *FakeFolderStore) GetDescendantFolders(ctx context.Context, orgID int64, folderID int64) ([]*models.Folder, error) {
+	ret := _m.Called(ctx, orgID, folderID)
+
+	var r0 []*models.Folder
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) []*models.Folder); ok {
+		r0 = rf(ctx, orgID, folderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Folder)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, orgID, folderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetFolderByID provides a mock function with given fields: ctx, orgID, id
 func (_m *FakeFolderStore) GetFolderByID(ctx context.Context, orgID int64, id int64) (*models.Folder, error) {
 	ret := _m.Called(ctx, orgID, id)
@@ -82,3 +105,26 @@ func (_m *FakeFolderStore) GetFolderByUID(ctx context.Context, orgID int64, uid
 
 	return r0, r1
 }
+
+// GetFolderPath provides a mock function with given fields: ctx, orgID, folderID
+func (_m *FakeFolderStore) GetFolderPath(ctx context.Context, orgID int64, folderID int64) ([]*models.FolderPathItem, error) {
+	ret := _m.Called(ctx, orgID, folderID)
+
+	var r0 []*models.FolderPathItem
+	if rf, ok := ret.Get(0).(func(context.Context, int64, int64) []*models.FolderPathItem); ok {
+		r0 = rf(ctx, orgID, folderID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.FolderPathItem)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int64, int64) error); ok {
+		r1 = rf(ctx, orgID, folderID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
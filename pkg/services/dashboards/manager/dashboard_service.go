@@ -2,12 +2,14 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
 
+	"github.com/grafana/grafana/pkg/components/dashdiffs"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
@@ -37,12 +39,13 @@ type DashboardServiceImpl struct {
 	features             featuremgmt.FeatureToggles
 	folderPermissions    accesscontrol.FolderPermissionsService
 	dashboardPermissions accesscontrol.DashboardPermissionsService
+	linter               m.DashboardLinter
 }
 
 func ProvideDashboardService(
 	cfg *setting.Cfg, store m.Store, dashAlertExtractor alerting.DashAlertExtractor,
 	features featuremgmt.FeatureToggles, folderPermissionsService accesscontrol.FolderPermissionsService,
-	dashboardPermissionsService accesscontrol.DashboardPermissionsService,
+	dashboardPermissionsService accesscontrol.DashboardPermissionsService, linter m.DashboardLinter,
 ) *DashboardServiceImpl {
 	return &DashboardServiceImpl{
 		cfg:                  cfg,
@@ -52,6 +55,7 @@ func ProvideDashboardService(
 		features:             features,
 		folderPermissions:    folderPermissionsService,
 		dashboardPermissions: dashboardPermissionsService,
+		linter:               linter,
 	}
 }
 
@@ -81,7 +85,9 @@ func (dr *DashboardServiceImpl) BuildSaveDashboardCommand(ctx context.Context, d
 	}
 
 	if dash.IsFolder && dash.FolderId > 0 {
-		return nil, models.ErrDashboardFolderCannotHaveParent
+		if err := dr.validateParentFolder(ctx, dash); err != nil {
+			return nil, err
+		}
 	}
 
 	if dash.IsFolder && strings.EqualFold(dash.Title, models.RootFolderName) {
@@ -106,6 +112,12 @@ func (dr *DashboardServiceImpl) BuildSaveDashboardCommand(ctx context.Context, d
 	}
 
 	isParentFolderChanged, err := dr.dashboardStore.ValidateDashboardBeforeSave(dash, dto.Overwrite)
+	if errors.Is(err, models.ErrDashboardVersionMismatch) {
+		if err := dr.mergeConcurrentChanges(ctx, dash); err != nil {
+			return nil, err
+		}
+		isParentFolderChanged, err = dr.dashboardStore.ValidateDashboardBeforeSave(dash, dto.Overwrite)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -149,6 +161,15 @@ func (dr *DashboardServiceImpl) BuildSaveDashboardCommand(ctx context.Context, d
 		}
 	}
 
+	// the lint rules check the dashboard as it is saved, once merged with the concurrent changes
+	if dr.linter != nil && !dash.IsFolder {
+		problems, err := dr.linter.CheckDashboard(ctx, dto.OrgId, dash.Data)
+		if err != nil {
+			return nil, err
+		}
+		dto.LintProblems = problems
+	}
+
 	cmd := &models.SaveDashboardCommand{
 		Dashboard: dash.Data,
 		Message:   dto.Message,
@@ -167,6 +188,107 @@ func (dr *DashboardServiceImpl) BuildSaveDashboardCommand(ctx context.Context, d
 	return cmd, nil
 }
 
+// mergeConcurrentChanges merges the changes made to a dashboard saved from an outdated version with the changes
+// saved since then, using the outdated version as the common base. The dashboard is updated to the merged result,
+// based on the stored version, or a DashboardMergeConflictError is returned when both changed the same values.
+func (dr *DashboardServiceImpl) mergeConcurrentChanges(ctx context.Context, dash *models.Dashboard) error {
+	// without a version there is no common base to merge from
+	if dash.IsFolder || dash.Version == 0 {
+		return models.ErrDashboardVersionMismatch
+	}
+
+	stored := models.GetDashboardQuery{Id: dash.Id, OrgId: dash.OrgId}
+	if err := dr.dashboardStore.GetDashboard(ctx, &stored); err != nil {
+		return err
+	}
+
+	base := models.GetDashboardVersionQuery{DashboardId: dash.Id, OrgId: dash.OrgId, Version: dash.Version}
+	if err := dr.dashboardStore.GetDashboardVersion(ctx, &base); err != nil {
+		if errors.Is(err, models.ErrDashboardVersionNotFound) {
+			return models.ErrDashboardVersionMismatch
+		}
+		return err
+	}
+
+	merged, conflicts, err := dashdiffs.Merge(base.Result.Data, stored.Result.Data, dash.Data)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return models.DashboardMergeConflictError{Version: stored.Result.Version, Conflicts: conflicts}
+	}
+
+	dr.log.Debug("Merged concurrent dashboard changes", "dashboardUid", dash.Uid, "baseVersion", dash.Version,
+		"storedVersion", stored.Result.Version)
+
+	dash.Data = merged
+	dash.Title = strings.TrimSpace(merged.Get("title").MustString())
+	dash.Data.Set("title", dash.Title)
+	if dash.Title == "" {
+		return models.ErrDashboardTitleEmpty
+	}
+	dash.UpdateSlug()
+	dash.SetVersion(stored.Result.Version)
+	return nil
+}
+
+// validateParentFolder checks that a folder can be saved in its parent folder, without moving it into one of its own
+// subfolders or nesting folders deeper than the maximum folder depth
+func (dr *DashboardServiceImpl) validateParentFolder(ctx context.Context, dash *models.Dashboard) error {
+	if dr.cfg == nil || dr.cfg.MaxFolderDepth <= 1 {
+		return models.ErrDashboardFolderCannotHaveParent
+	}
+
+	if dash.Id > 0 {
+		existing, err := dr.dashboardStore.GetFolderByID(ctx, dash.OrgId, dash.Id)
+		if err != nil && !errors.Is(err, models.ErrFolderNotFound) {
+			return err
+		}
+		if existing != nil && existing.ParentId == dash.FolderId {
+			return nil
+		}
+	}
+
+	path, err := dr.dashboardStore.GetFolderPath(ctx, dash.OrgId, dash.FolderId)
+	if err != nil {
+		return err
+	}
+	for _, parent := range path {
+		if parent.ID == dash.Id {
+			return models.ErrDashboardFolderInvalidParent
+		}
+	}
+
+	height := 1
+	if dash.Id > 0 {
+		descendants, err := dr.dashboardStore.GetDescendantFolders(ctx, dash.OrgId, dash.Id)
+		if err != nil {
+			return err
+		}
+		height += subfoldersHeight(dash.Id, descendants)
+	}
+
+	if len(path)+height > dr.cfg.MaxFolderDepth {
+		return models.ErrDashboardFolderMaxDepthExceeded
+	}
+	return nil
+}
+
+// subfoldersHeight returns the number of levels of subfolders below the folder
+func subfoldersHeight(folderID int64, descendants []*models.Folder) int {
+	depths := map[int64]int{folderID: 0}
+	height := 0
+	// descendants are ordered from the closest to the farthest ones, parents always come before their subfolders
+	for _, folder := range descendants {
+		depth := depths[folder.ParentId] + 1
+		depths[folder.Id] = depth
+		if depth > height {
+			height = depth
+		}
+	}
+	return height
+}
+
 func (dr *DashboardServiceImpl) UpdateDashboardACL(ctx context.Context, uid int64, items []*models.DashboardAcl) error {
 	return dr.dashboardStore.UpdateDashboardACL(ctx, uid, items)
 }
@@ -789,6 +789,67 @@ func TestIntegratedDashboardService(t *testing.T) {
 					})
 			})
 		})
+
+		t.Run("Given concurrent changes to a dashboard", func(t *testing.T) {
+			saveVersion := func(t *testing.T, sc *permissionScenarioContext, version int, panels []interface{}) models.SaveDashboardCommand {
+				return models.SaveDashboardCommand{
+					OrgId: testOrgID,
+					Dashboard: simplejson.NewFromAny(map[string]interface{}{
+						"id":      sc.savedDashInGeneralFolder.Id,
+						"title":   sc.savedDashInGeneralFolder.Title,
+						"version": version,
+						"panels":  panels,
+					}),
+				}
+			}
+
+			permissionScenario(t, "When saving changes to different panels from the same version", true,
+				func(t *testing.T, sc *permissionScenarioContext) {
+					base := callSaveWithResult(t, saveVersion(t, sc, sc.savedDashInGeneralFolder.Version, []interface{}{
+						map[string]interface{}{"id": 1, "title": "CPU"},
+						map[string]interface{}{"id": 2, "title": "Memory"},
+					}), sc.sqlStore)
+
+					callSaveWithResult(t, saveVersion(t, sc, base.Version, []interface{}{
+						map[string]interface{}{"id": 1, "title": "CPU usage"},
+						map[string]interface{}{"id": 2, "title": "Memory"},
+					}), sc.sqlStore)
+
+					res := callSaveWithResult(t, saveVersion(t, sc, base.Version, []interface{}{
+						map[string]interface{}{"id": 1, "title": "CPU"},
+						map[string]interface{}{"id": 2, "title": "Memory usage"},
+					}), sc.sqlStore)
+					assert.Equal(t, base.Version+2, res.Version)
+
+					query := models.GetDashboardQuery{Id: sc.savedDashInGeneralFolder.Id, OrgId: testOrgID}
+					err := sc.sqlStore.GetDashboard(context.Background(), &query)
+					require.NoError(t, err)
+					panels := query.Result.Data.Get("panels")
+					assert.Equal(t, "CPU usage", panels.GetIndex(0).Get("title").MustString())
+					assert.Equal(t, "Memory usage", panels.GetIndex(1).Get("title").MustString())
+				})
+
+			permissionScenario(t, "When saving changes to the same panel from the same version", true,
+				func(t *testing.T, sc *permissionScenarioContext) {
+					base := callSaveWithResult(t, saveVersion(t, sc, sc.savedDashInGeneralFolder.Version, []interface{}{
+						map[string]interface{}{"id": 1, "title": "CPU"},
+					}), sc.sqlStore)
+
+					stored := callSaveWithResult(t, saveVersion(t, sc, base.Version, []interface{}{
+						map[string]interface{}{"id": 1, "title": "CPU usage"},
+					}), sc.sqlStore)
+
+					err := callSaveWithError(saveVersion(t, sc, base.Version, []interface{}{
+						map[string]interface{}{"id": 1, "title": "CPU load"},
+					}), sc.sqlStore)
+
+					var conflictErr models.DashboardMergeConflictError
+					require.ErrorAs(t, err, &conflictErr)
+					assert.Equal(t, stored.Version, conflictErr.Version)
+					require.Len(t, conflictErr.Conflicts, 1)
+					assert.Equal(t, "panels[id=1].title", conflictErr.Conflicts[0].Path)
+				})
+		})
 	})
 }
 
@@ -864,7 +925,7 @@ func callSaveWithResult(t *testing.T, cmd models.SaveDashboardCommand, sqlStore
 		cfg, dashboardStore, &dummyDashAlertExtractor{},
 		featuremgmt.WithFeatures(),
 		accesscontrolmock.NewMockedPermissionsService(),
-		accesscontrolmock.NewMockedPermissionsService(),
+		accesscontrolmock.NewMockedPermissionsService(), nil,
 	)
 	res, err := service.SaveDashboard(context.Background(), &dto, false)
 	require.NoError(t, err)
@@ -881,7 +942,7 @@ func callSaveWithError(cmd models.SaveDashboardCommand, sqlStore *sqlstore.SQLSt
 		cfg, dashboardStore, &dummyDashAlertExtractor{},
 		featuremgmt.WithFeatures(),
 		accesscontrolmock.NewMockedPermissionsService(),
-		accesscontrolmock.NewMockedPermissionsService(),
+		accesscontrolmock.NewMockedPermissionsService(), nil,
 	)
 	_, err := service.SaveDashboard(context.Background(), &dto, false)
 	return err
@@ -915,7 +976,7 @@ func saveTestDashboard(t *testing.T, title string, orgID, folderID int64, sqlSto
 	service := ProvideDashboardService(
 		cfg, dashboardStore, &dummyDashAlertExtractor{},
 		featuremgmt.WithFeatures(),
-		accesscontrolmock.NewMockedPermissionsService(), accesscontrolmock.NewMockedPermissionsService(),
+		accesscontrolmock.NewMockedPermissionsService(), accesscontrolmock.NewMockedPermissionsService(), nil,
 	)
 	res, err := service.SaveDashboard(context.Background(), &dto, false)
 	require.NoError(t, err)
@@ -950,7 +1011,7 @@ func saveTestFolder(t *testing.T, title string, orgID int64, sqlStore *sqlstore.
 	service := ProvideDashboardService(
 		cfg, dashboardStore, &dummyDashAlertExtractor{},
 		featuremgmt.WithFeatures(),
-		accesscontrolmock.NewMockedPermissionsService(), accesscontrolmock.NewMockedPermissionsService(),
+		accesscontrolmock.NewMockedPermissionsService(), accesscontrolmock.NewMockedPermissionsService(), nil,
 	)
 	res, err := service.SaveDashboard(context.Background(), &dto, false)
 	require.NoError(t, err)
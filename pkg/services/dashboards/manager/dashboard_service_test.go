@@ -120,6 +120,36 @@ func TestDashboardService(t *testing.T) {
 				_, err := service.SaveDashboard(context.Background(), dto, false)
 				require.Equal(t, err.Error(), "alert validation error")
 			})
+
+			t.Run("Should return lint error if lint rules block the dashboard", func(t *testing.T) {
+				fakeStore.On("ValidateDashboardBeforeSave", mock.Anything, mock.Anything).Return(true, nil).Once()
+				fakeStore.On("GetProvisionedDataByDashboardID", mock.Anything).Return(nil, nil).Once()
+				lintErr := models.DashboardLintError{Problems: []models.DashboardLintProblem{{Rule: "max-panels", Severity: models.DashboardLintSeverityBlocking}}}
+				service.linter = &fakeDashboardLinter{err: lintErr}
+				defer func() { service.linter = nil }()
+
+				dto.Dashboard = models.NewDashboard("Dash")
+				dto.User = &models.SignedInUser{UserId: 1}
+				_, err := service.SaveDashboard(context.Background(), dto, false)
+				require.Equal(t, lintErr, err)
+			})
+
+			t.Run("Should save dashboard with lint warnings", func(t *testing.T) {
+				fakeStore.On("ValidateDashboardBeforeSave", mock.Anything, mock.Anything).Return(true, nil).Once()
+				fakeStore.On("GetProvisionedDataByDashboardID", mock.Anything).Return(nil, nil).Once()
+				fakeStore.On("SaveDashboard", mock.Anything).Return(&models.Dashboard{Data: simplejson.New()}, nil).Once()
+				fakeStore.On("SaveAlerts", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+				problems := []models.DashboardLintProblem{{Rule: "max-panels", Severity: models.DashboardLintSeverityWarning}}
+				service.linter = &fakeDashboardLinter{problems: problems}
+				defer func() { service.linter = nil }()
+
+				dto.Dashboard = models.NewDashboard("Dash")
+				dto.Dashboard.SetId(3)
+				dto.User = &models.SignedInUser{UserId: 1}
+				_, err := service.SaveDashboard(context.Background(), dto, false)
+				require.NoError(t, err)
+				require.Equal(t, problems, dto.LintProblems)
+			})
 		})
 
 		t.Run("Save provisioned dashboard validation", func(t *testing.T) {
@@ -209,3 +239,12 @@ func TestDashboardService(t *testing.T) {
 type Result struct {
 	deleteWasCalled bool
 }
+
+type fakeDashboardLinter struct {
+	problems []models.DashboardLintProblem
+	err      error
+}
+
+func (f *fakeDashboardLinter) CheckDashboard(_ context.Context, _ int64, _ *simplejson.Json) ([]models.DashboardLintProblem, error) {
+	return f.problems, f.err
+}
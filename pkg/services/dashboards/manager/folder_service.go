@@ -34,6 +34,9 @@ func ProvideFolderService(
 ) *FolderServiceImpl {
 	ac.RegisterScopeAttributeResolver(dashboards.NewFolderNameScopeResolver(dashboardStore))
 	ac.RegisterScopeAttributeResolver(dashboards.NewFolderIDScopeResolver(dashboardStore))
+	if cfg.MaxFolderDepth > 1 {
+		ac.RegisterScopeAttributeResolver(dashboards.NewFolderUIDScopeResolver(dashboardStore))
+	}
 
 	return &FolderServiceImpl{
 		cfg:              cfg,
@@ -130,7 +133,7 @@ func (f *FolderServiceImpl) GetFolderByTitle(ctx context.Context, user *models.S
 	return dashFolder, nil
 }
 
-func (f *FolderServiceImpl) CreateFolder(ctx context.Context, user *models.SignedInUser, orgID int64, title, uid string) (*models.Folder, error) {
+func (f *FolderServiceImpl) CreateFolder(ctx context.Context, user *models.SignedInUser, orgID int64, title, uid, parentUID string) (*models.Folder, error) {
 	dashFolder := models.NewDashboardFolder(title)
 	dashFolder.OrgId = orgID
 
@@ -139,6 +142,14 @@ func (f *FolderServiceImpl) CreateFolder(ctx context.Context, user *models.Signe
 		return nil, models.ErrFolderInvalidUID
 	}
 
+	if parentUID != "" && parentUID != accesscontrol.GeneralFolderUID {
+		parent, err := f.getParentFolder(ctx, user, orgID, parentUID)
+		if err != nil {
+			return nil, err
+		}
+		dashFolder.FolderId = parent.Id
+	}
+
 	dashFolder.SetUid(trimmedUID)
 	userID := user.UserId
 	if userID == 0 {
@@ -228,6 +239,87 @@ func (f *FolderServiceImpl) UpdateFolder(ctx context.Context, user *models.Signe
 	return nil
 }
 
+func (f *FolderServiceImpl) MoveFolder(ctx context.Context, user *models.SignedInUser, orgID int64, uid string, cmd *models.MoveFolderCommand) error {
+	query := models.GetDashboardQuery{OrgId: orgID, Uid: uid}
+	if err := f.sqlStore.GetDashboard(ctx, &query); err != nil {
+		return toFolderError(err)
+	}
+
+	dashFolder := query.Result
+
+	if !dashFolder.IsFolder {
+		return models.ErrFolderNotFound
+	}
+
+	guard := guardian.New(ctx, dashFolder.Id, orgID, user)
+	if canSave, err := guard.CanSave(); err != nil || !canSave {
+		if err != nil {
+			return toFolderError(err)
+		}
+		return models.ErrFolderAccessDenied
+	}
+
+	var parentID int64
+	if cmd.ParentUid != "" && cmd.ParentUid != accesscontrol.GeneralFolderUID {
+		parent, err := f.getParentFolder(ctx, user, orgID, cmd.ParentUid)
+		if err != nil {
+			return err
+		}
+		parentID = parent.Id
+	}
+
+	dashFolder.FolderId = parentID
+	if cmd.Version > 0 {
+		dashFolder.Version = cmd.Version
+	}
+	dashFolder.UpdatedBy = user.UserId
+
+	dto := &dashboards.SaveDashboardDTO{
+		Dashboard: dashFolder,
+		OrgId:     orgID,
+		User:      user,
+		Overwrite: cmd.Overwrite,
+	}
+
+	saveDashboardCmd, err := f.dashboardService.BuildSaveDashboardCommand(ctx, dto, false, false)
+	if err != nil {
+		return toFolderError(err)
+	}
+
+	dash, err := f.dashboardStore.SaveDashboard(*saveDashboardCmd)
+	if err != nil {
+		return toFolderError(err)
+	}
+
+	folder, err := f.dashboardStore.GetFolderByID(ctx, orgID, dash.Id)
+	if err != nil {
+		return err
+	}
+	cmd.Result = folder
+	return nil
+}
+
+// getParentFolder returns the folder in which the user wants to create or move a folder, if the user can save folders in it
+func (f *FolderServiceImpl) getParentFolder(ctx context.Context, user *models.SignedInUser, orgID int64, parentUID string) (*models.Folder, error) {
+	if f.cfg.MaxFolderDepth <= 1 {
+		return nil, models.ErrDashboardFolderCannotHaveParent
+	}
+
+	parent, err := f.dashboardStore.GetFolderByUID(ctx, orgID, parentUID)
+	if err != nil {
+		return nil, err
+	}
+
+	guard := guardian.New(ctx, parent.Id, orgID, user)
+	if canSave, err := guard.CanSave(); err != nil || !canSave {
+		if err != nil {
+			return nil, toFolderError(err)
+		}
+		return nil, models.ErrFolderAccessDenied
+	}
+	return parent, nil
+}
+
 func (f *FolderServiceImpl) DeleteFolder(ctx context.Context, user *models.SignedInUser, orgID int64, uid string, forceDeleteRules bool) (*models.Folder, error) {
 	dashFolder, err := f.dashboardStore.GetFolderByUID(ctx, orgID, uid)
 	if err != nil {
@@ -255,6 +347,51 @@ func (f *FolderServiceImpl) MakeUserAdmin(ctx context.Context, orgID int64, user
 	return f.dashboardService.MakeUserAdmin(ctx, orgID, userID, folderID, setViewAndEditPermissions)
 }
 
+// GetParents returns the parents of the folder that the user can view, from the root folder to its closest parent
+func (f *FolderServiceImpl) GetParents(ctx context.Context, user *models.SignedInUser, orgID int64, folderID int64) ([]*models.FolderPathItem, error) {
+	path, err := f.dashboardStore.GetFolderPath(ctx, orgID, folderID)
+	if err != nil {
+		return nil, err
+	}
+	parents := path[:len(path)-1]
+	if len(parents) == 0 {
+		return parents, nil
+	}
+
+	ids := make([]int64, 0, len(parents))
+	for _, parent := range parents {
+		ids = append(ids, parent.ID)
+	}
+	searchQuery := models.FindPersistedDashboardsQuery{
+		SignedInUser: user,
+		OrgId:        orgID,
+		DashboardIds: ids,
+		Type:         "dash-folder",
+		Permission:   models.PERMISSION_VIEW,
+		Limit:        int64(len(ids)),
+	}
+	if err := f.sqlStore.SearchDashboards(ctx, &searchQuery); err != nil {
+		return nil, err
+	}
+
+	canView := make(map[int64]bool, len(searchQuery.Result))
+	for _, hit := range searchQuery.Result {
+		canView[hit.ID] = true
+	}
+	viewable := make([]*models.FolderPathItem, 0, len(parents))
+	for _, parent := range parents {
+		if canView[parent.ID] {
+			viewable = append(viewable, parent)
+		}
+	}
+	return viewable, nil
+}
+
+// GetDescendants returns the subfolders of the folder, and their own subfolders
+func (f *FolderServiceImpl) GetDescendants(ctx context.Context, orgID int64, folderID int64) ([]*models.Folder, error) {
+	return f.dashboardStore.GetDescendantFolders(ctx, orgID, folderID)
+}
+
 func toFolderError(err error) error {
 	if errors.Is(err, models.ErrDashboardTitleEmpty) {
 		return models.ErrFolderTitleEmpty
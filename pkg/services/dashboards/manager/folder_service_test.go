@@ -34,7 +34,7 @@ func TestProvideFolderService(t *testing.T) {
 		cfg.IsFeatureToggleEnabled = features.IsEnabled
 		folderPermissions := acmock.NewMockedPermissionsService()
 		dashboardPermissions := acmock.NewMockedPermissionsService()
-		dashboardService := ProvideDashboardService(cfg, store, nil, features, folderPermissions, dashboardPermissions)
+		dashboardService := ProvideDashboardService(cfg, store, nil, features, folderPermissions, dashboardPermissions, nil)
 		ac := acmock.New()
 
 		ProvideFolderService(
@@ -44,6 +44,159 @@ func TestProvideFolderService(t *testing.T) {
 
 		require.Len(t, ac.Calls.RegisterAttributeScopeResolver, 2)
 	})
+
+	t.Run("should register uid scope resolver when folders are nested", func(t *testing.T) {
+		store := &dashboards.FakeDashboardStore{}
+		cfg := setting.NewCfg()
+		cfg.MaxFolderDepth = 3
+		features := featuremgmt.WithFeatures()
+		cfg.IsFeatureToggleEnabled = features.IsEnabled
+		folderPermissions := acmock.NewMockedPermissionsService()
+		dashboardPermissions := acmock.NewMockedPermissionsService()
+		dashboardService := ProvideDashboardService(cfg, store, nil, features, folderPermissions, dashboardPermissions, nil)
+		ac := acmock.New()
+
+		ProvideFolderService(
+			cfg, &dashboards.FakeDashboardService{DashboardService: dashboardService},
+			store, nil, features, folderPermissions, ac, mockstore.NewSQLStoreMock(),
+		)
+
+		require.Len(t, ac.Calls.RegisterAttributeScopeResolver, 3)
+	})
+}
+
+func TestNestedFolderService(t *testing.T) {
+	store := &dashboards.FakeDashboardStore{}
+	cfg := setting.NewCfg()
+	cfg.MaxFolderDepth = 3
+	features := featuremgmt.WithFeatures()
+	cfg.IsFeatureToggleEnabled = features.IsEnabled
+	folderPermissions := acmock.NewMockedPermissionsService()
+	dashboardPermissions := acmock.NewMockedPermissionsService()
+	dashboardService := ProvideDashboardService(cfg, store, nil, features, folderPermissions, dashboardPermissions, nil)
+	mockStore := mockstore.NewSQLStoreMock()
+
+	service := FolderServiceImpl{
+		cfg:              cfg,
+		log:              log.New("test-folder-service"),
+		dashboardService: dashboardService,
+		dashboardStore:   store,
+		features:         features,
+		permissions:      folderPermissions,
+		sqlStore:         mockStore,
+	}
+
+	origNewGuardian := guardian.New
+	guardian.MockDashboardGuardian(&guardian.FakeDashboardGuardian{CanSaveValue: true, CanViewValue: true})
+	t.Cleanup(func() {
+		guardian.New = origNewGuardian
+	})
+
+	// Team > Service > Environment, and Other > Subfolder
+	team := &models.Folder{Id: 1, Uid: "team", Title: "Team"}
+	serviceFolder := &models.Folder{Id: 2, Uid: "service", Title: "Service", ParentId: 1}
+	environment := &models.Folder{Id: 3, Uid: "environment", Title: "Environment", ParentId: 2}
+	other := &models.Folder{Id: 4, Uid: "other", Title: "Other"}
+	subfolder := &models.Folder{Id: 5, Uid: "subfolder", Title: "Subfolder", ParentId: 4}
+	pathItem := func(f *models.Folder) *models.FolderPathItem {
+		return &models.FolderPathItem{ID: f.Id, UID: f.Uid, Title: f.Title}
+	}
+
+	for _, f := range []*models.Folder{team, serviceFolder, environment, other, subfolder} {
+		store.On("GetFolderByUID", mock.Anything, orgID, f.Uid).Return(f, nil)
+		store.On("GetFolderByID", mock.Anything, orgID, f.Id).Return(f, nil)
+	}
+	store.On("GetFolderPath", mock.Anything, orgID, team.Id).Return([]*models.FolderPathItem{pathItem(team)}, nil)
+	store.On("GetFolderPath", mock.Anything, orgID, serviceFolder.Id).Return([]*models.FolderPathItem{pathItem(team), pathItem(serviceFolder)}, nil)
+	store.On("GetFolderPath", mock.Anything, orgID, environment.Id).Return([]*models.FolderPathItem{pathItem(team), pathItem(serviceFolder), pathItem(environment)}, nil)
+	store.On("GetDescendantFolders", mock.Anything, orgID, team.Id).Return([]*models.Folder{serviceFolder, environment}, nil)
+	store.On("GetDescendantFolders", mock.Anything, orgID, other.Id).Return([]*models.Folder{subfolder}, nil)
+
+	folderDashboard := func(f *models.Folder) *models.Dashboard {
+		dash := models.NewDashboardFolder(f.Title)
+		dash.Id = f.Id
+		dash.Uid = f.Uid
+		dash.FolderId = f.ParentId
+		return dash
+	}
+
+	t.Run("When creating folder in a parent folder should save it in the parent", func(t *testing.T) {
+		dash := models.NewDashboardFolder("Nested")
+		dash.Id = rand.Int63()
+		f := models.DashboardToFolder(dash)
+
+		var actualCmd models.SaveDashboardCommand
+		store.On("ValidateDashboardBeforeSave", mock.Anything, mock.Anything).Return(true, nil)
+		store.On("SaveDashboard", mock.Anything).Run(func(args mock.Arguments) {
+			actualCmd = args.Get(0).(models.SaveDashboardCommand)
+		}).Return(dash, nil).Once()
+		store.On("GetFolderByID", mock.Anything, orgID, dash.Id).Return(f, nil)
+
+		_, err := service.CreateFolder(context.Background(), user, orgID, dash.Title, "", serviceFolder.Uid)
+		require.NoError(t, err)
+		require.Equal(t, serviceFolder.Id, actualCmd.FolderId)
+	})
+
+	t.Run("When creating folder deeper than the maximum depth should return error", func(t *testing.T) {
+		_, err := service.CreateFolder(context.Background(), user, orgID, "Too deep", "", environment.Uid)
+		require.ErrorIs(t, err, models.ErrDashboardFolderMaxDepthExceeded)
+	})
+
+	t.Run("When moving folder into one of its subfolders should return error", func(t *testing.T) {
+		mockStore.ExpectedDashboard = folderDashboard(team)
+
+		err := service.MoveFolder(context.Background(), user, orgID, team.Uid, &models.MoveFolderCommand{ParentUid: environment.Uid})
+		require.ErrorIs(t, err, models.ErrDashboardFolderInvalidParent)
+	})
+
+	t.Run("When moving folder with subfolders deeper than the maximum depth should return error", func(t *testing.T) {
+		mockStore.ExpectedDashboard = folderDashboard(other)
+
+		err := service.MoveFolder(context.Background(), user, orgID, other.Uid, &models.MoveFolderCommand{ParentUid: serviceFolder.Uid})
+		require.ErrorIs(t, err, models.ErrDashboardFolderMaxDepthExceeded)
+	})
+
+	t.Run("When moving folder with subfolders into a root folder should move it", func(t *testing.T) {
+		mockStore.ExpectedDashboard = folderDashboard(other)
+
+		var actualCmd models.SaveDashboardCommand
+		store.On("ValidateDashboardBeforeSave", mock.Anything, mock.Anything).Return(true, nil)
+		store.On("SaveDashboard", mock.Anything).Run(func(args mock.Arguments) {
+			actualCmd = args.Get(0).(models.SaveDashboardCommand)
+		}).Return(folderDashboard(other), nil).Once()
+
+		cmd := &models.MoveFolderCommand{ParentUid: team.Uid}
+		err := service.MoveFolder(context.Background(), user, orgID, other.Uid, cmd)
+		require.NoError(t, err)
+		require.Equal(t, team.Id, actualCmd.FolderId)
+		require.Equal(t, other, cmd.Result)
+	})
+
+	t.Run("When getting parents should return the path without the folder", func(t *testing.T) {
+		mockStore.ExpectedPersistedDashboards = models.HitList{{ID: team.Id}, {ID: serviceFolder.Id}}
+
+		parents, err := service.GetParents(context.Background(), user, orgID, environment.Id)
+		require.NoError(t, err)
+		require.Equal(t, []*models.FolderPathItem{pathItem(team), pathItem(serviceFolder)}, parents)
+	})
+
+	t.Run("When getting parents should not return the parents the user cannot view", func(t *testing.T) {
+		mockStore.ExpectedPersistedDashboards = models.HitList{{ID: serviceFolder.Id}}
+
+		parents, err := service.GetParents(context.Background(), user, orgID, environment.Id)
+		require.NoError(t, err)
+		require.Equal(t, []*models.FolderPathItem{pathItem(serviceFolder)}, parents)
+	})
+
+	t.Run("When folders cannot be nested should return error", func(t *testing.T) {
+		flatCfg := setting.NewCfg()
+		flatCfg.MaxFolderDepth = 1
+		flatService := service
+		flatService.cfg = flatCfg
+
+		_, err := flatService.CreateFolder(context.Background(), user, orgID, "Nested", "", team.Uid)
+		require.ErrorIs(t, err, models.ErrDashboardFolderCannotHaveParent)
+	})
 }
 
 func TestFolderService(t *testing.T) {
@@ -54,7 +207,7 @@ func TestFolderService(t *testing.T) {
 		cfg.IsFeatureToggleEnabled = features.IsEnabled
 		folderPermissions := acmock.NewMockedPermissionsService()
 		dashboardPermissions := acmock.NewMockedPermissionsService()
-		dashboardService := ProvideDashboardService(cfg, store, nil, features, folderPermissions, dashboardPermissions)
+		dashboardService := ProvideDashboardService(cfg, store, nil, features, folderPermissions, dashboardPermissions, nil)
 		mockStore := mockstore.NewSQLStoreMock()
 
 		service := FolderServiceImpl{
@@ -100,7 +253,7 @@ func TestFolderService(t *testing.T) {
 
 			t.Run("When creating folder should return access denied error", func(t *testing.T) {
 				store.On("ValidateDashboardBeforeSave", mock.Anything, mock.Anything).Return(true, nil).Times(2)
-				_, err := service.CreateFolder(context.Background(), user, orgID, folder.Title, folderUID)
+				_, err := service.CreateFolder(context.Background(), user, orgID, folder.Title, folderUID, "")
 				require.Equal(t, err, models.ErrFolderAccessDenied)
 			})
 
@@ -137,7 +290,7 @@ func TestFolderService(t *testing.T) {
 				store.On("SaveDashboard", mock.Anything).Return(dash, nil).Once()
 				store.On("GetFolderByID", mock.Anything, orgID, dash.Id).Return(f, nil)
 
-				actualFolder, err := service.CreateFolder(context.Background(), user, orgID, dash.Title, "")
+				actualFolder, err := service.CreateFolder(context.Background(), user, orgID, dash.Title, "", "")
 				require.NoError(t, err)
 				require.Equal(t, f, actualFolder)
 			})
@@ -146,7 +299,7 @@ func TestFolderService(t *testing.T) {
 				dash := models.NewDashboardFolder("Test-Folder")
 				dash.Id = rand.Int63()
 
-				_, err := service.CreateFolder(context.Background(), user, orgID, dash.Title, "general")
+				_, err := service.CreateFolder(context.Background(), user, orgID, dash.Title, "general", "")
 				require.ErrorIs(t, err, models.ErrFolderInvalidUID)
 			})
 
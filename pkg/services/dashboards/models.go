@@ -13,4 +13,7 @@ type SaveDashboardDTO struct {
 	Message   string
 	Overwrite bool
 	Dashboard *models.Dashboard
+	// LintProblems are the problems found in the dashboard by the lint rules of the organization, which
+	// didn't block it from being saved.
+	LintProblems []models.DashboardLintProblem
 }
@@ -2,25 +2,118 @@ package dashboardsnapshots
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/filestorage"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/secrets"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
+var (
+	ErrSnapshotTooLarge     = errors.New("snapshot is larger than the maximum snapshot size")
+	ErrSnapshotQuotaReached = models.ErrDashboardSnapshotQuotaReached
+)
+
+// number of snapshots moved from the database to the snapshot storage at once
+const migrationBatchSize = 100
+
 type Service struct {
-	SQLStore       sqlstore.Store
-	SecretsService secrets.Service
+	SQLStore          sqlstore.Store
+	SecretsService    secrets.Service
+	cfg               *setting.Cfg
+	serverLockService *serverlock.ServerLockService
+	blobStorage       filestorage.FileStorage
+	log               log.Logger
 }
 
-func ProvideService(store sqlstore.Store, secretsService secrets.Service) *Service {
+func ProvideService(store sqlstore.Store, secretsService secrets.Service, cfg *setting.Cfg,
+	serverLockService *serverlock.ServerLockService) (*Service, error) {
 	s := &Service{
-		SQLStore:       store,
-		SecretsService: secretsService,
+		SQLStore:          store,
+		SecretsService:    secretsService,
+		cfg:               cfg,
+		serverLockService: serverLockService,
+		log:               log.New("dashboard-snapshots"),
+	}
+
+	blobStorage, err := openBlobStorage(context.Background(), cfg, s.log)
+	if err != nil {
+		return nil, err
+	}
+	s.blobStorage = blobStorage
+
+	return s, nil
+}
+
+// Run moves the dashboards of the snapshots that are still stored in the database to the snapshot storage.
+func (s *Service) Run(ctx context.Context) error {
+	err := s.serverLockService.LockAndExecute(ctx, "migrate dashboard snapshots", 10*time.Minute, func(ctx context.Context) {
+		if err := s.migrateSnapshots(ctx); err != nil {
+			s.log.Error("Failed to move snapshots to the snapshot storage", "error", err)
+		}
+	})
+	if err != nil {
+		s.log.Error("Failed to lock and execute the snapshot migration", "error", err)
+	}
+	return nil
+}
+
+func (s *Service) migrateSnapshots(ctx context.Context) error {
+	migrated := 0
+	for ctx.Err() == nil {
+		query := models.GetDashboardSnapshotsWithoutBlobQuery{Limit: migrationBatchSize}
+		if err := s.SQLStore.GetDashboardSnapshotsWithoutBlob(ctx, &query); err != nil {
+			return err
+		}
+		if len(query.Result) == 0 {
+			break
+		}
+
+		for _, snapshot := range query.Result {
+			if err := s.migrateSnapshot(ctx, snapshot); err != nil {
+				return err
+			}
+			migrated++
+		}
+	}
+
+	if migrated > 0 {
+		s.log.Info("Moved snapshots to the snapshot storage", "count", migrated)
+	}
+	return ctx.Err()
+}
+
+func (s *Service) migrateSnapshot(ctx context.Context, snapshot *models.DashboardSnapshot) error {
+	dashboard, err := s.legacyDashboard(ctx, snapshot)
+	if err != nil {
+		return err
+	}
+	marshalledData, err := dashboard.Encode()
+	if err != nil {
+		return err
 	}
 
-	return s
+	content, err := encodeBlob(ctx, s.SecretsService, marshalledData)
+	if err != nil {
+		return err
+	}
+	key, err := s.storeBlob(ctx, snapshot.OrgId, content)
+	if err != nil {
+		return err
+	}
+
+	cmd := models.SetDashboardSnapshotBlobCommand{Id: snapshot.Id, BlobKey: key, BlobSize: int64(len(content))}
+	if err := s.SQLStore.SetDashboardSnapshotBlob(ctx, &cmd); err != nil {
+		s.deleteBlob(ctx, key)
+		return err
+	}
+	return nil
 }
 
 func (s *Service) CreateDashboardSnapshot(ctx context.Context, cmd *models.CreateDashboardSnapshotCommand) error {
@@ -29,14 +122,43 @@ func (s *Service) CreateDashboardSnapshot(ctx context.Context, cmd *models.Creat
 		return err
 	}
 
-	encryptedDashboard, err := s.SecretsService.Encrypt(ctx, marshalledData, secrets.WithoutScope())
+	if s.cfg.SnapshotMaxSize > 0 && int64(len(marshalledData)) > s.cfg.SnapshotMaxSize {
+		return ErrSnapshotTooLarge
+	}
+
+	content, err := encodeBlob(ctx, s.SecretsService, marshalledData)
 	if err != nil {
 		return err
 	}
 
-	cmd.DashboardEncrypted = encryptedDashboard
+	// checked again when the snapshot is saved, this avoids storing snapshots over the quota
+	hasQuota := s.cfg.SnapshotOrgQuota >= 0
+	if hasQuota {
+		query := models.GetDashboardSnapshotsSizeQuery{OrgId: cmd.OrgId}
+		if err := s.SQLStore.GetDashboardSnapshotsSize(ctx, &query); err != nil {
+			return err
+		}
+		if query.Result+int64(len(content)) > s.cfg.SnapshotOrgQuota {
+			return ErrSnapshotQuotaReached
+		}
+	}
+
+	key, err := s.storeBlob(ctx, cmd.OrgId, content)
+	if err != nil {
+		return err
+	}
 
-	return s.SQLStore.CreateDashboardSnapshot(ctx, cmd)
+	cmd.BlobKey = key
+	cmd.BlobSize = int64(len(content))
+	if hasQuota {
+		quota := s.cfg.SnapshotOrgQuota
+		cmd.MaxOrgSize = &quota
+	}
+	if err := s.SQLStore.CreateDashboardSnapshot(ctx, cmd); err != nil {
+		s.deleteBlob(ctx, key)
+		return err
+	}
+	return nil
 }
 
 func (s *Service) GetDashboardSnapshot(ctx context.Context, query *models.GetDashboardSnapshotQuery) error {
@@ -45,25 +167,72 @@ func (s *Service) GetDashboardSnapshot(ctx context.Context, query *models.GetDas
 		return err
 	}
 
-	if query.Result.DashboardEncrypted != nil {
-		decryptedDashboard, err := s.SecretsService.Decrypt(ctx, query.Result.DashboardEncrypted)
+	if query.Result.BlobKey == "" {
+		dashboard, err := s.legacyDashboard(ctx, query.Result)
 		if err != nil {
 			return err
 		}
+		query.Result.Dashboard = dashboard
+		return nil
+	}
 
-		dashboard, err := simplejson.NewJson(decryptedDashboard)
-		if err != nil {
-			return err
+	file, err := s.blobStorage.Get(ctx, query.Result.BlobKey)
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		return models.ErrDashboardSnapshotNotFound
+	}
+
+	marshalledData, err := decodeBlob(ctx, s.SecretsService, file.Contents)
+	if err != nil {
+		return err
+	}
+
+	dashboard, err := simplejson.NewJson(marshalledData)
+	if err != nil {
+		return err
+	}
+
+	query.Result.Dashboard = dashboard
+	return nil
+}
+
+// legacyDashboard returns the dashboard of a snapshot stored in the database, before snapshots were moved to the
+// snapshot storage.
+func (s *Service) legacyDashboard(ctx context.Context, snapshot *models.DashboardSnapshot) (*simplejson.Json, error) {
+	if snapshot.DashboardEncrypted == nil {
+		if snapshot.Dashboard == nil {
+			return simplejson.New(), nil
 		}
+		return snapshot.Dashboard, nil
+	}
 
-		query.Result.Dashboard = dashboard
+	decryptedDashboard, err := s.SecretsService.Decrypt(ctx, snapshot.DashboardEncrypted)
+	if err != nil {
+		return nil, err
 	}
 
-	return err
+	return simplejson.NewJson(decryptedDashboard)
 }
 
 func (s *Service) DeleteDashboardSnapshot(ctx context.Context, cmd *models.DeleteDashboardSnapshotCommand) error {
-	return s.SQLStore.DeleteDashboardSnapshot(ctx, cmd)
+	query := models.GetDashboardSnapshotQuery{DeleteKey: cmd.DeleteKey}
+	if err := s.SQLStore.GetDashboardSnapshot(ctx, &query); err != nil {
+		if errors.Is(err, models.ErrDashboardSnapshotNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if err := s.SQLStore.DeleteDashboardSnapshot(ctx, cmd); err != nil {
+		return err
+	}
+
+	if query.Result.BlobKey != "" {
+		s.deleteBlob(ctx, query.Result.BlobKey)
+	}
+	return nil
 }
 
 func (s *Service) SearchDashboardSnapshots(ctx context.Context, query *models.GetDashboardSnapshotsQuery) error {
@@ -71,5 +240,29 @@ func (s *Service) SearchDashboardSnapshots(ctx context.Context, query *models.Ge
 }
 
 func (s *Service) DeleteExpiredSnapshots(ctx context.Context, cmd *models.DeleteExpiredSnapshotsCommand) error {
-	return s.SQLStore.DeleteExpiredSnapshots(ctx, cmd)
+	if err := s.SQLStore.DeleteExpiredSnapshots(ctx, cmd); err != nil {
+		return err
+	}
+
+	for _, key := range cmd.BlobKeys {
+		s.deleteBlob(ctx, key)
+	}
+	return nil
+}
+
+// storeBlob stores the compressed and encrypted dashboard of a snapshot, and returns its key.
+func (s *Service) storeBlob(ctx context.Context, orgID int64, content []byte) (string, error) {
+	key := newBlobKey(orgID)
+	if err := s.blobStorage.Upsert(ctx, &filestorage.UpsertFileCommand{Path: key, Contents: content}); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// deleteBlob removes a snapshot from the snapshot storage. The snapshot is not in the database anymore, so a failure
+// only leaves an unreachable file behind.
+func (s *Service) deleteBlob(ctx context.Context, key string) {
+	if err := s.blobStorage.Delete(ctx, key); err != nil {
+		s.log.Warn("Failed to delete snapshot from the snapshot storage", "key", key, "error", err)
+	}
 }
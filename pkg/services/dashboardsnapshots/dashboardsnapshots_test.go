@@ -3,25 +3,35 @@ package dashboardsnapshots
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana/pkg/services/secrets/database"
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/secrets"
 	secretsManager "github.com/grafana/grafana/pkg/services/secrets/manager"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/stretchr/testify/require"
 )
 
-func TestDashboardSnapshotsService(t *testing.T) {
+func setupTestService(t *testing.T, cfg *setting.Cfg) (*Service, *sqlstore.SQLStore) {
+	t.Helper()
+
 	sqlStore := sqlstore.InitTestDB(t)
 	secretsService := secretsManager.SetupTestService(t, database.ProvideSecretsStore(sqlStore))
 
-	s := &Service{
-		SQLStore:       sqlStore,
-		SecretsService: secretsService,
-	}
+	cfg.SnapshotStorageURL = "mem://"
+	s, err := ProvideService(sqlStore, secretsService, cfg, serverlock.ProvideService(sqlStore))
+	require.NoError(t, err)
+
+	return s, sqlStore
+}
+
+func TestDashboardSnapshotsService(t *testing.T) {
+	s, _ := setupTestService(t, &setting.Cfg{SnapshotOrgQuota: -1})
 
 	origSecret := setting.SecretKey
 	setting.SecretKey = "dashboard_snapshot_service_test"
@@ -35,22 +45,30 @@ func TestDashboardSnapshotsService(t *testing.T) {
 	dashboard, err := simplejson.NewJson(rawDashboard)
 	require.NoError(t, err)
 
-	t.Run("create dashboard snapshot should encrypt the dashboard", func(t *testing.T) {
+	t.Run("create dashboard snapshot should store the dashboard compressed and encrypted", func(t *testing.T) {
 		ctx := context.Background()
 
 		cmd := models.CreateDashboardSnapshotCommand{
 			Key:       dashboardKey,
 			DeleteKey: dashboardKey,
+			OrgId:     1,
 			Dashboard: dashboard,
 		}
 
 		err = s.CreateDashboardSnapshot(ctx, &cmd)
 		require.NoError(t, err)
 
-		decrypted, err := s.SecretsService.Decrypt(ctx, cmd.Result.DashboardEncrypted)
+		require.NotEmpty(t, cmd.Result.BlobKey)
+		require.Nil(t, cmd.Result.DashboardEncrypted)
+
+		file, err := s.blobStorage.Get(ctx, cmd.Result.BlobKey)
 		require.NoError(t, err)
+		require.NotNil(t, file)
+		require.Equal(t, cmd.Result.BlobSize, int64(len(file.Contents)))
 
-		require.Equal(t, rawDashboard, decrypted)
+		decoded, err := decodeBlob(ctx, s.SecretsService, file.Contents)
+		require.NoError(t, err)
+		require.Equal(t, rawDashboard, decoded)
 	})
 
 	t.Run("get dashboard snapshot should return the dashboard decrypted", func(t *testing.T) {
@@ -69,4 +87,117 @@ func TestDashboardSnapshotsService(t *testing.T) {
 
 		require.Equal(t, rawDashboard, decrypted)
 	})
+
+	t.Run("delete dashboard snapshot should delete the stored dashboard", func(t *testing.T) {
+		ctx := context.Background()
+
+		query := models.GetDashboardSnapshotQuery{Key: dashboardKey}
+		require.NoError(t, s.GetDashboardSnapshot(ctx, &query))
+
+		err := s.DeleteDashboardSnapshot(ctx, &models.DeleteDashboardSnapshotCommand{DeleteKey: dashboardKey})
+		require.NoError(t, err)
+
+		file, err := s.blobStorage.Get(ctx, query.Result.BlobKey)
+		require.NoError(t, err)
+		require.Nil(t, file)
+	})
+}
+
+func TestDashboardSnapshotsLimits(t *testing.T) {
+	ctx := context.Background()
+	dashboard := simplejson.NewFromAny(map[string]interface{}{"title": "A dashboard with some data"})
+
+	t.Run("snapshots larger than the maximum size are rejected", func(t *testing.T) {
+		s, _ := setupTestService(t, &setting.Cfg{SnapshotMaxSize: 10, SnapshotOrgQuota: -1})
+
+		err := s.CreateDashboardSnapshot(ctx, &models.CreateDashboardSnapshotCommand{
+			Key: "a", DeleteKey: "a", OrgId: 1, Dashboard: dashboard,
+		})
+		require.ErrorIs(t, err, ErrSnapshotTooLarge)
+	})
+
+	t.Run("snapshots are rejected once the quota of the organization is used up", func(t *testing.T) {
+		s, _ := setupTestService(t, &setting.Cfg{SnapshotOrgQuota: 1})
+
+		err := s.CreateDashboardSnapshot(ctx, &models.CreateDashboardSnapshotCommand{
+			Key: "a", DeleteKey: "a", OrgId: 1, Dashboard: dashboard,
+		})
+		require.ErrorIs(t, err, ErrSnapshotQuotaReached)
+
+		s.cfg.SnapshotOrgQuota = -1
+		err = s.CreateDashboardSnapshot(ctx, &models.CreateDashboardSnapshotCommand{
+			Key: "b", DeleteKey: "b", OrgId: 1, Dashboard: dashboard,
+		})
+		require.NoError(t, err)
+
+		query := models.GetDashboardSnapshotsSizeQuery{OrgId: 1}
+		require.NoError(t, s.SQLStore.GetDashboardSnapshotsSize(ctx, &query))
+		require.Greater(t, query.Result, int64(0))
+
+		s.cfg.SnapshotOrgQuota = query.Result
+		err = s.CreateDashboardSnapshot(ctx, &models.CreateDashboardSnapshotCommand{
+			Key: "c", DeleteKey: "c", OrgId: 2, Dashboard: dashboard,
+		})
+		require.NoError(t, err)
+		err = s.CreateDashboardSnapshot(ctx, &models.CreateDashboardSnapshotCommand{
+			Key: "d", DeleteKey: "d", OrgId: 1, Dashboard: dashboard,
+		})
+		require.ErrorIs(t, err, ErrSnapshotQuotaReached)
+	})
+}
+
+func TestDashboardSnapshotsMigration(t *testing.T) {
+	ctx := context.Background()
+	s, sqlStore := setupTestService(t, &setting.Cfg{SnapshotOrgQuota: -1})
+
+	rawDashboard := []byte(`{"title":"Legacy"}`)
+	encrypted, err := s.SecretsService.Encrypt(ctx, rawDashboard, secrets.WithoutScope())
+	require.NoError(t, err)
+
+	// snapshots saved before the snapshot storage
+	err = sqlStore.CreateDashboardSnapshot(ctx, &models.CreateDashboardSnapshotCommand{
+		Key: "encrypted", DeleteKey: "encrypted", OrgId: 1, DashboardEncrypted: encrypted,
+	})
+	require.NoError(t, err)
+	err = sqlStore.CreateDashboardSnapshot(ctx, &models.CreateDashboardSnapshotCommand{
+		Key: "expired", DeleteKey: "expired", OrgId: 1, DashboardEncrypted: encrypted,
+	})
+	require.NoError(t, err)
+	err = sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Exec("UPDATE dashboard_snapshot SET expires = ? WHERE delete_key = ?", time.Now().Add(-time.Hour), "expired")
+		return err
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, s.Run(ctx))
+
+	pending := models.GetDashboardSnapshotsWithoutBlobQuery{Limit: 10}
+	require.NoError(t, sqlStore.GetDashboardSnapshotsWithoutBlob(ctx, &pending))
+	require.Empty(t, pending.Result)
+
+	query := models.GetDashboardSnapshotQuery{Key: "encrypted"}
+	require.NoError(t, s.GetDashboardSnapshot(ctx, &query))
+	require.NotEmpty(t, query.Result.BlobKey)
+	require.Nil(t, query.Result.DashboardEncrypted)
+	require.Equal(t, "Legacy", query.Result.Dashboard.Get("title").MustString())
+
+	t.Run("expired snapshots are deleted from the snapshot storage", func(t *testing.T) {
+		expired := models.GetDashboardSnapshotQuery{Key: "expired"}
+		require.NoError(t, sqlStore.GetDashboardSnapshot(ctx, &expired))
+
+		origRemoveExpired := setting.SnapShotRemoveExpired
+		setting.SnapShotRemoveExpired = true
+		t.Cleanup(func() {
+			setting.SnapShotRemoveExpired = origRemoveExpired
+		})
+
+		cmd := models.DeleteExpiredSnapshotsCommand{}
+		require.NoError(t, s.DeleteExpiredSnapshots(ctx, &cmd))
+		require.Equal(t, int64(1), cmd.DeletedRows)
+		require.Equal(t, []string{expired.Result.BlobKey}, cmd.BlobKeys)
+
+		file, err := s.blobStorage.Get(ctx, expired.Result.BlobKey)
+		require.NoError(t, err)
+		require.Nil(t, file)
+	})
 }
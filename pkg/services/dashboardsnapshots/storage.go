@@ -0,0 +1,76 @@
+package dashboardsnapshots
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"gocloud.dev/blob"
+	"gocloud.dev/blob/fileblob"
+
+	// registers the gs:// bucket URLs
+	_ "gocloud.dev/blob/gcsblob"
+	// registers the s3:// bucket URLs
+	_ "gocloud.dev/blob/s3blob"
+
+	"github.com/grafana/grafana/pkg/infra/filestorage"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// openBlobStorage opens the bucket configured for snapshots, or the snapshots folder of the data path.
+func openBlobStorage(ctx context.Context, cfg *setting.Cfg, logger log.Logger) (filestorage.FileStorage, error) {
+	var bucket *blob.Bucket
+	var err error
+	if cfg.SnapshotStorageURL == "" {
+		bucket, err = fileblob.OpenBucket(filepath.Join(cfg.DataPath, "snapshots"), &fileblob.Options{CreateDir: true})
+	} else {
+		bucket, err = blob.OpenBucket(ctx, cfg.SnapshotStorageURL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot storage: %w", err)
+	}
+
+	return filestorage.NewCdkBlobStorage(logger, bucket, "", nil), nil
+}
+
+func newBlobKey(orgID int64) string {
+	return filestorage.Join(fmt.Sprintf("%d", orgID), uuid.NewString())
+}
+
+// encodeBlob compresses and encrypts the dashboard of a snapshot.
+func encodeBlob(ctx context.Context, secretsService secrets.Service, dashboard []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(dashboard); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return secretsService.Encrypt(ctx, buf.Bytes(), secrets.WithoutScope())
+}
+
+// decodeBlob decrypts and decompresses the dashboard of a snapshot.
+func decodeBlob(ctx context.Context, secretsService secrets.Service, content []byte) ([]byte, error) {
+	compressed, err := secretsService.Decrypt(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = zr.Close()
+	}()
+
+	return io.ReadAll(zr)
+}
@@ -1,11 +1,15 @@
 package datasourceproxy
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/grafana/grafana/pkg/api/datasource"
 	"github.com/grafana/grafana/pkg/api/pluginproxy"
@@ -15,7 +19,10 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement"
+	"github.com/grafana/grafana/pkg/services/datasources/ratelimit"
 	"github.com/grafana/grafana/pkg/services/oauthtoken"
+	"github.com/grafana/grafana/pkg/services/queryaudit"
 	"github.com/grafana/grafana/pkg/services/secrets"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util"
@@ -25,30 +32,37 @@ import (
 func ProvideService(dataSourceCache datasources.CacheService, plugReqValidator models.PluginRequestValidator,
 	pluginStore plugins.Store, cfg *setting.Cfg, httpClientProvider httpclient.Provider,
 	oauthTokenService *oauthtoken.Service, dsService datasources.DataSourceService,
-	tracer tracing.Tracer, secretsService secrets.Service) *DataSourceProxyService {
+	tracer tracing.Tracer, secretsService secrets.Service, dataSourceLimiter ratelimit.Service,
+	queryAuditService queryaudit.Service, labelEnforcementService labelenforcement.Service) *DataSourceProxyService {
 	return &DataSourceProxyService{
-		DataSourceCache:        dataSourceCache,
-		PluginRequestValidator: plugReqValidator,
-		pluginStore:            pluginStore,
-		Cfg:                    cfg,
-		HTTPClientProvider:     httpClientProvider,
-		OAuthTokenService:      oauthTokenService,
-		DataSourcesService:     dsService,
-		tracer:                 tracer,
-		secretsService:         secretsService,
+		DataSourceCache:         dataSourceCache,
+		PluginRequestValidator:  plugReqValidator,
+		pluginStore:             pluginStore,
+		Cfg:                     cfg,
+		HTTPClientProvider:      httpClientProvider,
+		OAuthTokenService:       oauthTokenService,
+		DataSourcesService:      dsService,
+		tracer:                  tracer,
+		secretsService:          secretsService,
+		dataSourceLimiter:       dataSourceLimiter,
+		queryAuditService:       queryAuditService,
+		labelEnforcementService: labelEnforcementService,
 	}
 }
 
 type DataSourceProxyService struct {
-	DataSourceCache        datasources.CacheService
-	PluginRequestValidator models.PluginRequestValidator
-	pluginStore            plugins.Store
-	Cfg                    *setting.Cfg
-	HTTPClientProvider     httpclient.Provider
-	OAuthTokenService      *oauthtoken.Service
-	DataSourcesService     datasources.DataSourceService
-	tracer                 tracing.Tracer
-	secretsService         secrets.Service
+	DataSourceCache         datasources.CacheService
+	PluginRequestValidator  models.PluginRequestValidator
+	pluginStore             plugins.Store
+	Cfg                     *setting.Cfg
+	HTTPClientProvider      httpclient.Provider
+	OAuthTokenService       *oauthtoken.Service
+	DataSourcesService      datasources.DataSourceService
+	tracer                  tracing.Tracer
+	secretsService          secrets.Service
+	dataSourceLimiter       ratelimit.Service
+	queryAuditService       queryaudit.Service
+	labelEnforcementService labelenforcement.Service
 }
 
 func (p *DataSourceProxyService) ProxyDataSourceRequest(c *models.ReqContext) {
@@ -109,6 +123,17 @@ func (p *DataSourceProxyService) proxyDatasourceRequest(c *models.ReqContext, ds
 		return
 	}
 
+	// the label rules are only enforced on queries, so the users they restrict cannot reach the datasource directly
+	matchers, err := p.labelEnforcementService.GetMatchers(c.Req.Context(), c.SignedInUser, ds)
+	if err != nil {
+		c.JsonApiErr(http.StatusInternalServerError, "Failed to get label rules", err)
+		return
+	}
+	if len(matchers) > 0 {
+		c.JsonApiErr(http.StatusForbidden, "Data source proxy is not available to users restricted by label rules", nil)
+		return
+	}
+
 	// find plugin
 	plugin, exists := p.pluginStore.Plugin(c.Req.Context(), ds.Type)
 	if !exists {
@@ -127,7 +152,74 @@ func (p *DataSourceProxyService) proxyDatasourceRequest(c *models.ReqContext, ds
 		}
 		return
 	}
+
+	release, err := p.dataSourceLimiter.Acquire(c.Req.Context(), ds, c.SignedInUser)
+	if err != nil {
+		var limitErr *ratelimit.LimitExceededError
+		if errors.As(err, &limitErr) {
+			c.Resp.Header().Set("Retry-After", strconv.Itoa(limitErr.RetryAfterSeconds()))
+			c.JsonApiErr(http.StatusTooManyRequests, "Data source limit reached", err)
+			return
+		}
+		c.JsonApiErr(http.StatusServiceUnavailable, "Failed waiting for data source limits", err)
+		return
+	}
+	defer release()
+
+	if !p.queryAuditService.Enabled() {
+		proxy.HandleRequest()
+		return
+	}
+
+	entry := queryaudit.NewEntry(queryaudit.SourceProxy, c.SignedInUser, ds)
+	entry.SetRequestInfo(c.Req)
+	entry.Query = proxyAuditQuery(c, proxyPath, p.Cfg.QueryAudit)
+
+	start := time.Now()
 	proxy.HandleRequest()
+
+	entry.DurationMs = time.Since(start).Milliseconds()
+	entry.BytesReturned = int64(c.Resp.Size())
+	if status := c.Resp.Status(); status >= http.StatusBadRequest {
+		entry.Error = fmt.Sprintf("data source proxy request failed with status %d", status)
+	}
+	p.queryAuditService.Record(entry)
+}
+
+// maxAuditBodyLength is the most of the proxied request body read for the query
+// audit log when the length of the query text is not limited.
+const maxAuditBodyLength = 1 << 20
+
+// proxyAuditQuery returns the method, path and body of the proxied request
+// as recorded in the query audit log. Only the part of the body that fits in
+// the query text is read, and the request keeps the whole body for the proxy.
+func proxyAuditQuery(c *models.ReqContext, proxyPath string, settings setting.QueryAuditSettings) string {
+	query := c.Req.Method + " " + proxyPath
+	if c.Req.URL.RawQuery != "" {
+		query += "?" + c.Req.URL.RawQuery
+	}
+
+	if c.Req.Body == nil || !settings.LogQueryText {
+		return query
+	}
+
+	maxLength := int64(maxAuditBodyLength)
+	if settings.MaxQueryTextLength > 0 {
+		maxLength = int64(settings.MaxQueryTextLength)
+	}
+	body := c.Req.Body
+	prefix, err := ioutil.ReadAll(io.LimitReader(body, maxLength+1))
+	c.Req.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(prefix), body), Closer: body}
+	if err == nil && len(prefix) > 0 {
+		query += "\n" + string(prefix)
+	}
+
+	return query
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
 }
 
 var proxyPathRegexp = regexp.MustCompile(`^\/api\/datasources\/proxy\/([\d]+|uid\/[\w]+)\/?`)
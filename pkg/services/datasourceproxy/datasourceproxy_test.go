@@ -1,9 +1,17 @@
 package datasourceproxy
 
 import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/web"
 )
 
 func TestDataProxy(t *testing.T) {
@@ -44,3 +52,33 @@ func TestDataProxy(t *testing.T) {
 		}
 	})
 }
+
+func TestProxyAuditQuery(t *testing.T) {
+	newContext := func(body string) *models.ReqContext {
+		req := httptest.NewRequest("POST", "/api/datasources/proxy/uid/abc/api/v1/query?time=1", strings.NewReader(body))
+		return &models.ReqContext{Context: &web.Context{Req: req}}
+	}
+
+	t.Run("reads the body up to the length of the query text, and keeps it for the proxy", func(t *testing.T) {
+		body := "query=" + strings.Repeat("a", 100)
+		c := newContext(body)
+
+		query := proxyAuditQuery(c, "api/v1/query", setting.QueryAuditSettings{LogQueryText: true, MaxQueryTextLength: 10})
+		require.Equal(t, "POST api/v1/query?time=1\n"+body[:11], query)
+
+		proxied, err := ioutil.ReadAll(c.Req.Body)
+		require.NoError(t, err)
+		require.Equal(t, body, string(proxied))
+	})
+
+	t.Run("does not read the body when the query text is not logged", func(t *testing.T) {
+		c := newContext("query=up")
+
+		query := proxyAuditQuery(c, "api/v1/query", setting.QueryAuditSettings{LogQueryText: false})
+		require.Equal(t, "POST api/v1/query?time=1", query)
+
+		proxied, err := ioutil.ReadAll(c.Req.Body)
+		require.NoError(t, err)
+		require.Equal(t, "query=up", string(proxied))
+	})
+}
@@ -0,0 +1,83 @@
+package labelenforcement
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func (s *LabelEnforcementService) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(s.accessControl)
+	uidScope := datasources.ScopeProvider.GetResourceScopeUID(accesscontrol.Parameter(":uid"))
+
+	s.routeRegister.Group("/api/datasources/uid/:uid/label-rules", func(rulesRoute routing.RouteRegister) {
+		rulesRoute.Get("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(datasources.ActionPermissionsRead, uidScope)), routing.Wrap(s.getRulesHandler))
+		rulesRoute.Post("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(datasources.ActionPermissionsWrite, uidScope)), routing.Wrap(s.addRuleHandler))
+		rulesRoute.Delete("/:ruleId", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(datasources.ActionPermissionsWrite, uidScope)), routing.Wrap(s.removeRuleHandler))
+	})
+}
+
+// GET /api/datasources/uid/:uid/label-rules
+func (s *LabelEnforcementService) getRulesHandler(c *models.ReqContext) response.Response {
+	rules, err := s.getRules(c.Req.Context(), c.OrgId, web.Params(c.Req)[":uid"])
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get label rules", err)
+	}
+	return response.JSON(http.StatusOK, rules)
+}
+
+// POST /api/datasources/uid/:uid/label-rules
+func (s *LabelEnforcementService) addRuleHandler(c *models.ReqContext) response.Response {
+	cmd := AddRuleCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if !model.LabelName(cmd.LabelName).IsValid() {
+		return response.Error(http.StatusBadRequest, "Label name is invalid", ErrInvalidLabelName)
+	}
+	if cmd.LabelValue == "" {
+		return response.Error(http.StatusBadRequest, "Label value is required", ErrEmptyLabelValue)
+	}
+
+	rule, err := s.addRule(c.Req.Context(), c.OrgId, web.Params(c.Req)[":uid"], &cmd)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrDataSourceNotFound):
+			return response.Error(http.StatusNotFound, "Data source not found", nil)
+		case errors.Is(err, models.ErrTeamNotFound):
+			return response.Error(http.StatusNotFound, "Team not found", nil)
+		case errors.Is(err, ErrUnsupportedDatasource):
+			return response.Error(http.StatusBadRequest, "Label rules are only supported by Prometheus and Loki data sources", nil)
+		case errors.Is(err, ErrRuleAlreadyAdded):
+			return response.Error(http.StatusBadRequest, "Label rule is already added to this team", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to add label rule", err)
+	}
+	return response.JSON(http.StatusOK, rule)
+}
+
+// DELETE /api/datasources/uid/:uid/label-rules/:ruleId
+func (s *LabelEnforcementService) removeRuleHandler(c *models.ReqContext) response.Response {
+	ruleID, err := strconv.ParseInt(web.Params(c.Req)[":ruleId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "ruleId is invalid", err)
+	}
+
+	if err := s.removeRule(c.Req.Context(), c.OrgId, web.Params(c.Req)[":uid"], ruleID); err != nil {
+		if errors.Is(err, ErrRuleNotFound) {
+			return response.Error(http.StatusNotFound, "Label rule not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to remove label rule", err)
+	}
+	return response.Success("Label rule removed")
+}
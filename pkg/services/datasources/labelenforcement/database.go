@@ -0,0 +1,117 @@
+package labelenforcement
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func (Rule) TableName() string {
+	return "datasource_label_rule"
+}
+
+func (s *LabelEnforcementService) getRules(ctx context.Context, orgID int64, datasourceUID string) ([]*RuleDTO, error) {
+	rules := make([]*Rule, 0)
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("org_id = ? AND datasource_uid = ?", orgID, datasourceUID).Asc("team_id", "label_name", "label_value").Find(&rules)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*RuleDTO, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, &RuleDTO{
+			ID:            rule.ID,
+			DatasourceUID: rule.DatasourceUID,
+			TeamID:        rule.TeamID,
+			LabelName:     rule.LabelName,
+			LabelValue:    rule.LabelValue,
+		})
+	}
+	return result, nil
+}
+
+// getUserRules returns the rules of the datasource of the teams the user is a member of
+func (s *LabelEnforcementService) getUserRules(ctx context.Context, orgID int64, datasourceUID string, userID int64) ([]*Rule, error) {
+	rules := make([]*Rule, 0)
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		rawSQL := `SELECT datasource_label_rule.* FROM datasource_label_rule
+			INNER JOIN team_member ON team_member.team_id = datasource_label_rule.team_id
+			WHERE datasource_label_rule.org_id = ? AND datasource_label_rule.datasource_uid = ? AND team_member.user_id = ?`
+		return sess.SQL(rawSQL, orgID, datasourceUID, userID).Find(&rules)
+	})
+	return rules, err
+}
+
+func (s *LabelEnforcementService) addRule(ctx context.Context, orgID int64, datasourceUID string, cmd *AddRuleCommand) (*RuleDTO, error) {
+	rule := &Rule{
+		OrgID:         orgID,
+		DatasourceUID: datasourceUID,
+		TeamID:        cmd.TeamID,
+		LabelName:     cmd.LabelName,
+		LabelValue:    cmd.LabelValue,
+	}
+
+	err := s.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		ds := &models.DataSource{}
+		exists, err := sess.Where("org_id = ? AND uid = ?", orgID, datasourceUID).Get(ds)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return models.ErrDataSourceNotFound
+		}
+		if !Supported(ds.Type) {
+			return ErrUnsupportedDatasource
+		}
+
+		exists, err = sess.Where("org_id = ? AND id = ?", orgID, cmd.TeamID).Exist(&models.Team{})
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return models.ErrTeamNotFound
+		}
+
+		exists, err = sess.Where("org_id = ? AND datasource_uid = ? AND team_id = ? AND label_name = ? AND label_value = ?",
+			orgID, datasourceUID, cmd.TeamID, cmd.LabelName, cmd.LabelValue).Exist(&Rule{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrRuleAlreadyAdded
+		}
+
+		rule.Created = time.Now()
+		rule.Updated = rule.Created
+		_, err = sess.Insert(rule)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &RuleDTO{
+		ID:            rule.ID,
+		DatasourceUID: rule.DatasourceUID,
+		TeamID:        rule.TeamID,
+		LabelName:     rule.LabelName,
+		LabelValue:    rule.LabelValue,
+	}, nil
+}
+
+func (s *LabelEnforcementService) removeRule(ctx context.Context, orgID int64, datasourceUID string, ruleID int64) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		affected, err := sess.Where("org_id = ? AND datasource_uid = ? AND id = ?", orgID, datasourceUID, ruleID).Delete(&Rule{})
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrRuleNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,289 @@
+package labelenforcement
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// variablePattern matches the interval and range variables, which are only replaced by the datasource backends.
+// The longer names come first, as the leftmost alternative wins.
+var variablePattern = regexp.MustCompile(`\$__(interval_ms|range_ms|range_s|rate_interval|interval|range)|\$\{__(interval_ms|range_ms|range_s|rate_interval|interval|range)\}`)
+
+// Supported returns true if the label rules are enforced on the queries of the datasource type
+func Supported(dsType string) bool {
+	return dsType == models.DS_PROMETHEUS || dsType == models.DS_LOKI
+}
+
+// EnforceQuery injects the matchers into every selector of the expression of the query model.
+// The query is rejected if its expression cannot be parsed, so that no selector is left unrestricted.
+func EnforceQuery(dsType string, queryJSON json.RawMessage, matchers []*labels.Matcher) (json.RawMessage, error) {
+	if len(matchers) == 0 || !Supported(dsType) {
+		return queryJSON, nil
+	}
+
+	model := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(queryJSON))
+	decoder.UseNumber()
+	if err := decoder.Decode(&model); err != nil {
+		return nil, err
+	}
+
+	expr, _ := model["expr"].(string)
+	if strings.TrimSpace(expr) == "" {
+		return queryJSON, nil
+	}
+
+	var err error
+	switch dsType {
+	case models.DS_PROMETHEUS:
+		expr, err = EnforcePromQL(expr, matchers)
+	case models.DS_LOKI:
+		expr, err = EnforceLogQL(expr, matchers)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	model["expr"] = expr
+	return json.Marshal(model)
+}
+
+// EnforcePromQL injects the matchers into every vector selector of the PromQL expression
+func EnforcePromQL(expr string, matchers []*labels.Matcher) (string, error) {
+	// the variables are replaced by placeholders of the same length, so that the positions of the selectors are
+	// the same in the expression and in the parsed one
+	parseable := replaceVariables(expr)
+	starts, err := promQLSelectorStarts(parseable)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse PromQL query: %w", err)
+	}
+
+	// the rewritten expression is parsed again, to make sure every selector got the matchers
+	parsed, err := parser.ParseExpr(injectMatchers(parseable, starts, matchers))
+	if err != nil {
+		return "", fmt.Errorf("failed to inject label matchers into PromQL query: %w", err)
+	}
+	for _, selector := range parser.ExtractSelectors(parsed) {
+		if !containsMatchers(selector, matchers) {
+			return "", fmt.Errorf("failed to inject label matchers into PromQL query")
+		}
+	}
+
+	return injectMatchers(expr, starts, matchers), nil
+}
+
+// EnforceLogQL injects the matchers into every stream selector of the LogQL expression.
+// Loki's own parser pulls in its whole query engine, so the stream selectors are found by skipping the string
+// literals, which are the only other place braces can appear in LogQL, and their matchers are parsed by the
+// PromQL parser, as LogQL shares their syntax.
+func EnforceLogQL(expr string, matchers []*labels.Matcher) (string, error) {
+	starts := []int{}
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '"', '`':
+			end := stringEnd(expr, i)
+			if end < 0 {
+				return "", fmt.Errorf("failed to parse LogQL query: unterminated string")
+			}
+			i = end
+		case '#':
+			// comment until the end of the line
+			for i < len(expr) && expr[i] != '\n' {
+				i++
+			}
+		case '{':
+			end := selectorEnd(expr, i)
+			if end < 0 {
+				return "", fmt.Errorf("failed to parse LogQL query: unterminated stream selector")
+			}
+			if _, err := parser.ParseMetricSelector(expr[i : end+1]); err != nil {
+				return "", fmt.Errorf("failed to parse LogQL stream selector: %w", err)
+			}
+			starts = append(starts, i)
+			i = end
+		case '}':
+			return "", fmt.Errorf("failed to parse LogQL query: unexpected }")
+		}
+	}
+
+	if len(starts) == 0 {
+		return "", fmt.Errorf("failed to parse LogQL query: no stream selector")
+	}
+
+	return injectMatchers(expr, starts, matchers), nil
+}
+
+// Matchers returns the matchers the rules restrict the queries to. The values of the same label are ORed.
+func Matchers(rules []*Rule) ([]*labels.Matcher, error) {
+	values := map[string]map[string]bool{}
+	for _, rule := range rules {
+		if values[rule.LabelName] == nil {
+			values[rule.LabelName] = map[string]bool{}
+		}
+		values[rule.LabelName][rule.LabelValue] = true
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	matchers := make([]*labels.Matcher, 0, len(names))
+	for _, name := range names {
+		labelValues := make([]string, 0, len(values[name]))
+		for value := range values[name] {
+			labelValues = append(labelValues, value)
+		}
+		sort.Strings(labelValues)
+
+		var matcher *labels.Matcher
+		var err error
+		if len(labelValues) == 1 {
+			matcher, err = labels.NewMatcher(labels.MatchEqual, name, labelValues[0])
+		} else {
+			for i, value := range labelValues {
+				labelValues[i] = regexp.QuoteMeta(value)
+			}
+			matcher, err = labels.NewMatcher(labels.MatchRegexp, name, strings.Join(labelValues, "|"))
+		}
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+	return matchers, nil
+}
+
+func replaceVariables(expr string) string {
+	return variablePattern.ReplaceAllStringFunc(expr, func(variable string) string {
+		name := strings.Trim(variable, "${}")
+		if strings.HasSuffix(name, "_ms") || strings.HasSuffix(name, "_s") {
+			return strings.Repeat("0", len(variable)-1) + "1"
+		}
+		return strings.Repeat("0", len(variable)-2) + "1s"
+	})
+}
+
+func promQLSelectorStarts(expr string) ([]int, error) {
+	parsed, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	starts := []int{}
+	parser.Inspect(parsed, func(node parser.Node, _ []parser.Node) error {
+		if selector, ok := node.(*parser.VectorSelector); ok {
+			starts = append(starts, int(selector.PosRange.Start))
+		}
+		return nil
+	})
+	return starts, nil
+}
+
+// injectMatchers adds the matchers to the selectors starting at the positions, which either start with a metric
+// name or with the opening brace of their matchers
+func injectMatchers(expr string, starts []int, matchers []*labels.Matcher) string {
+	injected := make([]string, 0, len(matchers))
+	for _, matcher := range matchers {
+		injected = append(injected, matcher.String())
+	}
+	text := strings.Join(injected, ",")
+
+	sorted := append([]int{}, starts...)
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+
+	for _, start := range sorted {
+		pos := start
+		for pos < len(expr) && isMetricNameChar(expr[pos]) {
+			pos++
+		}
+		brace := pos
+		for brace < len(expr) && isSpace(expr[brace]) {
+			brace++
+		}
+
+		if brace >= len(expr) || expr[brace] != '{' {
+			expr = expr[:pos] + "{" + text + "}" + expr[pos:]
+			continue
+		}
+
+		next := brace + 1
+		for next < len(expr) && isSpace(expr[next]) {
+			next++
+		}
+		insert := text
+		if next < len(expr) && expr[next] != '}' {
+			insert += ","
+		}
+		expr = expr[:brace+1] + insert + expr[brace+1:]
+	}
+	return expr
+}
+
+func containsMatchers(selector []*labels.Matcher, matchers []*labels.Matcher) bool {
+	for _, matcher := range matchers {
+		found := false
+		for _, m := range selector {
+			if m.String() == matcher.String() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// stringEnd returns the position of the quote closing the string starting at the position, -1 if there is none
+func stringEnd(expr string, start int) int {
+	quote := expr[start]
+	for i := start + 1; i < len(expr); i++ {
+		if quote != '`' && expr[i] == '\\' {
+			i++
+			continue
+		}
+		if expr[i] == quote {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectorEnd returns the position of the brace closing the selector starting at the position, -1 if there is none
+func selectorEnd(expr string, start int) int {
+	for i := start + 1; i < len(expr); i++ {
+		switch expr[i] {
+		case '"', '`':
+			end := stringEnd(expr, i)
+			if end < 0 {
+				return -1
+			}
+			i = end
+		case '{':
+			return -1
+		case '}':
+			return i
+		}
+	}
+	return -1
+}
+
+func isMetricNameChar(c byte) bool {
+	return c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
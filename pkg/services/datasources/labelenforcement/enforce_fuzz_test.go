@@ -0,0 +1,27 @@
+//go:build go1.18
+// +build go1.18
+
+package labelenforcement
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// FuzzEnforceLogQL checks that every stream selector of the generated LogQL queries gets the matchers, whatever
+// braces, quotes and comments surround them. Run with go test -fuzz=FuzzEnforceLogQL.
+func FuzzEnforceLogQL(f *testing.F) {
+	for seed := int64(0); seed < 100; seed++ {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, seed int64) {
+		expr, expected := randomLogQL(rand.New(rand.NewSource(seed)))
+		rewritten, err := EnforceLogQL(expr, tenantMatchers)
+		if err != nil {
+			t.Fatalf("failed to enforce %q: %v", expr, err)
+		}
+		if rewritten != expected {
+			t.Fatalf("enforced %q into %q, expected %q", expr, rewritten, expected)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package labelenforcement
+
+import (
+	"encoding/json"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+var tenantMatchers = []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "tenant", "x")}
+
+func TestEnforcePromQL(t *testing.T) {
+	for _, tc := range []struct {
+		expr     string
+		expected string
+	}{
+		{expr: `up`, expected: `up{tenant="x"}`},
+		{expr: `up{job="api"}`, expected: `up{tenant="x",job="api"}`},
+		{expr: `{__name__="up"}`, expected: `{tenant="x",__name__="up"}`},
+		{expr: `up{}`, expected: `up{tenant="x"}`},
+		{expr: `up {job="api"}`, expected: `up {tenant="x",job="api"}`},
+		{expr: `up{tenant="y"}`, expected: `up{tenant="x",tenant="y"}`},
+		{
+			expr:     `sum by (job) (rate(http_requests_total{code=~"5.."}[5m] offset 1h)) / sum by (job) (rate(http_requests_total[5m]))`,
+			expected: `sum by (job) (rate(http_requests_total{tenant="x",code=~"5.."}[5m] offset 1h)) / sum by (job) (rate(http_requests_total{tenant="x"}[5m]))`,
+		},
+		{
+			expr:     `rate(http_requests_total[$__rate_interval]) * $__interval_ms / ${__range_s}`,
+			expected: `rate(http_requests_total{tenant="x"}[$__rate_interval]) * $__interval_ms / ${__range_s}`,
+		},
+		{expr: `max_over_time(up[1h:5m])`, expected: `max_over_time(up{tenant="x"}[1h:5m])`},
+		{expr: `label_replace(up, "a", "$1", "job", "(.*)")`, expected: `label_replace(up{tenant="x"}, "a", "$1", "job", "(.*)")`},
+		{expr: `vector(1)`, expected: `vector(1)`},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			rewritten, err := EnforcePromQL(tc.expr, tenantMatchers)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, rewritten)
+		})
+	}
+
+	t.Run("invalid queries are rejected", func(t *testing.T) {
+		for _, expr := range []string{`up{`, `rate(up[$custom])`, `sum(`} {
+			_, err := EnforcePromQL(expr, tenantMatchers)
+			require.Error(t, err, expr)
+		}
+	})
+}
+
+func TestEnforceLogQL(t *testing.T) {
+	for _, tc := range []struct {
+		expr     string
+		expected string
+	}{
+		{expr: `{app="api"}`, expected: `{tenant="x",app="api"}`},
+		{expr: `{app="api"} |= "{" | json | line_format "{{.msg}}"`, expected: `{tenant="x",app="api"} |= "{" | json | line_format "{{.msg}}"`},
+		{expr: "{app=\"api\"} |~ `}\"` | logfmt", expected: "{tenant=\"x\",app=\"api\"} |~ `}\"` | logfmt"},
+		{expr: `{app="a\"}"}`, expected: `{tenant="x",app="a\"}"}`},
+		{
+			expr:     `sum by (level) (count_over_time({app="api"} | json [$__interval])) / sum(count_over_time({app="api"}[$__interval]))`,
+			expected: `sum by (level) (count_over_time({tenant="x",app="api"} | json [$__interval])) / sum(count_over_time({tenant="x",app="api"}[$__interval]))`,
+		},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			rewritten, err := EnforceLogQL(tc.expr, tenantMatchers)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, rewritten)
+		})
+	}
+
+	t.Run("invalid queries are rejected", func(t *testing.T) {
+		for _, expr := range []string{`{app="api"`, `{app=}`, `app="api"}`, `{app="api"} |= "unterminated`, `count_over_time(1[5m])`} {
+			_, err := EnforceLogQL(expr, tenantMatchers)
+			require.Error(t, err, expr)
+		}
+	})
+}
+
+func TestEnforceQuery(t *testing.T) {
+	query := json.RawMessage(`{"refId":"A","expr":"up","intervalMs":15000,"maxDataPoints":1000}`)
+
+	rewritten, err := EnforceQuery(models.DS_PROMETHEUS, query, tenantMatchers)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"refId":"A","expr":"up{tenant=\"x\"}","intervalMs":15000,"maxDataPoints":1000}`, string(rewritten))
+
+	rewritten, err = EnforceQuery(models.DS_PROMETHEUS, query, nil)
+	require.NoError(t, err)
+	assert.Equal(t, query, rewritten)
+
+	rewritten, err = EnforceQuery("graphite", query, tenantMatchers)
+	require.NoError(t, err)
+	assert.Equal(t, query, rewritten)
+
+	_, err = EnforceQuery(models.DS_LOKI, json.RawMessage(`{"refId":"A","expr":"up"}`), tenantMatchers)
+	require.Error(t, err)
+}
+
+func TestMatchers(t *testing.T) {
+	matchers, err := Matchers([]*Rule{
+		{TeamID: 1, LabelName: "tenant", LabelValue: "x"},
+		{TeamID: 2, LabelName: "tenant", LabelValue: "y.z"},
+		{TeamID: 2, LabelName: "env", LabelValue: "prod"},
+		{TeamID: 3, LabelName: "tenant", LabelValue: "x"},
+	})
+	require.NoError(t, err)
+	require.Len(t, matchers, 2)
+	assert.Equal(t, `env="prod"`, matchers[0].String())
+	assert.Equal(t, `tenant=~"x|y\\.z"`, matchers[1].String())
+	assert.True(t, matchers[1].Matches("y.z"))
+	assert.False(t, matchers[1].Matches("yaz"))
+}
+
+func TestEnforceLogQLRandomQueries(t *testing.T) {
+	for seed := int64(0); seed < 2000; seed++ {
+		expr, expected := randomLogQL(rand.New(rand.NewSource(seed)))
+		rewritten, err := EnforceLogQL(expr, tenantMatchers)
+		require.NoError(t, err, expr)
+		require.Equal(t, expected, rewritten, expr)
+	}
+}
+
+// randomLogQL generates a LogQL query whose string literals and comments contain braces and quotes, and the query
+// every stream selector of which has the tenant matcher
+func randomLogQL(r *rand.Rand) (expr string, expected string) {
+	var b, e strings.Builder
+	write := func(s string) {
+		b.WriteString(s)
+		e.WriteString(s)
+	}
+
+	logExpr := func() {
+		write(pick(r, "", " ", "\n"))
+		selector := "{" + pick(r, "", " ")
+		for i := 0; i <= r.Intn(3); i++ {
+			if i > 0 {
+				selector += pick(r, ",", ", ")
+			}
+			op := pick(r, "=", "!=", "=~", "!~")
+			value := randomText(r)
+			if strings.HasSuffix(op, "~") {
+				value = regexp.QuoteMeta(value)
+			}
+			selector += pick(r, "app", "job", "tenant", "namespace") + op + quote(r, value)
+		}
+		selector += pick(r, "", " ") + "}"
+		b.WriteString(selector)
+		e.WriteString("{" + `tenant="x",` + selector[1:])
+
+		for i := 0; i < r.Intn(4); i++ {
+			switch r.Intn(6) {
+			case 0:
+				write(" " + pick(r, "|=", "!=", "|~", "!~") + " " + randomString(r))
+			case 1:
+				write(" | " + pick(r, "json", "logfmt", "unpack"))
+			case 2:
+				write(" | line_format " + randomString(r))
+			case 3:
+				write(" | label_format level=" + randomString(r))
+			case 4:
+				write(" | level=" + randomString(r))
+			case 5:
+				write(" # " + strings.ReplaceAll(randomText(r), "\n", "") + "\n")
+			}
+		}
+	}
+	var metricExpr func(depth int)
+	metricExpr = func(depth int) {
+		switch r.Intn(4) {
+		case 0:
+			write(pick(r, "count_over_time", "rate", "bytes_over_time") + "(")
+			logExpr()
+			write(pick(r, "[5m]", " [$__interval]") + ")")
+		case 1:
+			write(pick(r, "sum", "max") + pick(r, "", " by (level)") + " (")
+			metricExpr(depth + 1)
+			write(")")
+		default:
+			if depth > 3 {
+				write("vector(1)")
+				return
+			}
+			metricExpr(depth + 1)
+			write(pick(r, " / ", " or ", " + "))
+			metricExpr(depth + 1)
+		}
+	}
+
+	if r.Intn(2) == 0 {
+		logExpr()
+	} else {
+		metricExpr(0)
+		// queries without stream selectors are rejected
+		write(" + count_over_time(")
+		logExpr()
+		write("[5m])")
+	}
+	return b.String(), e.String()
+}
+
+// randomString returns a double quoted or a raw string literal
+func randomString(r *rand.Rand) string {
+	return quote(r, randomText(r))
+}
+
+func quote(r *rand.Rand, text string) string {
+	if r.Intn(2) == 0 {
+		return "`" + strings.ReplaceAll(text, "`", "") + "`"
+	}
+	return strconv.Quote(text)
+}
+
+func randomText(r *rand.Rand) string {
+	chars := []string{"a", "b", " ", "{", "}", `"`, "`", `\`, "#", "|", "=", "\n", "{{.msg}}", `{app="api"}`}
+	text := ""
+	for i := 0; i < r.Intn(8); i++ {
+		text += chars[r.Intn(len(chars))]
+	}
+	return text
+}
+
+func pick(r *rand.Rand, values ...string) string {
+	return values[r.Intn(len(values))]
+}
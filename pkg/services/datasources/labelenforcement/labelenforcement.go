@@ -0,0 +1,58 @@
+package labelenforcement
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// Service restricts the data the members of teams can query from shared Prometheus and Loki datasources, by
+// injecting the label matchers of the rules of their teams into every selector of their queries.
+type Service interface {
+	// GetMatchers returns the label matchers the queries of the user to the datasource are restricted to, none if
+	// the user is not restricted.
+	GetMatchers(ctx context.Context, user *models.SignedInUser, ds *models.DataSource) ([]*labels.Matcher, error)
+}
+
+type LabelEnforcementService struct {
+	sqlStore      *sqlstore.SQLStore
+	accessControl accesscontrol.AccessControl
+	routeRegister routing.RouteRegister
+	log           log.Logger
+}
+
+func ProvideService(sqlStore *sqlstore.SQLStore, accessControl accesscontrol.AccessControl,
+	routeRegister routing.RouteRegister) *LabelEnforcementService {
+	s := &LabelEnforcementService{
+		sqlStore:      sqlStore,
+		accessControl: accessControl,
+		routeRegister: routeRegister,
+		log:           log.New("datasource.labelenforcement"),
+	}
+
+	s.registerAPIEndpoints()
+
+	return s
+}
+
+func (s *LabelEnforcementService) GetMatchers(ctx context.Context, user *models.SignedInUser, ds *models.DataSource) ([]*labels.Matcher, error) {
+	if ds == nil || !Supported(ds.Type) || user == nil || user.UserId == 0 {
+		return nil, nil
+	}
+
+	rules, err := s.getUserRules(ctx, ds.OrgId, ds.Uid, user.UserId)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	return Matchers(rules)
+}
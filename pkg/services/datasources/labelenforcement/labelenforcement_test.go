@@ -0,0 +1,142 @@
+//go:build integration
+// +build integration
+
+package labelenforcement
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/models"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+const testOrgID = int64(1)
+
+type testEnv struct {
+	service  *LabelEnforcementService
+	sqlStore *sqlstore.SQLStore
+}
+
+func setupTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	sqlStore := sqlstore.InitTestDB(t)
+	service := ProvideService(sqlStore, accesscontrolmock.New(), routing.NewRouteRegister())
+	return &testEnv{service: service, sqlStore: sqlStore}
+}
+
+func (env *testEnv) createDatasource(t *testing.T, name, dsType string) *models.DataSource {
+	t.Helper()
+	cmd := &models.AddDataSourceCommand{OrgId: testOrgID, Name: name, Type: dsType, Access: models.DS_ACCESS_PROXY, Url: "http://localhost"}
+	require.NoError(t, env.sqlStore.AddDataSource(context.Background(), cmd))
+	return cmd.Result
+}
+
+func (env *testEnv) createTeam(t *testing.T, name string, members ...int64) int64 {
+	t.Helper()
+	team, err := env.sqlStore.CreateTeam(name, "", testOrgID)
+	require.NoError(t, err)
+	for _, userID := range members {
+		require.NoError(t, env.sqlStore.AddTeamMember(userID, testOrgID, team.Id, false, 0))
+	}
+	return team.Id
+}
+
+func (env *testEnv) createUser(t *testing.T, login string) *models.SignedInUser {
+	t.Helper()
+	user, err := env.sqlStore.CreateUser(context.Background(), models.CreateUserCommand{Login: login, OrgId: testOrgID})
+	require.NoError(t, err)
+	return &models.SignedInUser{UserId: user.Id, OrgId: testOrgID, OrgRole: models.ROLE_VIEWER}
+}
+
+func TestIntegrationLabelRules(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+	prometheus := env.createDatasource(t, "prometheus", models.DS_PROMETHEUS)
+	teamID := env.createTeam(t, "team-a")
+
+	t.Run("rules can be added, listed and removed", func(t *testing.T) {
+		rule, err := env.service.addRule(ctx, testOrgID, prometheus.Uid, &AddRuleCommand{TeamID: teamID, LabelName: "tenant", LabelValue: "a"})
+		require.NoError(t, err)
+
+		_, err = env.service.addRule(ctx, testOrgID, prometheus.Uid, &AddRuleCommand{TeamID: teamID, LabelName: "tenant", LabelValue: "a"})
+		require.ErrorIs(t, err, ErrRuleAlreadyAdded)
+
+		rules, err := env.service.getRules(ctx, testOrgID, prometheus.Uid)
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		assert.Equal(t, rule, rules[0])
+
+		require.NoError(t, env.service.removeRule(ctx, testOrgID, prometheus.Uid, rule.ID))
+		require.ErrorIs(t, env.service.removeRule(ctx, testOrgID, prometheus.Uid, rule.ID), ErrRuleNotFound)
+	})
+
+	t.Run("rules are rejected for unknown teams and unsupported datasources", func(t *testing.T) {
+		graphite := env.createDatasource(t, "graphite", models.DS_GRAPHITE)
+
+		_, err := env.service.addRule(ctx, testOrgID, graphite.Uid, &AddRuleCommand{TeamID: teamID, LabelName: "tenant", LabelValue: "a"})
+		require.ErrorIs(t, err, ErrUnsupportedDatasource)
+
+		_, err = env.service.addRule(ctx, testOrgID, "unknown", &AddRuleCommand{TeamID: teamID, LabelName: "tenant", LabelValue: "a"})
+		require.ErrorIs(t, err, models.ErrDataSourceNotFound)
+
+		_, err = env.service.addRule(ctx, testOrgID, prometheus.Uid, &AddRuleCommand{TeamID: teamID + 100, LabelName: "tenant", LabelValue: "a"})
+		require.ErrorIs(t, err, models.ErrTeamNotFound)
+	})
+}
+
+func TestIntegrationGetMatchers(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+	prometheus := env.createDatasource(t, "prometheus", models.DS_PROMETHEUS)
+	loki := env.createDatasource(t, "loki", models.DS_LOKI)
+
+	restricted := env.createUser(t, "restricted")
+	unrestricted := env.createUser(t, "unrestricted")
+	teamA := env.createTeam(t, "team-a", restricted.UserId)
+	teamB := env.createTeam(t, "team-b", restricted.UserId)
+	teamC := env.createTeam(t, "team-c", unrestricted.UserId)
+
+	for _, cmd := range []*AddRuleCommand{
+		{TeamID: teamA, LabelName: "tenant", LabelValue: "a"},
+		{TeamID: teamB, LabelName: "tenant", LabelValue: "b"},
+		{TeamID: teamB, LabelName: "env", LabelValue: "prod"},
+	} {
+		_, err := env.service.addRule(ctx, testOrgID, prometheus.Uid, cmd)
+		require.NoError(t, err)
+	}
+	_, err := env.service.addRule(ctx, testOrgID, loki.Uid, &AddRuleCommand{TeamID: teamC, LabelName: "tenant", LabelValue: "c"})
+	require.NoError(t, err)
+
+	t.Run("the rules of all the teams of the user are combined", func(t *testing.T) {
+		matchers, err := env.service.GetMatchers(ctx, restricted, prometheus)
+		require.NoError(t, err)
+		require.Len(t, matchers, 2)
+		assert.Equal(t, `env="prod"`, matchers[0].String())
+		assert.Equal(t, `tenant=~"a|b"`, matchers[1].String())
+	})
+
+	t.Run("users are only restricted on the datasources of the rules of their teams", func(t *testing.T) {
+		matchers, err := env.service.GetMatchers(ctx, restricted, loki)
+		require.NoError(t, err)
+		assert.Empty(t, matchers)
+
+		matchers, err = env.service.GetMatchers(ctx, unrestricted, prometheus)
+		require.NoError(t, err)
+		assert.Empty(t, matchers)
+	})
+
+	t.Run("the rules are removed with the team", func(t *testing.T) {
+		require.NoError(t, env.sqlStore.DeleteTeam(ctx, &models.DeleteTeamCommand{OrgId: testOrgID, Id: teamC}))
+
+		rules, err := env.service.getRules(ctx, testOrgID, loki.Uid)
+		require.NoError(t, err)
+		assert.Empty(t, rules)
+	})
+}
@@ -0,0 +1,22 @@
+package labelenforcementtest
+
+import (
+	"context"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+type FakeLabelEnforcementService struct {
+	ExpectedMatchers []*labels.Matcher
+	ExpectedError    error
+}
+
+func NewLabelEnforcementServiceFake() *FakeLabelEnforcementService {
+	return &FakeLabelEnforcementService{}
+}
+
+func (f *FakeLabelEnforcementService) GetMatchers(ctx context.Context, user *models.SignedInUser, ds *models.DataSource) ([]*labels.Matcher, error) {
+	return f.ExpectedMatchers, f.ExpectedError
+}
@@ -0,0 +1,42 @@
+package labelenforcement
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrRuleAlreadyAdded = errors.New("label rule is already added to this team")
+	ErrRuleNotFound     = errors.New("label rule not found")
+	ErrInvalidLabelName = errors.New("label name is invalid")
+	ErrEmptyLabelValue  = errors.New("label value is required")
+	// ErrUnsupportedDatasource is returned for the datasources whose queries cannot be rewritten
+	ErrUnsupportedDatasource = errors.New("label rules are only supported by Prometheus and Loki datasources")
+)
+
+// Rule restricts the queries of the members of a team to the series of a datasource with the label value.
+// Members of several teams with rules on the same label can query the values of all their teams.
+type Rule struct {
+	ID            int64  `xorm:"pk autoincr 'id'"`
+	OrgID         int64  `xorm:"org_id"`
+	DatasourceUID string `xorm:"datasource_uid"`
+	TeamID        int64  `xorm:"team_id"`
+	LabelName     string `xorm:"label_name"`
+	LabelValue    string `xorm:"label_value"`
+	Created       time.Time
+	Updated       time.Time
+}
+
+type RuleDTO struct {
+	ID            int64  `json:"id"`
+	DatasourceUID string `json:"datasourceUid"`
+	TeamID        int64  `json:"teamId"`
+	LabelName     string `json:"labelName"`
+	LabelValue    string `json:"labelValue"`
+}
+
+type AddRuleCommand struct {
+	TeamID     int64  `json:"teamId" binding:"Required"`
+	LabelName  string `json:"labelName" binding:"Required"`
+	LabelValue string `json:"labelValue"`
+}
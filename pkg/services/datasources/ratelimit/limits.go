@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// jsonData keys used to configure the limits of a datasource.
+const (
+	maxConcurrentQueriesKey       = "maxConcurrentQueries"
+	maxQueriesPerSecondPerUserKey = "maxQueriesPerSecondPerUser"
+	queryQueueSizeKey             = "queryQueueSize"
+	queryQueueTimeoutKey          = "queryQueueTimeout"
+)
+
+// Limits holds the request limits configured for a single datasource.
+// A zero value for any of the fields disables that particular limit.
+type Limits struct {
+	// MaxConcurrentQueries is the maximum number of requests that may be
+	// in flight against the datasource at the same time.
+	MaxConcurrentQueries int
+	// MaxQueriesPerSecondPerUser is the sustained number of requests per
+	// second a single user may send to the datasource.
+	MaxQueriesPerSecondPerUser float64
+	// QueueSize is the maximum number of requests waiting for a free
+	// concurrency slot. Zero means the queue is unbounded.
+	QueueSize int
+	// QueueTimeout is how long a request waits for a free concurrency slot
+	// before it is rejected. Zero disables queueing.
+	QueueTimeout time.Duration
+}
+
+// Enabled returns true if at least one limit is configured.
+func (l Limits) Enabled() bool {
+	return l.MaxConcurrentQueries > 0 || l.MaxQueriesPerSecondPerUser > 0
+}
+
+// userBurst is the number of requests a user can send at once before the
+// per-user rate starts to apply.
+func (l Limits) userBurst() int {
+	return int(math.Max(1, math.Ceil(l.MaxQueriesPerSecondPerUser)))
+}
+
+// LimitsFromJSONData reads the datasource limits from the datasource jsonData.
+func LimitsFromJSONData(jsonData *simplejson.Json) Limits {
+	if jsonData == nil {
+		return Limits{}
+	}
+
+	limits := Limits{
+		MaxConcurrentQueries:       jsonData.Get(maxConcurrentQueriesKey).MustInt(0),
+		MaxQueriesPerSecondPerUser: jsonData.Get(maxQueriesPerSecondPerUserKey).MustFloat64(0),
+		QueueSize:                  jsonData.Get(queryQueueSizeKey).MustInt(0),
+	}
+
+	// The queue timeout can be set either as a duration string, e.g. "30s",
+	// or as a number of seconds.
+	if timeout, err := jsonData.Get(queryQueueTimeoutKey).String(); err == nil {
+		if d, err := time.ParseDuration(timeout); err == nil {
+			limits.QueueTimeout = d
+		}
+	} else if seconds, err := jsonData.Get(queryQueueTimeoutKey).Float64(); err == nil {
+		limits.QueueTimeout = time.Duration(seconds * float64(time.Second))
+	}
+
+	if limits.MaxConcurrentQueries < 0 {
+		limits.MaxConcurrentQueries = 0
+	}
+	if limits.MaxQueriesPerSecondPerUser < 0 {
+		limits.MaxQueriesPerSecondPerUser = 0
+	}
+	if limits.QueueSize < 0 {
+		limits.QueueSize = 0
+	}
+	if limits.QueueTimeout < 0 {
+		limits.QueueTimeout = 0
+	}
+
+	return limits
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	reasonConcurrency  = "concurrency"
+	reasonQueueFull    = "queue_full"
+	reasonQueueTimeout = "queue_timeout"
+	reasonUserRate     = "user_rate"
+)
+
+var (
+	requestsRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "grafana",
+			Subsystem: "datasource_limiter",
+			Name:      "rejected_total",
+			Help:      "A counter for datasource requests rejected because a datasource limit was hit",
+		},
+		[]string{"datasource_uid", "reason"},
+	)
+
+	requestsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "grafana",
+			Subsystem: "datasource_limiter",
+			Name:      "in_flight",
+			Help:      "A gauge of datasource requests currently holding a concurrency slot",
+		},
+		[]string{"datasource_uid"},
+	)
+
+	requestsQueued = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "grafana",
+			Subsystem: "datasource_limiter",
+			Name:      "queued",
+			Help:      "A gauge of datasource requests waiting for a concurrency slot",
+		},
+		[]string{"datasource_uid"},
+	)
+
+	queueWaitDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "grafana",
+			Subsystem: "datasource_limiter",
+			Name:      "queue_wait_duration_seconds",
+			Help:      "Histogram of the time datasource requests spent waiting for a concurrency slot",
+			Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"datasource_uid"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(requestsRejected, requestsInFlight, requestsQueued, queueWaitDuration)
+}
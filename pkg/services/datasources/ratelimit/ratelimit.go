@@ -0,0 +1,263 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// userLimiterIdleTimeout is how long a per-user rate limiter is kept around
+// after the user last sent a request to the datasource.
+const userLimiterIdleTimeout = 10 * time.Minute
+
+// ErrLimitExceeded is returned, wrapped in a LimitExceededError, whenever a
+// request is rejected because of a datasource limit.
+var ErrLimitExceeded = errors.New("datasource limit exceeded")
+
+// LimitExceededError describes which datasource limit was hit and when the
+// client may retry.
+type LimitExceededError struct {
+	DataSourceUID string
+	Reason        string
+	RetryAfter    time.Duration
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s: datasource %s (%s)", ErrLimitExceeded.Error(), e.DataSourceUID, e.Reason)
+}
+
+func (e *LimitExceededError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// RetryAfterSeconds returns the value of the Retry-After header that should be
+// sent along with the rejected request.
+func (e *LimitExceededError) RetryAfterSeconds() int {
+	return int(math.Max(1, math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// Service limits how much traffic is sent to a datasource.
+type Service interface {
+	// Acquire blocks until the request is allowed to be sent to the
+	// datasource or returns a LimitExceededError if one of the datasource
+	// limits was hit. The returned release function must be called once
+	// the request is done.
+	Acquire(ctx context.Context, ds *models.DataSource, user *models.SignedInUser) (func(), error)
+}
+
+// DataSourceLimiter applies the limits configured in the datasource jsonData.
+type DataSourceLimiter struct {
+	mu       sync.Mutex
+	limiters map[int64]*dsLimiter
+	now      func() time.Time
+	log      log.Logger
+}
+
+func ProvideService() *DataSourceLimiter {
+	return &DataSourceLimiter{
+		limiters: map[int64]*dsLimiter{},
+		now:      time.Now,
+		log:      log.New("datasource.limiter"),
+	}
+}
+
+func noopRelease() {}
+
+func (s *DataSourceLimiter) Acquire(ctx context.Context, ds *models.DataSource, user *models.SignedInUser) (func(), error) {
+	if ds == nil {
+		return noopRelease, nil
+	}
+
+	limits := LimitsFromJSONData(ds.JsonData)
+	if !limits.Enabled() {
+		s.forget(ds.Id)
+		return noopRelease, nil
+	}
+
+	l := s.limiterFor(ds, limits)
+	if err := l.allowUser(userKey(user), s.now()); err != nil {
+		s.log.Debug("Datasource request rejected", "datasource", ds.Uid, "reason", err.Reason, "user", userKey(user))
+		return nil, err
+	}
+
+	release, err := l.acquireSlot(ctx)
+	if err != nil {
+		var limitErr *LimitExceededError
+		if errors.As(err, &limitErr) {
+			s.log.Debug("Datasource request rejected", "datasource", ds.Uid, "reason", limitErr.Reason, "user", userKey(user))
+		}
+		return nil, err
+	}
+	return release, nil
+}
+
+// limiterFor returns the limiter for the datasource, creating a new one if the
+// datasource has been updated since the limiter was created.
+func (s *DataSourceLimiter) limiterFor(ds *models.DataSource, limits Limits) *dsLimiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l, ok := s.limiters[ds.Id]; ok && l.version == ds.Version && l.limits == limits {
+		return l
+	}
+
+	l := newDSLimiter(ds, limits)
+	s.limiters[ds.Id] = l
+	return l
+}
+
+func (s *DataSourceLimiter) forget(dsID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.limiters, dsID)
+}
+
+type userLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+type dsLimiter struct {
+	uid     string
+	version int
+	limits  Limits
+	// slots holds one element per in-flight request, nil when there is no
+	// concurrency limit.
+	slots  chan struct{}
+	queued int64
+
+	mu        sync.Mutex
+	users     map[string]*userLimiter
+	lastPrune time.Time
+}
+
+func newDSLimiter(ds *models.DataSource, limits Limits) *dsLimiter {
+	l := &dsLimiter{
+		uid:     ds.Uid,
+		version: ds.Version,
+		limits:  limits,
+		users:   map[string]*userLimiter{},
+	}
+	if limits.MaxConcurrentQueries > 0 {
+		l.slots = make(chan struct{}, limits.MaxConcurrentQueries)
+	}
+	return l
+}
+
+func (l *dsLimiter) reject(reason string, retryAfter time.Duration) *LimitExceededError {
+	requestsRejected.WithLabelValues(l.uid, reason).Inc()
+	return &LimitExceededError{
+		DataSourceUID: l.uid,
+		Reason:        reason,
+		RetryAfter:    retryAfter,
+	}
+}
+
+func (l *dsLimiter) allowUser(key string, now time.Time) *LimitExceededError {
+	if l.limits.MaxQueriesPerSecondPerUser <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) > time.Minute {
+		for k, u := range l.users {
+			if now.Sub(u.lastSeen) > userLimiterIdleTimeout {
+				delete(l.users, k)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	u, ok := l.users[key]
+	if !ok {
+		u = &userLimiter{
+			limiter: rate.NewLimiter(rate.Limit(l.limits.MaxQueriesPerSecondPerUser), l.limits.userBurst()),
+		}
+		l.users[key] = u
+	}
+	u.lastSeen = now
+
+	reservation := u.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return l.reject(reasonUserRate, delay)
+	}
+	return nil
+}
+
+func (l *dsLimiter) acquireSlot(ctx context.Context) (func(), error) {
+	if l.slots == nil {
+		return noopRelease, nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return l.release(), nil
+	default:
+	}
+
+	if l.limits.QueueTimeout <= 0 {
+		return nil, l.reject(reasonConcurrency, time.Second)
+	}
+
+	queued := atomic.AddInt64(&l.queued, 1)
+	defer atomic.AddInt64(&l.queued, -1)
+	if l.limits.QueueSize > 0 && queued > int64(l.limits.QueueSize) {
+		return nil, l.reject(reasonQueueFull, l.limits.QueueTimeout)
+	}
+
+	queuedGauge := requestsQueued.WithLabelValues(l.uid)
+	queuedGauge.Inc()
+	defer queuedGauge.Dec()
+
+	start := time.Now()
+	timer := time.NewTimer(l.limits.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		queueWaitDuration.WithLabelValues(l.uid).Observe(time.Since(start).Seconds())
+		return l.release(), nil
+	case <-timer.C:
+		return nil, l.reject(reasonQueueTimeout, l.limits.QueueTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *dsLimiter) release() func() {
+	inFlight := requestsInFlight.WithLabelValues(l.uid)
+	inFlight.Inc()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-l.slots
+			inFlight.Dec()
+		})
+	}
+}
+
+func userKey(user *models.SignedInUser) string {
+	switch {
+	case user == nil:
+		return ""
+	case user.ApiKeyId > 0:
+		return fmt.Sprintf("apikey:%d", user.ApiKeyId)
+	case user.UserId > 0:
+		return fmt.Sprintf("user:%d", user.UserId)
+	default:
+		return fmt.Sprintf("anonymous:%d", user.OrgId)
+	}
+}
@@ -0,0 +1,204 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func TestLimitsFromJSONData(t *testing.T) {
+	t.Run("no jsonData disables limits", func(t *testing.T) {
+		require.False(t, LimitsFromJSONData(nil).Enabled())
+		require.False(t, LimitsFromJSONData(simplejson.New()).Enabled())
+	})
+
+	t.Run("reads all limits", func(t *testing.T) {
+		limits := LimitsFromJSONData(simplejson.NewFromAny(map[string]interface{}{
+			"maxConcurrentQueries":       10,
+			"maxQueriesPerSecondPerUser": 2.5,
+			"queryQueueSize":             20,
+			"queryQueueTimeout":          "30s",
+		}))
+		require.Equal(t, Limits{
+			MaxConcurrentQueries:       10,
+			MaxQueriesPerSecondPerUser: 2.5,
+			QueueSize:                  20,
+			QueueTimeout:               30 * time.Second,
+		}, limits)
+		require.True(t, limits.Enabled())
+		require.Equal(t, 3, limits.userBurst())
+	})
+
+	t.Run("queue timeout as number of seconds", func(t *testing.T) {
+		limits := LimitsFromJSONData(simplejson.NewFromAny(map[string]interface{}{
+			"queryQueueTimeout": 5,
+		}))
+		require.Equal(t, 5*time.Second, limits.QueueTimeout)
+	})
+
+	t.Run("negative values are ignored", func(t *testing.T) {
+		limits := LimitsFromJSONData(simplejson.NewFromAny(map[string]interface{}{
+			"maxConcurrentQueries":       -1,
+			"maxQueriesPerSecondPerUser": -1,
+		}))
+		require.False(t, limits.Enabled())
+	})
+}
+
+func newDataSource(jsonData map[string]interface{}) *models.DataSource {
+	return &models.DataSource{Id: 1, Uid: "ds", Version: 1, JsonData: simplejson.NewFromAny(jsonData)}
+}
+
+func requireLimitExceeded(t *testing.T, err error, reason string) *LimitExceededError {
+	t.Helper()
+	require.Error(t, err)
+	require.True(t, errors.Is(err, ErrLimitExceeded))
+	var limitErr *LimitExceededError
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, reason, limitErr.Reason)
+	return limitErr
+}
+
+func TestDataSourceLimiter(t *testing.T) {
+	user := &models.SignedInUser{UserId: 1, OrgId: 1}
+
+	t.Run("no limits never rejects", func(t *testing.T) {
+		s := ProvideService()
+		ds := newDataSource(nil)
+		for i := 0; i < 100; i++ {
+			release, err := s.Acquire(context.Background(), ds, user)
+			require.NoError(t, err)
+			defer release()
+		}
+	})
+
+	t.Run("rejects when max concurrent queries is reached", func(t *testing.T) {
+		s := ProvideService()
+		ds := newDataSource(map[string]interface{}{"maxConcurrentQueries": 2})
+
+		release1, err := s.Acquire(context.Background(), ds, user)
+		require.NoError(t, err)
+		release2, err := s.Acquire(context.Background(), ds, user)
+		require.NoError(t, err)
+
+		_, err = s.Acquire(context.Background(), ds, user)
+		limitErr := requireLimitExceeded(t, err, reasonConcurrency)
+		require.Equal(t, 1, limitErr.RetryAfterSeconds())
+
+		release1()
+		// releasing twice must not free a second slot
+		release1()
+		release3, err := s.Acquire(context.Background(), ds, user)
+		require.NoError(t, err)
+
+		_, err = s.Acquire(context.Background(), ds, user)
+		requireLimitExceeded(t, err, reasonConcurrency)
+
+		release2()
+		release3()
+	})
+
+	t.Run("queued request gets a slot once one is released", func(t *testing.T) {
+		s := ProvideService()
+		ds := newDataSource(map[string]interface{}{"maxConcurrentQueries": 1, "queryQueueTimeout": "5s"})
+
+		release, err := s.Acquire(context.Background(), ds, user)
+		require.NoError(t, err)
+
+		done := make(chan error)
+		go func() {
+			release, err := s.Acquire(context.Background(), ds, user)
+			if err == nil {
+				release()
+			}
+			done <- err
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		release()
+		require.NoError(t, <-done)
+	})
+
+	t.Run("queued request is rejected after the queue timeout", func(t *testing.T) {
+		s := ProvideService()
+		ds := newDataSource(map[string]interface{}{"maxConcurrentQueries": 1, "queryQueueTimeout": "50ms"})
+
+		release, err := s.Acquire(context.Background(), ds, user)
+		require.NoError(t, err)
+		defer release()
+
+		_, err = s.Acquire(context.Background(), ds, user)
+		requireLimitExceeded(t, err, reasonQueueTimeout)
+	})
+
+	t.Run("rejects when the queue is full", func(t *testing.T) {
+		s := ProvideService()
+		ds := newDataSource(map[string]interface{}{"maxConcurrentQueries": 1, "queryQueueSize": 1, "queryQueueTimeout": "5s"})
+
+		release, err := s.Acquire(context.Background(), ds, user)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		queued := make(chan error)
+		go func() {
+			_, err := s.Acquire(ctx, ds, user)
+			queued <- err
+		}()
+		require.Eventually(t, func() bool {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			return s.limiters[ds.Id].queued == 1
+		}, time.Second, 10*time.Millisecond)
+
+		_, err = s.Acquire(context.Background(), ds, user)
+		requireLimitExceeded(t, err, reasonQueueFull)
+
+		cancel()
+		require.ErrorIs(t, <-queued, context.Canceled)
+		release()
+	})
+
+	t.Run("rejects when a user exceeds the queries per second", func(t *testing.T) {
+		s := ProvideService()
+		now := time.Now()
+		s.now = func() time.Time { return now }
+		ds := newDataSource(map[string]interface{}{"maxQueriesPerSecondPerUser": 1})
+
+		release, err := s.Acquire(context.Background(), ds, user)
+		require.NoError(t, err)
+		release()
+
+		_, err = s.Acquire(context.Background(), ds, user)
+		limitErr := requireLimitExceeded(t, err, reasonUserRate)
+		require.Equal(t, 1, limitErr.RetryAfterSeconds())
+
+		// other users have their own rate
+		_, err = s.Acquire(context.Background(), ds, &models.SignedInUser{UserId: 2, OrgId: 1})
+		require.NoError(t, err)
+
+		now = now.Add(time.Second)
+		_, err = s.Acquire(context.Background(), ds, user)
+		require.NoError(t, err)
+	})
+
+	t.Run("updated datasource limits are applied", func(t *testing.T) {
+		s := ProvideService()
+		ds := newDataSource(map[string]interface{}{"maxConcurrentQueries": 1})
+
+		_, err := s.Acquire(context.Background(), ds, user)
+		require.NoError(t, err)
+		_, err = s.Acquire(context.Background(), ds, user)
+		requireLimitExceeded(t, err, reasonConcurrency)
+
+		ds.Version++
+		ds.JsonData = simplejson.NewFromAny(map[string]interface{}{"maxConcurrentQueries": 2})
+		_, err = s.Acquire(context.Background(), ds, user)
+		require.NoError(t, err)
+	})
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/httpclient"
+	"github.com/grafana/grafana/pkg/infra/tunnel"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/datasources"
@@ -379,6 +380,22 @@ func (s *Service) httpClientOptions(ctx context.Context, ds *models.DataSource)
 
 	if ds.JsonData != nil {
 		opts.CustomOptions = ds.JsonData.MustMap()
+
+		tunnelCfg, err := tunnel.ConfigFromJSONData(ds.JsonData.MustMap(), decryptedValues)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tunnel settings: %w", err)
+		}
+		if tunnelCfg != nil {
+			dialer, err := tunnel.NewDialer(tunnelCfg)
+			if err != nil {
+				return nil, err
+			}
+			opts.ConfigureTransport = func(_ sdkhttpclient.Options, transport *http.Transport) {
+				transport.DialContext = dialer.DialContext
+				// the tunnel replaces any HTTP proxy from the environment
+				transport.Proxy = nil
+			}
+		}
 	}
 	if ds.BasicAuth {
 		password, err := s.DecryptedBasicAuthPassword(ctx, ds)
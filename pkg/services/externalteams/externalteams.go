@@ -0,0 +1,101 @@
+// Package externalteams manages the team memberships of external users, which are added to and removed from
+// teams by the synchronization of their groups.
+package externalteams
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// MemberPermission is the team permission of the members added by a sync
+const MemberPermission = "Member"
+
+// GetTeamsByName returns the ids of the teams of the organization by name. Names without a team are left out.
+func GetTeamsByName(ctx context.Context, sqlStore *sqlstore.SQLStore, orgID int64, names []string) (map[string]int64, error) {
+	teams := make([]*models.Team, 0)
+	err := sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("org_id = ?", orgID).In("name", names).Find(&teams)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]int64, len(teams))
+	for _, team := range teams {
+		result[team.Name] = team.Id
+	}
+	return result, nil
+}
+
+// SetMemberPermission sets the team permission of an external user, an empty permission removes the user
+// from the team.
+func SetMemberPermission(ctx context.Context, teamPermissionsService accesscontrol.TeamPermissionsService, orgID, userID, teamID int64, permission string) error {
+	user := accesscontrol.User{ID: userID, IsExternal: true}
+	_, err := teamPermissionsService.SetUserPermission(ctx, orgID, user, strconv.FormatInt(teamID, 10), permission)
+	return err
+}
+
+// Memberships are the teams mapped to groups by organization, and whether the external user has one of the
+// groups of each team. The memberships of every sync are merged before they are applied, so that a team
+// mapped by several syncs keeps the members any of them adds.
+type Memberships map[int64]map[int64]bool
+
+// Add adds a mapped team, the user stays a member of the team if any of its mappings wants it.
+func (m Memberships) Add(orgID, teamID int64, wanted bool) {
+	if m[orgID] == nil {
+		m[orgID] = map[int64]bool{}
+	}
+	m[orgID][teamID] = m[orgID][teamID] || wanted
+}
+
+// Merge adds the mapped teams of other.
+func (m Memberships) Merge(other Memberships) {
+	for orgID, teams := range other {
+		for teamID, wanted := range teams {
+			m.Add(orgID, teamID, wanted)
+		}
+	}
+}
+
+// Sync adds the user to the mapped teams it is wanted in, and removes it from the other mapped teams. Only
+// the members added by a sync are removed.
+func Sync(ctx context.Context, sqlStore *sqlstore.SQLStore, teamPermissionsService accesscontrol.TeamPermissionsService,
+	logger log.Logger, userID int64, memberships Memberships) error {
+	for orgID, teams := range memberships {
+		if len(teams) == 0 {
+			continue
+		}
+
+		current, err := sqlStore.GetUserTeamMemberships(ctx, orgID, userID, false)
+		if err != nil {
+			return err
+		}
+
+		members := map[int64]*models.TeamMemberDTO{}
+		for _, membership := range current {
+			members[membership.TeamId] = membership
+		}
+
+		for teamID, wanted := range teams {
+			membership, isMember := members[teamID]
+			switch {
+			case wanted && !isMember:
+				logger.Debug("Adding user to team", "userId", userID, "orgId", orgID, "teamId", teamID)
+				if err := SetMemberPermission(ctx, teamPermissionsService, orgID, userID, teamID, MemberPermission); err != nil {
+					return err
+				}
+			case !wanted && isMember && membership.External:
+				logger.Debug("Removing user from team", "userId", userID, "orgId", orgID, "teamId", teamID)
+				if err := SetMemberPermission(ctx, teamPermissionsService, orgID, userID, teamID, ""); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
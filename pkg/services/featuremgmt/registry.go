@@ -139,10 +139,9 @@ var (
 			RequiresDevMode: true,
 		},
 		{
-			Name:            "publicDashboards",
-			Description:     "enables public access to dashboards",
-			State:           FeatureStateAlpha,
-			RequiresDevMode: true,
+			Name:        "publicDashboards",
+			Description: "enables public access to dashboards with an access token",
+			State:       FeatureStateBeta,
 		},
 		{
 			Name:        "lokiLive",
@@ -104,7 +104,7 @@ const (
 	FlagValidatedQueries = "validatedQueries"
 
 	// FlagPublicDashboards
-	// enables public access to dashboards
+	// enables public access to dashboards with an access token
 	FlagPublicDashboards = "publicDashboards"
 
 	// FlagLokiLive
@@ -255,9 +255,12 @@ func (server *Server) Users(logins []string) (
 ) {
 	var users [][]*ldap.Entry
 	err := getUsersIteration(logins, func(previous, current int) error {
-		var err error
-		users, err = server.users(logins[previous:current])
-		return err
+		batch, err := server.users(logins[previous:current])
+		if err != nil {
+			return err
+		}
+		users = append(users, batch...)
+		return nil
 	})
 	if err != nil {
 		return nil, err
@@ -417,6 +420,14 @@ func (server *Server) buildGrafanaUser(user *ldap.Entry) (*models.ExternalUserIn
 	}
 
 	for _, group := range server.Config.Groups {
+		// teams of all the matches are used
+		if len(group.Teams) > 0 && IsMemberOf(memberOf, group.GroupDN) {
+			if extUser.OrgTeams == nil {
+				extUser.OrgTeams = map[int64][]string{}
+			}
+			extUser.OrgTeams[group.OrgId] = append(extUser.OrgTeams[group.OrgId], group.Teams...)
+		}
+
 		// only use the first match for each org
 		if extUser.OrgRoles[group.OrgId] != "" {
 			continue
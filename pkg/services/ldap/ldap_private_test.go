@@ -147,6 +147,38 @@ func TestSerializeUsers(t *testing.T) {
 		assert.Len(t, result, 1)
 		assert.True(t, result[0].IsDisabled)
 	})
+
+	t.Run("use the teams of all matching groups", func(t *testing.T) {
+		server := &Server{
+			Config: &ServerConfig{
+				Attr: AttributeMap{
+					MemberOf: "memberof",
+				},
+				Groups: []*GroupToOrgRole{
+					{GroupDN: "admins", OrgId: 1, OrgRole: models.ROLE_ADMIN, Teams: []string{"Admins"}},
+					{GroupDN: "backend", OrgId: 1, OrgRole: models.ROLE_EDITOR, Teams: []string{"Backend", "On-call"}},
+					{GroupDN: "frontend", OrgId: 1, OrgRole: models.ROLE_EDITOR, Teams: []string{"Frontend"}},
+					{GroupDN: "*", OrgId: 2, OrgRole: models.ROLE_VIEWER, Teams: []string{"Everyone"}},
+				},
+			},
+			Connection: &MockConnection{},
+			log:        log.New("test-logger"),
+		}
+
+		entry := ldap.Entry{
+			DN: "dn",
+			Attributes: []*ldap.EntryAttribute{
+				{Name: "memberof", Values: []string{"admins", "backend"}},
+			},
+		}
+		users := [][]*ldap.Entry{{&entry}}
+
+		result, err := server.serializeUsers(users)
+		require.NoError(t, err)
+
+		assert.Equal(t, map[int64]models.RoleType{1: models.ROLE_ADMIN, 2: models.ROLE_VIEWER}, result[0].OrgRoles)
+		assert.Equal(t, map[int64][]string{1: {"Admins", "Backend", "On-call"}, 2: {"Everyone"}}, result[0].OrgTeams)
+	})
 }
 
 func TestServer_validateGrafanaUser(t *testing.T) {
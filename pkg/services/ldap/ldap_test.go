@@ -3,6 +3,7 @@ package ldap
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -175,6 +176,46 @@ func TestServer_Users(t *testing.T) {
 		assert.Len(t, searchResult, 2)
 	})
 
+	t.Run("more users than a single request", func(t *testing.T) {
+		conn := &MockConnection{}
+		searches := 0
+		conn.setSearchFunc(func(request *ldap.SearchRequest) (*ldap.SearchResult, error) {
+			searches++
+			result := &ldap.SearchResult{}
+			for _, match := range regexp.MustCompile(`\(username=([^)]+)\)`).FindAllStringSubmatch(request.Filter, -1) {
+				result.Entries = append(result.Entries, &ldap.Entry{
+					DN: "cn=" + match[1], Attributes: []*ldap.EntryAttribute{
+						{Name: "username", Values: []string{match[1]}},
+					}})
+			}
+			return result, nil
+		})
+
+		server := &Server{
+			Config: &ServerConfig{
+				Attr: AttributeMap{
+					Username: "username",
+				},
+				SearchFilter:  "(username=%s)",
+				SearchBaseDNs: []string{"dc=users,dc=example,dc=org"},
+			},
+			Connection: conn,
+			log:        log.New("test-logger"),
+		}
+
+		logins := make([]string, 0, UsersMaxRequest*2+1)
+		for i := 0; i < cap(logins); i++ {
+			logins = append(logins, fmt.Sprintf("user%d", i))
+		}
+
+		res, err := server.Users(logins)
+		require.NoError(t, err)
+		assert.Equal(t, 3, searches)
+		require.Len(t, res, len(logins))
+		assert.Equal(t, "user0", res[0].Login)
+		assert.Equal(t, "user1000", res[len(res)-1].Login)
+	})
+
 	t.Run("same user in multiple DNs", func(t *testing.T) {
 		conn := &MockConnection{}
 		firstDN := "dc=users1,dc=example,dc=org"
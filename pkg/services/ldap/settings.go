@@ -61,6 +61,9 @@ type GroupToOrgRole struct {
 	IsGrafanaAdmin *bool `toml:"grafana_admin"`
 
 	OrgRole models.RoleType `toml:"org_role"`
+
+	// Names of the teams of the organization that members of the group are synced to
+	Teams []string `toml:"teams"`
 }
 
 // logger for all LDAP stuff
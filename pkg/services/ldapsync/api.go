@@ -0,0 +1,46 @@
+package ldapsync
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+// SyncStatusDTO is the schedule and the last report of the LDAP sync
+type SyncStatusDTO struct {
+	Enabled  bool        `json:"enabled"`
+	Schedule string      `json:"schedule"`
+	NextSync *time.Time  `json:"nextSync"`
+	PrevSync *SyncReport `json:"prevSync"`
+}
+
+func (s *LDAPSyncService) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(s.accessControl)
+
+	s.routeRegister.Get("/api/admin/ldap-sync-status",
+		authorize(middleware.ReqGrafanaAdmin, accesscontrol.EvalPermission(accesscontrol.ActionLDAPStatusRead)),
+		routing.Wrap(s.getSyncStatusHandler))
+}
+
+// GET /api/admin/ldap-sync-status
+func (s *LDAPSyncService) getSyncStatusHandler(c *models.ReqContext) response.Response {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	status := SyncStatusDTO{
+		Enabled:  !s.IsDisabled(),
+		Schedule: s.cfg.LDAPSyncCron,
+		PrevSync: s.prevSync,
+	}
+	if !s.nextSync.IsZero() {
+		nextSync := s.nextSync
+		status.NextSync = &nextSync
+	}
+
+	return response.JSON(http.StatusOK, status)
+}
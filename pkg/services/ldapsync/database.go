@@ -0,0 +1,28 @@
+package ldapsync
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+type ldapUser struct {
+	ID         int64 `xorm:"id"`
+	Login      string
+	IsDisabled bool
+}
+
+// getLDAPUsers returns the users that authenticated with LDAP.
+func (s *LDAPSyncService) getLDAPUsers(ctx context.Context) ([]*ldapUser, error) {
+	users := make([]*ldapUser, 0)
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		rawSQL := `SELECT DISTINCT u.id, u.login, u.is_disabled
+			FROM ` + s.sqlStore.Dialect.Quote("user") + ` AS u
+			INNER JOIN user_auth AS ua ON ua.user_id = u.id
+			WHERE ua.auth_module = ?
+			ORDER BY u.id`
+		return sess.SQL(rawSQL, models.AuthModuleLDAP).Find(&users)
+	})
+	return users, err
+}
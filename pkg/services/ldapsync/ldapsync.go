@@ -0,0 +1,259 @@
+package ldapsync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/ldap"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/multildap"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// getLDAPConfig gets the LDAP config
+var getLDAPConfig = multildap.GetConfig
+
+// newLDAP creates a single LDAP server
+var newLDAP = ldap.New
+
+// SyncReport is the result of a synchronization of the LDAP users
+type SyncReport struct {
+	Started  time.Time `json:"started"`
+	Elapsed  string    `json:"elapsed"`
+	Synced   int       `json:"synced"`
+	Disabled int       `json:"disabled"`
+	Skipped  int       `json:"skipped"`
+	Failed   int       `json:"failed"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// LDAPSyncService periodically syncs the org roles and teams of the LDAP users, and disables the ones
+// that were removed from LDAP, instead of waiting for them to log in.
+type LDAPSyncService struct {
+	cfg                    *setting.Cfg
+	sqlStore               *sqlstore.SQLStore
+	loginService           login.Service
+	userTokenService       models.UserTokenService
+	serverLockService      *serverlock.ServerLockService
+	teamPermissionsService accesscontrol.TeamPermissionsService
+	accessControl          accesscontrol.AccessControl
+	routeRegister          routing.RouteRegister
+	schedule               cron.Schedule
+	log                    log.Logger
+
+	mutex    sync.RWMutex
+	nextSync time.Time
+	prevSync *SyncReport
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, loginService login.Service,
+	userTokenService models.UserTokenService, serverLockService *serverlock.ServerLockService,
+	teamPermissionsService accesscontrol.TeamPermissionsService, accessControl accesscontrol.AccessControl,
+	routeRegister routing.RouteRegister) (*LDAPSyncService, error) {
+	s := &LDAPSyncService{
+		cfg:                    cfg,
+		sqlStore:               sqlStore,
+		loginService:           loginService,
+		userTokenService:       userTokenService,
+		serverLockService:      serverLockService,
+		teamPermissionsService: teamPermissionsService,
+		accessControl:          accessControl,
+		routeRegister:          routeRegister,
+		log:                    log.New("ldap.sync"),
+	}
+
+	if !cfg.LDAPEnabled {
+		return s, nil
+	}
+
+	if cfg.LDAPActiveSyncEnabled {
+		schedule, err := cron.ParseStandard(cfg.LDAPSyncCron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LDAP sync_cron %q: %w", cfg.LDAPSyncCron, err)
+		}
+		s.schedule = schedule
+	}
+
+	s.registerAPIEndpoints()
+
+	return s, nil
+}
+
+func (s *LDAPSyncService) IsDisabled() bool {
+	return s.schedule == nil
+}
+
+func (s *LDAPSyncService) Run(ctx context.Context) error {
+	for {
+		now := time.Now()
+		next := s.schedule.Next(now)
+		s.mutex.Lock()
+		s.nextSync = next
+		s.mutex.Unlock()
+
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-timer.C:
+			// all the instances wake up at the same time, the lock lets only one of them sync
+			interval := s.schedule.Next(next).Sub(next)
+			err := s.serverLockService.LockAndExecute(ctx, "ldap sync", interval/2, func(ctx context.Context) {
+				s.Sync(ctx)
+			})
+			if err != nil {
+				s.log.Error("Failed to lock and execute the LDAP sync", "error", err)
+			}
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Sync syncs all the LDAP users, and returns the report of the synchronization.
+func (s *LDAPSyncService) Sync(ctx context.Context) *SyncReport {
+	report := &SyncReport{Started: time.Now()}
+	s.log.Info("Starting LDAP sync")
+
+	if err := s.sync(ctx, report); err != nil {
+		report.Error = err.Error()
+	}
+	report.Elapsed = time.Since(report.Started).Round(time.Millisecond).String()
+
+	if report.Error != "" {
+		s.log.Error("LDAP sync failed", "error", report.Error, "elapsed", report.Elapsed,
+			"synced", report.Synced, "disabled", report.Disabled, "skipped", report.Skipped, "failed", report.Failed)
+	} else {
+		s.log.Info("LDAP sync finished", "elapsed", report.Elapsed,
+			"synced", report.Synced, "disabled", report.Disabled, "skipped", report.Skipped, "failed", report.Failed)
+	}
+
+	s.mutex.Lock()
+	s.prevSync = report
+	s.mutex.Unlock()
+
+	return report
+}
+
+func (s *LDAPSyncService) sync(ctx context.Context, report *SyncReport) error {
+	config, err := getLDAPConfig(s.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to get LDAP config: %w", err)
+	}
+
+	users, err := s.getLDAPUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get LDAP users: %w", err)
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	found, err := s.searchUsers(config.Servers, users)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// the server admin must never be disabled, or nobody could log in
+		if user.Login == s.cfg.AdminUser {
+			report.Skipped++
+			continue
+		}
+
+		extUser := found[strings.ToLower(user.Login)]
+		if extUser == nil || extUser.IsDisabled {
+			if user.IsDisabled {
+				report.Skipped++
+				continue
+			}
+
+			if err := s.disableUser(ctx, user); err != nil {
+				s.log.Error("Failed to disable LDAP user", "userId", user.ID, "login", user.Login, "error", err)
+				report.Failed++
+				continue
+			}
+			report.Disabled++
+			continue
+		}
+
+		extUser.UserId = user.ID
+		upsert := &models.UpsertUserCommand{
+			ReqContext:    &models.ReqContext{Logger: s.log},
+			ExternalUser:  extUser,
+			SignupAllowed: false,
+		}
+		if err := s.loginService.UpsertUser(ctx, upsert); err != nil {
+			s.log.Error("Failed to sync LDAP user", "userId", user.ID, "login", user.Login, "error", err)
+			report.Failed++
+			continue
+		}
+		report.Synced++
+	}
+
+	return nil
+}
+
+// searchUsers looks the users up in all the servers, by lower case login. Unlike a login, the sync
+// is aborted when a server is unavailable, as its users would be disabled otherwise.
+func (s *LDAPSyncService) searchUsers(configs []*ldap.ServerConfig, users []*ldapUser) (map[string]*models.ExternalUserInfo, error) {
+	logins := make([]string, 0, len(users))
+	for _, user := range users {
+		logins = append(logins, user.Login)
+	}
+
+	found := map[string]*models.ExternalUserInfo{}
+	for _, config := range configs {
+		server := newLDAP(config)
+		if err := server.Dial(); err != nil {
+			return nil, fmt.Errorf("failed to dial LDAP server %s:%d: %w", config.Host, config.Port, err)
+		}
+
+		extUsers, err := s.searchServer(server, logins)
+		server.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to search LDAP server %s:%d: %w", config.Host, config.Port, err)
+		}
+
+		// the first server a user is found in wins, as it does at login
+		for _, extUser := range extUsers {
+			key := strings.ToLower(extUser.Login)
+			if _, ok := found[key]; !ok {
+				found[key] = extUser
+			}
+		}
+	}
+
+	return found, nil
+}
+
+func (s *LDAPSyncService) searchServer(server ldap.IServer, logins []string) ([]*models.ExternalUserInfo, error) {
+	if err := server.Bind(); err != nil {
+		return nil, err
+	}
+	return server.Users(logins)
+}
+
+func (s *LDAPSyncService) disableUser(ctx context.Context, user *ldapUser) error {
+	s.log.Info("Disabling user removed from LDAP", "userId", user.ID, "login", user.Login)
+
+	if err := s.sqlStore.DisableUser(ctx, &models.DisableUserCommand{UserId: user.ID, IsDisabled: true}); err != nil {
+		return err
+	}
+
+	return s.userTokenService.RevokeAllUserTokens(ctx, user.ID)
+}
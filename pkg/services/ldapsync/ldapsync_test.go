@@ -0,0 +1,318 @@
+//go:build integration
+// +build integration
+
+package ldapsync
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	acdatabase "github.com/grafana/grafana/pkg/services/accesscontrol/database"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/ossaccesscontrol"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/ldap"
+	"github.com/grafana/grafana/pkg/services/login/authinfoservice"
+	authinfostore "github.com/grafana/grafana/pkg/services/login/authinfoservice/database"
+	"github.com/grafana/grafana/pkg/services/login/loginservice"
+	"github.com/grafana/grafana/pkg/services/quota"
+	secretsdatabase "github.com/grafana/grafana/pkg/services/secrets/database"
+	secretsManager "github.com/grafana/grafana/pkg/services/secrets/manager"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const testOrgID = int64(1)
+
+// fakeServer is an LDAP server that knows the given users
+type fakeServer struct {
+	users   []*models.ExternalUserInfo
+	dialErr error
+}
+
+func (s *fakeServer) Login(*models.LoginUserQuery) (*models.ExternalUserInfo, error) {
+	return nil, ldap.ErrInvalidCredentials
+}
+
+func (s *fakeServer) Users(logins []string) ([]*models.ExternalUserInfo, error) {
+	result := []*models.ExternalUserInfo{}
+	for _, user := range s.users {
+		for _, login := range logins {
+			if user.Login == login {
+				result = append(result, user)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *fakeServer) Bind() error                   { return nil }
+func (s *fakeServer) UserBind(string, string) error { return nil }
+func (s *fakeServer) Dial() error                   { return s.dialErr }
+func (s *fakeServer) Close()                        {}
+
+type testEnv struct {
+	service  *LDAPSyncService
+	sqlStore *sqlstore.SQLStore
+	revoked  []int64
+}
+
+func setupTestEnv(t *testing.T, server *fakeServer) *testEnv {
+	t.Helper()
+
+	cfg := setting.NewCfg()
+	cfg.AdminUser = "admin"
+	cfg.LDAPEnabled = true
+
+	groups := []*ldap.GroupToOrgRole{
+		{GroupDN: "cn=backend", OrgId: testOrgID, OrgRole: models.ROLE_EDITOR, Teams: []string{"Backend"}},
+		{GroupDN: "cn=frontend", OrgId: testOrgID, OrgRole: models.ROLE_VIEWER, Teams: []string{"Frontend", "Missing"}},
+	}
+	origGetLDAPConfig, origNewLDAP := getLDAPConfig, newLDAP
+	getLDAPConfig = func(*setting.Cfg) (*ldap.Config, error) {
+		return &ldap.Config{Servers: []*ldap.ServerConfig{{Host: "ldap", Port: 389, Groups: groups}}}, nil
+	}
+	newLDAP = func(*ldap.ServerConfig) ldap.IServer {
+		return server
+	}
+	t.Cleanup(func() {
+		getLDAPConfig, newLDAP = origGetLDAPConfig, origNewLDAP
+	})
+
+	sqlStore := sqlstore.InitTestDB(t)
+	secretsService := secretsManager.SetupTestService(t, secretsdatabase.ProvideSecretsStore(sqlStore))
+	authInfoService := authinfoservice.ProvideAuthInfoService(&authinfoservice.OSSUserProtectionImpl{}, authinfostore.ProvideAuthInfoStore(sqlStore, secretsService))
+	loginService := loginservice.ProvideService(sqlStore, &quota.QuotaService{}, authInfoService)
+	teamPermissionsService, err := ossaccesscontrol.ProvideTeamPermissions(cfg, routing.NewRouteRegister(), sqlStore, accesscontrolmock.New(), acdatabase.ProvideService(sqlStore))
+	require.NoError(t, err)
+
+	env := &testEnv{sqlStore: sqlStore}
+	tokens := auth.NewFakeUserAuthTokenService()
+	tokens.RevokeAllUserTokensProvider = func(ctx context.Context, userID int64) error {
+		env.revoked = append(env.revoked, userID)
+		return nil
+	}
+
+	env.service = &LDAPSyncService{
+		cfg:                    cfg,
+		sqlStore:               sqlStore,
+		loginService:           loginService,
+		userTokenService:       tokens,
+		teamPermissionsService: teamPermissionsService,
+		log:                    log.New("ldap.sync.test"),
+	}
+	loginService.SetTeamSyncFunc(env.service.SyncTeams)
+
+	return env
+}
+
+func (env *testEnv) createUser(t *testing.T, login string, authModule string) int64 {
+	t.Helper()
+
+	user, err := env.sqlStore.CreateUser(context.Background(), models.CreateUserCommand{Login: login, Email: login + "@example.com", OrgId: testOrgID})
+	require.NoError(t, err)
+
+	if authModule != "" {
+		err := env.sqlStore.WithDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+			_, err := sess.Insert(&models.UserAuth{UserId: user.Id, AuthModule: authModule, AuthId: "cn=" + login, Created: time.Now()})
+			return err
+		})
+		require.NoError(t, err)
+	}
+
+	return user.Id
+}
+
+func (env *testEnv) createTeam(t *testing.T, name string) int64 {
+	t.Helper()
+
+	team, err := env.sqlStore.CreateTeam(name, "", testOrgID)
+	require.NoError(t, err)
+	return team.Id
+}
+
+func (env *testEnv) addTeamMember(t *testing.T, userID, teamID int64, external bool) {
+	t.Helper()
+
+	err := env.sqlStore.AddTeamMember(userID, testOrgID, teamID, external, 0)
+	require.NoError(t, err)
+}
+
+func (env *testEnv) getUser(t *testing.T, userID int64) *models.User {
+	t.Helper()
+
+	query := &models.GetUserByIdQuery{Id: userID}
+	require.NoError(t, env.sqlStore.GetUserById(context.Background(), query))
+	return query.Result
+}
+
+func (env *testEnv) getTeams(t *testing.T, userID int64) []int64 {
+	t.Helper()
+
+	memberships, err := env.sqlStore.GetUserTeamMemberships(context.Background(), testOrgID, userID, false)
+	require.NoError(t, err)
+
+	teams := []int64{}
+	for _, membership := range memberships {
+		teams = append(teams, membership.TeamId)
+	}
+	return teams
+}
+
+func (env *testEnv) getOrgRole(t *testing.T, userID int64) models.RoleType {
+	t.Helper()
+
+	query := &models.GetUserOrgListQuery{UserId: userID}
+	require.NoError(t, env.sqlStore.GetUserOrgList(context.Background(), query))
+	for _, org := range query.Result {
+		if org.OrgId == testOrgID {
+			return org.Role
+		}
+	}
+	return ""
+}
+
+func newExtUser(login string, groups ...string) *models.ExternalUserInfo {
+	user := &models.ExternalUserInfo{
+		AuthModule: models.AuthModuleLDAP,
+		AuthId:     "cn=" + login,
+		Login:      login,
+		Email:      login + "@example.com",
+		Name:       login,
+		Groups:     groups,
+		OrgRoles:   map[int64]models.RoleType{},
+		OrgTeams:   map[int64][]string{},
+	}
+	for _, group := range groups {
+		switch group {
+		case "cn=backend":
+			user.OrgRoles[testOrgID] = models.ROLE_EDITOR
+			user.OrgTeams[testOrgID] = append(user.OrgTeams[testOrgID], "Backend")
+		case "cn=frontend":
+			if user.OrgRoles[testOrgID] == "" {
+				user.OrgRoles[testOrgID] = models.ROLE_VIEWER
+			}
+			user.OrgTeams[testOrgID] = append(user.OrgTeams[testOrgID], "Frontend", "Missing")
+		}
+	}
+	user.IsDisabled = len(user.OrgRoles) == 0
+	return user
+}
+
+func TestLDAPSync(t *testing.T) {
+	server := &fakeServer{users: []*models.ExternalUserInfo{
+		newExtUser("alice", "cn=backend"),
+		// erin does not match any group mapping anymore
+		newExtUser("erin"),
+		newExtUser("frank", "cn=frontend"),
+	}}
+	env := setupTestEnv(t, server)
+
+	adminID := env.createUser(t, "admin", models.AuthModuleLDAP)
+	aliceID := env.createUser(t, "alice", models.AuthModuleLDAP)
+	bobID := env.createUser(t, "bob", models.AuthModuleLDAP)
+	erinID := env.createUser(t, "erin", models.AuthModuleLDAP)
+	frankID := env.createUser(t, "frank", models.AuthModuleLDAP)
+	localID := env.createUser(t, "local", "")
+
+	backendID := env.createTeam(t, "Backend")
+	frontendID := env.createTeam(t, "Frontend")
+	otherID := env.createTeam(t, "Other")
+	env.addTeamMember(t, aliceID, frontendID, true)
+	env.addTeamMember(t, aliceID, otherID, true)
+	env.addTeamMember(t, frankID, backendID, false)
+
+	report := env.service.Sync(context.Background())
+	assert.Empty(t, report.Error)
+	assert.Equal(t, 2, report.Synced)
+	assert.Equal(t, 2, report.Disabled)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 0, report.Failed)
+
+	t.Run("should sync the org role and teams of the users found", func(t *testing.T) {
+		assert.Equal(t, models.ROLE_EDITOR, env.getOrgRole(t, aliceID))
+		assert.ElementsMatch(t, []int64{backendID, otherID}, env.getTeams(t, aliceID))
+
+		assert.Equal(t, models.ROLE_VIEWER, env.getOrgRole(t, frankID))
+		assert.ElementsMatch(t, []int64{backendID, frontendID}, env.getTeams(t, frankID), "manual members are not removed")
+	})
+
+	t.Run("should disable the users not found and revoke their sessions", func(t *testing.T) {
+		assert.True(t, env.getUser(t, bobID).IsDisabled)
+		assert.True(t, env.getUser(t, erinID).IsDisabled)
+		assert.ElementsMatch(t, []int64{bobID, erinID}, env.revoked)
+	})
+
+	t.Run("should not disable the server admin nor other users", func(t *testing.T) {
+		assert.False(t, env.getUser(t, adminID).IsDisabled)
+		assert.False(t, env.getUser(t, localID).IsDisabled)
+	})
+
+	t.Run("should re-enable users found again", func(t *testing.T) {
+		server.users = append(server.users, newExtUser("bob", "cn=frontend"))
+		env.revoked = nil
+
+		report := env.service.Sync(context.Background())
+		assert.Empty(t, report.Error)
+		assert.Equal(t, 3, report.Synced)
+		assert.Equal(t, 2, report.Skipped)
+		assert.Equal(t, 0, report.Disabled)
+
+		assert.False(t, env.getUser(t, bobID).IsDisabled)
+		assert.ElementsMatch(t, []int64{frontendID}, env.getTeams(t, bobID))
+		assert.Empty(t, env.revoked)
+	})
+
+	t.Run("should report the last sync", func(t *testing.T) {
+		assert.NotNil(t, env.service.prevSync)
+		assert.NotEmpty(t, env.service.prevSync.Elapsed)
+	})
+}
+
+func TestLDAPSync_ServerUnavailable(t *testing.T) {
+	env := setupTestEnv(t, &fakeServer{dialErr: errors.New("connection refused")})
+	userID := env.createUser(t, "alice", models.AuthModuleLDAP)
+
+	report := env.service.Sync(context.Background())
+	assert.Contains(t, report.Error, "connection refused")
+	assert.Equal(t, 0, report.Disabled)
+	assert.False(t, env.getUser(t, userID).IsDisabled)
+	assert.Empty(t, env.revoked)
+}
+
+func TestLDAPSync_Teams(t *testing.T) {
+	env := setupTestEnv(t, &fakeServer{})
+	userID := env.createUser(t, "alice", models.AuthModuleLDAP)
+	backendID := env.createTeam(t, "Backend")
+
+	user := env.getUser(t, userID)
+	require.NoError(t, env.service.SyncTeams(user, newExtUser("alice", "cn=backend")))
+	assert.Equal(t, []int64{backendID}, env.getTeams(t, userID))
+
+	memberships, err := env.sqlStore.GetUserTeamMemberships(context.Background(), testOrgID, userID, true)
+	require.NoError(t, err)
+	assert.Len(t, memberships, 1, "members added by the sync are external")
+
+	t.Run("should ignore users of other auth modules", func(t *testing.T) {
+		extUser := newExtUser("alice")
+		extUser.AuthModule = "oauth_generic_oauth"
+		require.NoError(t, env.service.SyncTeams(user, extUser))
+		assert.Equal(t, []int64{backendID}, env.getTeams(t, userID))
+	})
+
+	t.Run("should remove users that lost the org role", func(t *testing.T) {
+		extUser := newExtUser("alice", "cn=backend")
+		extUser.OrgRoles = map[int64]models.RoleType{}
+		require.NoError(t, env.service.SyncTeams(user, extUser))
+		assert.Empty(t, env.getTeams(t, userID))
+	})
+
+}
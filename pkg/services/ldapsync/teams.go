@@ -0,0 +1,83 @@
+package ldapsync
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/externalteams"
+)
+
+// SyncTeams adds the LDAP user to the teams of the group mappings it matches, and removes it from the
+// teams of the ones it does not match anymore. Team members that were added manually are left alone.
+func (s *LDAPSyncService) SyncTeams(user *models.User, extUser *models.ExternalUserInfo) error {
+	ctx := context.Background()
+	memberships, err := s.TeamMemberships(ctx, extUser)
+	if err != nil {
+		return err
+	}
+
+	return externalteams.Sync(ctx, s.sqlStore, s.teamPermissionsService, s.log, user.Id, memberships)
+}
+
+// TeamMemberships returns the teams of the LDAP group mappings, and whether the LDAP user matches them.
+// It is called by the team sync of every login and synchronization of external users.
+func (s *LDAPSyncService) TeamMemberships(ctx context.Context, extUser *models.ExternalUserInfo) (externalteams.Memberships, error) {
+	memberships := externalteams.Memberships{}
+	if !s.cfg.LDAPEnabled || extUser.AuthModule != models.AuthModuleLDAP {
+		return memberships, nil
+	}
+
+	config, err := getLDAPConfig(s.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// the teams of all the servers are synced, a user only matches the group mappings of its own server
+	mappedTeams := map[int64][]string{}
+	for _, server := range config.Servers {
+		for _, group := range server.Groups {
+			mappedTeams[group.OrgId] = append(mappedTeams[group.OrgId], group.Teams...)
+		}
+	}
+
+	for orgID, names := range mappedTeams {
+		if len(names) == 0 {
+			continue
+		}
+
+		if err := s.addOrgTeamMemberships(ctx, memberships, orgID, names, extUser); err != nil {
+			return nil, err
+		}
+	}
+
+	return memberships, nil
+}
+
+func (s *LDAPSyncService) addOrgTeamMemberships(ctx context.Context, memberships externalteams.Memberships, orgID int64, names []string, extUser *models.ExternalUserInfo) error {
+	teams, err := externalteams.GetTeamsByName(ctx, s.sqlStore, orgID, names)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if _, ok := teams[name]; !ok {
+			s.log.Warn("Team of LDAP group mapping not found", "orgId", orgID, "team", name)
+		}
+	}
+
+	// users only belong to the teams of the orgs they have a role in
+	wanted := map[int64]bool{}
+	if extUser.OrgRoles[orgID] != "" {
+		for _, name := range extUser.OrgTeams[orgID] {
+			if teamID, ok := teams[name]; ok {
+				wanted[teamID] = true
+			}
+		}
+	}
+
+	for _, teamID := range teams {
+		memberships.Add(orgID, teamID, wanted[teamID])
+	}
+
+	return nil
+}
@@ -3,9 +3,11 @@ package libraryelements
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/components/dashdiffs"
 	"github.com/grafana/grafana/pkg/middleware"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/web"
@@ -20,6 +22,13 @@ func (l *LibraryElementService) registerAPIEndpoints() {
 		entities.Get("/:uid/connections/", middleware.ReqSignedIn, routing.Wrap(l.getConnectionsHandler))
 		entities.Get("/name/:name", middleware.ReqSignedIn, routing.Wrap(l.getByNameHandler))
 		entities.Patch("/:uid", middleware.ReqSignedIn, routing.Wrap(l.patchHandler))
+		entities.Post("/:uid/impact", middleware.ReqSignedIn, routing.Wrap(l.impactHandler))
+		entities.Get("/:uid/versions", middleware.ReqSignedIn, routing.Wrap(l.getVersionsHandler))
+		entities.Get("/:uid/versions/:version", middleware.ReqSignedIn, routing.Wrap(l.getVersionHandler))
+		entities.Post("/:uid/versions/:version/restore", middleware.ReqSignedIn, routing.Wrap(l.restoreVersionHandler))
+		entities.Get("/:uid/diff", middleware.ReqSignedIn, routing.Wrap(l.diffHandler))
+		entities.Post("/:uid/connections/:connectionId/pin", middleware.ReqSignedIn, routing.Wrap(l.pinVersionHandler))
+		entities.Delete("/:uid/connections/:connectionId/pin", middleware.ReqSignedIn, routing.Wrap(l.unpinVersionHandler))
 	})
 }
 
@@ -122,7 +131,7 @@ func (l *LibraryElementService) patchHandler(c *models.ReqContext) response.Resp
 		}
 	}
 
-	element, err := l.patchLibraryElement(c.Req.Context(), c.SignedInUser, cmd, web.Params(c.Req)[":uid"])
+	element, err := l.patchLibraryElement(c.Req.Context(), c.SignedInUser, cmd, web.Params(c.Req)[":uid"], 0)
 	if err != nil {
 		return toLibraryElementError(err, "Failed to update library element")
 	}
@@ -160,6 +169,123 @@ func (l *LibraryElementService) getByNameHandler(c *models.ReqContext) response.
 	return response.JSON(http.StatusOK, LibraryElementArrayResponse{Result: elements})
 }
 
+// impactHandler handles POST /api/library-elements/:uid/impact.
+func (l *LibraryElementService) impactHandler(c *models.ReqContext) response.Response {
+	cmd := PatchLibraryElementCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	impact, err := l.getImpact(c.Req.Context(), c.SignedInUser, web.Params(c.Req)[":uid"], cmd)
+	if err != nil {
+		return toLibraryElementError(err, "Failed to get library element impact")
+	}
+
+	return response.JSON(http.StatusOK, LibraryElementImpactResponse{Result: impact})
+}
+
+// getVersionsHandler handles GET /api/library-elements/:uid/versions.
+func (l *LibraryElementService) getVersionsHandler(c *models.ReqContext) response.Response {
+	versions, err := l.getVersions(c.Req.Context(), c.SignedInUser, web.Params(c.Req)[":uid"])
+	if err != nil {
+		return toLibraryElementError(err, "Failed to get library element versions")
+	}
+
+	return response.JSON(http.StatusOK, LibraryElementVersionsResponse{Result: versions})
+}
+
+// getVersionHandler handles GET /api/library-elements/:uid/versions/:version.
+func (l *LibraryElementService) getVersionHandler(c *models.ReqContext) response.Response {
+	version, err := strconv.ParseInt(web.Params(c.Req)[":version"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "version is invalid", err)
+	}
+
+	element, err := l.getVersion(c.Req.Context(), c.SignedInUser, web.Params(c.Req)[":uid"], version)
+	if err != nil {
+		return toLibraryElementError(err, "Failed to get library element version")
+	}
+
+	return response.JSON(http.StatusOK, LibraryElementVersionResponse{Result: element})
+}
+
+// restoreVersionHandler handles POST /api/library-elements/:uid/versions/:version/restore.
+func (l *LibraryElementService) restoreVersionHandler(c *models.ReqContext) response.Response {
+	version, err := strconv.ParseInt(web.Params(c.Req)[":version"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "version is invalid", err)
+	}
+
+	element, err := l.restoreVersion(c.Req.Context(), c.SignedInUser, web.Params(c.Req)[":uid"], version)
+	if err != nil {
+		return toLibraryElementError(err, "Failed to restore library element version")
+	}
+
+	if element.FolderID != 0 {
+		folder, err := l.folderService.GetFolderByID(c.Req.Context(), c.SignedInUser, element.FolderID, c.OrgId)
+		if err != nil {
+			return response.Error(http.StatusInternalServerError, "failed to get folder", err)
+		}
+		element.FolderUID = folder.Uid
+		element.Meta.FolderUID = folder.Uid
+		element.Meta.FolderName = folder.Title
+	}
+
+	return response.JSON(http.StatusOK, LibraryElementResponse{Result: element})
+}
+
+// diffHandler handles GET /api/library-elements/:uid/diff.
+func (l *LibraryElementService) diffHandler(c *models.ReqContext) response.Response {
+	baseVersion, newVersion := c.QueryInt64("base"), c.QueryInt64("new")
+	if baseVersion <= 0 || newVersion <= 0 {
+		return response.Error(http.StatusBadRequest, "base and new versions are required", nil)
+	}
+
+	diffType := dashdiffs.ParseDiffType(c.Query("diffType"))
+	result, err := l.diffVersions(c.Req.Context(), c.SignedInUser, web.Params(c.Req)[":uid"], baseVersion, newVersion, diffType)
+	if err != nil {
+		return toLibraryElementError(err, "Unable to compute diff")
+	}
+
+	if diffType == dashdiffs.DiffDelta {
+		return response.Respond(http.StatusOK, result.Delta).SetHeader("Content-Type", "application/json")
+	}
+
+	return response.Respond(http.StatusOK, result.Delta).SetHeader("Content-Type", "text/html")
+}
+
+// pinVersionHandler handles POST /api/library-elements/:uid/connections/:connectionId/pin.
+func (l *LibraryElementService) pinVersionHandler(c *models.ReqContext) response.Response {
+	cmd := PinLibraryElementVersionCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	connectionID, err := strconv.ParseInt(web.Params(c.Req)[":connectionId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "connectionId is invalid", err)
+	}
+
+	if err := l.pinVersion(c.Req.Context(), c.SignedInUser, web.Params(c.Req)[":uid"], connectionID, cmd.Version); err != nil {
+		return toLibraryElementError(err, "Failed to pin library element version")
+	}
+
+	return response.Success("Library element version pinned")
+}
+
+// unpinVersionHandler handles DELETE /api/library-elements/:uid/connections/:connectionId/pin.
+func (l *LibraryElementService) unpinVersionHandler(c *models.ReqContext) response.Response {
+	connectionID, err := strconv.ParseInt(web.Params(c.Req)[":connectionId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "connectionId is invalid", err)
+	}
+
+	if err := l.pinVersion(c.Req.Context(), c.SignedInUser, web.Params(c.Req)[":uid"], connectionID, 0); err != nil {
+		return toLibraryElementError(err, "Failed to unpin library element version")
+	}
+
+	return response.Success("Library element version unpinned")
+}
+
 func toLibraryElementError(err error, message string) response.Response {
 	if errors.Is(err, errLibraryElementAlreadyExists) {
 		return response.Error(400, errLibraryElementAlreadyExists.Error(), err)
@@ -188,5 +314,14 @@ func toLibraryElementError(err error, message string) response.Response {
 	if errors.Is(err, errLibraryElementUIDTooLong) {
 		return response.Error(400, errLibraryElementUIDTooLong.Error(), err)
 	}
+	if errors.Is(err, errLibraryElementVersionNotFound) {
+		return response.Error(404, errLibraryElementVersionNotFound.Error(), err)
+	}
+	if errors.Is(err, errLibraryElementVersionInvalid) {
+		return response.Error(400, errLibraryElementVersionInvalid.Error(), err)
+	}
+	if errors.Is(err, errLibraryElementDashboardAccessDenied) {
+		return response.Error(403, errLibraryElementDashboardAccessDenied.Error(), err)
+	}
 	return response.Error(500, message, err)
 }
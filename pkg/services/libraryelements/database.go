@@ -134,7 +134,7 @@ func (l *LibraryElementService) createLibraryElement(c context.Context, signedIn
 			}
 			return err
 		}
-		return nil
+		return insertLibraryElementVersion(session, element, 0)
 	})
 
 	dto := LibraryElementDTO{
@@ -189,6 +189,9 @@ func (l *LibraryElementService) deleteLibraryElement(c context.Context, signedIn
 			return errLibraryElementHasConnections
 		}
 
+		if _, err := session.Exec("DELETE FROM library_element_version WHERE element_id=?", element.ID); err != nil {
+			return err
+		}
 		result, err := session.Exec("DELETE FROM library_element WHERE id=?", element.ID)
 		if err != nil {
 			return err
@@ -437,8 +440,9 @@ func (l *LibraryElementService) handleFolderIDPatches(ctx context.Context, eleme
 	return nil
 }
 
-// patchLibraryElement updates a Library Element.
-func (l *LibraryElementService) patchLibraryElement(c context.Context, signedInUser *models.SignedInUser, cmd PatchLibraryElementCommand, uid string) (LibraryElementDTO, error) {
+// patchLibraryElement updates a Library Element, and saves the patched element as a new version. restoredFrom is
+// the version restored by the patch, if any.
+func (l *LibraryElementService) patchLibraryElement(c context.Context, signedInUser *models.SignedInUser, cmd PatchLibraryElementCommand, uid string, restoredFrom int64) (LibraryElementDTO, error) {
 	var dto LibraryElementDTO
 	if err := l.requireSupportedElementKind(cmd.Kind); err != nil {
 		return LibraryElementDTO{}, err
@@ -504,6 +508,9 @@ func (l *LibraryElementService) patchLibraryElement(c context.Context, signedInU
 		} else if rowsAffected != 1 {
 			return ErrLibraryElementNotFound
 		}
+		if err := insertLibraryElementVersion(session, libraryElement, restoredFrom); err != nil {
+			return err
+		}
 
 		dto = LibraryElementDTO{
 			ID:          libraryElement.ID,
@@ -562,11 +569,12 @@ func (l *LibraryElementService) getConnections(c context.Context, signedInUser *
 
 		for _, connection := range libraryElementConnections {
 			connections = append(connections, LibraryElementConnectionDTO{
-				ID:           connection.ID,
-				Kind:         connection.Kind,
-				ElementID:    connection.ElementID,
-				ConnectionID: connection.ConnectionID,
-				Created:      connection.Created,
+				ID:            connection.ID,
+				Kind:          connection.Kind,
+				ElementID:     connection.ElementID,
+				ConnectionID:  connection.ConnectionID,
+				PinnedVersion: connection.PinnedVersion,
+				Created:       connection.Created,
 				CreatedBy: LibraryElementDTOMetaUser{
 					ID:        connection.CreatedBy,
 					Name:      connection.CreatedByName,
@@ -630,6 +638,34 @@ func (l *LibraryElementService) getElementsForDashboardID(c context.Context, das
 			}
 		}
 
+		// dashboards pinned to a version of an element use that version instead of the current one
+		var pinnedVersions []struct {
+			UID         string `xorm:"uid"`
+			Version     int64
+			Name        string
+			Type        string
+			Description string
+			Model       json.RawMessage
+		}
+		sql = "SELECT le.uid, lev.version, lev.name, lev.type, lev.description, lev.model FROM library_element AS le" +
+			" INNER JOIN " + models.LibraryElementConnectionTableName + " AS lce ON lce.element_id = le.id AND lce.kind=1 AND lce.connection_id=?" +
+			" INNER JOIN library_element_version AS lev ON lev.element_id = le.id AND lev.version = lce.pinned_version"
+		if err := session.SQL(sql, dashboardID).Find(&pinnedVersions); err != nil {
+			return err
+		}
+		for _, pinned := range pinnedVersions {
+			element, ok := libraryElementMap[pinned.UID]
+			if !ok {
+				continue
+			}
+			element.Version = pinned.Version
+			element.Name = pinned.Name
+			element.Type = pinned.Type
+			element.Description = pinned.Description
+			element.Model = pinned.Model
+			libraryElementMap[pinned.UID] = element
+		}
+
 		return nil
 	})
 
@@ -639,6 +675,16 @@ func (l *LibraryElementService) getElementsForDashboardID(c context.Context, das
 // connectElementsToDashboardID adds connections for all elements Library Elements in a Dashboard.
 func (l *LibraryElementService) connectElementsToDashboardID(c context.Context, signedInUser *models.SignedInUser, elementUIDs []string, dashboardID int64) error {
 	err := l.SQLStore.WithTransactionalDbSession(c, func(session *sqlstore.DBSession) error {
+		// the versions pinned in the dashboard are kept
+		var pinnedConnections []libraryElementConnection
+		if err := session.SQL("SELECT * FROM "+models.LibraryElementConnectionTableName+" WHERE kind=1 AND connection_id=? AND pinned_version<>0", dashboardID).Find(&pinnedConnections); err != nil {
+			return err
+		}
+		pinnedVersions := make(map[int64]int64, len(pinnedConnections))
+		for _, connection := range pinnedConnections {
+			pinnedVersions[connection.ElementID] = connection.PinnedVersion
+		}
+
 		_, err := session.Exec("DELETE FROM "+models.LibraryElementConnectionTableName+" WHERE kind=1 AND connection_id=?", dashboardID)
 		if err != nil {
 			return err
@@ -653,11 +699,12 @@ func (l *LibraryElementService) connectElementsToDashboardID(c context.Context,
 			}
 
 			connection := libraryElementConnection{
-				ElementID:    element.ID,
-				Kind:         1,
-				ConnectionID: dashboardID,
-				Created:      time.Now(),
-				CreatedBy:    signedInUser.UserId,
+				ElementID:     element.ID,
+				Kind:          1,
+				ConnectionID:  dashboardID,
+				PinnedVersion: pinnedVersions[element.ID],
+				Created:       time.Now(),
+				CreatedBy:     signedInUser.UserId,
 			}
 			if _, err := session.Insert(&connection); err != nil {
 				if l.SQLStore.Dialect.IsUniqueConstraintViolation(err) {
@@ -733,6 +780,9 @@ func (l *LibraryElementService) deleteLibraryElementsInFolderUID(c context.Conte
 			if err != nil {
 				return err
 			}
+			if _, err := session.Exec("DELETE FROM library_element_version WHERE element_id=?", elementID.ID); err != nil {
+				return err
+			}
 		}
 		if _, err := session.Exec("DELETE FROM library_element WHERE folder_id=? AND org_id=?", folderID, signedInUser.OrgId); err != nil {
 			return err
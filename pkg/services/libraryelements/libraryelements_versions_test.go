@@ -0,0 +1,163 @@
+package libraryelements
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/dashdiffs"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func TestLibraryElementVersions(t *testing.T) {
+	patchTitle := func(t *testing.T, sc scenarioContext, title string, version int64) {
+		t.Helper()
+		cmd := PatchLibraryElementCommand{
+			FolderID: -1,
+			Model:    []byte(`{"title": "` + title + `", "type": "text", "description": "A description"}`),
+			Kind:     int64(models.PanelElement),
+			Version:  version,
+		}
+		sc.ctx.Req = web.SetURLParams(sc.ctx.Req, map[string]string{":uid": sc.initialResult.Result.UID})
+		sc.reqContext.Req.Body = mockRequestBody(cmd)
+		resp := sc.service.patchHandler(sc.reqContext)
+		require.Equal(t, 200, resp.Status())
+	}
+
+	scenarioWithPanel(t, "When an admin patches and restores a library panel, its versions should be kept",
+		func(t *testing.T, sc scenarioContext) {
+			patchTitle(t, sc, "Patched", 1)
+
+			resp := sc.service.getVersionsHandler(sc.reqContext)
+			require.Equal(t, 200, resp.Status())
+			var versions LibraryElementVersionsResponse
+			require.NoError(t, json.Unmarshal(resp.Body(), &versions))
+			require.Len(t, versions.Result, 2)
+			require.Equal(t, int64(2), versions.Result[0].Version)
+			require.Equal(t, int64(1), versions.Result[1].Version)
+			require.Nil(t, versions.Result[0].Model)
+
+			sc.ctx.Req = web.SetURLParams(sc.ctx.Req, map[string]string{":uid": sc.initialResult.Result.UID, ":version": "1"})
+			resp = sc.service.getVersionHandler(sc.reqContext)
+			require.Equal(t, 200, resp.Status())
+			var version LibraryElementVersionResponse
+			require.NoError(t, json.Unmarshal(resp.Body(), &version))
+			require.Equal(t, "Text - Library Panel", modelTitle(t, version.Result.Model))
+
+			resp = sc.service.restoreVersionHandler(sc.reqContext)
+			result := validateAndUnMarshalResponse(t, resp)
+			require.Equal(t, int64(3), result.Result.Version)
+			require.Equal(t, "Text - Library Panel", result.Result.Model["title"])
+
+			versionsDTO, err := sc.service.getVersions(sc.reqContext.Req.Context(), sc.reqContext.SignedInUser, sc.initialResult.Result.UID)
+			require.NoError(t, err)
+			require.Len(t, versionsDTO, 3)
+			require.Equal(t, int64(1), versionsDTO[0].RestoredFrom)
+
+			diff, err := sc.service.diffVersions(sc.reqContext.Req.Context(), sc.reqContext.SignedInUser, sc.initialResult.Result.UID, 1, 2, dashdiffs.DiffDelta)
+			require.NoError(t, err)
+			require.Contains(t, string(diff.Delta), "Patched")
+
+			diff, err = sc.service.diffVersions(sc.reqContext.Req.Context(), sc.reqContext.SignedInUser, sc.initialResult.Result.UID, 1, 3, dashdiffs.DiffDelta)
+			require.NoError(t, err)
+			require.Empty(t, diff.Delta)
+		})
+
+	scenarioWithPanel(t, "When an admin tries to get a version that does not exist, it should fail",
+		func(t *testing.T, sc scenarioContext) {
+			sc.ctx.Req = web.SetURLParams(sc.ctx.Req, map[string]string{":uid": sc.initialResult.Result.UID, ":version": "7"})
+			resp := sc.service.getVersionHandler(sc.reqContext)
+			require.Equal(t, 404, resp.Status())
+			resp = sc.service.restoreVersionHandler(sc.reqContext)
+			require.Equal(t, 404, resp.Status())
+		})
+
+	scenarioWithPanel(t, "When an admin tries to get a version that is not a positive number, it should fail",
+		func(t *testing.T, sc scenarioContext) {
+			sc.ctx.Req = web.SetURLParams(sc.ctx.Req, map[string]string{":uid": sc.initialResult.Result.UID, ":version": "0"})
+			resp := sc.service.getVersionHandler(sc.reqContext)
+			require.Equal(t, 400, resp.Status())
+			resp = sc.service.restoreVersionHandler(sc.reqContext)
+			require.Equal(t, 400, resp.Status())
+
+			sc.ctx.Req.URL = &url.URL{RawQuery: "new=1"}
+			resp = sc.service.diffHandler(sc.reqContext)
+			require.Equal(t, 400, resp.Status())
+
+			_, err := sc.service.diffVersions(sc.reqContext.Req.Context(), sc.reqContext.SignedInUser, sc.initialResult.Result.UID, 0, 1, dashdiffs.DiffDelta)
+			require.ErrorIs(t, err, errLibraryElementVersionInvalid)
+		})
+
+	scenarioWithPanel(t, "When a library panel is pinned in a dashboard, patches should not change it in that dashboard",
+		func(t *testing.T, sc scenarioContext) {
+			dashJSON := map[string]interface{}{
+				"panels": []interface{}{
+					map[string]interface{}{
+						"id":           int64(1),
+						"libraryPanel": map[string]interface{}{"uid": sc.initialResult.Result.UID},
+					},
+				},
+			}
+			dash := createDashboard(t, sc.sqlStore, sc.user, &models.Dashboard{Title: "Pinned", Data: simplejson.NewFromAny(dashJSON)}, sc.folder.Id)
+			err := sc.service.ConnectElementsToDashboard(sc.reqContext.Req.Context(), sc.reqContext.SignedInUser, []string{sc.initialResult.Result.UID}, dash.Id)
+			require.NoError(t, err)
+
+			cmd := PatchLibraryElementCommand{Model: []byte(`{"title": "Patched", "type": "text", "description": "A description"}`)}
+			impact, err := sc.service.getImpact(sc.reqContext.Req.Context(), sc.reqContext.SignedInUser, sc.initialResult.Result.UID, cmd)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), impact.TotalDashboards)
+			require.Len(t, impact.Dashboards, 1)
+			require.Equal(t, dash.Uid, impact.Dashboards[0].UID)
+			require.True(t, impact.Dashboards[0].Affected)
+			require.Contains(t, string(impact.Diff), "Patched")
+
+			sc.ctx.Req = web.SetURLParams(sc.ctx.Req, map[string]string{":uid": sc.initialResult.Result.UID, ":connectionId": strconv.FormatInt(dash.Id, 10)})
+			sc.reqContext.Req.Body = mockRequestBody(PinLibraryElementVersionCommand{Version: 1})
+			resp := sc.service.pinVersionHandler(sc.reqContext)
+			require.Equal(t, 200, resp.Status())
+
+			impact, err = sc.service.getImpact(sc.reqContext.Req.Context(), sc.reqContext.SignedInUser, sc.initialResult.Result.UID, cmd)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), impact.Dashboards[0].PinnedVersion)
+			require.False(t, impact.Dashboards[0].Affected)
+
+			patchTitle(t, sc, "Patched", 1)
+			// saving the dashboard connects its elements again
+			err = sc.service.ConnectElementsToDashboard(sc.reqContext.Req.Context(), sc.reqContext.SignedInUser, []string{sc.initialResult.Result.UID}, dash.Id)
+			require.NoError(t, err)
+
+			elements, err := sc.service.GetElementsForDashboard(sc.reqContext.Req.Context(), dash.Id)
+			require.NoError(t, err)
+			element := elements[sc.initialResult.Result.UID]
+			require.Equal(t, int64(1), element.Version)
+			require.Equal(t, "Text - Library Panel", modelTitle(t, element.Model))
+
+			sc.ctx.Req = web.SetURLParams(sc.ctx.Req, map[string]string{":uid": sc.initialResult.Result.UID, ":connectionId": strconv.FormatInt(dash.Id, 10)})
+			resp = sc.service.unpinVersionHandler(sc.reqContext)
+			require.Equal(t, 200, resp.Status())
+
+			elements, err = sc.service.GetElementsForDashboard(sc.reqContext.Req.Context(), dash.Id)
+			require.NoError(t, err)
+			require.Equal(t, int64(2), elements[sc.initialResult.Result.UID].Version)
+		})
+
+	scenarioWithPanel(t, "When an admin tries to pin a version in a dashboard that is not connected, it should fail",
+		func(t *testing.T, sc scenarioContext) {
+			sc.ctx.Req = web.SetURLParams(sc.ctx.Req, map[string]string{":uid": sc.initialResult.Result.UID, ":connectionId": "42"})
+			sc.reqContext.Req.Body = mockRequestBody(PinLibraryElementVersionCommand{Version: 1})
+			resp := sc.service.pinVersionHandler(sc.reqContext)
+			require.Equal(t, 404, resp.Status())
+		})
+}
+
+func modelTitle(t *testing.T, model json.RawMessage) string {
+	t.Helper()
+	data, err := simplejson.NewJson(model)
+	require.NoError(t, err)
+	return data.Get("title").MustString()
+}
@@ -105,12 +105,13 @@ type LibraryElementDTOMetaUser struct {
 
 // libraryElementConnection is the model for library element connections.
 type libraryElementConnection struct {
-	ID           int64 `xorm:"pk autoincr 'id'"`
-	ElementID    int64 `xorm:"element_id"`
-	Kind         int64 `xorm:"kind"`
-	ConnectionID int64 `xorm:"connection_id"`
-	Created      time.Time
-	CreatedBy    int64
+	ID            int64 `xorm:"pk autoincr 'id'"`
+	ElementID     int64 `xorm:"element_id"`
+	Kind          int64 `xorm:"kind"`
+	ConnectionID  int64 `xorm:"connection_id"`
+	PinnedVersion int64 `xorm:"pinned_version"`
+	Created       time.Time
+	CreatedBy     int64
 }
 
 // libraryElementConnectionWithMeta is the model for library element connections with meta.
@@ -119,6 +120,7 @@ type libraryElementConnectionWithMeta struct {
 	ElementID      int64 `xorm:"element_id"`
 	Kind           int64 `xorm:"kind"`
 	ConnectionID   int64 `xorm:"connection_id"`
+	PinnedVersion  int64 `xorm:"pinned_version"`
 	Created        time.Time
 	CreatedBy      int64
 	CreatedByName  string
@@ -127,14 +129,81 @@ type libraryElementConnectionWithMeta struct {
 
 // LibraryElementConnectionDTO is the frontend DTO for element connections.
 type LibraryElementConnectionDTO struct {
+	ID            int64                     `json:"id"`
+	Kind          int64                     `json:"kind"`
+	ElementID     int64                     `json:"elementId"`
+	ConnectionID  int64                     `json:"connectionId"`
+	PinnedVersion int64                     `json:"pinnedVersion"`
+	Created       time.Time                 `json:"created"`
+	CreatedBy     LibraryElementDTOMetaUser `json:"createdBy"`
+}
+
+// libraryElementVersion is the model for the versions of library elements.
+type libraryElementVersion struct {
+	ID           int64 `xorm:"pk autoincr 'id'"`
+	ElementID    int64 `xorm:"element_id"`
+	Version      int64
+	RestoredFrom int64
+	Name         string
+	Type         string
+	Description  string
+	Model        json.RawMessage
+	Created      time.Time
+	CreatedBy    int64
+}
+
+// libraryElementVersionWithMeta is the model for the versions of library elements with meta.
+type libraryElementVersionWithMeta struct {
+	ID             int64 `xorm:"pk autoincr 'id'"`
+	ElementID      int64 `xorm:"element_id"`
+	Version        int64
+	RestoredFrom   int64
+	Name           string
+	Type           string
+	Description    string
+	Model          json.RawMessage
+	Created        time.Time
+	CreatedBy      int64
+	CreatedByName  string
+	CreatedByEmail string
+}
+
+// LibraryElementVersionDTO is the frontend DTO for the versions of library elements.
+type LibraryElementVersionDTO struct {
 	ID           int64                     `json:"id"`
-	Kind         int64                     `json:"kind"`
 	ElementID    int64                     `json:"elementId"`
-	ConnectionID int64                     `json:"connectionId"`
+	Version      int64                     `json:"version"`
+	RestoredFrom int64                     `json:"restoredFrom"`
+	Name         string                    `json:"name"`
+	Type         string                    `json:"type"`
+	Description  string                    `json:"description"`
+	Model        json.RawMessage           `json:"model,omitempty"`
 	Created      time.Time                 `json:"created"`
 	CreatedBy    LibraryElementDTOMetaUser `json:"createdBy"`
 }
 
+// LibraryElementImpactDTO lists the dashboards connected to a library element, and what a patch would change in them.
+type LibraryElementImpactDTO struct {
+	Version int64 `json:"version"`
+	// Diff is the delta between the current model and the patched model, null when the model is not changed.
+	Diff json.RawMessage `json:"diff"`
+	// Dashboards are the connected dashboards the user can view, TotalDashboards counts all of them.
+	Dashboards      []LibraryElementImpactDashboardDTO `json:"dashboards"`
+	TotalDashboards int64                              `json:"totalDashboards"`
+}
+
+// LibraryElementImpactDashboardDTO is a dashboard connected to a library element. Dashboards pinned to a version
+// are not affected by patches.
+type LibraryElementImpactDashboardDTO struct {
+	ID            int64  `json:"id"`
+	UID           string `json:"uid"`
+	Title         string `json:"title"`
+	FolderTitle   string `json:"folderTitle"`
+	URL           string `json:"url"`
+	PinnedVersion int64  `json:"pinnedVersion"`
+	Affected      bool   `json:"affected"`
+}
+
 var (
 	// errLibraryElementAlreadyExists is an error for when the user tries to add a library element that already exists.
 	errLibraryElementAlreadyExists = errors.New("library element with that name or UID already exists")
@@ -154,6 +223,12 @@ var (
 	errLibraryElementInvalidUID = errors.New("uid contains illegal characters")
 	// errLibraryElementUIDTooLong is an error for when the uid of a library element is invalid
 	errLibraryElementUIDTooLong = errors.New("uid too long, max 40 characters")
+	// errLibraryElementVersionNotFound is an error for when a version of a library element can't be found.
+	errLibraryElementVersionNotFound = errors.New("library element version could not be found")
+	// errLibraryElementVersionInvalid is an error for when the version of a library element is not a positive number.
+	errLibraryElementVersionInvalid = errors.New("library element version is invalid")
+	// errLibraryElementDashboardAccessDenied is an error for when an user pins a version in a dashboard they can't edit.
+	errLibraryElementDashboardAccessDenied = errors.New("access denied to the dashboard")
 )
 
 // Commands
@@ -202,6 +277,12 @@ type PatchLibraryElementCommand struct {
 	UID string `json:"uid"`
 }
 
+// PinLibraryElementVersionCommand is the command for pinning a version of a LibraryElement in a dashboard
+type PinLibraryElementVersionCommand struct {
+	// Version of the library element the dashboard uses.
+	Version int64 `json:"version" binding:"Required"`
+}
+
 // searchLibraryElementsQuery is the query used for searching for Elements
 type searchLibraryElementsQuery struct {
 	perPage       int
@@ -234,6 +315,21 @@ type LibraryElementConnectionsResponse struct {
 	Result []LibraryElementConnectionDTO `json:"result"`
 }
 
+// LibraryElementVersionsResponse is a response struct for an array of LibraryElementVersionDTO.
+type LibraryElementVersionsResponse struct {
+	Result []LibraryElementVersionDTO `json:"result"`
+}
+
+// LibraryElementVersionResponse is a response struct for LibraryElementVersionDTO.
+type LibraryElementVersionResponse struct {
+	Result LibraryElementVersionDTO `json:"result"`
+}
+
+// LibraryElementImpactResponse is a response struct for LibraryElementImpactDTO.
+type LibraryElementImpactResponse struct {
+	Result LibraryElementImpactDTO `json:"result"`
+}
+
 // DeleteLibraryElementResponse is the response struct for deleting a library element.
 type DeleteLibraryElementResponse struct {
 	ID      int64  `json:"id"`
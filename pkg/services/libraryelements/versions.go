@@ -0,0 +1,281 @@
+package libraryelements
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/components/dashdiffs"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// insertLibraryElementVersion saves the element as a version.
+func insertLibraryElementVersion(session *sqlstore.DBSession, element LibraryElement, restoredFrom int64) error {
+	version := libraryElementVersion{
+		ElementID:    element.ID,
+		Version:      element.Version,
+		RestoredFrom: restoredFrom,
+		Name:         element.Name,
+		Type:         element.Type,
+		Description:  element.Description,
+		Model:        element.Model,
+		Created:      element.Updated,
+		CreatedBy:    element.UpdatedBy,
+	}
+	_, err := session.Insert(&version)
+	return err
+}
+
+func getLibraryElementVersions(l *LibraryElementService, session *sqlstore.DBSession, elementID int64, version int64) ([]libraryElementVersionWithMeta, error) {
+	versions := make([]libraryElementVersionWithMeta, 0)
+	builder := sqlstore.SQLBuilder{}
+	builder.Write("SELECT lev.*, u1.login AS created_by_name, u1.email AS created_by_email")
+	builder.Write(" FROM library_element_version AS lev")
+	builder.Write(" LEFT JOIN " + l.SQLStore.Dialect.Quote("user") + " AS u1 ON lev.created_by = u1.id")
+	builder.Write(" WHERE lev.element_id=?", elementID)
+	if version != 0 {
+		builder.Write(" AND lev.version=?", version)
+	}
+	builder.Write(" ORDER BY lev.version DESC")
+	err := session.SQL(builder.GetSQLString(), builder.GetParams()...).Find(&versions)
+	return versions, err
+}
+
+func toLibraryElementVersionDTO(version libraryElementVersionWithMeta) LibraryElementVersionDTO {
+	return LibraryElementVersionDTO{
+		ID:           version.ID,
+		ElementID:    version.ElementID,
+		Version:      version.Version,
+		RestoredFrom: version.RestoredFrom,
+		Name:         version.Name,
+		Type:         version.Type,
+		Description:  version.Description,
+		Model:        version.Model,
+		Created:      version.Created,
+		CreatedBy: LibraryElementDTOMetaUser{
+			ID:        version.CreatedBy,
+			Name:      version.CreatedByName,
+			AvatarURL: dtos.GetGravatarUrl(version.CreatedByEmail),
+		},
+	}
+}
+
+// getVersions gets the versions of a Library Element, latest first, without their model.
+func (l *LibraryElementService) getVersions(c context.Context, signedInUser *models.SignedInUser, uid string) ([]LibraryElementVersionDTO, error) {
+	element, err := l.getLibraryElementByUid(c, signedInUser, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]LibraryElementVersionDTO, 0)
+	err = l.SQLStore.WithDbSession(c, func(session *sqlstore.DBSession) error {
+		versions, err := getLibraryElementVersions(l, session, element.ID, 0)
+		if err != nil {
+			return err
+		}
+		for _, version := range versions {
+			dto := toLibraryElementVersionDTO(version)
+			dto.Model = nil
+			result = append(result, dto)
+		}
+		return nil
+	})
+	return result, err
+}
+
+// getVersion gets a version of a Library Element.
+func (l *LibraryElementService) getVersion(c context.Context, signedInUser *models.SignedInUser, uid string, version int64) (LibraryElementVersionDTO, error) {
+	// getLibraryElementVersions gets all the versions for 0
+	if version <= 0 {
+		return LibraryElementVersionDTO{}, errLibraryElementVersionInvalid
+	}
+
+	element, err := l.getLibraryElementByUid(c, signedInUser, uid)
+	if err != nil {
+		return LibraryElementVersionDTO{}, err
+	}
+
+	var result LibraryElementVersionDTO
+	err = l.SQLStore.WithDbSession(c, func(session *sqlstore.DBSession) error {
+		versions, err := getLibraryElementVersions(l, session, element.ID, version)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			return errLibraryElementVersionNotFound
+		}
+		result = toLibraryElementVersionDTO(versions[0])
+		return nil
+	})
+	return result, err
+}
+
+// restoreVersion restores a version of a Library Element, by saving it as a new version.
+func (l *LibraryElementService) restoreVersion(c context.Context, signedInUser *models.SignedInUser, uid string, version int64) (LibraryElementDTO, error) {
+	element, err := l.getLibraryElementByUid(c, signedInUser, uid)
+	if err != nil {
+		return LibraryElementDTO{}, err
+	}
+	restored, err := l.getVersion(c, signedInUser, uid, version)
+	if err != nil {
+		return LibraryElementDTO{}, err
+	}
+
+	cmd := PatchLibraryElementCommand{
+		FolderID: -1,
+		Name:     restored.Name,
+		Model:    restored.Model,
+		Kind:     element.Kind,
+		Version:  element.Version,
+	}
+	return l.patchLibraryElement(c, signedInUser, cmd, uid, restored.Version)
+}
+
+// diffVersions computes the diff between two versions of a Library Element.
+func (l *LibraryElementService) diffVersions(c context.Context, signedInUser *models.SignedInUser, uid string, baseVersion int64, newVersion int64, diffType dashdiffs.DiffType) (*dashdiffs.Result, error) {
+	base, err := l.getVersion(c, signedInUser, uid, baseVersion)
+	if err != nil {
+		return nil, err
+	}
+	compared, err := l.getVersion(c, signedInUser, uid, newVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return diffModels(c, base.Model, compared.Model, diffType)
+}
+
+// diffModels computes the diff between two models of a Library Element, which is empty when they are identical.
+func diffModels(c context.Context, baseModel []byte, newModel []byte, diffType dashdiffs.DiffType) (*dashdiffs.Result, error) {
+	baseData, err := simplejson.NewJson(baseModel)
+	if err != nil {
+		return nil, err
+	}
+	newData, err := simplejson.NewJson(newModel)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := dashdiffs.CalculateDiff(c, &dashdiffs.Options{DiffType: diffType}, baseData, newData)
+	if errors.Is(err, dashdiffs.ErrNilDiff) {
+		return &dashdiffs.Result{}, nil
+	}
+	return result, err
+}
+
+// getImpact lists the dashboards connected to a Library Element, and the diff the patch would make to the
+// dashboards that are not pinned to a version.
+func (l *LibraryElementService) getImpact(c context.Context, signedInUser *models.SignedInUser, uid string, cmd PatchLibraryElementCommand) (LibraryElementImpactDTO, error) {
+	element, err := l.getLibraryElementByUid(c, signedInUser, uid)
+	if err != nil {
+		return LibraryElementImpactDTO{}, err
+	}
+
+	impact := LibraryElementImpactDTO{Version: element.Version, Dashboards: make([]LibraryElementImpactDashboardDTO, 0)}
+	if cmd.Model != nil {
+		patched := LibraryElement{
+			Name:        cmd.Name,
+			Kind:        element.Kind,
+			Type:        element.Type,
+			Description: element.Description,
+			Model:       cmd.Model,
+		}
+		if cmd.Name == "" {
+			patched.Name = element.Name
+		}
+		if err := syncFieldsWithModel(&patched); err != nil {
+			return LibraryElementImpactDTO{}, err
+		}
+		diff, err := diffModels(c, element.Model, patched.Model, dashdiffs.DiffDelta)
+		if err != nil {
+			return LibraryElementImpactDTO{}, err
+		}
+		if len(diff.Delta) > 0 {
+			impact.Diff = diff.Delta
+		}
+	}
+
+	err = l.SQLStore.WithDbSession(c, func(session *sqlstore.DBSession) error {
+		var dashboards []struct {
+			ID            int64  `xorm:"id"`
+			UID           string `xorm:"uid"`
+			Title         string
+			Slug          string
+			FolderTitle   string
+			PinnedVersion int64
+		}
+		builder := sqlstore.SQLBuilder{}
+		builder.Write("SELECT dashboard.id, dashboard.uid, dashboard.title, dashboard.slug, lec.pinned_version")
+		builder.Write(", coalesce(folder.title, 'General') AS folder_title")
+		builder.Write(" FROM " + models.LibraryElementConnectionTableName + " AS lec")
+		builder.Write(" INNER JOIN dashboard AS dashboard on lec.connection_id = dashboard.id")
+		builder.Write(" LEFT JOIN dashboard AS folder on folder.id = dashboard.folder_id")
+		builder.Write(" WHERE lec.element_id=? AND lec.kind=1", element.ID)
+		if signedInUser.OrgRole != models.ROLE_ADMIN {
+			builder.WriteDashboardPermissionFilter(signedInUser, models.PERMISSION_VIEW)
+		}
+		builder.Write(" ORDER BY dashboard.title ASC")
+		if err := session.SQL(builder.GetSQLString(), builder.GetParams()...).Find(&dashboards); err != nil {
+			return err
+		}
+
+		for _, dashboard := range dashboards {
+			impact.Dashboards = append(impact.Dashboards, LibraryElementImpactDashboardDTO{
+				ID:            dashboard.ID,
+				UID:           dashboard.UID,
+				Title:         dashboard.Title,
+				FolderTitle:   dashboard.FolderTitle,
+				URL:           models.GetDashboardUrl(dashboard.UID, dashboard.Slug),
+				PinnedVersion: dashboard.PinnedVersion,
+				Affected:      dashboard.PinnedVersion == 0 && impact.Diff != nil,
+			})
+		}
+
+		impact.TotalDashboards, err = session.Table(models.LibraryElementConnectionTableName).Where("element_id=? AND kind=1", element.ID).Count()
+		return err
+	})
+	return impact, err
+}
+
+// pinVersion pins a version of a Library Element in a connected dashboard, which then uses that version instead
+// of the current one. Version 0 unpins the dashboard.
+func (l *LibraryElementService) pinVersion(c context.Context, signedInUser *models.SignedInUser, uid string, dashboardID int64, version int64) error {
+	element, err := l.getLibraryElementByUid(c, signedInUser, uid)
+	if err != nil {
+		return err
+	}
+
+	canEdit, err := guardian.New(c, dashboardID, signedInUser.OrgId, signedInUser).CanEdit()
+	if err != nil {
+		return err
+	}
+	if !canEdit {
+		return errLibraryElementDashboardAccessDenied
+	}
+
+	return l.SQLStore.WithTransactionalDbSession(c, func(session *sqlstore.DBSession) error {
+		connected, err := session.Table(models.LibraryElementConnectionTableName).Where("element_id=? AND kind=1 AND connection_id=?", element.ID, dashboardID).Exist()
+		if err != nil {
+			return err
+		}
+		if !connected {
+			return errLibraryElementDashboardNotFound
+		}
+		if version != 0 {
+			versions, err := getLibraryElementVersions(l, session, element.ID, version)
+			if err != nil {
+				return err
+			}
+			if len(versions) == 0 {
+				return errLibraryElementVersionNotFound
+			}
+		}
+
+		_, err = session.Exec("UPDATE "+models.LibraryElementConnectionTableName+" SET pinned_version=? WHERE element_id=? AND kind=1 AND connection_id=?",
+			version, element.ID, dashboardID)
+		return err
+	})
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/comments/commentmodel"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/live/database"
 	"github.com/grafana/grafana/pkg/services/live/features"
@@ -71,19 +72,20 @@ func ProvideService(plugCtxProvider *plugincontext.Provider, cfg *setting.Cfg, r
 	pluginStore plugins.Store, cacheService *localcache.CacheService,
 	dataSourceCache datasources.CacheService, sqlStore *sqlstore.SQLStore, secretsService secrets.Service,
 	usageStatsService usagestats.Service, queryDataService *query.Service, toggles featuremgmt.FeatureToggles,
-	accessControl accesscontrol.AccessControl) (*GrafanaLive, error) {
+	accessControl accesscontrol.AccessControl, labelEnforcementService labelenforcement.Service) (*GrafanaLive, error) {
 	g := &GrafanaLive{
-		Cfg:                   cfg,
-		Features:              toggles,
-		PluginContextProvider: plugCtxProvider,
-		RouteRegister:         routeRegister,
-		pluginStore:           pluginStore,
-		CacheService:          cacheService,
-		DataSourceCache:       dataSourceCache,
-		SQLStore:              sqlStore,
-		SecretsService:        secretsService,
-		queryDataService:      queryDataService,
-		channels:              make(map[string]models.ChannelHandler),
+		Cfg:                     cfg,
+		Features:                toggles,
+		PluginContextProvider:   plugCtxProvider,
+		RouteRegister:           routeRegister,
+		pluginStore:             pluginStore,
+		CacheService:            cacheService,
+		DataSourceCache:         dataSourceCache,
+		SQLStore:                sqlStore,
+		SecretsService:          secretsService,
+		queryDataService:        queryDataService,
+		labelEnforcementService: labelEnforcementService,
+		channels:                make(map[string]models.ChannelHandler),
 		GrafanaScope: CoreGrafanaScope{
 			Features: make(map[string]models.ChannelHandlerFactory),
 		},
@@ -407,6 +409,9 @@ type GrafanaLive struct {
 	SecretsService        secrets.Service
 	pluginStore           plugins.Store
 	queryDataService      *query.Service
+	// labelEnforcementService tells which users are restricted by label rules, and can't tail the logs of the
+	// datasources as the streams are shared by all the subscribers of a channel
+	labelEnforcementService labelenforcement.Service
 
 	node         *centrifuge.Node
 	surveyCaller *survey.Caller
@@ -631,6 +636,17 @@ func (g *GrafanaLive) handleOnSubscribe(ctx context.Context, client *centrifuge.
 		return centrifuge.SubscribeReply{}, centrifuge.ErrorPermissionDenied
 	}
 
+	restricted, err := g.isRestrictedByLabelRules(ctx, user, channel)
+	if err != nil {
+		logger.Error("Error checking label rules", "user", client.UserID(), "client", client.ID(), "channel", e.Channel, "error", err)
+		return centrifuge.SubscribeReply{}, centrifuge.ErrorInternal
+	}
+	if restricted {
+		logger.Info("Error subscribing: datasource queries of the user are restricted by label rules", "user", client.UserID(), "client", client.ID(), "channel", e.Channel)
+		code, text := subscribeStatusToHTTPError(backend.SubscribeStreamStatusPermissionDenied)
+		return centrifuge.SubscribeReply{}, &centrifuge.Error{Code: uint32(code), Message: text}
+	}
+
 	var reply models.SubscribeReply
 	var status backend.SubscribeStreamStatus
 	var ruleFound bool
@@ -812,6 +828,25 @@ func (g *GrafanaLive) handleOnPublish(ctx context.Context, client *centrifuge.Cl
 	return centrifugeReply, nil
 }
 
+// isRestrictedByLabelRules returns true if the channel belongs to a datasource the queries of the user are restricted
+// to some labels of. The label matchers can't be enforced on the streams, which run once for all the subscribers of
+// a channel whatever their rules.
+func (g *GrafanaLive) isRestrictedByLabelRules(ctx context.Context, user *models.SignedInUser, channel string) (bool, error) {
+	addr, err := live.ParseChannel(channel)
+	if err != nil || addr.Scope != live.ScopeDatasource || g.labelEnforcementService == nil {
+		return false, nil
+	}
+	ds, err := g.DataSourceCache.GetDatasourceByUID(ctx, addr.Namespace, user, false)
+	if err != nil {
+		return false, err
+	}
+	matchers, err := g.labelEnforcementService.GetMatchers(ctx, user, ds)
+	if err != nil {
+		return false, err
+	}
+	return len(matchers) > 0, nil
+}
+
 func subscribeStatusToHTTPError(status backend.SubscribeStreamStatus) (int, string) {
 	switch status {
 	case backend.SubscribeStreamStatusNotFound:
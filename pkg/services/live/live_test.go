@@ -7,8 +7,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/grafana/pkg/models"
+	datasources "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement/labelenforcementtest"
 	"github.com/grafana/grafana/pkg/setting"
 
+	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/stretchr/testify/require"
 )
 
@@ -199,3 +203,30 @@ func Test_getHistogramMetric(t *testing.T) {
 		})
 	}
 }
+
+func TestIsRestrictedByLabelRules(t *testing.T) {
+	labelEnforcementService := labelenforcementtest.NewLabelEnforcementServiceFake()
+	g := &GrafanaLive{
+		DataSourceCache: &datasources.FakeCacheService{DataSources: []*models.DataSource{
+			{Uid: "loki", Type: models.DS_LOKI},
+		}},
+		labelEnforcementService: labelEnforcementService,
+	}
+	user := &models.SignedInUser{UserId: 1, OrgId: 1}
+
+	restricted, err := g.isRestrictedByLabelRules(context.Background(), user, "ds/loki/tail/abc")
+	require.NoError(t, err)
+	require.False(t, restricted)
+
+	labelEnforcementService.ExpectedMatchers = []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "tenant", "x")}
+	restricted, err = g.isRestrictedByLabelRules(context.Background(), user, "ds/loki/tail/abc")
+	require.NoError(t, err)
+	require.True(t, restricted)
+
+	restricted, err = g.isRestrictedByLabelRules(context.Background(), user, "grafana/dashboard/uid/abc")
+	require.NoError(t, err)
+	require.False(t, restricted)
+
+	_, err = g.isRestrictedByLabelRules(context.Background(), user, "ds/unknown/tail/abc")
+	require.ErrorIs(t, err, models.ErrDataSourceNotFound)
+}
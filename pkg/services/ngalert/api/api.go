@@ -11,6 +11,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/datasourceproxy"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
@@ -80,6 +81,8 @@ type API struct {
 	Policies             *provisioning.NotificationPolicyService
 	ContactPointService  *provisioning.ContactPointService
 	Templates            *provisioning.TemplateService
+	// LabelEnforcementService restricts the evaluations of the users whose teams have label rules
+	LabelEnforcementService labelenforcement.Service
 }
 
 // RegisterAPIEndpoints registers API handlers
@@ -106,25 +109,27 @@ func (api *API) RegisterAPIEndpoints(m *metrics.API) {
 		api.DatasourceCache,
 		NewLotexRuler(proxy, logger),
 		&RulerSrv{
-			DatasourceCache: api.DatasourceCache,
-			QuotaService:    api.QuotaService,
-			scheduleService: api.Schedule,
-			store:           api.RuleStore,
-			provenanceStore: api.ProvenanceStore,
-			xactManager:     api.TransactionManager,
-			log:             logger,
-			cfg:             &api.Cfg.UnifiedAlerting,
-			ac:              api.AccessControl,
+			DatasourceCache:         api.DatasourceCache,
+			QuotaService:            api.QuotaService,
+			scheduleService:         api.Schedule,
+			store:                   api.RuleStore,
+			provenanceStore:         api.ProvenanceStore,
+			xactManager:             api.TransactionManager,
+			log:                     logger,
+			cfg:                     &api.Cfg.UnifiedAlerting,
+			ac:                      api.AccessControl,
+			labelEnforcementService: api.LabelEnforcementService,
 		},
 	), m)
 	api.RegisterTestingApiEndpoints(NewForkedTestingApi(
 		&TestingApiSrv{
-			AlertingProxy:     proxy,
-			ExpressionService: api.ExpressionService,
-			DatasourceCache:   api.DatasourceCache,
-			log:               logger,
-			accessControl:     api.AccessControl,
-			evaluator:         eval.NewEvaluator(api.Cfg, log.New("ngalert.eval"), api.DatasourceCache, api.SecretsService),
+			AlertingProxy:           proxy,
+			ExpressionService:       api.ExpressionService,
+			DatasourceCache:         api.DatasourceCache,
+			log:                     logger,
+			accessControl:           api.AccessControl,
+			evaluator:               eval.NewEvaluator(api.Cfg, log.New("ngalert.eval"), api.DatasourceCache, api.SecretsService),
+			labelEnforcementService: api.LabelEnforcementService,
 		}), m)
 	api.RegisterConfigurationApiEndpoints(NewForkedConfiguration(
 		&AdminSrv{
@@ -9,6 +9,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement"
 	"github.com/grafana/grafana/pkg/services/ngalert/provisioning"
 	"github.com/grafana/grafana/pkg/services/ngalert/store"
 	"github.com/grafana/grafana/pkg/services/quota"
@@ -29,15 +30,16 @@ import (
 )
 
 type RulerSrv struct {
-	xactManager     provisioning.TransactionManager
-	provenanceStore provisioning.ProvisioningStore
-	store           store.RuleStore
-	DatasourceCache datasources.CacheService
-	QuotaService    *quota.QuotaService
-	scheduleService schedule.ScheduleService
-	log             log.Logger
-	cfg             *setting.UnifiedAlertingSettings
-	ac              accesscontrol.AccessControl
+	xactManager             provisioning.TransactionManager
+	provenanceStore         provisioning.ProvisioningStore
+	store                   store.RuleStore
+	DatasourceCache         datasources.CacheService
+	QuotaService            *quota.QuotaService
+	scheduleService         schedule.ScheduleService
+	log                     log.Logger
+	cfg                     *setting.UnifiedAlertingSettings
+	ac                      accesscontrol.AccessControl
+	labelEnforcementService labelenforcement.Service
 }
 
 var (
@@ -368,7 +370,7 @@ func (srv RulerSrv) RoutePostNameRulesConfig(c *models.ReqContext, ruleGroupConf
 
 // updateAlertRulesInGroup calculates changes (rules to add,update,delete), verifies that the user is authorized to do the calculated changes and updates database.
 // All operations are performed in a single transaction
-//nolint: gocyclo
+// nolint: gocyclo
 func (srv RulerSrv) updateAlertRulesInGroup(c *models.ReqContext, namespace *models.Folder, groupName string, rules []*ngmodels.AlertRule) response.Response {
 	var finalChanges *changes
 	hasAccess := accesscontrol.HasAccess(srv.ac, c)
@@ -386,6 +388,17 @@ func (srv RulerSrv) updateAlertRulesInGroup(c *models.ReqContext, namespace *mod
 			return nil
 		}
 
+		for _, rule := range groupChanges.New {
+			if err := authorizeLabelRulesForRule(tranCtx, c.SignedInUser, rule, srv.DatasourceCache, srv.labelEnforcementService); err != nil {
+				return err
+			}
+		}
+		for _, update := range groupChanges.Update {
+			if err := authorizeLabelRulesForRule(tranCtx, c.SignedInUser, update.New, srv.DatasourceCache, srv.labelEnforcementService); err != nil {
+				return err
+			}
+		}
+
 		authorizedChanges, err := authorizeRuleChanges(namespace, groupChanges, func(evaluator accesscontrol.Evaluator) bool {
 			return hasAccess(accesscontrol.ReqOrgAdminOrEditor, evaluator)
 		})
@@ -16,6 +16,7 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement"
 	apimodels "github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
@@ -25,11 +26,12 @@ import (
 
 type TestingApiSrv struct {
 	*AlertingProxy
-	ExpressionService *expr.Service
-	DatasourceCache   datasources.CacheService
-	log               log.Logger
-	accessControl     accesscontrol.AccessControl
-	evaluator         eval.Evaluator
+	ExpressionService       *expr.Service
+	DatasourceCache         datasources.CacheService
+	log                     log.Logger
+	accessControl           accesscontrol.AccessControl
+	evaluator               eval.Evaluator
+	labelEnforcementService labelenforcement.Service
 }
 
 func (srv TestingApiSrv) RouteTestGrafanaRuleConfig(c *models.ReqContext, body apimodels.TestRulePayload) response.Response {
@@ -53,6 +55,12 @@ func (srv TestingApiSrv) RouteTestGrafanaRuleConfig(c *models.ReqContext, body a
 		return ErrResp(http.StatusBadRequest, err, "invalid condition")
 	}
 
+	queries, err := enforceLabelRules(c.Req.Context(), c.SignedInUser, c.SkipCache, evalCond.Data, srv.DatasourceCache, srv.labelEnforcementService)
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "failed to enforce label rules")
+	}
+	evalCond.Data = queries
+
 	now := body.GrafanaManagedCondition.Now
 	if now.IsZero() {
 		now = timeNow()
@@ -127,7 +135,12 @@ func (srv TestingApiSrv) RouteEvalQueries(c *models.ReqContext, cmd apimodels.Ev
 		return ErrResp(http.StatusBadRequest, err, "invalid queries or expressions")
 	}
 
-	evalResults, err := srv.evaluator.QueriesAndExpressionsEval(c.SignedInUser.OrgId, cmd.Data, now, srv.ExpressionService)
+	queries, err := enforceLabelRules(c.Req.Context(), c.SignedInUser, c.SkipCache, cmd.Data, srv.DatasourceCache, srv.labelEnforcementService)
+	if err != nil {
+		return ErrResp(http.StatusBadRequest, err, "failed to enforce label rules")
+	}
+
+	evalResults, err := srv.evaluator.QueriesAndExpressionsEval(c.SignedInUser.OrgId, queries, now, srv.ExpressionService)
 	if err != nil {
 		return ErrResp(http.StatusBadRequest, err, "Failed to evaluate queries and expressions")
 	}
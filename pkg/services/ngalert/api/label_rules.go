@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement"
+	ngmodels "github.com/grafana/grafana/pkg/services/ngalert/models"
+)
+
+// enforceLabelRules injects the label matchers the teams of the user are restricted to into the queries, like the
+// query service does for the queries of the dashboards and Explore, so that the evaluations requested by the user
+// only return the data the user can query.
+func enforceLabelRules(ctx context.Context, user *models.SignedInUser, skipCache bool, data []ngmodels.AlertQuery,
+	datasourceCache datasources.CacheService, labelEnforcementService labelenforcement.Service) ([]ngmodels.AlertQuery, error) {
+	if labelEnforcementService == nil {
+		return data, nil
+	}
+
+	enforced := make([]ngmodels.AlertQuery, 0, len(data))
+	for _, query := range data {
+		ds, matchers, err := getLabelMatchers(ctx, user, skipCache, query, datasourceCache, labelEnforcementService)
+		if err != nil {
+			return nil, err
+		}
+		if len(matchers) == 0 {
+			enforced = append(enforced, query)
+			continue
+		}
+
+		model, err := labelenforcement.EnforceQuery(ds.Type, query.Model, matchers)
+		if err != nil {
+			return nil, fmt.Errorf("query %s cannot be restricted to the labels of your teams: %w", query.RefID, err)
+		}
+		// the query is copied field by field, as it caches the properties of the model it was created with
+		enforced = append(enforced, ngmodels.AlertQuery{
+			RefID:             query.RefID,
+			QueryType:         query.QueryType,
+			RelativeTimeRange: query.RelativeTimeRange,
+			DatasourceUID:     query.DatasourceUID,
+			Model:             model,
+		})
+	}
+	return enforced, nil
+}
+
+// authorizeLabelRulesForRule returns ErrAuthorization if the rule queries a datasource the queries of the user are
+// restricted to some labels of. The rules are evaluated without a user, so the label matchers of the user who saved
+// them can't be enforced.
+func authorizeLabelRulesForRule(ctx context.Context, user *models.SignedInUser, rule *ngmodels.AlertRule,
+	datasourceCache datasources.CacheService, labelEnforcementService labelenforcement.Service) error {
+	if labelEnforcementService == nil {
+		return nil
+	}
+
+	for _, query := range rule.Data {
+		_, matchers, err := getLabelMatchers(ctx, user, false, query, datasourceCache, labelEnforcementService)
+		if err != nil {
+			return err
+		}
+		if len(matchers) > 0 {
+			return fmt.Errorf("%w to save alert rule '%s' because the queries of the user to the data source %s are restricted by label rules", ErrAuthorization, rule.Title, query.DatasourceUID)
+		}
+	}
+	return nil
+}
+
+func getLabelMatchers(ctx context.Context, user *models.SignedInUser, skipCache bool, query ngmodels.AlertQuery,
+	datasourceCache datasources.CacheService, labelEnforcementService labelenforcement.Service) (*models.DataSource, []*labels.Matcher, error) {
+	isExpression, err := query.IsExpression()
+	if err != nil || isExpression {
+		return nil, nil, err
+	}
+
+	ds, err := datasourceCache.GetDatasourceByUID(ctx, query.DatasourceUID, user, skipCache)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid query %s: %w: %s", query.RefID, err, query.DatasourceUID)
+	}
+	matchers, err := labelEnforcementService.GetMatchers(ctx, user, ds)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ds, matchers, nil
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/expr"
+	models2 "github.com/grafana/grafana/pkg/models"
+	acMock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	fakes "github.com/grafana/grafana/pkg/services/datasources/fakes"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement/labelenforcementtest"
+	"github.com/grafana/grafana/pkg/services/ngalert/api/tooling/definitions"
+	"github.com/grafana/grafana/pkg/services/ngalert/eval"
+	"github.com/grafana/grafana/pkg/services/ngalert/models"
+	"github.com/grafana/grafana/pkg/services/ngalert/schedule"
+	"github.com/grafana/grafana/pkg/services/ngalert/store"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func TestRouteEvalQueriesWithLabelRules(t *testing.T) {
+	rc := &models2.ReqContext{
+		Context:      &web.Context{Req: &http.Request{}},
+		IsSignedIn:   true,
+		SignedInUser: &models2.SignedInUser{UserId: 1, OrgId: 1},
+	}
+	ds := &fakes.FakeCacheService{DataSources: []*models2.DataSource{
+		{Uid: "prometheus", Type: models2.DS_PROMETHEUS},
+	}}
+	labelEnforcementService := labelenforcementtest.NewLabelEnforcementServiceFake()
+	labelEnforcementService.ExpectedMatchers = []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "tenant", "x")}
+
+	query := func(expr string) models.AlertQuery {
+		q := models.GenerateAlertQuery()
+		q.DatasourceUID = "prometheus"
+		q.Model = json.RawMessage(`{"refId":"A","expr":"` + expr + `"}`)
+		return q
+	}
+
+	t.Run("should inject the label matchers into the evaluated queries", func(t *testing.T) {
+		var evaluated []models.AlertQuery
+		evaluator := &eval.FakeEvaluator{}
+		evaluator.EXPECT().QueriesAndExpressionsEval(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Run(func(_ int64, data []models.AlertQuery, _ time.Time, _ *expr.Service) { evaluated = data }).
+			Return(&backend.QueryDataResponse{}, nil)
+
+		srv := createTestingApiSrv(ds, acMock.New().WithDisabled(), evaluator)
+		srv.labelEnforcementService = labelEnforcementService
+
+		q := query("up")
+		// caches the properties of the model
+		_, err := q.IsExpression()
+		require.NoError(t, err)
+		response := srv.RouteEvalQueries(rc, definitions.EvalQueriesPayload{Data: []models.AlertQuery{q}})
+		require.Equal(t, http.StatusOK, response.Status())
+
+		require.Len(t, evaluated, 1)
+		model, err := evaluated[0].GetModel()
+		require.NoError(t, err)
+		require.Contains(t, string(model), `up{tenant=\"x\"}`)
+	})
+
+	t.Run("should reject the queries the label matchers cannot be injected into", func(t *testing.T) {
+		evaluator := &eval.FakeEvaluator{}
+		srv := createTestingApiSrv(ds, acMock.New().WithDisabled(), evaluator)
+		srv.labelEnforcementService = labelEnforcementService
+
+		response := srv.RouteEvalQueries(rc, definitions.EvalQueriesPayload{Data: []models.AlertQuery{query("up{")}})
+		require.Equal(t, http.StatusBadRequest, response.Status())
+		evaluator.AssertNotCalled(t, "QueriesAndExpressionsEval", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestUpdateAlertRulesWithLabelRules(t *testing.T) {
+	orgID := rand.Int63()
+	folder := randFolder()
+	ruleStore := store.NewFakeRuleStore(t)
+	ruleStore.Folders[orgID] = append(ruleStore.Folders[orgID], folder)
+	ruleStore.Hook = func(cmd interface{}) error {
+		if _, ok := cmd.([]models.AlertRule); ok {
+			return errors.New("rules should not be inserted")
+		}
+		return nil
+	}
+	rules := models.GenerateAlertRules(1, models.AlertRuleGen(withOrgID(orgID), withNamespace(folder), withGroup("group")))
+	rules[0].UID = ""
+
+	dataSources := []*models2.DataSource{}
+	for _, query := range rules[0].Data {
+		dataSources = append(dataSources, &models2.DataSource{Uid: query.DatasourceUID, Type: models2.DS_PROMETHEUS})
+	}
+	labelEnforcementService := labelenforcementtest.NewLabelEnforcementServiceFake()
+	labelEnforcementService.ExpectedMatchers = []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "tenant", "x")}
+
+	srv := createService(acMock.New().WithDisabled(), ruleStore, &schedule.FakeScheduleService{})
+	srv.DatasourceCache = &fakes.FakeCacheService{DataSources: dataSources}
+	srv.labelEnforcementService = labelEnforcementService
+
+	request := createRequestContext(orgID, models2.ROLE_EDITOR, nil)
+	request.Req = request.Req.WithContext(context.Background())
+	response := srv.updateAlertRulesInGroup(request, folder, "group", rules)
+	require.Equalf(t, http.StatusUnauthorized, response.Status(), string(response.Body()))
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/grafana/grafana/pkg/services/dashboards"
 	"github.com/grafana/grafana/pkg/services/datasourceproxy"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement"
 	"github.com/grafana/grafana/pkg/services/ngalert/api"
 	"github.com/grafana/grafana/pkg/services/ngalert/eval"
 	"github.com/grafana/grafana/pkg/services/ngalert/metrics"
@@ -33,22 +34,23 @@ import (
 func ProvideService(cfg *setting.Cfg, dataSourceCache datasources.CacheService, routeRegister routing.RouteRegister,
 	sqlStore *sqlstore.SQLStore, kvStore kvstore.KVStore, expressionService *expr.Service, dataProxy *datasourceproxy.DataSourceProxyService,
 	quotaService *quota.QuotaService, secretsService secrets.Service, notificationService notifications.Service, m *metrics.NGAlert,
-	folderService dashboards.FolderService, ac accesscontrol.AccessControl) (*AlertNG, error) {
+	folderService dashboards.FolderService, ac accesscontrol.AccessControl, labelEnforcementService labelenforcement.Service) (*AlertNG, error) {
 	ng := &AlertNG{
-		Cfg:                 cfg,
-		DataSourceCache:     dataSourceCache,
-		RouteRegister:       routeRegister,
-		SQLStore:            sqlStore,
-		KVStore:             kvStore,
-		ExpressionService:   expressionService,
-		DataProxy:           dataProxy,
-		QuotaService:        quotaService,
-		SecretsService:      secretsService,
-		Metrics:             m,
-		Log:                 log.New("ngalert"),
-		NotificationService: notificationService,
-		folderService:       folderService,
-		accesscontrol:       ac,
+		Cfg:                     cfg,
+		DataSourceCache:         dataSourceCache,
+		RouteRegister:           routeRegister,
+		SQLStore:                sqlStore,
+		KVStore:                 kvStore,
+		ExpressionService:       expressionService,
+		DataProxy:               dataProxy,
+		QuotaService:            quotaService,
+		SecretsService:          secretsService,
+		Metrics:                 m,
+		Log:                     log.New("ngalert"),
+		NotificationService:     notificationService,
+		folderService:           folderService,
+		accesscontrol:           ac,
+		labelEnforcementService: labelEnforcementService,
 	}
 
 	if ng.IsDisabled() {
@@ -83,6 +85,8 @@ type AlertNG struct {
 	// Alerting notification services
 	MultiOrgAlertmanager *notifier.MultiOrgAlertmanager
 	accesscontrol        accesscontrol.AccessControl
+	// labelEnforcementService restricts the evaluations of the users whose teams have label rules
+	labelEnforcementService labelenforcement.Service
 }
 
 func (ng *AlertNG) init() error {
@@ -143,26 +147,27 @@ func (ng *AlertNG) init() error {
 	templateService := provisioning.NewTemplateService(store, store, store, ng.Log)
 
 	api := api.API{
-		Cfg:                  ng.Cfg,
-		DatasourceCache:      ng.DataSourceCache,
-		RouteRegister:        ng.RouteRegister,
-		ExpressionService:    ng.ExpressionService,
-		Schedule:             ng.schedule,
-		DataProxy:            ng.DataProxy,
-		QuotaService:         ng.QuotaService,
-		SecretsService:       ng.SecretsService,
-		TransactionManager:   store,
-		InstanceStore:        store,
-		RuleStore:            store,
-		AlertingStore:        store,
-		AdminConfigStore:     store,
-		ProvenanceStore:      store,
-		MultiOrgAlertmanager: ng.MultiOrgAlertmanager,
-		StateManager:         ng.stateManager,
-		AccessControl:        ng.accesscontrol,
-		Policies:             policyService,
-		ContactPointService:  contactPointService,
-		Templates:            templateService,
+		Cfg:                     ng.Cfg,
+		DatasourceCache:         ng.DataSourceCache,
+		RouteRegister:           ng.RouteRegister,
+		ExpressionService:       ng.ExpressionService,
+		Schedule:                ng.schedule,
+		DataProxy:               ng.DataProxy,
+		QuotaService:            ng.QuotaService,
+		SecretsService:          ng.SecretsService,
+		TransactionManager:      store,
+		InstanceStore:           store,
+		RuleStore:               store,
+		AlertingStore:           store,
+		AdminConfigStore:        store,
+		ProvenanceStore:         store,
+		MultiOrgAlertmanager:    ng.MultiOrgAlertmanager,
+		StateManager:            ng.stateManager,
+		AccessControl:           ng.accesscontrol,
+		Policies:                policyService,
+		ContactPointService:     contactPointService,
+		Templates:               templateService,
+		LabelEnforcementService: ng.labelEnforcementService,
 	}
 	api.RegisterAPIEndpoints(ng.Metrics.GetAPIMetrics())
 
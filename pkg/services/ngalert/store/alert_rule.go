@@ -295,6 +295,7 @@ func (st DBstore) GetUserVisibleNamespaces(ctx context.Context, orgID int64, use
 		},
 	}
 
+	var nestedFolderIDs []int64
 	var page int64 = 1
 	for {
 		query := searchQuery
@@ -313,13 +314,38 @@ func (st DBstore) GetUserVisibleNamespaces(ctx context.Context, orgID int64, use
 				continue
 			}
 			namespaceMap[hit.UID] = &models.Folder{
-				Id:    hit.ID,
-				Uid:   hit.UID,
-				Title: hit.Title,
+				Id:       hit.ID,
+				Uid:      hit.UID,
+				Title:    hit.Title,
+				ParentId: hit.FolderID,
+			}
+			if hit.FolderID > 0 {
+				nestedFolderIDs = append(nestedFolderIDs, hit.ID)
 			}
 		}
 		page += 1
 	}
+
+	if len(nestedFolderIDs) == 0 {
+		return namespaceMap, nil
+	}
+
+	// namespaces of nested folders are named after the path of the folder, so that they can be found by title
+	paths, err := st.SQLStore.GetFolderPaths(ctx, orgID, nestedFolderIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, folder := range namespaceMap {
+		path, ok := paths[folder.Id]
+		if !ok || len(path) < 2 {
+			continue
+		}
+		titles := make([]string, 0, len(path))
+		for _, item := range path {
+			titles = append(titles, item.Title)
+		}
+		folder.Title = strings.Join(titles, "/")
+	}
 	return namespaceMap, nil
 }
 
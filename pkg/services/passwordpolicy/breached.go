@@ -0,0 +1,134 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1" // #nosec G505 the breached password lists are indexed by SHA-1 hashes
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	hashLength   = 40
+	prefixLength = 5
+)
+
+// breachedList is a list of breached passwords, looked up by the SHA-1 hashes of the passwords split into a prefix
+// and a suffix, like the k-anonymity range API of Have I Been Pwned. The passwords never leave the server.
+type breachedList interface {
+	contains(password string) (bool, error)
+}
+
+func loadBreachedList(path string) (breachedList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &rangeDirList{dir: path}, nil
+	}
+
+	// #nosec G304 the path comes from the configuration
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	return readHashList(file)
+}
+
+// hashList is a list of hashes loaded in memory, grouped by prefix
+type hashList map[string]map[string]struct{}
+
+func readHashList(reader io.Reader) (hashList, error) {
+	list := hashList{}
+	scanner := bufio.NewScanner(reader)
+	for line := 1; scanner.Scan(); line++ {
+		hash, ok := parseHashLine(scanner.Text(), hashLength)
+		if !ok {
+			if strings.TrimSpace(scanner.Text()) == "" {
+				continue
+			}
+			return nil, fmt.Errorf("invalid SHA-1 hash at line %d", line)
+		}
+
+		prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+		if list[prefix] == nil {
+			list[prefix] = map[string]struct{}{}
+		}
+		list[prefix][suffix] = struct{}{}
+	}
+	return list, scanner.Err()
+}
+
+func (l hashList) contains(password string) (bool, error) {
+	prefix, suffix := hashPassword(password)
+	_, ok := l[prefix][suffix]
+	return ok, nil
+}
+
+// rangeDirList is a directory of range files, named after the prefix of their hashes and listing their suffixes,
+// of which only the file of the prefix of a password is read
+type rangeDirList struct {
+	dir string
+}
+
+func (l *rangeDirList) contains(password string) (bool, error) {
+	prefix, suffix := hashPassword(password)
+
+	for _, name := range []string{prefix, prefix + ".txt"} {
+		// #nosec G304 the prefix is the hex encoding of a hash
+		file, err := os.Open(filepath.Join(l.dir, name))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return false, err
+		}
+
+		found, err := containsSuffix(file, suffix)
+		_ = file.Close()
+		return found, err
+	}
+	return false, nil
+}
+
+func containsSuffix(reader io.Reader, suffix string) (bool, error) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if hash, ok := parseHashLine(scanner.Text(), hashLength-prefixLength); ok && hash == suffix {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// parseHashLine returns the uppercase hash of a line of a list, ignoring the count of occurrences following it
+func parseHashLine(line string, length int) (string, bool) {
+	hash := strings.TrimSpace(line)
+	if i := strings.IndexByte(hash, ':'); i >= 0 {
+		hash = hash[:i]
+	}
+	if len(hash) != length {
+		return "", false
+	}
+	for _, c := range hash {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return "", false
+		}
+	}
+	return strings.ToUpper(hash), true
+}
+
+func hashPassword(password string) (string, string) {
+	// #nosec G401 the breached password lists are indexed by SHA-1 hashes
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return hash[:prefixLength], hash[prefixLength:]
+}
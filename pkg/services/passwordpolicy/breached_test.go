@@ -0,0 +1,64 @@
+package passwordpolicy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sha1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+const (
+	breachedPassword = "password"
+	breachedPrefix   = "5BAA6"
+	breachedSuffix   = "1E4C9B93F3F0682250B6CF8331B7EE68FD8"
+)
+
+func TestHashPassword(t *testing.T) {
+	prefix, suffix := hashPassword(breachedPassword)
+	assert.Equal(t, breachedPrefix, prefix)
+	assert.Equal(t, breachedSuffix, suffix)
+}
+
+func TestHashList(t *testing.T) {
+	t.Run("finds the passwords of the list, whatever the case and the counts", func(t *testing.T) {
+		list, err := readHashList(strings.NewReader(strings.ToLower(breachedPrefix+breachedSuffix) + ":3861493\n\n" +
+			"7C4A8D09CA3762AF61E59520943DC26494F8941B\n"))
+		require.NoError(t, err)
+
+		found, err := list.contains(breachedPassword)
+		require.NoError(t, err)
+		assert.True(t, found)
+
+		found, err = list.contains("Correct-Horse-42")
+		require.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("rejects invalid hashes", func(t *testing.T) {
+		_, err := readHashList(strings.NewReader(breachedPrefix + breachedSuffix + "\nnot-a-hash\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "line 2")
+	})
+}
+
+func TestRangeDirList(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, breachedPrefix+".txt"), []byte("0018A45C4D1DEF81644B54AB7F969B88D65:1\n"+
+		breachedSuffix+":3861493\n"), 0600)
+	require.NoError(t, err)
+
+	list, err := loadBreachedList(dir)
+	require.NoError(t, err)
+
+	found, err := list.contains(breachedPassword)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = list.contains("Correct-Horse-42")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
@@ -0,0 +1,83 @@
+package passwordpolicy
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// passwordHistory is a password a user has set, hashed with the salt of the user at that time
+type passwordHistory struct {
+	ID       int64 `xorm:"pk autoincr 'id'"`
+	UserID   int64 `xorm:"user_id"`
+	Password string
+	Salt     string
+	Created  time.Time
+}
+
+func (passwordHistory) TableName() string {
+	return "user_password_history"
+}
+
+// addPasswordHistory adds the password to the history of the user, and deletes its passwords past the ones to keep
+func (s *PasswordPolicyService) addPasswordHistory(ctx context.Context, userID int64, hashedPassword string, salt string,
+	created time.Time, keep int) error {
+	return s.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		entry := &passwordHistory{UserID: userID, Password: hashedPassword, Salt: salt, Created: created}
+		if _, err := sess.Insert(entry); err != nil {
+			return err
+		}
+
+		var ids []int64
+		if err := sess.Table("user_password_history").Cols("id").Where("user_id = ?", userID).
+			Desc("created", "id").Find(&ids); err != nil {
+			return err
+		}
+		if len(ids) <= keep {
+			return nil
+		}
+
+		_, err := sess.In("id", ids[keep:]).Delete(&passwordHistory{})
+		return err
+	})
+}
+
+func (s *PasswordPolicyService) getLastPassword(ctx context.Context, userID int64) (*passwordHistory, error) {
+	var entry *passwordHistory
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		result := &passwordHistory{}
+		exists, err := sess.Where("user_id = ?", userID).Desc("created", "id").Get(result)
+		if err != nil {
+			return err
+		}
+		if exists {
+			entry = result
+		}
+		return nil
+	})
+	return entry, err
+}
+
+// isReused checks if the password is one of the last passwords of the user kept by the policy
+func (s *PasswordPolicyService) isReused(ctx context.Context, userID int64, password string) (bool, error) {
+	entries := make([]*passwordHistory, 0)
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("user_id = ?", userID).Desc("created", "id").Limit(s.cfg.PasswordPolicyHistoryCount).Find(&entries)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, entry := range entries {
+		hashed, err := util.EncodePassword(password, entry.Salt)
+		if err != nil {
+			return false, err
+		}
+		if hashed == entry.Password {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,231 @@
+package passwordpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	challengeTTL         = 10 * time.Minute
+	challengeCachePrefix = "password-change-"
+	challengeTokenLength = 32
+)
+
+var (
+	// ErrPolicyViolation is wrapped by the errors of the passwords that do not comply with the policy, which can be
+	// shown to the user
+	ErrPolicyViolation   = errors.New("password does not meet the password policy")
+	ErrChallengeNotFound = errors.New("password change not found or expired")
+	ErrPasswordsMismatch = errors.New("passwords do not match")
+)
+
+func init() {
+	remotecache.Register(&challenge{})
+}
+
+// challenge is the state of a login waiting for the user to change its expired password
+type challenge struct {
+	UserID  int64
+	Expires time.Time
+}
+
+// Service enforces the password policy on the passwords of the users that log in with a Grafana password
+type Service interface {
+	// Validate checks the password the user wants to set against the policy. The user is not created yet if its
+	// id is 0.
+	Validate(ctx context.Context, password string, user *models.User) error
+	// RecordPassword adds the hashed password the user has just set to its password history.
+	RecordPassword(ctx context.Context, userID int64, hashedPassword string, salt string) error
+	// IsExpired checks if the user must change its password before logging in.
+	IsExpired(ctx context.Context, user *models.User) (bool, error)
+	// CreateChangeChallenge creates the step of a login changing the expired password of the user.
+	CreateChangeChallenge(ctx context.Context, user *models.User) (string, error)
+	// ChangeExpiredPassword changes the expired password of the user of the challenge, and returns the id of the
+	// user to log in.
+	ChangeExpiredPassword(ctx context.Context, cmd *ChangeExpiredPasswordCommand) (int64, error)
+}
+
+type ChangeExpiredPasswordCommand struct {
+	Token           string `json:"challenge"`
+	NewPassword     string `json:"newPassword"`
+	ConfirmPassword string `json:"confirmPassword"`
+}
+
+type PasswordPolicyService struct {
+	cfg         *setting.Cfg
+	sqlStore    *sqlstore.SQLStore
+	remoteCache *remotecache.RemoteCache
+	breached    breachedList
+	log         log.Logger
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, remoteCache *remotecache.RemoteCache) (*PasswordPolicyService, error) {
+	s := &PasswordPolicyService{
+		cfg:         cfg,
+		sqlStore:    sqlStore,
+		remoteCache: remoteCache,
+		log:         log.New("passwordpolicy"),
+	}
+
+	if cfg.PasswordPolicyEnabled && cfg.PasswordPolicyBreachedFile != "" {
+		breached, err := loadBreachedList(cfg.PasswordPolicyBreachedFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load breached passwords: %w", err)
+		}
+		s.breached = breached
+	}
+
+	return s, nil
+}
+
+func (s *PasswordPolicyService) Validate(ctx context.Context, password string, user *models.User) error {
+	if !s.cfg.PasswordPolicyEnabled {
+		return nil
+	}
+
+	if err := s.checkRequirements(password); err != nil {
+		return err
+	}
+
+	if s.cfg.PasswordPolicyRejectUserInfo && containsUserInfo(password, user) {
+		return fmt.Errorf("%w: it must not contain your login or email", ErrPolicyViolation)
+	}
+
+	if s.breached != nil {
+		breached, err := s.breached.contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			return fmt.Errorf("%w: it appears in a list of breached passwords", ErrPolicyViolation)
+		}
+	}
+
+	if user == nil || user.Id == 0 {
+		return nil
+	}
+
+	// the current password cannot be kept, even without a history
+	if user.Password != "" {
+		hashed, err := util.EncodePassword(password, user.Salt)
+		if err != nil {
+			return err
+		}
+		if hashed == user.Password {
+			return fmt.Errorf("%w: it must be different from your current password", ErrPolicyViolation)
+		}
+	}
+
+	if s.cfg.PasswordPolicyHistoryCount > 0 {
+		reused, err := s.isReused(ctx, user.Id, password)
+		if err != nil {
+			return err
+		}
+		if reused {
+			return fmt.Errorf("%w: it must not be one of your last %d passwords", ErrPolicyViolation, s.cfg.PasswordPolicyHistoryCount)
+		}
+	}
+
+	return nil
+}
+
+func (s *PasswordPolicyService) RecordPassword(ctx context.Context, userID int64, hashedPassword string, salt string) error {
+	if !s.cfg.PasswordPolicyEnabled {
+		return nil
+	}
+
+	// the last password is always kept, as it is when it was set that it expires
+	keep := s.cfg.PasswordPolicyHistoryCount
+	if keep < 1 {
+		keep = 1
+	}
+	return s.addPasswordHistory(ctx, userID, hashedPassword, salt, time.Now(), keep)
+}
+
+func (s *PasswordPolicyService) IsExpired(ctx context.Context, user *models.User) (bool, error) {
+	if !s.cfg.PasswordPolicyEnabled || s.cfg.PasswordPolicyMaxAgeDays <= 0 {
+		return false, nil
+	}
+
+	entry, err := s.getLastPassword(ctx, user.Id)
+	if err != nil {
+		return false, err
+	}
+
+	// the passwords set before the policy was enabled, or reset with the CLI, expire from the next login of their
+	// user
+	if entry == nil || entry.Password != user.Password {
+		return false, s.RecordPassword(ctx, user.Id, user.Password, user.Salt)
+	}
+
+	maxAge := time.Duration(s.cfg.PasswordPolicyMaxAgeDays) * 24 * time.Hour
+	return time.Since(entry.Created) > maxAge, nil
+}
+
+func (s *PasswordPolicyService) CreateChangeChallenge(ctx context.Context, user *models.User) (string, error) {
+	token, err := util.GetRandomString(challengeTokenLength)
+	if err != nil {
+		return "", err
+	}
+
+	item := &challenge{UserID: user.Id, Expires: time.Now().Add(challengeTTL)}
+	if err := s.remoteCache.Set(ctx, challengeCachePrefix+token, item, challengeTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *PasswordPolicyService) ChangeExpiredPassword(ctx context.Context, cmd *ChangeExpiredPasswordCommand) (int64, error) {
+	key := challengeCachePrefix + cmd.Token
+	value, err := s.remoteCache.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return 0, ErrChallengeNotFound
+		}
+		return 0, err
+	}
+	item, ok := value.(*challenge)
+	if !ok || time.Now().After(item.Expires) {
+		return 0, ErrChallengeNotFound
+	}
+
+	if cmd.NewPassword != cmd.ConfirmPassword {
+		return 0, ErrPasswordsMismatch
+	}
+
+	query := models.GetUserByIdQuery{Id: item.UserID}
+	if err := s.sqlStore.GetUserById(ctx, &query); err != nil {
+		return 0, err
+	}
+	user := query.Result
+
+	// the challenge is kept when the new password is rejected, so that the user can choose another one
+	if err := s.Validate(ctx, cmd.NewPassword, user); err != nil {
+		return 0, err
+	}
+
+	hashed, err := util.EncodePassword(cmd.NewPassword, user.Salt)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.sqlStore.ChangeUserPassword(ctx, &models.ChangeUserPasswordCommand{UserId: user.Id, NewPassword: hashed}); err != nil {
+		return 0, err
+	}
+	if err := s.RecordPassword(ctx, user.Id, hashed, user.Salt); err != nil {
+		return 0, err
+	}
+
+	if err := s.remoteCache.Delete(ctx, key); err != nil {
+		s.log.Warn("Failed to delete password change challenge", "error", err)
+	}
+	return user.Id, nil
+}
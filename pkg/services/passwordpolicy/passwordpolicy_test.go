@@ -0,0 +1,137 @@
+//go:build integration
+// +build integration
+
+package passwordpolicy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func setupTestService(t *testing.T) (*PasswordPolicyService, *models.User) {
+	t.Helper()
+
+	sqlStore := sqlstore.InitTestDB(t)
+	cfg := sqlStore.Cfg
+	cfg.PasswordPolicyEnabled = true
+	cfg.PasswordPolicyHistoryCount = 2
+	cfg.PasswordPolicyMaxAgeDays = 90
+
+	s, err := ProvideService(cfg, sqlStore, remotecache.NewFakeStore(t))
+	require.NoError(t, err)
+
+	user, err := sqlStore.CreateUser(context.Background(), models.CreateUserCommand{
+		Login:    "policy-user",
+		Email:    "policy-user@example.com",
+		Password: "Initial-Password-1",
+	})
+	require.NoError(t, err)
+	return s, user
+}
+
+// setPassword changes the password of the user the way the API does.
+func setPassword(t *testing.T, s *PasswordPolicyService, user *models.User, password string) {
+	t.Helper()
+
+	require.NoError(t, s.Validate(context.Background(), password, user))
+	hashed, err := util.EncodePassword(password, user.Salt)
+	require.NoError(t, err)
+	err = s.sqlStore.ChangeUserPassword(context.Background(), &models.ChangeUserPasswordCommand{UserId: user.Id, NewPassword: hashed})
+	require.NoError(t, err)
+	require.NoError(t, s.RecordPassword(context.Background(), user.Id, hashed, user.Salt))
+	user.Password = hashed
+}
+
+func TestIntegrationPasswordHistory(t *testing.T) {
+	s, user := setupTestService(t)
+	ctx := context.Background()
+
+	err := s.Validate(ctx, "Initial-Password-1", user)
+	require.True(t, errors.Is(err, ErrPolicyViolation))
+	assert.Contains(t, err.Error(), "different from your current password")
+
+	setPassword(t, s, user, "Second-Password-2")
+	setPassword(t, s, user, "Third-Password-3")
+
+	err = s.Validate(ctx, "Second-Password-2", user)
+	require.True(t, errors.Is(err, ErrPolicyViolation))
+	assert.Contains(t, err.Error(), "last 2 passwords")
+
+	// only the last 2 passwords are kept
+	setPassword(t, s, user, "Fourth-Password-4")
+	require.NoError(t, s.Validate(ctx, "Second-Password-2", user))
+
+	var count int64
+	err = s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var err error
+		count, err = sess.Where("user_id = ?", user.Id).Count(&passwordHistory{})
+		return err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestIntegrationPasswordExpiry(t *testing.T) {
+	s, user := setupTestService(t)
+	ctx := context.Background()
+
+	t.Run("the password set before the policy expires from the next login", func(t *testing.T) {
+		expired, err := s.IsExpired(ctx, user)
+		require.NoError(t, err)
+		assert.False(t, expired)
+
+		entry, err := s.getLastPassword(ctx, user.Id)
+		require.NoError(t, err)
+		require.NotNil(t, entry)
+		assert.Equal(t, user.Password, entry.Password)
+	})
+
+	t.Run("the password expires after the maximum age", func(t *testing.T) {
+		err := s.addPasswordHistory(ctx, user.Id, user.Password, user.Salt, time.Now().AddDate(0, 0, -91), 2)
+		require.NoError(t, err)
+		// the history is ordered by creation, so the entry from the first login is the last one
+		err = s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+			_, err := sess.Where("user_id = ? AND created > ?", user.Id, time.Now().AddDate(0, 0, -1)).Delete(&passwordHistory{})
+			return err
+		})
+		require.NoError(t, err)
+
+		expired, err := s.IsExpired(ctx, user)
+		require.NoError(t, err)
+		assert.True(t, expired)
+	})
+
+	t.Run("the expired password is changed through a challenge", func(t *testing.T) {
+		token, err := s.CreateChangeChallenge(ctx, user)
+		require.NoError(t, err)
+
+		_, err = s.ChangeExpiredPassword(ctx, &ChangeExpiredPasswordCommand{Token: token, NewPassword: "New-Password-5", ConfirmPassword: "Other-Password-5"})
+		require.ErrorIs(t, err, ErrPasswordsMismatch)
+
+		_, err = s.ChangeExpiredPassword(ctx, &ChangeExpiredPasswordCommand{Token: token, NewPassword: "Initial-Password-1", ConfirmPassword: "Initial-Password-1"})
+		require.ErrorIs(t, err, ErrPolicyViolation)
+
+		userID, err := s.ChangeExpiredPassword(ctx, &ChangeExpiredPasswordCommand{Token: token, NewPassword: "New-Password-5", ConfirmPassword: "New-Password-5"})
+		require.NoError(t, err)
+		assert.Equal(t, user.Id, userID)
+
+		query := models.GetUserByIdQuery{Id: user.Id}
+		require.NoError(t, s.sqlStore.GetUserById(ctx, &query))
+		expired, err := s.IsExpired(ctx, query.Result)
+		require.NoError(t, err)
+		assert.False(t, expired)
+
+		_, err = s.ChangeExpiredPassword(ctx, &ChangeExpiredPasswordCommand{Token: token, NewPassword: "Newer-Password-6", ConfirmPassword: "Newer-Password-6"})
+		require.ErrorIs(t, err, ErrChallengeNotFound)
+	})
+}
@@ -0,0 +1,43 @@
+package passwordpolicytest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/passwordpolicy"
+)
+
+type FakePasswordPolicyService struct {
+	ExpectedValidateError error
+	ExpectedExpired       bool
+	ExpectedChallenge     string
+	ExpectedUserID        int64
+	ExpectedError         error
+
+	RecordedUserIDs []int64
+}
+
+func NewPasswordPolicyServiceFake() *FakePasswordPolicyService {
+	return &FakePasswordPolicyService{}
+}
+
+func (f *FakePasswordPolicyService) Validate(ctx context.Context, password string, user *models.User) error {
+	return f.ExpectedValidateError
+}
+
+func (f *FakePasswordPolicyService) RecordPassword(ctx context.Context, userID int64, hashedPassword string, salt string) error {
+	f.RecordedUserIDs = append(f.RecordedUserIDs, userID)
+	return f.ExpectedError
+}
+
+func (f *FakePasswordPolicyService) IsExpired(ctx context.Context, user *models.User) (bool, error) {
+	return f.ExpectedExpired, f.ExpectedError
+}
+
+func (f *FakePasswordPolicyService) CreateChangeChallenge(ctx context.Context, user *models.User) (string, error) {
+	return f.ExpectedChallenge, f.ExpectedError
+}
+
+func (f *FakePasswordPolicyService) ChangeExpiredPassword(ctx context.Context, cmd *passwordpolicy.ChangeExpiredPasswordCommand) (int64, error) {
+	return f.ExpectedUserID, f.ExpectedError
+}
@@ -0,0 +1,82 @@
+package passwordpolicy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// minUserInfoLength is the length below which the login and email of a user are too common to be rejected in
+// passwords
+const minUserInfoLength = 3
+
+// checkRequirements checks the length and the character classes of the password, and reports all the requirements
+// it misses at once
+func (s *PasswordPolicyService) checkRequirements(password string) error {
+	missing := []string{}
+
+	if utf8.RuneCountInString(password) < s.cfg.PasswordPolicyMinLength {
+		missing = append(missing, fmt.Sprintf("at least %d characters", s.cfg.PasswordPolicyMinLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+
+	if s.cfg.PasswordPolicyRequireUppercase && !upper {
+		missing = append(missing, "an uppercase letter")
+	}
+	if s.cfg.PasswordPolicyRequireLowercase && !lower {
+		missing = append(missing, "a lowercase letter")
+	}
+	if s.cfg.PasswordPolicyRequireDigit && !digit {
+		missing = append(missing, "a digit")
+	}
+	if s.cfg.PasswordPolicyRequireSymbol && !symbol {
+		missing = append(missing, "a symbol")
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	requirements := missing[0]
+	if len(missing) > 1 {
+		requirements = strings.Join(missing[:len(missing)-1], ", ") + " and " + missing[len(missing)-1]
+	}
+	return fmt.Errorf("%w: it must contain %s", ErrPolicyViolation, requirements)
+}
+
+// containsUserInfo checks if the password contains the login, the email or the local part of the email of the
+// user, ignoring the case
+func containsUserInfo(password string, user *models.User) bool {
+	if user == nil {
+		return false
+	}
+
+	infos := []string{user.Login, user.Email}
+	if at := strings.LastIndex(user.Email, "@"); at > 0 {
+		infos = append(infos, user.Email[:at])
+	}
+
+	lower := strings.ToLower(password)
+	for _, info := range infos {
+		if utf8.RuneCountInString(info) >= minUserInfoLength && strings.Contains(lower, strings.ToLower(info)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package passwordpolicy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestCheckRequirements(t *testing.T) {
+	cfg := setting.NewCfg()
+	cfg.PasswordPolicyMinLength = 12
+	cfg.PasswordPolicyRequireUppercase = true
+	cfg.PasswordPolicyRequireLowercase = true
+	cfg.PasswordPolicyRequireDigit = true
+	cfg.PasswordPolicyRequireSymbol = true
+	s := &PasswordPolicyService{cfg: cfg}
+
+	t.Run("accepts a password meeting all the requirements", func(t *testing.T) {
+		require.NoError(t, s.checkRequirements("Correct-Horse-42"))
+	})
+
+	t.Run("reports a single missing requirement", func(t *testing.T) {
+		err := s.checkRequirements("Correct-Horse-Battery")
+		require.True(t, errors.Is(err, ErrPolicyViolation))
+		assert.Contains(t, err.Error(), "it must contain a digit")
+	})
+
+	t.Run("reports all the missing requirements", func(t *testing.T) {
+		err := s.checkRequirements("short")
+		require.True(t, errors.Is(err, ErrPolicyViolation))
+		assert.Contains(t, err.Error(), "it must contain at least 12 characters, an uppercase letter, a digit and a symbol")
+	})
+
+	t.Run("counts characters rather than bytes", func(t *testing.T) {
+		err := s.checkRequirements("Ééééééééé-1")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "at least 12 characters")
+	})
+
+	t.Run("ignores the requirements that are disabled", func(t *testing.T) {
+		relaxed := setting.NewCfg()
+		relaxed.PasswordPolicyMinLength = 4
+		relaxed.PasswordPolicyRequireUppercase = false
+		relaxed.PasswordPolicyRequireLowercase = true
+		relaxed.PasswordPolicyRequireDigit = false
+		relaxed.PasswordPolicyRequireSymbol = false
+		require.NoError(t, (&PasswordPolicyService{cfg: relaxed}).checkRequirements("horse"))
+	})
+}
+
+func TestContainsUserInfo(t *testing.T) {
+	user := &models.User{Login: "jdoe", Email: "john.doe@example.com"}
+
+	testCases := []struct {
+		desc     string
+		password string
+		expected bool
+	}{
+		{desc: "login", password: "MyJDoe-Password1", expected: true},
+		{desc: "email", password: "x-John.Doe@Example.com-1", expected: true},
+		{desc: "local part of the email", password: "john.doe-2022!", expected: true},
+		{desc: "unrelated password", password: "Correct-Horse-42", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.expected, containsUserInfo(tc.password, user))
+		})
+	}
+
+	t.Run("ignores the user info that is too short", func(t *testing.T) {
+		assert.False(t, containsUserInfo("Correct-Horse-42", &models.User{Login: "or", Email: "h@example.org"}))
+	})
+
+	t.Run("ignores a missing user", func(t *testing.T) {
+		assert.False(t, containsUserInfo("Correct-Horse-42", nil))
+	})
+}
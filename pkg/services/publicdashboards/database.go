@@ -0,0 +1,59 @@
+package publicdashboards
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func (s *PublicDashboardService) getPublicDashboard(ctx context.Context, orgID int64, dashboardUID string) (*PublicDashboard, error) {
+	pd := &PublicDashboard{}
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		exists, err := sess.Where("org_id = ? AND dashboard_uid = ?", orgID, dashboardUID).Get(pd)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrPublicDashboardNotFound
+		}
+		return nil
+	})
+	return pd, err
+}
+
+func (s *PublicDashboardService) getPublicDashboardByAccessToken(ctx context.Context, accessToken string) (*PublicDashboard, error) {
+	pd := &PublicDashboard{}
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		exists, err := sess.Where("access_token = ?", accessToken).Get(pd)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrPublicDashboardNotFound
+		}
+		return nil
+	})
+	return pd, err
+}
+
+func (s *PublicDashboardService) createPublicDashboard(ctx context.Context, pd *PublicDashboard) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Insert(pd)
+		return err
+	})
+}
+
+func (s *PublicDashboardService) updatePublicDashboard(ctx context.Context, pd *PublicDashboard) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		affected, err := sess.ID(pd.ID).
+			Cols("access_token", "is_enabled", "time_settings", "updated_by", "updated", "revoked_by", "revoked").
+			Update(pd)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrPublicDashboardNotFound
+		}
+		return nil
+	})
+}
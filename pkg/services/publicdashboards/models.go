@@ -0,0 +1,116 @@
+package publicdashboards
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+var (
+	ErrPublicDashboardNotFound = errors.New("public dashboard not found")
+	ErrPanelNotFound           = errors.New("panel not found in the public dashboard")
+	ErrPanelHasNoQueries       = errors.New("panel has no queries")
+	ErrInvalidTimeRange        = errors.New("time range is invalid")
+	ErrTimeRangeTooLong        = errors.New("time range is longer than the maximum time range of public dashboards")
+)
+
+const (
+	defaultTimeFrom = "now-6h"
+	defaultTimeTo   = "now"
+)
+
+// PublicDashboard shares a dashboard with anyone who knows its access token, without login. Revoking the
+// access of a dashboard replaces its token, so that the previous URL never works again.
+type PublicDashboard struct {
+	ID           int64         `xorm:"pk autoincr 'id'"`
+	UID          string        `xorm:"uid"`
+	DashboardUID string        `xorm:"dashboard_uid"`
+	OrgID        int64         `xorm:"org_id"`
+	AccessToken  string        `xorm:"access_token"`
+	IsEnabled    bool          `xorm:"is_enabled"`
+	TimeSettings *TimeSettings `xorm:"time_settings"`
+	CreatedBy    int64         `xorm:"created_by"`
+	Created      time.Time
+	UpdatedBy    int64 `xorm:"updated_by"`
+	Updated      time.Time
+	RevokedBy    *int64     `xorm:"revoked_by"`
+	Revoked      *time.Time `xorm:"revoked"`
+}
+
+func (PublicDashboard) TableName() string {
+	return "dashboard_public_config"
+}
+
+// TimeSettings is the time range the panels are queried for when the request does not set one.
+type TimeSettings struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func (ts *TimeSettings) FromDB(data []byte) error {
+	return json.Unmarshal(data, ts)
+}
+
+func (ts *TimeSettings) ToDB() ([]byte, error) {
+	if ts == nil {
+		return nil, nil
+	}
+	return json.Marshal(ts)
+}
+
+// SignedInUser is the identity public dashboard requests are served with: an anonymous viewer of the
+// organization of the dashboard.
+func (pd *PublicDashboard) SignedInUser() *models.SignedInUser {
+	return &models.SignedInUser{
+		OrgId:       pd.OrgID,
+		OrgRole:     models.ROLE_VIEWER,
+		Login:       "public-dashboard:" + pd.UID,
+		IsAnonymous: true,
+	}
+}
+
+func (pd *PublicDashboard) ToDTO() *PublicDashboardDTO {
+	return &PublicDashboardDTO{
+		UID:          pd.UID,
+		DashboardUID: pd.DashboardUID,
+		AccessToken:  pd.AccessToken,
+		IsEnabled:    pd.IsEnabled,
+		TimeSettings: pd.TimeSettings,
+		CreatedBy:    pd.CreatedBy,
+		Created:      pd.Created,
+		UpdatedBy:    pd.UpdatedBy,
+		Updated:      pd.Updated,
+		RevokedBy:    pd.RevokedBy,
+		Revoked:      pd.Revoked,
+	}
+}
+
+type PublicDashboardDTO struct {
+	UID          string        `json:"uid"`
+	DashboardUID string        `json:"dashboardUid"`
+	AccessToken  string        `json:"accessToken"`
+	IsEnabled    bool          `json:"isEnabled"`
+	TimeSettings *TimeSettings `json:"timeSettings"`
+	CreatedBy    int64         `json:"createdBy"`
+	Created      time.Time     `json:"created"`
+	UpdatedBy    int64         `json:"updatedBy"`
+	Updated      time.Time     `json:"updated"`
+	RevokedBy    *int64        `json:"revokedBy,omitempty"`
+	Revoked      *time.Time    `json:"revoked,omitempty"`
+}
+
+type SavePublicDashboardCommand struct {
+	IsEnabled    bool          `json:"isEnabled"`
+	TimeSettings *TimeSettings `json:"timeSettings"`
+}
+
+// QueryPanelCommand is the time range a panel of a public dashboard is queried for. The queries themselves are
+// always the ones saved in the dashboard.
+type QueryPanelCommand struct {
+	From          string `json:"from"`
+	To            string `json:"to"`
+	IntervalMs    int64  `json:"intervalMs"`
+	MaxDataPoints int64  `json:"maxDataPoints"`
+}
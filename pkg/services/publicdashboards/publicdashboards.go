@@ -0,0 +1,189 @@
+package publicdashboards
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+// length of the access tokens of public dashboards
+const accessTokenLength = 32
+
+type Service interface {
+	IsDisabled() bool
+	// GetPublicDashboardByAccessToken returns the enabled public dashboard shared with the access token.
+	GetPublicDashboardByAccessToken(ctx context.Context, accessToken string) (*PublicDashboard, error)
+	GetPublicDashboard(ctx context.Context, orgID int64, dashboardUID string) (*PublicDashboard, error)
+	// SavePublicDashboard creates the public dashboard of a dashboard, or updates its settings.
+	SavePublicDashboard(ctx context.Context, user *models.SignedInUser, dashboardUID string, cmd *SavePublicDashboardCommand) (*PublicDashboard, error)
+	// RotateAccessToken replaces the access token of a public dashboard, the previous one stops working.
+	RotateAccessToken(ctx context.Context, user *models.SignedInUser, dashboardUID string) (*PublicDashboard, error)
+	// RevokePublicDashboard disables a public dashboard and replaces its access token.
+	RevokePublicDashboard(ctx context.Context, user *models.SignedInUser, dashboardUID string) (*PublicDashboard, error)
+	// GetDashboard returns the saved dashboard shared by a public dashboard.
+	GetDashboard(ctx context.Context, pd *PublicDashboard) (*models.Dashboard, error)
+	// BuildMetricRequests returns the queries saved in a panel of the dashboard, for the time range of the
+	// command. Queries are grouped by datasource, one request each.
+	BuildMetricRequests(ctx context.Context, pd *PublicDashboard, dashboard *models.Dashboard, panelID int64, cmd QueryPanelCommand) ([]dtos.MetricRequest, error)
+}
+
+type PublicDashboardService struct {
+	cfg      *setting.Cfg
+	sqlStore *sqlstore.SQLStore
+	features featuremgmt.FeatureToggles
+	log      log.Logger
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, features featuremgmt.FeatureToggles) *PublicDashboardService {
+	return &PublicDashboardService{
+		cfg:      cfg,
+		sqlStore: sqlStore,
+		features: features,
+		log:      log.New("publicdashboards"),
+	}
+}
+
+func (s *PublicDashboardService) IsDisabled() bool {
+	return !s.features.IsEnabled(featuremgmt.FlagPublicDashboards)
+}
+
+func (s *PublicDashboardService) GetPublicDashboardByAccessToken(ctx context.Context, accessToken string) (*PublicDashboard, error) {
+	if accessToken == "" {
+		return nil, ErrPublicDashboardNotFound
+	}
+
+	pd, err := s.getPublicDashboardByAccessToken(ctx, accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if !pd.IsEnabled {
+		return nil, ErrPublicDashboardNotFound
+	}
+	return pd, nil
+}
+
+func (s *PublicDashboardService) GetPublicDashboard(ctx context.Context, orgID int64, dashboardUID string) (*PublicDashboard, error) {
+	return s.getPublicDashboard(ctx, orgID, dashboardUID)
+}
+
+func (s *PublicDashboardService) SavePublicDashboard(ctx context.Context, user *models.SignedInUser, dashboardUID string, cmd *SavePublicDashboardCommand) (*PublicDashboard, error) {
+	if cmd.TimeSettings != nil {
+		if err := validateTimeSettings(cmd.TimeSettings); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	pd, err := s.getPublicDashboard(ctx, user.OrgId, dashboardUID)
+	if errors.Is(err, ErrPublicDashboardNotFound) {
+		accessToken, err := util.GetRandomString(accessTokenLength)
+		if err != nil {
+			return nil, err
+		}
+
+		pd = &PublicDashboard{
+			UID:          util.GenerateShortUID(),
+			DashboardUID: dashboardUID,
+			OrgID:        user.OrgId,
+			AccessToken:  accessToken,
+			IsEnabled:    cmd.IsEnabled,
+			TimeSettings: cmd.TimeSettings,
+			CreatedBy:    user.UserId,
+			Created:      now,
+			UpdatedBy:    user.UserId,
+			Updated:      now,
+		}
+		if err := s.createPublicDashboard(ctx, pd); err != nil {
+			return nil, err
+		}
+
+		s.log.Info("Public dashboard created", "uid", pd.UID, "dashboardUid", dashboardUID, "orgId", user.OrgId,
+			"userId", user.UserId, "enabled", pd.IsEnabled)
+		return pd, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pd.IsEnabled = cmd.IsEnabled
+	pd.TimeSettings = cmd.TimeSettings
+	pd.UpdatedBy = user.UserId
+	pd.Updated = now
+	if err := s.updatePublicDashboard(ctx, pd); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Public dashboard updated", "uid", pd.UID, "dashboardUid", dashboardUID, "orgId", user.OrgId,
+		"userId", user.UserId, "enabled", pd.IsEnabled)
+	return pd, nil
+}
+
+func (s *PublicDashboardService) RotateAccessToken(ctx context.Context, user *models.SignedInUser, dashboardUID string) (*PublicDashboard, error) {
+	pd, err := s.getPublicDashboard(ctx, user.OrgId, dashboardUID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := util.GetRandomString(accessTokenLength)
+	if err != nil {
+		return nil, err
+	}
+	pd.AccessToken = accessToken
+	pd.UpdatedBy = user.UserId
+	pd.Updated = time.Now()
+	if err := s.updatePublicDashboard(ctx, pd); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Public dashboard access token rotated", "uid", pd.UID, "dashboardUid", dashboardUID, "orgId", user.OrgId,
+		"userId", user.UserId)
+	return pd, nil
+}
+
+func (s *PublicDashboardService) RevokePublicDashboard(ctx context.Context, user *models.SignedInUser, dashboardUID string) (*PublicDashboard, error) {
+	pd, err := s.getPublicDashboard(ctx, user.OrgId, dashboardUID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := util.GetRandomString(accessTokenLength)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	pd.AccessToken = accessToken
+	pd.IsEnabled = false
+	pd.UpdatedBy = user.UserId
+	pd.Updated = now
+	pd.RevokedBy = &user.UserId
+	pd.Revoked = &now
+	if err := s.updatePublicDashboard(ctx, pd); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Public dashboard revoked", "uid", pd.UID, "dashboardUid", dashboardUID, "orgId", user.OrgId,
+		"userId", user.UserId)
+	return pd, nil
+}
+
+func (s *PublicDashboardService) GetDashboard(ctx context.Context, pd *PublicDashboard) (*models.Dashboard, error) {
+	query := models.GetDashboardQuery{Uid: pd.DashboardUID, OrgId: pd.OrgID}
+	if err := s.sqlStore.GetDashboard(ctx, &query); err != nil {
+		if errors.Is(err, models.ErrDashboardNotFound) {
+			return nil, ErrPublicDashboardNotFound
+		}
+		return nil, err
+	}
+	if query.Result.IsFolder {
+		return nil, ErrPublicDashboardNotFound
+	}
+	return query.Result, nil
+}
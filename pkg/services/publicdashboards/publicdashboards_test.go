@@ -0,0 +1,251 @@
+package publicdashboards
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	dashboardsdb "github.com/grafana/grafana/pkg/services/dashboards/database"
+	"github.com/grafana/grafana/pkg/services/featuremgmt"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+const testDashboardJSON = `{
+	"title": "Status",
+	"time": {"from": "now-1h", "to": "now"},
+	"panels": [
+		{"id": 1, "datasource": {"uid": "prom", "type": "prometheus"}, "targets": [
+			{"refId": "A", "expr": "up"},
+			{"refId": "B", "expr": "errors", "hide": true}
+		]},
+		{"id": 2, "datasource": {"uid": "-- Mixed --"}, "targets": [
+			{"refId": "A", "datasource": {"uid": "prom", "type": "prometheus"}, "expr": "up"},
+			{"refId": "B", "datasource": {"uid": "loki", "type": "loki"}, "expr": "{job=\"app\"}"},
+			{"refId": "C"}
+		]},
+		{"id": 3, "type": "row", "collapsed": true, "panels": [
+			{"id": 4, "datasource": "Loki", "targets": [{"refId": "A", "expr": "{job=\"app\"}"}]}
+		]},
+		{"id": 5, "type": "text"}
+	]
+}`
+
+type testEnv struct {
+	service   *PublicDashboardService
+	sqlStore  *sqlstore.SQLStore
+	user      *models.SignedInUser
+	dashboard *models.Dashboard
+}
+
+func setupTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	sqlStore := sqlstore.InitTestDB(t)
+	cfg := sqlStore.Cfg
+	cfg.PublicDashboardMaxTimeRange = 24 * time.Hour
+
+	data, err := simplejson.NewJson([]byte(testDashboardJSON))
+	require.NoError(t, err)
+	dashboard, err := dashboardsdb.ProvideDashboardStore(sqlStore).SaveDashboard(models.SaveDashboardCommand{OrgId: 1, Dashboard: data})
+	require.NoError(t, err)
+
+	for _, cmd := range []*models.AddDataSourceCommand{
+		{OrgId: 1, Name: "Prometheus", Type: "prometheus", Uid: "prom", Access: models.DS_ACCESS_PROXY, IsDefault: true},
+		{OrgId: 1, Name: "Loki", Type: "loki", Uid: "loki", Access: models.DS_ACCESS_PROXY},
+	} {
+		require.NoError(t, sqlStore.AddDataSource(context.Background(), cmd))
+	}
+
+	return &testEnv{
+		service:   ProvideService(cfg, sqlStore, featuremgmt.WithFeatures(featuremgmt.FlagPublicDashboards)),
+		sqlStore:  sqlStore,
+		user:      &models.SignedInUser{UserId: 2, OrgId: 1, OrgRole: models.ROLE_ADMIN},
+		dashboard: dashboard,
+	}
+}
+
+func TestPublicDashboardService(t *testing.T) {
+	ctx := context.Background()
+	env := setupTestEnv(t)
+	s := env.service
+
+	pd, err := s.SavePublicDashboard(ctx, env.user, env.dashboard.Uid, &SavePublicDashboardCommand{IsEnabled: true})
+	require.NoError(t, err)
+	require.Len(t, pd.AccessToken, accessTokenLength)
+	require.Equal(t, int64(2), pd.CreatedBy)
+
+	t.Run("public dashboards are found by their access token", func(t *testing.T) {
+		found, err := s.GetPublicDashboardByAccessToken(ctx, pd.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, pd.UID, found.UID)
+
+		_, err = s.GetPublicDashboardByAccessToken(ctx, "unknown")
+		require.ErrorIs(t, err, ErrPublicDashboardNotFound)
+		_, err = s.GetPublicDashboardByAccessToken(ctx, "")
+		require.ErrorIs(t, err, ErrPublicDashboardNotFound)
+
+		user := found.SignedInUser()
+		assert.Equal(t, int64(1), user.OrgId)
+		assert.Equal(t, models.ROLE_VIEWER, user.OrgRole)
+		assert.True(t, user.IsAnonymous)
+	})
+
+	t.Run("disabled public dashboards can't be accessed", func(t *testing.T) {
+		_, err := s.SavePublicDashboard(ctx, env.user, env.dashboard.Uid, &SavePublicDashboardCommand{IsEnabled: false})
+		require.NoError(t, err)
+		_, err = s.GetPublicDashboardByAccessToken(ctx, pd.AccessToken)
+		require.ErrorIs(t, err, ErrPublicDashboardNotFound)
+
+		updated, err := s.SavePublicDashboard(ctx, env.user, env.dashboard.Uid, &SavePublicDashboardCommand{IsEnabled: true})
+		require.NoError(t, err)
+		require.Equal(t, pd.UID, updated.UID)
+		require.Equal(t, pd.AccessToken, updated.AccessToken)
+	})
+
+	t.Run("rotating the access token invalidates the previous one", func(t *testing.T) {
+		rotated, err := s.RotateAccessToken(ctx, env.user, env.dashboard.Uid)
+		require.NoError(t, err)
+		require.NotEqual(t, pd.AccessToken, rotated.AccessToken)
+
+		_, err = s.GetPublicDashboardByAccessToken(ctx, pd.AccessToken)
+		require.ErrorIs(t, err, ErrPublicDashboardNotFound)
+		_, err = s.GetPublicDashboardByAccessToken(ctx, rotated.AccessToken)
+		require.NoError(t, err)
+		pd = rotated
+	})
+
+	t.Run("revoked public dashboards are disabled and get a new access token", func(t *testing.T) {
+		revoked, err := s.RevokePublicDashboard(ctx, env.user, env.dashboard.Uid)
+		require.NoError(t, err)
+		require.False(t, revoked.IsEnabled)
+		require.NotEqual(t, pd.AccessToken, revoked.AccessToken)
+		require.Equal(t, int64(2), *revoked.RevokedBy)
+		require.NotNil(t, revoked.Revoked)
+
+		_, err = s.GetPublicDashboardByAccessToken(ctx, pd.AccessToken)
+		require.ErrorIs(t, err, ErrPublicDashboardNotFound)
+
+		enabled, err := s.SavePublicDashboard(ctx, env.user, env.dashboard.Uid, &SavePublicDashboardCommand{IsEnabled: true})
+		require.NoError(t, err)
+		_, err = s.GetPublicDashboardByAccessToken(ctx, enabled.AccessToken)
+		require.NoError(t, err)
+		_, err = s.GetPublicDashboardByAccessToken(ctx, pd.AccessToken)
+		require.ErrorIs(t, err, ErrPublicDashboardNotFound)
+	})
+
+	t.Run("public dashboards are deleted with their dashboard", func(t *testing.T) {
+		err := dashboardsdb.ProvideDashboardStore(env.sqlStore).DeleteDashboard(ctx, &models.DeleteDashboardCommand{Id: env.dashboard.Id, OrgId: 1})
+		require.NoError(t, err)
+
+		_, err = s.GetPublicDashboard(ctx, 1, env.dashboard.Uid)
+		require.ErrorIs(t, err, ErrPublicDashboardNotFound)
+	})
+}
+
+func TestBuildMetricRequests(t *testing.T) {
+	ctx := context.Background()
+	env := setupTestEnv(t)
+	s := env.service
+
+	pd, err := s.SavePublicDashboard(ctx, env.user, env.dashboard.Uid, &SavePublicDashboardCommand{IsEnabled: true})
+	require.NoError(t, err)
+
+	t.Run("only the visible queries saved in the panel are sent", func(t *testing.T) {
+		reqs, err := s.BuildMetricRequests(ctx, pd, env.dashboard, 1, QueryPanelCommand{MaxDataPoints: 100, IntervalMs: 1})
+		require.NoError(t, err)
+		require.Len(t, reqs, 1)
+		require.Len(t, reqs[0].Queries, 1)
+
+		query := reqs[0].Queries[0]
+		assert.Equal(t, "up", query.Get("expr").MustString())
+		assert.Equal(t, "prom", query.Get("datasource").Get("uid").MustString())
+		assert.Equal(t, int64(100), query.Get("maxDataPoints").MustInt64())
+		// one hour over 100 data points
+		assert.Equal(t, int64(36000), query.Get("intervalMs").MustInt64())
+		assert.NotEmpty(t, reqs[0].From)
+		assert.NotEmpty(t, reqs[0].To)
+	})
+
+	t.Run("queries of mixed panels are grouped by datasource", func(t *testing.T) {
+		reqs, err := s.BuildMetricRequests(ctx, pd, env.dashboard, 2, QueryPanelCommand{})
+		require.NoError(t, err)
+		require.Len(t, reqs, 2)
+		require.Len(t, reqs[0].Queries, 2)
+		require.Len(t, reqs[1].Queries, 1)
+
+		// the query without datasource uses the default one
+		assert.Equal(t, "C", reqs[0].Queries[1].Get("refId").MustString())
+		assert.Equal(t, "prom", reqs[0].Queries[1].Get("datasource").Get("uid").MustString())
+		assert.Equal(t, "loki", reqs[1].Queries[0].Get("datasource").Get("uid").MustString())
+	})
+
+	t.Run("panels of collapsed rows are found, and datasources referenced by name", func(t *testing.T) {
+		reqs, err := s.BuildMetricRequests(ctx, pd, env.dashboard, 4, QueryPanelCommand{})
+		require.NoError(t, err)
+		require.Len(t, reqs, 1)
+		assert.Equal(t, "loki", reqs[0].Queries[0].Get("datasource").Get("uid").MustString())
+	})
+
+	t.Run("unknown panels and panels without queries", func(t *testing.T) {
+		_, err := s.BuildMetricRequests(ctx, pd, env.dashboard, 42, QueryPanelCommand{})
+		require.ErrorIs(t, err, ErrPanelNotFound)
+		_, err = s.BuildMetricRequests(ctx, pd, env.dashboard, 5, QueryPanelCommand{})
+		require.ErrorIs(t, err, ErrPanelHasNoQueries)
+	})
+
+	t.Run("time range", func(t *testing.T) {
+		_, err := s.BuildMetricRequests(ctx, pd, env.dashboard, 1, QueryPanelCommand{From: "now-7d", To: "now"})
+		require.ErrorIs(t, err, ErrTimeRangeTooLong)
+		_, err = s.BuildMetricRequests(ctx, pd, env.dashboard, 1, QueryPanelCommand{From: "now", To: "now-1h"})
+		require.ErrorIs(t, err, ErrInvalidTimeRange)
+		_, err = s.BuildMetricRequests(ctx, pd, env.dashboard, 1, QueryPanelCommand{From: "yesterday", To: "now"})
+		require.ErrorIs(t, err, ErrInvalidTimeRange)
+
+		reqs, err := s.BuildMetricRequests(ctx, pd, env.dashboard, 1, QueryPanelCommand{From: "1600000000000", To: "1600003600000"})
+		require.NoError(t, err)
+		assert.Equal(t, "1600000000000", reqs[0].From)
+		assert.Equal(t, "1600003600000", reqs[0].To)
+
+		// the time settings of the public dashboard are used when the request sets no time range
+		pd.TimeSettings = &TimeSettings{From: "now-2d", To: "now"}
+		_, err = s.BuildMetricRequests(ctx, pd, env.dashboard, 1, QueryPanelCommand{})
+		require.ErrorIs(t, err, ErrTimeRangeTooLong)
+	})
+}
+
+func TestSanitizeDashboard(t *testing.T) {
+	dashboardData, err := simplejson.NewJson([]byte(testDashboardJSON))
+	require.NoError(t, err)
+	dashboardData.Set("templating", map[string]interface{}{"list": []interface{}{
+		map[string]interface{}{
+			"name": "job", "type": "query", "datasource": map[string]interface{}{"uid": "prom"},
+			"query": "label_values(up, job)", "definition": "label_values(up, job)",
+			"current": map[string]interface{}{"value": "app"},
+		},
+	}})
+	dashboardData.Set("annotations", map[string]interface{}{"list": []interface{}{
+		map[string]interface{}{"name": "Deploys", "datasource": map[string]interface{}{"uid": "loki"}, "expr": "{job=\"deploy\"}"},
+	}})
+
+	data, err := SanitizeDashboard(dashboardData)
+	require.NoError(t, err)
+
+	for _, id := range []int64{1, 2, 4} {
+		panel := findPanel(data, id)
+		require.NotNil(t, panel)
+		assert.Nil(t, panel.Interface().(map[string]interface{})["targets"])
+		assert.Nil(t, panel.Interface().(map[string]interface{})["datasource"])
+	}
+	variable := data.GetPath("templating", "list").GetIndex(0)
+	assert.Equal(t, map[string]interface{}{"name": "job", "type": "query", "current": map[string]interface{}{"value": "app"}},
+		variable.Interface())
+	assert.Equal(t, map[string]interface{}{"name": "Deploys"}, data.GetPath("annotations", "list").GetIndex(0).Interface())
+
+	// the saved dashboard is not changed
+	assert.Len(t, findPanel(dashboardData, 1).Get("targets").MustArray(), 2)
+}
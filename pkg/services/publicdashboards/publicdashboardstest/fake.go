@@ -0,0 +1,53 @@
+package publicdashboardstest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/publicdashboards"
+)
+
+type FakePublicDashboardService struct {
+	ExpectedDisabled        bool
+	ExpectedPublicDashboard *publicdashboards.PublicDashboard
+	ExpectedDashboard       *models.Dashboard
+	ExpectedMetricRequests  []dtos.MetricRequest
+	ExpectedError           error
+}
+
+func NewPublicDashboardServiceFake() *FakePublicDashboardService {
+	return &FakePublicDashboardService{ExpectedDisabled: true}
+}
+
+func (f *FakePublicDashboardService) IsDisabled() bool {
+	return f.ExpectedDisabled
+}
+
+func (f *FakePublicDashboardService) GetPublicDashboardByAccessToken(ctx context.Context, accessToken string) (*publicdashboards.PublicDashboard, error) {
+	return f.ExpectedPublicDashboard, f.ExpectedError
+}
+
+func (f *FakePublicDashboardService) GetPublicDashboard(ctx context.Context, orgID int64, dashboardUID string) (*publicdashboards.PublicDashboard, error) {
+	return f.ExpectedPublicDashboard, f.ExpectedError
+}
+
+func (f *FakePublicDashboardService) SavePublicDashboard(ctx context.Context, user *models.SignedInUser, dashboardUID string, cmd *publicdashboards.SavePublicDashboardCommand) (*publicdashboards.PublicDashboard, error) {
+	return f.ExpectedPublicDashboard, f.ExpectedError
+}
+
+func (f *FakePublicDashboardService) RotateAccessToken(ctx context.Context, user *models.SignedInUser, dashboardUID string) (*publicdashboards.PublicDashboard, error) {
+	return f.ExpectedPublicDashboard, f.ExpectedError
+}
+
+func (f *FakePublicDashboardService) RevokePublicDashboard(ctx context.Context, user *models.SignedInUser, dashboardUID string) (*publicdashboards.PublicDashboard, error) {
+	return f.ExpectedPublicDashboard, f.ExpectedError
+}
+
+func (f *FakePublicDashboardService) GetDashboard(ctx context.Context, pd *publicdashboards.PublicDashboard) (*models.Dashboard, error) {
+	return f.ExpectedDashboard, f.ExpectedError
+}
+
+func (f *FakePublicDashboardService) BuildMetricRequests(ctx context.Context, pd *publicdashboards.PublicDashboard, dashboard *models.Dashboard, panelID int64, cmd publicdashboards.QueryPanelCommand) ([]dtos.MetricRequest, error) {
+	return f.ExpectedMetricRequests, f.ExpectedError
+}
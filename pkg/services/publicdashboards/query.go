@@ -0,0 +1,259 @@
+package publicdashboards
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/tsdb/legacydata"
+)
+
+const (
+	defaultMaxDataPoints = 1000
+	maxMaxDataPoints     = 10000
+	mixedDatasourceUID   = "-- Mixed --"
+)
+
+func (s *PublicDashboardService) BuildMetricRequests(ctx context.Context, pd *PublicDashboard, dashboard *models.Dashboard, panelID int64, cmd QueryPanelCommand) ([]dtos.MetricRequest, error) {
+	panel := findPanel(dashboard.Data, panelID)
+	if panel == nil {
+		return nil, ErrPanelNotFound
+	}
+
+	from, to, err := s.timeRange(pd, dashboard.Data, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	maxDataPoints := cmd.MaxDataPoints
+	if maxDataPoints <= 0 {
+		maxDataPoints = defaultMaxDataPoints
+	}
+	if maxDataPoints > maxMaxDataPoints {
+		maxDataPoints = maxMaxDataPoints
+	}
+	// the interval can't be shorter than what maxDataPoints allows, so that a public dashboard can't be used
+	// to fetch data at an arbitrary resolution
+	intervalMs := cmd.IntervalMs
+	if minInterval := to.Sub(from).Milliseconds() / maxDataPoints; intervalMs < minInterval {
+		intervalMs = minInterval
+	}
+	if intervalMs < 1 {
+		intervalMs = 1
+	}
+
+	panelDatasource := panel.Get("datasource")
+	requests := []dtos.MetricRequest{}
+	byDatasource := map[string]int{}
+	hasExpression := false
+	for _, target := range panel.Get("targets").MustArray() {
+		query, err := simplejson.NewFromAny(target).Encode()
+		if err != nil {
+			return nil, err
+		}
+		queryJSON, err := simplejson.NewJson(query)
+		if err != nil {
+			return nil, err
+		}
+		if queryJSON.Get("hide").MustBool(false) {
+			continue
+		}
+
+		datasource := panelDatasource
+		if uid := datasourceUID(panelDatasource); uid == "" || uid == mixedDatasourceUID {
+			datasource = queryJSON.Get("datasource")
+		}
+		ds, err := s.resolveDatasource(ctx, pd.OrgID, datasource)
+		if err != nil {
+			return nil, err
+		}
+
+		queryJSON.Set("datasource", ds)
+		queryJSON.Del("datasourceId")
+		queryJSON.Set("intervalMs", intervalMs)
+		queryJSON.Set("maxDataPoints", maxDataPoints)
+
+		uid := ds["uid"].(string)
+		if expr.IsDataSource(uid) {
+			hasExpression = true
+		}
+		i, ok := byDatasource[uid]
+		if !ok {
+			i = len(requests)
+			byDatasource[uid] = i
+			requests = append(requests, dtos.MetricRequest{
+				From: strconv.FormatInt(from.UnixMilli(), 10),
+				To:   strconv.FormatInt(to.UnixMilli(), 10),
+			})
+		}
+		requests[i].Queries = append(requests[i].Queries, queryJSON)
+	}
+
+	if len(requests) == 0 {
+		return nil, ErrPanelHasNoQueries
+	}
+
+	// expressions reference the queries of other datasources, all of them are sent at once
+	if hasExpression {
+		for _, req := range requests[1:] {
+			requests[0].Queries = append(requests[0].Queries, req.Queries...)
+		}
+		requests = requests[:1]
+	}
+	return requests, nil
+}
+
+// timeRange returns the time range requested, or the one of the public dashboard or the dashboard when the
+// request does not set one. It must not be longer than the maximum time range of public dashboards.
+func (s *PublicDashboardService) timeRange(pd *PublicDashboard, dashboardData *simplejson.Json, cmd QueryPanelCommand) (time.Time, time.Time, error) {
+	timeFrom, timeTo := cmd.From, cmd.To
+	if timeFrom == "" || timeTo == "" {
+		switch {
+		case pd.TimeSettings != nil && pd.TimeSettings.From != "" && pd.TimeSettings.To != "":
+			timeFrom, timeTo = pd.TimeSettings.From, pd.TimeSettings.To
+		default:
+			timeFrom = dashboardData.Get("time").Get("from").MustString(defaultTimeFrom)
+			timeTo = dashboardData.Get("time").Get("to").MustString(defaultTimeTo)
+		}
+	}
+
+	now := time.Now()
+	tr := legacydata.NewDataTimeRange(timeFrom, timeTo)
+	from, err := tr.ParseFrom(legacydata.WithNow(now))
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidTimeRange
+	}
+	to, err := tr.ParseTo(legacydata.WithNow(now))
+	if err != nil {
+		return time.Time{}, time.Time{}, ErrInvalidTimeRange
+	}
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, ErrInvalidTimeRange
+	}
+	if s.cfg.PublicDashboardMaxTimeRange > 0 && to.Sub(from) > s.cfg.PublicDashboardMaxTimeRange {
+		return time.Time{}, time.Time{}, ErrTimeRangeTooLong
+	}
+	return from, to, nil
+}
+
+// validateTimeSettings checks that the time range of a public dashboard can be parsed. Its length is checked when
+// the panels are queried.
+func validateTimeSettings(ts *TimeSettings) error {
+	if ts.From == "" && ts.To == "" {
+		return nil
+	}
+
+	tr := legacydata.NewDataTimeRange(ts.From, ts.To)
+	if _, err := tr.ParseFrom(); err != nil {
+		return ErrInvalidTimeRange
+	}
+	if _, err := tr.ParseTo(); err != nil {
+		return ErrInvalidTimeRange
+	}
+	return nil
+}
+
+// resolveDatasource returns the reference to the datasource of a query, the default datasource of the
+// organization when the query has none, and the datasource with that name for dashboards saved before
+// datasources were referenced by uid.
+func (s *PublicDashboardService) resolveDatasource(ctx context.Context, orgID int64, datasource *simplejson.Json) (map[string]interface{}, error) {
+	if uid := datasource.Get("uid").MustString(); uid != "" {
+		return map[string]interface{}{"uid": uid, "type": datasource.Get("type").MustString()}, nil
+	}
+
+	if name := datasource.MustString(); name != "" {
+		if expr.IsDataSource(name) {
+			return map[string]interface{}{"uid": name, "type": name}, nil
+		}
+		query := models.GetDataSourceQuery{Name: name, OrgId: orgID}
+		if err := s.sqlStore.GetDataSource(ctx, &query); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"uid": query.Result.Uid, "type": query.Result.Type}, nil
+	}
+
+	query := models.GetDefaultDataSourceQuery{OrgId: orgID}
+	if err := s.sqlStore.GetDefaultDataSource(ctx, &query); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"uid": query.Result.Uid, "type": query.Result.Type}, nil
+}
+
+func datasourceUID(datasource *simplejson.Json) string {
+	if uid := datasource.Get("uid").MustString(); uid != "" {
+		return uid
+	}
+	return datasource.MustString()
+}
+
+// findPanel returns the panel with the id, including the panels of collapsed rows and of dashboards saved with
+// the rows of the previous schema.
+func findPanel(dashboardData *simplejson.Json, panelID int64) *simplejson.Json {
+	panels := dashboardData.Get("panels").MustArray()
+	for _, row := range dashboardData.Get("rows").MustArray() {
+		panels = append(panels, simplejson.NewFromAny(row).Get("panels").MustArray()...)
+	}
+
+	for _, p := range panels {
+		panel := simplejson.NewFromAny(p)
+		if panel.Get("id").MustInt64() == panelID {
+			return panel
+		}
+		for _, nested := range panel.Get("panels").MustArray() {
+			nestedPanel := simplejson.NewFromAny(nested)
+			if nestedPanel.Get("id").MustInt64() == panelID {
+				return nestedPanel
+			}
+		}
+	}
+	return nil
+}
+
+// SanitizeDashboard returns a copy of the dashboard data without the queries and the datasources of its panels,
+// template variables and annotations. Public dashboards query their panels by id, the viewers don't need them.
+func SanitizeDashboard(dashboardData *simplejson.Json) (*simplejson.Json, error) {
+	raw, err := dashboardData.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	data, err := simplejson.NewJson(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var sanitizePanels func(panels []interface{})
+	sanitizePanels = func(panels []interface{}) {
+		for _, p := range panels {
+			panel := simplejson.NewFromAny(p)
+			panel.Del("targets")
+			panel.Del("datasource")
+			sanitizePanels(panel.Get("panels").MustArray())
+		}
+	}
+	sanitizePanels(data.Get("panels").MustArray())
+	for _, row := range data.Get("rows").MustArray() {
+		sanitizePanels(simplejson.NewFromAny(row).Get("panels").MustArray())
+	}
+
+	// the current values of the variables are kept, they are displayed and can be used by the panels
+	for _, v := range data.GetPath("templating", "list").MustArray() {
+		variable := simplejson.NewFromAny(v)
+		variable.Del("datasource")
+		if variable.Get("type").MustString() == "query" {
+			variable.Del("query")
+			variable.Del("definition")
+		}
+	}
+	for _, a := range data.GetPath("annotations", "list").MustArray() {
+		annotation := simplejson.NewFromAny(a)
+		annotation.Del("datasource")
+		annotation.Del("target")
+		annotation.Del("expr")
+		annotation.Del("query")
+	}
+	return data, nil
+}
@@ -0,0 +1,111 @@
+package query
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+
+	"github.com/grafana/grafana/pkg/expr"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/queryaudit"
+)
+
+// recordQueryAudit records one audit entry per datasource used by the request.
+func (s *Service) recordQueryAudit(user *models.SignedInUser, httpReq *http.Request, parsedReq *parsedRequest,
+	resp *backend.QueryDataResponse, queryErr error, duration time.Duration) {
+	if !s.queryAuditService.Enabled() {
+		return
+	}
+
+	byDatasource := map[string][]parsedQuery{}
+	uids := []string{}
+	for _, pq := range parsedReq.parsedQueries {
+		if pq.datasource == nil || expr.IsDataSource(pq.datasource.Uid) {
+			continue
+		}
+		if _, ok := byDatasource[pq.datasource.Uid]; !ok {
+			uids = append(uids, pq.datasource.Uid)
+		}
+		byDatasource[pq.datasource.Uid] = append(byDatasource[pq.datasource.Uid], pq)
+	}
+
+	for _, uid := range uids {
+		queries := byDatasource[uid]
+		entry := queryaudit.NewEntry(queryaudit.SourceQuery, user, queries[0].datasource)
+		entry.SetRequestInfo(httpReq)
+		entry.DurationMs = duration.Milliseconds()
+
+		queryModels := make([]json.RawMessage, 0, len(queries))
+		errs := []string{}
+		for _, q := range queries {
+			queryModels = append(queryModels, q.query.JSON)
+			if resp == nil {
+				continue
+			}
+			if res, ok := resp.Responses[q.query.RefID]; ok {
+				entry.BytesReturned += framesSize(res.Frames)
+				if res.Error != nil {
+					errs = append(errs, res.Error.Error())
+				}
+			}
+		}
+
+		if queryText, err := json.Marshal(queryModels); err == nil {
+			entry.Query = string(queryText)
+		}
+
+		if queryErr != nil {
+			entry.Error = queryErr.Error()
+		} else {
+			entry.Error = strings.Join(errs, "; ")
+		}
+
+		s.queryAuditService.Record(entry)
+	}
+}
+
+// framesSize estimates the size of the frames from the number and the type of
+// their values, without encoding them again.
+func framesSize(frames data.Frames) int64 {
+	var size int64
+	for _, frame := range frames {
+		for _, field := range frame.Fields {
+			size += fieldSize(field)
+		}
+	}
+	return size
+}
+
+func fieldSize(field *data.Field) int64 {
+	length := field.Len()
+	switch field.Type().NonNullableType() {
+	case data.FieldTypeString:
+		var size int64
+		for i := 0; i < length; i++ {
+			if v, ok := field.ConcreteAt(i); ok {
+				size += int64(len(v.(string)))
+			}
+		}
+		return size
+	case data.FieldTypeJSON:
+		var size int64
+		for i := 0; i < length; i++ {
+			if v, ok := field.ConcreteAt(i); ok {
+				size += int64(len(v.(json.RawMessage)))
+			}
+		}
+		return size
+	case data.FieldTypeInt8, data.FieldTypeUint8, data.FieldTypeBool:
+		return int64(length)
+	case data.FieldTypeInt16, data.FieldTypeUint16:
+		return int64(length) * 2
+	case data.FieldTypeInt32, data.FieldTypeUint32, data.FieldTypeFloat32:
+		return int64(length) * 4
+	default:
+		return int64(length) * 8
+	}
+}
@@ -0,0 +1,28 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFramesSize(t *testing.T) {
+	value := "up"
+	frames := data.Frames{
+		data.NewFrame("A",
+			data.NewField("time", nil, []time.Time{time.Now(), time.Now()}),
+			data.NewField("value", nil, []float64{1, 2}),
+			data.NewField("ok", nil, []bool{true, false}),
+		),
+		data.NewFrame("B",
+			data.NewField("name", nil, []string{"cpu", "memory"}),
+			data.NewField("label", nil, []*string{&value, nil}),
+			data.NewField("count", nil, []int32{1, 2}),
+		),
+	}
+
+	// 2 times and 2 floats of 8 bytes, 2 bools, 9 + 2 bytes of strings and 2 int32
+	require.Equal(t, int64(16+16+2+11+8), framesSize(frames))
+}
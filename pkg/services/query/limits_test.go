@@ -0,0 +1,56 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/datasources/ratelimit"
+)
+
+type recordingLimiter struct {
+	acquired []string
+	released []string
+	failUID  string
+}
+
+func (l *recordingLimiter) Acquire(_ context.Context, ds *models.DataSource, _ *models.SignedInUser) (func(), error) {
+	if ds.Uid == l.failUID {
+		return nil, ratelimit.ErrLimitExceeded
+	}
+	l.acquired = append(l.acquired, ds.Uid)
+	return func() { l.released = append(l.released, ds.Uid) }, nil
+}
+
+func TestAcquireDataSourceLimits(t *testing.T) {
+	parsedReq := &parsedRequest{parsedQueries: []parsedQuery{
+		{datasource: &models.DataSource{Uid: "loki"}},
+		{datasource: &models.DataSource{Uid: "prometheus"}},
+		{datasource: &models.DataSource{Uid: "elasticsearch"}},
+		{datasource: &models.DataSource{Uid: "loki"}},
+		{},
+	}}
+
+	t.Run("limits are acquired once per datasource, by datasource uid", func(t *testing.T) {
+		limiter := &recordingLimiter{}
+		s := &Service{dataSourceLimiter: limiter}
+
+		release, err := s.acquireDataSourceLimits(context.Background(), nil, parsedReq)
+		require.NoError(t, err)
+		require.Equal(t, []string{"elasticsearch", "loki", "prometheus"}, limiter.acquired)
+
+		release()
+		require.Equal(t, []string{"elasticsearch", "loki", "prometheus"}, limiter.released)
+	})
+
+	t.Run("acquired limits are released when a limit is exceeded", func(t *testing.T) {
+		limiter := &recordingLimiter{failUID: "prometheus"}
+		s := &Service{dataSourceLimiter: limiter}
+
+		_, err := s.acquireDataSourceLimits(context.Background(), nil, parsedReq)
+		require.ErrorIs(t, err, ratelimit.ErrLimitExceeded)
+		require.Equal(t, []string{"elasticsearch", "loki"}, limiter.released)
+	})
+}
@@ -3,6 +3,7 @@ package query
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -13,13 +14,18 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/adapters"
+	"github.com/grafana/grafana/pkg/services/correlations"
 	"github.com/grafana/grafana/pkg/services/datasources"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement"
+	"github.com/grafana/grafana/pkg/services/datasources/ratelimit"
 	"github.com/grafana/grafana/pkg/services/oauthtoken"
+	"github.com/grafana/grafana/pkg/services/queryaudit"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/tsdb/grafanads"
 	"github.com/grafana/grafana/pkg/tsdb/legacydata"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/prometheus/prometheus/pkg/labels"
 )
 
 const (
@@ -35,30 +41,46 @@ func ProvideService(
 	dataSourceService datasources.DataSourceService,
 	pluginClient plugins.Client,
 	oAuthTokenService oauthtoken.OAuthTokenService,
+	dataSourceLimiter ratelimit.Service,
+	queryAuditService queryaudit.Service,
+	correlationsService correlations.Service,
+	labelEnforcementService labelenforcement.Service,
 ) *Service {
 	g := &Service{
-		cfg:                    cfg,
-		dataSourceCache:        dataSourceCache,
-		expressionService:      expressionService,
-		pluginRequestValidator: pluginRequestValidator,
-		dataSourceService:      dataSourceService,
-		pluginClient:           pluginClient,
-		oAuthTokenService:      oAuthTokenService,
-		log:                    log.New("query_data"),
+		cfg:                     cfg,
+		dataSourceCache:         dataSourceCache,
+		expressionService:       expressionService,
+		pluginRequestValidator:  pluginRequestValidator,
+		dataSourceService:       dataSourceService,
+		pluginClient:            pluginClient,
+		oAuthTokenService:       oAuthTokenService,
+		dataSourceLimiter:       dataSourceLimiter,
+		queryAuditService:       queryAuditService,
+		correlationsService:     correlationsService,
+		labelEnforcementService: labelEnforcementService,
+		log:                     log.New("query_data"),
+	}
+	if cfg != nil && cfg.QuerySplitting.Enabled {
+		g.querySplitter = newQuerySplitter(cfg.QuerySplitting, pluginClient)
 	}
 	g.log.Info("Query Service initialization")
 	return g
 }
 
 type Service struct {
-	cfg                    *setting.Cfg
-	dataSourceCache        datasources.CacheService
-	expressionService      *expr.Service
-	pluginRequestValidator models.PluginRequestValidator
-	dataSourceService      datasources.DataSourceService
-	pluginClient           plugins.Client
-	oAuthTokenService      oauthtoken.OAuthTokenService
-	log                    log.Logger
+	cfg                     *setting.Cfg
+	dataSourceCache         datasources.CacheService
+	expressionService       *expr.Service
+	pluginRequestValidator  models.PluginRequestValidator
+	dataSourceService       datasources.DataSourceService
+	pluginClient            plugins.Client
+	oAuthTokenService       oauthtoken.OAuthTokenService
+	dataSourceLimiter       ratelimit.Service
+	queryAuditService       queryaudit.Service
+	correlationsService     correlations.Service
+	labelEnforcementService labelenforcement.Service
+	querySplitter           *querySplitter
+	log                     log.Logger
 }
 
 // Run Service.
@@ -73,10 +95,101 @@ func (s *Service) QueryData(ctx context.Context, user *models.SignedInUser, skip
 	if err != nil {
 		return nil, err
 	}
+
+	if err := s.enforceLabelRules(ctx, user, parsedReq); err != nil {
+		s.recordQueryAudit(user, reqDTO.HTTPRequest, parsedReq, nil, err, 0)
+		return nil, err
+	}
+
+	start := time.Now()
+	release, err := s.acquireDataSourceLimits(ctx, user, parsedReq)
+	if err != nil {
+		s.recordQueryAudit(user, reqDTO.HTTPRequest, parsedReq, nil, err, time.Since(start))
+		return nil, err
+	}
+	defer release()
+
+	var resp *backend.QueryDataResponse
 	if handleExpressions && parsedReq.hasExpression {
-		return s.handleExpressions(ctx, user, parsedReq)
+		resp, err = s.handleExpressions(ctx, user, parsedReq)
+	} else {
+		resp, err = s.handleQueryData(ctx, user, parsedReq)
+		if err == nil {
+			ds := parsedReq.parsedQueries[0].datasource
+			s.correlationsService.ApplyCorrelations(ctx, ds.OrgId, ds.Uid, resp)
+		}
 	}
-	return s.handleQueryData(ctx, user, parsedReq)
+	s.recordQueryAudit(user, reqDTO.HTTPRequest, parsedReq, resp, err, time.Since(start))
+
+	return resp, err
+}
+
+// acquireDataSourceLimits acquires the request limits of every datasource
+// used by the request. The returned function releases all of them. The limits
+// are acquired by datasource uid, so that requests using the same datasources
+// in a different order don't wait for each other while holding some of them.
+func (s *Service) acquireDataSourceLimits(ctx context.Context, user *models.SignedInUser, parsedReq *parsedRequest) (func(), error) {
+	byUID := map[string]*models.DataSource{}
+	for _, pq := range parsedReq.parsedQueries {
+		if pq.datasource != nil {
+			byUID[pq.datasource.Uid] = pq.datasource
+		}
+	}
+	uids := make([]string, 0, len(byUID))
+	for uid := range byUID {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+
+	releases := make([]func(), 0, len(uids))
+	releaseAll := func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+
+	for _, uid := range uids {
+		release, err := s.dataSourceLimiter.Acquire(ctx, byUID[uid], user)
+		if err != nil {
+			releaseAll()
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+
+	return releaseAll, nil
+}
+
+// enforceLabelRules injects the label matchers the teams of the user are
+// restricted to into the queries, before they reach the datasources.
+func (s *Service) enforceLabelRules(ctx context.Context, user *models.SignedInUser, parsedReq *parsedRequest) error {
+	matchersByUID := map[string][]*labels.Matcher{}
+	for i, pq := range parsedReq.parsedQueries {
+		if pq.datasource == nil || !labelenforcement.Supported(pq.datasource.Type) {
+			continue
+		}
+
+		matchers, ok := matchersByUID[pq.datasource.Uid]
+		if !ok {
+			var err error
+			matchers, err = s.labelEnforcementService.GetMatchers(ctx, user, pq.datasource)
+			if err != nil {
+				return err
+			}
+			matchersByUID[pq.datasource.Uid] = matchers
+		}
+		if len(matchers) == 0 {
+			continue
+		}
+
+		queryJSON, err := labelenforcement.EnforceQuery(pq.datasource.Type, pq.query.JSON, matchers)
+		if err != nil {
+			s.log.Debug("Failed to enforce label rules", "datasource", pq.datasource.Uid, "refId", pq.query.RefID, "error", err)
+			return NewErrBadQuery(fmt.Sprintf("query %s cannot be restricted to the labels of your teams: %s", pq.query.RefID, err))
+		}
+		parsedReq.parsedQueries[i].query.JSON = queryJSON
+	}
+	return nil
 }
 
 // handleExpressions handles POST /api/ds/query when there is an expression.
@@ -134,7 +247,8 @@ func (s *Service) handleQueryData(ctx context.Context, user *models.SignedInUser
 		Queries: []backend.DataQuery{},
 	}
 
-	if s.oAuthTokenService.IsOAuthPassThruEnabled(ds) {
+	oAuthPassThru := s.oAuthTokenService.IsOAuthPassThruEnabled(ds)
+	if oAuthPassThru {
 		if token := s.oAuthTokenService.GetCurrentOAuthToken(ctx, user); token != nil {
 			req.Headers["Authorization"] = fmt.Sprintf("%s %s", token.Type(), token.AccessToken)
 
@@ -153,6 +267,11 @@ func (s *Service) handleQueryData(ctx context.Context, user *models.SignedInUser
 		req.Queries = append(req.Queries, q.query)
 	}
 
+	if s.querySplitter != nil {
+		// responses depend on the user's token when OAuth pass-through is enabled
+		return s.querySplitter.QueryData(ctx, ds, req, !oAuthPassThru)
+	}
+
 	return s.pluginClient.QueryData(ctx, req)
 }
 
@@ -3,23 +3,37 @@ package query_test
 import (
 	"context"
 	"encoding/json"
+	"math"
 	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/prometheus/prometheus/pkg/labels"
 	"golang.org/x/oauth2"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
 	acmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/correlations/correlationstest"
+	"github.com/grafana/grafana/pkg/services/datasources/labelenforcement/labelenforcementtest"
+	"github.com/grafana/grafana/pkg/services/datasources/ratelimit"
 	datasources "github.com/grafana/grafana/pkg/services/datasources/service"
 	"github.com/grafana/grafana/pkg/services/featuremgmt"
 	"github.com/grafana/grafana/pkg/services/query"
+	"github.com/grafana/grafana/pkg/services/queryaudit"
+	"github.com/grafana/grafana/pkg/services/queryaudit/queryaudittest"
 	"github.com/grafana/grafana/pkg/services/secrets/fakes"
 	"github.com/grafana/grafana/pkg/services/secrets/kvstore"
 	secretsManager "github.com/grafana/grafana/pkg/services/secrets/manager"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tsdb/intervalv2"
 
 	"github.com/stretchr/testify/require"
 )
@@ -61,35 +75,292 @@ func TestQueryData(t *testing.T) {
 		}
 		require.Equal(t, expected, tc.pluginContext.req.Headers)
 	})
+
+	t.Run("it applies the data source limits", func(t *testing.T) {
+		tc := setup(t)
+		tc.dataSourceCache.ds.JsonData = simplejson.NewFromAny(map[string]interface{}{"maxQueriesPerSecondPerUser": 1})
+
+		_, err := tc.queryService.QueryData(context.Background(), nil, true, metricRequest(), false)
+		require.NoError(t, err)
+
+		_, err = tc.queryService.QueryData(context.Background(), nil, true, metricRequest(), false)
+		require.ErrorIs(t, err, ratelimit.ErrLimitExceeded)
+	})
+
+	t.Run("it records query audit entries", func(t *testing.T) {
+		tc := setup(t)
+		tc.dataSourceCache.ds.Uid = "ds-uid"
+		tc.dataSourceCache.ds.Type = "prometheus"
+		user := &models.SignedInUser{UserId: 2, OrgId: 1, Login: "viewer"}
+
+		req := metricRequest()
+		req.HTTPRequest = httptest.NewRequest(http.MethodPost, "/api/ds/query", nil)
+		req.HTTPRequest.Header.Set("X-Dashboard-Uid", "dash-uid")
+		req.HTTPRequest.Header.Set("X-Panel-Id", "4")
+
+		_, err := tc.queryService.QueryData(context.Background(), user, true, req, false)
+		require.NoError(t, err)
+
+		require.Len(t, tc.queryAuditService.Entries, 1)
+		entry := tc.queryAuditService.Entries[0]
+		require.Equal(t, queryaudit.SourceQuery, entry.Source)
+		require.Equal(t, int64(2), entry.UserID)
+		require.Equal(t, "viewer", entry.UserLogin)
+		require.Equal(t, "ds-uid", entry.DatasourceUID)
+		require.Equal(t, "prometheus", entry.DatasourceType)
+		require.Equal(t, "dash-uid", entry.DashboardUID)
+		require.Equal(t, int64(4), entry.PanelID)
+		require.JSONEq(t, `[{"datasourceId":1}]`, entry.Query)
+		require.Empty(t, entry.Error)
+	})
+
+	t.Run("it applies correlations of the data source", func(t *testing.T) {
+		tc := setup(t)
+		tc.dataSourceCache.ds.Uid = "ds-uid"
+		tc.dataSourceCache.ds.OrgId = 3
+
+		var orgID int64
+		var sourceUID string
+		tc.correlationsService.ApplyFn = func(o int64, uid string, resp *backend.QueryDataResponse) {
+			orgID, sourceUID = o, uid
+		}
+
+		_, err := tc.queryService.QueryData(context.Background(), nil, true, metricRequest(), false)
+		require.NoError(t, err)
+		require.Equal(t, int64(3), orgID)
+		require.Equal(t, "ds-uid", sourceUID)
+	})
+
+	t.Run("it restricts the queries to the labels of the teams of the user", func(t *testing.T) {
+		tc := setup(t)
+		tc.dataSourceCache.ds.Uid = "ds-uid"
+		tc.dataSourceCache.ds.Type = "prometheus"
+		tc.labelEnforcementService.ExpectedMatchers = []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "tenant", "a")}
+		user := &models.SignedInUser{UserId: 2, OrgId: 1, Login: "viewer"}
+
+		q, err := simplejson.NewJson([]byte(`{"datasourceId":1,"refId":"A","expr":"rate(up[$__rate_interval])"}`))
+		require.NoError(t, err)
+		req := dtos.MetricRequest{Queries: []*simplejson.Json{q}}
+
+		_, err = tc.queryService.QueryData(context.Background(), user, true, req, false)
+		require.NoError(t, err)
+		require.Len(t, tc.pluginContext.req.Queries, 1)
+		require.JSONEq(t, `{"datasourceId":1,"refId":"A","expr":"rate(up{tenant=\"a\"}[$__rate_interval])"}`, string(tc.pluginContext.req.Queries[0].JSON))
+
+		q, err = simplejson.NewJson([]byte(`{"datasourceId":1,"refId":"B","expr":"rate(up[5m]"}`))
+		require.NoError(t, err)
+		req = dtos.MetricRequest{Queries: []*simplejson.Json{q}}
+
+		_, err = tc.queryService.QueryData(context.Background(), user, true, req, false)
+		var badQuery *query.ErrBadQuery
+		require.ErrorAs(t, err, &badQuery)
+		require.Len(t, tc.pluginContext.reqs, 1)
+	})
+
+	t.Run("it splits long range queries", func(t *testing.T) {
+		cfg := setting.NewCfg()
+		cfg.QuerySplitting = setting.QuerySplittingSettings{
+			Enabled:        true,
+			MaxConcurrency: 2,
+			Intervals:      map[string]time.Duration{"prometheus": 24 * time.Hour},
+			CacheTTL:       time.Hour,
+		}
+		tc := setupWithCfg(t, cfg)
+		tc.dataSourceCache.ds.Uid = "ds-uid"
+		tc.dataSourceCache.ds.Type = "prometheus"
+		tc.pluginContext.queryDataFn = func(req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+			resp := backend.NewQueryDataResponse()
+			for _, q := range req.Queries {
+				resp.Responses[q.RefID] = backend.DataResponse{Frames: data.Frames{
+					data.NewFrame("up",
+						data.NewField("Time", nil, []time.Time{q.TimeRange.From, q.TimeRange.To}),
+						data.NewField("Value", data.Labels{"job": "grafana"}, []float64{1, 2}),
+					),
+				}}
+			}
+			return resp, nil
+		}
+
+		from := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+		to := from.Add(48 * time.Hour)
+		q, err := simplejson.NewJson([]byte(`{"datasourceId":1,"refId":"A","expr":"up","maxDataPoints":100}`))
+		require.NoError(t, err)
+		req := dtos.MetricRequest{
+			From:    strconv.FormatInt(from.UnixMilli(), 10),
+			To:      strconv.FormatInt(to.UnixMilli(), 10),
+			Queries: []*simplejson.Json{q},
+		}
+
+		resp, err := tc.queryService.QueryData(context.Background(), nil, true, req, false)
+		require.NoError(t, err)
+		require.Len(t, tc.pluginContext.reqs, 3)
+
+		frames := resp.Responses["A"].Frames
+		require.Len(t, frames, 1)
+		require.Equal(t, 4, frames[0].Fields[0].Len())
+		require.Equal(t, from, frames[0].Fields[0].At(0))
+		require.Equal(t, to, frames[0].Fields[0].At(3))
+
+		_, err = tc.queryService.QueryData(context.Background(), nil, true, req, false)
+		require.NoError(t, err)
+		require.Len(t, tc.pluginContext.reqs, 3, "completed sub-ranges should be served from the cache")
+	})
+}
+
+func TestQueryDataSplitting(t *testing.T) {
+	from := time.Date(2020, 1, 1, 12, 3, 17, 0, time.UTC)
+	to := from.Add(72 * time.Hour)
+
+	testCases := []struct {
+		dsType string
+		query  string
+	}{
+		{"prometheus", `{"datasourceId":1,"refId":"A","expr":"up","intervalMs":420000,"maxDataPoints":1000,"utcOffsetSec":3600}`},
+		{"prometheus", `{"datasourceId":1,"refId":"A","expr":"up","interval":"$__interval","intervalMs":1000,"maxDataPoints":500}`},
+		{"loki", `{"datasourceId":1,"refId":"A","expr":"rate({job=\"grafana\"}[5m])","intervalMs":420000,"maxDataPoints":1000}`},
+		{"loki", `{"datasourceId":1,"refId":"A","expr":"rate({job=\"grafana\"}[5m])","intervalMs":130000,"resolution":2}`},
+	}
+
+	for _, testCase := range testCases {
+		t.Run("merged sub-range results of "+testCase.query+" equal the unsplit results", func(t *testing.T) {
+			q, err := simplejson.NewJson([]byte(testCase.query))
+			require.NoError(t, err)
+			req := dtos.MetricRequest{
+				From:    strconv.FormatInt(from.UnixMilli(), 10),
+				To:      strconv.FormatInt(to.UnixMilli(), 10),
+				Queries: []*simplejson.Json{q},
+			}
+
+			queryData := func(splitting bool) (data.Frames, int) {
+				cfg := setting.NewCfg()
+				cfg.QuerySplitting = setting.QuerySplittingSettings{
+					Enabled:        splitting,
+					MaxConcurrency: 2,
+					Intervals:      map[string]time.Duration{testCase.dsType: 24 * time.Hour},
+				}
+				tc := setupWithCfg(t, cfg)
+				tc.dataSourceCache.ds.Type = testCase.dsType
+				tc.pluginContext.queryDataFn = func(req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+					resp := backend.NewQueryDataResponse()
+					for _, q := range req.Queries {
+						resp.Responses[q.RefID] = backend.DataResponse{Frames: data.Frames{evaluate(t, testCase.dsType, q)}}
+					}
+					return resp, nil
+				}
+
+				resp, err := tc.queryService.QueryData(context.Background(), nil, true, req, false)
+				require.NoError(t, err)
+				return resp.Responses["A"].Frames, len(tc.pluginContext.reqs)
+			}
+
+			unsplit, _ := queryData(false)
+			split, requests := queryData(true)
+			require.Equal(t, 4, requests)
+			require.Equal(t, unsplit, split)
+		})
+	}
+}
+
+// evaluate returns the points a data source of the type evaluates the range
+// query at, the value of each point is its timestamp.
+func evaluate(t *testing.T, dsType string, q backend.DataQuery) *data.Frame {
+	t.Helper()
+
+	model := struct {
+		Interval       string `json:"interval"`
+		IntervalMS     int64  `json:"intervalMS"`
+		IntervalFactor int64  `json:"intervalFactor"`
+		UtcOffsetSec   int64  `json:"utcOffsetSec"`
+		Resolution     int64  `json:"resolution"`
+	}{}
+	require.NoError(t, json.Unmarshal(q.JSON, &model))
+
+	var step time.Duration
+	start, end := q.TimeRange.From, q.TimeRange.To
+	switch dsType {
+	case "prometheus":
+		queryInterval := model.Interval
+		if queryInterval == "$__interval" {
+			queryInterval = ""
+		}
+		minInterval, err := intervalv2.GetIntervalFrom("", queryInterval, model.IntervalMS, 15*time.Second)
+		require.NoError(t, err)
+		calculator := intervalv2.NewCalculator()
+		step = calculator.Calculate(q.TimeRange, minInterval, q.MaxDataPoints).Value
+		if safe := calculator.CalculateSafeInterval(q.TimeRange, 11000).Value; safe > step {
+			step = safe
+		}
+		if model.IntervalFactor > 0 {
+			step *= time.Duration(model.IntervalFactor)
+		}
+		align := func(t time.Time) time.Time {
+			return time.Unix(int64(math.Floor(float64(t.Unix()+model.UtcOffsetSec)/step.Seconds())*step.Seconds())-model.UtcOffsetSec, 0).UTC()
+		}
+		start, end = align(start), align(end)
+	case "loki":
+		resolution := model.Resolution
+		if resolution == 0 {
+			resolution = 1
+		}
+		step = q.Interval * time.Duration(resolution)
+		if safe := q.TimeRange.Duration() / 11000; safe > step {
+			step = safe
+		}
+		step = step.Truncate(time.Millisecond)
+	}
+
+	times := []time.Time{}
+	values := []float64{}
+	for ts := start; !ts.After(end); ts = ts.Add(step) {
+		times = append(times, ts.UTC())
+		values = append(values, float64(ts.Unix()))
+	}
+	return data.NewFrame("",
+		data.NewField("Time", nil, times),
+		data.NewField("Value", data.Labels{"job": "grafana"}, values),
+	)
 }
 
 func setup(t *testing.T) *testContext {
+	return setupWithCfg(t, nil)
+}
+
+func setupWithCfg(t *testing.T, cfg *setting.Cfg) *testContext {
 	pc := &fakePluginClient{}
 	dc := &fakeDataSourceCache{ds: &models.DataSource{}}
 	tc := &fakeOAuthTokenService{}
 	rv := &fakePluginRequestValidator{}
+	qa := queryaudittest.NewQueryAuditServiceFake()
+	cs := correlationstest.NewCorrelationsServiceFake()
+	le := labelenforcementtest.NewLabelEnforcementServiceFake()
 
 	ss := kvstore.SetupTestService(t)
 	ssvc := secretsManager.SetupTestService(t, fakes.NewFakeSecretsStore())
 	ds := datasources.ProvideService(nil, ssvc, ss, nil, featuremgmt.WithFeatures(), acmock.New(), acmock.NewMockedPermissionsService())
 
 	return &testContext{
-		pluginContext:          pc,
-		secretStore:            ss,
-		dataSourceCache:        dc,
-		oauthTokenService:      tc,
-		pluginRequestValidator: rv,
-		queryService:           query.ProvideService(nil, dc, nil, rv, ds, pc, tc),
+		pluginContext:           pc,
+		secretStore:             ss,
+		dataSourceCache:         dc,
+		oauthTokenService:       tc,
+		pluginRequestValidator:  rv,
+		queryAuditService:       qa,
+		correlationsService:     cs,
+		labelEnforcementService: le,
+		queryService:            query.ProvideService(cfg, dc, nil, rv, ds, pc, tc, ratelimit.ProvideService(), qa, cs, le),
 	}
 }
 
 type testContext struct {
-	pluginContext          *fakePluginClient
-	secretStore            kvstore.SecretsKVStore
-	dataSourceCache        *fakeDataSourceCache
-	oauthTokenService      *fakeOAuthTokenService
-	pluginRequestValidator *fakePluginRequestValidator
-	queryService           *query.Service
+	pluginContext           *fakePluginClient
+	secretStore             kvstore.SecretsKVStore
+	dataSourceCache         *fakeDataSourceCache
+	oauthTokenService       *fakeOAuthTokenService
+	pluginRequestValidator  *fakePluginRequestValidator
+	queryAuditService       *queryaudittest.FakeQueryAuditService
+	correlationsService     *correlationstest.FakeCorrelationsService
+	labelEnforcementService *labelenforcementtest.FakeLabelEnforcementService
+	queryService            *query.Service
 }
 
 func metricRequest() dtos.MetricRequest {
@@ -138,10 +409,20 @@ func (c *fakeDataSourceCache) GetDatasourceByUID(ctx context.Context, datasource
 type fakePluginClient struct {
 	plugins.Client
 
-	req *backend.QueryDataRequest
+	mtx         sync.Mutex
+	req         *backend.QueryDataRequest
+	reqs        []*backend.QueryDataRequest
+	queryDataFn func(req *backend.QueryDataRequest) (*backend.QueryDataResponse, error)
 }
 
 func (c *fakePluginClient) QueryData(ctx context.Context, req *backend.QueryDataRequest) (*backend.QueryDataResponse, error) {
+	c.mtx.Lock()
 	c.req = req
+	c.reqs = append(c.reqs, req)
+	c.mtx.Unlock()
+
+	if c.queryDataFn != nil {
+		return c.queryDataFn(req)
+	}
 	return nil, nil
 }
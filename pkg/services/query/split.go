@@ -0,0 +1,488 @@
+package query
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/grafana/grafana/pkg/infra/localcache"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tsdb/intervalv2"
+)
+
+var subRangeQueries = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "grafana",
+	Subsystem: "query_splitting",
+	Name:      "sub_range_queries_total",
+	Help:      "A counter for sub-range queries of split queries, by whether they were served from the cache",
+}, []string{"datasource_type", "cache"})
+
+func init() {
+	prometheus.MustRegister(subRangeQueries)
+}
+
+// lokiRangeSelector matches the range selector of LogQL metric queries,
+// e.g. [5m] or [$__interval]. Log queries have no range selector.
+var lokiRangeSelector = regexp.MustCompile(`\[(\d+(ms|[smhdwy]))+\]|\[\$\{?__\w+\}?\]`)
+
+// querySplitter splits long range queries into sub-ranges aligned to a
+// per datasource type interval, executes them in parallel and merges the
+// resulting frames.
+type querySplitter struct {
+	cfg          setting.QuerySplittingSettings
+	pluginClient plugins.Client
+	cache        *localcache.CacheService
+	log          log.Logger
+}
+
+func newQuerySplitter(cfg setting.QuerySplittingSettings, pluginClient plugins.Client) *querySplitter {
+	qs := &querySplitter{
+		cfg:          cfg,
+		pluginClient: pluginClient,
+		log:          log.New("query_data.splitting"),
+	}
+	if cfg.CacheTTL > 0 {
+		qs.cache = localcache.New(cfg.CacheTTL, 10*time.Minute)
+	}
+	return qs
+}
+
+type splitQueryModel struct {
+	Expr           string `json:"expr"`
+	QueryType      string `json:"queryType"`
+	Instant        bool   `json:"instant"`
+	Exemplar       bool   `json:"exemplar"`
+	Interval       string `json:"interval"`
+	IntervalMS     int64  `json:"intervalMS"`
+	IntervalFactor int64  `json:"intervalFactor"`
+	UtcOffsetSec   int64  `json:"utcOffsetSec"`
+	Resolution     int64  `json:"resolution"`
+}
+
+// canSplit returns true if the query can be executed as several sub-range
+// queries whose results are merged without changing its meaning.
+func canSplit(dsType string, q backend.DataQuery) bool {
+	model := splitQueryModel{}
+	if err := json.Unmarshal(q.JSON, &model); err != nil || model.Expr == "" {
+		return false
+	}
+
+	// $__range would be interpolated to the length of the sub-range
+	if strings.Contains(model.Expr, "__range") {
+		return false
+	}
+
+	switch dsType {
+	case models.DS_PROMETHEUS:
+		// the step of $__rate_interval queries depends on the scrape
+		// interval, it cannot be pinned in the sub-range queries
+		if model.Interval == "$__rate_interval" || model.Interval == "${__rate_interval}" {
+			return false
+		}
+		return !model.Instant && !model.Exemplar
+	case models.DS_LOKI:
+		if model.QueryType == "instant" {
+			return false
+		}
+		// log queries are limited to a number of lines, splitting them
+		// would return more lines than requested
+		return lokiRangeSelector.MatchString(model.Expr)
+	default:
+		return false
+	}
+}
+
+// splitTimeRange splits the time range into sub-ranges whose boundaries are
+// aligned to multiples of the interval, rounded down to a multiple of the
+// step counted from the anchor. The data source evaluates the sub-ranges at
+// the same points as the whole range. Adjacent sub-ranges share their
+// boundary, points at a boundary are deduplicated when merging.
+func splitTimeRange(tr backend.TimeRange, interval, step time.Duration, anchor time.Time) []backend.TimeRange {
+	ranges := []backend.TimeRange{}
+	from := tr.From
+	for boundary := tr.From.Truncate(interval).Add(interval); boundary.Before(tr.To); boundary = boundary.Add(interval) {
+		to := alignToStep(boundary, step, anchor)
+		if !to.After(from) {
+			continue
+		}
+		ranges = append(ranges, backend.TimeRange{From: from, To: to})
+		from = to
+	}
+	return append(ranges, backend.TimeRange{From: from, To: tr.To})
+}
+
+// alignToStep rounds t down to a multiple of the step counted from the anchor.
+func alignToStep(t time.Time, step time.Duration, anchor time.Time) time.Time {
+	if step <= 0 {
+		return t
+	}
+	offset := t.Sub(anchor) % step
+	if offset < 0 {
+		offset += step
+	}
+	return t.Add(-offset)
+}
+
+// queryStep returns the step the data source evaluates the query of the whole
+// range with, and the anchor its evaluation points are aligned to. It
+// returns false if the step of the sub-ranges would differ.
+func queryStep(ds *models.DataSource, q backend.DataQuery) (time.Duration, time.Time, bool) {
+	model := splitQueryModel{}
+	if err := json.Unmarshal(q.JSON, &model); err != nil {
+		return 0, time.Time{}, false
+	}
+
+	switch ds.Type {
+	case models.DS_PROMETHEUS:
+		// same as the step calculated by the Prometheus data source, which
+		// aligns the start and end of the range to multiples of the step
+		queryInterval := model.Interval
+		if strings.HasPrefix(queryInterval, "$") {
+			queryInterval = ""
+		}
+		dsInterval := ""
+		if ds.JsonData != nil {
+			dsInterval = ds.JsonData.Get("timeInterval").MustString()
+		}
+		minInterval, err := intervalv2.GetIntervalFrom(dsInterval, queryInterval, model.IntervalMS, 15*time.Second)
+		if err != nil {
+			return 0, time.Time{}, false
+		}
+		calculator := intervalv2.NewCalculator()
+		step := calculator.Calculate(q.TimeRange, minInterval, q.MaxDataPoints).Value
+		if safe := calculator.CalculateSafeInterval(q.TimeRange, 11000).Value; safe > step {
+			step = safe
+		}
+		if model.IntervalFactor > 0 {
+			step *= time.Duration(model.IntervalFactor)
+		}
+		return step, time.Unix(-model.UtcOffsetSec, 0), step > 0
+	case models.DS_LOKI:
+		// same as the step calculated by the Loki data source. Loki evaluates
+		// the range from its start, so the step is counted from there. When
+		// the step is raised to limit the number of points of the whole
+		// range, it would be lower for the shorter sub-ranges.
+		resolution := int64(1)
+		if model.Resolution >= 1 && model.Resolution <= 5 || model.Resolution == 10 {
+			resolution = model.Resolution
+		}
+		step := ceilMs(q.Interval * time.Duration(resolution))
+		if safe := ceilMs(q.TimeRange.Duration() / 11000); safe > step {
+			return 0, time.Time{}, false
+		}
+		return step, q.TimeRange.From, step > 0
+	default:
+		return 0, time.Time{}, false
+	}
+}
+
+// pinStep makes the data source evaluate the sub-range query with the step of
+// the whole range.
+func pinStep(dsType string, q backend.DataQuery, step time.Duration) (backend.DataQuery, error) {
+	if dsType != models.DS_PROMETHEUS {
+		return q, nil
+	}
+
+	model := map[string]interface{}{}
+	if err := json.Unmarshal(q.JSON, &model); err != nil {
+		return q, err
+	}
+	// keys are matched case-insensitively when the query is parsed
+	for key := range model {
+		for _, pinned := range []string{"interval", "intervalMS", "intervalFactor"} {
+			if strings.EqualFold(key, pinned) {
+				delete(model, key)
+			}
+		}
+	}
+	model["intervalMS"] = step.Milliseconds()
+	model["intervalFactor"] = 1
+
+	raw, err := json.Marshal(model)
+	if err != nil {
+		return q, err
+	}
+	q.JSON = raw
+	// the step calculated from the max data points stays below the pinned one
+	q.MaxDataPoints = int64(q.TimeRange.Duration()/step) + 1
+	return q, nil
+}
+
+func ceilMs(d time.Duration) time.Duration {
+	return time.Duration(math.Ceil(float64(d)/float64(time.Millisecond))) * time.Millisecond
+}
+
+// QueryData executes the request, splitting the queries that support it.
+// Results of completed sub-ranges are cached if cacheable is true.
+func (qs *querySplitter) QueryData(ctx context.Context, ds *models.DataSource, req *backend.QueryDataRequest, cacheable bool) (*backend.QueryDataResponse, error) {
+	interval, ok := qs.cfg.Intervals[ds.Type]
+	if !ok {
+		return qs.pluginClient.QueryData(ctx, req)
+	}
+
+	unsplit := []backend.DataQuery{}
+	splitQueries := []backend.DataQuery{}
+	steps := map[string]time.Duration{}
+	anchors := map[string]time.Time{}
+	for _, q := range req.Queries {
+		if canSplit(ds.Type, q) && q.TimeRange.Duration() > interval {
+			if step, anchor, ok := queryStep(ds, q); ok && step < interval {
+				steps[q.RefID], anchors[q.RefID] = step, anchor
+				splitQueries = append(splitQueries, q)
+				continue
+			}
+		}
+		unsplit = append(unsplit, q)
+	}
+	if len(splitQueries) == 0 {
+		return qs.pluginClient.QueryData(ctx, req)
+	}
+
+	// results holds the responses per refID and sub-range
+	results := map[string][]*backend.DataResponse{}
+	pending := map[int][]backend.DataQuery{}
+	keys := map[string]string{}
+	ranges := map[string][]backend.TimeRange{}
+	cacheBefore := time.Now().Add(-qs.cfg.CacheMinAge)
+
+	for _, q := range splitQueries {
+		subRanges := splitTimeRange(q.TimeRange, interval, steps[q.RefID], anchors[q.RefID])
+		ranges[q.RefID] = subRanges
+		results[q.RefID] = make([]*backend.DataResponse, len(subRanges))
+
+		for i, tr := range subRanges {
+			sq := q
+			sq.TimeRange = tr
+			// keep the resolution of the whole range
+			sq.MaxDataPoints = int64(math.Ceil(float64(q.MaxDataPoints) * float64(tr.Duration()) / float64(q.TimeRange.Duration())))
+			if sq.MaxDataPoints < 1 {
+				sq.MaxDataPoints = 1
+			}
+			sq, err := pinStep(ds.Type, sq, steps[q.RefID])
+			if err != nil {
+				return nil, err
+			}
+
+			if cacheable && qs.cache != nil && tr.To.Before(cacheBefore) {
+				key := subRangeCacheKey(ds, sq)
+				keys[subRangeID(q.RefID, i)] = key
+				if cached, ok := qs.cache.Get(key); ok {
+					subRangeQueries.WithLabelValues(ds.Type, "hit").Inc()
+					results[q.RefID][i] = cached.(*backend.DataResponse)
+					continue
+				}
+			}
+
+			subRangeQueries.WithLabelValues(ds.Type, "miss").Inc()
+			pending[i] = append(pending[i], sq)
+		}
+	}
+
+	var mtx sync.Mutex
+	resp := backend.NewQueryDataResponse()
+	g, gctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, qs.cfg.MaxConcurrency)
+	run := func(queries []backend.DataQuery, handle func(*backend.QueryDataResponse)) {
+		g.Go(func() error {
+			select {
+			case sem <- struct{}{}:
+			case <-gctx.Done():
+				return gctx.Err()
+			}
+			defer func() { <-sem }()
+
+			subReq := *req
+			subReq.Queries = queries
+			subResp, err := qs.pluginClient.QueryData(gctx, &subReq)
+			if err != nil {
+				return err
+			}
+			if subResp == nil {
+				subResp = backend.NewQueryDataResponse()
+			}
+
+			mtx.Lock()
+			defer mtx.Unlock()
+			handle(subResp)
+			return nil
+		})
+	}
+
+	if len(unsplit) > 0 {
+		run(unsplit, func(subResp *backend.QueryDataResponse) {
+			for refID, dr := range subResp.Responses {
+				resp.Responses[refID] = dr
+			}
+		})
+	}
+
+	for i, queries := range pending {
+		i, queries := i, queries
+		run(queries, func(subResp *backend.QueryDataResponse) {
+			for _, q := range queries {
+				dr, ok := subResp.Responses[q.RefID]
+				if !ok {
+					continue
+				}
+				results[q.RefID][i] = &dr
+				if key, ok := keys[subRangeID(q.RefID, i)]; ok && dr.Error == nil {
+					qs.cache.SetDefault(key, &dr)
+				}
+			}
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	for refID, subResponses := range results {
+		resp.Responses[refID] = mergeDataResponses(subResponses)
+		qs.log.Debug("Split query", "refId", refID, "datasource", ds.Uid, "subRanges", len(ranges[refID]))
+	}
+
+	return resp, nil
+}
+
+func subRangeID(refID string, i int) string {
+	return fmt.Sprintf("%s/%d", refID, i)
+}
+
+func subRangeCacheKey(ds *models.DataSource, q backend.DataQuery) string {
+	h := sha256.New()
+	_, _ = fmt.Fprintf(h, "%d/%s/%d/%s/%d/%d/%d/%d/", ds.OrgId, ds.Uid, ds.Version, q.QueryType, q.Interval, q.MaxDataPoints,
+		q.TimeRange.From.UnixNano(), q.TimeRange.To.UnixNano())
+	_, _ = h.Write(q.JSON)
+	return "query-split-" + hex.EncodeToString(h.Sum(nil))
+}
+
+// mergeDataResponses merges the responses of consecutive sub-ranges. Frames
+// of the same series are concatenated in time order, points overlapping a
+// previous sub-range are dropped.
+func mergeDataResponses(responses []*backend.DataResponse) backend.DataResponse {
+	merged := backend.DataResponse{}
+	series := map[string]*data.Frame{}
+
+	for _, dr := range responses {
+		if dr == nil {
+			continue
+		}
+		if dr.Error != nil {
+			return backend.DataResponse{Error: dr.Error}
+		}
+
+		for _, frame := range dr.Frames {
+			key := seriesKey(frame)
+			target, ok := series[key]
+			if !ok {
+				target = emptyFrameLike(frame)
+				series[key] = target
+				merged.Frames = append(merged.Frames, target)
+			}
+			appendRows(target, frame)
+		}
+	}
+
+	return merged
+}
+
+func seriesKey(frame *data.Frame) string {
+	sb := strings.Builder{}
+	sb.WriteString(frame.Name)
+	for _, field := range frame.Fields {
+		sb.WriteString("\x00")
+		sb.WriteString(field.Name)
+		sb.WriteString(field.Type().String())
+		sb.WriteString(field.Labels.String())
+	}
+	return sb.String()
+}
+
+func emptyFrameLike(frame *data.Frame) *data.Frame {
+	fields := make([]*data.Field, 0, len(frame.Fields))
+	for _, field := range frame.Fields {
+		f := data.NewFieldFromFieldType(field.Type(), 0)
+		f.Name = field.Name
+		if field.Labels != nil {
+			f.Labels = field.Labels.Copy()
+		}
+		f.Config = field.Config
+		fields = append(fields, f)
+	}
+
+	merged := data.NewFrame(frame.Name, fields...)
+	merged.RefID = frame.RefID
+	merged.Meta = frame.Meta
+	return merged
+}
+
+// appendRows appends the rows of src to dst, skipping rows that are not
+// newer than the last row of dst. Frames with different fields are skipped.
+func appendRows(dst, src *data.Frame) {
+	if len(src.Fields) != len(dst.Fields) {
+		return
+	}
+	for j, field := range src.Fields {
+		if field.Type() != dst.Fields[j].Type() {
+			return
+		}
+	}
+
+	timeIndex := timeFieldIndex(src)
+	rows, err := src.RowLen()
+	if err != nil {
+		return
+	}
+
+	var last time.Time
+	hasLast := false
+	if timeIndex >= 0 {
+		if n := dst.Fields[timeIndex].Len(); n > 0 {
+			last, hasLast = timeAt(dst.Fields[timeIndex], n-1)
+		}
+	}
+
+	for i := 0; i < rows; i++ {
+		if hasLast {
+			if t, ok := timeAt(src.Fields[timeIndex], i); ok && !t.After(last) {
+				continue
+			}
+		}
+		for j, field := range src.Fields {
+			dst.Fields[j].Append(field.At(i))
+		}
+	}
+}
+
+func timeFieldIndex(frame *data.Frame) int {
+	for i, field := range frame.Fields {
+		if field.Type() == data.FieldTypeTime || field.Type() == data.FieldTypeNullableTime {
+			return i
+		}
+	}
+	return -1
+}
+
+func timeAt(field *data.Field, i int) (time.Time, bool) {
+	v, ok := field.ConcreteAt(i)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, ok := v.(time.Time)
+	return t, ok
+}
@@ -0,0 +1,62 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeDataResponses(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	times := func(offsets ...int) []time.Time {
+		ts := make([]time.Time, 0, len(offsets))
+		for _, o := range offsets {
+			ts = append(ts, start.Add(time.Duration(o)*time.Minute))
+		}
+		return ts
+	}
+	one, two := 1.0, 2.0
+
+	t.Run("frames with nullable and non-nullable values are merged into separate series", func(t *testing.T) {
+		merged := mergeDataResponses([]*backend.DataResponse{
+			{Frames: data.Frames{data.NewFrame("A",
+				data.NewField("time", nil, times(0, 1)),
+				data.NewField("value", nil, []float64{0, 1}),
+			)}},
+			{Frames: data.Frames{data.NewFrame("A",
+				data.NewField("time", nil, times(1, 2)),
+				data.NewField("value", nil, []*float64{&one, &two}),
+			)}},
+			{Frames: data.Frames{data.NewFrame("A",
+				data.NewField("time", nil, times(2, 3)),
+				data.NewField("value", nil, []float64{2, 3}),
+			)}},
+		})
+
+		require.NoError(t, merged.Error)
+		require.Len(t, merged.Frames, 2)
+		require.Equal(t, data.FieldTypeFloat64, merged.Frames[0].Fields[1].Type())
+		require.Equal(t, 4, merged.Frames[0].Fields[1].Len())
+		require.Equal(t, data.FieldTypeNullableFloat64, merged.Frames[1].Fields[1].Type())
+		require.Equal(t, 2, merged.Frames[1].Fields[1].Len())
+	})
+
+	t.Run("frames with different fields are not appended", func(t *testing.T) {
+		dst := data.NewFrame("A",
+			data.NewField("time", nil, times(0)),
+			data.NewField("value", nil, []float64{0}),
+		)
+		appendRows(dst, data.NewFrame("A", data.NewField("time", nil, times(1))))
+		appendRows(dst, data.NewFrame("A",
+			data.NewField("time", nil, times(1)),
+			data.NewField("value", nil, []*float64{&one}),
+		))
+
+		rows, err := dst.RowLen()
+		require.NoError(t, err)
+		require.Equal(t, 1, rows)
+	})
+}
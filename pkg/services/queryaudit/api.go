@@ -0,0 +1,56 @@
+package queryaudit
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/tsdb/legacydata"
+)
+
+func (s *QueryAuditService) registerAPIEndpoints() {
+	s.RouteRegister.Group("/api/admin/query-audit", func(entities routing.RouteRegister) {
+		entities.Get("/", middleware.ReqGrafanaAdmin, routing.Wrap(s.searchHandler))
+	})
+}
+
+func (s *QueryAuditService) searchHandler(c *models.ReqContext) response.Response {
+	query := &SearchQuery{
+		OrgID:         c.QueryInt64("orgId"),
+		UserID:        c.QueryInt64("userId"),
+		UserLogin:     c.Query("login"),
+		DatasourceUID: c.Query("datasourceUid"),
+		DashboardUID:  c.Query("dashboardUid"),
+		Source:        c.Query("source"),
+		QueryString:   c.Query("query"),
+		ErrorsOnly:    c.QueryBoolWithDefault("errorsOnly", false),
+		Page:          c.QueryInt("page"),
+		Limit:         c.QueryInt("limit"),
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	if from != "" || to != "" {
+		if from == "" {
+			from = "0"
+		}
+		if to == "" {
+			to = "now"
+		}
+		timeRange := legacydata.NewDataTimeRange(from, to)
+		query.From = timeRange.GetFromAsTimeUTC()
+		query.To = timeRange.GetToAsTimeUTC()
+	}
+
+	result, err := s.Search(c.Req.Context(), query)
+	if err != nil {
+		if errors.Is(err, ErrSearchNotSupported) {
+			return response.Error(http.StatusBadRequest, "Query audit search requires the sql sink", err)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to search query audit log", err)
+	}
+
+	return response.JSON(http.StatusOK, result)
+}
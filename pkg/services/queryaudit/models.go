@@ -0,0 +1,105 @@
+package queryaudit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+var ErrSearchNotSupported = errors.New("query audit search requires the sql sink")
+
+// Sources of audit entries.
+const (
+	SourceQuery = "query"
+	SourceProxy = "proxy"
+)
+
+// Entry is a single audited datasource request.
+type Entry struct {
+	ID             int64     `xorm:"pk autoincr 'id'" json:"id"`
+	Time           time.Time `xorm:"time" json:"time"`
+	Source         string    `xorm:"source" json:"source"`
+	OrgID          int64     `xorm:"org_id" json:"orgId"`
+	UserID         int64     `xorm:"user_id" json:"userId"`
+	UserLogin      string    `xorm:"user_login" json:"userLogin"`
+	DatasourceUID  string    `xorm:"datasource_uid" json:"datasourceUid"`
+	DatasourceType string    `xorm:"datasource_type" json:"datasourceType"`
+	DashboardUID   string    `xorm:"dashboard_uid" json:"dashboardUid,omitempty"`
+	DashboardID    int64     `xorm:"dashboard_id" json:"dashboardId,omitempty"`
+	PanelID        int64     `xorm:"panel_id" json:"panelId,omitempty"`
+	Query          string    `xorm:"query" json:"query"`
+	DurationMs     int64     `xorm:"duration_ms" json:"durationMs"`
+	BytesReturned  int64     `xorm:"bytes_returned" json:"bytesReturned"`
+	Error          string    `xorm:"error" json:"error,omitempty"`
+}
+
+func (Entry) TableName() string {
+	return "query_audit"
+}
+
+// NewEntry creates an entry for a request sent by user to ds.
+func NewEntry(source string, user *models.SignedInUser, ds *models.DataSource) *Entry {
+	entry := &Entry{
+		Time:   time.Now(),
+		Source: source,
+	}
+	if user != nil {
+		entry.OrgID = user.OrgId
+		entry.UserID = user.UserId
+		entry.UserLogin = user.Login
+	}
+	if ds != nil {
+		entry.OrgID = ds.OrgId
+		entry.DatasourceUID = ds.Uid
+		entry.DatasourceType = ds.Type
+	}
+	return entry
+}
+
+// SetRequestInfo sets the dashboard and panel the request was sent from,
+// read from the request headers or the route parameters.
+func (e *Entry) SetRequestInfo(req *http.Request) {
+	if req == nil {
+		return
+	}
+
+	params := web.Params(req)
+	e.DashboardUID = req.Header.Get("X-Dashboard-Uid")
+	if e.DashboardUID == "" {
+		e.DashboardUID = params[":dashboardUid"]
+	}
+	e.DashboardID, _ = strconv.ParseInt(req.Header.Get("X-Dashboard-Id"), 10, 64)
+
+	panelID := req.Header.Get("X-Panel-Id")
+	if panelID == "" {
+		panelID = params[":panelId"]
+	}
+	e.PanelID, _ = strconv.ParseInt(panelID, 10, 64)
+}
+
+// SearchQuery filters audit entries. Empty fields are ignored.
+type SearchQuery struct {
+	OrgID         int64
+	UserID        int64
+	UserLogin     string
+	DatasourceUID string
+	DashboardUID  string
+	Source        string
+	QueryString   string
+	ErrorsOnly    bool
+	From          time.Time
+	To            time.Time
+	Page          int
+	Limit         int
+}
+
+type SearchResult struct {
+	TotalCount int64    `json:"totalCount"`
+	Entries    []*Entry `json:"entries"`
+	Page       int      `json:"page"`
+	PerPage    int      `json:"perPage"`
+}
@@ -0,0 +1,208 @@
+package queryaudit
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	flushInterval     = time.Second
+	maxBatchSize      = 500
+	retentionInterval = time.Hour
+	shutdownTimeout   = 5 * time.Second
+)
+
+var (
+	entriesWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "query_audit",
+		Name:      "entries_written_total",
+		Help:      "A counter for query audit entries written per sink",
+	}, []string{"sink"})
+
+	writeFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "query_audit",
+		Name:      "write_failures_total",
+		Help:      "A counter for failed query audit batch writes per sink",
+	}, []string{"sink"})
+
+	entriesDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "query_audit",
+		Name:      "entries_dropped_total",
+		Help:      "A counter for query audit entries dropped because the buffer was full",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(entriesWritten, writeFailures, entriesDropped)
+}
+
+// Service records an audit trail of datasource queries.
+type Service interface {
+	// Enabled returns true if query auditing is enabled.
+	Enabled() bool
+	// Record queues an entry to be written to the configured sinks.
+	Record(entry *Entry)
+	// Search returns entries matching the query.
+	Search(ctx context.Context, query *SearchQuery) (SearchResult, error)
+}
+
+type QueryAuditService struct {
+	Cfg           *setting.Cfg
+	RouteRegister routing.RouteRegister
+	sinks         []Sink
+	entries       chan *Entry
+	log           log.Logger
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, routeRegister routing.RouteRegister) (*QueryAuditService, error) {
+	s := &QueryAuditService{
+		Cfg:           cfg,
+		RouteRegister: routeRegister,
+		log:           log.New("query-audit"),
+	}
+
+	if !cfg.QueryAudit.Enabled {
+		return s, nil
+	}
+
+	sinks, err := newSinks(cfg, sqlStore)
+	if err != nil {
+		return nil, err
+	}
+	s.sinks = sinks
+	s.entries = make(chan *Entry, cfg.QueryAudit.BufferSize)
+	s.registerAPIEndpoints()
+
+	return s, nil
+}
+
+func (s *QueryAuditService) IsDisabled() bool {
+	return !s.Enabled()
+}
+
+func (s *QueryAuditService) Enabled() bool {
+	return s.Cfg.QueryAudit.Enabled
+}
+
+func (s *QueryAuditService) Record(entry *Entry) {
+	if !s.Enabled() || entry == nil {
+		return
+	}
+
+	if !s.Cfg.QueryAudit.LogQueryText {
+		entry.Query = ""
+	} else if max := s.Cfg.QueryAudit.MaxQueryTextLength; max > 0 && len(entry.Query) > max {
+		entry.Query = entry.Query[:max]
+	}
+
+	select {
+	case s.entries <- entry:
+	default:
+		entriesDropped.Inc()
+		s.log.Warn("Query audit buffer is full, dropping entry", "user", entry.UserLogin, "datasource", entry.DatasourceUID)
+	}
+}
+
+func (s *QueryAuditService) Search(ctx context.Context, query *SearchQuery) (SearchResult, error) {
+	for _, sink := range s.sinks {
+		if searcher, ok := sink.(Searcher); ok {
+			return searcher.Search(ctx, query)
+		}
+	}
+	return SearchResult{}, ErrSearchNotSupported
+}
+
+// Run writes the queued entries to the sinks in batches.
+func (s *QueryAuditService) Run(ctx context.Context) error {
+	flushTicker := time.NewTicker(flushInterval)
+	defer flushTicker.Stop()
+	retentionTicker := time.NewTicker(retentionInterval)
+	defer retentionTicker.Stop()
+
+	s.deleteExpired(ctx)
+
+	batch := make([]*Entry, 0, maxBatchSize)
+	for {
+		select {
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+			if len(batch) >= maxBatchSize {
+				s.write(ctx, batch)
+				batch = make([]*Entry, 0, maxBatchSize)
+			}
+		case <-flushTicker.C:
+			if len(batch) > 0 {
+				s.write(ctx, batch)
+				batch = make([]*Entry, 0, maxBatchSize)
+			}
+		case <-retentionTicker.C:
+			s.deleteExpired(ctx)
+		case <-ctx.Done():
+			s.shutdown(batch)
+			return ctx.Err()
+		}
+	}
+}
+
+// shutdown writes the remaining entries and closes the sinks.
+func (s *QueryAuditService) shutdown(batch []*Entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+drain:
+	for {
+		select {
+		case entry := <-s.entries:
+			batch = append(batch, entry)
+		default:
+			break drain
+		}
+	}
+	if len(batch) > 0 {
+		s.write(ctx, batch)
+	}
+
+	for _, sink := range s.sinks {
+		if err := sink.Close(); err != nil {
+			s.log.Warn("Failed to close query audit sink", "sink", sink.Name(), "error", err)
+		}
+	}
+}
+
+func (s *QueryAuditService) write(ctx context.Context, batch []*Entry) {
+	for _, sink := range s.sinks {
+		if err := sink.Write(ctx, batch); err != nil {
+			writeFailures.WithLabelValues(sink.Name()).Inc()
+			s.log.Error("Failed to write query audit entries", "sink", sink.Name(), "entries", len(batch), "error", err)
+			continue
+		}
+		entriesWritten.WithLabelValues(sink.Name()).Add(float64(len(batch)))
+	}
+}
+
+func (s *QueryAuditService) deleteExpired(ctx context.Context) {
+	for _, sink := range s.sinks {
+		pruner, ok := sink.(Pruner)
+		if !ok {
+			continue
+		}
+		deleted, err := pruner.DeleteExpired(ctx)
+		if err != nil {
+			s.log.Error("Failed to delete expired query audit entries", "sink", sink.Name(), "error", err)
+			continue
+		}
+		if deleted > 0 {
+			s.log.Debug("Deleted expired query audit entries", "sink", sink.Name(), "count", deleted)
+		}
+	}
+}
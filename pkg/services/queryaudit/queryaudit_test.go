@@ -0,0 +1,126 @@
+package queryaudit
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestQueryAuditService_Record(t *testing.T) {
+	newService := func(settings setting.QueryAuditSettings) *QueryAuditService {
+		cfg := setting.NewCfg()
+		cfg.QueryAudit = settings
+		return &QueryAuditService{
+			Cfg:     cfg,
+			entries: make(chan *Entry, settings.BufferSize),
+		}
+	}
+
+	t.Run("should truncate the query text", func(t *testing.T) {
+		s := newService(setting.QueryAuditSettings{Enabled: true, LogQueryText: true, MaxQueryTextLength: 4, BufferSize: 1})
+		s.Record(&Entry{Query: "sum(rate(up[5m]))"})
+		entry := <-s.entries
+		require.Equal(t, "sum(", entry.Query)
+	})
+
+	t.Run("should strip the query text when disabled", func(t *testing.T) {
+		s := newService(setting.QueryAuditSettings{Enabled: true, LogQueryText: false, BufferSize: 1})
+		s.Record(&Entry{Query: "up"})
+		entry := <-s.entries
+		require.Empty(t, entry.Query)
+	})
+
+	t.Run("should drop entries when the buffer is full", func(t *testing.T) {
+		s := newService(setting.QueryAuditSettings{Enabled: true, BufferSize: 1})
+		s.log = log.New("query-audit.test")
+		dropped := testutil.ToFloat64(entriesDropped)
+		s.Record(&Entry{DatasourceUID: "a"})
+		s.Record(&Entry{DatasourceUID: "b"})
+		require.Len(t, s.entries, 1)
+		require.Equal(t, dropped+1, testutil.ToFloat64(entriesDropped))
+		entry := <-s.entries
+		require.Equal(t, "a", entry.DatasourceUID)
+	})
+
+	t.Run("should not record when disabled", func(t *testing.T) {
+		s := newService(setting.QueryAuditSettings{Enabled: false, BufferSize: 1})
+		s.Record(&Entry{DatasourceUID: "a"})
+		require.Len(t, s.entries, 0)
+	})
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "query-audit.log")
+	sink, err := newFileSink(setting.QueryAuditSettings{FilePath: path, FileMaxLines: 1000, FileMaxSizeMB: 10})
+	require.NoError(t, err)
+
+	err = sink.Write(context.Background(), []*Entry{
+		{Source: SourceQuery, DatasourceUID: "a"},
+		{Source: SourceProxy, DatasourceUID: "b"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 2)
+
+	var entry Entry
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	require.Equal(t, SourceProxy, entry.Source)
+	require.Equal(t, "b", entry.DatasourceUID)
+}
+
+func TestLokiSink(t *testing.T) {
+	var received lokiPushRequest
+	var tenant string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, lokiPushPath, r.URL.Path)
+		tenant = r.Header.Get("X-Scope-OrgID")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	sink, err := newLokiSink(setting.QueryAuditSettings{LokiURL: server.URL, LokiTenantID: "tenant", LokiTimeout: time.Second})
+	require.NoError(t, err)
+
+	now := time.Now()
+	err = sink.Write(context.Background(), []*Entry{
+		{Time: now, Source: SourceQuery},
+		{Time: now, Source: SourceProxy},
+		{Time: now, Source: SourceQuery},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "tenant", tenant)
+	require.Len(t, received.Streams, 2)
+	require.Equal(t, SourceQuery, received.Streams[0].Stream["source"])
+	require.Len(t, received.Streams[0].Values, 2)
+	require.Len(t, received.Streams[1].Values, 1)
+
+	t.Run("should return an error for non 2xx responses", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+		}))
+		t.Cleanup(failing.Close)
+
+		sink, err := newLokiSink(setting.QueryAuditSettings{LokiURL: failing.URL, LokiTimeout: time.Second})
+		require.NoError(t, err)
+		err = sink.Write(context.Background(), []*Entry{{Time: now, Source: SourceQuery}})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "429")
+	})
+}
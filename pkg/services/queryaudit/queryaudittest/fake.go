@@ -0,0 +1,38 @@
+package queryaudittest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/grafana/pkg/services/queryaudit"
+)
+
+type FakeQueryAuditService struct {
+	Disabled       bool
+	ExpectedResult queryaudit.SearchResult
+	ExpectedError  error
+
+	mu      sync.Mutex
+	Entries []*queryaudit.Entry
+}
+
+func NewQueryAuditServiceFake() *FakeQueryAuditService {
+	return &FakeQueryAuditService{}
+}
+
+func (f *FakeQueryAuditService) Enabled() bool {
+	return !f.Disabled
+}
+
+func (f *FakeQueryAuditService) Record(entry *queryaudit.Entry) {
+	if f.Disabled {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Entries = append(f.Entries, entry)
+}
+
+func (f *FakeQueryAuditService) Search(ctx context.Context, query *queryaudit.SearchQuery) (queryaudit.SearchResult, error) {
+	return f.ExpectedResult, f.ExpectedError
+}
@@ -0,0 +1,55 @@
+package queryaudit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	fileSinkName = "file"
+	sqlSinkName  = "sql"
+	lokiSinkName = "loki"
+)
+
+// Sink writes audit entries to a storage backend.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, entries []*Entry) error
+	Close() error
+}
+
+// Searcher is implemented by sinks that can be searched through the admin API.
+type Searcher interface {
+	Search(ctx context.Context, query *SearchQuery) (SearchResult, error)
+}
+
+// Pruner is implemented by sinks that need to delete expired entries themselves.
+type Pruner interface {
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+func newSinks(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore) ([]Sink, error) {
+	sinks := make([]Sink, 0, len(cfg.QueryAudit.Sinks))
+	for _, name := range cfg.QueryAudit.Sinks {
+		var sink Sink
+		var err error
+		switch name {
+		case fileSinkName:
+			sink, err = newFileSink(cfg.QueryAudit)
+		case sqlSinkName:
+			sink = newSQLSink(sqlStore, cfg.QueryAudit.SQLRetention)
+		case lokiSinkName:
+			sink, err = newLokiSink(cfg.QueryAudit)
+		default:
+			err = fmt.Errorf("unknown query audit sink %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
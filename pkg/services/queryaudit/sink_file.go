@@ -0,0 +1,56 @@
+package queryaudit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// fileSink writes entries as JSON lines to a rotating file.
+type fileSink struct {
+	writer *log.FileLogWriter
+}
+
+func newFileSink(cfg setting.QueryAuditSettings) (*fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.FilePath), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create query audit log directory: %w", err)
+	}
+
+	w := log.NewFileWriter()
+	w.Filename = cfg.FilePath
+	w.Maxlines = cfg.FileMaxLines
+	w.Maxsize = cfg.FileMaxSizeMB << 20
+	w.Daily = cfg.FileDailyRotate
+	w.Maxdays = cfg.FileMaxDays
+	if err := w.StartLogger(); err != nil {
+		return nil, fmt.Errorf("failed to open query audit log file: %w", err)
+	}
+
+	return &fileSink{writer: w}, nil
+}
+
+func (s *fileSink) Name() string {
+	return fileSinkName
+}
+
+func (s *fileSink) Write(_ context.Context, entries []*Entry) error {
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := s.writer.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *fileSink) Close() error {
+	return s.writer.Close()
+}
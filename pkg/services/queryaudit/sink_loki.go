@@ -0,0 +1,117 @@
+package queryaudit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const lokiPushPath = "/loki/api/v1/push"
+
+// lokiSink pushes entries to Loki, one stream per entry source.
+type lokiSink struct {
+	url               string
+	tenantID          string
+	basicAuthUser     string
+	basicAuthPassword string
+	client            *http.Client
+}
+
+func newLokiSink(cfg setting.QueryAuditSettings) (*lokiSink, error) {
+	if cfg.LokiURL == "" {
+		return nil, errors.New("query audit loki sink requires url to be set")
+	}
+	u, err := url.Parse(cfg.LokiURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query audit loki url: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + lokiPushPath
+
+	return &lokiSink{
+		url:               u.String(),
+		tenantID:          cfg.LokiTenantID,
+		basicAuthUser:     cfg.LokiBasicAuthUsername,
+		basicAuthPassword: cfg.LokiBasicAuthPassword,
+		client:            &http.Client{Timeout: cfg.LokiTimeout},
+	}, nil
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+type lokiPushRequest struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+func (s *lokiSink) Name() string {
+	return lokiSinkName
+}
+
+func (s *lokiSink) Write(ctx context.Context, entries []*Entry) error {
+	streams := map[string]*lokiStream{}
+	push := lokiPushRequest{}
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+
+		stream, ok := streams[entry.Source]
+		if !ok {
+			stream = &lokiStream{
+				Stream: map[string]string{"job": "grafana_query_audit", "source": entry.Source},
+			}
+			streams[entry.Source] = stream
+			push.Streams = append(push.Streams, stream)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(entry.Time.UnixNano(), 10), string(line)})
+	}
+
+	body, err := json.Marshal(push)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", s.tenantID)
+	}
+	if s.basicAuthUser != "" {
+		req.SetBasicAuth(s.basicAuthUser, s.basicAuthPassword)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("loki push failed with status %d: %s", resp.StatusCode, string(msg))
+	}
+	return nil
+}
+
+func (s *lokiSink) Close() error {
+	return nil
+}
@@ -0,0 +1,156 @@
+package queryaudit
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 5000
+	deleteBatchSize    = 1000
+)
+
+// sqlSink stores entries in the query_audit table.
+type sqlSink struct {
+	sqlStore  *sqlstore.SQLStore
+	retention time.Duration
+}
+
+func newSQLSink(sqlStore *sqlstore.SQLStore, retention time.Duration) *sqlSink {
+	return &sqlSink{
+		sqlStore:  sqlStore,
+		retention: retention,
+	}
+}
+
+func (s *sqlSink) Name() string {
+	return sqlSinkName
+}
+
+func (s *sqlSink) Write(ctx context.Context, entries []*Entry) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.InsertMulti(entries)
+		return err
+	})
+}
+
+func (s *sqlSink) Close() error {
+	return nil
+}
+
+// DeleteExpired deletes entries older than the retention in batches to avoid
+// holding long locks on the table.
+func (s *sqlSink) DeleteExpired(ctx context.Context) (int64, error) {
+	if s.retention <= 0 {
+		return 0, nil
+	}
+
+	before := time.Now().Add(-s.retention)
+	var total int64
+	for {
+		var affected int64
+		err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+			var ids []int64
+			if err := sess.Table("query_audit").Cols("id").Where("time < ?", before).Limit(deleteBatchSize).Find(&ids); err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+
+			var err error
+			affected, err = sess.In("id", ids).Delete(&Entry{})
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+
+		total += affected
+		if affected < deleteBatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (s *sqlSink) Search(ctx context.Context, query *SearchQuery) (SearchResult, error) {
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+	if query.Limit <= 0 {
+		query.Limit = defaultSearchLimit
+	}
+	if query.Limit > maxSearchLimit {
+		query.Limit = maxSearchLimit
+	}
+
+	result := SearchResult{
+		Entries: make([]*Entry, 0),
+		Page:    query.Page,
+		PerPage: query.Limit,
+	}
+
+	filter := strings.Builder{}
+	params := make([]interface{}, 0)
+	and := func(sql string, args ...interface{}) {
+		if filter.Len() > 0 {
+			filter.WriteString(" AND ")
+		}
+		filter.WriteString(sql)
+		params = append(params, args...)
+	}
+
+	if query.OrgID > 0 {
+		and("org_id = ?", query.OrgID)
+	}
+	if query.UserID > 0 {
+		and("user_id = ?", query.UserID)
+	}
+	if query.UserLogin != "" {
+		and("user_login = ?", query.UserLogin)
+	}
+	if query.DatasourceUID != "" {
+		and("datasource_uid = ?", query.DatasourceUID)
+	}
+	if query.DashboardUID != "" {
+		and("dashboard_uid = ?", query.DashboardUID)
+	}
+	if query.Source != "" {
+		and("source = ?", query.Source)
+	}
+	if query.QueryString != "" {
+		and("query "+s.sqlStore.Dialect.LikeStr()+" ?", "%"+query.QueryString+"%")
+	}
+	if query.ErrorsOnly {
+		and("error <> ?", "")
+	}
+	if !query.From.IsZero() {
+		and("time >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		and("time <= ?", query.To)
+	}
+
+	where := filter.String()
+	if where == "" {
+		where = "1 = 1"
+	}
+
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		count, err := sess.Table("query_audit").Where(where, params...).Count()
+		if err != nil {
+			return err
+		}
+		result.TotalCount = count
+
+		return sess.Table("query_audit").Where(where, params...).
+			Desc("time").
+			Limit(query.Limit, (query.Page-1)*query.Limit).
+			Find(&result.Entries)
+	})
+	return result, err
+}
@@ -0,0 +1,257 @@
+package reporting
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/tsdb/legacydata"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const (
+	defaultTimeFrom = "now-24h"
+	defaultTimeTo   = "now"
+)
+
+func (s *ReportingService) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(s.accessControl)
+
+	s.routeRegister.Group("/api/reports", func(reportsRoute routing.RouteRegister) {
+		reportsRoute.Get("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionRead)), routing.Wrap(s.getReportsHandler))
+		reportsRoute.Post("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionCreate)), routing.Wrap(s.createReportHandler))
+		reportsRoute.Get("/:id", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionRead, ScopeID)), routing.Wrap(s.getReportHandler))
+		reportsRoute.Put("/:id", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionWrite, ScopeID)), routing.Wrap(s.updateReportHandler))
+		reportsRoute.Delete("/:id", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionDelete, ScopeID)), routing.Wrap(s.deleteReportHandler))
+		reportsRoute.Post("/:id/send", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(ActionSend, ScopeID)), routing.Wrap(s.sendReportHandler))
+	})
+}
+
+// GET /api/reports
+func (s *ReportingService) getReportsHandler(c *models.ReqContext) response.Response {
+	reports, err := s.getReports(c.Req.Context(), c.OrgId)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get reports", err)
+	}
+
+	dtos := make([]*ReportDTO, 0, len(reports))
+	for _, report := range reports {
+		dtos = append(dtos, report.ToDTO())
+	}
+	return response.JSON(http.StatusOK, dtos)
+}
+
+// GET /api/reports/:id
+func (s *ReportingService) getReportHandler(c *models.ReqContext) response.Response {
+	id, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+
+	report, err := s.getReport(c.Req.Context(), c.OrgId, id)
+	if err != nil {
+		if errors.Is(err, ErrReportNotFound) {
+			return response.Error(http.StatusNotFound, "Report not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get report", err)
+	}
+	return response.JSON(http.StatusOK, report.ToDTO())
+}
+
+// POST /api/reports
+func (s *ReportingService) createReportHandler(c *models.ReqContext) response.Response {
+	cmd := SaveReportCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	now := time.Now()
+	report := &Report{OrgID: c.OrgId, Created: now}
+	if errResp := s.applyCommand(c.Req.Context(), c.SignedInUser, report, &cmd, now); errResp != nil {
+		return errResp
+	}
+
+	if err := s.createReport(c.Req.Context(), report); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to create report", err)
+	}
+	return response.JSON(http.StatusOK, report.ToDTO())
+}
+
+// PUT /api/reports/:id
+func (s *ReportingService) updateReportHandler(c *models.ReqContext) response.Response {
+	id, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+	cmd := SaveReportCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	report, err := s.getReport(c.Req.Context(), c.OrgId, id)
+	if err != nil {
+		if errors.Is(err, ErrReportNotFound) {
+			return response.Error(http.StatusNotFound, "Report not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get report", err)
+	}
+
+	if errResp := s.applyCommand(c.Req.Context(), c.SignedInUser, report, &cmd, time.Now()); errResp != nil {
+		return errResp
+	}
+
+	if err := s.updateReport(c.Req.Context(), report); err != nil {
+		if errors.Is(err, ErrReportNotFound) {
+			return response.Error(http.StatusNotFound, "Report not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to update report", err)
+	}
+	return response.JSON(http.StatusOK, report.ToDTO())
+}
+
+// DELETE /api/reports/:id
+func (s *ReportingService) deleteReportHandler(c *models.ReqContext) response.Response {
+	id, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+
+	if err := s.deleteReport(c.Req.Context(), c.OrgId, id); err != nil {
+		if errors.Is(err, ErrReportNotFound) {
+			return response.Error(http.StatusNotFound, "Report not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to delete report", err)
+	}
+	return response.Success("Report deleted")
+}
+
+// POST /api/reports/:id/send
+func (s *ReportingService) sendReportHandler(c *models.ReqContext) response.Response {
+	id, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+
+	report, err := s.getReport(c.Req.Context(), c.OrgId, id)
+	if err != nil {
+		if errors.Is(err, ErrReportNotFound) {
+			return response.Error(http.StatusNotFound, "Report not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get report", err)
+	}
+
+	// sending now does not change the schedule of the report
+	if err := s.Send(c.Req.Context(), report); err != nil {
+		if errors.Is(err, ErrDashboardNotFound) {
+			return response.Error(http.StatusNotFound, "Dashboard not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to send report", err)
+	}
+	return response.Success("Report sent")
+}
+
+// applyCommand validates the command and applies it to the report, which is then rendered with the permissions
+// of the user saving it
+func (s *ReportingService) applyCommand(ctx context.Context, user *models.SignedInUser, report *Report,
+	cmd *SaveReportCommand, now time.Time) response.Response {
+	if user.UserId == 0 {
+		return response.Error(http.StatusBadRequest, "Reports must be saved by a user or a service account", nil)
+	}
+
+	if cmd.TimeFrom == "" {
+		cmd.TimeFrom = defaultTimeFrom
+	}
+	if cmd.TimeTo == "" {
+		cmd.TimeTo = defaultTimeTo
+	}
+	timeRange := legacydata.NewDataTimeRange(cmd.TimeFrom, cmd.TimeTo)
+	if _, err := timeRange.ParseFrom(); err != nil {
+		return response.Error(http.StatusBadRequest, "Time range is invalid", err)
+	}
+	if _, err := timeRange.ParseTo(); err != nil {
+		return response.Error(http.StatusBadRequest, "Time range is invalid", err)
+	}
+
+	next, err := nextRun(cmd.Schedule, cmd.Timezone, now)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+
+	formats, err := validateFormats(cmd.Formats)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+	recipients, err := validateRecipients(cmd.Recipients)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+
+	if _, err := s.getDashboard(ctx, cmd.DashboardUID, user); err != nil {
+		if errors.Is(err, ErrDashboardNotFound) {
+			return response.Error(http.StatusNotFound, "Dashboard not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get dashboard", err)
+	}
+
+	report.UserID = user.UserId
+	report.Name = cmd.Name
+	report.DashboardUID = cmd.DashboardUID
+	report.TimeFrom = cmd.TimeFrom
+	report.TimeTo = cmd.TimeTo
+	report.Variables = cmd.Variables
+	report.Schedule = cmd.Schedule
+	report.Timezone = cmd.Timezone
+	report.Recipients = recipients
+	report.Formats = formats
+	report.Message = cmd.Message
+	report.NextRun = next
+	report.Updated = now
+	return nil
+}
+
+func validateFormats(formats []string) ([]string, error) {
+	if len(formats) == 0 {
+		return []string{FormatPDF}, nil
+	}
+
+	valid := make([]string, 0, len(formats))
+	seen := map[string]bool{}
+	for _, format := range formats {
+		format = strings.ToLower(format)
+		if format != FormatPDF && format != FormatPNG {
+			return nil, ErrInvalidFormat
+		}
+		if !seen[format] {
+			seen[format] = true
+			valid = append(valid, format)
+		}
+	}
+	return valid, nil
+}
+
+func validateRecipients(recipients []string) ([]string, error) {
+	valid := make([]string, 0, len(recipients))
+	for _, recipient := range recipients {
+		recipient = strings.TrimSpace(recipient)
+		if recipient == "" {
+			continue
+		}
+		if !util.IsEmail(recipient) {
+			return nil, ErrInvalidRecipient
+		}
+		valid = append(valid, recipient)
+	}
+	if len(valid) == 0 {
+		return nil, ErrNoRecipients
+	}
+	return valid, nil
+}
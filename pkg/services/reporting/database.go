@@ -0,0 +1,99 @@
+package reporting
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func (s *ReportingService) getReports(ctx context.Context, orgID int64) ([]*Report, error) {
+	reports := make([]*Report, 0)
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("org_id = ?", orgID).Asc("name", "id").Find(&reports)
+	})
+	return reports, err
+}
+
+func (s *ReportingService) getReport(ctx context.Context, orgID int64, id int64) (*Report, error) {
+	report := &Report{}
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		exists, err := sess.Where("org_id = ? AND id = ?", orgID, id).Get(report)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrReportNotFound
+		}
+		return nil
+	})
+	return report, err
+}
+
+func (s *ReportingService) createReport(ctx context.Context, report *Report) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Insert(report)
+		return err
+	})
+}
+
+// updateReport saves the definition of the report, and keeps the outcome of its last run
+func (s *ReportingService) updateReport(ctx context.Context, report *Report) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		affected, err := sess.Where("org_id = ? AND id = ?", report.OrgID, report.ID).
+			Cols("user_id", "name", "dashboard_uid", "time_from", "time_to", "variables", "schedule", "timezone",
+				"recipients", "formats", "message", "next_run", "updated").
+			Update(report)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrReportNotFound
+		}
+		return nil
+	})
+}
+
+func (s *ReportingService) deleteReport(ctx context.Context, orgID int64, id int64) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		affected, err := sess.Where("org_id = ? AND id = ?", orgID, id).Delete(&Report{})
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrReportNotFound
+		}
+		return nil
+	})
+}
+
+func (s *ReportingService) getDueReports(ctx context.Context, now time.Time) ([]*Report, error) {
+	reports := make([]*Report, 0)
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("next_run <= ?", now).Asc("next_run", "id").Find(&reports)
+	})
+	return reports, err
+}
+
+// claimReport schedules the next run of a due report, and reports whether it was still due, so that it is only
+// sent once
+func (s *ReportingService) claimReport(ctx context.Context, report *Report, nextRun time.Time) (bool, error) {
+	var claimed bool
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		affected, err := sess.ID(report.ID).Where("next_run <= ?", report.NextRun).Cols("next_run").
+			Update(&Report{NextRun: nextRun})
+		claimed = affected == 1
+		return err
+	})
+	if claimed {
+		report.NextRun = nextRun
+	}
+	return claimed, err
+}
+
+func (s *ReportingService) recordRun(ctx context.Context, id int64, lastRun time.Time, lastError string) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.ID(id).Cols("last_run", "last_error").Update(&Report{LastRun: &lastRun, LastError: lastError})
+		return err
+	})
+}
@@ -0,0 +1,120 @@
+package reporting
+
+import (
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+const (
+	ActionRead   = "reports:read"
+	ActionCreate = "reports:create"
+	ActionWrite  = "reports:write"
+	ActionDelete = "reports:delete"
+	ActionSend   = "reports:send"
+
+	ScopeAll = "reports:*"
+)
+
+var ScopeID = accesscontrol.Scope("reports", "id", accesscontrol.Parameter(":id"))
+
+const (
+	FormatPDF = "pdf"
+	FormatPNG = "png"
+)
+
+var (
+	ErrReportNotFound     = errors.New("report not found")
+	ErrDashboardNotFound  = errors.New("dashboard not found")
+	ErrInvalidSchedule    = errors.New("schedule is not a valid cron expression")
+	ErrInvalidTimezone    = errors.New("timezone is invalid")
+	ErrInvalidFormat      = errors.New("formats must be pdf or png")
+	ErrInvalidRecipient   = errors.New("recipients must be email addresses")
+	ErrNoRecipients       = errors.New("at least one recipient is required")
+	ErrAttachmentTooLarge = errors.New("report attachments exceed the maximum size")
+)
+
+// Report is a dashboard sent by email on a schedule, rendered with the permissions of the user who last saved
+// the report
+type Report struct {
+	ID           int64               `xorm:"pk autoincr 'id'"`
+	OrgID        int64               `xorm:"org_id"`
+	UserID       int64               `xorm:"user_id"`
+	Name         string              `xorm:"name"`
+	DashboardUID string              `xorm:"dashboard_uid"`
+	TimeFrom     string              `xorm:"time_from"`
+	TimeTo       string              `xorm:"time_to"`
+	Variables    map[string][]string `xorm:"variables"`
+	Schedule     string              `xorm:"schedule"`
+	Timezone     string              `xorm:"timezone"`
+	Recipients   []string            `xorm:"recipients"`
+	Formats      []string            `xorm:"formats"`
+	Message      string              `xorm:"message"`
+	NextRun      time.Time           `xorm:"next_run"`
+	LastRun      *time.Time          `xorm:"last_run"`
+	LastError    string              `xorm:"last_error"`
+	Created      time.Time
+	Updated      time.Time
+}
+
+func (Report) TableName() string {
+	return "report"
+}
+
+type ReportDTO struct {
+	ID           int64               `json:"id"`
+	UserID       int64               `json:"userId"`
+	Name         string              `json:"name"`
+	DashboardUID string              `json:"dashboardUid"`
+	TimeFrom     string              `json:"timeFrom"`
+	TimeTo       string              `json:"timeTo"`
+	Variables    map[string][]string `json:"variables"`
+	Schedule     string              `json:"schedule"`
+	Timezone     string              `json:"timezone"`
+	Recipients   []string            `json:"recipients"`
+	Formats      []string            `json:"formats"`
+	Message      string              `json:"message"`
+	NextRun      time.Time           `json:"nextRun"`
+	LastRun      *time.Time          `json:"lastRun"`
+	LastError    string              `json:"lastError,omitempty"`
+	Created      time.Time           `json:"created"`
+	Updated      time.Time           `json:"updated"`
+}
+
+func (r *Report) ToDTO() *ReportDTO {
+	return &ReportDTO{
+		ID:           r.ID,
+		UserID:       r.UserID,
+		Name:         r.Name,
+		DashboardUID: r.DashboardUID,
+		TimeFrom:     r.TimeFrom,
+		TimeTo:       r.TimeTo,
+		Variables:    r.Variables,
+		Schedule:     r.Schedule,
+		Timezone:     r.Timezone,
+		Recipients:   r.Recipients,
+		Formats:      r.Formats,
+		Message:      r.Message,
+		NextRun:      r.NextRun,
+		LastRun:      r.LastRun,
+		LastError:    r.LastError,
+		Created:      r.Created,
+		Updated:      r.Updated,
+	}
+}
+
+// SaveReportCommand creates or updates a report. The time range defaults to the last 24 hours, the timezone to
+// the one of the server and the formats to a PDF.
+type SaveReportCommand struct {
+	Name         string              `json:"name" binding:"Required"`
+	DashboardUID string              `json:"dashboardUid" binding:"Required"`
+	TimeFrom     string              `json:"timeFrom"`
+	TimeTo       string              `json:"timeTo"`
+	Variables    map[string][]string `json:"variables"`
+	Schedule     string              `json:"schedule" binding:"Required"`
+	Timezone     string              `json:"timezone"`
+	Recipients   []string            `json:"recipients"`
+	Formats      []string            `json:"formats"`
+	Message      string              `json:"message"`
+}
@@ -0,0 +1,86 @@
+package reporting
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+const (
+	pdfMargin       = 10.0
+	pdfHeaderHeight = 18.0
+	pdfFooterHeight = 8.0
+)
+
+// assemblePDF lays the rendered panels out on landscape A4 pages the way they are on the grid of the dashboard,
+// starting a new page at the first panel that does not fit. Panels taller than a page are shrunk to fit it.
+func assemblePDF(title string, subtitle string, panels []renderedPanel) ([]byte, error) {
+	pdf := gofpdf.New("L", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, pdfMargin)
+	pdf.SetTitle(title, true)
+	pdf.SetCreator("Grafana", true)
+	// the core fonts only have the characters of the cp1252 code page
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pdfMargin - pdfFooterHeight/2)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(128, 128, 128)
+		pdf.CellFormat(0, pdfFooterHeight/2, fmt.Sprintf("%d", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pageWidth, pageHeight := pdf.GetPageSize()
+	// millimeters per pixel of the dashboard
+	scale := (pageWidth - 2*pdfMargin) / float64(gridColumns*gridColumnWidth)
+	bottom := pageHeight - pdfMargin - pdfFooterHeight
+
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(0, 8, translate(title), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(96, 96, 96)
+	pdf.CellFormat(0, 6, translate(subtitle), "", 1, "L", false, 0, "")
+
+	top := pdfMargin + pdfHeaderHeight
+	if len(panels) == 0 {
+		pdf.SetY(top)
+		pdf.CellFormat(0, 6, "The dashboard has no panels.", "", 1, "L", false, 0, "")
+	}
+
+	pageStartRow := 0
+	if len(panels) > 0 {
+		pageStartRow = panels[0].Y
+	}
+	for i, p := range panels {
+		x := pdfMargin + float64(p.X*gridColumnWidth)*scale
+		y := top + float64((p.Y-pageStartRow)*(gridCellHeight+gridCellVMargin))*scale
+		width := float64(p.width()) * scale
+		height := float64(p.height()) * scale
+
+		if y+height > bottom && p.Y > pageStartRow {
+			pdf.AddPage()
+			top = pdfMargin
+			pageStartRow = p.Y
+			y = top
+		}
+		if y+height > bottom {
+			ratio := (bottom - y) / height
+			width *= ratio
+			height *= ratio
+		}
+
+		name := fmt.Sprintf("panel-%d", i)
+		options := gofpdf.ImageOptions{ImageType: "PNG"}
+		pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(p.Image))
+		pdf.ImageOptions(name, x, y, width, height, false, options, 0, "")
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to assemble PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,73 @@
+package reporting
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pdfPage = regexp.MustCompile(`/Type /Page\b[^s]`)
+
+func testPNG(t *testing.T, width int, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, height/2, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func renderedPanels(t *testing.T, panels ...panel) []renderedPanel {
+	t.Helper()
+
+	rendered := make([]renderedPanel, 0, len(panels))
+	for _, p := range panels {
+		rendered = append(rendered, renderedPanel{panel: p, Image: testPNG(t, p.width(), p.height())})
+	}
+	return rendered
+}
+
+func TestAssemblePDF(t *testing.T) {
+	t.Run("panels side by side share a page", func(t *testing.T) {
+		content, err := assemblePDF("Daily report", "Servers, last 24 hours", renderedPanels(t,
+			panel{ID: 1, X: 0, Y: 0, W: 12, H: 8},
+			panel{ID: 2, X: 12, Y: 0, W: 12, H: 8},
+		))
+		require.NoError(t, err)
+
+		assert.True(t, bytes.HasPrefix(content, []byte("%PDF-")))
+		assert.Len(t, pdfPage.FindAll(content, -1), 1)
+	})
+
+	t.Run("panels that do not fit start a new page", func(t *testing.T) {
+		content, err := assemblePDF("Daily report", "Servers, last 24 hours", renderedPanels(t,
+			panel{ID: 1, X: 0, Y: 0, W: 24, H: 12},
+			panel{ID: 2, X: 0, Y: 12, W: 24, H: 12},
+			panel{ID: 3, X: 0, Y: 24, W: 24, H: 40},
+		))
+		require.NoError(t, err)
+
+		assert.Len(t, pdfPage.FindAll(content, -1), 3)
+	})
+
+	t.Run("dashboard without panels", func(t *testing.T) {
+		content, err := assemblePDF("Rapport journalier é", "", nil)
+		require.NoError(t, err)
+
+		assert.Len(t, pdfPage.FindAll(content, -1), 1)
+	})
+
+	t.Run("invalid image", func(t *testing.T) {
+		_, err := assemblePDF("Daily report", "", []renderedPanel{{panel: panel{ID: 1, W: 24, H: 8}, Image: []byte("not a png")}})
+		require.Error(t, err)
+	})
+}
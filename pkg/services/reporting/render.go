@@ -0,0 +1,220 @@
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/rendering"
+)
+
+const (
+	// the dashboards are rendered on a grid of 24 columns of 50 pixels, with rows of 30 pixels separated by
+	// margins of 8 pixels, like in a browser 1200 pixels wide
+	gridColumns      = 24
+	gridColumnWidth  = 50
+	gridCellHeight   = 30
+	gridCellVMargin  = 8
+	defaultPanelSize = 8
+)
+
+// panel is a panel of a dashboard, positioned on its grid
+type panel struct {
+	ID    int64
+	Title string
+	X     int
+	Y     int
+	W     int
+	H     int
+}
+
+func (p panel) width() int {
+	return p.W * gridColumnWidth
+}
+
+func (p panel) height() int {
+	return p.H*(gridCellHeight+gridCellVMargin) - gridCellVMargin
+}
+
+// renderedPanel is the PNG image of a panel
+type renderedPanel struct {
+	panel
+	Image []byte
+}
+
+// dashboardPanels returns the panels of the dashboard, including the ones of the collapsed rows, ordered by
+// position. Rows themselves are not rendered.
+func dashboardPanels(data *simplejson.Json) []panel {
+	panels := make([]panel, 0)
+	for _, item := range data.Get("panels").MustArray() {
+		jsonPanel := simplejson.NewFromAny(item)
+		if jsonPanel.Get("type").MustString() == "row" {
+			for _, rowItem := range jsonPanel.Get("panels").MustArray() {
+				panels = append(panels, newPanel(simplejson.NewFromAny(rowItem)))
+			}
+			continue
+		}
+		panels = append(panels, newPanel(jsonPanel))
+	}
+
+	sort.SliceStable(panels, func(i, j int) bool {
+		if panels[i].Y != panels[j].Y {
+			return panels[i].Y < panels[j].Y
+		}
+		return panels[i].X < panels[j].X
+	})
+	return panels
+}
+
+func newPanel(jsonPanel *simplejson.Json) panel {
+	gridPos := jsonPanel.Get("gridPos")
+	p := panel{
+		ID:    jsonPanel.Get("id").MustInt64(),
+		Title: jsonPanel.Get("title").MustString(),
+		X:     gridPos.Get("x").MustInt(),
+		Y:     gridPos.Get("y").MustInt(),
+		W:     gridPos.Get("w").MustInt(gridColumns),
+		H:     gridPos.Get("h").MustInt(defaultPanelSize),
+	}
+	if p.W <= 0 || p.W > gridColumns {
+		p.W = gridColumns
+	}
+	if p.X < 0 || p.X+p.W > gridColumns {
+		p.X = 0
+	}
+	if p.H <= 0 {
+		p.H = defaultPanelSize
+	}
+	return p
+}
+
+// dashboardHeight returns the height in pixels of the grid of the panels
+func dashboardHeight(panels []panel) int {
+	rows := 0
+	for _, p := range panels {
+		if p.Y+p.H > rows {
+			rows = p.Y + p.H
+		}
+	}
+	if rows == 0 {
+		rows = defaultPanelSize
+	}
+	return rows * (gridCellHeight + gridCellVMargin)
+}
+
+// dashboardQuery returns the query parameters of the template variables of the report, and of its time range
+// resolved when the report is sent, so that all the panels show the same range
+func dashboardQuery(report *Report, from time.Time, to time.Time) url.Values {
+	values := url.Values{}
+	values.Set("orgId", strconv.FormatInt(report.OrgID, 10))
+	values.Set("from", strconv.FormatInt(from.UnixNano()/int64(time.Millisecond), 10))
+	values.Set("to", strconv.FormatInt(to.UnixNano()/int64(time.Millisecond), 10))
+	for name, variableValues := range report.Variables {
+		for _, value := range variableValues {
+			values.Add("var-"+name, value)
+		}
+	}
+	return values
+}
+
+type renderer struct {
+	service rendering.Service
+	session rendering.Session
+	opts    rendering.Opts
+}
+
+func (s *ReportingService) newRenderer(ctx context.Context, report *Report, user *models.SignedInUser) (*renderer, error) {
+	authOpts := rendering.AuthOpts{OrgID: report.OrgID, UserID: user.UserId, OrgRole: user.OrgRole}
+	session, err := s.renderService.CreateRenderingSession(ctx, authOpts, rendering.SessionOpts{
+		Expiry:                     s.cfg.ReportsRenderingTimeout * 3,
+		RefreshExpiryOnEachRequest: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &renderer{
+		service: s.renderService,
+		session: session,
+		opts: rendering.Opts{
+			TimeoutOpts: rendering.TimeoutOpts{Timeout: s.cfg.ReportsRenderingTimeout},
+			AuthOpts:    authOpts,
+			ErrorOpts: rendering.ErrorOpts{
+				ErrorConcurrentLimitReached: true,
+				ErrorRenderUnavailable:      true,
+			},
+			Timezone:          report.Timezone,
+			ConcurrentLimit:   s.cfg.RendererConcurrentRequestLimit,
+			DeviceScaleFactor: s.cfg.ReportsImageScaleFactor,
+			Theme:             models.ThemeLight,
+		},
+	}, nil
+}
+
+func (r *renderer) dispose(ctx context.Context) {
+	if r.session != nil {
+		r.session.Dispose(ctx)
+	}
+}
+
+// renderPanels renders each panel of the dashboard on its own, in the size it has on the dashboard
+func (r *renderer) renderPanels(ctx context.Context, dash *models.Dashboard, query url.Values, panels []panel) ([]renderedPanel, error) {
+	rendered := make([]renderedPanel, 0, len(panels))
+	for _, p := range panels {
+		values := url.Values{}
+		for key, value := range query {
+			values[key] = value
+		}
+		values.Set("panelId", strconv.FormatInt(p.ID, 10))
+		values.Set("theme", string(models.ThemeLight))
+
+		image, err := r.render(ctx, fmt.Sprintf("d-solo/%s/%s?%s", dash.Uid, dash.Slug, values.Encode()), p.width(), p.height())
+		if err != nil {
+			return nil, fmt.Errorf("failed to render panel %d: %w", p.ID, err)
+		}
+		rendered = append(rendered, renderedPanel{panel: p, Image: image})
+	}
+	return rendered, nil
+}
+
+// renderDashboard renders the whole dashboard in kiosk mode, tall enough for all its panels
+func (r *renderer) renderDashboard(ctx context.Context, dash *models.Dashboard, query url.Values, panels []panel) ([]byte, error) {
+	path := strings.TrimPrefix(models.GetKioskModeDashboardUrl(dash.Uid, dash.Slug, models.ThemeLight), "/") + "&" +
+		query.Encode()
+
+	image, err := r.render(ctx, path, gridColumns*gridColumnWidth, dashboardHeight(panels))
+	if err != nil {
+		return nil, fmt.Errorf("failed to render dashboard: %w", err)
+	}
+	return image, nil
+}
+
+func (r *renderer) render(ctx context.Context, path string, width int, height int) ([]byte, error) {
+	opts := r.opts
+	opts.Path = path
+	opts.Width = width
+	opts.Height = height
+
+	result, err := r.service.Render(ctx, opts, r.session)
+	if err != nil {
+		return nil, err
+	}
+
+	// #nosec G304 the file is a render of the rendering service
+	image, err := os.ReadFile(result.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	// the rendering service returns static images of public/img when it fails, which must not be removed
+	if !strings.Contains(result.FilePath, "public/img") {
+		_ = os.Remove(result.FilePath)
+	}
+	return image, nil
+}
@@ -0,0 +1,97 @@
+package reporting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+func TestDashboardPanels(t *testing.T) {
+	data, err := simplejson.NewJson([]byte(`{
+		"panels": [
+			{"id": 2, "title": "Right", "gridPos": {"x": 12, "y": 0, "w": 12, "h": 8}},
+			{"id": 1, "title": "Left", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}},
+			{"id": 3, "type": "row", "title": "Expanded", "collapsed": false, "gridPos": {"x": 0, "y": 8, "w": 24, "h": 1}, "panels": []},
+			{"id": 4, "title": "Below", "gridPos": {"x": 0, "y": 9, "w": 24, "h": 6}},
+			{"id": 5, "type": "row", "title": "Collapsed", "collapsed": true, "gridPos": {"x": 0, "y": 15, "w": 24, "h": 1}, "panels": [
+				{"id": 6, "title": "Hidden", "gridPos": {"x": 0, "y": 16, "w": 8, "h": 4}}
+			]},
+			{"id": 7, "title": "No position"}
+		]
+	}`))
+	require.NoError(t, err)
+
+	panels := dashboardPanels(data)
+
+	ids := make([]int64, 0, len(panels))
+	for _, p := range panels {
+		ids = append(ids, p.ID)
+	}
+	assert.Equal(t, []int64{1, 7, 2, 4, 6}, ids)
+
+	assert.Equal(t, panel{ID: 7, Title: "No position", X: 0, Y: 0, W: gridColumns, H: defaultPanelSize}, panels[1])
+	assert.Equal(t, 600, panels[0].width())
+	assert.Equal(t, 8*38-8, panels[0].height())
+	assert.Equal(t, 20*38, dashboardHeight(panels))
+}
+
+func TestDashboardQuery(t *testing.T) {
+	report := &Report{OrgID: 2, Variables: map[string][]string{"host": {"a", "b"}}}
+	from := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	assert.Equal(t, "from=1651363200000&orgId=2&to=1651449600000&var-host=a&var-host=b", dashboardQuery(report, from, to).Encode())
+}
+
+func TestNextRun(t *testing.T) {
+	now := time.Date(2022, 5, 2, 10, 30, 0, 0, time.UTC)
+
+	t.Run("in the timezone of the server", func(t *testing.T) {
+		next, err := nextRun("0 * * * *", "", now)
+		require.NoError(t, err)
+		assert.True(t, next.Equal(time.Date(2022, 5, 2, 11, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("in the timezone of the report", func(t *testing.T) {
+		next, err := nextRun("0 8 * * *", "Europe/Paris", now)
+		require.NoError(t, err)
+		assert.True(t, next.Equal(time.Date(2022, 5, 3, 6, 0, 0, 0, time.UTC)))
+	})
+
+	t.Run("invalid schedule", func(t *testing.T) {
+		_, err := nextRun("every monday", "", now)
+		require.ErrorIs(t, err, ErrInvalidSchedule)
+	})
+
+	t.Run("invalid timezone", func(t *testing.T) {
+		_, err := nextRun("0 8 * * *", "Mars/Olympus", now)
+		require.ErrorIs(t, err, ErrInvalidTimezone)
+	})
+}
+
+func TestValidateFormatsAndRecipients(t *testing.T) {
+	formats, err := validateFormats(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{FormatPDF}, formats)
+
+	formats, err = validateFormats([]string{"PNG", "pdf", "png"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{FormatPNG, FormatPDF}, formats)
+
+	_, err = validateFormats([]string{"csv"})
+	require.ErrorIs(t, err, ErrInvalidFormat)
+
+	recipients, err := validateRecipients([]string{" ops@example.com", ""})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ops@example.com"}, recipients)
+
+	_, err = validateRecipients([]string{"ops"})
+	require.ErrorIs(t, err, ErrInvalidRecipient)
+
+	_, err = validateRecipients([]string{" "})
+	require.ErrorIs(t, err, ErrNoRecipients)
+}
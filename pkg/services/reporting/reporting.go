@@ -0,0 +1,159 @@
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/rendering"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// ReportingService sends the scheduled reports of dashboards by email, as a PDF assembled from the renders of
+// their panels or as a PNG of the whole dashboard.
+type ReportingService struct {
+	cfg               *setting.Cfg
+	sqlStore          *sqlstore.SQLStore
+	renderService     rendering.Service
+	emailSender       notifications.EmailSender
+	serverLockService *serverlock.ServerLockService
+	accessControl     accesscontrol.AccessControl
+	routeRegister     routing.RouteRegister
+	log               log.Logger
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, renderService rendering.Service,
+	emailSender notifications.EmailSender, serverLockService *serverlock.ServerLockService,
+	accessControl accesscontrol.AccessControl, routeRegister routing.RouteRegister) (*ReportingService, error) {
+	s := &ReportingService{
+		cfg:               cfg,
+		sqlStore:          sqlStore,
+		renderService:     renderService,
+		emailSender:       emailSender,
+		serverLockService: serverLockService,
+		accessControl:     accessControl,
+		routeRegister:     routeRegister,
+		log:               log.New("reporting"),
+	}
+
+	if !cfg.ReportsEnabled {
+		return s, nil
+	}
+
+	if err := RegisterRoles(accessControl); err != nil {
+		return nil, err
+	}
+
+	s.registerAPIEndpoints()
+
+	return s, nil
+}
+
+func (s *ReportingService) IsDisabled() bool {
+	return !s.cfg.ReportsEnabled
+}
+
+// Run checks every minute for the reports that are due. All the instances wake up at the same time, and the
+// lock lets only one of them send the reports.
+func (s *ReportingService) Run(ctx context.Context) error {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		select {
+		case <-timer.C:
+			err := s.serverLockService.LockAndExecute(ctx, "scheduled reports", 30*time.Second, func(ctx context.Context) {
+				s.sendDueReports(ctx, time.Now())
+			})
+			if err != nil {
+				s.log.Error("Failed to lock and execute the scheduled reports", "error", err)
+			}
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *ReportingService) sendDueReports(ctx context.Context, now time.Time) {
+	reports, err := s.getDueReports(ctx, now)
+	if err != nil {
+		s.log.Error("Failed to get the scheduled reports", "error", err)
+		return
+	}
+
+	for _, report := range reports {
+		if ctx.Err() != nil {
+			return
+		}
+
+		// the report is scheduled again before it is sent, so that a slow report is not sent twice
+		nextRun, err := nextRun(report.Schedule, report.Timezone, now)
+		if err != nil {
+			s.log.Error("Invalid report schedule", "reportId", report.ID, "schedule", report.Schedule, "error", err)
+			continue
+		}
+		claimed, err := s.claimReport(ctx, report, nextRun)
+		if err != nil {
+			s.log.Error("Failed to schedule report", "reportId", report.ID, "error", err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.Send(ctx, report); err != nil {
+			s.log.Error("Failed to send scheduled report", "reportId", report.ID, "orgId", report.OrgID, "error", err)
+		}
+	}
+}
+
+// Send renders the report and emails it to its recipients, and records the outcome in the report.
+func (s *ReportingService) Send(ctx context.Context, report *Report) error {
+	start := time.Now()
+	err := s.send(ctx, report)
+
+	lastError := ""
+	if err != nil {
+		lastError = err.Error()
+	} else {
+		s.log.Info("Report sent", "reportId", report.ID, "orgId", report.OrgID, "recipients", len(report.Recipients),
+			"elapsed", time.Since(start).Round(time.Millisecond).String())
+	}
+	if err := s.recordRun(ctx, report.ID, start, lastError); err != nil {
+		s.log.Error("Failed to record report run", "reportId", report.ID, "error", err)
+	}
+
+	return err
+}
+
+// nextRun returns the next time of the cron schedule after now, in the timezone of the report
+func nextRun(schedule string, timezone string, now time.Time) (time.Time, error) {
+	spec, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidSchedule, err)
+	}
+	location, err := loadLocation(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return spec.Next(now.In(location)), nil
+}
+
+func loadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return location, nil
+}
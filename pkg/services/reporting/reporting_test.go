@@ -0,0 +1,456 @@
+//go:build integration
+// +build integration
+
+package reporting
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/models"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	dashboardsdb "github.com/grafana/grafana/pkg/services/dashboards/database"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/services/notifications"
+	"github.com/grafana/grafana/pkg/services/rendering"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+const testOrgID = int64(1)
+
+type testEnv struct {
+	service   *ReportingService
+	sqlStore  *sqlstore.SQLStore
+	renderer  *fakeRenderService
+	smtp      *smtpServer
+	user      *models.SignedInUser
+	dashboard *models.Dashboard
+}
+
+func setupTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	smtp := startSMTPServer(t)
+
+	sqlStore := sqlstore.InitTestDB(t)
+	cfg := sqlStore.Cfg
+	cfg.ReportsEnabled = true
+	cfg.ReportsRenderingTimeout = 10 * time.Second
+	cfg.ReportsImageScaleFactor = 1
+	cfg.ReportsMaxAttachmentSizeMB = 10
+	cfg.StaticRootPath = "../../../public/"
+	cfg.Smtp.Enabled = true
+	cfg.Smtp.Host = smtp.addr()
+	cfg.Smtp.StartTLSPolicy = "NoStartTLS"
+	cfg.Smtp.TemplatesPatterns = []string{"emails/*.html", "emails/*.txt"}
+	cfg.Smtp.FromAddress = "grafana@example.com"
+	cfg.Smtp.ContentTypes = []string{"text/html", "text/plain"}
+
+	mailer, err := notifications.ProvideSmtpService(cfg)
+	require.NoError(t, err)
+	notificationService, err := notifications.ProvideService(bus.New(), cfg, mailer, nil)
+	require.NoError(t, err)
+
+	renderer := &fakeRenderService{dir: t.TempDir()}
+	service, err := ProvideService(cfg, sqlStore, renderer, notificationService, serverlock.ProvideService(sqlStore),
+		accesscontrolmock.New(), routing.NewRouteRegister())
+	require.NoError(t, err)
+
+	guardian.MockDashboardGuardian(&guardian.FakeDashboardGuardian{CanViewValue: true})
+
+	user, err := sqlStore.CreateUser(context.Background(), models.CreateUserCommand{Login: "reporter", Email: "reporter@example.com", OrgId: testOrgID})
+	require.NoError(t, err)
+	query := models.GetSignedInUserQuery{UserId: user.Id, OrgId: testOrgID}
+	require.NoError(t, sqlStore.GetSignedInUser(context.Background(), &query))
+
+	data, err := simplejson.NewJson([]byte(`{
+		"title": "Servers",
+		"panels": [
+			{"id": 1, "title": "CPU", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}},
+			{"id": 2, "title": "Memory", "gridPos": {"x": 12, "y": 0, "w": 12, "h": 8}}
+		]
+	}`))
+	require.NoError(t, err)
+	dashboard, err := dashboardsdb.ProvideDashboardStore(sqlStore).SaveDashboard(models.SaveDashboardCommand{OrgId: testOrgID, Dashboard: data})
+	require.NoError(t, err)
+
+	return &testEnv{service: service, sqlStore: sqlStore, renderer: renderer, smtp: smtp, user: query.Result, dashboard: dashboard}
+}
+
+func (env *testEnv) createReport(t *testing.T, cmd SaveReportCommand) *Report {
+	t.Helper()
+
+	now := time.Now()
+	report := &Report{OrgID: testOrgID, Created: now}
+	errResp := env.service.applyCommand(context.Background(), env.user, report, &cmd, now)
+	require.Nil(t, errResp)
+	require.NoError(t, env.service.createReport(context.Background(), report))
+	return report
+}
+
+func TestIntegrationSendReport(t *testing.T) {
+	env := setupTestEnv(t)
+
+	report := env.createReport(t, SaveReportCommand{
+		Name:         "Daily servers",
+		DashboardUID: env.dashboard.Uid,
+		TimeFrom:     "now-7d",
+		Variables:    map[string][]string{"host": {"web-1", "web-2"}},
+		Schedule:     "0 8 * * *",
+		Recipients:   []string{"ops@example.com", "cto@example.com"},
+		Formats:      []string{FormatPDF, FormatPNG},
+		Message:      "Good morning!",
+	})
+
+	require.NoError(t, env.service.Send(context.Background(), report))
+
+	t.Run("renders every panel, and the whole dashboard, with the user of the report", func(t *testing.T) {
+		renders := env.renderer.getRenders()
+		require.Len(t, renders, 3)
+		assert.True(t, strings.HasPrefix(renders[0].Path, "d-solo/"+env.dashboard.Uid+"/"))
+		assert.Contains(t, renders[0].Path, "panelId=1")
+		assert.Contains(t, renders[1].Path, "panelId=2")
+		assert.Contains(t, renders[2].Path, "d/"+env.dashboard.Uid+"/")
+		assert.Contains(t, renders[2].Path, "kiosk")
+		for _, render := range renders {
+			assert.Contains(t, render.Path, "var-host=web-1&var-host=web-2")
+			assert.Equal(t, env.user.UserId, render.UserID)
+			assert.Equal(t, testOrgID, render.OrgID)
+		}
+		assert.Equal(t, 600, renders[0].Width)
+		assert.Equal(t, gridColumns*gridColumnWidth, renders[2].Width)
+	})
+
+	t.Run("sends one email to each recipient with the attachments", func(t *testing.T) {
+		messages := env.smtp.getMessages()
+		require.Len(t, messages, 2)
+		assert.ElementsMatch(t, []string{"ops@example.com", "cto@example.com"}, []string{messages[0].to, messages[1].to})
+
+		for _, message := range messages {
+			assert.Equal(t, "Daily servers - Grafana report", message.subject)
+			assert.Contains(t, message.text, "Good morning!")
+			require.Contains(t, message.attachments, "Daily_servers.pdf")
+			assert.True(t, bytes.HasPrefix(message.attachments["Daily_servers.pdf"], []byte("%PDF-")))
+			require.Contains(t, message.attachments, "Daily_servers.png")
+			_, err := png.Decode(bytes.NewReader(message.attachments["Daily_servers.png"]))
+			assert.NoError(t, err)
+		}
+	})
+
+	t.Run("records the run", func(t *testing.T) {
+		saved, err := env.service.getReport(context.Background(), testOrgID, report.ID)
+		require.NoError(t, err)
+		require.NotNil(t, saved.LastRun)
+		assert.Empty(t, saved.LastError)
+		assert.Equal(t, report.NextRun.Unix(), saved.NextRun.Unix())
+	})
+}
+
+func TestIntegrationScheduledReports(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+
+	due := env.createReport(t, SaveReportCommand{
+		Name:         "Due",
+		DashboardUID: env.dashboard.Uid,
+		Schedule:     "*/5 * * * *",
+		Recipients:   []string{"ops@example.com"},
+	})
+	later := env.createReport(t, SaveReportCommand{
+		Name:         "Later",
+		DashboardUID: env.dashboard.Uid,
+		Schedule:     "0 0 1 1 *",
+		Recipients:   []string{"ops@example.com"},
+	})
+
+	now := time.Now()
+	due.NextRun = now.Add(-time.Minute)
+	require.NoError(t, env.service.updateReport(ctx, due))
+
+	env.service.sendDueReports(ctx, now)
+	require.Len(t, env.smtp.getMessages(), 1)
+	assert.Equal(t, "Due - Grafana report", env.smtp.getMessages()[0].subject)
+
+	saved, err := env.service.getReport(ctx, testOrgID, due.ID)
+	require.NoError(t, err)
+	assert.True(t, saved.NextRun.After(now))
+	require.NotNil(t, saved.LastRun)
+
+	// the report is not sent again until its next run
+	env.service.sendDueReports(ctx, now)
+	assert.Len(t, env.smtp.getMessages(), 1)
+
+	saved, err = env.service.getReport(ctx, testOrgID, later.ID)
+	require.NoError(t, err)
+	assert.Nil(t, saved.LastRun)
+}
+
+func TestIntegrationReportErrors(t *testing.T) {
+	env := setupTestEnv(t)
+	ctx := context.Background()
+
+	t.Run("invalid reports are rejected", func(t *testing.T) {
+		testCases := []struct {
+			desc   string
+			cmd    SaveReportCommand
+			status int
+		}{
+			{desc: "schedule", cmd: SaveReportCommand{Schedule: "daily", Recipients: []string{"ops@example.com"}}, status: http.StatusBadRequest},
+			{desc: "timezone", cmd: SaveReportCommand{Schedule: "@daily", Timezone: "Nowhere", Recipients: []string{"ops@example.com"}}, status: http.StatusBadRequest},
+			{desc: "recipients", cmd: SaveReportCommand{Schedule: "@daily"}, status: http.StatusBadRequest},
+			{desc: "format", cmd: SaveReportCommand{Schedule: "@daily", Recipients: []string{"ops@example.com"}, Formats: []string{"csv"}}, status: http.StatusBadRequest},
+			{desc: "time range", cmd: SaveReportCommand{Schedule: "@daily", Recipients: []string{"ops@example.com"}, TimeFrom: "yesterday"}, status: http.StatusBadRequest},
+			{desc: "dashboard", cmd: SaveReportCommand{Schedule: "@daily", Recipients: []string{"ops@example.com"}, DashboardUID: "missing"}, status: http.StatusNotFound},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.desc, func(t *testing.T) {
+				tc.cmd.Name = "Invalid"
+				if tc.cmd.DashboardUID == "" {
+					tc.cmd.DashboardUID = env.dashboard.Uid
+				}
+				errResp := env.service.applyCommand(ctx, env.user, &Report{}, &tc.cmd, time.Now())
+				require.NotNil(t, errResp)
+				assert.Equal(t, tc.status, errResp.Status())
+			})
+		}
+	})
+
+	t.Run("a failed report records the error", func(t *testing.T) {
+		report := env.createReport(t, SaveReportCommand{
+			Name:         "Failing",
+			DashboardUID: env.dashboard.Uid,
+			Schedule:     "@daily",
+			Recipients:   []string{"ops@example.com"},
+		})
+		env.renderer.err = rendering.ErrRenderUnavailable
+		defer func() { env.renderer.err = nil }()
+
+		require.ErrorIs(t, env.service.Send(ctx, report), rendering.ErrRenderUnavailable)
+		assert.Empty(t, env.smtp.getMessages())
+
+		saved, err := env.service.getReport(ctx, testOrgID, report.ID)
+		require.NoError(t, err)
+		require.NotNil(t, saved.LastRun)
+		assert.Contains(t, saved.LastError, "rendering plugin not available")
+	})
+
+	t.Run("reports larger than the maximum size are not sent", func(t *testing.T) {
+		report := env.createReport(t, SaveReportCommand{
+			Name:         "Large",
+			DashboardUID: env.dashboard.Uid,
+			Schedule:     "@daily",
+			Recipients:   []string{"ops@example.com"},
+		})
+		env.service.cfg.ReportsMaxAttachmentSizeMB = 0
+		defer func() { env.service.cfg.ReportsMaxAttachmentSizeMB = 10 }()
+
+		require.ErrorIs(t, env.service.Send(ctx, report), ErrAttachmentTooLarge)
+		assert.Empty(t, env.smtp.getMessages())
+	})
+}
+
+type fakeRender struct {
+	rendering.Opts
+	UserID int64
+	OrgID  int64
+}
+
+// fakeRenderService renders blank images of the requested size
+type fakeRenderService struct {
+	rendering.Service
+	dir string
+	err error
+
+	mutex   sync.Mutex
+	renders []fakeRender
+}
+
+func (s *fakeRenderService) CreateRenderingSession(ctx context.Context, authOpts rendering.AuthOpts, sessionOpts rendering.SessionOpts) (rendering.Session, error) {
+	return nil, nil
+}
+
+func (s *fakeRenderService) Render(ctx context.Context, opts rendering.Opts, session rendering.Session) (*rendering.RenderResult, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	s.mutex.Lock()
+	s.renders = append(s.renders, fakeRender{Opts: opts, UserID: opts.UserID, OrgID: opts.OrgID})
+	path := filepath.Join(s.dir, strings.ReplaceAll(opts.Path, "/", "_")[:10]+time.Now().Format("150405.000000000")+".png")
+	s.mutex.Unlock()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	if err := png.Encode(file, image.NewGray(image.Rect(0, 0, opts.Width, opts.Height))); err != nil {
+		return nil, err
+	}
+	return &rendering.RenderResult{FilePath: path}, nil
+}
+
+func (s *fakeRenderService) getRenders() []fakeRender {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]fakeRender{}, s.renders...)
+}
+
+type smtpMessage struct {
+	to          string
+	subject     string
+	text        string
+	attachments map[string][]byte
+}
+
+// smtpServer is a local stand-in for an SMTP server, which accepts all the emails
+type smtpServer struct {
+	t        *testing.T
+	listener net.Listener
+
+	mutex    sync.Mutex
+	messages []smtpMessage
+}
+
+func startSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &smtpServer{t: t, listener: listener}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *smtpServer) getMessages() []smtpMessage {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]smtpMessage{}, s.messages...)
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		_, _ = conn.Write([]byte(line + "\r\n"))
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "DATA"):
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data bytes.Buffer
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(dataLine, "."))
+			}
+			s.receive(data.Bytes())
+			reply("250 OK")
+		case strings.HasPrefix(command, "QUIT"):
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpServer) receive(data []byte) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if !assert.NoError(s.t, err) {
+		return
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NoError(s.t, err)
+
+	message := smtpMessage{to: msg.Header.Get("To"), subject: subject, attachments: map[string][]byte{}}
+	s.readParts(msg.Header.Get("Content-Type"), msg.Body, &message)
+
+	s.mutex.Lock()
+	s.messages = append(s.messages, message)
+	s.mutex.Unlock()
+}
+
+func (s *smtpServer) readParts(contentType string, body io.Reader, message *smtpMessage) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if !assert.NoError(s.t, err) || !strings.HasPrefix(mediaType, "multipart/") {
+		return
+	}
+
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return
+		}
+		content, err := io.ReadAll(part)
+		assert.NoError(s.t, err)
+		if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+			content, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(content)), ""))
+			assert.NoError(s.t, err)
+		}
+
+		partType := part.Header.Get("Content-Type")
+		switch {
+		case strings.HasPrefix(partType, "multipart/"):
+			s.readParts(partType, bytes.NewReader(content), message)
+		case part.FileName() != "":
+			message.attachments[part.FileName()] = content
+		case strings.HasPrefix(partType, "text/plain"):
+			message.text = string(content)
+		}
+	}
+}
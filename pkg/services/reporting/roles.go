@@ -0,0 +1,55 @@
+package reporting
+
+import (
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+)
+
+func RegisterRoles(ac accesscontrol.AccessControl) error {
+	reportsReader := accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Version:     1,
+			Name:        "fixed:reports:reader",
+			DisplayName: "Report reader",
+			Description: "Read all reports.",
+			Group:       "Reports",
+			Permissions: []accesscontrol.Permission{
+				{
+					Action: ActionRead,
+					Scope:  ScopeAll,
+				},
+			},
+		},
+		Grants: []string{string(models.ROLE_ADMIN)},
+	}
+
+	reportsWriter := accesscontrol.RoleRegistration{
+		Role: accesscontrol.RoleDTO{
+			Version:     1,
+			Name:        "fixed:reports:writer",
+			DisplayName: "Report writer",
+			Description: "Create, read, update, delete or send all reports.",
+			Group:       "Reports",
+			Permissions: accesscontrol.ConcatPermissions(reportsReader.Role.Permissions, []accesscontrol.Permission{
+				{
+					Action: ActionCreate,
+				},
+				{
+					Action: ActionWrite,
+					Scope:  ScopeAll,
+				},
+				{
+					Action: ActionDelete,
+					Scope:  ScopeAll,
+				},
+				{
+					Action: ActionSend,
+					Scope:  ScopeAll,
+				},
+			}),
+		},
+		Grants: []string{string(models.ROLE_ADMIN)},
+	}
+
+	return ac.DeclareFixedRoles(reportsReader, reportsWriter)
+}
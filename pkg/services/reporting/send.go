@@ -0,0 +1,143 @@
+package reporting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/tsdb/legacydata"
+)
+
+const (
+	emailTemplate    = "report"
+	timeFormat       = "2006-01-02 15:04 MST"
+	bytesPerMegaByte = 1024 * 1024
+)
+
+var unsafeFileNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+func (s *ReportingService) send(ctx context.Context, report *Report) error {
+	user, err := s.getReportUser(ctx, report)
+	if err != nil {
+		return err
+	}
+	dash, err := s.getDashboard(ctx, report.DashboardUID, user)
+	if err != nil {
+		return err
+	}
+
+	location, err := loadLocation(report.Timezone)
+	if err != nil {
+		return err
+	}
+	timeRange := legacydata.NewDataTimeRange(report.TimeFrom, report.TimeTo)
+	from, err := timeRange.ParseFrom(legacydata.WithLocation(location))
+	if err != nil {
+		return fmt.Errorf("invalid time range: %w", err)
+	}
+	to, err := timeRange.ParseTo(legacydata.WithLocation(location))
+	if err != nil {
+		return fmt.Errorf("invalid time range: %w", err)
+	}
+	query := dashboardQuery(report, from, to)
+	period := fmt.Sprintf("%s to %s", from.In(location).Format(timeFormat), to.In(location).Format(timeFormat))
+
+	r, err := s.newRenderer(ctx, report, user)
+	if err != nil {
+		return err
+	}
+	defer r.dispose(ctx)
+
+	panels := dashboardPanels(dash.Data)
+	fileName := strings.Trim(unsafeFileNameChars.ReplaceAllString(report.Name, "_"), "_")
+	if fileName == "" {
+		fileName = "report"
+	}
+
+	attachments := make([]*models.SendEmailAttachFile, 0, len(report.Formats))
+	size := 0
+	for _, format := range report.Formats {
+		var content []byte
+		switch format {
+		case FormatPDF:
+			rendered, err := r.renderPanels(ctx, dash, query, panels)
+			if err != nil {
+				return err
+			}
+			content, err = assemblePDF(report.Name, fmt.Sprintf("%s, %s", dash.Title, period), rendered)
+			if err != nil {
+				return err
+			}
+		case FormatPNG:
+			content, err = r.renderDashboard(ctx, dash, query, panels)
+			if err != nil {
+				return err
+			}
+		default:
+			return ErrInvalidFormat
+		}
+
+		size += len(content)
+		attachments = append(attachments, &models.SendEmailAttachFile{Name: fileName + "." + format, Content: content})
+	}
+	if size > s.cfg.ReportsMaxAttachmentSizeMB*bytesPerMegaByte {
+		return fmt.Errorf("%w of %d MB", ErrAttachmentTooLarge, s.cfg.ReportsMaxAttachmentSizeMB)
+	}
+
+	// each recipient gets its own email, without the addresses of the others
+	cmd := &models.SendEmailCommandSync{
+		SendEmailCommand: models.SendEmailCommand{
+			To:       report.Recipients,
+			Template: emailTemplate,
+			Data: map[string]interface{}{
+				"Name":           report.Name,
+				"Message":        report.Message,
+				"DashboardTitle": dash.Title,
+				"DashboardUrl":   models.GetFullDashboardUrl(dash.Uid, dash.Slug) + "?" + query.Encode(),
+				"Period":         period,
+			},
+			AttachedFiles: attachments,
+		},
+	}
+	if err := s.emailSender.SendEmailCommandHandlerSync(ctx, cmd); err != nil {
+		return fmt.Errorf("failed to send report email: %w", err)
+	}
+	return nil
+}
+
+// getReportUser returns the user whose permissions the report is rendered with
+func (s *ReportingService) getReportUser(ctx context.Context, report *Report) (*models.SignedInUser, error) {
+	query := models.GetSignedInUserQuery{UserId: report.UserID, OrgId: report.OrgID}
+	if err := s.sqlStore.GetSignedInUser(ctx, &query); err != nil {
+		return nil, fmt.Errorf("failed to get the user of the report: %w", err)
+	}
+	// the user was removed from the organization of the report, or disabled
+	if query.Result.OrgId != report.OrgID || query.Result.IsDisabled {
+		return nil, fmt.Errorf("failed to get the user of the report: %w", models.ErrUserNotFound)
+	}
+	return query.Result, nil
+}
+
+// getDashboard returns the dashboard of the report, if the user can view it
+func (s *ReportingService) getDashboard(ctx context.Context, uid string, user *models.SignedInUser) (*models.Dashboard, error) {
+	query := models.GetDashboardQuery{Uid: uid, OrgId: user.OrgId}
+	if err := s.sqlStore.GetDashboard(ctx, &query); err != nil {
+		if errors.Is(err, models.ErrDashboardNotFound) {
+			return nil, ErrDashboardNotFound
+		}
+		return nil, err
+	}
+
+	canView, err := guardian.New(ctx, query.Result.Id, user.OrgId, user).CanView()
+	if err != nil {
+		return nil, err
+	}
+	if !canView {
+		return nil, ErrDashboardNotFound
+	}
+	return query.Result, nil
+}
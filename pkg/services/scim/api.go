@@ -0,0 +1,359 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+const contentType = "application/scim+json"
+
+// provisioningEvaluator requires the permissions to manage the users and the
+// teams of the organization.
+var provisioningEvaluator = accesscontrol.EvalAll(
+	accesscontrol.EvalPermission(accesscontrol.ActionOrgUsersRead, accesscontrol.ScopeUsersAll),
+	accesscontrol.EvalPermission(accesscontrol.ActionOrgUsersAdd, accesscontrol.ScopeUsersAll),
+	accesscontrol.EvalPermission(accesscontrol.ActionOrgUsersRemove, accesscontrol.ScopeUsersAll),
+	accesscontrol.EvalPermission(accesscontrol.ActionTeamsCreate),
+	accesscontrol.EvalPermission(accesscontrol.ActionTeamsWrite, accesscontrol.ScopeTeamsAll),
+	accesscontrol.EvalPermission(accesscontrol.ActionTeamsDelete, accesscontrol.ScopeTeamsAll),
+)
+
+func (s *SCIMService) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(s.AccessControl)
+
+	s.RouteRegister.Group("/api/scim/v2", func(r routing.RouteRegister) {
+		r.Get("/ServiceProviderConfig", routing.Wrap(s.getServiceProviderConfigHandler))
+		r.Get("/ResourceTypes", routing.Wrap(s.getResourceTypesHandler))
+		r.Get("/ResourceTypes/:id", routing.Wrap(s.getResourceTypeHandler))
+		r.Get("/Schemas", routing.Wrap(s.getSchemasHandler))
+		r.Get("/Schemas/:id", routing.Wrap(s.getSchemaHandler))
+
+		r.Get("/Users", routing.Wrap(s.listUsersHandler))
+		r.Post("/Users", routing.Wrap(s.createUserHandler))
+		r.Get("/Users/:id", routing.Wrap(s.getUserHandler))
+		r.Put("/Users/:id", routing.Wrap(s.replaceUserHandler))
+		r.Patch("/Users/:id", routing.Wrap(s.patchUserHandler))
+		r.Delete("/Users/:id", routing.Wrap(s.deleteUserHandler))
+
+		r.Get("/Groups", routing.Wrap(s.listGroupsHandler))
+		r.Post("/Groups", routing.Wrap(s.createGroupHandler))
+		r.Get("/Groups/:id", routing.Wrap(s.getGroupHandler))
+		r.Put("/Groups/:id", routing.Wrap(s.replaceGroupHandler))
+		r.Patch("/Groups/:id", routing.Wrap(s.patchGroupHandler))
+		r.Delete("/Groups/:id", routing.Wrap(s.deleteGroupHandler))
+	}, reqServiceAccount, authorize(middleware.ReqOrgAdmin, provisioningEvaluator))
+}
+
+// reqServiceAccount only lets requests authenticated with the token of a
+// service account through, identity providers send it as a bearer token.
+func reqServiceAccount(c *models.ReqContext) {
+	if !c.IsSignedIn || c.SignedInUser == nil || !c.SignedInUser.IsServiceAccount {
+		errorResponse(newError(http.StatusUnauthorized, "", "a service account token is required")).WriteTo(c)
+	}
+}
+
+// GET /api/scim/v2/ServiceProviderConfig
+func (s *SCIMService) getServiceProviderConfigHandler(c *models.ReqContext) response.Response {
+	return scimResponse(http.StatusOK, s.serviceProviderConfig())
+}
+
+// GET /api/scim/v2/ResourceTypes
+func (s *SCIMService) getResourceTypesHandler(c *models.ReqContext) response.Response {
+	types := s.resourceTypes()
+	resources := make([]interface{}, 0, len(types))
+	for _, t := range types {
+		resources = append(resources, t)
+	}
+	return scimResponse(http.StatusOK, staticListResponse(resources))
+}
+
+// GET /api/scim/v2/ResourceTypes/:id
+func (s *SCIMService) getResourceTypeHandler(c *models.ReqContext) response.Response {
+	id := web.Params(c.Req)[":id"]
+	for _, t := range s.resourceTypes() {
+		if t.ID == id {
+			return scimResponse(http.StatusOK, t)
+		}
+	}
+	return errorResponse(errNotFound("ResourceType", id))
+}
+
+// GET /api/scim/v2/Schemas
+func (s *SCIMService) getSchemasHandler(c *models.ReqContext) response.Response {
+	schemas := s.schemas()
+	resources := make([]interface{}, 0, len(schemas))
+	for _, schema := range schemas {
+		resources = append(resources, schema)
+	}
+	return scimResponse(http.StatusOK, staticListResponse(resources))
+}
+
+// GET /api/scim/v2/Schemas/:id
+func (s *SCIMService) getSchemaHandler(c *models.ReqContext) response.Response {
+	id := web.Params(c.Req)[":id"]
+	for _, schema := range s.schemas() {
+		if schema.ID == id {
+			return scimResponse(http.StatusOK, schema)
+		}
+	}
+	return errorResponse(errNotFound("Schema", id))
+}
+
+// GET /api/scim/v2/Users
+func (s *SCIMService) listUsersHandler(c *models.ReqContext) response.Response {
+	result, err := s.ListUsers(c.Req.Context(), listQuery(c))
+	if err != nil {
+		return errorResponse(err)
+	}
+	return projectedResponse(c, http.StatusOK, result)
+}
+
+// POST /api/scim/v2/Users
+func (s *SCIMService) createUserHandler(c *models.ReqContext) response.Response {
+	user := User{}
+	if err := bind(c, &user); err != nil {
+		return errorResponse(err)
+	}
+
+	result, err := s.CreateUser(c.Req.Context(), c.OrgId, &user)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return projectedResponse(c, http.StatusCreated, result)
+}
+
+// GET /api/scim/v2/Users/:id
+func (s *SCIMService) getUserHandler(c *models.ReqContext) response.Response {
+	result, err := s.GetUser(c.Req.Context(), c.OrgId, web.Params(c.Req)[":id"])
+	if err != nil {
+		return errorResponse(err)
+	}
+	return projectedResponse(c, http.StatusOK, result)
+}
+
+// PUT /api/scim/v2/Users/:id
+func (s *SCIMService) replaceUserHandler(c *models.ReqContext) response.Response {
+	user := User{}
+	if err := bind(c, &user); err != nil {
+		return errorResponse(err)
+	}
+
+	result, err := s.ReplaceUser(c.Req.Context(), c.OrgId, web.Params(c.Req)[":id"], &user)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return projectedResponse(c, http.StatusOK, result)
+}
+
+// PATCH /api/scim/v2/Users/:id
+func (s *SCIMService) patchUserHandler(c *models.ReqContext) response.Response {
+	patch := PatchRequest{}
+	if err := bind(c, &patch); err != nil {
+		return errorResponse(err)
+	}
+
+	result, err := s.PatchUser(c.Req.Context(), c.OrgId, web.Params(c.Req)[":id"], patch)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return projectedResponse(c, http.StatusOK, result)
+}
+
+// DELETE /api/scim/v2/Users/:id
+func (s *SCIMService) deleteUserHandler(c *models.ReqContext) response.Response {
+	if err := s.DeleteUser(c.Req.Context(), c.OrgId, web.Params(c.Req)[":id"]); err != nil {
+		return errorResponse(err)
+	}
+	return response.Respond(http.StatusNoContent, "")
+}
+
+// GET /api/scim/v2/Groups
+func (s *SCIMService) listGroupsHandler(c *models.ReqContext) response.Response {
+	result, err := s.ListGroups(c.Req.Context(), listQuery(c))
+	if err != nil {
+		return errorResponse(err)
+	}
+	return projectedResponse(c, http.StatusOK, result)
+}
+
+// POST /api/scim/v2/Groups
+func (s *SCIMService) createGroupHandler(c *models.ReqContext) response.Response {
+	group := Group{}
+	if err := bind(c, &group); err != nil {
+		return errorResponse(err)
+	}
+
+	result, err := s.CreateGroup(c.Req.Context(), c.OrgId, &group)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return projectedResponse(c, http.StatusCreated, result)
+}
+
+// GET /api/scim/v2/Groups/:id
+func (s *SCIMService) getGroupHandler(c *models.ReqContext) response.Response {
+	result, err := s.GetGroup(c.Req.Context(), c.OrgId, web.Params(c.Req)[":id"])
+	if err != nil {
+		return errorResponse(err)
+	}
+	return projectedResponse(c, http.StatusOK, result)
+}
+
+// PUT /api/scim/v2/Groups/:id
+func (s *SCIMService) replaceGroupHandler(c *models.ReqContext) response.Response {
+	group := Group{}
+	if err := bind(c, &group); err != nil {
+		return errorResponse(err)
+	}
+
+	result, err := s.ReplaceGroup(c.Req.Context(), c.OrgId, web.Params(c.Req)[":id"], &group)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return projectedResponse(c, http.StatusOK, result)
+}
+
+// PATCH /api/scim/v2/Groups/:id
+func (s *SCIMService) patchGroupHandler(c *models.ReqContext) response.Response {
+	patch := PatchRequest{}
+	if err := bind(c, &patch); err != nil {
+		return errorResponse(err)
+	}
+
+	result, err := s.PatchGroup(c.Req.Context(), c.OrgId, web.Params(c.Req)[":id"], patch)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return projectedResponse(c, http.StatusOK, result)
+}
+
+// DELETE /api/scim/v2/Groups/:id
+func (s *SCIMService) deleteGroupHandler(c *models.ReqContext) response.Response {
+	if err := s.DeleteGroup(c.Req.Context(), c.OrgId, web.Params(c.Req)[":id"]); err != nil {
+		return errorResponse(err)
+	}
+	return response.Respond(http.StatusNoContent, "")
+}
+
+func listQuery(c *models.ReqContext) ListQuery {
+	count := defaultCount
+	if c.Query("count") != "" {
+		count = c.QueryInt("count")
+	}
+
+	return ListQuery{
+		OrgID:      c.OrgId,
+		Filter:     c.Query("filter"),
+		StartIndex: c.QueryInt("startIndex"),
+		Count:      count,
+	}
+}
+
+// bind decodes the request body, SCIM clients send either application/json
+// or application/scim+json.
+func bind(c *models.ReqContext, v interface{}) error {
+	m, _, err := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	if err != nil || (m != "application/json" && m != contentType) {
+		return newError(http.StatusBadRequest, "invalidSyntax", "the content type must be %s", contentType)
+	}
+
+	defer func() { _ = c.Req.Body.Close() }()
+	if err := json.NewDecoder(c.Req.Body).Decode(v); err != nil {
+		return newError(http.StatusBadRequest, "invalidSyntax", "invalid request body: %s", err)
+	}
+	return nil
+}
+
+func scimResponse(status int, v interface{}) *response.NormalResponse {
+	return response.JSON(status, v).SetHeader("Content-Type", contentType)
+}
+
+// projectedResponse returns the resource with the attributes requested with
+// the attributes and excludedAttributes query parameters, see RFC 7644
+// section 3.9. Only top-level attributes are projected.
+func projectedResponse(c *models.ReqContext, status int, v interface{}) response.Response {
+	attributes := splitAttributes(c.Query("attributes"))
+	excluded := splitAttributes(c.Query("excludedAttributes"))
+	if len(attributes) == 0 && len(excluded) == 0 {
+		return scimResponse(status, v)
+	}
+
+	if list, ok := v.(*ListResponse); ok {
+		projected := *list
+		projected.Resources = make([]interface{}, 0, len(list.Resources))
+		for _, resource := range list.Resources {
+			doc, err := project(resource, attributes, excluded)
+			if err != nil {
+				return errorResponse(err)
+			}
+			projected.Resources = append(projected.Resources, doc)
+		}
+		return scimResponse(status, projected)
+	}
+
+	doc, err := project(v, attributes, excluded)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return scimResponse(status, doc)
+}
+
+func splitAttributes(value string) map[string]bool {
+	result := make(map[string]bool)
+	for _, attribute := range strings.Split(value, ",") {
+		path := splitAttributePath(strings.TrimSpace(attribute))
+		if len(path) > 0 {
+			result[strings.ToLower(path[0])] = true
+		}
+	}
+	return result
+}
+
+func project(resource interface{}, attributes, excluded map[string]bool) (map[string]interface{}, error) {
+	doc, err := toDocument(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	for key := range doc {
+		lower := strings.ToLower(key)
+		// id and schemas are always returned
+		if lower == "id" || lower == "schemas" {
+			continue
+		}
+		if (len(attributes) > 0 && !attributes[lower]) || excluded[lower] {
+			delete(doc, key)
+		}
+	}
+	return doc, nil
+}
+
+func staticListResponse(resources []interface{}) ListResponse {
+	return ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(resources),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func errorResponse(err error) *response.NormalResponse {
+	var scimErr *Error
+	if errors.As(err, &scimErr) {
+		return response.JSON(scimErr.statusCode, scimErr).SetHeader("Content-Type", contentType)
+	}
+
+	return response.Error(http.StatusInternalServerError, "Failed to process SCIM request", err).
+		SetHeader("Content-Type", contentType)
+}
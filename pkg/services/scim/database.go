@@ -0,0 +1,248 @@
+package scim
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+type userRecord struct {
+	ID         int64 `xorm:"id"`
+	Login      string
+	Email      string
+	Name       string
+	IsDisabled bool
+	IsAdmin    bool
+	Created    time.Time
+	Updated    time.Time
+	ExternalID string `xorm:"external_id"`
+	OrgCount   int64
+}
+
+type groupRecord struct {
+	ID         int64 `xorm:"id"`
+	Name       string
+	Email      string
+	Created    time.Time
+	Updated    time.Time
+	ExternalID string `xorm:"external_id"`
+}
+
+// membership is a member of a team, it is used for both the members of a
+// group and the groups of a user.
+type membership struct {
+	TeamID   int64 `xorm:"team_id"`
+	TeamName string
+	UserID   int64 `xorm:"user_id"`
+	Login    string
+}
+
+type externalID struct {
+	ID           int64 `xorm:"pk autoincr 'id'"`
+	OrgID        int64 `xorm:"org_id"`
+	ResourceType string
+	ResourceID   int64  `xorm:"resource_id"`
+	ExternalID   string `xorm:"external_id"`
+}
+
+func (e externalID) TableName() string {
+	return "scim_external_id"
+}
+
+// userQuery selects the users of an organization, service accounts are not
+// provisioned by SCIM and are always excluded.
+type userQuery struct {
+	orgID int64
+	// userID selects a single user when not 0
+	userID int64
+	// condition is an SQL condition on the users, see userFilterSQL
+	condition string
+	params    []interface{}
+	// offset and limit select a page of the users ordered by id, all of them
+	// are returned when limit is 0
+	offset int
+	limit  int
+}
+
+func (s *SCIMService) userQuerySQL(query userQuery) (string, []interface{}) {
+	dialect := s.SQLStore.Dialect
+	rawSQL := `
+		FROM org_user
+		INNER JOIN ` + dialect.Quote("user") + ` AS u ON u.id = org_user.user_id
+		LEFT OUTER JOIN scim_external_id ON scim_external_id.org_id = org_user.org_id
+			AND scim_external_id.resource_type = ? AND scim_external_id.resource_id = u.id
+		WHERE org_user.org_id = ? AND u.is_service_account = ` + dialect.BooleanStr(false)
+	params := []interface{}{ResourceTypeUser, query.orgID}
+
+	if query.userID != 0 {
+		rawSQL += " AND u.id = ?"
+		params = append(params, query.userID)
+	}
+	if query.condition != "" {
+		rawSQL += " AND (" + query.condition + ")"
+		params = append(params, query.params...)
+	}
+	return rawSQL, params
+}
+
+// getUsersByLoginOrEmail returns the users, including service accounts, with
+// the login or the email.
+func (s *SCIMService) getUsersByLoginOrEmail(ctx context.Context, login, email string) ([]models.User, error) {
+	result := make([]models.User, 0)
+	err := s.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("login = ? OR email = ?", login, email).Find(&result)
+	})
+
+	return result, err
+}
+
+// getUsers returns the users selected by the query.
+func (s *SCIMService) getUsers(ctx context.Context, query userQuery) ([]userRecord, error) {
+	result := make([]userRecord, 0)
+	err := s.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		from, params := s.userQuerySQL(query)
+		rawSQL := `SELECT
+			u.id,
+			u.login,
+			u.email,
+			u.name,
+			u.is_disabled,
+			u.is_admin,
+			u.created,
+			u.updated,
+			COALESCE(scim_external_id.external_id, '') AS external_id,
+			(SELECT COUNT(*) FROM org_user AS other WHERE other.user_id = u.id) AS org_count` + from + `
+			ORDER BY u.id`
+		if query.limit > 0 {
+			rawSQL += s.SQLStore.Dialect.LimitOffset(int64(query.limit), int64(query.offset))
+		}
+
+		return sess.SQL(rawSQL, params...).Find(&result)
+	})
+
+	return result, err
+}
+
+// countUsers returns the number of users selected by the query, regardless
+// of its page.
+func (s *SCIMService) countUsers(ctx context.Context, query userQuery) (int, error) {
+	var count int64
+	err := s.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		from, params := s.userQuerySQL(query)
+		_, err := sess.SQL("SELECT COUNT(*)"+from, params...).Get(&count)
+		return err
+	})
+
+	return int(count), err
+}
+
+// getGroups returns the teams of the organization. If teamID is not 0 only
+// that team is returned.
+func (s *SCIMService) getGroups(ctx context.Context, orgID, teamID int64) ([]groupRecord, error) {
+	result := make([]groupRecord, 0)
+	err := s.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		rawSQL := `SELECT
+			team.id,
+			team.name,
+			team.email,
+			team.created,
+			team.updated,
+			COALESCE(scim_external_id.external_id, '') AS external_id
+			FROM team
+			LEFT OUTER JOIN scim_external_id ON scim_external_id.org_id = team.org_id
+				AND scim_external_id.resource_type = ? AND scim_external_id.resource_id = team.id
+			WHERE team.org_id = ?`
+		params := []interface{}{ResourceTypeGroup, orgID}
+
+		if teamID != 0 {
+			rawSQL += " AND team.id = ?"
+			params = append(params, teamID)
+		}
+		rawSQL += " ORDER BY team.id"
+
+		return sess.SQL(rawSQL, params...).Find(&result)
+	})
+
+	return result, err
+}
+
+// getMemberships returns the team memberships of the organization, filtered
+// by team when teamID is not 0 and by users when userIDs are given.
+func (s *SCIMService) getMemberships(ctx context.Context, orgID, teamID int64, userIDs ...int64) ([]membership, error) {
+	result := make([]membership, 0)
+	err := s.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		rawSQL := `SELECT
+			team.id AS team_id,
+			team.name AS team_name,
+			u.id AS user_id,
+			u.login
+			FROM team_member
+			INNER JOIN team ON team.id = team_member.team_id
+			INNER JOIN ` + s.SQLStore.Dialect.Quote("user") + ` AS u ON u.id = team_member.user_id
+			WHERE team_member.org_id = ?`
+		params := []interface{}{orgID}
+
+		if teamID != 0 {
+			rawSQL += " AND team.id = ?"
+			params = append(params, teamID)
+		}
+		if len(userIDs) > 0 {
+			rawSQL += " AND u.id IN (?" + strings.Repeat(",?", len(userIDs)-1) + ")"
+			for _, userID := range userIDs {
+				params = append(params, userID)
+			}
+		}
+		rawSQL += " ORDER BY team.id, u.id"
+
+		return sess.SQL(rawSQL, params...).Find(&result)
+	})
+
+	return result, err
+}
+
+// getResourceByExternalID returns the id of the resource with the external
+// id, or 0 if there is none.
+func (s *SCIMService) getResourceByExternalID(ctx context.Context, orgID int64, resourceType, value string) (int64, error) {
+	var result externalID
+	err := s.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Where("org_id = ? AND resource_type = ? AND external_id = ?", orgID, resourceType, value).Get(&result)
+		return err
+	})
+
+	return result.ResourceID, err
+}
+
+// setExternalID stores the external id of a resource, an empty value
+// removes it.
+func (s *SCIMService) setExternalID(ctx context.Context, orgID int64, resourceType string, resourceID int64, value string) error {
+	return s.SQLStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		if _, err := sess.Exec("DELETE FROM scim_external_id WHERE org_id = ? AND resource_type = ? AND resource_id = ?", orgID, resourceType, resourceID); err != nil {
+			return err
+		}
+		if value == "" {
+			return nil
+		}
+
+		_, err := sess.Insert(&externalID{OrgID: orgID, ResourceType: resourceType, ResourceID: resourceID, ExternalID: value})
+		return err
+	})
+}
+
+// isOrgUser returns true if the user is a member of the organization and is
+// not a service account.
+func (s *SCIMService) isOrgUser(ctx context.Context, orgID, userID int64) (bool, error) {
+	var exists bool
+	err := s.SQLStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var err error
+		exists, err = sess.Table("org_user").
+			Join("INNER", s.SQLStore.Dialect.Quote("user")+" AS u", "u.id = org_user.user_id").
+			Where("org_user.org_id = ? AND org_user.user_id = ? AND u.is_service_account = ?", orgID, userID, s.SQLStore.Dialect.BooleanStr(false)).
+			Exist()
+		return err
+	})
+
+	return exists, err
+}
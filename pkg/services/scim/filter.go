@@ -0,0 +1,476 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// filter is a parsed SCIM filter expression, see RFC 7644 section 3.4.2.2.
+// Filters are evaluated against the JSON representation of a resource.
+type filter interface {
+	match(resource map[string]interface{}) bool
+}
+
+type logicalFilter struct {
+	and         bool
+	left, right filter
+}
+
+func (f logicalFilter) match(resource map[string]interface{}) bool {
+	if f.and {
+		return f.left.match(resource) && f.right.match(resource)
+	}
+	return f.left.match(resource) || f.right.match(resource)
+}
+
+type notFilter struct {
+	filter filter
+}
+
+func (f notFilter) match(resource map[string]interface{}) bool {
+	return !f.filter.match(resource)
+}
+
+type compareFilter struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+func (f compareFilter) match(resource map[string]interface{}) bool {
+	values := resolveValues(resource, f.path)
+
+	switch f.op {
+	case "pr":
+		for _, v := range values {
+			if !isEmpty(v) {
+				return true
+			}
+		}
+		return false
+	case "ne":
+		return !(compareFilter{path: f.path, op: "eq", value: f.value}).match(resource)
+	}
+
+	caseExact := isCaseExact(f.path)
+	for _, v := range values {
+		if compare(f.op, v, f.value, caseExact) {
+			return true
+		}
+	}
+	return false
+}
+
+// valuePathFilter matches the resources with an element of a multi-valued
+// attribute that matches the filter, such as emails[type eq "work"].
+type valuePathFilter struct {
+	attribute string
+	filter    filter
+}
+
+func (f valuePathFilter) match(resource map[string]interface{}) bool {
+	for _, element := range resolveElements(resource, f.attribute) {
+		if m, ok := element.(map[string]interface{}); ok && f.filter.match(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// userFilterSQL translates the eq filters on the userName and externalId of
+// the users, and their combinations with and and or, to an SQL condition of a
+// userQuery. False is returned for the other filters.
+func userFilterSQL(f filter) (string, []interface{}, bool) {
+	switch f := f.(type) {
+	case logicalFilter:
+		left, leftParams, ok := userFilterSQL(f.left)
+		if !ok {
+			return "", nil, false
+		}
+		right, rightParams, ok := userFilterSQL(f.right)
+		if !ok {
+			return "", nil, false
+		}
+		op := " OR "
+		if f.and {
+			op = " AND "
+		}
+		return "(" + left + op + right + ")", append(leftParams, rightParams...), true
+	case compareFilter:
+		value, ok := f.value.(string)
+		if !ok || f.op != "eq" || len(f.path) != 1 {
+			return "", nil, false
+		}
+		// userName is case insensitive, externalId is case exact
+		switch strings.ToLower(f.path[0]) {
+		case "username":
+			return "LOWER(u.login) = ?", []interface{}{strings.ToLower(value)}, true
+		case "externalid":
+			return "scim_external_id.external_id = ?", []interface{}{value}, true
+		}
+	}
+	return "", nil, false
+}
+
+var compareOperators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true,
+}
+
+func parseFilter(expr string) (filter, error) {
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, errInvalidFilter("unexpected %q in filter", p.tokens[p.pos].text)
+	}
+	return f, nil
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOpenParen
+	tokenCloseParen
+	tokenOpenBracket
+	tokenCloseBracket
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenizeFilter(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpenParen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenCloseParen, text: ")"})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenOpenBracket, text: "["})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenCloseBracket, text: "]"})
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(expr); end++ {
+				if expr[end] == '\\' {
+					end++
+					continue
+				}
+				if expr[end] == '"' {
+					break
+				}
+			}
+			if end >= len(expr) {
+				return nil, errInvalidFilter("unterminated string in filter")
+			}
+			tokens = append(tokens, token{kind: tokenString, text: expr[i : end+1]})
+			i = end + 1
+		default:
+			end := i
+			for end < len(expr) && !strings.ContainsRune(" \t()[]\"", rune(expr[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: expr[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) peekKeyword(keyword string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenWord && strings.EqualFold(p.tokens[p.pos].text, keyword)
+}
+
+func (p *filterParser) expect(kind tokenKind, text string) error {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != kind {
+		return errInvalidFilter("expected %q in filter", text)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *filterParser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalFilter{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filter, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errInvalidFilter("unexpected end of filter")
+	}
+
+	if p.peekKeyword("not") {
+		p.pos++
+		if err := p.expect(tokenOpenParen, "("); err != nil {
+			return nil, err
+		}
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseParen, ")"); err != nil {
+			return nil, err
+		}
+		return notFilter{filter: f}, nil
+	}
+
+	tok := p.tokens[p.pos]
+	switch tok.kind {
+	case tokenOpenParen:
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseParen, ")"); err != nil {
+			return nil, err
+		}
+		return f, nil
+	case tokenWord:
+		p.pos++
+		return p.parseAttributeExpression(tok.text)
+	default:
+		return nil, errInvalidFilter("unexpected %q in filter", tok.text)
+	}
+}
+
+func (p *filterParser) parseAttributeExpression(attribute string) (filter, error) {
+	path := splitAttributePath(attribute)
+	if len(path) == 0 {
+		return nil, errInvalidFilter("invalid attribute %q in filter", attribute)
+	}
+
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOpenBracket {
+		p.pos++
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseBracket, "]"); err != nil {
+			return nil, err
+		}
+		if len(path) != 1 {
+			return nil, errInvalidFilter("invalid attribute %q in filter", attribute)
+		}
+		return valuePathFilter{attribute: path[0], filter: f}, nil
+	}
+
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenWord {
+		return nil, errInvalidFilter("expected an operator after %q in filter", attribute)
+	}
+	op := strings.ToLower(p.tokens[p.pos].text)
+	p.pos++
+
+	if op == "pr" {
+		return compareFilter{path: path, op: op}, nil
+	}
+	if !compareOperators[op] {
+		return nil, errInvalidFilter("unknown operator %q in filter", op)
+	}
+
+	if p.pos >= len(p.tokens) || (p.tokens[p.pos].kind != tokenWord && p.tokens[p.pos].kind != tokenString) {
+		return nil, errInvalidFilter("expected a value after %q in filter", op)
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(p.tokens[p.pos].text), &value); err != nil {
+		return nil, errInvalidFilter("invalid value %s in filter", p.tokens[p.pos].text)
+	}
+	p.pos++
+
+	return compareFilter{path: path, op: op, value: value}, nil
+}
+
+// splitAttributePath removes the schema URN of an attribute and splits it
+// into its sub-attributes.
+func splitAttributePath(attribute string) []string {
+	for _, schema := range []string{SchemaUser, SchemaGroup} {
+		if len(attribute) > len(schema) && strings.EqualFold(attribute[:len(schema)+1], schema+":") {
+			attribute = attribute[len(schema)+1:]
+			break
+		}
+	}
+	if attribute == "" {
+		return nil
+	}
+	return strings.Split(attribute, ".")
+}
+
+// resolveElements returns the values of an attribute, multi-valued
+// attributes are flattened.
+func resolveElements(resource map[string]interface{}, attribute string) []interface{} {
+	value, ok := lookup(resource, attribute)
+	if !ok || value == nil {
+		return nil
+	}
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{value}
+}
+
+// resolveValues returns the values of an attribute path. Complex
+// multi-valued attributes compare on their value sub-attribute, so that
+// emails eq "a@example.com" matches as emails.value would.
+func resolveValues(resource map[string]interface{}, path []string) []interface{} {
+	current := []interface{}{resource}
+	for _, attribute := range path {
+		var next []interface{}
+		for _, value := range current {
+			if m, ok := value.(map[string]interface{}); ok {
+				next = append(next, resolveElements(m, attribute)...)
+			}
+		}
+		current = next
+	}
+
+	result := make([]interface{}, 0, len(current))
+	for _, value := range current {
+		if m, ok := value.(map[string]interface{}); ok {
+			if v, ok := lookup(m, "value"); ok {
+				result = append(result, v)
+			}
+			continue
+		}
+		result = append(result, value)
+	}
+	return result
+}
+
+// lookup returns an attribute of a resource, attribute names are case
+// insensitive.
+func lookup(resource map[string]interface{}, attribute string) (interface{}, bool) {
+	if value, ok := resource[attribute]; ok {
+		return value, true
+	}
+	for key, value := range resource {
+		if strings.EqualFold(key, attribute) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+func isCaseExact(path []string) bool {
+	last := strings.ToLower(path[len(path)-1])
+	return last == "id" || last == "externalid"
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func compare(op string, actual, expected interface{}, caseExact bool) bool {
+	switch a := actual.(type) {
+	case string:
+		e, ok := expected.(string)
+		if !ok {
+			return false
+		}
+		if !caseExact {
+			a, e = strings.ToLower(a), strings.ToLower(e)
+		}
+		switch op {
+		case "eq":
+			return a == e
+		case "co":
+			return strings.Contains(a, e)
+		case "sw":
+			return strings.HasPrefix(a, e)
+		case "ew":
+			return strings.HasSuffix(a, e)
+		case "gt":
+			return a > e
+		case "ge":
+			return a >= e
+		case "lt":
+			return a < e
+		case "le":
+			return a <= e
+		}
+	case float64:
+		e, ok := expected.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return a == e
+		case "gt":
+			return a > e
+		case "ge":
+			return a >= e
+		case "lt":
+			return a < e
+		case "le":
+			return a <= e
+		}
+	case bool:
+		e, ok := expected.(bool)
+		return ok && op == "eq" && a == e
+	case nil:
+		return op == "eq" && expected == nil
+	}
+	return false
+}
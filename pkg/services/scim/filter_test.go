@@ -0,0 +1,114 @@
+package scim
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	resource := map[string]interface{}{
+		"id":          "42",
+		"externalId":  "Ab12",
+		"userName":    "Alice",
+		"displayName": "Alice Smith",
+		"active":      true,
+		"emails": []interface{}{
+			map[string]interface{}{"value": "alice@example.com", "type": "work", "primary": true},
+		},
+		"name": map[string]interface{}{"formatted": "Alice Smith"},
+		"meta": map[string]interface{}{"lastModified": "2022-05-01T10:00:00Z"},
+	}
+
+	tests := []struct {
+		filter string
+		match  bool
+	}{
+		{filter: `userName eq "alice"`, match: true},
+		{filter: `USERNAME Eq "ALICE"`, match: true},
+		{filter: `userName ne "alice"`, match: false},
+		{filter: `externalId eq "ab12"`, match: false},
+		{filter: `externalId eq "Ab12"`, match: true},
+		{filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName sw "Al"`, match: true},
+		{filter: `displayName co "smith"`, match: true},
+		{filter: `displayName ew "jones"`, match: false},
+		{filter: `name.formatted eq "Alice Smith"`, match: true},
+		{filter: `emails eq "alice@example.com"`, match: true},
+		{filter: `emails.value eq "alice@example.com"`, match: true},
+		{filter: `emails[type eq "work" and value co "example"]`, match: true},
+		{filter: `emails[type eq "home"]`, match: false},
+		{filter: `active eq true`, match: true},
+		{filter: `active eq false`, match: false},
+		{filter: `title pr`, match: false},
+		{filter: `externalId pr`, match: true},
+		{filter: `meta.lastModified gt "2022-01-01T00:00:00Z"`, match: true},
+		{filter: `meta.lastModified lt "2022-01-01T00:00:00Z"`, match: false},
+		{filter: `userName eq "bob" or userName eq "alice"`, match: true},
+		{filter: `userName eq "alice" and active eq false`, match: false},
+		{filter: `userName eq "bob" or userName eq "alice" and active eq false`, match: false},
+		{filter: `(userName eq "bob" or userName eq "alice") and active eq true`, match: true},
+		{filter: `not (userName eq "bob")`, match: true},
+		{filter: `displayName eq "Alice \"Al\" Smith"`, match: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := parseFilter(tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.match, f.match(resource))
+		})
+	}
+}
+
+func TestFilter_Invalid(t *testing.T) {
+	for _, filter := range []string{
+		`userName`,
+		`userName eq`,
+		`userName like "alice"`,
+		`userName eq alice`,
+		`userName eq "alice`,
+		`(userName eq "alice"`,
+		`userName eq "alice" or`,
+		`emails[type eq "work"`,
+		`not userName eq "alice"`,
+	} {
+		t.Run(filter, func(t *testing.T) {
+			_, err := parseFilter(filter)
+			var scimErr *Error
+			require.ErrorAs(t, err, &scimErr)
+			assert.Equal(t, "invalidFilter", scimErr.ScimType)
+		})
+	}
+}
+
+func TestUserFilterSQL(t *testing.T) {
+	tests := []struct {
+		filter    string
+		condition string
+		params    []interface{}
+	}{
+		{filter: `userName eq "Alice"`, condition: "LOWER(u.login) = ?", params: []interface{}{"alice"}},
+		{filter: `urn:ietf:params:scim:schemas:core:2.0:User:externalId eq "Ab12"`, condition: "scim_external_id.external_id = ?", params: []interface{}{"Ab12"}},
+		{
+			filter:    `userName eq "bob" or userName eq "alice" and externalId eq "Ab12"`,
+			condition: "(LOWER(u.login) = ? OR (LOWER(u.login) = ? AND scim_external_id.external_id = ?))",
+			params:    []interface{}{"bob", "alice", "Ab12"},
+		},
+		{filter: `userName sw "al"`},
+		{filter: `emails.value eq "alice@example.com"`},
+		{filter: `userName eq "alice" or active eq true`},
+		{filter: `not (userName eq "bob")`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := parseFilter(tt.filter)
+			require.NoError(t, err)
+			condition, params, ok := userFilterSQL(f)
+			assert.Equal(t, tt.condition != "", ok)
+			assert.Equal(t, tt.condition, condition)
+			assert.Equal(t, tt.params, params)
+		})
+	}
+}
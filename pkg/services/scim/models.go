@@ -0,0 +1,172 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+const (
+	ResourceTypeUser  = "User"
+	ResourceTypeGroup = "Group"
+)
+
+// Boolean accepts both JSON booleans and strings, some identity providers
+// send "True" and "False" in PATCH operations.
+type Boolean bool
+
+func (b *Boolean) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		*b = Boolean(v)
+	case string:
+		parsed, err := strconv.ParseBool(strings.ToLower(v))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", v)
+		}
+		*b = Boolean(parsed)
+	default:
+		return fmt.Errorf("invalid boolean %s", string(data))
+	}
+	return nil
+}
+
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// MultiValue is an element of a multi-valued attribute such as the emails of
+// a user or the members of a group.
+type MultiValue struct {
+	Value   string  `json:"value"`
+	Display string  `json:"display,omitempty"`
+	Type    string  `json:"type,omitempty"`
+	Primary Boolean `json:"primary,omitempty"`
+	Ref     string  `json:"$ref,omitempty"`
+}
+
+type User struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	Name        *Name        `json:"name,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Emails      []MultiValue `json:"emails,omitempty"`
+	Active      *Boolean     `json:"active,omitempty"`
+	Groups      []MultiValue `json:"groups,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+type Group struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []MultiValue `json:"members,omitempty"`
+	Meta        *Meta        `json:"meta,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// ListQuery holds the query parameters of a list request.
+type ListQuery struct {
+	OrgID      int64
+	Filter     string
+	StartIndex int
+	Count      int
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Error is both returned by the service and written as is by the API, as
+// described in RFC 7644 section 3.12.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+
+	statusCode int
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func newError(statusCode int, scimType string, format string, args ...interface{}) *Error {
+	return &Error{
+		Schemas:    []string{SchemaError},
+		Status:     strconv.Itoa(statusCode),
+		ScimType:   scimType,
+		Detail:     fmt.Sprintf(format, args...),
+		statusCode: statusCode,
+	}
+}
+
+func errNotFound(resourceType, id string) *Error {
+	return newError(http.StatusNotFound, "", "%s %s not found", resourceType, id)
+}
+
+func errInvalidValue(format string, args ...interface{}) *Error {
+	return newError(http.StatusBadRequest, "invalidValue", format, args...)
+}
+
+func errInvalidFilter(format string, args ...interface{}) *Error {
+	return newError(http.StatusBadRequest, "invalidFilter", format, args...)
+}
+
+func errInvalidPath(format string, args ...interface{}) *Error {
+	return newError(http.StatusBadRequest, "invalidPath", format, args...)
+}
+
+func errNoTarget(path string) *Error {
+	return newError(http.StatusBadRequest, "noTarget", "no value matches the path %s", path)
+}
+
+func errUniqueness(format string, args ...interface{}) *Error {
+	return newError(http.StatusConflict, "uniqueness", format, args...)
+}
@@ -0,0 +1,275 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// patchPath is the target of a PATCH operation, see RFC 7644 section 3.5.2.
+// For example emails[type eq "work"].value has the attribute emails, a
+// filter on its elements and the sub-attribute value.
+type patchPath struct {
+	attribute    string
+	filter       filter
+	subAttribute string
+}
+
+func parsePatchPath(path string) (patchPath, error) {
+	if i := strings.Index(path, "["); i >= 0 {
+		j := strings.LastIndex(path, "]")
+		if j < i {
+			return patchPath{}, errInvalidPath("invalid path %q", path)
+		}
+
+		attribute := splitAttributePath(path[:i])
+		if len(attribute) != 1 {
+			return patchPath{}, errInvalidPath("invalid path %q", path)
+		}
+
+		f, err := parseFilter(path[i+1 : j])
+		if err != nil {
+			return patchPath{}, errInvalidPath("invalid filter in path %q", path)
+		}
+
+		result := patchPath{attribute: attribute[0], filter: f}
+		if rest := path[j+1:]; rest != "" {
+			if !strings.HasPrefix(rest, ".") || len(rest) == 1 {
+				return patchPath{}, errInvalidPath("invalid path %q", path)
+			}
+			result.subAttribute = rest[1:]
+		}
+		return result, nil
+	}
+
+	attribute := splitAttributePath(path)
+	switch len(attribute) {
+	case 1:
+		return patchPath{attribute: attribute[0]}, nil
+	case 2:
+		return patchPath{attribute: attribute[0], subAttribute: attribute[1]}, nil
+	}
+	return patchPath{}, errInvalidPath("invalid path %q", path)
+}
+
+// applyPatch applies the operations to the JSON representation of a
+// resource.
+func applyPatch(resource map[string]interface{}, operations []PatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			return errInvalidValue("unknown operation %q", operation.Op)
+		}
+
+		var value interface{}
+		if len(operation.Value) > 0 {
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return errInvalidValue("invalid value for operation %q", operation.Op)
+			}
+		}
+
+		if operation.Path == "" {
+			if op == "remove" {
+				return errNoTarget("")
+			}
+			attributes, ok := value.(map[string]interface{})
+			if !ok {
+				return errInvalidValue("operation %q without path requires an object value", operation.Op)
+			}
+			// some identity providers send sub-attributes such as name.givenName as keys
+			for key, v := range attributes {
+				path, err := parsePatchPath(key)
+				if err != nil {
+					return err
+				}
+				if err := applyPatchPath(resource, op, path, key, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		path, err := parsePatchPath(operation.Path)
+		if err != nil {
+			return err
+		}
+		if err := applyPatchPath(resource, op, path, operation.Path, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyPatchPath(resource map[string]interface{}, op string, path patchPath, rawPath string, value interface{}) error {
+	key := attributeKey(resource, path.attribute)
+	current := resource[key]
+
+	if path.filter != nil {
+		elements, _ := current.([]interface{})
+		return applyFilteredPatch(resource, key, elements, op, path, rawPath, value)
+	}
+
+	if path.subAttribute != "" {
+		switch c := current.(type) {
+		case map[string]interface{}:
+			setOrRemove(c, op, path.subAttribute, value)
+		case []interface{}:
+			for _, element := range c {
+				if m, ok := element.(map[string]interface{}); ok {
+					setOrRemove(m, op, path.subAttribute, value)
+				}
+			}
+		case nil:
+			if op != "remove" {
+				resource[key] = map[string]interface{}{path.subAttribute: value}
+			}
+		default:
+			return errInvalidPath("%s has no sub-attributes", path.attribute)
+		}
+		return nil
+	}
+
+	switch op {
+	case "remove":
+		// removing elements by value is not in the RFC but commonly sent for members
+		if list, ok := current.([]interface{}); ok && value != nil {
+			resource[key] = removeElements(list, toList(value))
+			return nil
+		}
+		delete(resource, key)
+	case "add":
+		if list, ok := current.([]interface{}); ok {
+			resource[key] = addElements(list, toList(value))
+			return nil
+		}
+		resource[key] = value
+	case "replace":
+		resource[key] = value
+	}
+	return nil
+}
+
+func applyFilteredPatch(resource map[string]interface{}, key string, elements []interface{}, op string, path patchPath, rawPath string, value interface{}) error {
+	matched := false
+	result := make([]interface{}, 0, len(elements))
+	for _, element := range elements {
+		m, ok := element.(map[string]interface{})
+		if !ok || !path.filter.match(m) {
+			result = append(result, element)
+			continue
+		}
+		matched = true
+
+		switch {
+		case op == "remove" && path.subAttribute == "":
+			continue
+		case path.subAttribute != "":
+			setOrRemove(m, op, path.subAttribute, value)
+		default:
+			if v, ok := value.(map[string]interface{}); ok {
+				for k, sub := range v {
+					m[attributeKey(m, k)] = sub
+				}
+			}
+		}
+		result = append(result, m)
+	}
+
+	if !matched {
+		if op == "remove" {
+			return nil
+		}
+		// emails[type eq "work"].value adds the element when there is none
+		element, ok := elementFromFilter(path.filter)
+		if !ok {
+			return errNoTarget(rawPath)
+		}
+		if path.subAttribute != "" {
+			element[path.subAttribute] = value
+		} else if v, ok := value.(map[string]interface{}); ok {
+			for k, sub := range v {
+				element[k] = sub
+			}
+		}
+		result = append(result, element)
+	}
+
+	resource[key] = result
+	return nil
+}
+
+// elementFromFilter returns the element created by a path filter that is a
+// single equality test such as type eq "work".
+func elementFromFilter(f filter) (map[string]interface{}, bool) {
+	c, ok := f.(compareFilter)
+	if !ok || c.op != "eq" || len(c.path) != 1 {
+		return nil, false
+	}
+	return map[string]interface{}{c.path[0]: c.value}, true
+}
+
+func setOrRemove(m map[string]interface{}, op, attribute string, value interface{}) {
+	key := attributeKey(m, attribute)
+	if op == "remove" {
+		delete(m, key)
+		return
+	}
+	m[key] = value
+}
+
+// attributeKey returns the key of an attribute in the resource, or the
+// attribute if the resource does not have it.
+func attributeKey(resource map[string]interface{}, attribute string) string {
+	for key := range resource {
+		if strings.EqualFold(key, attribute) {
+			return key
+		}
+	}
+	return attribute
+}
+
+func toList(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	return []interface{}{value}
+}
+
+func addElements(list []interface{}, elements []interface{}) []interface{} {
+	for _, element := range elements {
+		if !containsElement(list, element) {
+			list = append(list, element)
+		}
+	}
+	return list
+}
+
+func removeElements(list []interface{}, elements []interface{}) []interface{} {
+	result := make([]interface{}, 0, len(list))
+	for _, element := range list {
+		if !containsElement(elements, element) {
+			result = append(result, element)
+		}
+	}
+	return result
+}
+
+func containsElement(list []interface{}, element interface{}) bool {
+	value := elementValue(element)
+	for _, e := range list {
+		if elementValue(e) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// elementValue returns the value sub-attribute of complex elements, elements
+// of multi-valued attributes are identified by it.
+func elementValue(element interface{}) string {
+	if m, ok := element.(map[string]interface{}); ok {
+		v, _ := lookup(m, "value")
+		return fmt.Sprint(v)
+	}
+	return fmt.Sprint(element)
+}
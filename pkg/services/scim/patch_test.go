@@ -0,0 +1,108 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPatch(t *testing.T) {
+	newUser := func() map[string]interface{} {
+		return map[string]interface{}{
+			"userName":    "alice",
+			"displayName": "Alice",
+			"active":      true,
+			"emails": []interface{}{
+				map[string]interface{}{"value": "alice@example.com", "type": "work", "primary": true},
+			},
+		}
+	}
+	newGroup := func() map[string]interface{} {
+		return map[string]interface{}{
+			"displayName": "Team",
+			"members": []interface{}{
+				map[string]interface{}{"value": "1"},
+				map[string]interface{}{"value": "2"},
+			},
+		}
+	}
+	op := func(op, path, value string) PatchOperation {
+		operation := PatchOperation{Op: op, Path: path}
+		if value != "" {
+			operation.Value = json.RawMessage(value)
+		}
+		return operation
+	}
+
+	t.Run("should replace attributes with and without path", func(t *testing.T) {
+		user := newUser()
+		err := applyPatch(user, []PatchOperation{
+			op("Replace", "active", `"False"`),
+			op("replace", "", `{"displayName": "Alice Smith", "name.givenName": "Alice"}`),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "False", user["active"])
+		assert.Equal(t, "Alice Smith", user["displayName"])
+		assert.Equal(t, map[string]interface{}{"givenName": "Alice"}, user["name"])
+	})
+
+	t.Run("should replace the value of filtered elements", func(t *testing.T) {
+		user := newUser()
+		err := applyPatch(user, []PatchOperation{op("replace", `emails[type eq "work"].value`, `"alice@grafana.com"`)})
+		require.NoError(t, err)
+		assert.Equal(t, "alice@grafana.com", user["emails"].([]interface{})[0].(map[string]interface{})["value"])
+	})
+
+	t.Run("should add an element when no element matches an equality filter", func(t *testing.T) {
+		user := newUser()
+		delete(user, "emails")
+		err := applyPatch(user, []PatchOperation{op("add", `emails[type eq "work"].value`, `"alice@grafana.com"`)})
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{map[string]interface{}{"type": "work", "value": "alice@grafana.com"}}, user["emails"])
+
+		err = applyPatch(user, []PatchOperation{op("replace", `emails[type eq "home" or type eq "other"].value`, `"x"`)})
+		var scimErr *Error
+		require.ErrorAs(t, err, &scimErr)
+		assert.Equal(t, "noTarget", scimErr.ScimType)
+	})
+
+	t.Run("should add members without duplicates", func(t *testing.T) {
+		group := newGroup()
+		err := applyPatch(group, []PatchOperation{op("add", "members", `[{"value": "2"}, {"value": "3"}]`)})
+		require.NoError(t, err)
+		assert.Len(t, group["members"], 3)
+	})
+
+	t.Run("should remove members with a filter or a value", func(t *testing.T) {
+		group := newGroup()
+		err := applyPatch(group, []PatchOperation{op("remove", `members[value eq "1"]`, "")})
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{map[string]interface{}{"value": "2"}}, group["members"])
+
+		group = newGroup()
+		err = applyPatch(group, []PatchOperation{op("remove", "members", `[{"value": "2"}]`)})
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{map[string]interface{}{"value": "1"}}, group["members"])
+
+		group = newGroup()
+		err = applyPatch(group, []PatchOperation{op("remove", "members", "")})
+		require.NoError(t, err)
+		assert.NotContains(t, group, "members")
+	})
+
+	t.Run("should reject invalid operations", func(t *testing.T) {
+		for _, operation := range []PatchOperation{
+			op("move", "displayName", `"x"`),
+			op("remove", "", ""),
+			op("replace", "", `"x"`),
+			op("replace", `members[value eq "1"`, `"x"`),
+			op("replace", "name.givenName.first", `"x"`),
+		} {
+			err := applyPatch(newGroup(), []PatchOperation{operation})
+			var scimErr *Error
+			require.ErrorAs(t, err, &scimErr, operation.Op+" "+operation.Path)
+		}
+	})
+}
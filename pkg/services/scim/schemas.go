@@ -0,0 +1,183 @@
+package scim
+
+// The discovery endpoints describe what the service supports, see RFC 7643
+// sections 5 to 7.
+
+type schemaAttribute struct {
+	Name           string            `json:"name"`
+	Type           string            `json:"type"`
+	MultiValued    bool              `json:"multiValued"`
+	Description    string            `json:"description"`
+	Required       bool              `json:"required"`
+	CaseExact      bool              `json:"caseExact"`
+	Mutability     string            `json:"mutability"`
+	Returned       string            `json:"returned"`
+	Uniqueness     string            `json:"uniqueness"`
+	ReferenceTypes []string          `json:"referenceTypes,omitempty"`
+	SubAttributes  []schemaAttribute `json:"subAttributes,omitempty"`
+}
+
+type schema struct {
+	Schemas     []string          `json:"schemas"`
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Attributes  []schemaAttribute `json:"attributes"`
+	Meta        *Meta             `json:"meta,omitempty"`
+}
+
+type resourceType struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Endpoint    string   `json:"endpoint"`
+	Description string   `json:"description"`
+	Schema      string   `json:"schema"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type filterSupported struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type bulkSupported struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type authenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+type serviceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	DocumentationURI      string                 `json:"documentationUri"`
+	Patch                 supported              `json:"patch"`
+	Bulk                  bulkSupported          `json:"bulk"`
+	Filter                filterSupported        `json:"filter"`
+	ChangePassword        supported              `json:"changePassword"`
+	Sort                  supported              `json:"sort"`
+	ETag                  supported              `json:"etag"`
+	AuthenticationSchemes []authenticationScheme `json:"authenticationSchemes"`
+	Meta                  *Meta                  `json:"meta,omitempty"`
+}
+
+func (s *SCIMService) serviceProviderConfig() serviceProviderConfig {
+	return serviceProviderConfig{
+		Schemas:          []string{SchemaServiceProviderConfig},
+		DocumentationURI: "https://grafana.com/docs/grafana/latest/http_api/scim/",
+		Patch:            supported{Supported: true},
+		Filter:           filterSupported{Supported: true, MaxResults: maxCount},
+		AuthenticationSchemes: []authenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "Service account token",
+			Description: "Authentication with the token of a service account of the organization",
+			Primary:     true,
+		}},
+		Meta: &Meta{ResourceType: "ServiceProviderConfig", Location: s.location("ServiceProviderConfig")},
+	}
+}
+
+func (s *SCIMService) resourceTypes() []resourceType {
+	return []resourceType{
+		{
+			Schemas:     []string{SchemaResourceType},
+			ID:          ResourceTypeUser,
+			Name:        ResourceTypeUser,
+			Endpoint:    "/Users",
+			Description: "Users of the organization",
+			Schema:      SchemaUser,
+			Meta:        &Meta{ResourceType: "ResourceType", Location: s.location("ResourceTypes/" + ResourceTypeUser)},
+		},
+		{
+			Schemas:     []string{SchemaResourceType},
+			ID:          ResourceTypeGroup,
+			Name:        ResourceTypeGroup,
+			Endpoint:    "/Groups",
+			Description: "Teams of the organization",
+			Schema:      SchemaGroup,
+			Meta:        &Meta{ResourceType: "ResourceType", Location: s.location("ResourceTypes/" + ResourceTypeGroup)},
+		},
+	}
+}
+
+func (s *SCIMService) schemas() []schema {
+	return []schema{
+		{
+			Schemas:     []string{SchemaSchema},
+			ID:          SchemaUser,
+			Name:        ResourceTypeUser,
+			Description: "User",
+			Attributes: []schemaAttribute{
+				stringAttribute("userName", "The login of the user.", true, "readWrite", "server"),
+				{
+					Name: "name", Type: "complex", Description: "The name of the user.", Mutability: "readWrite", Returned: "default", Uniqueness: "none",
+					SubAttributes: []schemaAttribute{
+						stringAttribute("formatted", "The full name of the user.", false, "readWrite", "none"),
+						stringAttribute("givenName", "The given name of the user.", false, "readWrite", "none"),
+						stringAttribute("familyName", "The family name of the user.", false, "readWrite", "none"),
+					},
+				},
+				stringAttribute("displayName", "The name of the user.", false, "readWrite", "none"),
+				{
+					Name: "emails", Type: "complex", MultiValued: true, Description: "The email of the user, only the primary email is stored.",
+					Mutability: "readWrite", Returned: "default", Uniqueness: "none",
+					SubAttributes: []schemaAttribute{
+						stringAttribute("value", "The email address.", false, "readWrite", "server"),
+						stringAttribute("type", "The type of email.", false, "readWrite", "none"),
+						{Name: "primary", Type: "boolean", Description: "Whether this is the primary email.", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+					},
+				},
+				{Name: "active", Type: "boolean", Description: "Inactive users are disabled and cannot sign in.", Mutability: "readWrite", Returned: "default", Uniqueness: "none"},
+				{
+					Name: "groups", Type: "complex", MultiValued: true, Description: "The teams of the user.", Mutability: "readOnly", Returned: "default", Uniqueness: "none",
+					SubAttributes: []schemaAttribute{
+						stringAttribute("value", "The id of the team.", false, "readOnly", "none"),
+						stringAttribute("display", "The name of the team.", false, "readOnly", "none"),
+						{Name: "$ref", Type: "reference", ReferenceTypes: []string{"Group"}, Description: "The URI of the team.", Mutability: "readOnly", Returned: "default", Uniqueness: "none"},
+					},
+				},
+			},
+			Meta: &Meta{ResourceType: "Schema", Location: s.location("Schemas/" + SchemaUser)},
+		},
+		{
+			Schemas:     []string{SchemaSchema},
+			ID:          SchemaGroup,
+			Name:        ResourceTypeGroup,
+			Description: "Group",
+			Attributes: []schemaAttribute{
+				stringAttribute("displayName", "The name of the team.", true, "readWrite", "server"),
+				{
+					Name: "members", Type: "complex", MultiValued: true, Description: "The members of the team.", Mutability: "readWrite", Returned: "default", Uniqueness: "none",
+					SubAttributes: []schemaAttribute{
+						stringAttribute("value", "The id of the user.", false, "immutable", "none"),
+						stringAttribute("display", "The login of the user.", false, "readOnly", "none"),
+						{Name: "$ref", Type: "reference", ReferenceTypes: []string{"User"}, Description: "The URI of the user.", Mutability: "immutable", Returned: "default", Uniqueness: "none"},
+					},
+				},
+			},
+			Meta: &Meta{ResourceType: "Schema", Location: s.location("Schemas/" + SchemaGroup)},
+		},
+	}
+}
+
+func stringAttribute(name, description string, required bool, mutability, uniqueness string) schemaAttribute {
+	return schemaAttribute{
+		Name:        name,
+		Type:        "string",
+		Description: description,
+		Required:    required,
+		Mutability:  mutability,
+		Returned:    "default",
+		Uniqueness:  uniqueness,
+	}
+}
@@ -0,0 +1,837 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const (
+	defaultCount = 100
+	maxCount     = 1000
+
+	// memberPermission is the team permission of the members added by SCIM
+	memberPermission = "Member"
+)
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, routeRegister routing.RouteRegister,
+	accessControl accesscontrol.AccessControl, teamPermissionsService accesscontrol.TeamPermissionsService,
+	userTokenService models.UserTokenService) *SCIMService {
+	s := &SCIMService{
+		Cfg:                    cfg,
+		SQLStore:               sqlStore,
+		RouteRegister:          routeRegister,
+		AccessControl:          accessControl,
+		TeamPermissionsService: teamPermissionsService,
+		UserTokenService:       userTokenService,
+		log:                    log.New("scim"),
+	}
+
+	if cfg.SCIMEnabled {
+		s.registerAPIEndpoints()
+	}
+
+	return s
+}
+
+// SCIMService provisions the users and teams of an organization with the
+// SCIM 2.0 protocol, see RFC 7643 and RFC 7644.
+type SCIMService struct {
+	Cfg                    *setting.Cfg
+	SQLStore               *sqlstore.SQLStore
+	RouteRegister          routing.RouteRegister
+	AccessControl          accesscontrol.AccessControl
+	TeamPermissionsService accesscontrol.TeamPermissionsService
+	UserTokenService       models.UserTokenService
+	log                    log.Logger
+}
+
+func (s *SCIMService) GetUser(ctx context.Context, orgID int64, id string) (*User, error) {
+	record, err := s.getUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := s.getMemberships(ctx, orgID, 0, record.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toUser(*record, memberships), nil
+}
+
+// ListUsers returns a page of the users of the organization. The filters on
+// the userName and externalId of the users are evaluated by the database with
+// the page, the other filters need to load every user of the organization.
+func (s *SCIMService) ListUsers(ctx context.Context, query ListQuery) (*ListResponse, error) {
+	f, err := parseListQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	usersQuery := userQuery{orgID: query.OrgID}
+	if f != nil {
+		var ok bool
+		if usersQuery.condition, usersQuery.params, ok = userFilterSQL(f); !ok {
+			return s.filterUsers(ctx, f, query)
+		}
+	}
+
+	startIndex, count := listPage(query)
+	total, err := s.countUsers(ctx, usersQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]interface{}, 0)
+	if count > 0 && startIndex <= total {
+		usersQuery.offset, usersQuery.limit = startIndex-1, count
+		records, err := s.getUsers(ctx, usersQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		userIDs := make([]int64, 0, len(records))
+		for _, record := range records {
+			userIDs = append(userIDs, record.ID)
+		}
+		memberships := []membership{}
+		if len(userIDs) > 0 {
+			if memberships, err = s.getMemberships(ctx, query.OrgID, 0, userIDs...); err != nil {
+				return nil, err
+			}
+		}
+		resources = s.toUsers(records, memberships)
+	}
+
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+// filterUsers evaluates the filter against every user of the organization,
+// for the filters the database can't evaluate.
+func (s *SCIMService) filterUsers(ctx context.Context, f filter, query ListQuery) (*ListResponse, error) {
+	records, err := s.getUsers(ctx, userQuery{orgID: query.OrgID})
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := s.getMemberships(ctx, query.OrgID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return listResponse(s.toUsers(records, memberships), f, query)
+}
+
+func (s *SCIMService) toUsers(records []userRecord, memberships []membership) []interface{} {
+	byUser := make(map[int64][]membership)
+	for _, m := range memberships {
+		byUser[m.UserID] = append(byUser[m.UserID], m)
+	}
+
+	resources := make([]interface{}, 0, len(records))
+	for _, record := range records {
+		resources = append(resources, s.toUser(record, byUser[record.ID]))
+	}
+	return resources
+}
+
+// CreateUser provisions the user in the organization. A user that already
+// exists, for example after logging in with OAuth or LDAP, is linked to the
+// organization unless they are already a member of it.
+func (s *SCIMService) CreateUser(ctx context.Context, orgID int64, user *User) (*User, error) {
+	login, email, name, err := userAttributes(user)
+	if err != nil {
+		return nil, err
+	}
+
+	var userID int64
+	err = s.SQLStore.InTransaction(ctx, func(ctx context.Context) error {
+		if err := s.checkExternalID(ctx, orgID, ResourceTypeUser, 0, user.ExternalID); err != nil {
+			return err
+		}
+
+		existing, err := s.getUsersByLoginOrEmail(ctx, login, email)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case len(existing) == 0:
+			created, err := s.SQLStore.CreateUser(ctx, models.CreateUserCommand{
+				Login:        login,
+				Email:        email,
+				Name:         name,
+				IsDisabled:   user.Active != nil && !bool(*user.Active),
+				SkipOrgSetup: true,
+			})
+			if err != nil {
+				if errors.Is(err, models.ErrUserAlreadyExists) {
+					return errUniqueness("a user with the login or email already exists")
+				}
+				return err
+			}
+			userID = created.Id
+		case len(existing) == 1 && !existing[0].IsServiceAccount:
+			isMember, err := s.isOrgUser(ctx, orgID, existing[0].Id)
+			if err != nil {
+				return err
+			}
+			if isMember {
+				return errUniqueness("a user with the login or email already exists")
+			}
+			userID = existing[0].Id
+		default:
+			return errUniqueness("a user with the login or email already exists")
+		}
+
+		if err := s.SQLStore.AddOrgUser(ctx, &models.AddOrgUserCommand{
+			OrgId:  orgID,
+			UserId: userID,
+			Role:   models.RoleType(s.Cfg.SCIMOrgRole),
+		}); err != nil {
+			return err
+		}
+
+		return s.setExternalID(ctx, orgID, ResourceTypeUser, userID, user.ExternalID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Provisioned user", "orgId", orgID, "userId", userID, "login", login)
+	return s.GetUser(ctx, orgID, strconv.FormatInt(userID, 10))
+}
+
+func (s *SCIMService) ReplaceUser(ctx context.Context, orgID int64, id string, user *User) (*User, error) {
+	record, err := s.getUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.updateUser(ctx, orgID, record, user); err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(ctx, orgID, id)
+}
+
+func (s *SCIMService) PatchUser(ctx context.Context, orgID int64, id string, patch PatchRequest) (*User, error) {
+	current, err := s.GetUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var user User
+	if err := patchResource(current, patch, &user); err != nil {
+		return nil, err
+	}
+
+	record, err := s.getUser(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.updateUser(ctx, orgID, record, &user); err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(ctx, orgID, id)
+}
+
+// DeleteUser removes the user from the organization and revokes their
+// sessions, users without any other organization are deleted. Grafana server
+// admins are not deprovisioned.
+func (s *SCIMService) DeleteUser(ctx context.Context, orgID int64, id string) error {
+	record, err := s.getUser(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+	if record.IsAdmin {
+		return newError(http.StatusForbidden, "", "cannot delete a Grafana server admin")
+	}
+
+	cmd := &models.RemoveOrgUserCommand{OrgId: orgID, UserId: record.ID, ShouldDeleteOrphanedUser: true}
+	if err := s.SQLStore.RemoveOrgUser(ctx, cmd); err != nil {
+		if errors.Is(err, models.ErrLastOrgAdmin) {
+			return errInvalidValue("cannot remove the last admin of the organization")
+		}
+		return err
+	}
+
+	// the sessions are revoked once the user is removed, a rejected removal keeps the user logged in
+	if err := s.UserTokenService.RevokeAllUserTokens(ctx, record.ID); err != nil {
+		return err
+	}
+
+	if err := s.setExternalID(ctx, orgID, ResourceTypeUser, record.ID, ""); err != nil {
+		return err
+	}
+
+	s.log.Info("Deprovisioned user", "orgId", orgID, "userId", record.ID, "deleted", cmd.UserWasDeleted)
+	return nil
+}
+
+func (s *SCIMService) GetGroup(ctx context.Context, orgID int64, id string) (*Group, error) {
+	record, err := s.getGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := s.getMemberships(ctx, orgID, record.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toGroup(*record, memberships), nil
+}
+
+func (s *SCIMService) ListGroups(ctx context.Context, query ListQuery) (*ListResponse, error) {
+	f, err := parseListQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := s.getGroups(ctx, query.OrgID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	memberships, err := s.getMemberships(ctx, query.OrgID, 0)
+	if err != nil {
+		return nil, err
+	}
+	byTeam := make(map[int64][]membership)
+	for _, m := range memberships {
+		byTeam[m.TeamID] = append(byTeam[m.TeamID], m)
+	}
+
+	resources := make([]interface{}, 0, len(records))
+	for _, record := range records {
+		resources = append(resources, s.toGroup(record, byTeam[record.ID]))
+	}
+
+	return listResponse(resources, f, query)
+}
+
+func (s *SCIMService) CreateGroup(ctx context.Context, orgID int64, group *Group) (*Group, error) {
+	name := strings.TrimSpace(group.DisplayName)
+	if name == "" {
+		return nil, errInvalidValue("displayName is required")
+	}
+
+	members, err := s.memberIDs(ctx, orgID, group.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkExternalID(ctx, orgID, ResourceTypeGroup, 0, group.ExternalID); err != nil {
+		return nil, err
+	}
+
+	team, err := s.SQLStore.CreateTeam(name, "", orgID)
+	if err != nil {
+		if errors.Is(err, models.ErrTeamNameTaken) {
+			return nil, errUniqueness("a team with the name %s already exists", name)
+		}
+		return nil, err
+	}
+
+	if err := s.setExternalID(ctx, orgID, ResourceTypeGroup, team.Id, group.ExternalID); err != nil {
+		return nil, err
+	}
+
+	if err := s.setMembers(ctx, orgID, team.Id, nil, members); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Provisioned team", "orgId", orgID, "teamId", team.Id, "name", name)
+	return s.GetGroup(ctx, orgID, strconv.FormatInt(team.Id, 10))
+}
+
+func (s *SCIMService) ReplaceGroup(ctx context.Context, orgID int64, id string, group *Group) (*Group, error) {
+	record, err := s.getGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.updateGroup(ctx, orgID, record, group); err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(ctx, orgID, id)
+}
+
+func (s *SCIMService) PatchGroup(ctx context.Context, orgID int64, id string, patch PatchRequest) (*Group, error) {
+	current, err := s.GetGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var group Group
+	if err := patchResource(current, patch, &group); err != nil {
+		return nil, err
+	}
+
+	record, err := s.getGroup(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.updateGroup(ctx, orgID, record, &group); err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(ctx, orgID, id)
+}
+
+func (s *SCIMService) DeleteGroup(ctx context.Context, orgID int64, id string) error {
+	record, err := s.getGroup(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.SQLStore.DeleteTeam(ctx, &models.DeleteTeamCommand{OrgId: orgID, Id: record.ID}); err != nil {
+		return err
+	}
+
+	if err := s.setExternalID(ctx, orgID, ResourceTypeGroup, record.ID, ""); err != nil {
+		return err
+	}
+
+	s.log.Info("Deprovisioned team", "orgId", orgID, "teamId", record.ID)
+	return nil
+}
+
+func (s *SCIMService) getUser(ctx context.Context, orgID int64, id string) (*userRecord, error) {
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, errNotFound(ResourceTypeUser, id)
+	}
+
+	records, err := s.getUsers(ctx, userQuery{orgID: orgID, userID: userID})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errNotFound(ResourceTypeUser, id)
+	}
+	return &records[0], nil
+}
+
+func (s *SCIMService) getGroup(ctx context.Context, orgID int64, id string) (*groupRecord, error) {
+	teamID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, errNotFound(ResourceTypeGroup, id)
+	}
+
+	records, err := s.getGroups(ctx, orgID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errNotFound(ResourceTypeGroup, id)
+	}
+	return &records[0], nil
+}
+
+// updateUser applies the attributes of a replaced or patched user. The
+// login, email, name and active attributes belong to the user rather than
+// to the organization, they can only be changed for users that are members
+// of this organization alone.
+func (s *SCIMService) updateUser(ctx context.Context, orgID int64, record *userRecord, user *User) error {
+	login, email, name, err := userAttributes(user)
+	if err != nil {
+		return err
+	}
+	disabled := record.IsDisabled
+	if user.Active != nil {
+		disabled = !bool(*user.Active)
+	}
+
+	if err := s.checkExternalID(ctx, orgID, ResourceTypeUser, record.ID, user.ExternalID); err != nil {
+		return err
+	}
+
+	profileChanged := login != record.Login || email != record.Email || name != record.Name
+	if profileChanged || disabled != record.IsDisabled {
+		if record.IsAdmin {
+			return newError(http.StatusForbidden, "", "cannot modify a Grafana server admin")
+		}
+		if record.OrgCount > 1 {
+			return newError(http.StatusForbidden, "", "cannot modify a user that is a member of other organizations")
+		}
+	}
+
+	if profileChanged {
+		if err := s.checkUserUnique(ctx, record.ID, login, email); err != nil {
+			return err
+		}
+		if err := s.SQLStore.UpdateUser(ctx, &models.UpdateUserCommand{UserId: record.ID, Login: login, Email: email, Name: name}); err != nil {
+			return err
+		}
+	}
+
+	if disabled != record.IsDisabled {
+		if err := s.SQLStore.DisableUser(ctx, &models.DisableUserCommand{UserId: record.ID, IsDisabled: disabled}); err != nil {
+			return err
+		}
+		if disabled {
+			if err := s.UserTokenService.RevokeAllUserTokens(ctx, record.ID); err != nil {
+				return err
+			}
+			s.log.Info("Deactivated user", "orgId", orgID, "userId", record.ID)
+		}
+	}
+
+	if user.ExternalID != record.ExternalID {
+		return s.setExternalID(ctx, orgID, ResourceTypeUser, record.ID, user.ExternalID)
+	}
+	return nil
+}
+
+func (s *SCIMService) updateGroup(ctx context.Context, orgID int64, record *groupRecord, group *Group) error {
+	name := strings.TrimSpace(group.DisplayName)
+	if name == "" {
+		return errInvalidValue("displayName is required")
+	}
+
+	members, err := s.memberIDs(ctx, orgID, group.Members)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkExternalID(ctx, orgID, ResourceTypeGroup, record.ID, group.ExternalID); err != nil {
+		return err
+	}
+
+	if name != record.Name {
+		if err := s.SQLStore.UpdateTeam(ctx, &models.UpdateTeamCommand{OrgId: orgID, Id: record.ID, Name: name, Email: record.Email}); err != nil {
+			if errors.Is(err, models.ErrTeamNameTaken) {
+				return errUniqueness("a team with the name %s already exists", name)
+			}
+			return err
+		}
+	}
+
+	if group.ExternalID != record.ExternalID {
+		if err := s.setExternalID(ctx, orgID, ResourceTypeGroup, record.ID, group.ExternalID); err != nil {
+			return err
+		}
+	}
+
+	memberships, err := s.getMemberships(ctx, orgID, record.ID)
+	if err != nil {
+		return err
+	}
+	current := make([]int64, 0, len(memberships))
+	for _, m := range memberships {
+		current = append(current, m.UserID)
+	}
+
+	return s.setMembers(ctx, orgID, record.ID, current, members)
+}
+
+// setMembers adds and removes team members so that the members of the team
+// are the desired users. Members are managed through the team permissions
+// so that the managed permissions of the members stay in sync.
+func (s *SCIMService) setMembers(ctx context.Context, orgID, teamID int64, current, desired []int64) error {
+	teamIDString := strconv.FormatInt(teamID, 10)
+
+	isCurrent := make(map[int64]bool, len(current))
+	for _, id := range current {
+		isCurrent[id] = true
+	}
+	isDesired := make(map[int64]bool, len(desired))
+	for _, id := range desired {
+		isDesired[id] = true
+	}
+
+	for _, id := range desired {
+		if isCurrent[id] {
+			continue
+		}
+		if _, err := s.TeamPermissionsService.SetUserPermission(ctx, orgID, accesscontrol.User{ID: id}, teamIDString, memberPermission); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range current {
+		if isDesired[id] {
+			continue
+		}
+		if _, err := s.TeamPermissionsService.SetUserPermission(ctx, orgID, accesscontrol.User{ID: id}, teamIDString, ""); err != nil {
+			if errors.Is(err, models.ErrTeamMemberExternal) {
+				return errInvalidValue("member %d is synced from an external group and cannot be removed", id)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// memberIDs returns the user ids of the members, members must be users of
+// the organization.
+func (s *SCIMService) memberIDs(ctx context.Context, orgID int64, members []MultiValue) ([]int64, error) {
+	result := make([]int64, 0, len(members))
+	seen := make(map[int64]bool, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseInt(member.Value, 10, 64)
+		if err != nil {
+			return nil, errInvalidValue("invalid member %q", member.Value)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		exists, err := s.isOrgUser(ctx, orgID, id)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, errInvalidValue("member %s is not a user of the organization", member.Value)
+		}
+		result = append(result, id)
+	}
+	return result, nil
+}
+
+// checkExternalID fails if another resource of the organization has the
+// external id.
+func (s *SCIMService) checkExternalID(ctx context.Context, orgID int64, resourceType string, resourceID int64, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	id, err := s.getResourceByExternalID(ctx, orgID, resourceType, value)
+	if err != nil {
+		return err
+	}
+	if id != 0 && id != resourceID {
+		return errUniqueness("a %s with the externalId %s already exists", strings.ToLower(resourceType), value)
+	}
+	return nil
+}
+
+// checkUserUnique fails if another user has the login or the email.
+func (s *SCIMService) checkUserUnique(ctx context.Context, userID int64, login, email string) error {
+	for _, loginOrEmail := range []string{login, email} {
+		query := &models.GetUserByLoginQuery{LoginOrEmail: loginOrEmail}
+		if err := s.SQLStore.GetUserByLogin(ctx, query); err != nil {
+			if errors.Is(err, models.ErrUserNotFound) {
+				continue
+			}
+			return err
+		}
+		if query.Result.Id != userID {
+			return errUniqueness("a user with the login or email %s already exists", loginOrEmail)
+		}
+	}
+	return nil
+}
+
+// userAttributes returns the login, email and name of a user resource. The
+// email is the primary email, or the first one, and falls back to the login
+// as for other users created without email.
+func userAttributes(user *User) (string, string, string, error) {
+	login := strings.TrimSpace(user.UserName)
+	if login == "" {
+		return "", "", "", errInvalidValue("userName is required")
+	}
+
+	email := ""
+	for _, e := range user.Emails {
+		if email == "" || e.Primary {
+			email = strings.TrimSpace(e.Value)
+		}
+		if e.Primary {
+			break
+		}
+	}
+	if email == "" {
+		email = login
+	}
+
+	name := strings.TrimSpace(user.DisplayName)
+	if name == "" && user.Name != nil {
+		name = strings.TrimSpace(user.Name.Formatted)
+		if name == "" {
+			name = strings.TrimSpace(user.Name.GivenName + " " + user.Name.FamilyName)
+		}
+	}
+
+	return login, email, name, nil
+}
+
+func (s *SCIMService) toUser(record userRecord, memberships []membership) *User {
+	id := strconv.FormatInt(record.ID, 10)
+	active := Boolean(!record.IsDisabled)
+
+	user := &User{
+		Schemas:     []string{SchemaUser},
+		ID:          id,
+		ExternalID:  record.ExternalID,
+		UserName:    record.Login,
+		DisplayName: record.Name,
+		Active:      &active,
+		Meta:        s.meta(ResourceTypeUser, "Users/"+id, record.Created, record.Updated),
+	}
+	if record.Name != "" {
+		user.Name = &Name{Formatted: record.Name}
+	}
+	if record.Email != "" {
+		user.Emails = []MultiValue{{Value: record.Email, Type: "work", Primary: true}}
+	}
+	for _, m := range memberships {
+		teamID := strconv.FormatInt(m.TeamID, 10)
+		user.Groups = append(user.Groups, MultiValue{Value: teamID, Display: m.TeamName, Ref: s.location("Groups/" + teamID)})
+	}
+
+	return user
+}
+
+func (s *SCIMService) toGroup(record groupRecord, memberships []membership) *Group {
+	id := strconv.FormatInt(record.ID, 10)
+
+	group := &Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          id,
+		ExternalID:  record.ExternalID,
+		DisplayName: record.Name,
+		Meta:        s.meta(ResourceTypeGroup, "Groups/"+id, record.Created, record.Updated),
+	}
+	for _, m := range memberships {
+		userID := strconv.FormatInt(m.UserID, 10)
+		group.Members = append(group.Members, MultiValue{Value: userID, Display: m.Login, Ref: s.location("Users/" + userID)})
+	}
+
+	return group
+}
+
+func (s *SCIMService) meta(resourceType, path string, created, updated time.Time) *Meta {
+	return &Meta{
+		ResourceType: resourceType,
+		Created:      &created,
+		LastModified: &updated,
+		Location:     s.location(path),
+	}
+}
+
+func (s *SCIMService) location(path string) string {
+	return strings.TrimSuffix(s.Cfg.AppURL, "/") + "/api/scim/v2/" + path
+}
+
+// patchResource applies a PATCH request to the JSON representation of a
+// resource and decodes the result into target.
+func patchResource(resource interface{}, patch PatchRequest, target interface{}) error {
+	if len(patch.Operations) == 0 {
+		return errInvalidValue("operations are required")
+	}
+
+	doc, err := toDocument(resource)
+	if err != nil {
+		return err
+	}
+
+	if err := applyPatch(doc, patch.Operations); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return errInvalidValue("invalid value: %s", err)
+	}
+	return nil
+}
+
+func parseListQuery(query ListQuery) (filter, error) {
+	if strings.TrimSpace(query.Filter) == "" {
+		return nil, nil
+	}
+	return parseFilter(query.Filter)
+}
+
+// listResponse filters the resources and returns the requested page,
+// startIndex is 1-based.
+func listResponse(resources []interface{}, f filter, query ListQuery) (*ListResponse, error) {
+	matching := resources
+	if f != nil {
+		matching = make([]interface{}, 0, len(resources))
+		for _, resource := range resources {
+			doc, err := toDocument(resource)
+			if err != nil {
+				return nil, err
+			}
+			if f.match(doc) {
+				matching = append(matching, resource)
+			}
+		}
+	}
+
+	startIndex, count := listPage(query)
+	page := make([]interface{}, 0)
+	if startIndex <= len(matching) {
+		end := startIndex - 1 + count
+		if end > len(matching) {
+			end = len(matching)
+		}
+		page = matching[startIndex-1 : end]
+	}
+
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(matching),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}, nil
+}
+
+// listPage returns the 1-based index of the first resource of the page, and
+// the number of resources of the page.
+func listPage(query ListQuery) (int, int) {
+	startIndex := query.StartIndex
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count := query.Count
+	if count < 0 {
+		count = 0
+	}
+	if count > maxCount {
+		count = maxCount
+	}
+	return startIndex, count
+}
+
+func toDocument(resource interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	err = json.Unmarshal(data, &doc)
+	return doc, err
+}
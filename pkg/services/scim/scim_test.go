@@ -0,0 +1,389 @@
+//go:build integration
+// +build integration
+
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/database"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/ossaccesscontrol"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const testOrgID = int64(1)
+
+func setupTestService(t *testing.T) (*SCIMService, *auth.FakeUserAuthTokenService) {
+	t.Helper()
+
+	cfg := setting.NewCfg()
+	cfg.AppURL = "http://localhost:3000/"
+	cfg.SCIMOrgRole = string(models.ROLE_VIEWER)
+
+	sqlStore := sqlstore.InitTestDB(t)
+	teamPermissionsService, err := ossaccesscontrol.ProvideTeamPermissions(cfg, routing.NewRouteRegister(), sqlStore, accesscontrolmock.New(), database.ProvideService(sqlStore))
+	require.NoError(t, err)
+
+	// the organization needs an admin that is not provisioned
+	_, err = sqlStore.CreateUser(context.Background(), models.CreateUserCommand{Login: "admin", OrgId: testOrgID})
+	require.NoError(t, err)
+
+	tokens := auth.NewFakeUserAuthTokenService()
+	return &SCIMService{
+		Cfg:                    cfg,
+		SQLStore:               sqlStore,
+		TeamPermissionsService: teamPermissionsService,
+		UserTokenService:       tokens,
+		log:                    log.New("scim.test"),
+	}, tokens
+}
+
+func newTestUser(userName, email string) *User {
+	return &User{
+		Schemas:    []string{SchemaUser},
+		UserName:   userName,
+		ExternalID: "ext-" + userName,
+		Name:       &Name{GivenName: "Test", FamilyName: userName},
+		Emails:     []MultiValue{{Value: "other@example.com"}, {Value: email, Primary: true}},
+	}
+}
+
+func patch(t *testing.T, operations ...string) PatchRequest {
+	t.Helper()
+
+	request := PatchRequest{Schemas: []string{SchemaPatchOp}}
+	for _, operation := range operations {
+		var op PatchOperation
+		require.NoError(t, json.Unmarshal([]byte(operation), &op))
+		request.Operations = append(request.Operations, op)
+	}
+	return request
+}
+
+func requireSCIMError(t *testing.T, err error, status int, scimType string) {
+	t.Helper()
+
+	var scimErr *Error
+	require.ErrorAs(t, err, &scimErr)
+	assert.Equal(t, strconv.Itoa(status), scimErr.Status)
+	assert.Equal(t, scimType, scimErr.ScimType)
+}
+
+func TestSCIMUsers(t *testing.T) {
+	ctx := context.Background()
+	s, tokens := setupTestService(t)
+
+	user, err := s.CreateUser(ctx, testOrgID, newTestUser("alice", "alice@example.com"))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.UserName)
+	assert.Equal(t, "Test alice", user.DisplayName)
+	assert.Equal(t, "ext-alice", user.ExternalID)
+	assert.Equal(t, "alice@example.com", user.Emails[0].Value)
+	assert.True(t, bool(*user.Active))
+	assert.Equal(t, "http://localhost:3000/api/scim/v2/Users/"+user.ID, user.Meta.Location)
+
+	t.Run("should add the user to the organization with the configured role", func(t *testing.T) {
+		query := &models.GetSignedInUserQuery{Login: "alice", OrgId: testOrgID}
+		require.NoError(t, s.SQLStore.GetSignedInUser(ctx, query))
+		assert.Equal(t, models.ROLE_VIEWER, query.Result.OrgRole)
+	})
+
+	t.Run("should reject duplicated users", func(t *testing.T) {
+		_, err := s.CreateUser(ctx, testOrgID, newTestUser("alice", "alice2@example.com"))
+		requireSCIMError(t, err, http.StatusConflict, "uniqueness")
+
+		other := newTestUser("alice2", "alice2@example.com")
+		other.ExternalID = "ext-alice"
+		_, err = s.CreateUser(ctx, testOrgID, other)
+		requireSCIMError(t, err, http.StatusConflict, "uniqueness")
+
+		_, err = s.CreateUser(ctx, testOrgID, &User{})
+		requireSCIMError(t, err, http.StatusBadRequest, "invalidValue")
+	})
+
+	t.Run("should link existing users that are not members of the organization", func(t *testing.T) {
+		existing, err := s.SQLStore.CreateUser(ctx, models.CreateUserCommand{Login: "oauth-user", Email: "oauth-user@example.com", SkipOrgSetup: true})
+		require.NoError(t, err)
+
+		linked, err := s.CreateUser(ctx, testOrgID, newTestUser("oauth-user", "oauth-user@example.com"))
+		require.NoError(t, err)
+		assert.Equal(t, strconv.FormatInt(existing.Id, 10), linked.ID)
+		assert.Equal(t, "ext-oauth-user", linked.ExternalID)
+
+		query := &models.GetSignedInUserQuery{UserId: existing.Id, OrgId: testOrgID}
+		require.NoError(t, s.SQLStore.GetSignedInUser(ctx, query))
+		assert.Equal(t, models.ROLE_VIEWER, query.Result.OrgRole)
+
+		_, err = s.CreateUser(ctx, testOrgID, newTestUser("oauth-user", "oauth-user@example.com"))
+		requireSCIMError(t, err, http.StatusConflict, "uniqueness")
+
+		require.NoError(t, s.DeleteUser(ctx, testOrgID, linked.ID))
+	})
+
+	t.Run("should not leave a user behind when the provisioning fails", func(t *testing.T) {
+		_, err := s.CreateUser(ctx, 404, newTestUser("orphan", "orphan@example.com"))
+		require.ErrorIs(t, err, models.ErrOrgNotFound)
+
+		err = s.SQLStore.GetUserByLogin(ctx, &models.GetUserByLoginQuery{LoginOrEmail: "orphan"})
+		require.ErrorIs(t, err, models.ErrUserNotFound)
+	})
+
+	t.Run("should list users with a filter", func(t *testing.T) {
+		_, err := s.CreateUser(ctx, testOrgID, newTestUser("bob", "bob@example.com"))
+		require.NoError(t, err)
+
+		result, err := s.ListUsers(ctx, ListQuery{OrgID: testOrgID, Filter: `userName eq "ALICE"`, Count: defaultCount})
+		require.NoError(t, err)
+		require.Equal(t, 1, result.TotalResults)
+		assert.Equal(t, user.ID, result.Resources[0].(*User).ID)
+
+		result, err = s.ListUsers(ctx, ListQuery{OrgID: testOrgID, Filter: `externalId eq "ext-bob" or emails[value ew "alice@example.com"]`, Count: defaultCount})
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.TotalResults)
+
+		result, err = s.ListUsers(ctx, ListQuery{OrgID: testOrgID, StartIndex: 2, Count: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.TotalResults)
+		assert.Equal(t, 2, result.StartIndex)
+		require.Len(t, result.Resources, 1)
+		assert.Equal(t, user.ID, result.Resources[0].(*User).ID)
+
+		// filters evaluated by the database are paged by the database
+		result, err = s.ListUsers(ctx, ListQuery{OrgID: testOrgID, Filter: `userName eq "alice" or externalId eq "ext-bob"`, StartIndex: 2, Count: 1})
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.TotalResults)
+		require.Len(t, result.Resources, 1)
+		assert.Equal(t, "bob", result.Resources[0].(*User).UserName)
+
+		result, err = s.ListUsers(ctx, ListQuery{OrgID: testOrgID, Filter: `externalId eq "EXT-BOB"`, Count: defaultCount})
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.TotalResults)
+
+		result, err = s.ListUsers(ctx, ListQuery{OrgID: testOrgID, StartIndex: 10, Count: defaultCount})
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.TotalResults)
+		assert.Empty(t, result.Resources)
+
+		result, err = s.ListUsers(ctx, ListQuery{OrgID: testOrgID, Count: 0})
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.TotalResults)
+		assert.Empty(t, result.Resources)
+
+		_, err = s.ListUsers(ctx, ListQuery{OrgID: testOrgID, Filter: `userName is "alice"`})
+		requireSCIMError(t, err, http.StatusBadRequest, "invalidFilter")
+
+		result, err = s.ListUsers(ctx, ListQuery{OrgID: 2, Count: defaultCount})
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.TotalResults)
+	})
+
+	t.Run("should patch and replace users", func(t *testing.T) {
+		patched, err := s.PatchUser(ctx, testOrgID, user.ID, patch(t,
+			`{"op": "Replace", "path": "emails[type eq \"work\"].value", "value": "alice@grafana.com"}`,
+			`{"op": "replace", "value": {"displayName": "Alice", "externalId": "ext-alice-2"}}`,
+		))
+		require.NoError(t, err)
+		assert.Equal(t, "alice@grafana.com", patched.Emails[0].Value)
+		assert.Equal(t, "Alice", patched.DisplayName)
+		assert.Equal(t, "ext-alice-2", patched.ExternalID)
+
+		replaced, err := s.ReplaceUser(ctx, testOrgID, user.ID, &User{UserName: "alice.smith", Emails: []MultiValue{{Value: "alice@grafana.com"}}})
+		require.NoError(t, err)
+		assert.Equal(t, "alice.smith", replaced.UserName)
+		assert.Empty(t, replaced.ExternalID)
+
+		_, err = s.ReplaceUser(ctx, testOrgID, user.ID, &User{UserName: "bob"})
+		requireSCIMError(t, err, http.StatusConflict, "uniqueness")
+
+		_, err = s.PatchUser(ctx, testOrgID, "1000", patch(t, `{"op": "replace", "path": "active", "value": false}`))
+		requireSCIMError(t, err, http.StatusNotFound, "")
+	})
+
+	t.Run("should disable users and revoke their sessions", func(t *testing.T) {
+		var revoked int64
+		tokens.RevokeAllUserTokensProvider = func(ctx context.Context, userID int64) error {
+			revoked = userID
+			return nil
+		}
+
+		patched, err := s.PatchUser(ctx, testOrgID, user.ID, patch(t, `{"op": "replace", "path": "active", "value": "False"}`))
+		require.NoError(t, err)
+		assert.False(t, bool(*patched.Active))
+		assert.Equal(t, user.ID, strconv.FormatInt(revoked, 10))
+
+		query := &models.GetUserByIdQuery{Id: revoked}
+		require.NoError(t, s.SQLStore.GetUserById(ctx, query))
+		assert.True(t, query.Result.IsDisabled)
+
+		patched, err = s.PatchUser(ctx, testOrgID, user.ID, patch(t, `{"op": "replace", "value": {"active": true}}`))
+		require.NoError(t, err)
+		assert.True(t, bool(*patched.Active))
+	})
+
+	t.Run("should not modify users of other organizations", func(t *testing.T) {
+		shared, err := s.CreateUser(ctx, testOrgID, newTestUser("shared", "shared@example.com"))
+		require.NoError(t, err)
+		id, err := strconv.ParseInt(shared.ID, 10, 64)
+		require.NoError(t, err)
+
+		org, err := s.SQLStore.CreateOrgWithMember("other", 1)
+		require.NoError(t, err)
+		require.NoError(t, s.SQLStore.AddOrgUser(ctx, &models.AddOrgUserCommand{OrgId: org.Id, UserId: id, Role: models.ROLE_VIEWER}))
+
+		_, err = s.PatchUser(ctx, testOrgID, shared.ID, patch(t, `{"op": "replace", "path": "active", "value": false}`))
+		requireSCIMError(t, err, http.StatusForbidden, "")
+
+		// deleting only removes the user from the organization
+		require.NoError(t, s.DeleteUser(ctx, testOrgID, shared.ID))
+		_, err = s.GetUser(ctx, testOrgID, shared.ID)
+		requireSCIMError(t, err, http.StatusNotFound, "")
+		_, err = s.GetUser(ctx, org.Id, shared.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("should not delete server admins", func(t *testing.T) {
+		admin, err := s.CreateUser(ctx, testOrgID, newTestUser("server-admin", "server-admin@example.com"))
+		require.NoError(t, err)
+		id, err := strconv.ParseInt(admin.ID, 10, 64)
+		require.NoError(t, err)
+		require.NoError(t, s.SQLStore.UpdateUserPermissions(id, true))
+
+		err = s.DeleteUser(ctx, testOrgID, admin.ID)
+		requireSCIMError(t, err, http.StatusForbidden, "")
+		_, err = s.GetUser(ctx, testOrgID, admin.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("should not revoke the sessions of the last admin of an organization", func(t *testing.T) {
+		orgAdmin, err := s.CreateUser(ctx, testOrgID, newTestUser("org-admin", "org-admin@example.com"))
+		require.NoError(t, err)
+		id, err := strconv.ParseInt(orgAdmin.ID, 10, 64)
+		require.NoError(t, err)
+		org, err := s.SQLStore.CreateOrgWithMember("admins", id)
+		require.NoError(t, err)
+
+		revoked := false
+		tokens.RevokeAllUserTokensProvider = func(ctx context.Context, userID int64) error {
+			revoked = true
+			return nil
+		}
+
+		err = s.DeleteUser(ctx, org.Id, orgAdmin.ID)
+		requireSCIMError(t, err, http.StatusBadRequest, "invalidValue")
+		assert.False(t, revoked)
+		_, err = s.GetUser(ctx, org.Id, orgAdmin.ID)
+		require.NoError(t, err)
+	})
+
+	t.Run("should delete users and revoke their sessions", func(t *testing.T) {
+		var revoked int64
+		tokens.RevokeAllUserTokensProvider = func(ctx context.Context, userID int64) error {
+			revoked = userID
+			return nil
+		}
+
+		require.NoError(t, s.DeleteUser(ctx, testOrgID, user.ID))
+		assert.Equal(t, user.ID, strconv.FormatInt(revoked, 10))
+
+		_, err := s.GetUser(ctx, testOrgID, user.ID)
+		requireSCIMError(t, err, http.StatusNotFound, "")
+
+		id, err := strconv.ParseInt(user.ID, 10, 64)
+		require.NoError(t, err)
+		err = s.SQLStore.GetUserById(ctx, &models.GetUserByIdQuery{Id: id})
+		require.ErrorIs(t, err, models.ErrUserNotFound)
+	})
+}
+
+func TestSCIMGroups(t *testing.T) {
+	ctx := context.Background()
+	s, _ := setupTestService(t)
+
+	alice, err := s.CreateUser(ctx, testOrgID, newTestUser("alice", "alice@example.com"))
+	require.NoError(t, err)
+	bob, err := s.CreateUser(ctx, testOrgID, newTestUser("bob", "bob@example.com"))
+	require.NoError(t, err)
+
+	group, err := s.CreateGroup(ctx, testOrgID, &Group{
+		DisplayName: "Engineering",
+		ExternalID:  "ext-engineering",
+		Members:     []MultiValue{{Value: alice.ID}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Engineering", group.DisplayName)
+	require.Len(t, group.Members, 1)
+	assert.Equal(t, "alice", group.Members[0].Display)
+
+	t.Run("should validate groups", func(t *testing.T) {
+		_, err := s.CreateGroup(ctx, testOrgID, &Group{DisplayName: "Engineering"})
+		requireSCIMError(t, err, http.StatusConflict, "uniqueness")
+
+		_, err = s.CreateGroup(ctx, testOrgID, &Group{DisplayName: "Sales", Members: []MultiValue{{Value: "1000"}}})
+		requireSCIMError(t, err, http.StatusBadRequest, "invalidValue")
+	})
+
+	t.Run("should return the groups of users", func(t *testing.T) {
+		user, err := s.GetUser(ctx, testOrgID, alice.ID)
+		require.NoError(t, err)
+		require.Len(t, user.Groups, 1)
+		assert.Equal(t, group.ID, user.Groups[0].Value)
+		assert.Equal(t, "Engineering", user.Groups[0].Display)
+	})
+
+	t.Run("should patch members", func(t *testing.T) {
+		patched, err := s.PatchGroup(ctx, testOrgID, group.ID, patch(t,
+			`{"op": "add", "path": "members", "value": [{"value": "`+bob.ID+`"}]}`,
+			`{"op": "remove", "path": "members[value eq \"`+alice.ID+`\"]"}`,
+			`{"op": "replace", "path": "displayName", "value": "Platform"}`,
+		))
+		require.NoError(t, err)
+		assert.Equal(t, "Platform", patched.DisplayName)
+		require.Len(t, patched.Members, 1)
+		assert.Equal(t, bob.ID, patched.Members[0].Value)
+
+		teamID, err := strconv.ParseInt(group.ID, 10, 64)
+		require.NoError(t, err)
+		query := &models.GetTeamMembersQuery{OrgId: testOrgID, TeamId: teamID}
+		require.NoError(t, s.SQLStore.GetTeamMembers(ctx, query))
+		require.Len(t, query.Result, 1)
+		assert.Equal(t, "bob", query.Result[0].Login)
+	})
+
+	t.Run("should list groups with a filter", func(t *testing.T) {
+		result, err := s.ListGroups(ctx, ListQuery{OrgID: testOrgID, Filter: `displayName eq "platform"`, Count: defaultCount})
+		require.NoError(t, err)
+		require.Equal(t, 1, result.TotalResults)
+		assert.Equal(t, group.ID, result.Resources[0].(*Group).ID)
+
+		result, err = s.ListGroups(ctx, ListQuery{OrgID: testOrgID, Filter: `members[value eq "` + alice.ID + `"]`, Count: defaultCount})
+		require.NoError(t, err)
+		assert.Equal(t, 0, result.TotalResults)
+	})
+
+	t.Run("should replace groups", func(t *testing.T) {
+		replaced, err := s.ReplaceGroup(ctx, testOrgID, group.ID, &Group{DisplayName: "Platform", Members: []MultiValue{{Value: alice.ID}, {Value: bob.ID}}})
+		require.NoError(t, err)
+		assert.Len(t, replaced.Members, 2)
+		assert.Empty(t, replaced.ExternalID)
+	})
+
+	t.Run("should delete groups", func(t *testing.T) {
+		require.NoError(t, s.DeleteGroup(ctx, testOrgID, group.ID))
+
+		_, err := s.GetGroup(ctx, testOrgID, group.ID)
+		requireSCIMError(t, err, http.StatusNotFound, "")
+	})
+}
@@ -53,7 +53,7 @@ func initBlugeIndex(dashboards []dashboard, logger log.Logger) (*bluge.Reader, e
 	batch := bluge.NewBatch()
 
 	// First index the folders
-	folderIdLookup := make(map[int64]string, 50)
+	folderIdLookup := folderPathLookup(dashboards)
 	for _, dashboard := range dashboards {
 		if !dashboard.isFolder {
 			continue
@@ -75,9 +75,12 @@ func initBlugeIndex(dashboards []dashboard, logger log.Logger) (*bluge.Reader, e
 			AddField(bluge.NewTextField(documentFieldName, dashboard.info.Title).StoreValue().SearchTermPositions()).
 			AddField(bluge.NewTextField(documentFieldDescription, dashboard.info.Description).SearchTermPositions())
 
-		batch.Insert(doc)
+		// nested folders are located in their parents, root folders have no location
+		if location, ok := folderIdLookup[dashboard.folderID]; ok && dashboard.folderID > 0 {
+			doc.AddField(bluge.NewKeywordField(documentFieldLocation, location).Aggregatable().StoreValue())
+		}
 
-		folderIdLookup[dashboard.id] = uid
+		batch.Insert(doc)
 	}
 
 	// Then each dashboard
@@ -87,8 +90,7 @@ func initBlugeIndex(dashboards []dashboard, logger log.Logger) (*bluge.Reader, e
 		}
 
 		url := fmt.Sprintf("/d/%s/%s", dashboard.uid, dashboard.slug)
-		folderUID := folderIdLookup[dashboard.folderID]
-		location := folderUID
+		location := folderIdLookup[dashboard.folderID]
 
 		// Dashboard document
 		doc := bluge.NewDocument(dashboard.uid).
@@ -167,11 +169,46 @@ func initBlugeIndex(dashboards []dashboard, logger log.Logger) (*bluge.Reader, e
 	return reader, err
 }
 
+// folderPathLookup returns the paths of the folders, made of the uids of their parents and their own uid separated by a slash
+func folderPathLookup(dashboards []dashboard) map[int64]string {
+	folders := make(map[int64]dashboard, 50)
+	for _, dashboard := range dashboards {
+		if dashboard.isFolder {
+			folders[dashboard.id] = dashboard
+		}
+	}
+
+	paths := make(map[int64]string, len(folders))
+	var lookup func(id int64, depth int) string
+	lookup = func(id int64, depth int) string {
+		if path, ok := paths[id]; ok {
+			return path
+		}
+		folder := folders[id]
+		uid := folder.uid
+		if uid == "" {
+			uid = "general"
+		}
+		path := uid
+		// the depth guards against cycles
+		if _, ok := folders[folder.folderID]; ok && folder.folderID > 0 && folder.folderID != id && depth < len(folders) {
+			path = lookup(folder.folderID, depth+1) + "/" + uid
+		}
+		paths[id] = path
+		return path
+	}
+	for id := range folders {
+		lookup(id, 0)
+	}
+	return paths
+}
+
 //nolint: gocyclo
 func doBlugeQuery(ctx context.Context, s *StandardSearchService, reader *bluge.Reader, filter ResourceFilter, q DashboardQuery) *backend.DataResponse {
 	response := &backend.DataResponse{}
 
 	// Folder listing structure
+	listRoot := false
 	idx := strings.Index(q.Query, ":")
 	if idx > 0 {
 		key := q.Query[0:idx]
@@ -185,9 +222,10 @@ func doBlugeQuery(ctx context.Context, s *StandardSearchService, reader *bluge.R
 			q.Facet = nil
 			if val == "root" || val == "" {
 				q.Kind = []string{string(entityKindFolder)}
+				listRoot = true
 			} else {
 				q.Location = val
-				q.Kind = []string{string(entityKindDashboard)}
+				q.Kind = []string{string(entityKindDashboard), string(entityKindFolder)}
 			}
 		}
 	}
@@ -244,10 +282,20 @@ func doBlugeQuery(ctx context.Context, s *StandardSearchService, reader *bluge.R
 
 	// Folder
 	if q.Location != "" {
-		fullQuery.AddMust(bluge.NewTermQuery(q.Location).SetField(documentFieldLocation))
+		bq := bluge.NewBooleanQuery().AddShould(bluge.NewTermQuery(q.Location).SetField(documentFieldLocation))
+		if !strings.Contains(q.Location, "/") {
+			// the location of the content of nested folders ends with the uid of the folder
+			bq.AddShould(bluge.NewWildcardQuery("*/" + q.Location).SetField(documentFieldLocation))
+		}
+		fullQuery.AddMust(bq)
 		hasConstraints = true
 	}
 
+	// Nested folders are listed in their parents
+	if listRoot {
+		fullQuery.AddMustNot(bluge.NewWildcardQuery("*").SetField(documentFieldLocation))
+	}
+
 	if q.Query == "*" || q.Query == "" {
 		if !hasConstraints {
 			fullQuery.AddShould(bluge.NewMatchAllQuery())
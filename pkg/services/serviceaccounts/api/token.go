@@ -2,27 +2,42 @@ package api
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/components/apikeygen"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
 	"github.com/grafana/grafana/pkg/services/serviceaccounts"
+	"github.com/grafana/grafana/pkg/services/serviceaccounts/database"
 	"github.com/grafana/grafana/pkg/web"
 )
 
 const failedToDeleteMsg = "Failed to delete API key"
 
+// defaultGracePeriod is how long a rotated token stays valid when no grace period is given
+const defaultGracePeriod = 24 * time.Hour
+
+// rotatedSuffix is appended to the name of the token replacing a rotated one
+const rotatedSuffix = " (rotated "
+
 type TokenDTO struct {
-	Id                     int64      `json:"id"`
-	Name                   string     `json:"name"`
-	Created                *time.Time `json:"created"`
-	Expiration             *time.Time `json:"expiration"`
-	SecondsUntilExpiration *float64   `json:"secondsUntilExpiration"`
-	HasExpired             bool       `json:"hasExpired"`
+	Id                     int64                     `json:"id"`
+	Name                   string                    `json:"name"`
+	Created                *time.Time                `json:"created"`
+	Expiration             *time.Time                `json:"expiration"`
+	SecondsUntilExpiration *float64                  `json:"secondsUntilExpiration"`
+	HasExpired             bool                      `json:"hasExpired"`
+	Permissions            []models.ApiKeyPermission `json:"permissions,omitempty"`
+	IPAllowList            []string                  `json:"ipAllowList,omitempty"`
+	LastUsedAt             *time.Time                `json:"lastUsedAt"`
+	LastUsedIP             string                    `json:"lastUsedIp,omitempty"`
 }
 
 func hasExpired(expiration *int64) bool {
@@ -63,6 +78,10 @@ func (api *ServiceAccountsAPI) ListTokens(ctx *models.ReqContext) response.Respo
 				Expiration:             expiration,
 				SecondsUntilExpiration: &secondsUntilExpiration,
 				HasExpired:             isExpired,
+				Permissions:            t.Permissions,
+				IPAllowList:            t.IPAllowList,
+				LastUsedAt:             t.LastUsedAt,
+				LastUsedIP:             t.LastUsedIP,
 			}
 		}
 
@@ -97,13 +116,18 @@ func (api *ServiceAccountsAPI) CreateToken(c *models.ReqContext) response.Respon
 	// Force affected service account to be the one referenced in the URL
 	cmd.OrgId = c.OrgId
 
-	if api.cfg.ApiKeyMaxSecondsToLive != -1 {
-		if cmd.SecondsToLive == 0 {
-			return response.Error(http.StatusBadRequest, "Number of seconds before expiration should be set", nil)
-		}
-		if cmd.SecondsToLive > api.cfg.ApiKeyMaxSecondsToLive {
-			return response.Error(http.StatusBadRequest, "Number of seconds before expiration is greater than the global limit", nil)
-		}
+	if resp := api.validateSecondsToLive(cmd.SecondsToLive); resp != nil {
+		return resp
+	}
+
+	if len(cmd.Permissions) > 0 && api.accesscontrol.IsDisabled() {
+		return response.Error(http.StatusBadRequest, "Tokens with permissions require access control to be enabled", nil)
+	}
+	if err := validatePermissions(cmd.Permissions); err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), nil)
+	}
+	if err := validateIPAllowList(cmd.IPAllowList); err != nil {
+		return response.Error(http.StatusBadRequest, err.Error(), nil)
 	}
 
 	newKeyInfo, err := apikeygen.New(cmd.OrgId, cmd.Name)
@@ -167,3 +191,129 @@ func (api *ServiceAccountsAPI) DeleteToken(c *models.ReqContext) response.Respon
 
 	return response.Success("API key deleted")
 }
+
+// RotateToken adds a token replacing the given one, which stays valid for a grace period
+func (api *ServiceAccountsAPI) RotateToken(c *models.ReqContext) response.Response {
+	saID, err := strconv.ParseInt(web.Params(c.Req)[":serviceAccountId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Service Account ID is invalid", err)
+	}
+
+	tokenID, err := strconv.ParseInt(web.Params(c.Req)[":tokenId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "Token ID is invalid", err)
+	}
+
+	form := serviceaccounts.RotateServiceAccountTokenForm{}
+	if err := web.Bind(c.Req, &form); err != nil {
+		return response.Error(http.StatusBadRequest, "Bad request data", err)
+	}
+
+	if resp := api.validateSecondsToLive(form.SecondsToLive); resp != nil {
+		return resp
+	}
+
+	gracePeriod := defaultGracePeriod
+	if form.GracePeriodSeconds != nil {
+		if *form.GracePeriodSeconds < 0 {
+			return response.Error(http.StatusBadRequest, "Grace period should not be negative", nil)
+		}
+		gracePeriod = time.Duration(*form.GracePeriodSeconds) * time.Second
+	}
+
+	tokens, err := api.store.ListTokens(c.Req.Context(), c.OrgId, saID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to retrieve tokens", err)
+	}
+
+	var token *models.ApiKey
+	for _, t := range tokens {
+		if t.Id == tokenID {
+			token = t
+		}
+	}
+	if token == nil {
+		return response.Error(http.StatusNotFound, "Failed to rotate API key", models.ErrApiKeyNotFound)
+	}
+
+	cmd := serviceaccounts.AddServiceAccountTokenCommand{
+		Name:          form.Name,
+		OrgId:         c.OrgId,
+		SecondsToLive: form.SecondsToLive,
+	}
+	if cmd.Name == "" {
+		cmd.Name = rotatedName(token.Name, time.Now())
+	}
+
+	newKeyInfo, err := apikeygen.New(cmd.OrgId, cmd.Name)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Generating API key failed", err)
+	}
+	cmd.Key = newKeyInfo.HashedKey
+
+	if err := api.store.RotateServiceAccountToken(c.Req.Context(), saID, tokenID, gracePeriod, &cmd); err != nil {
+		var missingErr *database.ErrMissingSAToken
+		switch {
+		case errors.As(err, &missingErr), errors.Is(err, models.ErrApiKeyNotFound):
+			return response.Error(http.StatusNotFound, "Failed to rotate API key", err)
+		case errors.Is(err, models.ErrInvalidApiKeyExpiration):
+			return response.Error(http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, models.ErrDuplicateApiKey):
+			return response.Error(http.StatusConflict, err.Error(), nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to rotate API key", err)
+	}
+
+	result := &dtos.NewApiKeyResult{
+		ID:   cmd.Result.Id,
+		Name: cmd.Result.Name,
+		Key:  newKeyInfo.ClientSecret,
+	}
+
+	return response.JSON(http.StatusOK, result)
+}
+
+func (api *ServiceAccountsAPI) validateSecondsToLive(secondsToLive int64) response.Response {
+	if api.cfg.ApiKeyMaxSecondsToLive == -1 {
+		return nil
+	}
+	if secondsToLive == 0 {
+		return response.Error(http.StatusBadRequest, "Number of seconds before expiration should be set", nil)
+	}
+	if secondsToLive > api.cfg.ApiKeyMaxSecondsToLive {
+		return response.Error(http.StatusBadRequest, "Number of seconds before expiration is greater than the global limit", nil)
+	}
+	return nil
+}
+
+func validatePermissions(permissions []models.ApiKeyPermission) error {
+	for _, p := range permissions {
+		if p.Action == "" {
+			return errors.New("permission action should be set")
+		}
+		if p.Scope != "" && !accesscontrol.ValidateScope(p.Scope) {
+			return fmt.Errorf("permission scope %q is invalid", p.Scope)
+		}
+	}
+	return nil
+}
+
+func validateIPAllowList(ipAllowList []string) error {
+	for _, entry := range ipAllowList {
+		if net.ParseIP(entry) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil {
+			return fmt.Errorf("%q is neither an IP address nor a CIDR range", entry)
+		}
+	}
+	return nil
+}
+
+// rotatedName names the token replacing the given one, keeping the name it had before any rotation
+func rotatedName(name string, now time.Time) string {
+	if i := strings.Index(name, rotatedSuffix); i > 0 {
+		name = name[:i]
+	}
+	return name + rotatedSuffix + now.UTC().Format(time.RFC3339) + ")"
+}
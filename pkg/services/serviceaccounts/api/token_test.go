@@ -108,6 +108,43 @@ func TestServiceAccountsAPI_CreateToken(t *testing.T) {
 			body:         map[string]interface{}{"name": "Test4", "role": "Viewer"},
 			expectedCode: http.StatusForbidden,
 		},
+		{
+			desc: "should be ok to create serviceaccount token with permissions and an IP allow-list",
+			acmock: tests.SetupMockAccesscontrol(
+				t,
+				func(c context.Context, siu *models.SignedInUser, _ accesscontrol.Options) ([]*accesscontrol.Permission, error) {
+					return []*accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: serviceaccounts.ScopeAll}}, nil
+				},
+				false,
+			),
+			body: map[string]interface{}{"name": "Test5", "secondsToLive": 1,
+				"permissions": []map[string]string{{"action": "dashboards:read", "scope": "folders:uid:ci"}}, "ipAllowList": []string{"10.0.0.1", "192.168.0.0/16"}},
+			expectedCode: http.StatusOK,
+		},
+		{
+			desc: "should not be able to create serviceaccount token with an invalid IP allow-list",
+			acmock: tests.SetupMockAccesscontrol(
+				t,
+				func(c context.Context, siu *models.SignedInUser, _ accesscontrol.Options) ([]*accesscontrol.Permission, error) {
+					return []*accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: serviceaccounts.ScopeAll}}, nil
+				},
+				false,
+			),
+			body:         map[string]interface{}{"name": "Test6", "secondsToLive": 1, "ipAllowList": []string{"10.0.0.1/40"}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			desc: "should not be able to create serviceaccount token with an invalid permission scope",
+			acmock: tests.SetupMockAccesscontrol(
+				t,
+				func(c context.Context, siu *models.SignedInUser, _ accesscontrol.Options) ([]*accesscontrol.Permission, error) {
+					return []*accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: serviceaccounts.ScopeAll}}, nil
+				},
+				false,
+			),
+			body:         map[string]interface{}{"name": "Test7", "secondsToLive": 1, "permissions": []map[string]string{{"action": "dashboards:read", "scope": "folders*"}}},
+			expectedCode: http.StatusBadRequest,
+		},
 	}
 
 	var requestResponse = func(server *web.Mux, httpMethod, requestpath string, requestBody io.Reader) *httptest.ResponseRecorder {
@@ -148,6 +185,9 @@ func TestServiceAccountsAPI_CreateToken(t *testing.T) {
 
 				assert.Equal(t, sa.Id, *query.Result.ServiceAccountId)
 				assert.Equal(t, sa.OrgId, query.Result.OrgId)
+				if ipAllowList, ok := tc.body["ipAllowList"]; ok {
+					assert.Equal(t, ipAllowList, query.Result.IPAllowList)
+				}
 			}
 		})
 	}
@@ -240,6 +280,112 @@ func TestServiceAccountsAPI_DeleteToken(t *testing.T) {
 	}
 }
 
+func TestServiceAccountsAPI_RotateToken(t *testing.T) {
+	store := sqlstore.InitTestDB(t)
+	svcMock := &tests.ServiceAccountMock{}
+	saStore := database.NewServiceAccountsStore(store)
+	sa := tests.SetupUserServiceAccount(t, store, tests.TestUser{Login: "sa", IsServiceAccount: true})
+	acmock := tests.SetupMockAccesscontrol(
+		t,
+		func(c context.Context, siu *models.SignedInUser, _ accesscontrol.Options) ([]*accesscontrol.Permission, error) {
+			return []*accesscontrol.Permission{{Action: serviceaccounts.ActionWrite, Scope: serviceaccounts.ScopeAll}}, nil
+		},
+		false,
+	)
+
+	rotateWithStore := func(t *testing.T, saStore serviceaccounts.Store, tokenID int64, body string) (int, map[string]interface{}) {
+		server, _ := setupTestServer(t, svcMock, routing.NewRouteRegister(), acmock, store, saStore)
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf(serviceaccountIDTokensDetailPath+"/rotate", sa.Id, tokenID), strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Add("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, req)
+
+		actualBody := map[string]interface{}{}
+		_ = json.Unmarshal(recorder.Body.Bytes(), &actualBody)
+		return recorder.Code, actualBody
+	}
+	rotate := func(t *testing.T, tokenID int64, body string) (int, map[string]interface{}) {
+		return rotateWithStore(t, saStore, tokenID, body)
+	}
+
+	getToken := func(t *testing.T, name string) (*models.ApiKey, error) {
+		query := models.GetApiKeyByNameQuery{KeyName: name, OrgId: sa.OrgId}
+		err := store.GetApiKeyByName(context.Background(), &query)
+		return query.Result, err
+	}
+
+	t.Run("should issue a replacement and keep the rotated token valid for the grace period", func(t *testing.T) {
+		key, err := apikeygen.New(sa.OrgId, "Test1")
+		require.NoError(t, err)
+		cmd := serviceaccounts.AddServiceAccountTokenCommand{
+			Name:        "Test1",
+			OrgId:       sa.OrgId,
+			Key:         key.HashedKey,
+			Permissions: []models.ApiKeyPermission{{Action: "dashboards:read", Scope: "folders:uid:ci"}},
+			IPAllowList: []string{"10.0.0.0/8"},
+		}
+		require.NoError(t, saStore.AddServiceAccountToken(context.Background(), sa.Id, &cmd))
+
+		code, body := rotate(t, cmd.Result.Id, `{"gracePeriodSeconds": 60}`)
+		require.Equal(t, http.StatusOK, code, body)
+		require.NotEmpty(t, body["key"])
+
+		name := body["name"].(string)
+		assert.True(t, strings.HasPrefix(name, "Test1 (rotated "), name)
+		replacement, err := getToken(t, name)
+		require.NoError(t, err)
+		assert.Equal(t, cmd.Permissions, replacement.Permissions)
+		assert.Equal(t, cmd.IPAllowList, replacement.IPAllowList)
+		assert.Nil(t, replacement.Expires)
+
+		rotated, err := getToken(t, "Test1")
+		require.NoError(t, err)
+		require.NotNil(t, rotated.Expires)
+		assert.LessOrEqual(t, *rotated.Expires, time.Now().Add(time.Minute).Unix())
+	})
+
+	t.Run("should delete the rotated token without a grace period", func(t *testing.T) {
+		token := createTokenforSA(t, saStore, "Test2", sa.OrgId, sa.Id, 0)
+
+		code, body := rotate(t, token.Id, `{"name": "Test2 replacement", "gracePeriodSeconds": 0}`)
+		require.Equal(t, http.StatusOK, code, body)
+		assert.Equal(t, "Test2 replacement", body["name"])
+
+		_, err := getToken(t, "Test2")
+		require.ErrorIs(t, err, models.ErrInvalidApiKey)
+	})
+
+	t.Run("should not find a token of another service account", func(t *testing.T) {
+		code, _ := rotate(t, 1000, `{}`)
+		require.Equal(t, http.StatusNotFound, code)
+	})
+	t.Run("should not find a token deleted while it is rotated", func(t *testing.T) {
+		token := createTokenforSA(t, saStore, "Test3", sa.OrgId, sa.Id, 0)
+
+		code, _ := rotateWithStore(t, &deletingTokenStore{Store: saStore}, token.Id, `{}`)
+		require.Equal(t, http.StatusNotFound, code)
+	})
+}
+
+// deletingTokenStore deletes the token before rotating it, like a concurrent deletion does.
+type deletingTokenStore struct {
+	serviceaccounts.Store
+}
+
+func (s *deletingTokenStore) RotateServiceAccountToken(ctx context.Context, saID, tokenID int64, gracePeriod time.Duration, cmd *serviceaccounts.AddServiceAccountTokenCommand) error {
+	if err := s.DeleteServiceAccountToken(ctx, cmd.OrgId, saID, tokenID); err != nil {
+		return err
+	}
+	return s.Store.RotateServiceAccountToken(ctx, saID, tokenID, gracePeriod, cmd)
+}
+
+func TestRotatedName(t *testing.T) {
+	now := time.Date(2022, 5, 4, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, "ci (rotated 2022-05-04T12:00:00Z)", rotatedName("ci", now))
+	assert.Equal(t, "ci (rotated 2022-05-04T12:00:00Z)", rotatedName("ci (rotated 2022-04-01T08:00:00Z)", now))
+}
+
 type saStoreMockTokens struct {
 	serviceaccounts.Store
 	saAPIKeys []*models.ApiKey
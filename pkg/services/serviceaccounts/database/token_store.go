@@ -11,37 +11,78 @@ import (
 
 func (s *ServiceAccountsStoreImpl) AddServiceAccountToken(ctx context.Context, saID int64, cmd *serviceaccounts.AddServiceAccountTokenCommand) error {
 	return s.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
-		key := models.ApiKey{OrgId: cmd.OrgId, Name: cmd.Name}
-		exists, _ := sess.Get(&key)
-		if exists {
-			return &ErrDuplicateSAToken{cmd.Name}
-		}
+		return addServiceAccountToken(sess, saID, cmd)
+	})
+}
+
+func addServiceAccountToken(sess *sqlstore.DBSession, saID int64, cmd *serviceaccounts.AddServiceAccountTokenCommand) error {
+	key := models.ApiKey{OrgId: cmd.OrgId, Name: cmd.Name}
+	exists, _ := sess.Get(&key)
+	if exists {
+		return &ErrDuplicateSAToken{cmd.Name}
+	}
+
+	updated := time.Now()
+	var expires *int64 = nil
+	if cmd.SecondsToLive > 0 {
+		v := updated.Add(time.Second * time.Duration(cmd.SecondsToLive)).Unix()
+		expires = &v
+	} else if cmd.SecondsToLive < 0 {
+		return &ErrInvalidExpirationSAToken{}
+	}
 
-		updated := time.Now()
-		var expires *int64 = nil
-		if cmd.SecondsToLive > 0 {
-			v := updated.Add(time.Second * time.Duration(cmd.SecondsToLive)).Unix()
-			expires = &v
-		} else if cmd.SecondsToLive < 0 {
-			return &ErrInvalidExpirationSAToken{}
+	t := models.ApiKey{
+		OrgId:            cmd.OrgId,
+		Name:             cmd.Name,
+		Role:             models.ROLE_VIEWER,
+		Key:              cmd.Key,
+		Created:          updated,
+		Updated:          updated,
+		Expires:          expires,
+		ServiceAccountId: &saID,
+		Permissions:      cmd.Permissions,
+		IPAllowList:      cmd.IPAllowList,
+	}
+
+	if _, err := sess.Insert(&t); err != nil {
+		return err
+	}
+	cmd.Result = &t
+	return nil
+}
+
+// RotateServiceAccountToken adds a token replacing the given one, with the same permissions and IP allow-list.
+// The replaced token stays valid for the grace period, or is deleted if there is none.
+func (s *ServiceAccountsStoreImpl) RotateServiceAccountToken(ctx context.Context, saID, tokenID int64, gracePeriod time.Duration, cmd *serviceaccounts.AddServiceAccountTokenCommand) error {
+	return s.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		var token models.ApiKey
+		exists, err := sess.Where("id=? AND org_id=? AND service_account_id=?", tokenID, cmd.OrgId, saID).Get(&token)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return &ErrMissingSAToken{}
 		}
 
-		t := models.ApiKey{
-			OrgId:            cmd.OrgId,
-			Name:             cmd.Name,
-			Role:             models.ROLE_VIEWER,
-			Key:              cmd.Key,
-			Created:          updated,
-			Updated:          updated,
-			Expires:          expires,
-			ServiceAccountId: &saID,
+		cmd.Permissions = token.Permissions
+		cmd.IPAllowList = token.IPAllowList
+		if err := addServiceAccountToken(sess, saID, cmd); err != nil {
+			return err
 		}
 
-		if _, err := sess.Insert(&t); err != nil {
+		if gracePeriod <= 0 {
+			_, err := sess.ID(token.Id).Delete(&models.ApiKey{})
 			return err
 		}
-		cmd.Result = &t
-		return nil
+
+		expires := time.Now().Add(gracePeriod).Unix()
+		if token.Expires != nil && *token.Expires < expires {
+			return nil
+		}
+		token.Expires = &expires
+		token.Updated = time.Now()
+		_, err = sess.ID(token.Id).Cols("expires", "updated").Update(&token)
+		return err
 	})
 }
 
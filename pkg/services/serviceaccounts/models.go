@@ -42,11 +42,20 @@ type ServiceAccountDTO struct {
 }
 
 type AddServiceAccountTokenCommand struct {
-	Name          string         `json:"name" binding:"Required"`
-	OrgId         int64          `json:"-"`
-	Key           string         `json:"-"`
-	SecondsToLive int64          `json:"secondsToLive"`
-	Result        *models.ApiKey `json:"-"`
+	Name          string                    `json:"name" binding:"Required"`
+	OrgId         int64                     `json:"-"`
+	Key           string                    `json:"-"`
+	SecondsToLive int64                     `json:"secondsToLive"`
+	Permissions   []models.ApiKeyPermission `json:"permissions"`
+	IPAllowList   []string                  `json:"ipAllowList"`
+	Result        *models.ApiKey            `json:"-"`
+}
+
+type RotateServiceAccountTokenForm struct {
+	Name          string `json:"name"`
+	SecondsToLive int64  `json:"secondsToLive"`
+	// GracePeriodSeconds is how long the rotated token stays valid, 24 hours by default
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds"`
 }
 
 type SearchServiceAccountsResult struct {
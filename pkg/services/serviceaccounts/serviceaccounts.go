@@ -2,6 +2,7 @@ package serviceaccounts
 
 import (
 	"context"
+	"time"
 
 	"github.com/grafana/grafana/pkg/models"
 )
@@ -27,5 +28,6 @@ type Store interface {
 	ListTokens(ctx context.Context, orgID int64, serviceAccount int64) ([]*models.ApiKey, error)
 	DeleteServiceAccountToken(ctx context.Context, orgID, serviceAccountID, tokenID int64) error
 	AddServiceAccountToken(ctx context.Context, serviceAccountID int64, cmd *AddServiceAccountTokenCommand) error
+	RotateServiceAccountToken(ctx context.Context, serviceAccountID, tokenID int64, gracePeriod time.Duration, cmd *AddServiceAccountTokenCommand) error
 	GetUsageMetrics(ctx context.Context) (map[string]interface{}, error)
 }
@@ -3,6 +3,7 @@ package tests
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
@@ -81,6 +82,7 @@ type Calls struct {
 	DeleteServiceAccountToken      []interface{}
 	UpdateServiceAccount           []interface{}
 	AddServiceAccountToken         []interface{}
+	RotateServiceAccountToken      []interface{}
 	SearchOrgServiceAccounts       []interface{}
 	RetrieveServiceAccountIdByName []interface{}
 }
@@ -156,6 +158,11 @@ func (s *ServiceAccountsStoreMock) AddServiceAccountToken(ctx context.Context, s
 	return nil
 }
 
+func (s *ServiceAccountsStoreMock) RotateServiceAccountToken(ctx context.Context, serviceAccountID, tokenID int64, gracePeriod time.Duration, cmd *serviceaccounts.AddServiceAccountTokenCommand) error {
+	s.Calls.RotateServiceAccountToken = append(s.Calls.RotateServiceAccountToken, []interface{}{ctx, serviceAccountID, tokenID, gracePeriod, cmd})
+	return nil
+}
+
 func (s *ServiceAccountsStoreMock) GetUsageMetrics(ctx context.Context) (map[string]interface{}, error) {
 	return map[string]interface{}{}, nil
 }
@@ -3,12 +3,30 @@ package sqlstore
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
+var annotationsCleanedUp = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "grafana",
+		Subsystem: "annotations_cleanup",
+		Name:      "deleted_total",
+		Help:      "A counter for annotations deleted by the cleanup job, by annotation type or retention rule",
+	},
+	[]string{"policy"},
+)
+
+func init() {
+	prometheus.MustRegister(annotationsCleanedUp)
+}
+
 // AnnotationCleanupService is responsible for cleaning old annotations.
 type AnnotationCleanupService struct {
 	batchSize int64
@@ -27,41 +45,113 @@ const (
 // from the annotation_tag table. Cleanup actions are performed in batches
 // so that no query takes too long to complete.
 //
+// Annotations matching a retention rule are deleted according to the first
+// rule they match, instead of the settings of their type.
+//
 // Returns the number of annotation and annotation_tag rows deleted. If an
 // error occurs, it returns the number of rows affected so far.
 func (acs *AnnotationCleanupService) CleanAnnotations(ctx context.Context, cfg *setting.Cfg) (int64, int64, error) {
 	var totalCleanedAnnotations int64
-	affected, err := acs.cleanAnnotations(ctx, cfg.AlertingAnnotationCleanupSetting, alertAnnotationType)
-	totalCleanedAnnotations += affected
-	if err != nil {
-		return totalCleanedAnnotations, 0, err
+	var previousRules []annotationFilter
+	for _, rule := range cfg.AnnotationRetentionRules {
+		ruleFilter := retentionRuleFilter(rule)
+		affected, err := acs.cleanAnnotations(ctx, rule.AnnotationCleanupSettings, ruleFilter.excluding(previousRules))
+		annotationsCleanedUp.WithLabelValues("rule:" + rule.Name).Add(float64(affected))
+		totalCleanedAnnotations += affected
+		if err != nil {
+			return totalCleanedAnnotations, 0, err
+		}
+		previousRules = append(previousRules, ruleFilter)
 	}
 
-	affected, err = acs.cleanAnnotations(ctx, cfg.APIAnnotationCleanupSettings, apiAnnotationType)
-	totalCleanedAnnotations += affected
-	if err != nil {
-		return totalCleanedAnnotations, 0, err
+	types := []struct {
+		name     string
+		settings setting.AnnotationCleanupSettings
+		sql      string
+	}{
+		{"alert", cfg.AlertingAnnotationCleanupSetting, alertAnnotationType},
+		{"api", cfg.APIAnnotationCleanupSettings, apiAnnotationType},
+		{"dashboard", cfg.DashboardAnnotationCleanupSettings, dashboardAnnotationType},
 	}
-
-	affected, err = acs.cleanAnnotations(ctx, cfg.DashboardAnnotationCleanupSettings, dashboardAnnotationType)
-	totalCleanedAnnotations += affected
-	if err != nil {
-		return totalCleanedAnnotations, 0, err
+	for _, annotationType := range types {
+		typeFilter := annotationFilter{sql: annotationType.sql}
+		affected, err := acs.cleanAnnotations(ctx, annotationType.settings, typeFilter.excluding(previousRules))
+		annotationsCleanedUp.WithLabelValues("type:" + annotationType.name).Add(float64(affected))
+		totalCleanedAnnotations += affected
+		if err != nil {
+			return totalCleanedAnnotations, 0, err
+		}
 	}
+
+	var affected int64
+	var err error
 	if totalCleanedAnnotations > 0 {
 		affected, err = acs.cleanOrphanedAnnotationTags(ctx)
 	}
 	return totalCleanedAnnotations, affected, err
 }
 
-func (acs *AnnotationCleanupService) cleanAnnotations(ctx context.Context, cfg setting.AnnotationCleanupSettings, annotationType string) (int64, error) {
+// annotationFilter is a condition on the annotation table, with its parameters.
+type annotationFilter struct {
+	sql    string
+	params []interface{}
+}
+
+// excluding returns the filter of the annotations that match f but none of the others.
+func (f annotationFilter) excluding(others []annotationFilter) annotationFilter {
+	filter := annotationFilter{sql: "(" + f.sql + ")", params: append([]interface{}{}, f.params...)}
+	for _, other := range others {
+		filter.sql += " AND NOT (" + other.sql + ")"
+		filter.params = append(filter.params, other.params...)
+	}
+	return filter
+}
+
+// retentionRuleFilter returns the filter of the annotations of the organization, of the dashboards and with all
+// the tags of the rule.
+func retentionRuleFilter(rule setting.AnnotationRetentionRule) annotationFilter {
+	conditions := []string{}
+	filter := annotationFilter{}
+
+	if rule.OrgID != 0 {
+		conditions = append(conditions, "org_id = ?")
+		filter.params = append(filter.params, rule.OrgID)
+	}
+
+	if len(rule.DashboardUIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"dashboard_id IN (SELECT id FROM dashboard WHERE dashboard.org_id = annotation.org_id AND dashboard.uid IN (?%s))",
+			strings.Repeat(",?", len(rule.DashboardUIDs)-1)))
+		for _, uid := range rule.DashboardUIDs {
+			filter.params = append(filter.params, uid)
+		}
+	}
+
+	// each tag of the rule is matched by its own tag of the annotation, a key without value matching any value
+	for _, tag := range models.ParseTagPairs(rule.Tags) {
+		tagFilter := "tag." + dialect.Quote("key") + " = ?"
+		filter.params = append(filter.params, tag.Key)
+		if tag.Value != "" {
+			tagFilter += " AND tag." + dialect.Quote("value") + " = ?"
+			filter.params = append(filter.params, tag.Value)
+		}
+		conditions = append(conditions, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM annotation_tag at INNER JOIN tag ON tag.id = at.tag_id WHERE at.annotation_id = annotation.id AND %s)",
+			tagFilter))
+	}
+
+	filter.sql = strings.Join(conditions, " AND ")
+	return filter
+}
+
+func (acs *AnnotationCleanupService) cleanAnnotations(ctx context.Context, cfg setting.AnnotationCleanupSettings, filter annotationFilter) (int64, error) {
 	var totalAffected int64
 	if cfg.MaxAge > 0 {
 		cutoffDate := time.Now().Add(-cfg.MaxAge).UnixNano() / int64(time.Millisecond)
 		deleteQuery := `DELETE FROM annotation WHERE id IN (SELECT id FROM (SELECT id FROM annotation WHERE %s AND created < %v ORDER BY id DESC %s) a)`
-		sql := fmt.Sprintf(deleteQuery, annotationType, cutoffDate, dialect.Limit(acs.batchSize))
+		sql := fmt.Sprintf(deleteQuery, filter.sql, cutoffDate, dialect.Limit(acs.batchSize))
 
-		affected, err := acs.executeUntilDoneOrCancelled(ctx, sql)
+		affected, err := acs.executeUntilDoneOrCancelled(ctx, sql, filter.params...)
 		totalAffected += affected
 		if err != nil {
 			return totalAffected, err
@@ -70,8 +160,8 @@ func (acs *AnnotationCleanupService) cleanAnnotations(ctx context.Context, cfg s
 
 	if cfg.MaxCount > 0 {
 		deleteQuery := `DELETE FROM annotation WHERE id IN (SELECT id FROM (SELECT id FROM annotation WHERE %s ORDER BY id DESC %s) a)`
-		sql := fmt.Sprintf(deleteQuery, annotationType, dialect.LimitOffset(acs.batchSize, cfg.MaxCount))
-		affected, err := acs.executeUntilDoneOrCancelled(ctx, sql)
+		sql := fmt.Sprintf(deleteQuery, filter.sql, dialect.LimitOffset(acs.batchSize, cfg.MaxCount))
+		affected, err := acs.executeUntilDoneOrCancelled(ctx, sql, filter.params...)
 		totalAffected += affected
 		return totalAffected, err
 	}
@@ -85,7 +175,7 @@ func (acs *AnnotationCleanupService) cleanOrphanedAnnotationTags(ctx context.Con
 	return acs.executeUntilDoneOrCancelled(ctx, sql)
 }
 
-func (acs *AnnotationCleanupService) executeUntilDoneOrCancelled(ctx context.Context, sql string) (int64, error) {
+func (acs *AnnotationCleanupService) executeUntilDoneOrCancelled(ctx context.Context, sql string, params ...interface{}) (int64, error) {
 	var totalAffected int64
 	for {
 		select {
@@ -94,7 +184,7 @@ func (acs *AnnotationCleanupService) executeUntilDoneOrCancelled(ctx context.Con
 		default:
 			var affected int64
 			err := withDbSession(ctx, acs.sqlstore.engine, func(session *DBSession) error {
-				res, err := session.Exec(sql)
+				res, err := session.Exec(append([]interface{}{sql}, params...)...)
 				if err != nil {
 					return err
 				}
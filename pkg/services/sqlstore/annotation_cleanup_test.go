@@ -5,9 +5,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/annotations"
 	"github.com/grafana/grafana/pkg/setting"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -143,7 +146,7 @@ func TestOldAnnotationsAreDeletedFirst(t *testing.T) {
 
 	// run the clean up task to keep one annotation.
 	cleaner := &AnnotationCleanupService{batchSize: 1, log: log.New("test-logger"), sqlstore: fakeSQL}
-	_, err = cleaner.cleanAnnotations(context.Background(), setting.AnnotationCleanupSettings{MaxCount: 1}, alertAnnotationType)
+	_, err = cleaner.cleanAnnotations(context.Background(), setting.AnnotationCleanupSettings{MaxCount: 1}, annotationFilter{sql: alertAnnotationType})
 	require.NoError(t, err)
 
 	// assert that the last annotations were kept
@@ -156,6 +159,114 @@ func TestOldAnnotationsAreDeletedFirst(t *testing.T) {
 	require.Equal(t, int64(0), countOld, "the two first annotations should have been deleted")
 }
 
+func TestAnnotationRetentionRules(t *testing.T) {
+	fakeSQL := InitTestDB(t)
+	repo := NewSQLAnnotationRepo(fakeSQL)
+	dashboard := &models.Dashboard{OrgId: 1, Uid: "releases", Title: "Releases", Slug: "releases", Data: simplejson.New(),
+		Created: time.Now(), Updated: time.Now()}
+	_, err := fakeSQL.NewSession(context.Background()).Insert(dashboard)
+	require.NoError(t, err)
+
+	days := func(n int) int64 {
+		return time.Now().AddDate(0, 0, -n).UnixNano() / int64(time.Millisecond)
+	}
+	save := func(text string, dashboardID int64, created int64, tags ...string) {
+		item := &annotations.Item{OrgId: 1, DashboardId: dashboardID, Text: text, Tags: tags}
+		require.NoError(t, repo.Save(item))
+		require.NoError(t, fakeSQL.WithDbSession(context.Background(), func(session *DBSession) error {
+			_, err := session.Exec("UPDATE annotation SET created = ? WHERE id = ?", created, item.Id)
+			return err
+		}))
+	}
+
+	save("recent deploy", 0, days(200), "deploy", "service:api")
+	save("old deploy", 0, days(400), "deploy", "service:api")
+	save("automated deploy", 0, days(10), "deploy", "service:api", "automated")
+	save("automated", 0, days(10), "automated")
+	save("old API annotation", 0, days(40))
+	save("API annotation", 0, days(10))
+	save("old release", dashboard.Id, days(3))
+	save("release", dashboard.Id, days(2))
+
+	cfg := &setting.Cfg{
+		APIAnnotationCleanupSettings: settingsFn(30*24*time.Hour, 0),
+		AnnotationRetentionRules: []setting.AnnotationRetentionRule{
+			{Name: "deploys", Tags: []string{"deploy", "service:api"}, AnnotationCleanupSettings: settingsFn(365*24*time.Hour, 0)},
+			{Name: "automated", Tags: []string{"automated"}, AnnotationCleanupSettings: settingsFn(7*24*time.Hour, 0)},
+			{Name: "releases", OrgID: 1, DashboardUIDs: []string{dashboard.Uid}, AnnotationCleanupSettings: settingsFn(0, 1)},
+		},
+	}
+
+	deletedByRule := func(name string) float64 {
+		return testutil.ToFloat64(annotationsCleanedUp.WithLabelValues(name))
+	}
+	deletedBefore := map[string]float64{}
+	for _, name := range []string{"rule:deploys", "rule:automated", "rule:releases", "type:api"} {
+		deletedBefore[name] = deletedByRule(name)
+	}
+
+	cleaner := &AnnotationCleanupService{batchSize: 1, log: log.New("test-logger"), sqlstore: fakeSQL}
+	affected, _, err := cleaner.CleanAnnotations(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), affected)
+
+	// annotations are kept according to the first rule they match
+	items := []annotations.Item{}
+	require.NoError(t, fakeSQL.NewSession(context.Background()).Asc("id").Find(&items))
+	texts := []string{}
+	for _, item := range items {
+		texts = append(texts, item.Text)
+	}
+	require.Equal(t, []string{"recent deploy", "automated deploy", "API annotation", "release"}, texts)
+
+	assert.Equal(t, float64(1), deletedByRule("rule:deploys")-deletedBefore["rule:deploys"])
+	assert.Equal(t, float64(1), deletedByRule("rule:automated")-deletedBefore["rule:automated"])
+	assert.Equal(t, float64(1), deletedByRule("rule:releases")-deletedBefore["rule:releases"])
+	assert.Equal(t, float64(1), deletedByRule("type:api")-deletedBefore["type:api"])
+}
+
+func TestAnnotationRetentionRuleTags(t *testing.T) {
+	fakeSQL := InitTestDB(t)
+	repo := NewSQLAnnotationRepo(fakeSQL)
+
+	created := time.Now().AddDate(0, 0, -10).UnixNano() / int64(time.Millisecond)
+	save := func(text string, tags ...string) {
+		item := &annotations.Item{OrgId: 1, Text: text, Tags: tags}
+		require.NoError(t, repo.Save(item))
+		require.NoError(t, fakeSQL.WithDbSession(context.Background(), func(session *DBSession) error {
+			_, err := session.Exec("UPDATE annotation SET created = ? WHERE id = ?", created, item.Id)
+			return err
+		}))
+	}
+
+	save("several environments", "env:prod", "env:dev")
+	save("no environment", "service:api")
+	save("team a", "team:a")
+	save("team b", "team:b")
+
+	cfg := &setting.Cfg{
+		AnnotationRetentionRules: []setting.AnnotationRetentionRule{
+			// a key without value matches the annotations with any number of values for the key
+			{Name: "environments", Tags: []string{"env"}, AnnotationCleanupSettings: settingsFn(7*24*time.Hour, 0)},
+			// a tag of the annotation can match several tags of the rule
+			{Name: "team a", Tags: []string{"team:a", "team"}, AnnotationCleanupSettings: settingsFn(7*24*time.Hour, 0)},
+		},
+	}
+
+	cleaner := &AnnotationCleanupService{batchSize: 1, log: log.New("test-logger"), sqlstore: fakeSQL}
+	affected, _, err := cleaner.CleanAnnotations(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), affected)
+
+	items := []annotations.Item{}
+	require.NoError(t, fakeSQL.NewSession(context.Background()).Asc("id").Find(&items))
+	texts := []string{}
+	for _, item := range items {
+		texts = append(texts, item.Text)
+	}
+	require.Equal(t, []string{"no environment", "team b"}, texts)
+}
+
 func assertAnnotationCount(t *testing.T, fakeSQL *SQLStore, sql string, expectedCount int64) {
 	t.Helper()
 
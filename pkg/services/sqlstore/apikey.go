@@ -113,6 +113,15 @@ func (ss *SQLStore) AddAPIKey(ctx context.Context, cmd *models.AddApiKeyCommand)
 	})
 }
 
+// UpdateApiKeyLastUsed records when and from which IP address the API key was last used.
+func (ss *SQLStore) UpdateApiKeyLastUsed(ctx context.Context, cmd *models.UpdateApiKeyLastUsedCommand) error {
+	return ss.WithDbSession(ctx, func(sess *DBSession) error {
+		key := models.ApiKey{LastUsedAt: &cmd.LastUsedAt, LastUsedIP: cmd.LastUsedIP}
+		_, err := sess.ID(cmd.Id).Cols("last_used_at", "last_used_ip").Update(&key)
+		return err
+	})
+}
+
 func (ss *SQLStore) GetApiKeyById(ctx context.Context, query *models.GetApiKeyByIdQuery) error {
 	return ss.WithDbSession(ctx, func(sess *DBSession) error {
 		var apikey models.ApiKey
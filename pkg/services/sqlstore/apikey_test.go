@@ -49,6 +49,23 @@ func TestApiKeyDataAccess(t *testing.T) {
 			assert.Nil(t, query.Result.Expires)
 		})
 
+		t.Run("Update the last use of a key", func(t *testing.T) {
+			cmd := models.AddApiKeyCommand{OrgId: 1, Name: "last-used", Key: "asd-last-used"}
+			err := ss.AddAPIKey(context.Background(), &cmd)
+			require.NoError(t, err)
+
+			lastUsedAt := time.Date(2022, 5, 4, 12, 0, 0, 0, time.UTC)
+			err = ss.UpdateApiKeyLastUsed(context.Background(), &models.UpdateApiKeyLastUsedCommand{Id: cmd.Result.Id, LastUsedAt: lastUsedAt, LastUsedIP: "10.0.0.1"})
+			require.NoError(t, err)
+
+			query := models.GetApiKeyByNameQuery{KeyName: "last-used", OrgId: 1}
+			err = ss.GetApiKeyByName(context.Background(), &query)
+			require.NoError(t, err)
+			require.NotNil(t, query.Result.LastUsedAt)
+			assert.True(t, lastUsedAt.Equal(*query.Result.LastUsedAt))
+			assert.Equal(t, "10.0.0.1", query.Result.LastUsedIP)
+		})
+
 		t.Run("Add an expiring key", func(t *testing.T) {
 			// expires in one hour
 			cmd := models.AddApiKeyCommand{OrgId: 1, Name: "expiring-in-an-hour", Key: "asd2", SecondsToLive: 3600}
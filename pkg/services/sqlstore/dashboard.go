@@ -71,14 +71,15 @@ func (ss *SQLStore) FindDashboards(ctx context.Context, query *models.FindPersis
 			Dialect:         dialect,
 			UserId:          query.SignedInUser.UserId,
 			PermissionLevel: query.Permission,
+			MaxFolderDepth:  ss.Cfg.MaxFolderDepth,
 		},
 	}
 
 	if !accesscontrol.IsDisabled(ss.Cfg) {
 		// if access control is enabled, overwrite the filters so far
-		filters = []interface{}{
-			permissions.NewAccessControlDashboardPermissionFilter(query.SignedInUser, query.Permission, query.Type),
-		}
+		acFilter := permissions.NewAccessControlDashboardPermissionFilter(query.SignedInUser, query.Permission, query.Type)
+		acFilter.MaxFolderDepth = ss.Cfg.MaxFolderDepth
+		filters = []interface{}{acFilter}
 	}
 
 	for _, filter := range query.Sort.Filter {
@@ -151,9 +152,144 @@ func (ss *SQLStore) SearchDashboards(ctx context.Context, query *models.FindPers
 
 	makeQueryResult(query, res)
 
+	if ss.Cfg.MaxFolderDepth > 1 {
+		return ss.setFolderPaths(ctx, query.SignedInUser, query.Result)
+	}
+
+	return nil
+}
+
+// setFolderPaths sets the path of nested folders of the hits in folders, without the folders the user cannot view
+func (ss *SQLStore) setFolderPaths(ctx context.Context, user *models.SignedInUser, hits []*models.Hit) error {
+	folderIDs := make([]int64, 0, len(hits))
+	for _, hit := range hits {
+		if hit.FolderID > 0 {
+			folderIDs = append(folderIDs, hit.FolderID)
+		}
+	}
+	if len(folderIDs) == 0 {
+		return nil
+	}
+
+	paths, err := ss.GetFolderPaths(ctx, user.OrgId, folderIDs)
+	if err != nil {
+		return err
+	}
+	if err := ss.filterViewableFolderPaths(ctx, user, paths); err != nil {
+		return err
+	}
+	for _, hit := range hits {
+		hit.FolderPath = paths[hit.FolderID]
+	}
+	return nil
+}
+
+// filterViewableFolderPaths removes the folders the user cannot view from the paths, so that the titles of the
+// parents of a folder are not shown to users that cannot view them.
+func (ss *SQLStore) filterViewableFolderPaths(ctx context.Context, user *models.SignedInUser, paths map[int64][]*models.FolderPathItem) error {
+	ids := make([]int64, 0)
+	seen := make(map[int64]bool)
+	for _, path := range paths {
+		for _, item := range path {
+			if !seen[item.ID] {
+				seen[item.ID] = true
+				ids = append(ids, item.ID)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	viewable, err := ss.FindDashboards(ctx, &models.FindPersistedDashboardsQuery{
+		SignedInUser: user,
+		DashboardIds: ids,
+		Type:         searchstore.TypeFolder,
+		Permission:   models.PERMISSION_VIEW,
+		Limit:        int64(len(ids)),
+	})
+	if err != nil {
+		return err
+	}
+
+	canView := make(map[int64]bool, len(viewable))
+	for _, folder := range viewable {
+		canView[folder.ID] = true
+	}
+	for id, path := range paths {
+		filtered := make([]*models.FolderPathItem, 0, len(path))
+		for _, item := range path {
+			if canView[item.ID] {
+				filtered = append(filtered, item)
+			}
+		}
+		paths[id] = filtered
+	}
 	return nil
 }
 
+type folderPathProjection struct {
+	ID       int64  `xorm:"id"`
+	UID      string `xorm:"uid"`
+	Title    string
+	Slug     string
+	FolderID int64 `xorm:"folder_id"`
+}
+
+// GetFolderPaths returns the path of each folder, from its root folder down to the folder itself.
+// Folders that do not exist are not part of the result.
+func (ss *SQLStore) GetFolderPaths(ctx context.Context, orgID int64, folderIDs []int64) (map[int64][]*models.FolderPathItem, error) {
+	folders := make(map[int64]folderPathProjection)
+	err := ss.WithDbSession(ctx, func(sess *DBSession) error {
+		// load the folders and their parents, one level at a time
+		pending := folderIDs
+		for len(pending) > 0 {
+			rows := make([]folderPathProjection, 0, len(pending))
+			err := sess.Table("dashboard").Cols("id", "uid", "title", "slug", "folder_id").
+				Where("org_id = ? AND is_folder = "+dialect.BooleanStr(true), orgID).
+				In("id", pending).Find(&rows)
+			if err != nil {
+				return err
+			}
+
+			pending = make([]int64, 0, len(rows))
+			for _, row := range rows {
+				folders[row.ID] = row
+			}
+			for _, row := range rows {
+				if _, loaded := folders[row.FolderID]; row.FolderID > 0 && !loaded {
+					pending = append(pending, row.FolderID)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[int64][]*models.FolderPathItem, len(folderIDs))
+	for _, id := range folderIDs {
+		if _, ok := folders[id]; !ok {
+			continue
+		}
+
+		var path []*models.FolderPathItem
+		visited := make(map[int64]bool)
+		for folder, ok := folders[id]; ok && !visited[folder.ID]; folder, ok = folders[folder.FolderID] {
+			visited[folder.ID] = true
+			path = append([]*models.FolderPathItem{{
+				ID:    folder.ID,
+				UID:   folder.UID,
+				Title: folder.Title,
+				URL:   models.GetFolderUrl(folder.UID, folder.Slug),
+			}}, path...)
+		}
+		paths[id] = path
+	}
+	return paths, nil
+}
+
 func getHitType(item DashboardSearchProjection) models.HitType {
 	var hitType models.HitType
 	if item.IsFolder {
@@ -4,13 +4,14 @@ import (
 	"context"
 
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore/permissions"
 )
 
 // GetDashboardAclInfoList returns a list of permissions for a dashboard. They can be fetched from three
 // different places.
 // 1) Permissions for the dashboard
-// 2) permissions for its parent folder
-// 3) if no specific permissions have been set for the dashboard or its parent folder then get the default permissions
+// 2) permissions for its parent folder, and the parents of this folder when folders are nested
+// 3) if no specific permissions have been set for the dashboard or its root folder then get the default permissions
 func (ss *SQLStore) GetDashboardAclInfoList(ctx context.Context, query *models.GetDashboardAclInfoListQuery) error {
 	outerErr := ss.WithDbSession(ctx, func(dbSession *DBSession) error {
 		query.Result = make([]*models.DashboardAclInfoDTO, 0)
@@ -40,8 +41,20 @@ func (ss *SQLStore) GetDashboardAclInfoList(ctx context.Context, query *models.G
 			return dbSession.SQL(sql).Find(&query.Result)
 		}
 
+		// permissions and default permissions are inherited from the parent folders
+		folderJoins, parentFolders := permissions.ParentFolderJoins(ss.Cfg.MaxFolderDepth)
+		parentPermissions := ""
+		defaultPermissions := `(folder.id IS NOT NULL AND folder.has_acl = ` + falseStr + `) OR
+					  (folder.id IS NULL AND d.has_acl = ` + falseStr + `)`
+		for _, parent := range parentFolders {
+			parentPermissions += ` OR da.dashboard_id = ` + parent + `.id`
+		}
+		if len(parentFolders) > 0 {
+			defaultPermissions = permissions.RootFolderColumn(parentFolders, "has_acl") + ` = ` + falseStr
+		}
+
 		rawSQL := `
-			-- get permissions for the dashboard and its parent folder
+			-- get permissions for the dashboard and its parent folders
 			SELECT
 				da.id,
 				da.org_id,
@@ -60,17 +73,17 @@ func (ss *SQLStore) GetDashboardAclInfoList(ctx context.Context, query *models.G
 				d.slug,
 				d.uid,
 				d.is_folder,
-				CASE WHEN (da.dashboard_id = -1 AND d.folder_id > 0) OR da.dashboard_id = d.folder_id THEN ` + dialect.BooleanStr(true) + ` ELSE ` + falseStr + ` END AS inherited
+				CASE WHEN (da.dashboard_id = -1 AND d.folder_id > 0) OR da.dashboard_id = d.folder_id` + parentPermissions + ` THEN ` + dialect.BooleanStr(true) + ` ELSE ` + falseStr + ` END AS inherited
 			FROM dashboard as d
 				LEFT JOIN dashboard folder on folder.id = d.folder_id
+				` + folderJoins + `
 				LEFT JOIN dashboard_acl AS da ON
 				da.dashboard_id = d.id OR
-				da.dashboard_id = d.folder_id OR
+				da.dashboard_id = d.folder_id` + parentPermissions + ` OR
 				(
 					-- include default permissions -->
 					da.org_id = -1 AND (
-					  (folder.id IS NOT NULL AND folder.has_acl = ` + falseStr + `) OR
-					  (folder.id IS NULL AND d.has_acl = ` + falseStr + `)
+					  ` + defaultPermissions + `
 					)
 				)
 				LEFT JOIN ` + dialect.Quote("user") + ` AS u ON u.id = da.user_id
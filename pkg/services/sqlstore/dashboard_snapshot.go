@@ -19,8 +19,16 @@ func (ss *SQLStore) DeleteExpiredSnapshots(ctx context.Context, cmd *models.Dele
 			return nil
 		}
 
+		now := time.Now()
+		var blobKeys []string
+		if err := sess.Table("dashboard_snapshot").Where("expires < ? AND blob_key IS NOT NULL AND blob_key <> ''", now).
+			Cols("blob_key").Find(&blobKeys); err != nil {
+			return err
+		}
+		cmd.BlobKeys = blobKeys
+
 		deleteExpiredSQL := "DELETE FROM dashboard_snapshot WHERE expires < ?"
-		expiredResponse, err := sess.Exec(deleteExpiredSQL, time.Now())
+		expiredResponse, err := sess.Exec(deleteExpiredSQL, now)
 		if err != nil {
 			return err
 		}
@@ -32,6 +40,20 @@ func (ss *SQLStore) DeleteExpiredSnapshots(ctx context.Context, cmd *models.Dele
 
 func (ss *SQLStore) CreateDashboardSnapshot(ctx context.Context, cmd *models.CreateDashboardSnapshotCommand) error {
 	return ss.WithTransactionalDbSession(ctx, func(sess *DBSession) error {
+		if cmd.MaxOrgSize != nil {
+			// the organization is locked so that the snapshots created concurrently are counted one after the other
+			if _, err := sess.ID(cmd.OrgId).Cols("id").ForUpdate().Get(&models.Org{}); err != nil {
+				return err
+			}
+			size, err := sess.Table("dashboard_snapshot").Where("org_id = ?", cmd.OrgId).SumInt(&models.DashboardSnapshot{}, "blob_size")
+			if err != nil {
+				return err
+			}
+			if size+cmd.BlobSize > *cmd.MaxOrgSize {
+				return models.ErrDashboardSnapshotQuotaReached
+			}
+		}
+
 		// never
 		var expires = time.Now().Add(time.Hour * 24 * 365 * 50)
 		if cmd.Expires > 0 {
@@ -49,6 +71,8 @@ func (ss *SQLStore) CreateDashboardSnapshot(ctx context.Context, cmd *models.Cre
 			ExternalDeleteUrl:  cmd.ExternalDeleteUrl,
 			Dashboard:          simplejson.New(),
 			DashboardEncrypted: cmd.DashboardEncrypted,
+			BlobKey:            cmd.BlobKey,
+			BlobSize:           cmd.BlobSize,
 			Expires:            expires,
 			Created:            time.Now(),
 			Updated:            time.Now(),
@@ -116,3 +140,36 @@ func (ss *SQLStore) SearchDashboardSnapshots(ctx context.Context, query *models.
 		return err
 	})
 }
+
+// GetDashboardSnapshotsSize returns the storage used by the snapshots of an organization.
+func (ss *SQLStore) GetDashboardSnapshotsSize(ctx context.Context, query *models.GetDashboardSnapshotsSizeQuery) error {
+	return ss.WithDbSession(ctx, func(sess *DBSession) error {
+		size, err := sess.Table("dashboard_snapshot").Where("org_id = ?", query.OrgId).SumInt(&models.DashboardSnapshot{}, "blob_size")
+		if err != nil {
+			return err
+		}
+
+		query.Result = size
+		return nil
+	})
+}
+
+// GetDashboardSnapshotsWithoutBlob returns snapshots whose dashboard is still stored in the database.
+func (ss *SQLStore) GetDashboardSnapshotsWithoutBlob(ctx context.Context, query *models.GetDashboardSnapshotsWithoutBlobQuery) error {
+	return ss.WithDbSession(ctx, func(sess *DBSession) error {
+		snapshots := make([]*models.DashboardSnapshot, 0)
+		err := sess.Where("blob_key IS NULL OR blob_key = ''").Asc("id").Limit(query.Limit).Find(&snapshots)
+		query.Result = snapshots
+		return err
+	})
+}
+
+// SetDashboardSnapshotBlob sets the storage key of the dashboard of a snapshot, and removes the dashboard from the
+// database.
+func (ss *SQLStore) SetDashboardSnapshotBlob(ctx context.Context, cmd *models.SetDashboardSnapshotBlobCommand) error {
+	return ss.WithTransactionalDbSession(ctx, func(sess *DBSession) error {
+		rawSQL := "UPDATE dashboard_snapshot SET blob_key = ?, blob_size = ?, dashboard = ?, dashboard_encrypted = NULL WHERE id = ?"
+		_, err := sess.Exec(rawSQL, cmd.BlobKey, cmd.BlobSize, "{}", cmd.Id)
+		return err
+	})
+}
@@ -182,6 +182,31 @@ func TestDeleteExpiredSnapshots(t *testing.T) {
 	})
 }
 
+func TestCreateDashboardSnapshotQuota(t *testing.T) {
+	sqlstore := InitTestDB(t)
+	create := func(key string, orgID, size int64, maxOrgSize *int64) error {
+		return sqlstore.CreateDashboardSnapshot(context.Background(), &models.CreateDashboardSnapshotCommand{
+			Key: key, DeleteKey: "delete" + key, OrgId: orgID, BlobKey: key, BlobSize: size, MaxOrgSize: maxOrgSize,
+		})
+	}
+	quota := func(size int64) *int64 {
+		return &size
+	}
+
+	require.NoError(t, create("a", 1, 60, quota(100)))
+	require.ErrorIs(t, create("b", 1, 60, quota(100)), models.ErrDashboardSnapshotQuotaReached)
+	require.NoError(t, create("c", 1, 40, quota(100)))
+	require.NoError(t, create("d", 2, 60, quota(100)))
+	// a quota of 0 leaves no storage for snapshots
+	require.ErrorIs(t, create("f", 3, 1, quota(0)), models.ErrDashboardSnapshotQuotaReached)
+	// snapshots are not counted without a quota
+	require.NoError(t, create("e", 1, 60, nil))
+
+	query := models.GetDashboardSnapshotsSizeQuery{OrgId: 1}
+	require.NoError(t, sqlstore.GetDashboardSnapshotsSize(context.Background(), &query))
+	require.Equal(t, int64(160), query.Result)
+}
+
 func createTestSnapshot(t *testing.T, sqlstore *SQLStore, key string, expires int64) *models.DashboardSnapshot {
 	cmd := models.CreateDashboardSnapshotCommand{
 		Key:       key,
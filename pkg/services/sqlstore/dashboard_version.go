@@ -2,7 +2,9 @@ package sqlstore
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/setting"
@@ -25,6 +27,10 @@ func (ss *SQLStore) GetDashboardVersion(ctx context.Context, query *models.GetDa
 		}
 
 		version.Data.Set("id", version.DashboardId)
+		version.Tags = make([]string, 0)
+		if err := sess.SQL("SELECT term FROM dashboard_version_tag WHERE dashboard_version_id=? ORDER BY term", version.Id).Find(&version.Tags); err != nil {
+			return err
+		}
 		query.Result = &version
 		return nil
 	})
@@ -61,6 +67,132 @@ func (ss *SQLStore) GetDashboardVersions(ctx context.Context, query *models.GetD
 		if len(query.Result) < 1 {
 			return models.ErrNoVersionsForDashboardId
 		}
+
+		var tags []*models.DashboardVersionTag
+		if err := sess.Where("dashboard_id=?", query.DashboardId).OrderBy("term").Find(&tags); err != nil {
+			return err
+		}
+		tagsByVersion := make(map[int64][]string)
+		for _, tag := range tags {
+			tagsByVersion[tag.DashboardVersionId] = append(tagsByVersion[tag.DashboardVersionId], tag.Term)
+		}
+		for _, version := range query.Result {
+			version.Tags = tagsByVersion[version.Id]
+			if version.Tags == nil {
+				version.Tags = make([]string, 0)
+			}
+		}
+		return nil
+	})
+}
+
+// AddDashboardVersionTag tags a dashboard version. Adding a tag that the version already has does nothing.
+func (ss *SQLStore) AddDashboardVersionTag(ctx context.Context, cmd *models.AddDashboardVersionTagCommand) error {
+	term := strings.TrimSpace(cmd.Tag)
+	if term == "" || len(term) > 50 {
+		return models.ErrDashboardVersionTagInvalid
+	}
+
+	return ss.WithTransactionalDbSession(ctx, func(sess *DBSession) error {
+		versionID, err := getDashboardVersionID(sess, cmd.OrgId, cmd.DashboardId, cmd.Version)
+		if err != nil {
+			return err
+		}
+
+		exists, err := sess.Where("dashboard_version_id=? AND term=?", versionID, term).Exist(&models.DashboardVersionTag{})
+		if err != nil || exists {
+			return err
+		}
+
+		_, err = sess.Insert(&models.DashboardVersionTag{DashboardVersionId: versionID, DashboardId: cmd.DashboardId, Term: term})
+		return err
+	})
+}
+
+// DeleteDashboardVersionTag removes a tag from a dashboard version.
+func (ss *SQLStore) DeleteDashboardVersionTag(ctx context.Context, cmd *models.DeleteDashboardVersionTagCommand) error {
+	return ss.WithTransactionalDbSession(ctx, func(sess *DBSession) error {
+		versionID, err := getDashboardVersionID(sess, cmd.OrgId, cmd.DashboardId, cmd.Version)
+		if err != nil {
+			return err
+		}
+
+		_, err = sess.Exec("DELETE FROM dashboard_version_tag WHERE dashboard_version_id=? AND term=?", versionID, cmd.Tag)
+		return err
+	})
+}
+
+func getDashboardVersionID(sess *DBSession, orgID int64, dashboardID int64, version int) (int64, error) {
+	var versionID int64
+	has, err := sess.SQL(`SELECT dashboard_version.id FROM dashboard_version
+		INNER JOIN dashboard ON dashboard.id = dashboard_version.dashboard_id
+		WHERE dashboard_version.dashboard_id=? AND dashboard_version.version=? AND dashboard.org_id=?`, dashboardID, version, orgID).
+		Get(&versionID)
+	if err != nil {
+		return 0, err
+	}
+	if !has {
+		return 0, models.ErrDashboardVersionNotFound
+	}
+	return versionID, nil
+}
+
+// GetDashboardVersionRetention gets the retention policy of the dashboard versions that overrides the default one for an organization.
+func (ss *SQLStore) GetDashboardVersionRetention(ctx context.Context, query *models.GetDashboardVersionRetentionQuery) error {
+	return ss.WithDbSession(ctx, func(sess *DBSession) error {
+		retention := models.DashboardVersionRetention{}
+		has, err := sess.Where("org_id=?", query.OrgId).Get(&retention)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrDashboardVersionRetentionNotFound
+		}
+
+		query.Result = &retention
+		return nil
+	})
+}
+
+// SaveDashboardVersionRetention overrides the default retention policy of the dashboard versions for an organization.
+// The durations must be validated with setting.ParseDashboardVersionRetention.
+func (ss *SQLStore) SaveDashboardVersionRetention(ctx context.Context, cmd *models.SaveDashboardVersionRetentionCommand) error {
+	return ss.WithTransactionalDbSession(ctx, func(sess *DBSession) error {
+		retention := models.DashboardVersionRetention{}
+		has, err := sess.Where("org_id=?", cmd.OrgId).Get(&retention)
+		if err != nil {
+			return err
+		}
+
+		retention.OrgId = cmd.OrgId
+		retention.KeepAll = cmd.KeepAll
+		retention.KeepDaily = cmd.KeepDaily
+		retention.KeepWeekly = cmd.KeepWeekly
+		retention.Updated = time.Now()
+		if has {
+			_, err = sess.ID(retention.Id).AllCols().Update(&retention)
+		} else {
+			_, err = sess.Insert(&retention)
+		}
+		if err != nil {
+			return err
+		}
+
+		cmd.Result = &retention
+		return nil
+	})
+}
+
+// DeleteDashboardVersionRetention restores the default retention policy of the dashboard versions for an organization.
+func (ss *SQLStore) DeleteDashboardVersionRetention(ctx context.Context, cmd *models.DeleteDashboardVersionRetentionCommand) error {
+	return ss.WithTransactionalDbSession(ctx, func(sess *DBSession) error {
+		affected, err := sess.Where("org_id=?", cmd.OrgId).Delete(&models.DashboardVersionRetention{})
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return models.ErrDashboardVersionRetentionNotFound
+		}
 		return nil
 	})
 }
@@ -68,17 +200,106 @@ func (ss *SQLStore) GetDashboardVersions(ctx context.Context, query *models.GetD
 const MAX_VERSIONS_TO_DELETE_PER_BATCH = 100
 const MAX_VERSION_DELETION_BATCHES = 50
 
+// DeleteExpiredVersions deletes the dashboard versions that the retention policy of their organization thins
+// out, or that are over the number of versions to keep when their organization has no policy. The latest
+// version of the dashboards, and the versions with protected tags, are never deleted.
 func (ss *SQLStore) DeleteExpiredVersions(ctx context.Context, cmd *models.DeleteExpiredVersionsCommand) error {
-	return ss.deleteExpiredVersions(ctx, cmd, MAX_VERSIONS_TO_DELETE_PER_BATCH, MAX_VERSION_DELETION_BATCHES)
+	return ss.deleteExpiredVersions(ctx, cmd, MAX_VERSIONS_TO_DELETE_PER_BATCH, MAX_VERSION_DELETION_BATCHES, time.Now())
 }
 
-func (ss *SQLStore) deleteExpiredVersions(ctx context.Context, cmd *models.DeleteExpiredVersionsCommand, perBatch int, maxBatches int) error {
+func (ss *SQLStore) deleteExpiredVersions(ctx context.Context, cmd *models.DeleteExpiredVersionsCommand, perBatch int, maxBatches int, now time.Time) error {
+	overrides, err := ss.getDashboardVersionRetentionOverrides(ctx)
+	if err != nil {
+		return err
+	}
+	defaultPolicy := ss.Cfg.DashboardVersionRetention
+
+	// the organizations with the default policy are the exception when it is disabled, and the rule otherwise
+	var policyOrgIDs, countOrgIDs []int64
+	for orgID, policy := range overrides {
+		if policy.Enabled() {
+			policyOrgIDs = append(policyOrgIDs, orgID)
+		} else {
+			countOrgIDs = append(countOrgIDs, orgID)
+		}
+	}
+
+	batches := 0
+	if !defaultPolicy.Enabled() || len(countOrgIDs) > 0 {
+		filter := SQLBuilder{}
+		if defaultPolicy.Enabled() {
+			filter.Write(" AND dashboard_version.dashboard_id IN (SELECT id FROM dashboard WHERE org_id IN (?"+strings.Repeat(",?", len(countOrgIDs)-1)+"))", int64sToParams(countOrgIDs)...)
+		} else if len(policyOrgIDs) > 0 {
+			filter.Write(" AND dashboard_version.dashboard_id NOT IN (SELECT id FROM dashboard WHERE org_id IN (?"+strings.Repeat(",?", len(policyOrgIDs)-1)+"))", int64sToParams(policyOrgIDs)...)
+		}
+
+		batches, err = ss.deleteVersionsOverCount(ctx, cmd, filter, perBatch, maxBatches)
+		if err != nil {
+			return err
+		}
+	}
+
+	if defaultPolicy.Enabled() {
+		policyOrgIDs = policyOrgIDs[:0]
+		err := ss.WithDbSession(ctx, func(sess *DBSession) error {
+			return sess.Table("org").Cols("id").OrderBy("id").Find(&policyOrgIDs)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, orgID := range policyOrgIDs {
+		policy, ok := overrides[orgID]
+		if !ok {
+			policy = defaultPolicy
+		}
+		if !policy.Enabled() || batches >= maxBatches {
+			continue
+		}
+
+		deleted, err := ss.thinOutVersions(ctx, cmd, orgID, policy, now, perBatch, maxBatches-batches)
+		if err != nil {
+			return err
+		}
+		batches += deleted
+	}
+
+	return nil
+}
+
+// getDashboardVersionRetentionOverrides gets the retention policies that override the default one, by organization.
+func (ss *SQLStore) getDashboardVersionRetentionOverrides(ctx context.Context) (map[int64]setting.DashboardVersionRetentionSettings, error) {
+	var retentions []*models.DashboardVersionRetention
+	err := ss.WithDbSession(ctx, func(sess *DBSession) error {
+		return sess.Find(&retentions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[int64]setting.DashboardVersionRetentionSettings, len(retentions))
+	for _, retention := range retentions {
+		policy, err := setting.ParseDashboardVersionRetention(retention.KeepAll, retention.KeepDaily, retention.KeepWeekly)
+		if err != nil {
+			ss.log.Warn("Ignoring invalid dashboard version retention", "orgId", retention.OrgId, "error", err)
+			continue
+		}
+		overrides[retention.OrgId] = policy
+	}
+	return overrides, nil
+}
+
+// deleteVersionsOverCount deletes the versions over the number of versions to keep, and returns the number of batches.
+func (ss *SQLStore) deleteVersionsOverCount(ctx context.Context, cmd *models.DeleteExpiredVersionsCommand, filter SQLBuilder, perBatch int, maxBatches int) (int, error) {
 	versionsToKeep := setting.DashboardVersionsToKeep
 	if versionsToKeep < 1 {
 		versionsToKeep = 1
 	}
 
-	for batch := 0; batch < maxBatches; batch++ {
+	batch := 0
+	for batch < maxBatches {
+		batch++
 		deleted := int64(0)
 
 		batchErr := ss.WithTransactionalDbSession(ctx, func(sess *DBSession) error {
@@ -86,39 +307,31 @@ func (ss *SQLStore) deleteExpiredVersions(ctx context.Context, cmd *models.Delet
 			// min_version_to_keep = min_version + (versions_count - versions_to_keep)
 			// where version stats is processed for each dashboard. This guarantees that we keep at least versions_to_keep
 			// versions, but in some cases (when versions are sparse) this number may be more.
-			versionIdsToDeleteQuery := `SELECT id
+			versionIdsToDeleteQuery := SQLBuilder{}
+			versionIdsToDeleteQuery.Write(`SELECT id
 				FROM dashboard_version, (
 					SELECT dashboard_id, count(version) as count, min(version) as min
 					FROM dashboard_version
 					GROUP BY dashboard_id
 				) AS vtd
 				WHERE dashboard_version.dashboard_id=vtd.dashboard_id
-				AND version < vtd.min + vtd.count - ?
-				LIMIT ?`
+				AND version < vtd.min + vtd.count - ?`, versionsToKeep)
+			versionIdsToDeleteQuery.Write(filter.GetSQLString(), filter.GetParams()...)
+			writeProtectedVersionsFilter(&versionIdsToDeleteQuery, ss.Cfg.DashboardVersionProtectedTags)
+			versionIdsToDeleteQuery.Write(" LIMIT ?", perBatch)
 
 			var versionIdsToDelete []interface{}
-			err := sess.SQL(versionIdsToDeleteQuery, versionsToKeep, perBatch).Find(&versionIdsToDelete)
+			err := sess.SQL(versionIdsToDeleteQuery.GetSQLString(), versionIdsToDeleteQuery.GetParams()...).Find(&versionIdsToDelete)
 			if err != nil {
 				return err
 			}
 
-			if len(versionIdsToDelete) < 1 {
-				return nil
-			}
-
-			deleteExpiredSQL := `DELETE FROM dashboard_version WHERE id IN (?` + strings.Repeat(",?", len(versionIdsToDelete)-1) + `)`
-			sqlOrArgs := append([]interface{}{deleteExpiredSQL}, versionIdsToDelete...)
-			expiredResponse, err := sess.Exec(sqlOrArgs...)
-			if err != nil {
-				return err
-			}
-
-			deleted, err = expiredResponse.RowsAffected()
+			deleted, err = deleteDashboardVersions(sess, versionIdsToDelete)
 			return err
 		})
 
 		if batchErr != nil {
-			return batchErr
+			return batch, batchErr
 		}
 
 		cmd.DeletedRows += deleted
@@ -128,5 +341,161 @@ func (ss *SQLStore) deleteExpiredVersions(ctx context.Context, cmd *models.Delet
 		}
 	}
 
-	return nil
+	return batch, nil
+}
+
+type dashboardVersionAge struct {
+	Id        int64
+	Created   time.Time
+	Protected int64
+}
+
+// thinOutVersions deletes the versions of the dashboards of an organization that its retention policy doesn't keep,
+// and returns the number of batches.
+func (ss *SQLStore) thinOutVersions(ctx context.Context, cmd *models.DeleteExpiredVersionsCommand, orgID int64, policy setting.DashboardVersionRetentionSettings, now time.Time, perBatch int, maxBatches int) (int, error) {
+	var dashboardIDs []int64
+	err := ss.WithDbSession(ctx, func(sess *DBSession) error {
+		return sess.SQL(`SELECT DISTINCT dashboard_version.dashboard_id FROM dashboard_version
+			INNER JOIN dashboard ON dashboard.id = dashboard_version.dashboard_id
+			WHERE dashboard.org_id=? AND dashboard_version.created < ?
+			ORDER BY dashboard_version.dashboard_id`, orgID, now.Add(-policy.KeepAll)).Find(&dashboardIDs)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var versionIdsToDelete []interface{}
+	for _, dashboardID := range dashboardIDs {
+		if len(versionIdsToDelete) >= perBatch*maxBatches {
+			break
+		}
+
+		// the latest version is kept, whatever its age
+		query := SQLBuilder{}
+		query.Write(`SELECT id, created`)
+		writeProtectedVersionsColumn(&query, ss.Cfg.DashboardVersionProtectedTags)
+		query.Write(` FROM dashboard_version
+			WHERE dashboard_id=? AND created < ?
+			AND version < (SELECT MAX(version) FROM dashboard_version AS latest WHERE latest.dashboard_id=?)
+			ORDER BY created DESC, version DESC`, dashboardID, now.Add(-policy.KeepAll), dashboardID)
+
+		var versions []dashboardVersionAge
+		err := ss.WithDbSession(ctx, func(sess *DBSession) error {
+			return sess.SQL(query.GetSQLString(), query.GetParams()...).Find(&versions)
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		versionIdsToDelete = append(versionIdsToDelete, versionsNotRetained(versions, policy, now)...)
+	}
+	if len(versionIdsToDelete) > perBatch*maxBatches {
+		versionIdsToDelete = versionIdsToDelete[:perBatch*maxBatches]
+	}
+
+	batches := 0
+	for start := 0; start < len(versionIdsToDelete); start += perBatch {
+		end := start + perBatch
+		if end > len(versionIdsToDelete) {
+			end = len(versionIdsToDelete)
+		}
+
+		var deleted int64
+		err := ss.WithTransactionalDbSession(ctx, func(sess *DBSession) error {
+			var err error
+			deleted, err = deleteDashboardVersions(sess, versionIdsToDelete[start:end])
+			return err
+		})
+		if err != nil {
+			return batches, err
+		}
+		batches++
+		cmd.DeletedRows += deleted
+	}
+	return batches, nil
+}
+
+// versionsNotRetained returns the ids of the versions, latest first, that are not the latest version of their day
+// or week when the policy keeps it, and aren't protected.
+func versionsNotRetained(versions []dashboardVersionAge, policy setting.DashboardVersionRetentionSettings, now time.Time) []interface{} {
+	var ids []interface{}
+	keptDays := make(map[string]bool)
+	keptWeeks := make(map[string]bool)
+	for _, version := range versions {
+		age := now.Sub(version.Created)
+		created := version.Created.UTC()
+
+		var kept map[string]bool
+		var period string
+		switch {
+		case age < policy.KeepAll:
+			continue
+		case age < policy.KeepDaily:
+			kept, period = keptDays, created.Format("2006-01-02")
+		case age < policy.KeepWeekly:
+			year, week := created.ISOWeek()
+			kept, period = keptWeeks, fmt.Sprintf("%d-%d", year, week)
+		}
+
+		if kept != nil && !kept[period] {
+			kept[period] = true
+			continue
+		}
+		if version.Protected == 0 {
+			ids = append(ids, version.Id)
+		}
+	}
+	return ids
+}
+
+func writeProtectedVersionsFilter(sb *SQLBuilder, protectedTags []string) {
+	if len(protectedTags) == 0 {
+		return
+	}
+	sb.Write(` AND NOT EXISTS (SELECT 1 FROM dashboard_version_tag
+		WHERE dashboard_version_tag.dashboard_version_id = dashboard_version.id
+		AND dashboard_version_tag.term IN (?`+strings.Repeat(",?", len(protectedTags)-1)+`))`, stringsToParams(protectedTags)...)
+}
+
+func writeProtectedVersionsColumn(sb *SQLBuilder, protectedTags []string) {
+	if len(protectedTags) == 0 {
+		sb.Write(`, 0 AS protected`)
+		return
+	}
+	sb.Write(`, (SELECT COUNT(*) FROM dashboard_version_tag
+		WHERE dashboard_version_tag.dashboard_version_id = dashboard_version.id
+		AND dashboard_version_tag.term IN (?`+strings.Repeat(",?", len(protectedTags)-1)+`)) AS protected`, stringsToParams(protectedTags)...)
+}
+
+// deleteDashboardVersions deletes the versions with their tags, and returns the number of deleted versions.
+func deleteDashboardVersions(sess *DBSession, versionIDs []interface{}) (int64, error) {
+	if len(versionIDs) < 1 {
+		return 0, nil
+	}
+
+	ids := "(?" + strings.Repeat(",?", len(versionIDs)-1) + ")"
+	if _, err := sess.Exec(append([]interface{}{"DELETE FROM dashboard_version_tag WHERE dashboard_version_id IN " + ids}, versionIDs...)...); err != nil {
+		return 0, err
+	}
+	res, err := sess.Exec(append([]interface{}{"DELETE FROM dashboard_version WHERE id IN " + ids}, versionIDs...)...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func int64sToParams(values []int64) []interface{} {
+	params := make([]interface{}, len(values))
+	for i, value := range values {
+		params[i] = value
+	}
+	return params
+}
+
+func stringsToParams(values []string) []interface{} {
+	params := make([]interface{}, len(values))
+	for i, value := range values {
+		params[i] = value
+	}
+	return params
 }
@@ -209,7 +209,7 @@ func TestDeleteExpiredVersions(t *testing.T) {
 			})
 		}
 
-		err := sqlStore.deleteExpiredVersions(context.Background(), &models.DeleteExpiredVersionsCommand{}, perBatch, maxBatches)
+		err := sqlStore.deleteExpiredVersions(context.Background(), &models.DeleteExpiredVersionsCommand{}, perBatch, maxBatches, time.Now())
 		require.Nil(t, err)
 
 		query := models.GetDashboardVersionsQuery{DashboardId: savedDash.Id, OrgId: 1, Limit: versionsToWriteBigNumber}
@@ -222,3 +222,138 @@ func TestDeleteExpiredVersions(t *testing.T) {
 		require.LessOrEqual(t, versionsToWriteBigNumber-len(query.Result), perBatch*maxBatches)
 	})
 }
+
+func TestDeleteExpiredVersionsWithRetentionPolicy(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 5, 20, 12, 0, 0, 0, time.UTC)
+	sqlStore := InitTestDB(t)
+	sqlStore.Cfg.DashboardVersionRetention = setting.DashboardVersionRetentionSettings{
+		KeepAll:    2 * 24 * time.Hour,
+		KeepDaily:  7 * 24 * time.Hour,
+		KeepWeekly: 28 * 24 * time.Hour,
+	}
+	sqlStore.Cfg.DashboardVersionProtectedTags = []string{"release"}
+	setting.DashboardVersionsToKeep = 2
+
+	for _, name := range []string{"policy", "count"} {
+		require.NoError(t, sqlStore.CreateOrg(ctx, &models.CreateOrgCommand{Name: name}))
+	}
+	require.NoError(t, sqlStore.SaveDashboardVersionRetention(ctx, &models.SaveDashboardVersionRetentionCommand{OrgId: 2}))
+
+	insertVersions := func(dash *models.Dashboard, created ...time.Time) {
+		t.Helper()
+		err := sqlStore.WithDbSession(ctx, func(sess *DBSession) error {
+			if _, err := sess.Exec("UPDATE dashboard_version SET created=? WHERE dashboard_id=?", created[0], dash.Id); err != nil {
+				return err
+			}
+			for i, c := range created[1:] {
+				if _, err := sess.Insert(&models.DashboardVersion{DashboardId: dash.Id, Version: i + 2, Created: c, Data: dash.Data}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	tag := func(dash *models.Dashboard, version int, tag string) {
+		t.Helper()
+		err := sqlStore.AddDashboardVersionTag(ctx, &models.AddDashboardVersionTagCommand{OrgId: dash.OrgId, DashboardId: dash.Id, Version: version, Tag: tag})
+		require.NoError(t, err)
+	}
+	versions := func(dash *models.Dashboard) []int {
+		t.Helper()
+		query := models.GetDashboardVersionsQuery{DashboardId: dash.Id, OrgId: dash.OrgId}
+		require.NoError(t, sqlStore.GetDashboardVersions(ctx, &query))
+		var result []int
+		for _, version := range query.Result {
+			result = append(result, version.Version)
+		}
+		return result
+	}
+
+	thinned := insertTestDashboard(t, sqlStore, "thinned", 1, 0, false)
+	insertVersions(thinned,
+		now.Add(-60*24*time.Hour),            // older than the weekly versions
+		now.Add(-20*24*time.Hour),            // not the latest of its week
+		now.Add(-20*24*time.Hour+time.Hour),  // latest of its week
+		now.Add(-5*24*time.Hour),             // not the latest of its day
+		now.Add(-5*24*time.Hour+time.Hour),   // not the latest of its day, but a release
+		now.Add(-5*24*time.Hour+2*time.Hour), // latest of its day
+		now.Add(-24*time.Hour),               // younger than the daily versions
+		now.Add(-3*time.Hour),
+	)
+	tag(thinned, 1, "draft")
+	tag(thinned, 5, "release")
+
+	old := insertTestDashboard(t, sqlStore, "old", 1, 0, false)
+	insertVersions(old, now.Add(-100*24*time.Hour))
+
+	counted := insertTestDashboard(t, sqlStore, "counted", 2, 0, false)
+	insertVersions(counted, now, now, now, now, now)
+	tag(counted, 1, "release")
+
+	cmd := models.DeleteExpiredVersionsCommand{}
+	err := sqlStore.deleteExpiredVersions(ctx, &cmd, MAX_VERSIONS_TO_DELETE_PER_BATCH, MAX_VERSION_DELETION_BATCHES, now)
+	require.NoError(t, err)
+	require.Equal(t, int64(5), cmd.DeletedRows)
+
+	require.Equal(t, []int{8, 7, 6, 5, 3}, versions(thinned))
+	require.Equal(t, []int{1}, versions(old))
+	require.Equal(t, []int{5, 4, 1}, versions(counted))
+
+	var tags int64
+	err = sqlStore.WithDbSession(ctx, func(sess *DBSession) error {
+		var err error
+		tags, err = sess.Where("term=?", "draft").Count(&models.DashboardVersionTag{})
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(0), tags)
+}
+
+func TestDashboardVersionTags(t *testing.T) {
+	ctx := context.Background()
+	sqlStore := InitTestDB(t)
+	dash := insertTestDashboard(t, sqlStore, "tagged", 1, 0, false)
+
+	cmd := models.AddDashboardVersionTagCommand{OrgId: 1, DashboardId: dash.Id, Version: 1, Tag: "release"}
+	require.NoError(t, sqlStore.AddDashboardVersionTag(ctx, &cmd))
+	// tagging again does nothing
+	require.NoError(t, sqlStore.AddDashboardVersionTag(ctx, &cmd))
+
+	query := models.GetDashboardVersionQuery{DashboardId: dash.Id, OrgId: 1, Version: 1}
+	require.NoError(t, sqlStore.GetDashboardVersion(ctx, &query))
+	require.Equal(t, []string{"release"}, query.Result.Tags)
+
+	cmd.Tag = " "
+	require.ErrorIs(t, sqlStore.AddDashboardVersionTag(ctx, &cmd), models.ErrDashboardVersionTagInvalid)
+	cmd.Tag, cmd.OrgId = "release", 2
+	require.ErrorIs(t, sqlStore.AddDashboardVersionTag(ctx, &cmd), models.ErrDashboardVersionNotFound)
+
+	err := sqlStore.DeleteDashboardVersionTag(ctx, &models.DeleteDashboardVersionTagCommand{OrgId: 1, DashboardId: dash.Id, Version: 1, Tag: "release"})
+	require.NoError(t, err)
+	versionsQuery := models.GetDashboardVersionsQuery{DashboardId: dash.Id, OrgId: 1}
+	require.NoError(t, sqlStore.GetDashboardVersions(ctx, &versionsQuery))
+	require.Empty(t, versionsQuery.Result[0].Tags)
+}
+
+func TestDashboardVersionRetention(t *testing.T) {
+	ctx := context.Background()
+	sqlStore := InitTestDB(t)
+
+	query := models.GetDashboardVersionRetentionQuery{OrgId: 1}
+	require.ErrorIs(t, sqlStore.GetDashboardVersionRetention(ctx, &query), models.ErrDashboardVersionRetentionNotFound)
+
+	cmd := models.SaveDashboardVersionRetentionCommand{OrgId: 1, KeepAll: "7d", KeepDaily: "30d"}
+	require.NoError(t, sqlStore.SaveDashboardVersionRetention(ctx, &cmd))
+	cmd.KeepWeekly = "1y"
+	require.NoError(t, sqlStore.SaveDashboardVersionRetention(ctx, &cmd))
+
+	require.NoError(t, sqlStore.GetDashboardVersionRetention(ctx, &query))
+	require.Equal(t, "7d", query.Result.KeepAll)
+	require.Equal(t, "30d", query.Result.KeepDaily)
+	require.Equal(t, "1y", query.Result.KeepWeekly)
+
+	require.NoError(t, sqlStore.DeleteDashboardVersionRetention(ctx, &models.DeleteDashboardVersionRetentionCommand{OrgId: 1}))
+	require.ErrorIs(t, sqlStore.DeleteDashboardVersionRetention(ctx, &models.DeleteDashboardVersionRetentionCommand{OrgId: 1}), models.ErrDashboardVersionRetentionNotFound)
+}
@@ -113,6 +113,11 @@ func (ss *SQLStore) DeleteDataSource(ctx context.Context, cmd *models.DeleteData
 				ac.Scope("datasources", "id", fmt.Sprint(dsQuery.Result.Id))); errDeletingPerms != nil {
 				return errDeletingPerms
 			}
+
+			// Remove the label rules of the data source
+			if _, err := sess.Exec("DELETE FROM datasource_label_rule WHERE org_id=? AND datasource_uid=?", ds.OrgId, ds.Uid); err != nil {
+				return err
+			}
 		}
 
 		// Publish data source deletion event
@@ -58,6 +58,17 @@ func (ss *SQLStore) DeleteOldLoginAttempts(ctx context.Context, cmd *models.Dele
 	})
 }
 
+func (ss *SQLStore) DeleteUserLoginAttempts(ctx context.Context, cmd *models.DeleteUserLoginAttemptsCommand) error {
+	if len(cmd.Usernames) == 0 {
+		return nil
+	}
+
+	return ss.WithDbSession(ctx, func(sess *DBSession) error {
+		_, err := sess.In("username", cmd.Usernames).Delete(&models.LoginAttempt{})
+		return err
+	})
+}
+
 func (ss *SQLStore) GetUserLoginAttemptCount(ctx context.Context, query *models.GetUserLoginAttemptCountQuery) error {
 	return ss.WithDbSession(ctx, func(dbSession *DBSession) error {
 		loginAttempt := new(models.LoginAttempt)
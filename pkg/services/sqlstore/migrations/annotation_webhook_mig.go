@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addAnnotationWebhookMigrations(mg *Migrator) {
+	annotationWebhookV1 := Table{
+		Name: "annotation_webhook",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "name", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "adapter", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "mapping", Type: DB_Text, Nullable: true},
+			{Name: "tags", Type: DB_Text, Nullable: true},
+			{Name: "dashboard_rules", Type: DB_Text, Nullable: true},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "uid"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create annotation_webhook table v1", NewAddTableMigration(annotationWebhookV1))
+	addTableIndicesMigrations(mg, "v1", annotationWebhookV1)
+
+	annotationWebhookRegionV1 := Table{
+		Name: "annotation_webhook_region",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "webhook_id", Type: DB_BigInt, Nullable: false},
+			{Name: "event_id", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "annotation_id", Type: DB_BigInt, Nullable: false},
+			{Name: "closed", Type: DB_Bool, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"webhook_id", "event_id"}, Type: UniqueIndex},
+			{Cols: []string{"updated"}},
+		},
+	}
+
+	mg.AddMigration("create annotation_webhook_region table v1", NewAddTableMigration(annotationWebhookRegionV1))
+	addTableIndicesMigrations(mg, "v1", annotationWebhookRegionV1)
+}
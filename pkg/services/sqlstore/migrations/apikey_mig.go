@@ -91,4 +91,20 @@ func addApiKeyMigrations(mg *Migrator) {
 
 	mg.AddMigration("set service account foreign key to nil if 0", NewRawSQLMigration(
 		"UPDATE api_key SET service_account_id = NULL WHERE service_account_id = 0;"))
+
+	mg.AddMigration("Add permissions to api_key table", NewAddColumnMigration(apiKeyV2, &Column{
+		Name: "permissions", Type: DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("Add ip_allow_list to api_key table", NewAddColumnMigration(apiKeyV2, &Column{
+		Name: "ip_allow_list", Type: DB_Text, Nullable: true,
+	}))
+
+	mg.AddMigration("Add last_used_at to api_key table", NewAddColumnMigration(apiKeyV2, &Column{
+		Name: "last_used_at", Type: DB_DateTime, Nullable: true,
+	}))
+
+	mg.AddMigration("Add last_used_ip to api_key table", NewAddColumnMigration(apiKeyV2, &Column{
+		Name: "last_used_ip", Type: DB_NVarchar, Length: 255, Nullable: true,
+	}))
 }
@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addCorrelationsMigrations(mg *Migrator) {
+	correlationV1 := Table{
+		Name: "correlation",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "source_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "target_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "label", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "description", Type: DB_Text, Nullable: false},
+			{Name: "field", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "query", Type: DB_Text, Nullable: false},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+			{Name: "updated", Type: DB_BigInt, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "uid"}, Type: UniqueIndex},
+			{Cols: []string{"org_id", "source_uid"}},
+		},
+	}
+
+	mg.AddMigration("create correlation table v1", NewAddTableMigration(correlationV1))
+	addTableIndicesMigrations(mg, "v1", correlationV1)
+}
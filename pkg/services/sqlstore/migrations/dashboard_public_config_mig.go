@@ -23,4 +23,34 @@ func addPublicDashboardMigration(mg *Migrator) {
 
 	mg.AddMigration("create dashboard public config v1", NewAddTableMigration(dashboardPublicCfgV1))
 	addTableIndicesMigrations(mg, "v1", dashboardPublicCfgV1)
+
+	// v1 was never used, public dashboards are shared with an access token since v2
+	mg.AddMigration("drop dashboard public config v1", NewDropTableMigration("dashboard_public_config"))
+
+	var dashboardPublicCfgV2 = Table{
+		Name: "dashboard_public_config",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "dashboard_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "access_token", Type: DB_NVarchar, Length: 64, Nullable: false},
+			{Name: "is_enabled", Type: DB_Bool, Nullable: false, Default: "0"},
+			{Name: "time_settings", Type: DB_Text, Nullable: true},
+			{Name: "created_by", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated_by", Type: DB_BigInt, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+			{Name: "revoked_by", Type: DB_BigInt, Nullable: true},
+			{Name: "revoked", Type: DB_DateTime, Nullable: true},
+		},
+		Indices: []*Index{
+			{Cols: []string{"uid"}, Type: UniqueIndex},
+			{Cols: []string{"access_token"}, Type: UniqueIndex},
+			{Cols: []string{"org_id", "dashboard_uid"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create dashboard public config v2", NewAddTableMigration(dashboardPublicCfgV2))
+	addTableIndicesMigrations(mg, "v2", dashboardPublicCfgV2)
 }
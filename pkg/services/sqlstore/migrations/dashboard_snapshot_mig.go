@@ -71,4 +71,12 @@ func addDashboardSnapshotMigrations(mg *Migrator) {
 
 	mg.AddMigration("Change dashboard_encrypted column to MEDIUMBLOB", NewRawSQLMigration("").
 		Mysql("ALTER TABLE dashboard_snapshot MODIFY dashboard_encrypted MEDIUMBLOB;"))
+
+	mg.AddMigration("Add column blob_key to dashboard_snapshot table", NewAddColumnMigration(snapshotV5, &Column{
+		Name: "blob_key", Type: DB_NVarchar, Length: 190, Nullable: true,
+	}))
+
+	mg.AddMigration("Add column blob_size to dashboard_snapshot table", NewAddColumnMigration(snapshotV5, &Column{
+		Name: "blob_size", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
 }
@@ -57,3 +57,41 @@ FROM dashboard;`
 	mg.AddMigration("alter dashboard_version.data to mediumtext v1", NewRawSQLMigration("").
 		Mysql("ALTER TABLE dashboard_version MODIFY data MEDIUMTEXT;"))
 }
+
+func addDashboardVersionRetentionMigrations(mg *Migrator) {
+	dashboardVersionTagV1 := Table{
+		Name: "dashboard_version_tag",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "dashboard_version_id", Type: DB_BigInt, Nullable: false},
+			{Name: "dashboard_id", Type: DB_BigInt, Nullable: false},
+			{Name: "term", Type: DB_NVarchar, Length: 50, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"dashboard_version_id", "term"}, Type: UniqueIndex},
+			{Cols: []string{"dashboard_id"}},
+			{Cols: []string{"term"}},
+		},
+	}
+
+	mg.AddMigration("create dashboard_version_tag table v1", NewAddTableMigration(dashboardVersionTagV1))
+	addTableIndicesMigrations(mg, "v1", dashboardVersionTagV1)
+
+	dashboardVersionRetentionV1 := Table{
+		Name: "dashboard_version_retention",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "keep_all", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "keep_daily", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "keep_weekly", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create dashboard_version_retention table v1", NewAddTableMigration(dashboardVersionRetentionV1))
+	addTableIndicesMigrations(mg, "v1", dashboardVersionRetentionV1)
+}
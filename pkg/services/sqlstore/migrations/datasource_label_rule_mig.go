@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addDatasourceLabelRuleMigrations(mg *Migrator) {
+	datasourceLabelRuleV1 := Table{
+		Name: "datasource_label_rule",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "datasource_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "team_id", Type: DB_BigInt, Nullable: false},
+			{Name: "label_name", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "label_value", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "datasource_uid", "team_id", "label_name", "label_value"}, Type: UniqueIndex},
+			{Cols: []string{"org_id", "team_id"}},
+		},
+	}
+
+	mg.AddMigration("create datasource_label_rule table v1", NewAddTableMigration(datasourceLabelRuleV1))
+	addTableIndicesMigrations(mg, "v1", datasourceLabelRuleV1)
+}
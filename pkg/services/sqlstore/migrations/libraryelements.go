@@ -58,4 +58,33 @@ func addLibraryElementsMigrations(mg *migrator.Migrator) {
 	mg.AddMigration("increase max description length to 2048", migrator.NewTableCharsetMigration("library_element", []*migrator.Column{
 		{Name: "description", Type: migrator.DB_NVarchar, Length: 2048, Nullable: false},
 	}))
+
+	libraryElementVersionV1 := migrator.Table{
+		Name: "library_element_version",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "element_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "version", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "restored_from", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "name", Type: migrator.DB_NVarchar, Length: 150, Nullable: false},
+			{Name: "type", Type: migrator.DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "description", Type: migrator.DB_NVarchar, Length: 2048, Nullable: false},
+			{Name: "model", Type: migrator.DB_Text, Nullable: false},
+			{Name: "created", Type: migrator.DB_DateTime, Nullable: false},
+			{Name: "created_by", Type: migrator.DB_BigInt, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"element_id", "version"}, Type: migrator.UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create library_element_version table v1", migrator.NewAddTableMigration(libraryElementVersionV1))
+	mg.AddMigration("add unique index library_element_version element_id-version", migrator.NewAddIndexMigration(libraryElementVersionV1, libraryElementVersionV1.Indices[0]))
+	mg.AddMigration("copy current library elements to library_element_version", migrator.NewRawSQLMigration(`
+		INSERT INTO library_element_version (element_id, version, restored_from, name, type, description, model, created, created_by)
+		SELECT id, version, 0, name, type, description, model, updated, updated_by FROM library_element`))
+
+	mg.AddMigration("add pinned_version column to "+models.LibraryElementConnectionTableName, migrator.NewAddColumnMigration(libraryElementConnectionV1, &migrator.Column{
+		Name: "pinned_version", Type: migrator.DB_BigInt, Nullable: false, Default: "0",
+	}))
 }
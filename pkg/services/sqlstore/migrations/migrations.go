@@ -94,6 +94,28 @@ func (*OSSMigrations) AddMigration(mg *Migrator) {
 	addEntityEventsTableMigration(mg)
 
 	addPublicDashboardMigration(mg)
+
+	addQueryAuditMigrations(mg)
+
+	addCorrelationsMigrations(mg)
+
+	addSCIMMigrations(mg)
+
+	addTOTPMigrations(mg)
+
+	addTeamGroupMigrations(mg)
+
+	addDatasourceLabelRuleMigrations(mg)
+
+	addPasswordHistoryMigrations(mg)
+
+	addReportMigrations(mg)
+
+	addAnnotationWebhookMigrations(mg)
+
+	addDashboardVersionRetentionMigrations(mg)
+
+	addDashboardLintMigrations(mg)
 }
 
 func addMigrationLogMigrations(mg *Migrator) {
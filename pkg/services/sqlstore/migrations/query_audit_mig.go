@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addQueryAuditMigrations(mg *Migrator) {
+	queryAuditV1 := Table{
+		Name: "query_audit",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "time", Type: DB_DateTime, Nullable: false},
+			{Name: "source", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_login", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "datasource_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "datasource_type", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "dashboard_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "dashboard_id", Type: DB_BigInt, Nullable: false},
+			{Name: "panel_id", Type: DB_BigInt, Nullable: false},
+			{Name: "query", Type: DB_MediumText, Nullable: false},
+			{Name: "duration_ms", Type: DB_BigInt, Nullable: false},
+			{Name: "bytes_returned", Type: DB_BigInt, Nullable: false},
+			{Name: "error", Type: DB_Text, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"time"}},
+			{Cols: []string{"org_id", "time"}},
+			{Cols: []string{"user_id", "time"}},
+			{Cols: []string{"datasource_uid", "time"}},
+		},
+	}
+
+	mg.AddMigration("create query_audit table v1", NewAddTableMigration(queryAuditV1))
+	addTableIndicesMigrations(mg, "v1", queryAuditV1)
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addReportMigrations(mg *Migrator) {
+	reportV1 := Table{
+		Name: "report",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "name", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "dashboard_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "time_from", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "time_to", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "variables", Type: DB_Text, Nullable: true},
+			{Name: "schedule", Type: DB_NVarchar, Length: 100, Nullable: false},
+			{Name: "timezone", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "recipients", Type: DB_Text, Nullable: false},
+			{Name: "formats", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "message", Type: DB_Text, Nullable: true},
+			{Name: "next_run", Type: DB_DateTime, Nullable: false},
+			{Name: "last_run", Type: DB_DateTime, Nullable: true},
+			{Name: "last_error", Type: DB_Text, Nullable: true},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id"}},
+			{Cols: []string{"next_run"}},
+		},
+	}
+
+	mg.AddMigration("create report table v1", NewAddTableMigration(reportV1))
+	addTableIndicesMigrations(mg, "v1", reportV1)
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addSCIMMigrations(mg *Migrator) {
+	scimExternalIDV1 := Table{
+		Name: "scim_external_id",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "resource_type", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "resource_id", Type: DB_BigInt, Nullable: false},
+			{Name: "external_id", Type: DB_NVarchar, Length: 190, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "resource_type", "resource_id"}, Type: UniqueIndex},
+			{Cols: []string{"org_id", "resource_type", "external_id"}},
+		},
+	}
+
+	mg.AddMigration("create scim_external_id table v1", NewAddTableMigration(scimExternalIDV1))
+	addTableIndicesMigrations(mg, "v1", scimExternalIDV1)
+}
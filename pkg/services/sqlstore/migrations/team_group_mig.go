@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addTeamGroupMigrations(mg *Migrator) {
+	teamGroupV1 := Table{
+		Name: "team_group",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "team_id", Type: DB_BigInt, Nullable: false},
+			{Name: "group_id", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "team_id", "group_id"}, Type: UniqueIndex},
+			{Cols: []string{"org_id", "group_id"}},
+		},
+	}
+
+	mg.AddMigration("create team_group table v1", NewAddTableMigration(teamGroupV1))
+	addTableIndicesMigrations(mg, "v1", teamGroupV1)
+}
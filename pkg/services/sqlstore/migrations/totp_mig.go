@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addTOTPMigrations(mg *Migrator) {
+	userTOTPV1 := Table{
+		Name: "user_totp",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "secret", Type: DB_Text, Nullable: false},
+			{Name: "enabled", Type: DB_Bool, Nullable: false},
+			{Name: "recovery_codes", Type: DB_Text, Nullable: false},
+			{Name: "last_used_step", Type: DB_BigInt, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"user_id"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create user_totp table v1", NewAddTableMigration(userTOTPV1))
+	addTableIndicesMigrations(mg, "v1", userTOTPV1)
+
+	// login challenges waiting for their second factor, the token is hashed
+	challengeV1 := Table{
+		Name: "user_totp_challenge",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "token", Type: DB_NVarchar, Length: 64, Nullable: false},
+			{Name: "user_id", Type: DB_BigInt, Nullable: false},
+			{Name: "attempts", Type: DB_Int, Nullable: false},
+			{Name: "expires", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"token"}, Type: UniqueIndex},
+			{Cols: []string{"expires"}},
+		},
+	}
+
+	mg.AddMigration("create user_totp_challenge table v1", NewAddTableMigration(challengeV1))
+	addTableIndicesMigrations(mg, "v1", challengeV1)
+}
@@ -44,4 +44,32 @@ func addUserAuthTokenMigrations(mg *Migrator) {
 			},
 		),
 	)
+
+	mg.AddMigration(
+		"Add last_active_at to the user auth token",
+		NewAddColumnMigration(
+			userAuthTokenV1,
+			&Column{
+				Name:     "last_active_at",
+				Type:     DB_Int,
+				Nullable: false,
+				Default:  "0",
+			},
+		),
+	)
+
+	mg.AddMigration("Set last_active_at of the user auth tokens", NewRawSQLMigration("UPDATE user_auth_token SET last_active_at = rotated_at"))
+
+	mg.AddMigration(
+		"Add revoked_reason to the user auth token",
+		NewAddColumnMigration(
+			userAuthTokenV1,
+			&Column{
+				Name:     "revoked_reason",
+				Type:     DB_NVarchar,
+				Length:   50,
+				Nullable: true,
+			},
+		),
+	)
 }
@@ -12,9 +12,10 @@ type OrgListResponse []struct {
 	Response error
 }
 type SQLStoreMock struct {
-	LastGetAlertsQuery      *models.GetAlertsQuery
-	LastLoginAttemptCommand *models.CreateLoginAttemptCommand
-	LatestUserId            int64
+	LastGetAlertsQuery                 *models.GetAlertsQuery
+	LastLoginAttemptCommand            *models.CreateLoginAttemptCommand
+	LastDeleteUserLoginAttemptsCommand *models.DeleteUserLoginAttemptsCommand
+	LatestUserId                       int64
 
 	ExpectedUser                   *models.User
 	ExpectedDatasource             *models.DataSource
@@ -101,6 +102,18 @@ func (m *SQLStoreMock) SearchDashboardSnapshots(ctx context.Context, query *mode
 	return m.ExpectedError
 }
 
+func (m *SQLStoreMock) GetDashboardSnapshotsSize(ctx context.Context, query *models.GetDashboardSnapshotsSizeQuery) error {
+	return m.ExpectedError
+}
+
+func (m *SQLStoreMock) GetDashboardSnapshotsWithoutBlob(ctx context.Context, query *models.GetDashboardSnapshotsWithoutBlobQuery) error {
+	return m.ExpectedError
+}
+
+func (m *SQLStoreMock) SetDashboardSnapshotBlob(ctx context.Context, cmd *models.SetDashboardSnapshotBlobCommand) error {
+	return m.ExpectedError
+}
+
 func (m *SQLStoreMock) GetOrgById(ctx context.Context, cmd *models.GetOrgByIdQuery) error {
 	return m.ExpectedError
 }
@@ -151,6 +164,11 @@ func (m *SQLStoreMock) DeleteOldLoginAttempts(ctx context.Context, cmd *models.D
 	return m.ExpectedError
 }
 
+func (m *SQLStoreMock) DeleteUserLoginAttempts(ctx context.Context, cmd *models.DeleteUserLoginAttemptsCommand) error {
+	m.LastDeleteUserLoginAttemptsCommand = cmd
+	return m.ExpectedError
+}
+
 func (m *SQLStoreMock) CreateUser(ctx context.Context, cmd models.CreateUserCommand) (*models.User, error) {
 	return nil, m.ExpectedError
 }
@@ -381,6 +399,26 @@ func (m *SQLStoreMock) DeleteExpiredVersions(ctx context.Context, cmd *models.De
 	return m.ExpectedError
 }
 
+func (m *SQLStoreMock) AddDashboardVersionTag(ctx context.Context, cmd *models.AddDashboardVersionTagCommand) error {
+	return m.ExpectedError
+}
+
+func (m *SQLStoreMock) DeleteDashboardVersionTag(ctx context.Context, cmd *models.DeleteDashboardVersionTagCommand) error {
+	return m.ExpectedError
+}
+
+func (m *SQLStoreMock) GetDashboardVersionRetention(ctx context.Context, query *models.GetDashboardVersionRetentionQuery) error {
+	return m.ExpectedError
+}
+
+func (m *SQLStoreMock) SaveDashboardVersionRetention(ctx context.Context, cmd *models.SaveDashboardVersionRetentionCommand) error {
+	return m.ExpectedError
+}
+
+func (m *SQLStoreMock) DeleteDashboardVersionRetention(ctx context.Context, cmd *models.DeleteDashboardVersionRetentionCommand) error {
+	return m.ExpectedError
+}
+
 func (m SQLStoreMock) GetDashboardAclInfoList(ctx context.Context, query *models.GetDashboardAclInfoListQuery) error {
 	query.Result = m.ExpectedDashboardAclInfoList
 	return m.ExpectedError
@@ -614,6 +652,10 @@ func (m *SQLStoreMock) GetApiKeyByName(ctx context.Context, query *models.GetApi
 	return m.ExpectedError
 }
 
+func (m *SQLStoreMock) UpdateApiKeyLastUsed(ctx context.Context, cmd *models.UpdateApiKeyLastUsedCommand) error {
+	return m.ExpectedError
+}
+
 func (m *SQLStoreMock) UpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand) error {
 	return m.ExpectedError
 }
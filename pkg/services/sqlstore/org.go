@@ -230,6 +230,7 @@ func (ss *SQLStore) DeleteOrg(ctx context.Context, cmd *models.DeleteOrgCommand)
 		deletes := []string{
 			"DELETE FROM star WHERE EXISTS (SELECT 1 FROM dashboard WHERE org_id = ? AND star.dashboard_id = dashboard.id)",
 			"DELETE FROM dashboard_tag WHERE EXISTS (SELECT 1 FROM dashboard WHERE org_id = ? AND dashboard_tag.dashboard_id = dashboard.id)",
+			"DELETE FROM dashboard_version_tag WHERE EXISTS (SELECT 1 FROM dashboard WHERE org_id = ? AND dashboard_version_tag.dashboard_id = dashboard.id)",
 			"DELETE FROM dashboard WHERE org_id = ?",
 			"DELETE FROM api_key WHERE org_id = ?",
 			"DELETE FROM data_source WHERE org_id = ?",
@@ -247,6 +248,14 @@ func (ss *SQLStore) DeleteOrg(ctx context.Context, cmd *models.DeleteOrgCommand)
 			"DELETE FROM alert WHERE org_id = ?",
 			"DELETE FROM annotation WHERE org_id = ?",
 			"DELETE FROM kv_store WHERE org_id = ?",
+			"DELETE FROM team_group WHERE org_id = ?",
+			"DELETE FROM datasource_label_rule WHERE org_id = ?",
+			"DELETE FROM report WHERE org_id = ?",
+			"DELETE FROM dashboard_public_config WHERE org_id = ?",
+			"DELETE FROM annotation_webhook WHERE org_id = ?",
+			"DELETE FROM annotation_webhook_region WHERE org_id = ?",
+			"DELETE FROM dashboard_version_retention WHERE org_id = ?",
+			"DELETE FROM dashboard_lint_rule WHERE org_id = ?",
 		}
 
 		for _, sql := range deletes {
@@ -1,6 +1,7 @@
 package permissions
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/grafana/grafana/pkg/models"
@@ -16,6 +17,8 @@ type DashboardPermissionFilter struct {
 	UserId          int64
 	OrgId           int64
 	PermissionLevel models.PermissionType
+	// MaxFolderDepth is the maximum number of nested folders, whose permissions are inherited by their subfolders and dashboards
+	MaxFolderDepth int
 }
 
 func (d DashboardPermissionFilter) Where() (string, []interface{}) {
@@ -30,14 +33,28 @@ func (d DashboardPermissionFilter) Where() (string, []interface{}) {
 
 	falseStr := d.Dialect.BooleanStr(false)
 
+	// permissions and default permissions are inherited from the parent folders
+	folderJoins, parentFolders := ParentFolderJoins(d.MaxFolderDepth)
+	aclJoins, aclParents := "", ""
+	defaultPermissions := `(folder.id IS NOT NULL AND folder.has_acl = ` + falseStr + `) OR
+							  (folder.id IS NULL AND d.has_acl = ` + falseStr + `)`
+	if len(parentFolders) > 0 {
+		aclJoins = `LEFT JOIN dashboard AS folder on folder.id = d.folder_id ` + folderJoins
+		for _, parent := range parentFolders {
+			aclParents += ` OR da.dashboard_id = ` + parent + `.id`
+		}
+		defaultPermissions = RootFolderColumn(parentFolders, "has_acl") + ` = ` + falseStr
+	}
+
 	sql := `(
 		dashboard.id IN (
 			SELECT distinct DashboardId from (
 				SELECT d.id AS DashboardId
 					FROM dashboard AS d
+					` + aclJoins + `
 					LEFT JOIN dashboard_acl AS da ON
 						da.dashboard_id = d.id OR
-						da.dashboard_id = d.folder_id
+						da.dashboard_id = d.folder_id` + aclParents + `
 					WHERE
 						d.org_id = ? AND
 						da.permission >= ? AND
@@ -50,12 +67,12 @@ func (d DashboardPermissionFilter) Where() (string, []interface{}) {
 				SELECT d.id AS DashboardId
 					FROM dashboard AS d
 					LEFT JOIN dashboard AS folder on folder.id = d.folder_id
+					` + folderJoins + `
 					LEFT JOIN dashboard_acl AS da ON
 						(
 							-- include default permissions -->
 							da.org_id = -1 AND (
-							  (folder.id IS NOT NULL AND folder.has_acl = ` + falseStr + `) OR
-							  (folder.id IS NULL AND d.has_acl = ` + falseStr + `)
+							  ` + defaultPermissions + `
 							)
 						)
 					WHERE
@@ -77,8 +94,36 @@ func (d DashboardPermissionFilter) Where() (string, []interface{}) {
 	return sql, params
 }
 
+// ParentFolderJoins returns the joins of the parents of the folder joined as folder, when folders can be nested up to
+// maxFolderDepth levels, and the aliases of the joined parents, from the closest to the farthest one
+func ParentFolderJoins(maxFolderDepth int) (string, []string) {
+	var joins strings.Builder
+	parents := make([]string, 0, maxFolderDepth)
+	child := "folder"
+	for i := 2; i <= maxFolderDepth; i++ {
+		parent := fmt.Sprintf("folder%d", i)
+		joins.WriteString(fmt.Sprintf("LEFT JOIN dashboard AS %s ON %s.id = %s.folder_id ", parent, parent, child))
+		parents = append(parents, parent)
+		child = parent
+	}
+	return joins.String(), parents
+}
+
+// RootFolderColumn returns the column of the root folder, among the parents returned by ParentFolderJoins, the folder
+// joined as folder and the dashboard or folder itself joined as d
+func RootFolderColumn(parents []string, column string) string {
+	columns := make([]string, 0, len(parents)+2)
+	for i := len(parents) - 1; i >= 0; i-- {
+		columns = append(columns, parents[i]+"."+column)
+	}
+	columns = append(columns, "folder."+column, "d."+column)
+	return "COALESCE(" + strings.Join(columns, ", ") + ")"
+}
+
 type AccessControlDashboardPermissionFilter struct {
-	User             *models.SignedInUser
+	User *models.SignedInUser
+	// MaxFolderDepth is the maximum number of nested folders, whose permissions are inherited by their subfolders and dashboards
+	MaxFolderDepth   int
 	dashboardActions []string
 	folderActions    []string
 }
@@ -118,9 +163,10 @@ func (f AccessControlDashboardPermissionFilter) Where() (string, []interface{})
 		builder.WriteString(" OR dashboard.folder_id IN(SELECT id FROM dashboard WHERE ")
 		dashFolderFilter, _ := accesscontrol.Filter(f.User, "dashboard.uid", dashboards.ScopeFoldersPrefix, f.dashboardActions...)
 
-		builder.WriteString(dashFolderFilter.Where)
+		dashFolderWhere, dashFolderArgs := nestedFolderFilter(dashFolderFilter, f.maxFolderDepth())
+		builder.WriteString(dashFolderWhere)
 		builder.WriteString(")) AND NOT dashboard.is_folder)")
-		args = append(args, dashFolderFilter.Args...)
+		args = append(args, dashFolderArgs...)
 	}
 
 	if len(f.folderActions) > 0 {
@@ -129,10 +175,35 @@ func (f AccessControlDashboardPermissionFilter) Where() (string, []interface{})
 		}
 		builder.WriteString("(")
 		folderFilter, _ := accesscontrol.Filter(f.User, "dashboard.uid", dashboards.ScopeFoldersPrefix, f.folderActions...)
-		builder.WriteString(folderFilter.Where)
+		if depth := f.maxFolderDepth(); depth > 1 {
+			// subfolders inherit the permissions of their parents
+			parentWhere, parentArgs := nestedFolderFilter(folderFilter, depth-1)
+			builder.WriteString("(" + folderFilter.Where + " OR dashboard.folder_id IN(SELECT id FROM dashboard WHERE " + parentWhere + "))")
+			args = append(args, folderFilter.Args...)
+			args = append(args, parentArgs...)
+		} else {
+			builder.WriteString(folderFilter.Where)
+			args = append(args, folderFilter.Args...)
+		}
 		builder.WriteString(" AND dashboard.is_folder)")
-		args = append(args, folderFilter.Args...)
 	}
 	builder.WriteString(")")
 	return builder.String(), args
 }
+
+func (f AccessControlDashboardPermissionFilter) maxFolderDepth() int {
+	if f.MaxFolderDepth < 1 {
+		return 1
+	}
+	return f.MaxFolderDepth
+}
+
+// nestedFolderFilter extends a filter on folders to the folders whose parents, up to depth levels, match the filter
+func nestedFolderFilter(filter accesscontrol.SQLFilter, depth int) (string, []interface{}) {
+	where, args := filter.Where, filter.Args
+	for i := 1; i < depth; i++ {
+		where = filter.Where + " OR folder_id IN(SELECT id FROM dashboard WHERE " + where + ")"
+		args = append(append([]interface{}{}, filter.Args...), args...)
+	}
+	return where, args
+}
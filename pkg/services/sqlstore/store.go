@@ -18,6 +18,9 @@ type Store interface {
 	GetDashboardSnapshot(ctx context.Context, query *models.GetDashboardSnapshotQuery) error
 	HasEditPermissionInFolders(ctx context.Context, query *models.HasEditPermissionInFoldersQuery) error
 	SearchDashboardSnapshots(ctx context.Context, query *models.GetDashboardSnapshotsQuery) error
+	GetDashboardSnapshotsSize(ctx context.Context, query *models.GetDashboardSnapshotsSizeQuery) error
+	GetDashboardSnapshotsWithoutBlob(ctx context.Context, query *models.GetDashboardSnapshotsWithoutBlobQuery) error
+	SetDashboardSnapshotBlob(ctx context.Context, cmd *models.SetDashboardSnapshotBlobCommand) error
 	GetOrgByName(name string) (*models.Org, error)
 	CreateOrg(ctx context.Context, cmd *models.CreateOrgCommand) error
 	CreateOrgWithMember(name string, userID int64) (models.Org, error)
@@ -29,6 +32,7 @@ type Store interface {
 	CreateLoginAttempt(ctx context.Context, cmd *models.CreateLoginAttemptCommand) error
 	GetUserLoginAttemptCount(ctx context.Context, query *models.GetUserLoginAttemptCountQuery) error
 	DeleteOldLoginAttempts(ctx context.Context, cmd *models.DeleteOldLoginAttemptsCommand) error
+	DeleteUserLoginAttempts(ctx context.Context, cmd *models.DeleteUserLoginAttemptsCommand) error
 	CreateUser(ctx context.Context, cmd models.CreateUserCommand) (*models.User, error)
 	GetUserById(ctx context.Context, query *models.GetUserByIdQuery) error
 	GetUserByLogin(ctx context.Context, query *models.GetUserByLoginQuery) error
@@ -81,6 +85,11 @@ type Store interface {
 	GetDashboardVersion(ctx context.Context, query *models.GetDashboardVersionQuery) error
 	GetDashboardVersions(ctx context.Context, query *models.GetDashboardVersionsQuery) error
 	DeleteExpiredVersions(ctx context.Context, cmd *models.DeleteExpiredVersionsCommand) error
+	AddDashboardVersionTag(ctx context.Context, cmd *models.AddDashboardVersionTagCommand) error
+	DeleteDashboardVersionTag(ctx context.Context, cmd *models.DeleteDashboardVersionTagCommand) error
+	GetDashboardVersionRetention(ctx context.Context, query *models.GetDashboardVersionRetentionQuery) error
+	SaveDashboardVersionRetention(ctx context.Context, cmd *models.SaveDashboardVersionRetentionCommand) error
+	DeleteDashboardVersionRetention(ctx context.Context, cmd *models.DeleteDashboardVersionRetentionCommand) error
 	GetDashboardAclInfoList(ctx context.Context, query *models.GetDashboardAclInfoListQuery) error
 	CreatePlaylist(ctx context.Context, cmd *models.CreatePlaylistCommand) error
 	UpdatePlaylist(ctx context.Context, cmd *models.UpdatePlaylistCommand) error
@@ -135,6 +144,7 @@ type Store interface {
 	AddAPIKey(ctx context.Context, cmd *models.AddApiKeyCommand) error
 	GetApiKeyById(ctx context.Context, query *models.GetApiKeyByIdQuery) error
 	GetApiKeyByName(ctx context.Context, query *models.GetApiKeyByNameQuery) error
+	UpdateApiKeyLastUsed(ctx context.Context, cmd *models.UpdateApiKeyLastUsedCommand) error
 	UpdateTempUserStatus(ctx context.Context, cmd *models.UpdateTempUserStatusCommand) error
 	CreateTempUser(ctx context.Context, cmd *models.CreateTempUserCommand) error
 	UpdateTempUserWithEmailSent(ctx context.Context, cmd *models.UpdateTempUserWithEmailSentCommand) error
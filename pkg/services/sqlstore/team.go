@@ -3,6 +3,7 @@ package sqlstore
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -135,6 +136,8 @@ func (ss *SQLStore) DeleteTeam(ctx context.Context, cmd *models.DeleteTeamComman
 			"DELETE FROM team WHERE org_id=? and id = ?",
 			"DELETE FROM dashboard_acl WHERE org_id=? and team_id = ?",
 			"DELETE FROM team_role WHERE org_id=? and team_id = ?",
+			"DELETE FROM team_group WHERE org_id=? and team_id = ?",
+			"DELETE FROM datasource_label_rule WHERE org_id=? and team_id = ?",
 		}
 
 		for _, sql := range deletes {
@@ -377,6 +380,19 @@ func isTeamMember(sess *DBSession, orgId int64, teamId int64, userId int64) (boo
 	return true, nil
 }
 
+// IsExternalTeamMemberHook is called from team resource permission service
+// it checks if the team member was added by the sync of an external group within the given transaction session
+func IsExternalTeamMemberHook(sess *DBSession, orgID, teamID, userID int64) (bool, error) {
+	member, err := getTeamMember(sess, orgID, teamID, userID)
+	if errors.Is(err, models.ErrTeamMemberNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return member.External, nil
+}
+
 // AddOrUpdateTeamMemberHook is called from team resource permission service
 // it adds user to a team or updates user permissions in a team within the given transaction session
 func AddOrUpdateTeamMemberHook(sess *DBSession, userID, orgID, teamID int64, isExternal bool, permission models.PermissionType) error {
@@ -536,6 +536,7 @@ func (ss *SQLStore) GetSignedInUser(ctx context.Context, query *models.GetSigned
 		u.login               as login,
 		u.name                as name,
 		u.is_disabled         as is_disabled,
+		u.is_service_account  as is_service_account,
 		u.help_flags1         as help_flags1,
 		u.last_seen_at        as last_seen_at,
 		(SELECT COUNT(*) FROM org_user where org_user.user_id = u.id) as org_count,
@@ -815,6 +816,8 @@ func UserDeletions() []string {
 		"DELETE FROM user_auth WHERE user_id = ?",
 		"DELETE FROM user_auth_token WHERE user_id = ?",
 		"DELETE FROM quota WHERE user_id = ?",
+		"DELETE FROM user_password_history WHERE user_id = ?",
+		"DELETE FROM report WHERE user_id = ?",
 	}
 	return deletes
 }
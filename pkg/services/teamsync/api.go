@@ -0,0 +1,81 @@
+package teamsync
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+func (s *TeamSyncService) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(s.accessControl)
+
+	s.routeRegister.Group("/api/teams/:teamId/groups", func(groupsRoute routing.RouteRegister) {
+		groupsRoute.Get("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(accesscontrol.ActionTeamsPermissionsRead, accesscontrol.ScopeTeamsID)), routing.Wrap(s.getTeamGroupsHandler))
+		groupsRoute.Post("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(accesscontrol.ActionTeamsPermissionsWrite, accesscontrol.ScopeTeamsID)), routing.Wrap(s.addTeamGroupHandler))
+		groupsRoute.Delete("/:groupId", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(accesscontrol.ActionTeamsPermissionsWrite, accesscontrol.ScopeTeamsID)), routing.Wrap(s.removeTeamGroupHandler))
+	})
+}
+
+// GET /api/teams/:teamId/groups
+func (s *TeamSyncService) getTeamGroupsHandler(c *models.ReqContext) response.Response {
+	teamID, err := strconv.ParseInt(web.Params(c.Req)[":teamId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "teamId is invalid", err)
+	}
+
+	groups, err := s.getTeamGroups(c.Req.Context(), c.OrgId, teamID)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get team groups", err)
+	}
+	return response.JSON(http.StatusOK, groups)
+}
+
+// POST /api/teams/:teamId/groups
+func (s *TeamSyncService) addTeamGroupHandler(c *models.ReqContext) response.Response {
+	teamID, err := strconv.ParseInt(web.Params(c.Req)[":teamId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "teamId is invalid", err)
+	}
+
+	cmd := AddTeamGroupCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+	if cmd.GroupID == "" {
+		return response.Error(http.StatusBadRequest, "groupId is required", nil)
+	}
+
+	if err := s.addTeamGroup(c.Req.Context(), c.OrgId, teamID, cmd.GroupID); err != nil {
+		switch {
+		case errors.Is(err, models.ErrTeamNotFound):
+			return response.Error(http.StatusNotFound, "Team not found", nil)
+		case errors.Is(err, ErrTeamGroupAlreadyAdded):
+			return response.Error(http.StatusBadRequest, "Group is already added to this team", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to add group to team", err)
+	}
+	return response.Success("Group added to Team")
+}
+
+// DELETE /api/teams/:teamId/groups/:groupId
+func (s *TeamSyncService) removeTeamGroupHandler(c *models.ReqContext) response.Response {
+	teamID, err := strconv.ParseInt(web.Params(c.Req)[":teamId"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "teamId is invalid", err)
+	}
+
+	if err := s.removeTeamGroup(c.Req.Context(), c.OrgId, teamID, web.Params(c.Req)[":groupId"]); err != nil {
+		if errors.Is(err, ErrTeamGroupNotFound) {
+			return response.Error(http.StatusNotFound, "Group not found", nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to remove group from team", err)
+	}
+	return response.Success("Team Group removed")
+}
@@ -0,0 +1,83 @@
+package teamsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func (TeamGroup) TableName() string {
+	return "team_group"
+}
+
+func (s *TeamSyncService) getTeamGroups(ctx context.Context, orgID, teamID int64) ([]*TeamGroupDTO, error) {
+	groups := make([]*TeamGroup, 0)
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("org_id = ? AND team_id = ?", orgID, teamID).Asc("group_id").Find(&groups)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*TeamGroupDTO, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, &TeamGroupDTO{OrgID: group.OrgID, TeamID: group.TeamID, GroupID: group.GroupID})
+	}
+	return result, nil
+}
+
+// getOrgTeamGroups returns the groups mapped to each team of the organization.
+func (s *TeamSyncService) getOrgTeamGroups(ctx context.Context, orgID int64) (map[int64][]string, error) {
+	groups := make([]*TeamGroup, 0)
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		return sess.Where("org_id = ?", orgID).Find(&groups)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[int64][]string{}
+	for _, group := range groups {
+		result[group.TeamID] = append(result[group.TeamID], group.GroupID)
+	}
+	return result, nil
+}
+
+func (s *TeamSyncService) addTeamGroup(ctx context.Context, orgID, teamID int64, groupID string) error {
+	return s.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		exists, err := sess.Where("org_id = ? AND id = ?", orgID, teamID).Exist(&models.Team{})
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return models.ErrTeamNotFound
+		}
+
+		exists, err = sess.Where("org_id = ? AND team_id = ? AND group_id = ?", orgID, teamID, groupID).Exist(&TeamGroup{})
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrTeamGroupAlreadyAdded
+		}
+
+		now := time.Now()
+		_, err = sess.Insert(&TeamGroup{OrgID: orgID, TeamID: teamID, GroupID: groupID, Created: now, Updated: now})
+		return err
+	})
+}
+
+func (s *TeamSyncService) removeTeamGroup(ctx context.Context, orgID, teamID int64, groupID string) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		affected, err := sess.Where("org_id = ? AND team_id = ? AND group_id = ?", orgID, teamID, groupID).Delete(&TeamGroup{})
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrTeamGroupNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,42 @@
+package teamsync
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrTeamGroupAlreadyAdded = errors.New("group is already added to this team")
+	ErrTeamGroupNotFound     = errors.New("group not found")
+)
+
+// TeamGroup maps the members of an external group to a team
+type TeamGroup struct {
+	ID      int64  `xorm:"pk autoincr 'id'"`
+	OrgID   int64  `xorm:"org_id"`
+	TeamID  int64  `xorm:"team_id"`
+	GroupID string `xorm:"group_id"`
+	Created time.Time
+	Updated time.Time
+}
+
+type TeamGroupDTO struct {
+	OrgID   int64  `json:"orgId"`
+	TeamID  int64  `json:"teamId"`
+	GroupID string `json:"groupId"`
+}
+
+type AddTeamGroupCommand struct {
+	GroupID string `json:"groupId" binding:"Required"`
+}
+
+// Config is the team sync config file, that maps groups to teams next to the mappings of the API
+type Config struct {
+	Mappings []*Mapping `toml:"mappings"`
+}
+
+type Mapping struct {
+	OrgID  int64    `toml:"org_id"`
+	Team   string   `toml:"team"`
+	Groups []string `toml:"groups"`
+}
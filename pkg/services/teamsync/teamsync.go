@@ -0,0 +1,188 @@
+package teamsync
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/externalteams"
+	"github.com/grafana/grafana/pkg/services/ldapsync"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// TeamSyncService adds external users to the teams their groups are mapped to, every time they log in or
+// are synchronized. Groups are mapped to teams by the team groups API and by the config file.
+type TeamSyncService struct {
+	cfg                    *setting.Cfg
+	sqlStore               *sqlstore.SQLStore
+	ldapSync               *ldapsync.LDAPSyncService
+	teamPermissionsService accesscontrol.TeamPermissionsService
+	accessControl          accesscontrol.AccessControl
+	routeRegister          routing.RouteRegister
+	config                 *Config
+	log                    log.Logger
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, loginService login.Service,
+	ldapSync *ldapsync.LDAPSyncService, teamPermissionsService accesscontrol.TeamPermissionsService,
+	accessControl accesscontrol.AccessControl, routeRegister routing.RouteRegister) (*TeamSyncService, error) {
+	s := &TeamSyncService{
+		cfg:                    cfg,
+		sqlStore:               sqlStore,
+		ldapSync:               ldapSync,
+		teamPermissionsService: teamPermissionsService,
+		accessControl:          accessControl,
+		routeRegister:          routeRegister,
+		config:                 &Config{},
+		log:                    log.New("teamsync"),
+	}
+
+	// the login service has a single team sync, that syncs the teams of the LDAP group mappings as well
+	loginService.SetTeamSyncFunc(s.SyncTeams)
+
+	if !cfg.TeamSyncEnabled {
+		return s, nil
+	}
+
+	if cfg.TeamSyncConfigFile != "" {
+		config, err := readConfig(cfg.TeamSyncConfigFile)
+		if err != nil {
+			return nil, err
+		}
+		s.config = config
+	}
+
+	s.registerAPIEndpoints()
+
+	return s, nil
+}
+
+func readConfig(configFile string) (*Config, error) {
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `configFile` comes from grafana configuration file
+	fileBytes, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load team sync config file: %w", err)
+	}
+
+	content, err := setting.ExpandVar(string(fileBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand variables: %w", err)
+	}
+
+	config := &Config{}
+	if _, err := toml.Decode(content, config); err != nil {
+		return nil, fmt.Errorf("failed to load team sync config file: %w", err)
+	}
+
+	for _, mapping := range config.Mappings {
+		if mapping.Team == "" {
+			return nil, fmt.Errorf("team sync mapping of groups %v has no team", mapping.Groups)
+		}
+		if mapping.OrgID == 0 {
+			mapping.OrgID = 1
+		}
+	}
+	return config, nil
+}
+
+// SyncTeams adds the external user to the teams its groups are mapped to, and removes it from the teams
+// its groups are not mapped to anymore. Only the members added by the sync are removed. The teams of the
+// LDAP group mappings are synced with the teams of the team groups, a team mapped by both keeps the members
+// either of them adds.
+func (s *TeamSyncService) SyncTeams(user *models.User, extUser *models.ExternalUserInfo) error {
+	ctx := context.Background()
+	memberships, err := s.ldapSync.TeamMemberships(ctx, extUser)
+	if err != nil {
+		return err
+	}
+
+	if s.cfg.TeamSyncEnabled {
+		groupMemberships, err := s.teamMemberships(ctx, user, extUser)
+		if err != nil {
+			return err
+		}
+		memberships.Merge(groupMemberships)
+	}
+
+	return externalteams.Sync(ctx, s.sqlStore, s.teamPermissionsService, s.log, user.Id, memberships)
+}
+
+// teamMemberships returns the teams mapped to groups in the orgs of the user, and whether the user has one
+// of their groups.
+func (s *TeamSyncService) teamMemberships(ctx context.Context, user *models.User, extUser *models.ExternalUserInfo) (externalteams.Memberships, error) {
+	query := &models.GetUserOrgListQuery{UserId: user.Id}
+	if err := s.sqlStore.GetUserOrgList(ctx, query); err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]bool, len(extUser.Groups))
+	for _, group := range extUser.Groups {
+		groups[strings.ToLower(group)] = true
+	}
+
+	memberships := externalteams.Memberships{}
+	for _, org := range query.Result {
+		teamGroups, err := s.getMappedTeams(ctx, org.OrgId)
+		if err != nil {
+			return nil, err
+		}
+
+		for teamID, mapped := range teamGroups {
+			wanted := false
+			for _, group := range mapped {
+				if groups[strings.ToLower(group)] {
+					wanted = true
+					break
+				}
+			}
+			memberships.Add(org.OrgId, teamID, wanted)
+		}
+	}
+	return memberships, nil
+}
+
+// getMappedTeams returns the groups of the teams of the organization that have group mappings.
+func (s *TeamSyncService) getMappedTeams(ctx context.Context, orgID int64) (map[int64][]string, error) {
+	teamGroups, err := s.getOrgTeamGroups(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, mapping := range s.config.Mappings {
+		if mapping.OrgID == orgID {
+			names = append(names, mapping.Team)
+		}
+	}
+	if len(names) == 0 {
+		return teamGroups, nil
+	}
+
+	teams, err := externalteams.GetTeamsByName(ctx, s.sqlStore, orgID, names)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mapping := range s.config.Mappings {
+		if mapping.OrgID != orgID {
+			continue
+		}
+		teamID, ok := teams[mapping.Team]
+		if !ok {
+			s.log.Warn("Team of team sync mapping not found", "orgId", orgID, "team", mapping.Team)
+			continue
+		}
+		teamGroups[teamID] = append(teamGroups[teamID], mapping.Groups...)
+	}
+	return teamGroups, nil
+}
@@ -0,0 +1,269 @@
+//go:build integration
+// +build integration
+
+package teamsync
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	acdatabase "github.com/grafana/grafana/pkg/services/accesscontrol/database"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/accesscontrol/ossaccesscontrol"
+	"github.com/grafana/grafana/pkg/services/ldapsync"
+	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/login/authinfoservice"
+	authinfostore "github.com/grafana/grafana/pkg/services/login/authinfoservice/database"
+	"github.com/grafana/grafana/pkg/services/login/loginservice"
+	"github.com/grafana/grafana/pkg/services/quota"
+	secretsdatabase "github.com/grafana/grafana/pkg/services/secrets/database"
+	secretsManager "github.com/grafana/grafana/pkg/services/secrets/manager"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+const testOrgID = int64(1)
+
+type testEnv struct {
+	service      *TeamSyncService
+	sqlStore     *sqlstore.SQLStore
+	loginService login.Service
+}
+
+func setupTestEnv(t *testing.T, config *Config) *testEnv {
+	t.Helper()
+
+	cfg := setting.NewCfg()
+	cfg.TeamSyncEnabled = true
+
+	sqlStore := sqlstore.InitTestDB(t)
+	secretsService := secretsManager.SetupTestService(t, secretsdatabase.ProvideSecretsStore(sqlStore))
+	authInfoService := authinfoservice.ProvideAuthInfoService(&authinfoservice.OSSUserProtectionImpl{}, authinfostore.ProvideAuthInfoStore(sqlStore, secretsService))
+	loginService := loginservice.ProvideService(sqlStore, &quota.QuotaService{Cfg: cfg}, authInfoService)
+	teamPermissionsService, err := ossaccesscontrol.ProvideTeamPermissions(cfg, routing.NewRouteRegister(), sqlStore, accesscontrolmock.New(), acdatabase.ProvideService(sqlStore))
+	require.NoError(t, err)
+	ldapSync, err := ldapsync.ProvideService(cfg, sqlStore, loginService, nil, nil, teamPermissionsService, accesscontrolmock.New(), routing.NewRouteRegister())
+	require.NoError(t, err)
+
+	service, err := ProvideService(cfg, sqlStore, loginService, ldapSync, teamPermissionsService, accesscontrolmock.New(), routing.NewRouteRegister())
+	require.NoError(t, err)
+	if config != nil {
+		service.config = config
+	}
+
+	return &testEnv{service: service, sqlStore: sqlStore, loginService: loginService}
+}
+
+func (env *testEnv) createTeam(t *testing.T, name string) int64 {
+	t.Helper()
+	team, err := env.sqlStore.CreateTeam(name, "", testOrgID)
+	require.NoError(t, err)
+	return team.Id
+}
+
+// login upserts the user like an OAuth login does, which syncs its teams.
+func (env *testEnv) login(t *testing.T, groups ...string) *models.User {
+	t.Helper()
+
+	cmd := &models.UpsertUserCommand{
+		ReqContext: &models.ReqContext{Logger: log.New("teamsync.test")},
+		ExternalUser: &models.ExternalUserInfo{
+			AuthModule: "oauth_generic_oauth",
+			AuthId:     "oauth-user",
+			Login:      "oauth-user",
+			Email:      "oauth-user@example.com",
+			Groups:     groups,
+		},
+		SignupAllowed: true,
+	}
+	require.NoError(t, env.loginService.UpsertUser(context.Background(), cmd))
+	return cmd.Result
+}
+
+// enableLDAP enables LDAP with a server that maps the group to the team.
+func (env *testEnv) enableLDAP(t *testing.T, group, team string) {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "ldap.toml")
+	content := `
+[[servers]]
+host = "ldap"
+search_filter = "(cn=%s)"
+search_base_dns = ["dc=grafana,dc=org"]
+
+[[servers.group_mappings]]
+group_dn = "` + group + `"
+org_role = "Viewer"
+teams = ["` + team + `"]
+`
+	require.NoError(t, os.WriteFile(file, []byte(content), 0600))
+
+	origLDAPConfigFile := setting.LDAPConfigFile
+	setting.LDAPConfigFile = file
+	env.service.cfg.LDAPEnabled = true
+	t.Cleanup(func() {
+		setting.LDAPConfigFile = origLDAPConfigFile
+	})
+}
+
+func (env *testEnv) teams(t *testing.T, userID int64) map[int64]bool {
+	t.Helper()
+
+	memberships, err := env.sqlStore.GetUserTeamMemberships(context.Background(), testOrgID, userID, false)
+	require.NoError(t, err)
+
+	result := map[int64]bool{}
+	for _, membership := range memberships {
+		result[membership.TeamId] = membership.External
+	}
+	return result
+}
+
+func TestTeamSync(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("users are added to the teams of their groups and removed when they leave them", func(t *testing.T) {
+		env := setupTestEnv(t, nil)
+		backend := env.createTeam(t, "Backend")
+		frontend := env.createTeam(t, "Frontend")
+		require.NoError(t, env.service.addTeamGroup(ctx, testOrgID, backend, "backend"))
+		require.NoError(t, env.service.addTeamGroup(ctx, testOrgID, frontend, "frontend"))
+
+		user := env.login(t, "Backend", "frontend", "ops")
+		assert.Equal(t, map[int64]bool{backend: true, frontend: true}, env.teams(t, user.Id))
+
+		env.login(t, "backend")
+		assert.Equal(t, map[int64]bool{backend: true}, env.teams(t, user.Id))
+	})
+
+	t.Run("members added manually are not removed", func(t *testing.T) {
+		env := setupTestEnv(t, nil)
+		backend := env.createTeam(t, "Backend")
+		require.NoError(t, env.service.addTeamGroup(ctx, testOrgID, backend, "backend"))
+
+		user := env.login(t)
+		require.NoError(t, env.sqlStore.AddTeamMember(user.Id, testOrgID, backend, false, 0))
+
+		env.login(t)
+		assert.Equal(t, map[int64]bool{backend: false}, env.teams(t, user.Id))
+	})
+
+	t.Run("members added by the sync cannot be changed through the team permissions", func(t *testing.T) {
+		env := setupTestEnv(t, nil)
+		backend := env.createTeam(t, "Backend")
+		require.NoError(t, env.service.addTeamGroup(ctx, testOrgID, backend, "backend"))
+		user := env.login(t, "backend")
+
+		for _, permission := range []string{"Admin", ""} {
+			_, err := env.service.teamPermissionsService.SetUserPermission(ctx, testOrgID, accesscontrol.User{ID: user.Id}, strconv.FormatInt(backend, 10), permission)
+			require.ErrorIs(t, err, models.ErrTeamMemberExternal)
+		}
+		assert.Equal(t, map[int64]bool{backend: true}, env.teams(t, user.Id))
+	})
+
+	t.Run("teams of the config file are synced", func(t *testing.T) {
+		env := setupTestEnv(t, &Config{Mappings: []*Mapping{
+			{OrgID: testOrgID, Team: "Backend", Groups: []string{"backend"}},
+			{OrgID: testOrgID, Team: "Missing", Groups: []string{"backend"}},
+		}})
+		backend := env.createTeam(t, "Backend")
+
+		user := env.login(t, "backend")
+		assert.Equal(t, map[int64]bool{backend: true}, env.teams(t, user.Id))
+	})
+
+	t.Run("teams mapped by LDAP group mappings and team groups keep the members of both", func(t *testing.T) {
+		env := setupTestEnv(t, nil)
+		backend := env.createTeam(t, "Backend")
+		require.NoError(t, env.service.addTeamGroup(ctx, testOrgID, backend, "backend"))
+		env.enableLDAP(t, "cn=backend", "Backend")
+
+		user, err := env.sqlStore.CreateUser(ctx, models.CreateUserCommand{Login: "ldap-user", OrgId: testOrgID})
+		require.NoError(t, err)
+		extUser := &models.ExternalUserInfo{
+			AuthModule: models.AuthModuleLDAP,
+			Login:      "ldap-user",
+			Groups:     []string{"cn=backend"},
+			OrgRoles:   map[int64]models.RoleType{testOrgID: models.ROLE_VIEWER},
+			OrgTeams:   map[int64][]string{testOrgID: {"Backend"}},
+		}
+
+		require.NoError(t, env.service.SyncTeams(user, extUser))
+		assert.Equal(t, map[int64]bool{backend: true}, env.teams(t, user.Id))
+
+		extUser.OrgTeams = nil
+		require.NoError(t, env.service.SyncTeams(user, extUser))
+		assert.Empty(t, env.teams(t, user.Id))
+	})
+
+	t.Run("teams are not synced when team sync is disabled", func(t *testing.T) {
+		env := setupTestEnv(t, nil)
+		env.service.cfg.TeamSyncEnabled = false
+		backend := env.createTeam(t, "Backend")
+		require.NoError(t, env.service.addTeamGroup(ctx, testOrgID, backend, "backend"))
+
+		user := env.login(t, "backend")
+		assert.Empty(t, env.teams(t, user.Id))
+	})
+}
+
+func TestTeamGroups(t *testing.T) {
+	ctx := context.Background()
+	env := setupTestEnv(t, nil)
+	backend := env.createTeam(t, "Backend")
+
+	require.NoError(t, env.service.addTeamGroup(ctx, testOrgID, backend, "backend"))
+	require.ErrorIs(t, env.service.addTeamGroup(ctx, testOrgID, backend, "backend"), ErrTeamGroupAlreadyAdded)
+	require.ErrorIs(t, env.service.addTeamGroup(ctx, testOrgID, backend+1, "backend"), models.ErrTeamNotFound)
+
+	groups, err := env.service.getTeamGroups(ctx, testOrgID, backend)
+	require.NoError(t, err)
+	assert.Equal(t, []*TeamGroupDTO{{OrgID: testOrgID, TeamID: backend, GroupID: "backend"}}, groups)
+
+	require.NoError(t, env.service.removeTeamGroup(ctx, testOrgID, backend, "backend"))
+	require.ErrorIs(t, env.service.removeTeamGroup(ctx, testOrgID, backend, "backend"), ErrTeamGroupNotFound)
+
+	t.Run("groups are removed with their team", func(t *testing.T) {
+		require.NoError(t, env.service.addTeamGroup(ctx, testOrgID, backend, "backend"))
+		require.NoError(t, env.sqlStore.DeleteTeam(ctx, &models.DeleteTeamCommand{OrgId: testOrgID, Id: backend}))
+
+		groups, err := env.service.getOrgTeamGroups(ctx, testOrgID)
+		require.NoError(t, err)
+		assert.Empty(t, groups)
+	})
+}
+
+func TestReadConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "team_sync.toml")
+	content := `
+[[mappings]]
+team = "Backend"
+groups = ["backend", "cn=backend,ou=groups,dc=grafana,dc=org"]
+
+[[mappings]]
+org_id = 2
+team = "Frontend"
+groups = ["frontend"]
+`
+	require.NoError(t, os.WriteFile(file, []byte(content), 0600))
+
+	config, err := readConfig(file)
+	require.NoError(t, err)
+	require.Len(t, config.Mappings, 2)
+	assert.Equal(t, &Mapping{OrgID: 1, Team: "Backend", Groups: []string{"backend", "cn=backend,ou=groups,dc=grafana,dc=org"}}, config.Mappings[0])
+	assert.Equal(t, int64(2), config.Mappings[1].OrgID)
+
+	require.NoError(t, os.WriteFile(file, []byte("[[mappings]]\ngroups = [\"backend\"]\n"), 0600))
+	_, err = readConfig(file)
+	require.Error(t, err)
+}
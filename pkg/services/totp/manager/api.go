@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/web"
+)
+
+// organization actions, as declared by the api package
+const (
+	actionOrgsRead  = "orgs:read"
+	actionOrgsWrite = "orgs:write"
+)
+
+func (s *TOTPService) registerAPIEndpoints() {
+	authorize := accesscontrol.Middleware(s.accessControl)
+
+	s.routeRegister.Group("/api/user/2fa", func(userRoute routing.RouteRegister) {
+		userRoute.Get("/", routing.Wrap(s.getStatusHandler))
+		userRoute.Post("/enroll", routing.Wrap(s.enrollHandler))
+		userRoute.Post("/activate", routing.Wrap(s.activateHandler))
+		userRoute.Post("/recovery-codes", routing.Wrap(s.regenerateRecoveryCodesHandler))
+		userRoute.Post("/disable", routing.Wrap(s.disableHandler))
+	}, middleware.ReqSignedInNoAnonymous)
+
+	userIDScope := accesscontrol.Scope("global.users", "id", accesscontrol.Parameter(":id"))
+	s.routeRegister.Delete("/api/admin/users/:id/2fa",
+		authorize(middleware.ReqGrafanaAdmin, accesscontrol.EvalPermission(accesscontrol.ActionUsersWrite, userIDScope)),
+		routing.Wrap(s.resetHandler))
+
+	s.routeRegister.Group("/api/org/2fa-policy", func(orgRoute routing.RouteRegister) {
+		orgRoute.Get("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(actionOrgsRead)), routing.Wrap(s.getOrgPolicyHandler))
+		orgRoute.Put("/", authorize(middleware.ReqOrgAdmin, accesscontrol.EvalPermission(actionOrgsWrite)), routing.Wrap(s.updateOrgPolicyHandler))
+	})
+}
+
+// GET /api/user/2fa
+func (s *TOTPService) getStatusHandler(c *models.ReqContext) response.Response {
+	status, err := s.GetStatus(c.Req.Context(), c.UserId)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get two-factor authentication status", err)
+	}
+	return response.JSON(http.StatusOK, status)
+}
+
+// POST /api/user/2fa/enroll
+func (s *TOTPService) enrollHandler(c *models.ReqContext) response.Response {
+	user := &models.User{Id: c.UserId, Login: c.Login}
+	enrollment, err := s.Enroll(c.Req.Context(), user)
+	if err != nil {
+		return errorResponse(err, "Failed to enroll two-factor authentication")
+	}
+	return response.JSON(http.StatusOK, enrollment)
+}
+
+// POST /api/user/2fa/activate
+func (s *TOTPService) activateHandler(c *models.ReqContext) response.Response {
+	cmd := totp.CodeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	codes, err := s.Activate(c.Req.Context(), c.UserId, cmd.Code)
+	if err != nil {
+		return errorResponse(err, "Failed to enable two-factor authentication")
+	}
+	return response.JSON(http.StatusOK, totp.RecoveryCodesDTO{RecoveryCodes: codes})
+}
+
+// POST /api/user/2fa/recovery-codes
+func (s *TOTPService) regenerateRecoveryCodesHandler(c *models.ReqContext) response.Response {
+	cmd := totp.CodeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	codes, err := s.RegenerateRecoveryCodes(c.Req.Context(), c.UserId, cmd.Code)
+	if err != nil {
+		return errorResponse(err, "Failed to generate recovery codes")
+	}
+	return response.JSON(http.StatusOK, totp.RecoveryCodesDTO{RecoveryCodes: codes})
+}
+
+// POST /api/user/2fa/disable
+func (s *TOTPService) disableHandler(c *models.ReqContext) response.Response {
+	cmd := totp.CodeCommand{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	if err := s.Disable(c.Req.Context(), c.UserId, cmd.Code); err != nil {
+		return errorResponse(err, "Failed to disable two-factor authentication")
+	}
+	return response.Success("Two-factor authentication disabled")
+}
+
+// DELETE /api/admin/users/:id/2fa
+func (s *TOTPService) resetHandler(c *models.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(web.Params(c.Req)[":id"], 10, 64)
+	if err != nil {
+		return response.Error(http.StatusBadRequest, "id is invalid", err)
+	}
+
+	query := &models.GetUserByIdQuery{Id: userID}
+	if err := s.sqlStore.GetUserById(c.Req.Context(), query); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return response.Error(http.StatusNotFound, models.ErrUserNotFound.Error(), nil)
+		}
+		return response.Error(http.StatusInternalServerError, "Failed to get user", err)
+	}
+
+	if err := s.Reset(c.Req.Context(), userID); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to reset two-factor authentication", err)
+	}
+	return response.Success("Two-factor authentication reset")
+}
+
+// GET /api/org/2fa-policy
+func (s *TOTPService) getOrgPolicyHandler(c *models.ReqContext) response.Response {
+	enforced, err := s.IsEnforcedByOrg(c.Req.Context(), c.OrgId)
+	if err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to get two-factor authentication policy", err)
+	}
+	return response.JSON(http.StatusOK, totp.OrgPolicyDTO{Enforced: enforced || s.cfg.TOTPEnforced})
+}
+
+// PUT /api/org/2fa-policy
+func (s *TOTPService) updateOrgPolicyHandler(c *models.ReqContext) response.Response {
+	cmd := totp.OrgPolicyDTO{}
+	if err := web.Bind(c.Req, &cmd); err != nil {
+		return response.Error(http.StatusBadRequest, "bad request data", err)
+	}
+
+	if err := s.SetEnforcedByOrg(c.Req.Context(), c.OrgId, cmd.Enforced); err != nil {
+		return response.Error(http.StatusInternalServerError, "Failed to update two-factor authentication policy", err)
+	}
+	return response.Success("Two-factor authentication policy updated")
+}
+
+func errorResponse(err error, message string) response.Response {
+	switch {
+	case errors.Is(err, totp.ErrInvalidCode):
+		return response.Error(http.StatusUnauthorized, err.Error(), nil)
+	case errors.Is(err, totp.ErrNotEnrolled), errors.Is(err, totp.ErrAlreadyEnabled), errors.Is(err, totp.ErrEnforced):
+		return response.Error(http.StatusBadRequest, err.Error(), nil)
+	default:
+		return response.Error(http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,141 @@
+package manager
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// userTOTP is the enrollment of a user. The secret is encrypted, and the recovery codes are hashed.
+type userTOTP struct {
+	ID            int64    `xorm:"pk autoincr 'id'"`
+	UserID        int64    `xorm:"user_id"`
+	Secret        string   `xorm:"secret"`
+	Enabled       bool     `xorm:"enabled"`
+	RecoveryCodes []string `xorm:"recovery_codes"`
+	LastUsedStep  int64    `xorm:"last_used_step"`
+	Created       time.Time
+	Updated       time.Time
+}
+
+func (userTOTP) TableName() string {
+	return "user_totp"
+}
+
+// challenge is a login waiting for its second factor. The token is hashed.
+type challenge struct {
+	ID       int64  `xorm:"pk autoincr 'id'"`
+	Token    string `xorm:"token"`
+	UserID   int64  `xorm:"user_id"`
+	Attempts int    `xorm:"attempts"`
+	Expires  time.Time
+}
+
+func (challenge) TableName() string {
+	return "user_totp_challenge"
+}
+
+// getUserTOTP returns the enrollment of the user, or nil if the user never enrolled.
+func (s *TOTPService) getUserTOTP(ctx context.Context, userID int64) (*userTOTP, error) {
+	var result *userTOTP
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		enrollment := userTOTP{}
+		has, err := sess.Where("user_id = ?", userID).Get(&enrollment)
+		if err != nil {
+			return err
+		}
+		if has {
+			result = &enrollment
+		}
+		return nil
+	})
+	return result, err
+}
+
+// saveUserTOTP creates or replaces the enrollment of the user.
+func (s *TOTPService) saveUserTOTP(ctx context.Context, enrollment *userTOTP) error {
+	return s.sqlStore.WithTransactionalDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		enrollment.Updated = time.Now()
+		if enrollment.ID != 0 {
+			_, err := sess.ID(enrollment.ID).AllCols().Update(enrollment)
+			return err
+		}
+
+		if _, err := sess.Where("user_id = ?", enrollment.UserID).Delete(&userTOTP{}); err != nil {
+			return err
+		}
+		enrollment.Created = enrollment.Updated
+		_, err := sess.Insert(enrollment)
+		return err
+	})
+}
+
+// useStep records the time step of a valid code, unless a concurrent login used it first.
+func (s *TOTPService) useStep(ctx context.Context, enrollment *userTOTP, step int64) (bool, error) {
+	var used bool
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		affected, err := sess.Table("user_totp").
+			Where("id = ? AND last_used_step < ?", enrollment.ID, step).
+			Update(map[string]interface{}{"last_used_step": step, "updated": time.Now()})
+		used = affected == 1
+		return err
+	})
+	if used {
+		enrollment.LastUsedStep = step
+	}
+	return used, err
+}
+
+func (s *TOTPService) deleteUserTOTP(ctx context.Context, userID int64) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Where("user_id = ?", userID).Delete(&userTOTP{})
+		return err
+	})
+}
+
+// createChallenge saves a challenge, and deletes the expired ones.
+func (s *TOTPService) createChallenge(ctx context.Context, item *challenge) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		if _, err := sess.Where("expires < ?", time.Now()).Delete(&challenge{}); err != nil {
+			return err
+		}
+		_, err := sess.Insert(item)
+		return err
+	})
+}
+
+// useChallengeAttempt counts an attempt to complete the challenge before its code is checked, and returns the
+// challenge. It returns nil if the challenge does not exist, expired, or has no attempt left. The attempt is
+// counted with a conditional update, so concurrent attempts cannot exceed the limit.
+func (s *TOTPService) useChallengeAttempt(ctx context.Context, token string) (*challenge, error) {
+	var result *challenge
+	err := s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		res, err := sess.Exec("UPDATE user_totp_challenge SET attempts = attempts + 1 WHERE token = ? AND attempts < ? AND expires > ?",
+			token, maxChallengeAttempts, time.Now())
+		if err != nil {
+			return err
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return err
+		}
+
+		item := challenge{}
+		has, err := sess.Where("token = ?", token).Get(&item)
+		if err != nil {
+			return err
+		}
+		if has {
+			result = &item
+		}
+		return nil
+	})
+	return result, err
+}
+
+func (s *TOTPService) deleteChallenge(ctx context.Context, token string) error {
+	return s.sqlStore.WithDbSession(ctx, func(sess *sqlstore.DBSession) error {
+		_, err := sess.Where("token = ?", token).Delete(&challenge{})
+		return err
+	})
+}
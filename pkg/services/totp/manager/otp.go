@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" // #nosec
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// period is the validity of a code, in seconds
+	period = 30
+	digits = 6
+	// skew is the number of periods before and after the current one whose code is accepted,
+	// to allow for clock drift and for the time it takes to type the code
+	skew = 1
+	// secretSize is the size of the generated secrets in bytes (RFC 4226 recommends 160 bits)
+	secretSize = 20
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateSecret returns a random secret, base32 encoded as authenticator apps expect.
+func generateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(secret), nil
+}
+
+// keyURI returns the otpauth:// URI of the secret, that authenticator apps read from a QR code.
+func keyURI(issuer, account, secret string) string {
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(digits))
+	params.Set("period", fmt.Sprint(period))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// timeStep returns the RFC 6238 time step of t.
+func timeStep(t time.Time) int64 {
+	return t.Unix() / period
+}
+
+// generateCode returns the code of the secret for the time step (RFC 4226 HOTP with the step as counter).
+func generateCode(secret string, step int64) (string, error) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod), nil
+}
+
+// validateCode checks the code against the steps around now, and returns the matching step. Steps up to
+// lastStep were already used and are rejected, so that a code cannot be replayed.
+func validateCode(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	if len(code) != digits {
+		return 0, false
+	}
+
+	current := timeStep(now)
+	for step := current - skew; step <= current+skew; step++ {
+		if step <= lastStep {
+			continue
+		}
+
+		expected, err := generateCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// normalizeCode removes the separators users may type in codes.
+func normalizeCode(code string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code))
+}
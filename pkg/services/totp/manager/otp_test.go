@@ -0,0 +1,86 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// the SHA1 secret of the test vectors of RFC 6238, base32 encoded
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, tc := range testCases {
+		code, err := generateCode(rfcSecret, timeStep(time.Unix(tc.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tc.code, code, "time %d", tc.unix)
+	}
+}
+
+func TestValidateCode(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := timeStep(now)
+
+	codeAt := func(step int64) string {
+		code, err := generateCode(rfcSecret, step)
+		require.NoError(t, err)
+		return code
+	}
+
+	t.Run("accepts the codes of the current and adjacent steps", func(t *testing.T) {
+		for _, step := range []int64{current - 1, current, current + 1} {
+			matched, ok := validateCode(rfcSecret, codeAt(step), now, 0)
+			require.True(t, ok)
+			assert.Equal(t, step, matched)
+		}
+	})
+
+	t.Run("rejects codes outside of the allowed skew", func(t *testing.T) {
+		_, ok := validateCode(rfcSecret, codeAt(current-2), now, 0)
+		assert.False(t, ok)
+		_, ok = validateCode(rfcSecret, codeAt(current+2), now, 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("rejects codes of steps already used", func(t *testing.T) {
+		_, ok := validateCode(rfcSecret, codeAt(current), now, current)
+		assert.False(t, ok)
+		_, ok = validateCode(rfcSecret, codeAt(current+1), now, current)
+		assert.True(t, ok)
+	})
+
+	t.Run("rejects malformed codes", func(t *testing.T) {
+		_, ok := validateCode(rfcSecret, "12345", now, 0)
+		assert.False(t, ok)
+		_, ok = validateCode(rfcSecret, "", now, 0)
+		assert.False(t, ok)
+	})
+}
+
+func TestKeyURI(t *testing.T) {
+	uri := keyURI("Grafana", "admin@example.com", rfcSecret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Grafana:admin@example.com?"), uri)
+	assert.Contains(t, uri, "secret="+rfcSecret)
+	assert.Contains(t, uri, "issuer=Grafana")
+}
+
+func TestNormalizeCode(t *testing.T) {
+	assert.Equal(t, "123456", normalizeCode(" 123 456 "))
+	assert.Equal(t, "abcdefghjk", normalizeCode("abcde-fghjk"))
+}
@@ -0,0 +1,452 @@
+package manager
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/accesscontrol"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	challengeTTL         = 5 * time.Minute
+	challengeTokenLength = 32
+	maxChallengeAttempts = 5
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	kvNamespace          = "totp"
+	kvKeyEnforced        = "enforced"
+)
+
+type TOTPService struct {
+	cfg            *setting.Cfg
+	sqlStore       *sqlstore.SQLStore
+	secretsService secrets.Service
+	kvStore        kvstore.KVStore
+	routeRegister  routing.RouteRegister
+	accessControl  accesscontrol.AccessControl
+	log            log.Logger
+}
+
+func ProvideService(cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, secretsService secrets.Service, kvStore kvstore.KVStore,
+	routeRegister routing.RouteRegister, accessControl accesscontrol.AccessControl) *TOTPService {
+	s := &TOTPService{
+		cfg:            cfg,
+		sqlStore:       sqlStore,
+		secretsService: secretsService,
+		kvStore:        kvStore,
+		routeRegister:  routeRegister,
+		accessControl:  accessControl,
+		log:            log.New("totp"),
+	}
+
+	if cfg.TOTPEnabled {
+		s.registerAPIEndpoints()
+	}
+
+	return s
+}
+
+var _ totp.Service = (*TOTPService)(nil)
+
+// IsEnabled checks if the user has two-factor authentication enabled.
+func (s *TOTPService) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	if !s.cfg.TOTPEnabled {
+		return false, nil
+	}
+
+	enrollment, err := s.getUserTOTP(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return enrollment != nil && enrollment.Enabled, nil
+}
+
+// IsRequired checks if the user must use two-factor authentication, because the server or one of the
+// organizations of the user enforces it.
+func (s *TOTPService) IsRequired(ctx context.Context, userID int64) (bool, error) {
+	if !s.cfg.TOTPEnabled {
+		return false, nil
+	}
+	if s.cfg.TOTPEnforced {
+		return true, nil
+	}
+
+	query := &models.GetUserOrgListQuery{UserId: userID}
+	if err := s.sqlStore.GetUserOrgList(ctx, query); err != nil {
+		return false, err
+	}
+
+	for _, org := range query.Result {
+		enforced, err := s.IsEnforcedByOrg(ctx, org.OrgId)
+		if err != nil {
+			return false, err
+		}
+		if enforced {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// IsEnforcedByOrg checks if the organization requires two-factor authentication of its members.
+func (s *TOTPService) IsEnforcedByOrg(ctx context.Context, orgID int64) (bool, error) {
+	value, ok, err := s.kvStore.Get(ctx, orgID, kvNamespace, kvKeyEnforced)
+	if err != nil || !ok {
+		return false, err
+	}
+	return value == "true", nil
+}
+
+// SetEnforcedByOrg sets if the organization requires two-factor authentication of its members.
+func (s *TOTPService) SetEnforcedByOrg(ctx context.Context, orgID int64, enforced bool) error {
+	if !enforced {
+		return s.kvStore.Del(ctx, orgID, kvNamespace, kvKeyEnforced)
+	}
+	return s.kvStore.Set(ctx, orgID, kvNamespace, kvKeyEnforced, "true")
+}
+
+// GetStatus returns the two-factor authentication status of the user.
+func (s *TOTPService) GetStatus(ctx context.Context, userID int64) (*totp.StatusDTO, error) {
+	enrollment, err := s.getUserTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	required, err := s.IsRequired(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &totp.StatusDTO{Required: required}
+	if enrollment != nil && enrollment.Enabled {
+		status.Enabled = true
+		status.RecoveryCodesRemaining = len(enrollment.RecoveryCodes)
+	}
+	return status, nil
+}
+
+// Enroll generates a new secret for the user, that is enabled once the user confirms it with a code.
+func (s *TOTPService) Enroll(ctx context.Context, user *models.User) (*totp.Enrollment, error) {
+	enrollment, err := s.getUserTOTP(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment != nil && enrollment.Enabled {
+		return nil, totp.ErrAlreadyEnabled
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.secretsService.Encrypt(ctx, []byte(secret), secrets.WithoutScope())
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.saveUserTOTP(ctx, &userTOTP{
+		UserID:        user.Id,
+		Secret:        base64.StdEncoding.EncodeToString(encrypted),
+		RecoveryCodes: []string{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &totp.Enrollment{
+		Secret: secret,
+		URL:    keyURI(s.cfg.TOTPIssuer, user.Login, secret),
+	}, nil
+}
+
+// Activate enables the pending enrollment of the user once confirmed with a code, and returns its recovery codes.
+func (s *TOTPService) Activate(ctx context.Context, userID int64, code string) ([]string, error) {
+	enrollment, err := s.getUserTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil {
+		return nil, totp.ErrNotEnrolled
+	}
+	if enrollment.Enabled {
+		return nil, totp.ErrAlreadyEnabled
+	}
+
+	if err := s.verifyTOTP(ctx, enrollment, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment.Enabled = true
+	enrollment.RecoveryCodes = hashes
+	if err := s.saveUserTOTP(ctx, enrollment); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Two-factor authentication enabled", "userId", userID)
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user once confirmed with a code.
+func (s *TOTPService) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	enrollment, err := s.getEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyTOTP(ctx, enrollment, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment.RecoveryCodes = hashes
+	if err := s.saveUserTOTP(ctx, enrollment); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable disables the two-factor authentication of the user once confirmed with a code, unless it is required.
+func (s *TOTPService) Disable(ctx context.Context, userID int64, code string) error {
+	enrollment, err := s.getEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	required, err := s.IsRequired(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return totp.ErrEnforced
+	}
+
+	if err := s.verifyCode(ctx, enrollment, code); err != nil {
+		return err
+	}
+
+	s.log.Info("Two-factor authentication disabled", "userId", userID)
+	return s.deleteUserTOTP(ctx, userID)
+}
+
+// Reset removes the two-factor authentication of the user, for users who lost their authenticator and
+// recovery codes. The user has to enroll again if it is required.
+func (s *TOTPService) Reset(ctx context.Context, userID int64) error {
+	s.log.Info("Two-factor authentication reset", "userId", userID)
+	return s.deleteUserTOTP(ctx, userID)
+}
+
+// CreateChallenge creates the second step of a login, once the password of the user was validated.
+func (s *TOTPService) CreateChallenge(ctx context.Context, user *models.User) (*totp.Challenge, error) {
+	if !s.cfg.TOTPEnabled {
+		return nil, nil
+	}
+
+	enabled, err := s.IsEnabled(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &totp.Challenge{}
+	if !enabled {
+		required, err := s.IsRequired(ctx, user.Id)
+		if err != nil || !required {
+			return nil, err
+		}
+
+		// users complete their enrollment to log in
+		result.Enrollment, err = s.Enroll(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result.Token, err = util.GetRandomString(challengeTokenLength)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &challenge{Token: hashChallengeToken(result.Token), UserID: user.Id, Expires: time.Now().Add(challengeTTL)}
+	if err := s.createChallenge(ctx, item); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// VerifyChallenge checks the code of the second step of a login. A challenge is deleted once used, or after too
+// many invalid codes.
+func (s *TOTPService) VerifyChallenge(ctx context.Context, cmd *totp.VerifyChallengeCommand) (*totp.VerifyChallengeResult, error) {
+	token := hashChallengeToken(cmd.Token)
+	item, err := s.useChallengeAttempt(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, totp.ErrChallengeNotFound
+	}
+
+	enrollment, err := s.getUserTOTP(ctx, item.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil {
+		return nil, totp.ErrChallengeNotFound
+	}
+
+	result := &totp.VerifyChallengeResult{UserID: item.UserID}
+	if enrollment.Enabled {
+		err = s.verifyCode(ctx, enrollment, cmd.Code)
+	} else {
+		result.RecoveryCodes, err = s.Activate(ctx, item.UserID, cmd.Code)
+	}
+
+	if err != nil {
+		if !errors.Is(err, totp.ErrInvalidCode) {
+			return nil, err
+		}
+		return nil, s.failChallenge(ctx, item, cmd.IPAddress)
+	}
+
+	if err := s.deleteChallenge(ctx, token); err != nil {
+		s.log.Warn("Failed to delete two-factor authentication challenge", "error", err)
+	}
+	return result, nil
+}
+
+// failChallenge counts an invalid code as a failed login, so that the brute force protection of logins applies.
+// The attempt itself was counted before checking the code.
+func (s *TOTPService) failChallenge(ctx context.Context, item *challenge, ipAddress string) error {
+	query := &models.GetUserByIdQuery{Id: item.UserID}
+	if err := s.sqlStore.GetUserById(ctx, query); err == nil {
+		cmd := &models.CreateLoginAttemptCommand{Username: query.Result.Login, IpAddress: ipAddress}
+		if err := s.sqlStore.CreateLoginAttempt(ctx, cmd); err != nil {
+			s.log.Error("Failed to save invalid login attempt", "error", err)
+		}
+	}
+
+	if item.Attempts >= maxChallengeAttempts {
+		if err := s.deleteChallenge(ctx, item.Token); err != nil {
+			return err
+		}
+		return totp.ErrTooManyAttempts
+	}
+	return totp.ErrInvalidCode
+}
+
+func (s *TOTPService) getEnabled(ctx context.Context, userID int64) (*userTOTP, error) {
+	enrollment, err := s.getUserTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil || !enrollment.Enabled {
+		return nil, totp.ErrNotEnrolled
+	}
+	return enrollment, nil
+}
+
+// verifyCode checks a code of the authenticator app or a recovery code, which can only be used once.
+func (s *TOTPService) verifyCode(ctx context.Context, enrollment *userTOTP, code string) error {
+	code = normalizeCode(code)
+	if len(code) != recoveryCodeLength {
+		return s.verifyTOTP(ctx, enrollment, code)
+	}
+
+	hash := hashRecoveryCode(code)
+	for i, recoveryCode := range enrollment.RecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(recoveryCode), []byte(hash)) == 1 {
+			enrollment.RecoveryCodes = append(enrollment.RecoveryCodes[:i:i], enrollment.RecoveryCodes[i+1:]...)
+			s.log.Info("Recovery code used", "userId", enrollment.UserID, "remaining", len(enrollment.RecoveryCodes))
+			return s.saveUserTOTP(ctx, enrollment)
+		}
+	}
+	return totp.ErrInvalidCode
+}
+
+// verifyTOTP checks a code of the authenticator app, which can only be used once.
+func (s *TOTPService) verifyTOTP(ctx context.Context, enrollment *userTOTP, code string) error {
+	secret, err := s.decryptSecret(ctx, enrollment)
+	if err != nil {
+		return err
+	}
+
+	step, ok := validateCode(secret, normalizeCode(code), time.Now(), enrollment.LastUsedStep)
+	if !ok {
+		return totp.ErrInvalidCode
+	}
+
+	used, err := s.useStep(ctx, enrollment, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return totp.ErrInvalidCode
+	}
+	return nil
+}
+
+func (s *TOTPService) decryptSecret(ctx context.Context, enrollment *userTOTP) (string, error) {
+	encrypted, err := base64.StdEncoding.DecodeString(enrollment.Secret)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := s.secretsService.Decrypt(ctx, encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt two-factor authentication secret: %w", err)
+	}
+	return string(secret), nil
+}
+
+// generateRecoveryCodes returns new recovery codes, formatted for the user, and their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := util.GetRandomString(recoveryCodeLength, []byte(recoveryCodeAlphabet)...)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashChallengeToken hashes the token of a challenge, so that stored tokens cannot be used to log in.
+func hashChallengeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashRecoveryCode hashes a recovery code. Recovery codes are random, so unlike passwords they don't need a salt.
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(code)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,239 @@
+//go:build integration
+// +build integration
+
+package manager
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/kvstore"
+	"github.com/grafana/grafana/pkg/models"
+	accesscontrolmock "github.com/grafana/grafana/pkg/services/accesscontrol/mock"
+	"github.com/grafana/grafana/pkg/services/secrets/fakes"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/totp"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func setupTestService(t *testing.T) (*TOTPService, *models.User) {
+	t.Helper()
+
+	sqlStore := sqlstore.InitTestDB(t)
+	cfg := setting.NewCfg()
+	cfg.TOTPEnabled = true
+	cfg.TOTPIssuer = "Grafana"
+
+	s := ProvideService(cfg, sqlStore, fakes.NewFakeSecretsService(), kvstore.ProvideService(sqlStore),
+		routing.NewRouteRegister(), accesscontrolmock.New())
+
+	user, err := sqlStore.CreateUser(context.Background(), models.CreateUserCommand{Login: "totp-user", Email: "totp-user@example.com"})
+	require.NoError(t, err)
+	return s, user
+}
+
+// currentCode returns the code of the authenticator app of the user for the next unused step.
+func currentCode(t *testing.T, s *TOTPService, userID int64) string {
+	t.Helper()
+
+	enrollment, err := s.getUserTOTP(context.Background(), userID)
+	require.NoError(t, err)
+	require.NotNil(t, enrollment)
+	secret, err := s.decryptSecret(context.Background(), enrollment)
+	require.NoError(t, err)
+
+	step := timeStep(time.Now())
+	if step <= enrollment.LastUsedStep {
+		step = enrollment.LastUsedStep + 1
+	}
+	code, err := generateCode(secret, step)
+	require.NoError(t, err)
+	return code
+}
+
+func enable(t *testing.T, s *TOTPService, user *models.User) []string {
+	t.Helper()
+
+	_, err := s.Enroll(context.Background(), user)
+	require.NoError(t, err)
+	codes, err := s.Activate(context.Background(), user.Id, currentCode(t, s, user.Id))
+	require.NoError(t, err)
+	return codes
+}
+
+func TestTOTPService_Enrollment(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("enrollment is enabled once confirmed with a code", func(t *testing.T) {
+		s, user := setupTestService(t)
+
+		enrollment, err := s.Enroll(ctx, user)
+		require.NoError(t, err)
+		assert.NotEmpty(t, enrollment.Secret)
+		assert.Contains(t, enrollment.URL, "otpauth://totp/Grafana:totp-user")
+
+		enabled, err := s.IsEnabled(ctx, user.Id)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+
+		_, err = s.Activate(ctx, user.Id, "000000")
+		require.ErrorIs(t, err, totp.ErrInvalidCode)
+
+		codes, err := s.Activate(ctx, user.Id, currentCode(t, s, user.Id))
+		require.NoError(t, err)
+		assert.Len(t, codes, recoveryCodeCount)
+
+		status, err := s.GetStatus(ctx, user.Id)
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.Equal(t, recoveryCodeCount, status.RecoveryCodesRemaining)
+
+		_, err = s.Enroll(ctx, user)
+		require.ErrorIs(t, err, totp.ErrAlreadyEnabled)
+	})
+
+	t.Run("codes cannot be replayed", func(t *testing.T) {
+		s, user := setupTestService(t)
+		_, err := s.Enroll(ctx, user)
+		require.NoError(t, err)
+
+		code := currentCode(t, s, user.Id)
+		_, err = s.Activate(ctx, user.Id, code)
+		require.NoError(t, err)
+
+		_, err = s.RegenerateRecoveryCodes(ctx, user.Id, code)
+		require.ErrorIs(t, err, totp.ErrInvalidCode)
+	})
+
+	t.Run("recovery codes can be used once", func(t *testing.T) {
+		s, user := setupTestService(t)
+		codes := enable(t, s, user)
+
+		err := s.Disable(ctx, user.Id, "wrong-codes")
+		require.ErrorIs(t, err, totp.ErrInvalidCode)
+
+		err = s.Disable(ctx, user.Id, codes[0])
+		require.NoError(t, err)
+
+		enabled, err := s.IsEnabled(ctx, user.Id)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+	})
+
+	t.Run("cannot be disabled when enforced by an organization", func(t *testing.T) {
+		s, user := setupTestService(t)
+		codes := enable(t, s, user)
+		require.NoError(t, s.SetEnforcedByOrg(ctx, user.OrgId, true))
+
+		err := s.Disable(ctx, user.Id, codes[0])
+		require.ErrorIs(t, err, totp.ErrEnforced)
+
+		require.NoError(t, s.Reset(ctx, user.Id))
+		enabled, err := s.IsEnabled(ctx, user.Id)
+		require.NoError(t, err)
+		assert.False(t, enabled)
+	})
+}
+
+func TestTOTPService_Challenge(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("no challenge without two-factor authentication", func(t *testing.T) {
+		s, user := setupTestService(t)
+
+		challenge, err := s.CreateChallenge(ctx, user)
+		require.NoError(t, err)
+		assert.Nil(t, challenge)
+	})
+
+	t.Run("login is completed with a code", func(t *testing.T) {
+		s, user := setupTestService(t)
+		codes := enable(t, s, user)
+
+		challenge, err := s.CreateChallenge(ctx, user)
+		require.NoError(t, err)
+		require.NotNil(t, challenge)
+		assert.Nil(t, challenge.Enrollment)
+
+		_, err = s.VerifyChallenge(ctx, &totp.VerifyChallengeCommand{Token: challenge.Token, Code: "000000"})
+		require.ErrorIs(t, err, totp.ErrInvalidCode)
+
+		result, err := s.VerifyChallenge(ctx, &totp.VerifyChallengeCommand{Token: challenge.Token, Code: codes[1]})
+		require.NoError(t, err)
+		assert.Equal(t, user.Id, result.UserID)
+
+		_, err = s.VerifyChallenge(ctx, &totp.VerifyChallengeCommand{Token: challenge.Token, Code: codes[2]})
+		require.ErrorIs(t, err, totp.ErrChallengeNotFound)
+	})
+
+	t.Run("challenge is deleted after too many invalid codes", func(t *testing.T) {
+		s, user := setupTestService(t)
+		enable(t, s, user)
+
+		challenge, err := s.CreateChallenge(ctx, user)
+		require.NoError(t, err)
+
+		for i := 1; i < maxChallengeAttempts; i++ {
+			_, err = s.VerifyChallenge(ctx, &totp.VerifyChallengeCommand{Token: challenge.Token, Code: "000000"})
+			require.ErrorIs(t, err, totp.ErrInvalidCode)
+		}
+		_, err = s.VerifyChallenge(ctx, &totp.VerifyChallengeCommand{Token: challenge.Token, Code: "000000"})
+		require.ErrorIs(t, err, totp.ErrTooManyAttempts)
+
+		_, err = s.VerifyChallenge(ctx, &totp.VerifyChallengeCommand{Token: challenge.Token, Code: currentCode(t, s, user.Id)})
+		require.ErrorIs(t, err, totp.ErrChallengeNotFound)
+	})
+
+	t.Run("concurrent invalid codes cannot exceed the attempts", func(t *testing.T) {
+		s, user := setupTestService(t)
+		enable(t, s, user)
+
+		challenge, err := s.CreateChallenge(ctx, user)
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 3*maxChallengeAttempts)
+		for i := 0; i < 3*maxChallengeAttempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := s.VerifyChallenge(ctx, &totp.VerifyChallengeCommand{Token: challenge.Token, Code: "000000"})
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		counts := map[error]int{}
+		for err := range errs {
+			counts[err]++
+		}
+		assert.Equal(t, maxChallengeAttempts-1, counts[totp.ErrInvalidCode])
+		assert.Equal(t, 1, counts[totp.ErrTooManyAttempts])
+		assert.Equal(t, 2*maxChallengeAttempts, counts[totp.ErrChallengeNotFound])
+	})
+
+	t.Run("users enroll on login when required", func(t *testing.T) {
+		s, user := setupTestService(t)
+		s.cfg.TOTPEnforced = true
+
+		challenge, err := s.CreateChallenge(ctx, user)
+		require.NoError(t, err)
+		require.NotNil(t, challenge)
+		require.NotNil(t, challenge.Enrollment)
+
+		result, err := s.VerifyChallenge(ctx, &totp.VerifyChallengeCommand{Token: challenge.Token, Code: currentCode(t, s, user.Id)})
+		require.NoError(t, err)
+		assert.Len(t, result.RecoveryCodes, recoveryCodeCount)
+
+		enabled, err := s.IsEnabled(ctx, user.Id)
+		require.NoError(t, err)
+		assert.True(t, enabled)
+	})
+}
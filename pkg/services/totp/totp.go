@@ -0,0 +1,79 @@
+package totp
+
+import (
+	"context"
+	"errors"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+var (
+	ErrNotEnrolled        = errors.New("two-factor authentication is not enrolled")
+	ErrAlreadyEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrEnforced           = errors.New("two-factor authentication is required and cannot be disabled")
+	ErrInvalidCode        = errors.New("invalid two-factor authentication code")
+	ErrChallengeNotFound  = errors.New("two-factor authentication challenge not found or expired")
+	ErrTooManyAttempts    = errors.New("too many invalid two-factor authentication codes")
+	ErrTwoFactorDisabled  = errors.New("two-factor authentication is disabled")
+	ErrBasicAuthForbidden = errors.New("basic authentication is not allowed for users with two-factor authentication enabled or required")
+)
+
+// Service is the TOTP (RFC 6238) two-factor authentication of the users that log in with a Grafana password
+type Service interface {
+	// IsEnabled checks if the user has two-factor authentication enabled.
+	IsEnabled(ctx context.Context, userID int64) (bool, error)
+	// IsRequired checks if the user must use two-factor authentication, because the server or one of the
+	// organizations of the user enforces it.
+	IsRequired(ctx context.Context, userID int64) (bool, error)
+	// CreateChallenge creates the second step of a login, once the password of the user was validated. It
+	// returns nil if the user does not need a second factor.
+	CreateChallenge(ctx context.Context, user *models.User) (*Challenge, error)
+	// VerifyChallenge checks the code of the second step of a login, and returns the user to log in.
+	VerifyChallenge(ctx context.Context, cmd *VerifyChallengeCommand) (*VerifyChallengeResult, error)
+}
+
+// Challenge is the second step of a login, to complete with a code of the authenticator app of the user or
+// with a recovery code
+type Challenge struct {
+	Token string `json:"challenge"`
+	// Enrollment is set when the user must enroll before completing the login
+	Enrollment *Enrollment `json:"enrollment,omitempty"`
+}
+
+// Enrollment is the secret to add to an authenticator app
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+}
+
+type VerifyChallengeCommand struct {
+	Token     string `json:"challenge"`
+	Code      string `json:"code"`
+	IPAddress string `json:"-"`
+}
+
+type VerifyChallengeResult struct {
+	UserID int64
+	// RecoveryCodes are set when the user enrolled with the challenge
+	RecoveryCodes []string
+}
+
+// StatusDTO is the two-factor authentication status of a user
+type StatusDTO struct {
+	Enabled                bool `json:"enabled"`
+	Required               bool `json:"required"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining"`
+}
+
+type CodeCommand struct {
+	Code string `json:"code"`
+}
+
+type RecoveryCodesDTO struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// OrgPolicyDTO is the two-factor authentication policy of an organization
+type OrgPolicyDTO struct {
+	Enforced bool `json:"enforced"`
+}
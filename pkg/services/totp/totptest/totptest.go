@@ -0,0 +1,32 @@
+package totptest
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/totp"
+)
+
+type FakeService struct {
+	ExpectedEnabled   bool
+	ExpectedRequired  bool
+	ExpectedChallenge *totp.Challenge
+	ExpectedResult    *totp.VerifyChallengeResult
+	ExpectedError     error
+}
+
+func (f *FakeService) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	return f.ExpectedEnabled, f.ExpectedError
+}
+
+func (f *FakeService) IsRequired(ctx context.Context, userID int64) (bool, error) {
+	return f.ExpectedRequired, f.ExpectedError
+}
+
+func (f *FakeService) CreateChallenge(ctx context.Context, user *models.User) (*totp.Challenge, error) {
+	return f.ExpectedChallenge, f.ExpectedError
+}
+
+func (f *FakeService) VerifyChallenge(ctx context.Context, cmd *totp.VerifyChallengeCommand) (*totp.VerifyChallengeResult, error) {
+	return f.ExpectedResult, f.ExpectedError
+}
@@ -245,6 +245,12 @@ type Cfg struct {
 	RendererCallbackUrl            string
 	RendererConcurrentRequestLimit int
 
+	// Scheduled reports
+	ReportsEnabled             bool
+	ReportsRenderingTimeout    time.Duration
+	ReportsImageScaleFactor    float64
+	ReportsMaxAttachmentSizeMB int
+
 	// Security
 	DisableInitAdminCreation          bool
 	DisableBruteForceLoginProtection  bool
@@ -285,17 +291,32 @@ type Cfg struct {
 
 	// Dashboards
 	DefaultHomeDashboardPath string
+	MaxFolderDepth           int
+	// PublicDashboardMaxTimeRange is the longest time range the panels of public dashboards can be queried for
+	PublicDashboardMaxTimeRange time.Duration
+	// DashboardVersionRetention thins the dashboard versions out as they get older, instead of keeping
+	// DashboardVersionsToKeep versions, unless organizations override it
+	DashboardVersionRetention DashboardVersionRetentionSettings
+	// DashboardVersionProtectedTags are the tags of the dashboard versions that are never deleted
+	DashboardVersionProtectedTags []string
+	// DashboardLintOnSave checks the dashboards with the lint rules when they are saved, imported or provisioned
+	DashboardLintOnSave bool
 
 	// Auth
 	LoginCookieName              string
 	LoginMaxInactiveLifetime     time.Duration
 	LoginMaxLifetime             time.Duration
 	TokenRotationIntervalMinutes int
-	SigV4AuthEnabled             bool
-	SigV4VerboseLogging          bool
-	BasicAuthEnabled             bool
-	AdminUser                    string
-	AdminPassword                string
+	// LoginIdleTimeout logs out the users whose session has not been used for longer, disabled if 0
+	LoginIdleTimeout time.Duration
+	// LoginMaxConcurrentSessions is the maximum number of sessions of a user, unlimited if 0
+	LoginMaxConcurrentSessions    int64
+	LoginConcurrentSessionsPolicy string
+	SigV4AuthEnabled              bool
+	SigV4VerboseLogging           bool
+	BasicAuthEnabled              bool
+	AdminUser                     string
+	AdminPassword                 string
 
 	// AWS Plugin Auth
 	AWSAllowedAuthProviders []string
@@ -331,6 +352,38 @@ type Cfg struct {
 	JWTAuthJWKSetFile    string
 	JWTAuthAutoSignUp    bool
 
+	// SCIM provisioning
+	SCIMEnabled bool
+	SCIMOrgRole string
+
+	// TOTP two-factor authentication
+	TOTPEnabled  bool
+	TOTPEnforced bool
+	TOTPIssuer   string
+
+	// Password policy of the users that log in with a Grafana password
+	PasswordPolicyEnabled          bool
+	PasswordPolicyMinLength        int
+	PasswordPolicyRequireUppercase bool
+	PasswordPolicyRequireLowercase bool
+	PasswordPolicyRequireDigit     bool
+	PasswordPolicyRequireSymbol    bool
+	PasswordPolicyRejectUserInfo   bool
+	PasswordPolicyBreachedFile     string
+	PasswordPolicyHistoryCount     int
+	PasswordPolicyMaxAgeDays       int
+	PasswordPolicyLockoutAttempts  int
+	PasswordPolicyLockoutDuration  time.Duration
+
+	// Team sync from the groups of external users
+	TeamSyncEnabled    bool
+	TeamSyncConfigFile string
+
+	// JWT bearer token exchange of machine clients
+	JWTBearerEnabled    bool
+	JWTBearerConfigFile string
+	JWTBearerTokenTTL   time.Duration
+
 	// Dataproxy
 	SendUserHeader                 bool
 	DataProxyLogging               bool
@@ -372,6 +425,7 @@ type Cfg struct {
 	AlertingAnnotationCleanupSetting   AnnotationCleanupSettings
 	DashboardAnnotationCleanupSettings AnnotationCleanupSettings
 	APIAnnotationCleanupSettings       AnnotationCleanupSettings
+	AnnotationRetentionRules           []AnnotationRetentionRule
 
 	// Sentry config
 	Sentry Sentry
@@ -381,6 +435,12 @@ type Cfg struct {
 
 	// Snapshots
 	SnapshotPublicMode bool
+	// SnapshotStorageURL is the URL of the bucket snapshots are stored in, local disk when empty
+	SnapshotStorageURL string
+	// SnapshotMaxSize is the maximum size of a snapshot in bytes, 0 for no limit
+	SnapshotMaxSize int64
+	// SnapshotOrgQuota is the storage available for the snapshots of an organization in bytes, -1 for no limit
+	SnapshotOrgQuota int64
 
 	ErrTemplateName string
 
@@ -398,8 +458,10 @@ type Cfg struct {
 	FeedbackLinksEnabled                bool
 
 	// LDAP
-	LDAPEnabled     bool
-	LDAPAllowSignup bool
+	LDAPEnabled           bool
+	LDAPAllowSignup       bool
+	LDAPSyncCron          string
+	LDAPActiveSyncEnabled bool
 
 	Quota QuotaSettings
 
@@ -442,6 +504,12 @@ type Cfg struct {
 	// Query history
 	QueryHistoryEnabled bool
 
+	// Query audit
+	QueryAudit QueryAuditSettings
+
+	// Query splitting
+	QuerySplitting QuerySplittingSettings
+
 	DashboardPreviews DashboardPreviewsSettings
 }
 
@@ -582,7 +650,7 @@ func (cfg *Cfg) readGrafanaEnvironmentMetrics() error {
 	return nil
 }
 
-func (cfg *Cfg) readAnnotationSettings() {
+func (cfg *Cfg) readAnnotationSettings() error {
 	section := cfg.Raw.Section("annotations")
 	cfg.AnnotationCleanupJobBatchSize = section.Key("cleanupjob_batchsize").MustInt64(100)
 
@@ -605,6 +673,45 @@ func (cfg *Cfg) readAnnotationSettings() {
 	cfg.AlertingAnnotationCleanupSetting = newAnnotationCleanupSettings(alertingSection, "max_annotation_age")
 	cfg.DashboardAnnotationCleanupSettings = newAnnotationCleanupSettings(dashboardAnnotation, "max_age")
 	cfg.APIAnnotationCleanupSettings = newAnnotationCleanupSettings(apiIAnnotation, "max_age")
+
+	return cfg.readAnnotationRetentionRules()
+}
+
+// readAnnotationRetentionRules reads the [annotations.retention.<name>] sections, in the order of the
+// configuration files.
+func (cfg *Cfg) readAnnotationRetentionRules() error {
+	cfg.AnnotationRetentionRules = []AnnotationRetentionRule{}
+	for _, section := range cfg.Raw.Sections() {
+		if !strings.HasPrefix(section.Name(), "annotations.retention.") {
+			continue
+		}
+
+		rule := AnnotationRetentionRule{
+			Name:          strings.TrimPrefix(section.Name(), "annotations.retention."),
+			OrgID:         section.Key("org_id").MustInt64(0),
+			DashboardUIDs: util.SplitString(section.Key("dashboard_uids").MustString("")),
+		}
+		// tags may contain spaces, they are only separated by commas
+		for _, tag := range strings.Split(section.Key("tags").MustString(""), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				rule.Tags = append(rule.Tags, tag)
+			}
+		}
+		if maxAge := section.Key("max_age").MustString(""); maxAge != "" {
+			duration, err := gtime.ParseDuration(maxAge)
+			if err != nil {
+				return fmt.Errorf("invalid max_age in [%s] configuration: %w", section.Name(), err)
+			}
+			rule.MaxAge = duration
+		}
+		rule.MaxCount = section.Key("max_annotations_to_keep").MustInt64(0)
+
+		if rule.OrgID == 0 && len(rule.DashboardUIDs) == 0 && len(rule.Tags) == 0 {
+			return fmt.Errorf("[%s] configuration must match on org_id, dashboard_uids or tags", section.Name())
+		}
+		cfg.AnnotationRetentionRules = append(cfg.AnnotationRetentionRules, rule)
+	}
+	return nil
 }
 
 func (cfg *Cfg) readExpressionsSettings() {
@@ -617,6 +724,55 @@ type AnnotationCleanupSettings struct {
 	MaxCount int64
 }
 
+// AnnotationRetentionRule keeps the annotations of the organization, of the dashboards and with all the tags
+// it matches on, for its own max age and count instead of the ones of their type.
+type AnnotationRetentionRule struct {
+	AnnotationCleanupSettings
+	Name          string
+	OrgID         int64
+	DashboardUIDs []string
+	Tags          []string
+}
+
+// DashboardVersionRetentionSettings keeps all the dashboard versions younger than KeepAll, then the latest
+// version of each day until KeepDaily, and the latest version of each week until KeepWeekly. Older versions
+// are deleted. The policy is disabled when none is set.
+type DashboardVersionRetentionSettings struct {
+	KeepAll    time.Duration
+	KeepDaily  time.Duration
+	KeepWeekly time.Duration
+}
+
+// Enabled returns true if the policy is enabled.
+func (s DashboardVersionRetentionSettings) Enabled() bool {
+	return s.KeepAll > 0 || s.KeepDaily > 0 || s.KeepWeekly > 0
+}
+
+// ParseDashboardVersionRetention parses the durations of a dashboard version retention policy, e.g. 7d.
+// Empty durations are not set, and the durations that are set must not decrease.
+func ParseDashboardVersionRetention(keepAll, keepDaily, keepWeekly string) (DashboardVersionRetentionSettings, error) {
+	var durations [3]time.Duration
+	var longest time.Duration
+	for i, value := range []string{keepAll, keepDaily, keepWeekly} {
+		if value == "" {
+			continue
+		}
+		duration, err := gtime.ParseDuration(value)
+		if err != nil {
+			return DashboardVersionRetentionSettings{}, fmt.Errorf("invalid dashboard version retention %q: %w", value, err)
+		}
+		if duration < 0 {
+			return DashboardVersionRetentionSettings{}, fmt.Errorf("invalid dashboard version retention %q: must not be negative", value)
+		}
+		if duration < longest {
+			return DashboardVersionRetentionSettings{}, fmt.Errorf("invalid dashboard version retention %q: must not be shorter than the previous ones", value)
+		}
+		durations[i] = duration
+		longest = duration
+	}
+	return DashboardVersionRetentionSettings{KeepAll: durations[0], KeepDaily: durations[1], KeepWeekly: durations[2]}, nil
+}
+
 func EnvKey(sectionName string, keyName string) string {
 	sN := strings.ToUpper(strings.ReplaceAll(sectionName, ".", "_"))
 	sN = strings.ReplaceAll(sN, "-", "_")
@@ -918,6 +1074,25 @@ func (cfg *Cfg) Load(args CommandLineArgs) error {
 	MinRefreshInterval = valueAsString(dashboards, "min_refresh_interval", "5s")
 
 	cfg.DefaultHomeDashboardPath = dashboards.Key("default_home_dashboard_path").MustString("")
+	cfg.MaxFolderDepth = dashboards.Key("max_folder_depth").MustInt(1)
+	if cfg.MaxFolderDepth < 1 {
+		cfg.MaxFolderDepth = 1
+	}
+	publicMaxTimeRange, err := gtime.ParseDuration(valueAsString(dashboards, "public_max_time_range", "30d"))
+	if err != nil {
+		return err
+	}
+	cfg.PublicDashboardMaxTimeRange = publicMaxTimeRange
+	cfg.DashboardVersionRetention, err = ParseDashboardVersionRetention(
+		valueAsString(dashboards, "versions_keep_all", ""),
+		valueAsString(dashboards, "versions_keep_daily", ""),
+		valueAsString(dashboards, "versions_keep_weekly", ""),
+	)
+	if err != nil {
+		return err
+	}
+	cfg.DashboardVersionProtectedTags = util.SplitString(dashboards.Key("versions_protected_tags").String())
+	cfg.DashboardLintOnSave = dashboards.Key("lint_on_save").MustBool(true)
 
 	if err := readUserSettings(iniFile, cfg); err != nil {
 		return err
@@ -929,6 +1104,8 @@ func (cfg *Cfg) Load(args CommandLineArgs) error {
 		return err
 	}
 
+	cfg.readReportingSettings(iniFile)
+
 	cfg.TempDataLifetime = iniFile.Section("paths").Key("temp_data_lifetime").MustDuration(time.Second * 3600 * 24)
 	cfg.MetricsEndpointEnabled = iniFile.Section("metrics").Key("enabled").MustBool(true)
 	cfg.MetricsEndpointBasicAuthUsername = valueAsString(iniFile.Section("metrics"), "basic_auth_username", "")
@@ -972,6 +1149,14 @@ func (cfg *Cfg) Load(args CommandLineArgs) error {
 	queryHistory := iniFile.Section("query_history")
 	cfg.QueryHistoryEnabled = queryHistory.Key("enabled").MustBool(false)
 
+	if err := cfg.readQueryAuditSettings(iniFile); err != nil {
+		return err
+	}
+
+	if err := cfg.readQuerySplittingSettings(iniFile); err != nil {
+		return err
+	}
+
 	panelsSection := iniFile.Section("panels")
 	cfg.DisableSanitizeHtml = panelsSection.Key("disable_sanitize_html").MustBool(false)
 
@@ -998,7 +1183,9 @@ func (cfg *Cfg) Load(args CommandLineArgs) error {
 	cfg.readSessionConfig()
 	cfg.readSmtpSettings()
 	cfg.readQuotaSettings()
-	cfg.readAnnotationSettings()
+	if err := cfg.readAnnotationSettings(); err != nil {
+		return err
+	}
 	cfg.readExpressionsSettings()
 	if err := cfg.readGrafanaEnvironmentMetrics(); err != nil {
 		return err
@@ -1073,9 +1260,11 @@ func (cfg *Cfg) readLDAPConfig() {
 	ldapSec := cfg.Raw.Section("auth.ldap")
 	LDAPConfigFile = ldapSec.Key("config_file").String()
 	LDAPSyncCron = ldapSec.Key("sync_cron").String()
+	cfg.LDAPSyncCron = LDAPSyncCron
 	LDAPEnabled = ldapSec.Key("enabled").MustBool(false)
 	cfg.LDAPEnabled = LDAPEnabled
 	LDAPActiveSyncEnabled = ldapSec.Key("active_sync_enabled").MustBool(false)
+	cfg.LDAPActiveSyncEnabled = LDAPActiveSyncEnabled
 	LDAPAllowSignup = ldapSec.Key("allow_sign_up").MustBool(true)
 	cfg.LDAPAllowSignup = LDAPAllowSignup
 }
@@ -1277,6 +1466,18 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 		cfg.TokenRotationIntervalMinutes = 2
 	}
 
+	idleTimeoutVal := valueAsString(auth, "login_idle_timeout", "0")
+	cfg.LoginIdleTimeout, err = gtime.ParseDuration(idleTimeoutVal)
+	if err != nil {
+		return err
+	}
+
+	cfg.LoginMaxConcurrentSessions = auth.Key("login_maximum_concurrent_sessions").MustInt64(0)
+	cfg.LoginConcurrentSessionsPolicy = valueAsString(auth, "login_concurrent_sessions_policy", "evict_oldest")
+	if cfg.LoginConcurrentSessionsPolicy != "evict_oldest" && cfg.LoginConcurrentSessionsPolicy != "reject" {
+		return fmt.Errorf("invalid login_concurrent_sessions_policy %q, must be evict_oldest or reject", cfg.LoginConcurrentSessionsPolicy)
+	}
+
 	DisableLoginForm = auth.Key("disable_login_form").MustBool(false)
 	DisableSignoutMenu = auth.Key("disable_signout_menu").MustBool(false)
 	OAuthAutoLogin = auth.Key("oauth_auto_login").MustBool(false)
@@ -1314,6 +1515,43 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 	cfg.JWTAuthJWKSetFile = valueAsString(authJWT, "jwk_set_file", "")
 	cfg.JWTAuthAutoSignUp = authJWT.Key("auto_sign_up").MustBool(false)
 
+	// SCIM provisioning
+	authSCIM := iniFile.Section("auth.scim")
+	cfg.SCIMEnabled = authSCIM.Key("enabled").MustBool(false)
+	cfg.SCIMOrgRole = authSCIM.Key("org_role").In("Viewer", []string{"Editor", "Admin", "Viewer"})
+
+	// TOTP two-factor authentication
+	authTOTP := iniFile.Section("auth.totp")
+	cfg.TOTPEnabled = authTOTP.Key("enabled").MustBool(true)
+	cfg.TOTPEnforced = authTOTP.Key("enforced").MustBool(false)
+	cfg.TOTPIssuer = valueAsString(authTOTP, "issuer", "Grafana")
+
+	// Password policy
+	passwordPolicy := iniFile.Section("auth.password_policy")
+	cfg.PasswordPolicyEnabled = passwordPolicy.Key("enabled").MustBool(false)
+	cfg.PasswordPolicyMinLength = passwordPolicy.Key("min_length").MustInt(12)
+	cfg.PasswordPolicyRequireUppercase = passwordPolicy.Key("require_uppercase").MustBool(true)
+	cfg.PasswordPolicyRequireLowercase = passwordPolicy.Key("require_lowercase").MustBool(true)
+	cfg.PasswordPolicyRequireDigit = passwordPolicy.Key("require_digit").MustBool(true)
+	cfg.PasswordPolicyRequireSymbol = passwordPolicy.Key("require_symbol").MustBool(false)
+	cfg.PasswordPolicyRejectUserInfo = passwordPolicy.Key("reject_user_info").MustBool(true)
+	cfg.PasswordPolicyBreachedFile = valueAsString(passwordPolicy, "breached_passwords_file", "")
+	cfg.PasswordPolicyHistoryCount = passwordPolicy.Key("history_count").MustInt(0)
+	cfg.PasswordPolicyMaxAgeDays = passwordPolicy.Key("max_age_days").MustInt(0)
+	cfg.PasswordPolicyLockoutAttempts = passwordPolicy.Key("lockout_attempts").MustInt(0)
+	cfg.PasswordPolicyLockoutDuration = passwordPolicy.Key("lockout_duration").MustDuration(30 * time.Minute)
+
+	// Team sync
+	teamSync := iniFile.Section("auth.team_sync")
+	cfg.TeamSyncEnabled = teamSync.Key("enabled").MustBool(false)
+	cfg.TeamSyncConfigFile = teamSync.Key("config_file").String()
+
+	// JWT bearer token exchange
+	jwtBearer := iniFile.Section("auth.jwt_bearer")
+	cfg.JWTBearerEnabled = jwtBearer.Key("enabled").MustBool(false)
+	cfg.JWTBearerConfigFile = valueAsString(jwtBearer, "config_file", "")
+	cfg.JWTBearerTokenTTL = jwtBearer.Key("token_ttl").MustDuration(10 * time.Minute)
+
 	authProxy := iniFile.Section("auth.proxy")
 	AuthProxyEnabled = authProxy.Key("enabled").MustBool(false)
 	cfg.AuthProxyEnabled = AuthProxyEnabled
@@ -1423,6 +1661,14 @@ func (cfg *Cfg) readRenderingSettings(iniFile *ini.File) error {
 	return nil
 }
 
+func (cfg *Cfg) readReportingSettings(iniFile *ini.File) {
+	reporting := iniFile.Section("reporting")
+	cfg.ReportsEnabled = reporting.Key("enabled").MustBool(true)
+	cfg.ReportsRenderingTimeout = reporting.Key("rendering_timeout").MustDuration(10 * time.Second)
+	cfg.ReportsImageScaleFactor = reporting.Key("image_scale_factor").MustFloat64(2)
+	cfg.ReportsMaxAttachmentSizeMB = reporting.Key("max_attachment_size_mb").MustInt(10)
+}
+
 func readAlertingSettings(iniFile *ini.File) error {
 	alerting := iniFile.Section("alerting")
 	enabled, err := alerting.Key("enabled").Bool()
@@ -1455,6 +1701,12 @@ func readSnapshotsSettings(cfg *Cfg, iniFile *ini.File) error {
 	ExternalEnabled = snapshots.Key("external_enabled").MustBool(true)
 	SnapShotRemoveExpired = snapshots.Key("snapshot_remove_expired").MustBool(true)
 	cfg.SnapshotPublicMode = snapshots.Key("public_mode").MustBool(false)
+	cfg.SnapshotStorageURL = valueAsString(snapshots, "storage_url", "")
+	cfg.SnapshotMaxSize = snapshots.Key("max_size_mb").MustInt64(0) * 1024 * 1024
+	cfg.SnapshotOrgQuota = snapshots.Key("org_quota_mb").MustInt64(-1)
+	if cfg.SnapshotOrgQuota > 0 {
+		cfg.SnapshotOrgQuota *= 1024 * 1024
+	}
 
 	return nil
 }
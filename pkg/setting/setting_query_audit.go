@@ -0,0 +1,78 @@
+package setting
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"gopkg.in/ini.v1"
+
+	"github.com/grafana/grafana/pkg/util"
+)
+
+type QueryAuditSettings struct {
+	Enabled            bool
+	Sinks              []string
+	LogQueryText       bool
+	MaxQueryTextLength int
+	BufferSize         int
+
+	// file sink
+	FilePath        string
+	FileMaxLines    int
+	FileMaxSizeMB   int
+	FileDailyRotate bool
+	FileMaxDays     int64
+
+	// sql sink
+	SQLRetention time.Duration
+
+	// loki sink
+	LokiURL               string
+	LokiTenantID          string
+	LokiBasicAuthUsername string
+	LokiBasicAuthPassword string
+	LokiTimeout           time.Duration
+}
+
+func (cfg *Cfg) readQueryAuditSettings(iniFile *ini.File) error {
+	s := QueryAuditSettings{}
+
+	section := iniFile.Section("query_audit")
+	s.Enabled = section.Key("enabled").MustBool(false)
+	s.Sinks = util.SplitString(valueAsString(section, "sinks", "file"))
+	for i, sink := range s.Sinks {
+		s.Sinks[i] = strings.ToLower(sink)
+	}
+	s.LogQueryText = section.Key("log_query_text").MustBool(true)
+	s.MaxQueryTextLength = section.Key("max_query_text_length").MustInt(10000)
+	s.BufferSize = section.Key("buffer_size").MustInt(10000)
+	if s.BufferSize <= 0 {
+		s.BufferSize = 10000
+	}
+
+	fileSection := iniFile.Section("query_audit.file")
+	s.FilePath = valueAsString(fileSection, "path", filepath.Join(cfg.LogsPath, "query_audit.log"))
+	s.FileMaxLines = fileSection.Key("max_lines").MustInt(1000000)
+	s.FileMaxSizeMB = fileSection.Key("max_size_mb").MustInt(256)
+	s.FileDailyRotate = fileSection.Key("daily_rotate").MustBool(true)
+	s.FileMaxDays = fileSection.Key("max_days").MustInt64(7)
+
+	sqlSection := iniFile.Section("query_audit.sql")
+	retention, err := gtime.ParseDuration(valueAsString(sqlSection, "retention", "90d"))
+	if err != nil {
+		return err
+	}
+	s.SQLRetention = retention
+
+	lokiSection := iniFile.Section("query_audit.loki")
+	s.LokiURL = valueAsString(lokiSection, "url", "")
+	s.LokiTenantID = valueAsString(lokiSection, "tenant_id", "")
+	s.LokiBasicAuthUsername = valueAsString(lokiSection, "basic_auth_username", "")
+	s.LokiBasicAuthPassword = valueAsString(lokiSection, "basic_auth_password", "")
+	s.LokiTimeout = lokiSection.Key("timeout").MustDuration(10 * time.Second)
+
+	cfg.QueryAudit = s
+	return nil
+}
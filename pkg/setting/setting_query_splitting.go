@@ -0,0 +1,59 @@
+package setting
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/gtime"
+	"gopkg.in/ini.v1"
+)
+
+type QuerySplittingSettings struct {
+	Enabled        bool
+	MaxConcurrency int
+	// Intervals holds the sub-range length per data source type.
+	// Only data source types listed here are split.
+	Intervals   map[string]time.Duration
+	CacheTTL    time.Duration
+	CacheMinAge time.Duration
+}
+
+func (cfg *Cfg) readQuerySplittingSettings(iniFile *ini.File) error {
+	s := QuerySplittingSettings{
+		Intervals: map[string]time.Duration{},
+	}
+
+	section := iniFile.Section("query_splitting")
+	s.Enabled = section.Key("enabled").MustBool(false)
+	s.MaxConcurrency = section.Key("max_concurrency").MustInt(4)
+	if s.MaxConcurrency <= 0 {
+		s.MaxConcurrency = 1
+	}
+
+	for dsType, key := range map[string]string{
+		"prometheus": "prometheus_interval",
+		"loki":       "loki_interval",
+	} {
+		interval, err := gtime.ParseDuration(valueAsString(section, key, "1d"))
+		if err != nil {
+			return err
+		}
+		if interval > 0 {
+			s.Intervals[dsType] = interval
+		}
+	}
+
+	cacheTTL, err := gtime.ParseDuration(valueAsString(section, "cache_ttl", "1h"))
+	if err != nil {
+		return err
+	}
+	s.CacheTTL = cacheTTL
+
+	cacheMinAge, err := gtime.ParseDuration(valueAsString(section, "cache_min_age", "10m"))
+	if err != nil {
+		return err
+	}
+	s.CacheMinAge = cacheMinAge
+
+	cfg.QuerySplitting = s
+	return nil
+}
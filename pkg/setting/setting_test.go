@@ -680,3 +680,57 @@ func TestAlertingEnabled(t *testing.T) {
 		})
 	}
 }
+
+func TestAnnotationRetentionRules(t *testing.T) {
+	f, err := ini.Load([]byte(`
+[annotations.retention.deploys]
+tags = deploy, service:api gateway
+max_age = 2w
+
+[annotations.retention.releases]
+org_id = 2
+dashboard_uids = abc, def
+max_annotations_to_keep = 100
+`))
+	require.NoError(t, err)
+	cfg := NewCfg()
+	cfg.Raw = f
+	require.NoError(t, cfg.readAnnotationSettings())
+	require.Equal(t, []AnnotationRetentionRule{
+		{
+			Name:                      "deploys",
+			DashboardUIDs:             []string{},
+			Tags:                      []string{"deploy", "service:api gateway"},
+			AnnotationCleanupSettings: AnnotationCleanupSettings{MaxAge: 14 * 24 * time.Hour},
+		},
+		{
+			Name:                      "releases",
+			OrgID:                     2,
+			DashboardUIDs:             []string{"abc", "def"},
+			AnnotationCleanupSettings: AnnotationCleanupSettings{MaxCount: 100},
+		},
+	}, cfg.AnnotationRetentionRules)
+
+	f, err = ini.Load([]byte("[annotations.retention.all]\nmax_age = 7d\n"))
+	require.NoError(t, err)
+	cfg.Raw = f
+	require.Error(t, cfg.readAnnotationSettings())
+}
+
+func TestParseDashboardVersionRetention(t *testing.T) {
+	policy, err := ParseDashboardVersionRetention("", "", "")
+	require.NoError(t, err)
+	require.False(t, policy.Enabled())
+
+	policy, err = ParseDashboardVersionRetention("7d", "", "8w")
+	require.NoError(t, err)
+	require.True(t, policy.Enabled())
+	require.Equal(t, DashboardVersionRetentionSettings{KeepAll: 7 * 24 * time.Hour, KeepWeekly: 56 * 24 * time.Hour}, policy)
+
+	_, err = ParseDashboardVersionRetention("30d", "7d", "")
+	require.Error(t, err)
+	_, err = ParseDashboardVersionRetention("-1d", "", "")
+	require.Error(t, err)
+	_, err = ParseDashboardVersionRetention("a week", "", "")
+	require.Error(t, err)
+}
@@ -82,8 +82,20 @@ func newInstanceSettings(cfg *setting.Cfg) datasource.InstanceFactoryFunc {
 		if cfg.Env == setting.Dev {
 			logger.Debug("getEngine", "connection", cnnstr)
 		}
+		driverName := "mssql"
+		tunnelCfg, err := sqleng.TunnelConfig(settings)
+		if err != nil {
+			return nil, err
+		}
+		if tunnelCfg != nil {
+			driverName, err = sqleng.RegisterTunnelDriver(driverName, tunnelCfg, newTunnelDriver)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		config := sqleng.DataPluginConfiguration{
-			DriverName:        "mssql",
+			DriverName:        driverName,
 			ConnectionString:  cnnstr,
 			DSInfo:            dsInfo,
 			MetricColumnTypes: []string{"VARCHAR", "CHAR", "NVARCHAR", "NCHAR"},
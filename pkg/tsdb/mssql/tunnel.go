@@ -0,0 +1,35 @@
+package mssql
+
+import (
+	"context"
+	"database/sql/driver"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"github.com/grafana/grafana/pkg/infra/tunnel"
+)
+
+// tunnelDriver opens MSSQL connections through a tunnel.
+type tunnelDriver struct {
+	dialer tunnel.Dialer
+}
+
+func newTunnelDriver(dialer tunnel.Dialer) driver.Driver {
+	return &tunnelDriver{dialer: dialer}
+}
+
+func (d *tunnelDriver) Open(dsn string) (driver.Conn, error) {
+	connector, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(context.Background())
+}
+
+func (d *tunnelDriver) OpenConnector(dsn string) (driver.Connector, error) {
+	connector, err := mssql.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	connector.Dialer = d.dialer
+	return connector, nil
+}
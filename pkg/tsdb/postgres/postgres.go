@@ -86,8 +86,20 @@ func (s *Service) newInstanceSettings(cfg *setting.Cfg) datasource.InstanceFacto
 			logger.Debug("getEngine", "connection", cnnstr)
 		}
 
+		driverName := "postgres"
+		tunnelCfg, err := sqleng.TunnelConfig(settings)
+		if err != nil {
+			return nil, err
+		}
+		if tunnelCfg != nil {
+			driverName, err = sqleng.RegisterTunnelDriver(driverName, tunnelCfg, newTunnelDriver)
+			if err != nil {
+				return nil, err
+			}
+		}
+
 		config := sqleng.DataPluginConfiguration{
-			DriverName:        "postgres",
+			DriverName:        driverName,
 			ConnectionString:  cnnstr,
 			DSInfo:            dsInfo,
 			MetricColumnTypes: []string{"UNKNOWN", "TEXT", "VARCHAR", "CHAR"},
@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/tunnel"
+	"github.com/lib/pq"
+)
+
+// tunnelDriver opens Postgres connections through a tunnel.
+type tunnelDriver struct {
+	dialer tunnel.Dialer
+}
+
+func newTunnelDriver(dialer tunnel.Dialer) driver.Driver {
+	return &tunnelDriver{dialer: dialer}
+}
+
+func (d *tunnelDriver) Open(dsn string) (driver.Conn, error) {
+	return pq.DialOpen(pqDialer{dialer: d.dialer}, dsn)
+}
+
+// pqDialer adapts a tunnel dialer to the dialer interfaces of lib/pq.
+type pqDialer struct {
+	dialer tunnel.Dialer
+}
+
+func (d pqDialer) Dial(network, address string) (net.Conn, error) {
+	return d.dialer.DialContext(context.Background(), network, address)
+}
+
+func (d pqDialer) DialTimeout(network, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d.dialer.DialContext(ctx, network, address)
+}
+
+func (d pqDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d.dialer.DialContext(ctx, network, address)
+}
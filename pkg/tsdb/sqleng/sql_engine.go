@@ -17,6 +17,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/grafana-plugin-sdk-go/data/sqlutil"
 	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/tunnel"
 	"github.com/grafana/grafana/pkg/tsdb/intervalv2"
 	"github.com/grafana/grafana/pkg/util/errutil"
 	"xorm.io/core"
@@ -105,6 +106,13 @@ type QueryJson struct {
 }
 
 func (e *DataSourceHandler) transformQueryError(err error) error {
+	// Tunnel errors only describe the tunnel and help users fix its settings.
+	var tunnelErr *tunnel.Error
+	if errors.As(err, &tunnelErr) {
+		e.log.Error("query error", "err", err)
+		return tunnelErr
+	}
+
 	// OpError is the error type usually returned by functions in the net
 	// package. It describes the operation, network type, and address of
 	// an error. We log this error rather than return it to the client
@@ -0,0 +1,61 @@
+package sqleng
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana/pkg/infra/tunnel"
+	"xorm.io/core"
+)
+
+var (
+	tunnelDriversMu sync.Mutex
+	tunnelDrivers   = map[string]bool{}
+)
+
+// TunnelConfig returns the tunnel config of the data source or nil if the
+// data source does not use a tunnel.
+func TunnelConfig(settings backend.DataSourceInstanceSettings) (*tunnel.Config, error) {
+	jsonData := map[string]interface{}{}
+	if len(settings.JSONData) > 0 {
+		if err := json.Unmarshal(settings.JSONData, &jsonData); err != nil {
+			return nil, fmt.Errorf("error reading settings: %w", err)
+		}
+	}
+
+	cfg, err := tunnel.ConfigFromJSONData(jsonData, settings.DecryptedSecureJSONData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tunnel settings: %w", err)
+	}
+	return cfg, nil
+}
+
+// RegisterTunnelDriver registers a variant of the driver that connects
+// through the tunnel and returns its name. database/sql drivers can not be
+// unregistered, so data sources with the same tunnel settings share one.
+func RegisterTunnelDriver(driverName string, cfg *tunnel.Config, newDriver func(dialer tunnel.Dialer) driver.Driver) (string, error) {
+	name := fmt.Sprintf("%s-tunnel-%s", driverName, cfg.Key())
+
+	tunnelDriversMu.Lock()
+	defer tunnelDriversMu.Unlock()
+
+	if tunnelDrivers[name] {
+		return name, nil
+	}
+
+	dialer, err := tunnel.NewDialer(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	sql.Register(name, newDriver(dialer))
+	// xorm only needs the driver to parse the connection string
+	core.RegisterDriver(name, core.QueryDriver(driverName))
+	tunnelDrivers[name] = true
+
+	return name, nil
+}